@@ -153,6 +153,9 @@ const (
 	dataFlagIndexName                      = "index-name"
 	dataFlagIndexSpecFile                  = "index-path"
 	dataFlagLimit                          = "limit"
+	dataFlagCaptureDir                     = "capture-dir"
+	dataFlagFormat                         = "format"
+	dataFlagEncryptionKey                  = "encryption-key"
 
 	datapipelineFlagSchedule       = "schedule"
 	datapipelineFlagEnableBackfill = "enable-backfill"
@@ -1430,6 +1433,69 @@ Note: There is no progress meter while copying is in progress.
 							},
 							Action: createActionCommandWithT[dataExportTabularArgs](DataExportTabularAction),
 						},
+						{
+							Name:  "local",
+							Usage: "export data from a local capture directory, without syncing it to Viam cloud",
+							UsageText: createUsageText("data export local", []string{
+								dataFlagCaptureDir,
+								generalFlagDestination,
+							}, true, false),
+							Description: "Reads the capture files that data capture wrote to a machine's capture directory, " +
+								"such as one copied from the machine, and exports them to the destination. The tabular " +
+								"readings of each method of each resource are written in time order to " +
+								"<destination>/<api>/<resource name>/<method>.<format>, with the fields of CSV and parquet " +
+								"files flattened into columns. Each binary reading is written to its own file in " +
+								"<destination>/<api>/<resource name>/<method>/, named by the time it was requested.",
+							Flags: []cli.Flag{
+								&cli.StringFlag{
+									Name:      dataFlagCaptureDir,
+									Required:  true,
+									Usage:     "capture directory to export data from",
+									TakesFile: true,
+								},
+								&cli.StringFlag{
+									Name:      generalFlagDestination,
+									Required:  true,
+									Usage:     "output directory for exported data",
+									TakesFile: true,
+								},
+								&cli.StringFlag{
+									Name:  dataFlagFormat,
+									Usage: "file format of tabular data: csv, jsonl or parquet",
+									Value: "csv",
+								},
+								&cli.StringFlag{
+									Name:  dataFlagComponentType,
+									Usage: "component type filter, either the whole API (rdk:component:sensor) or its subtype (sensor)",
+								},
+								&cli.StringFlag{
+									Name:  dataFlagComponentName,
+									Usage: "component name filter",
+								},
+								&cli.StringFlag{
+									Name:  generalFlagMethod,
+									Usage: "method filter",
+								},
+								&cli.StringFlag{
+									Name:  generalFlagStart,
+									Usage: "ISO-8601 timestamp in RFC3339 format indicating the start of the interval",
+								},
+								&cli.StringFlag{
+									Name:  generalFlagEnd,
+									Usage: "ISO-8601 timestamp in RFC3339 format indicating the end of the interval",
+								},
+								&cli.StringSliceFlag{
+									Name:  generalFlagTags,
+									Usage: "tags filter. exports only the capture files that have all of the tags",
+								},
+								&cli.StringSliceFlag{
+									Name: dataFlagEncryptionKey,
+									Usage: "key to decrypt capture files encrypted at rest, as <key id>=<path to file holding the " +
+										"base64 encoded key>. repeat for each key the files were encrypted with",
+								},
+							},
+							Action: createActionCommandWithT[dataExportLocalArgs](DataExportLocalAction),
+						},
 					},
 				},
 				{
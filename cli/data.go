@@ -153,6 +153,73 @@ func DataExportTabularAction(ctx context.Context, cmd *cli.Command, args dataExp
 	return client.dataExportTabularAction(ctx, cmd, args)
 }
 
+type dataExportLocalArgs struct {
+	CaptureDir    string
+	Destination   string
+	Format        string
+	ComponentType string
+	ComponentName string
+	Method        string
+	Start         string
+	End           string
+	Tags          []string
+	EncryptionKey []string
+}
+
+// DataExportLocalAction is the corresponding action for 'data export local'. It reads capture files from disk, so
+// needs neither a login nor a network connection.
+func DataExportLocalAction(ctx context.Context, cmd *cli.Command, args dataExportLocalArgs) error {
+	opts := data.ExportOptions{
+		Format:        data.ExportFormat(args.Format),
+		ComponentType: args.ComponentType,
+		ComponentName: args.ComponentName,
+		MethodName:    args.Method,
+		Tags:          args.Tags,
+	}
+	start, err := parseTimeString(args.Start)
+	if err != nil {
+		return err
+	}
+	if start != nil {
+		opts.Start = start.AsTime()
+	}
+	end, err := parseTimeString(args.End)
+	if err != nil {
+		return err
+	}
+	if end != nil {
+		opts.End = end.AsTime()
+	}
+	if opts.Keyring, err = parseEncryptionKeys(args.EncryptionKey); err != nil {
+		return err
+	}
+
+	summary, err := data.ExportCaptureDir(args.CaptureDir, args.Destination, opts)
+	if err != nil {
+		return err
+	}
+	printf(cmd.Root().Writer, "Exported %d tabular and %d binary readings to %d files in %s",
+		summary.TabularReadings, summary.BinaryReadings, len(summary.Files), args.Destination)
+	return nil
+}
+
+// parseEncryptionKeys returns a keyring that decrypts with the keys given as <key id>=<key file> flags, or nil if
+// there are none.
+func parseEncryptionKeys(flags []string) (*data.Keyring, error) {
+	if len(flags) == 0 {
+		return nil, nil
+	}
+	keys := make([]data.EncryptionKeyConfig, 0, len(flags))
+	for _, flag := range flags {
+		id, keyFile, ok := strings.Cut(flag, "=")
+		if !ok || id == "" || keyFile == "" {
+			return nil, errors.Errorf("%s must be <key id>=<key file>, got %q", dataFlagEncryptionKey, flag)
+		}
+		keys = append(keys, data.EncryptionKeyConfig{ID: id, KeyFile: keyFile})
+	}
+	return data.LoadKeyring("", keys)
+}
+
 type dataQuerySQLArgs struct {
 	OrgID       string
 	SQL         string
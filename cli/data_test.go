@@ -2,6 +2,8 @@ package cli
 
 import (
 	"context"
+	crand "crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,13 +12,18 @@ import (
 	"strings"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	datapb "go.viam.com/api/app/data/v1"
 	datapipelinespb "go.viam.com/api/app/datapipelines/v1"
+	v1 "go.viam.com/api/app/datasync/v1"
 	"go.viam.com/test"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/testutils/inject"
 )
 
@@ -411,3 +418,97 @@ func TestDataQueryBinaryAction(t *testing.T) {
 		test.That(t, calls, test.ShouldEqual, 1)
 	})
 }
+
+func TestDataExportLocalAction(t *testing.T) {
+	captureDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	f, err := data.NewCaptureFile(captureDir, &v1.DataCaptureMetadata{
+		ComponentType: "rdk:component:sensor", ComponentName: "imu", MethodName: "Readings",
+		Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	})
+	test.That(t, err, test.ShouldBeNil)
+	for i := 0; i < 3; i++ {
+		ts := timestamppb.New(start.Add(time.Duration(i) * time.Second))
+		s, err := structpb.NewStruct(map[string]interface{}{"readings": map[string]interface{}{"i": i}})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, f.WriteNext(&v1.SensorData{
+			Metadata: &v1.SensorMetadata{TimeRequested: ts, TimeReceived: ts},
+			Data:     &v1.SensorData_Struct{Struct: s},
+		}), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	destination := t.TempDir()
+	out := &testWriter{}
+	cCtx := buildTestCmd(out, &testWriter{}, map[string]any{
+		dataFlagCaptureDir:     captureDir,
+		generalFlagDestination: destination,
+		dataFlagFormat:         "jsonl",
+		dataFlagComponentType:  "sensor",
+		generalFlagStart:       start.Add(time.Second).Format(time.RFC3339),
+	})
+	err = DataExportLocalAction(context.Background(), cCtx, parseStructFromCtx[dataExportLocalArgs](cCtx))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, out.messages, test.ShouldHaveLength, 1)
+	test.That(t, out.messages[0], test.ShouldContainSubstring, "Exported 2 tabular and 0 binary readings to 1 files")
+
+	//nolint:gosec
+	b, err := os.ReadFile(filepath.Join(destination, "rdk_component_sensor", "imu", "Readings.jsonl"))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, strings.Count(string(b), "\n"), test.ShouldEqual, 2)
+
+	cCtx = buildTestCmd(out, &testWriter{}, map[string]any{
+		dataFlagCaptureDir:     captureDir,
+		generalFlagDestination: destination,
+		dataFlagFormat:         "xlsx",
+	})
+	err = DataExportLocalAction(context.Background(), cCtx, parseStructFromCtx[dataExportLocalArgs](cCtx))
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "unsupported export format")
+}
+
+func TestDataExportLocalActionEncrypted(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := crand.Read(key)
+	test.That(t, err, test.ShouldBeNil)
+	keyFile := filepath.Join(t.TempDir(), "key")
+	test.That(t, os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)), 0o600), test.ShouldBeNil)
+	keyring, err := data.NewKeyring("a", map[string][]byte{"a": key})
+	test.That(t, err, test.ShouldBeNil)
+
+	captureDir := t.TempDir()
+	f, err := data.NewCaptureFileWithKeyring(captureDir, &v1.DataCaptureMetadata{
+		ComponentType: "rdk:component:sensor", ComponentName: "imu", MethodName: "Readings",
+		Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+	}, keyring)
+	test.That(t, err, test.ShouldBeNil)
+	s, err := structpb.NewStruct(map[string]interface{}{"readings": map[string]interface{}{"i": 1}})
+	test.That(t, err, test.ShouldBeNil)
+	ts := timestamppb.Now()
+	test.That(t, f.WriteNext(&v1.SensorData{
+		Metadata: &v1.SensorMetadata{TimeRequested: ts, TimeReceived: ts},
+		Data:     &v1.SensorData_Struct{Struct: s},
+	}), test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	export := func(encryptionKeys []string) (*testWriter, error) {
+		out := &testWriter{}
+		cCtx := buildTestCmd(out, &testWriter{}, map[string]any{
+			dataFlagCaptureDir:     captureDir,
+			generalFlagDestination: t.TempDir(),
+			dataFlagEncryptionKey:  encryptionKeys,
+		})
+		return out, DataExportLocalAction(context.Background(), cCtx, parseStructFromCtx[dataExportLocalArgs](cCtx))
+	}
+
+	_, err = export(nil)
+	test.That(t, errors.Is(err, data.ErrUnknownEncryptionKey), test.ShouldBeTrue)
+
+	out, err := export([]string{"a=" + keyFile})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, out.messages[0], test.ShouldContainSubstring, "Exported 1 tabular and 0 binary readings to 1 files")
+
+	_, err = export([]string{keyFile})
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "<key id>=<key file>")
+}
@@ -200,7 +200,7 @@ func newArm(
 		})
 	}
 
-	ret.gen = newTrajectoryGenerator(logger)
+	ret.gen = newTrajectoryGenerator()
 
 	return ret
 }
@@ -231,8 +231,8 @@ func (sa *simulatedArm) updateForTime(now time.Time) {
 
 	t := now.Sub(op.trajStart).Seconds()
 
-	// Declare done when within one sample period of the trajectory end. The trajex sampler
-	// in uniform_sampler.cpp spaces samples evenly from 0 to `duration`, and `duration` is
+	// Declare done when within one sample period of the trajectory end. The sampler in
+	// motionplan spaces samples evenly from 0 to `duration`, and `duration` is
 	// unavoidably larger than the nominal motion time by the acceleration ramp. One sample
 	// period of slop matches the sampler's own quantization granularity.
 	if last == 0 || t >= op.sampleTimes[last-1] {
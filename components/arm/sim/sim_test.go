@@ -15,7 +15,7 @@ import (
 
 // assertJointsAlmostResemble compares two joint-position slices element-wise with an
 // absolute tolerance. test.ShouldResemble itself is bit-exact (reflect.DeepEqual under the
-// hood), which is too strict once trajex-quantized sampling produces non-exact intermediate
+// hood), which is too strict once quantized trajectory sampling produces non-exact intermediate
 // values.
 func assertJointsAlmostResemble(t *testing.T, got, want []float64, eps float64) {
 	t.Helper()
@@ -0,0 +1,332 @@
+// Package ackermann implements a car-like base, with motors driving its wheels and a servo steering its front wheels.
+package ackermann
+
+/*
+   The ackermann model drives a base like a car: the drive motors turn the wheels at the same speed, and a servo steers
+   the front wheels. The base follows the bicycle model about the center of its rear axle, which is the origin of its
+   frame, so it turns with a radius of wheelbase / tan(steering angle) and cannot spin in place.
+   The servo turns the front wheels left as its angle increases from servo_center_degs, unless invert_steering is set.
+   Example Config:
+   {
+     "name": "myBase",
+     "type": "base",
+     "model": "ackermann",
+     "attributes": {
+       "drive": ["rear_left", "rear_right"],
+       "steering": "steering_servo",
+       "width_mm": 200,
+       "wheelbase_mm": 260,
+       "wheel_circumference_mm": 330,
+       "max_steering_angle_degs": 30
+     }
+   }
+*/
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/servo"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// Model is the name of the ackermann model of a base component.
+var Model = resource.DefaultModelFamily.WithModel("ackermann")
+
+const (
+	defaultServoCenterDegs = 90
+	maxServoDegs           = 180
+)
+
+// ErrCannotSpin is returned when an ackermann base is asked to turn without moving.
+var ErrCannotSpin = errors.New("an ackermann base cannot spin in place")
+
+// Config is how you configure an ackermann base.
+type Config struct {
+	Drive    []string `json:"drive"`
+	Steering string   `json:"steering"`
+	// WidthMM is the distance between the centers of the left and right wheels.
+	WidthMM int `json:"width_mm"`
+	// WheelbaseMM is the distance between the front and rear axles.
+	WheelbaseMM          int     `json:"wheelbase_mm"`
+	WheelCircumferenceMM int     `json:"wheel_circumference_mm"`
+	MaxSteeringAngleDegs float64 `json:"max_steering_angle_degs"`
+	// ServoCenterDegs is the angle of the servo that points the front wheels straight ahead. Defaults to 90.
+	ServoCenterDegs *float64 `json:"servo_center_degs,omitempty"`
+	InvertSteering  bool     `json:"invert_steering,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if len(cfg.Drive) == 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "drive")
+	}
+	if cfg.Steering == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "steering")
+	}
+	if cfg.WidthMM <= 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "width_mm")
+	}
+	if cfg.WheelbaseMM <= 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "wheelbase_mm")
+	}
+	if cfg.WheelCircumferenceMM <= 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "wheel_circumference_mm")
+	}
+	if cfg.MaxSteeringAngleDegs <= 0 || cfg.MaxSteeringAngleDegs >= 90 {
+		return nil, nil, resource.NewConfigValidationError(path,
+			fmt.Errorf("max_steering_angle_degs must be between 0 and 90, not %.2f", cfg.MaxSteeringAngleDegs))
+	}
+	center := cfg.servoCenter()
+	if center-cfg.MaxSteeringAngleDegs < 0 || center+cfg.MaxSteeringAngleDegs > maxServoDegs {
+		return nil, nil, resource.NewConfigValidationError(path,
+			fmt.Errorf("steering %.2f degrees either way from servo_center_degs %.2f exceeds the range of the servo",
+				cfg.MaxSteeringAngleDegs, center))
+	}
+
+	deps := append([]string{}, cfg.Drive...)
+	deps = append(deps, cfg.Steering)
+	return deps, nil, nil
+}
+
+func (cfg *Config) servoCenter() float64 {
+	if cfg.ServoCenterDegs == nil {
+		return defaultServoCenterDegs
+	}
+	return *cfg.ServoCenterDegs
+}
+
+func init() {
+	resource.RegisterComponent(base.API, Model, resource.Registration[base.Base, *Config]{Constructor: newAckermannBase})
+}
+
+type ackermannBase struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	drive    []motor.Motor
+	steering servo.Servo
+
+	widthMM              float64
+	wheelbaseMM          float64
+	wheelCircumferenceMM float64
+	maxSteeringRad       float64
+	servoCenterDegs      float64
+	steeringSign         float64
+	geometries           []spatialmath.Geometry
+
+	opMgr  *operation.SingleOperationManager
+	logger logging.Logger
+}
+
+func newAckermannBase(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (base.Base, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	ab := &ackermannBase{
+		Named:                conf.ResourceName().AsNamed(),
+		widthMM:              float64(newConf.WidthMM),
+		wheelbaseMM:          float64(newConf.WheelbaseMM),
+		wheelCircumferenceMM: float64(newConf.WheelCircumferenceMM),
+		maxSteeringRad:       rdkutils.DegToRad(newConf.MaxSteeringAngleDegs),
+		servoCenterDegs:      newConf.servoCenter(),
+		steeringSign:         1,
+		opMgr:                operation.NewSingleOperationManager(),
+		logger:               logger,
+	}
+	if newConf.InvertSteering {
+		ab.steeringSign = -1
+	}
+	for _, name := range newConf.Drive {
+		m, err := motor.FromProvider(deps, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "no drive motor named (%s)", name)
+		}
+		ab.drive = append(ab.drive, m)
+	}
+	if ab.steering, err = servo.FromProvider(deps, newConf.Steering); err != nil {
+		return nil, errors.Wrapf(err, "no steering servo named (%s)", newConf.Steering)
+	}
+
+	if conf.Frame != nil && conf.Frame.Geometry != nil {
+		geometry, err := conf.Frame.Geometry.ParseConfig()
+		if err != nil {
+			return nil, err
+		}
+		ab.geometries = []spatialmath.Geometry{geometry}
+	} else {
+		// The footprint spans the centers of the wheels side to side, and from the back of the rear wheels to the
+		// front of the front wheels.
+		diameter := ab.wheelCircumferenceMM / math.Pi
+		footprint, err := spatialmath.NewBox(
+			spatialmath.NewPoseFromPoint(r3.Vector{Y: ab.wheelbaseMM / 2}),
+			r3.Vector{X: ab.widthMM, Y: ab.wheelbaseMM + diameter, Z: diameter},
+			conf.Name)
+		if err != nil {
+			return nil, err
+		}
+		ab.geometries = []spatialmath.Geometry{footprint}
+	}
+	return ab, nil
+}
+
+// steer points the front wheels at the given angle, in radians to the left, clamped to the steering range.
+func (ab *ackermannBase) steer(ctx context.Context, angle float64) error {
+	angle = math.Max(-ab.maxSteeringRad, math.Min(ab.maxSteeringRad, angle))
+	servoDegs := ab.servoCenterDegs + ab.steeringSign*rdkutils.RadToDeg(angle)
+	return ab.steering.Move(ctx, uint32(math.Round(servoDegs)), nil)
+}
+
+// steeringAngleFor returns the steering angle, in radians to the left, that turns the base at degsPerSec while it
+// moves at mmPerSec.
+func (ab *ackermannBase) steeringAngleFor(mmPerSec, degsPerSec float64) (float64, error) {
+	if degsPerSec == 0 {
+		return 0, nil
+	}
+	if mmPerSec == 0 {
+		return 0, ErrCannotSpin
+	}
+	// In the bicycle model the base turns at v * tan(steering angle) / wheelbase.
+	return math.Atan(ab.wheelbaseMM * rdkutils.DegToRad(degsPerSec) / mmPerSec), nil
+}
+
+// Spin is not possible for an ackermann base, which must move to turn.
+func (ab *ackermannBase) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	return ErrCannotSpin
+}
+
+// MoveStraight straightens the front wheels, then drives forward or backwards at a linear speed and for a specific
+// distance.
+func (ab *ackermannBase) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	ab.logger.CDebugf(ctx, "received a MoveStraight with distanceMM:%d, mmPerSec:%.2f", distanceMm, mmPerSec)
+	if math.Abs(mmPerSec) < 0.0001 || distanceMm == 0 {
+		return ab.Stop(ctx, nil)
+	}
+	ctx, done := ab.opMgr.New(ctx)
+	defer done()
+	if err := ab.steer(ctx, 0); err != nil {
+		return err
+	}
+	rpm := mmPerSec / ab.wheelCircumferenceMM * 60
+	revolutions := float64(distanceMm) / ab.wheelCircumferenceMM
+	return ab.runAllDrive(ctx, func(ctx context.Context, m motor.Motor) error { return m.GoFor(ctx, rpm, revolutions, nil) })
+}
+
+// SetVelocity steers to turn at angular.Z while moving at linear.Y. Turning faster than the steering range allows
+// turns as fast as possible, and turning without moving is an error.
+func (ab *ackermannBase) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	ab.logger.CDebugf(ctx,
+		"received a SetVelocity with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f(mmPerSec),"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+	if linear.Y == 0 && angular.Z == 0 {
+		return ab.Stop(ctx, nil)
+	}
+	angle, err := ab.steeringAngleFor(linear.Y, angular.Z)
+	if err != nil {
+		return err
+	}
+	if math.Abs(angle) > ab.maxSteeringRad {
+		ab.logger.CDebugf(ctx, "cannot turn at %.2f degs/sec at %.2f mm/sec, turning as tightly as possible", angular.Z, linear.Y)
+	}
+
+	ctx, done := ab.opMgr.New(ctx)
+	defer done()
+	if err := ab.steer(ctx, angle); err != nil {
+		return err
+	}
+	rpm := linear.Y / ab.wheelCircumferenceMM * 60
+	return ab.runAllDrive(ctx, func(ctx context.Context, m motor.Motor) error { return m.SetRPM(ctx, rpm, nil) })
+}
+
+// SetPower drives the motors at linear.Y power and steers by angular.Z, where full power is the full steering range.
+// Positive angular.Z turns the base to the left when moving both forwards and backwards.
+func (ab *ackermannBase) SetPower(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	ab.opMgr.CancelRunning(ctx)
+	ab.logger.CDebugf(ctx,
+		"received a SetPower with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f,"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+	if linear.Y == 0 && angular.Z == 0 {
+		return ab.Stop(ctx, nil)
+	}
+	angle := angular.Z * ab.maxSteeringRad
+	if linear.Y < 0 {
+		// Reversing with the wheels turned left turns the base to the right.
+		angle = -angle
+	}
+	if err := ab.steer(ctx, angle); err != nil {
+		return err
+	}
+	power := math.Max(-1, math.Min(1, linear.Y))
+	return ab.runAllDrive(ctx, func(ctx context.Context, m motor.Motor) error { return m.SetPower(ctx, power, extra) })
+}
+
+// runAllDrive runs the command on every drive motor in parallel and stops the base if any fail.
+func (ab *ackermannBase) runAllDrive(ctx context.Context, command func(context.Context, motor.Motor) error) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(ab.drive))
+	for _, m := range ab.drive {
+		funcs = append(funcs, func(ctx context.Context) error { return command(ctx, m) })
+	}
+	if _, err := rdkutils.RunInParallel(ctx, funcs); err != nil {
+		err := multierr.Combine(err, ab.Stop(ctx, nil))
+		// Ignore the context canceled error - this occurs when the base is stopped by the user.
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		ab.logger.CWarnw(ctx, "context cancelled while moving the base", "error", err)
+	}
+	return nil
+}
+
+// Stop stops the drive motors, leaving the front wheels where they are.
+func (ab *ackermannBase) Stop(ctx context.Context, extra map[string]interface{}) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(ab.drive))
+	for _, m := range ab.drive {
+		funcs = append(funcs, func(ctx context.Context) error { return m.Stop(ctx, extra) })
+	}
+	_, err := rdkutils.RunInParallel(ctx, funcs)
+	return err
+}
+
+func (ab *ackermannBase) IsMoving(ctx context.Context) (bool, error) {
+	for _, m := range ab.drive {
+		isMoving, _, err := m.IsPowered(ctx, nil)
+		if err != nil {
+			return false, err
+		}
+		if isMoving {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (ab *ackermannBase) Close(ctx context.Context) error {
+	return ab.Stop(ctx, nil)
+}
+
+// Properties reports the turning radius of the center of the rear axle at full steering.
+func (ab *ackermannBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+	return base.Properties{
+		TurningRadiusMeters:      ab.wheelbaseMM / math.Tan(ab.maxSteeringRad) * 0.001,
+		WidthMeters:              ab.widthMM * 0.001,              // convert to meters from mm
+		WheelCircumferenceMeters: ab.wheelCircumferenceMM * 0.001, // convert to meters from mm
+	}, nil
+}
+
+func (ab *ackermannBase) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	return ab.geometries, nil
+}
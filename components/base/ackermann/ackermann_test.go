@@ -0,0 +1,206 @@
+package ackermann
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/servo"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// fakeCar records the last command of each drive motor and the last angle of the steering servo.
+type fakeCar struct {
+	mu       sync.Mutex
+	commands map[string][]float64
+	angle    uint32
+}
+
+func (c *fakeCar) record(name string, values ...float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commands[name] = values
+}
+
+func (c *fakeCar) get(name string) []float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.commands[name]
+}
+
+func (c *fakeCar) servoAngle() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.angle
+}
+
+func (c *fakeCar) deps() resource.Dependencies {
+	c.commands = map[string][]float64{}
+	deps := resource.Dependencies{}
+	for _, name := range []string{"left", "right"} {
+		m := inject.NewMotor(name)
+		m.GoForFunc = func(ctx context.Context, rpm, revolutions float64, extra map[string]interface{}) error {
+			c.record(name, rpm, revolutions)
+			return nil
+		}
+		m.SetRPMFunc = func(ctx context.Context, rpm float64, extra map[string]interface{}) error {
+			c.record(name, rpm)
+			return nil
+		}
+		m.SetPowerFunc = func(ctx context.Context, power float64, extra map[string]interface{}) error {
+			c.record(name, power)
+			return nil
+		}
+		m.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+			c.record(name)
+			return nil
+		}
+		m.IsPoweredFunc = func(ctx context.Context, extra map[string]interface{}) (bool, float64, error) {
+			return len(c.get(name)) > 0, 0, nil
+		}
+		deps[motor.Named(name)] = m
+	}
+	s := inject.NewServo("steering")
+	s.MoveFunc = func(ctx context.Context, angleDeg uint32, extra map[string]interface{}) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.angle = angleDeg
+		return nil
+	}
+	deps[servo.Named("steering")] = s
+	return deps
+}
+
+func testConfig() *Config {
+	return &Config{
+		Drive:                []string{"left", "right"},
+		Steering:             "steering",
+		WidthMM:              200,
+		WheelbaseMM:          260,
+		WheelCircumferenceMM: 330,
+		MaxSteeringAngleDegs: 30,
+	}
+}
+
+func newTestBase(t *testing.T, c *fakeCar, cfg *Config) base.Base {
+	t.Helper()
+	b, err := newAckermannBase(context.Background(), c.deps(),
+		resource.Config{Name: "car", API: base.API, Model: Model, ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return b
+}
+
+func TestValidate(t *testing.T) {
+	deps, _, err := testConfig().Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"left", "right", "steering"})
+
+	cfg := testConfig()
+	cfg.Drive = nil
+	_, _, err = cfg.Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "drive"))
+
+	cfg = testConfig()
+	cfg.Steering = ""
+	_, _, err = cfg.Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "steering"))
+
+	cfg = testConfig()
+	cfg.MaxSteeringAngleDegs = 90
+	_, _, err = cfg.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "max_steering_angle_degs")
+
+	cfg = testConfig()
+	center := 160.
+	cfg.ServoCenterDegs = &center
+	_, _, err = cfg.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "range of the servo")
+}
+
+func TestAckermann(t *testing.T) {
+	ctx := context.Background()
+	c := &fakeCar{}
+	b := newTestBase(t, c, testConfig())
+
+	t.Run("steers to turn at the commanded rate", func(t *testing.T) {
+		// A turning radius of 1m at 1m/s turns the base at 1 radian/s, which needs atan(0.26) of steering.
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 1000}, r3.Vector{Z: rdkutils.RadToDeg(1)}, nil), test.ShouldBeNil)
+		test.That(t, c.servoAngle(), test.ShouldEqual, 90+uint32(math.Round(rdkutils.RadToDeg(math.Atan(0.26)))))
+		test.That(t, c.get("left"), test.ShouldResemble, []float64{1000. / 330 * 60})
+		test.That(t, c.get("right"), test.ShouldResemble, []float64{1000. / 330 * 60})
+
+		// Turning faster than the steering allows turns as tightly as possible.
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 100}, r3.Vector{Z: -90}, nil), test.ShouldBeNil)
+		test.That(t, c.servoAngle(), test.ShouldEqual, 60)
+	})
+
+	t.Run("cannot spin in place", func(t *testing.T) {
+		test.That(t, b.Spin(ctx, 90, 45, nil), test.ShouldBeError, ErrCannotSpin)
+		test.That(t, b.SetVelocity(ctx, r3.Vector{}, r3.Vector{Z: 45}, nil), test.ShouldBeError, ErrCannotSpin)
+	})
+
+	t.Run("moves straight with the wheels centered", func(t *testing.T) {
+		test.That(t, b.MoveStraight(ctx, -660, 330, nil), test.ShouldBeNil)
+		test.That(t, c.servoAngle(), test.ShouldEqual, 90)
+		test.That(t, c.get("left"), test.ShouldResemble, []float64{60, -2})
+		test.That(t, c.get("right"), test.ShouldResemble, []float64{60, -2})
+	})
+
+	t.Run("mirrors the steering when reversing under power", func(t *testing.T) {
+		test.That(t, b.SetPower(ctx, r3.Vector{Y: 0.5}, r3.Vector{Z: 0.5}, nil), test.ShouldBeNil)
+		test.That(t, c.servoAngle(), test.ShouldEqual, 105)
+		test.That(t, c.get("left"), test.ShouldResemble, []float64{0.5})
+
+		test.That(t, b.SetPower(ctx, r3.Vector{Y: -0.5}, r3.Vector{Z: 0.5}, nil), test.ShouldBeNil)
+		test.That(t, c.servoAngle(), test.ShouldEqual, 75)
+		test.That(t, c.get("left"), test.ShouldResemble, []float64{-0.5})
+	})
+
+	t.Run("stops the drive motors", func(t *testing.T) {
+		moving, err := b.IsMoving(ctx)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, moving, test.ShouldBeTrue)
+
+		test.That(t, b.Stop(ctx, nil), test.ShouldBeNil)
+		moving, err = b.IsMoving(ctx)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, moving, test.ShouldBeFalse)
+		test.That(t, c.servoAngle(), test.ShouldEqual, 75)
+	})
+
+	t.Run("reports its turning radius and footprint", func(t *testing.T) {
+		props, err := b.Properties(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props.TurningRadiusMeters, test.ShouldAlmostEqual, 0.26/math.Tan(math.Pi/6))
+		test.That(t, props.WidthMeters, test.ShouldAlmostEqual, 0.2)
+		test.That(t, props.WheelCircumferenceMeters, test.ShouldAlmostEqual, 0.33)
+
+		geometries, err := b.Geometries(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, geometries, test.ShouldHaveLength, 1)
+		test.That(t, geometries[0].Pose().Point().Y, test.ShouldAlmostEqual, 130)
+	})
+}
+
+func TestInvertSteering(t *testing.T) {
+	c := &fakeCar{}
+	cfg := testConfig()
+	cfg.InvertSteering = true
+	center := 100.
+	cfg.ServoCenterDegs = &center
+	b := newTestBase(t, c, cfg)
+
+	test.That(t, b.SetPower(context.Background(), r3.Vector{Y: 1}, r3.Vector{Z: 1}, nil), test.ShouldBeNil)
+	test.That(t, c.servoAngle(), test.ShouldEqual, 70)
+}
@@ -0,0 +1,279 @@
+// Package holonomic implements bases that can translate in any direction while rotating, such as mecanum and omni
+// wheeled bases.
+package holonomic
+
+import (
+	"context"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// wheel is a driven wheel of a holonomic base. The speed of the surface of the wheel, in mm/s, is the dot product of
+// its row with the velocity of the base: (right in mm/s, forward in mm/s, counterclockwise in radians/s).
+type wheel struct {
+	motor motor.Motor
+	row   r3.Vector
+}
+
+// holonomicBase drives any number of wheels through the linear map from the velocity of the base to the surface
+// speed of each wheel, so the same implementation serves every wheel layout that can be written as such a map.
+type holonomicBase struct {
+	resource.Named
+	resource.AlwaysRebuild
+
+	wheels               []wheel
+	wheelCircumferenceMM float64
+	widthMM              float64
+	geometries           []spatialmath.Geometry
+
+	opMgr  *operation.SingleOperationManager
+	logger logging.Logger
+}
+
+// newHolonomicBase returns a base driving the given wheels. If the frame of the base does not configure a geometry,
+// footprint is used instead.
+func newHolonomicBase(
+	conf resource.Config,
+	wheels []wheel,
+	wheelCircumferenceMM, widthMM float64,
+	footprint spatialmath.Geometry,
+	logger logging.Logger,
+) (*holonomicBase, error) {
+	geometry := footprint
+	if conf.Frame != nil && conf.Frame.Geometry != nil {
+		var err error
+		if geometry, err = conf.Frame.Geometry.ParseConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return &holonomicBase{
+		Named:                conf.ResourceName().AsNamed(),
+		wheels:               wheels,
+		wheelCircumferenceMM: wheelCircumferenceMM,
+		widthMM:              widthMM,
+		geometries:           []spatialmath.Geometry{geometry},
+		opMgr:                operation.NewSingleOperationManager(),
+		logger:               logger,
+	}, nil
+}
+
+// motorsFromDeps looks up the named motors of a base.
+func motorsFromDeps(deps resource.Dependencies, names []string) ([]motor.Motor, error) {
+	motors := make([]motor.Motor, 0, len(names))
+	for _, name := range names {
+		m, err := motor.FromProvider(deps, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "no motor named (%s)", name)
+		}
+		motors = append(motors, m)
+	}
+	return motors, nil
+}
+
+// checkHolonomic returns an error unless the wheels can produce any combination of translation and rotation, which
+// needs the rows of the wheels to span all three dimensions.
+func checkHolonomic(rows []r3.Vector) error {
+	// The rows span three dimensions if and only if the sum of their outer products, whose rows are a, b and c, is
+	// invertible.
+	var a, b, c r3.Vector
+	for _, row := range rows {
+		a = a.Add(row.Mul(row.X))
+		b = b.Add(row.Mul(row.Y))
+		c = c.Add(row.Mul(row.Z))
+	}
+	det := a.Dot(b.Cross(c))
+	scale := a.Norm() * b.Norm() * c.Norm()
+	if scale == 0 || math.Abs(det) < 1e-9*scale {
+		return errors.New("the wheels cannot move the base in every direction")
+	}
+	return nil
+}
+
+// wheelRPMs returns the rpm of each wheel that moves the base at the given velocity, in mm/s right and forward and
+// radians/s counterclockwise.
+func (hb *holonomicBase) wheelRPMs(velocity r3.Vector) []float64 {
+	rpms := make([]float64, len(hb.wheels))
+	for i, w := range hb.wheels {
+		rpms[i] = w.row.Dot(velocity) / hb.wheelCircumferenceMM * 60
+	}
+	return rpms
+}
+
+// Spin commands a base to turn about its center at a angular speed and for a specific angle.
+func (hb *holonomicBase) Spin(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+	hb.logger.CDebugf(ctx, "received a Spin with angleDeg:%.2f, degsPerSec:%.2f", angleDeg, degsPerSec)
+	if math.Abs(angleDeg) < 0.0001 {
+		return errors.Errorf("cannot move base %v for an angle that is nearly 0", hb.Name().ShortName())
+	}
+	if math.Abs(degsPerSec) < 0.0001 {
+		return hb.Stop(ctx, nil)
+	}
+	ctx, done := hb.opMgr.New(ctx)
+	defer done()
+	return hb.runAllGoFor(ctx, r3.Vector{Z: 1}, rdkutils.DegToRad(degsPerSec), rdkutils.DegToRad(angleDeg))
+}
+
+// MoveStraight commands a base to drive forward or backwards at a linear speed and for a specific distance.
+func (hb *holonomicBase) MoveStraight(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+	hb.logger.CDebugf(ctx, "received a MoveStraight with distanceMM:%d, mmPerSec:%.2f", distanceMm, mmPerSec)
+	if math.Abs(mmPerSec) < 0.0001 || distanceMm == 0 {
+		return hb.Stop(ctx, nil)
+	}
+	ctx, done := hb.opMgr.New(ctx)
+	defer done()
+	return hb.runAllGoFor(ctx, r3.Vector{Y: 1}, mmPerSec, float64(distanceMm))
+}
+
+// runAllGoFor moves the base along one axis of its velocity, in the manner of motor.GoFor: the signs of speed and
+// distance together set the direction of travel. Wheels that do not contribute to the motion are stopped.
+func (hb *holonomicBase) runAllGoFor(ctx context.Context, axis r3.Vector, speed, distance float64) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(hb.wheels))
+	for i, rpm := range hb.wheelRPMs(axis.Mul(speed)) {
+		m := hb.wheels[i].motor
+		// The sign of the rpm already accounts for the direction of the wheel, so the revolutions keep the sign of
+		// the distance alone.
+		revolutions := math.Abs(hb.wheels[i].row.Dot(axis)) * distance / hb.wheelCircumferenceMM
+		if math.Abs(rpm) < 1e-6 || math.Abs(revolutions) < 1e-6 {
+			funcs = append(funcs, func(ctx context.Context) error { return m.Stop(ctx, nil) })
+			continue
+		}
+		funcs = append(funcs, func(ctx context.Context) error { return m.GoFor(ctx, rpm, revolutions, nil) })
+	}
+	return hb.runAll(ctx, funcs)
+}
+
+// SetVelocity commands the base to move at the input linear and angular velocities. Unlike a differential drive,
+// linear.X moves the base to the right.
+func (hb *holonomicBase) SetVelocity(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	hb.logger.CDebugf(ctx,
+		"received a SetVelocity with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f(mmPerSec),"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+	if linear.Norm() == 0 && angular.Norm() == 0 {
+		return hb.Stop(ctx, nil)
+	}
+
+	rpms := hb.wheelRPMs(r3.Vector{X: linear.X, Y: linear.Y, Z: rdkutils.DegToRad(angular.Z)})
+	funcs := make([]rdkutils.SimpleFunc, 0, len(hb.wheels))
+	for i, rpm := range rpms {
+		m := hb.wheels[i].motor
+		funcs = append(funcs, func(ctx context.Context) error { return m.SetRPM(ctx, rpm, nil) })
+	}
+
+	ctx, done := hb.opMgr.New(ctx)
+	defer done()
+	return hb.runAll(ctx, funcs)
+}
+
+// SetPower commands the base motors to run at powers corresponding to input linear and angular powers. Full power
+// along any one axis drives the fastest wheel at full power; combined commands that would exceed full power on any
+// wheel are scaled down together, preserving the direction of motion.
+func (hb *holonomicBase) SetPower(ctx context.Context, linear, angular r3.Vector, extra map[string]interface{}) error {
+	hb.opMgr.CancelRunning(ctx)
+	hb.logger.CDebugf(ctx,
+		"received a SetPower with linear.X: %.2f, linear.Y: %.2f linear.Z: %.2f,"+
+			" angular.X: %.2f, angular.Y: %.2f, angular.Z: %.2f",
+		linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z)
+	if linear.Norm() == 0 && angular.Norm() == 0 {
+		return hb.Stop(ctx, nil)
+	}
+
+	// Normalize each axis by the wheel it drives the fastest.
+	var maxRow r3.Vector
+	for _, w := range hb.wheels {
+		row := w.row.Abs()
+		maxRow = r3.Vector{X: math.Max(maxRow.X, row.X), Y: math.Max(maxRow.Y, row.Y), Z: math.Max(maxRow.Z, row.Z)}
+	}
+	normalize := func(value, max float64) float64 {
+		if max == 0 {
+			return 0
+		}
+		return value / max
+	}
+	power := r3.Vector{X: normalize(linear.X, maxRow.X), Y: normalize(linear.Y, maxRow.Y), Z: normalize(angular.Z, maxRow.Z)}
+
+	powers := make([]float64, len(hb.wheels))
+	scale := 1.
+	for i, w := range hb.wheels {
+		powers[i] = w.row.Dot(power)
+		if math.Abs(powers[i]) > 1 {
+			scale = math.Min(scale, 1/math.Abs(powers[i]))
+		}
+	}
+	funcs := make([]rdkutils.SimpleFunc, 0, len(hb.wheels))
+	for i, p := range powers {
+		m := hb.wheels[i].motor
+		p *= scale
+		funcs = append(funcs, func(ctx context.Context) error { return m.SetPower(ctx, p, extra) })
+	}
+	if _, err := rdkutils.RunInParallel(ctx, funcs); err != nil {
+		return multierr.Combine(err, hb.Stop(ctx, nil))
+	}
+	return nil
+}
+
+// runAll runs the motor commands in parallel and stops the base if any fail. All callers must register an
+// operation via `hb.opMgr.New` so that every wheel receives consistent instructions.
+func (hb *holonomicBase) runAll(ctx context.Context, funcs []rdkutils.SimpleFunc) error {
+	if _, err := rdkutils.RunInParallel(ctx, funcs); err != nil {
+		err := multierr.Combine(err, hb.Stop(ctx, nil))
+		// Ignore the context canceled error - this occurs when the base is stopped by the user.
+		if !errors.Is(err, context.Canceled) {
+			return err
+		}
+		hb.logger.CWarnw(ctx, "context cancelled while moving the base", "error", err)
+	}
+	return nil
+}
+
+// Stop commands the base to stop moving.
+func (hb *holonomicBase) Stop(ctx context.Context, extra map[string]interface{}) error {
+	funcs := make([]rdkutils.SimpleFunc, 0, len(hb.wheels))
+	for _, w := range hb.wheels {
+		m := w.motor
+		funcs = append(funcs, func(ctx context.Context) error { return m.Stop(ctx, extra) })
+	}
+	_, err := rdkutils.RunInParallel(ctx, funcs)
+	return err
+}
+
+func (hb *holonomicBase) IsMoving(ctx context.Context) (bool, error) {
+	for _, w := range hb.wheels {
+		isMoving, _, err := w.motor.IsPowered(ctx, nil)
+		if err != nil {
+			return false, err
+		}
+		if isMoving {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (hb *holonomicBase) Close(ctx context.Context) error {
+	return hb.Stop(ctx, nil)
+}
+
+// Properties reports a turning radius of zero, since the base can spin in place.
+func (hb *holonomicBase) Properties(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+	return base.Properties{
+		TurningRadiusMeters:      0,
+		WidthMeters:              hb.widthMM * 0.001,              // convert to meters from mm
+		WheelCircumferenceMeters: hb.wheelCircumferenceMM * 0.001, // convert to meters from mm
+	}, nil
+}
+
+func (hb *holonomicBase) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	return hb.geometries, nil
+}
@@ -0,0 +1,263 @@
+package holonomic
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+const testCircumferenceMM = 300
+
+// command is the last command a recorded motor received.
+type command struct {
+	method      string
+	rpm         float64
+	revolutions float64
+	power       float64
+}
+
+type recorder struct {
+	mu       sync.Mutex
+	commands map[string]command
+}
+
+// motors returns injected motors with the given names that record their last command.
+func (r *recorder) motors(names ...string) resource.Dependencies {
+	r.commands = map[string]command{}
+	deps := resource.Dependencies{}
+	for _, name := range names {
+		m := inject.NewMotor(name)
+		record := func(c command) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.commands[name] = c
+			return nil
+		}
+		m.SetRPMFunc = func(ctx context.Context, rpm float64, extra map[string]interface{}) error {
+			return record(command{method: "SetRPM", rpm: rpm})
+		}
+		m.GoForFunc = func(ctx context.Context, rpm, revolutions float64, extra map[string]interface{}) error {
+			return record(command{method: "GoFor", rpm: rpm, revolutions: revolutions})
+		}
+		m.SetPowerFunc = func(ctx context.Context, power float64, extra map[string]interface{}) error {
+			return record(command{method: "SetPower", power: power})
+		}
+		m.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+			return record(command{method: "Stop"})
+		}
+		m.IsPoweredFunc = func(ctx context.Context, extra map[string]interface{}) (bool, float64, error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			c := r.commands[name]
+			return c.method != "" && c.method != "Stop", 0, nil
+		}
+		deps[motor.Named(name)] = m
+	}
+	return deps
+}
+
+func (r *recorder) get(name string) command {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.commands[name]
+}
+
+// mmPerSecToRPM converts the speed of the surface of a wheel to the rpm of its motor.
+func mmPerSecToRPM(mmPerSec float64) float64 {
+	return mmPerSec / testCircumferenceMM * 60
+}
+
+func newMecanum(t *testing.T, r *recorder) base.Base {
+	t.Helper()
+	cfg := &MecanumConfig{
+		FrontLeft: "fl", FrontRight: "fr", BackLeft: "bl", BackRight: "br",
+		WidthMM: 300, WheelbaseMM: 200, WheelCircumferenceMM: testCircumferenceMM,
+	}
+	deps, _, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	b, err := newMecanumBase(context.Background(), r.motors(deps...),
+		resource.Config{Name: "mecanum", API: base.API, Model: MecanumModel, ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return b
+}
+
+// kiwiConfig has three wheels 150mm from the center, the first at the front, each pointing counterclockwise.
+func kiwiConfig() *OmniConfig {
+	cfg := &OmniConfig{WheelCircumferenceMM: testCircumferenceMM}
+	for i, name := range []string{"front", "back_left", "back_right"} {
+		theta := math.Pi/2 + float64(i)*2*math.Pi/3
+		cfg.Wheels = append(cfg.Wheels, OmniWheelConfig{
+			Motor:     name,
+			XMM:       150 * math.Cos(theta),
+			YMM:       150 * math.Sin(theta),
+			AngleDegs: rdkutils.RadToDeg(theta + math.Pi/2),
+		})
+	}
+	return cfg
+}
+
+func TestMecanum(t *testing.T) {
+	ctx := context.Background()
+	r := &recorder{}
+	b := newMecanum(t, r)
+	expectRPMs := func(expected map[string]float64) {
+		t.Helper()
+		for name, rpm := range expected {
+			test.That(t, r.get(name).method, test.ShouldEqual, "SetRPM")
+			test.That(t, r.get(name).rpm, test.ShouldAlmostEqual, rpm)
+		}
+	}
+
+	t.Run("drives forward", func(t *testing.T) {
+		test.That(t, b.SetVelocity(ctx, r3.Vector{Y: 100}, r3.Vector{}, nil), test.ShouldBeNil)
+		rpm := mmPerSecToRPM(100)
+		expectRPMs(map[string]float64{"fl": rpm, "fr": rpm, "bl": rpm, "br": rpm})
+	})
+
+	t.Run("strafes right", func(t *testing.T) {
+		test.That(t, b.SetVelocity(ctx, r3.Vector{X: 100}, r3.Vector{}, nil), test.ShouldBeNil)
+		rpm := mmPerSecToRPM(100)
+		expectRPMs(map[string]float64{"fl": rpm, "fr": -rpm, "bl": -rpm, "br": rpm})
+	})
+
+	t.Run("spins counterclockwise", func(t *testing.T) {
+		test.That(t, b.SetVelocity(ctx, r3.Vector{}, r3.Vector{Z: 90}, nil), test.ShouldBeNil)
+		// Each wheel moves a quarter of the way around a circle through its neighbors, (300 + 200) / 2 from the center
+		// along each axis, per second.
+		rpm := mmPerSecToRPM(250 * math.Pi / 2)
+		expectRPMs(map[string]float64{"fl": -rpm, "fr": rpm, "bl": -rpm, "br": rpm})
+	})
+
+	t.Run("moves straight and spins for a distance", func(t *testing.T) {
+		test.That(t, b.MoveStraight(ctx, -600, 100, nil), test.ShouldBeNil)
+		for _, name := range []string{"fl", "fr", "bl", "br"} {
+			c := r.get(name)
+			test.That(t, c.method, test.ShouldEqual, "GoFor")
+			test.That(t, c.rpm*c.revolutions, test.ShouldAlmostEqual, -mmPerSecToRPM(100)*2)
+		}
+		// Spinning a positive angle at a negative speed turns clockwise, driving the left wheels forward.
+		test.That(t, b.Spin(ctx, 90, -45, nil), test.ShouldBeNil)
+		for name, sign := range map[string]float64{"fl": 1, "fr": -1, "bl": 1, "br": -1} {
+			c := r.get(name)
+			test.That(t, math.Abs(c.rpm), test.ShouldAlmostEqual, mmPerSecToRPM(250*math.Pi/4))
+			test.That(t, math.Abs(c.revolutions), test.ShouldAlmostEqual, 250*math.Pi/2/testCircumferenceMM)
+			test.That(t, math.Signbit(c.rpm*c.revolutions), test.ShouldEqual, sign < 0)
+		}
+		test.That(t, b.Spin(ctx, 0, 45, nil), test.ShouldNotBeNil)
+	})
+
+	t.Run("scales power down together", func(t *testing.T) {
+		test.That(t, b.SetPower(ctx, r3.Vector{Y: 1}, r3.Vector{}, nil), test.ShouldBeNil)
+		test.That(t, r.get("fl").power, test.ShouldAlmostEqual, 1)
+		test.That(t, b.SetPower(ctx, r3.Vector{X: 1, Y: 1}, r3.Vector{}, nil), test.ShouldBeNil)
+		// Moving diagonally needs only one diagonal of wheels, at full power.
+		test.That(t, r.get("fl").power, test.ShouldAlmostEqual, 1)
+		test.That(t, r.get("fr").power, test.ShouldAlmostEqual, 0)
+		test.That(t, b.SetPower(ctx, r3.Vector{Y: 1}, r3.Vector{Z: 1}, nil), test.ShouldBeNil)
+		test.That(t, r.get("fr").power, test.ShouldAlmostEqual, 1)
+		test.That(t, r.get("fl").power, test.ShouldAlmostEqual, 0)
+	})
+
+	t.Run("stops", func(t *testing.T) {
+		moving, err := b.IsMoving(ctx)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, moving, test.ShouldBeTrue)
+		test.That(t, b.SetVelocity(ctx, r3.Vector{}, r3.Vector{}, nil), test.ShouldBeNil)
+		moving, err = b.IsMoving(ctx)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, moving, test.ShouldBeFalse)
+	})
+
+	t.Run("properties and geometries", func(t *testing.T) {
+		props, err := b.Properties(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props, test.ShouldResemble, base.Properties{WidthMeters: 0.3, WheelCircumferenceMeters: 0.3})
+		geoms, err := b.Geometries(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, geoms, test.ShouldHaveLength, 1)
+		test.That(t, geoms[0].Label(), test.ShouldEqual, "mecanum")
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		_, _, err := (&MecanumConfig{FrontLeft: "fl", FrontRight: "fr", BackLeft: "bl", WidthMM: 1, WheelbaseMM: 1,
+			WheelCircumferenceMM: 1}).Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "back_right")
+		_, _, err = (&MecanumConfig{FrontLeft: "fl", FrontRight: "fr", BackLeft: "bl", BackRight: "br"}).Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
+
+func TestOmni(t *testing.T) {
+	ctx := context.Background()
+	r := &recorder{}
+	cfg := kiwiConfig()
+	deps, _, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	b, err := newOmniBase(ctx, r.motors(deps...),
+		resource.Config{Name: "kiwi", API: base.API, Model: OmniModel, ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+
+	t.Run("spins with every wheel", func(t *testing.T) {
+		test.That(t, b.SetVelocity(ctx, r3.Vector{}, r3.Vector{Z: -90}, nil), test.ShouldBeNil)
+		for _, name := range deps {
+			test.That(t, r.get(name).rpm, test.ShouldAlmostEqual, -mmPerSecToRPM(150*math.Pi/2))
+		}
+	})
+
+	t.Run("translates without rotating", func(t *testing.T) {
+		// Driving right, the front wheel pushes right and the back wheels share the rest, with no net torque.
+		test.That(t, b.SetVelocity(ctx, r3.Vector{X: 100}, r3.Vector{}, nil), test.ShouldBeNil)
+		test.That(t, r.get("front").rpm, test.ShouldAlmostEqual, mmPerSecToRPM(-100))
+		test.That(t, r.get("back_left").rpm, test.ShouldAlmostEqual, mmPerSecToRPM(50))
+		test.That(t, r.get("back_right").rpm, test.ShouldAlmostEqual, mmPerSecToRPM(50))
+	})
+
+	t.Run("moves straight without the front wheel", func(t *testing.T) {
+		test.That(t, b.MoveStraight(ctx, 300, 100, nil), test.ShouldBeNil)
+		test.That(t, r.get("front").method, test.ShouldEqual, "Stop")
+		left, right := r.get("back_left"), r.get("back_right")
+		test.That(t, left.method, test.ShouldEqual, "GoFor")
+		test.That(t, left.rpm, test.ShouldAlmostEqual, -right.rpm)
+		test.That(t, left.revolutions, test.ShouldAlmostEqual, right.revolutions)
+		test.That(t, left.revolutions, test.ShouldAlmostEqual, 300*math.Sqrt(3)/2/testCircumferenceMM)
+	})
+
+	t.Run("properties and geometries", func(t *testing.T) {
+		props, err := b.Properties(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props.WidthMeters, test.ShouldAlmostEqual, 0.3*math.Sqrt(3)/2)
+		test.That(t, props.TurningRadiusMeters, test.ShouldEqual, 0)
+		geoms, err := b.Geometries(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, geoms, test.ShouldHaveLength, 1)
+		// The wheels are further forward than back, so the footprint is centered ahead of the base.
+		test.That(t, geoms[0].Pose().Point().Y, test.ShouldAlmostEqual, 37.5)
+	})
+
+	t.Run("invalid config", func(t *testing.T) {
+		parallel := kiwiConfig()
+		for i := range parallel.Wheels {
+			parallel.Wheels[i].AngleDegs = 90
+		}
+		_, _, err := parallel.Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "cannot move the base in every direction")
+
+		tooFew := kiwiConfig()
+		tooFew.Wheels = tooFew.Wheels[:2]
+		_, _, err = tooFew.Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,112 @@
+package holonomic
+
+/*
+   The mecanum model drives four mecanum wheels, one at each corner of a rectangle, whose rollers are at 45 degrees to
+   the axles and form an X when viewed from above. Besides driving and spinning like a differential drive, the base can
+   strafe sideways: SetVelocity accepts a lateral velocity in linear.X, positive to the right.
+   Example Config:
+   {
+     "name": "myBase",
+     "type": "base",
+     "model": "mecanum",
+     "attributes": {
+       "front_left": "fl",
+       "front_right": "fr",
+       "back_left": "bl",
+       "back_right": "br",
+       "width_mm": 300,
+       "wheelbase_mm": 250,
+       "wheel_circumference_mm": 300
+     }
+   }
+*/
+
+import (
+	"context"
+	"math"
+
+	"github.com/golang/geo/r3"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+)
+
+// MecanumModel is the name of the mecanum model of a base component.
+var MecanumModel = resource.DefaultModelFamily.WithModel("mecanum")
+
+// MecanumConfig is how you configure a mecanum base.
+type MecanumConfig struct {
+	FrontLeft  string `json:"front_left"`
+	FrontRight string `json:"front_right"`
+	BackLeft   string `json:"back_left"`
+	BackRight  string `json:"back_right"`
+	// WidthMM is the distance between the centers of the left and right wheels.
+	WidthMM int `json:"width_mm"`
+	// WheelbaseMM is the distance between the centers of the front and back wheels.
+	WheelbaseMM          int `json:"wheelbase_mm"`
+	WheelCircumferenceMM int `json:"wheel_circumference_mm"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *MecanumConfig) Validate(path string) ([]string, []string, error) {
+	for field, value := range map[string]int{
+		"width_mm":               cfg.WidthMM,
+		"wheelbase_mm":           cfg.WheelbaseMM,
+		"wheel_circumference_mm": cfg.WheelCircumferenceMM,
+	} {
+		if value <= 0 {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, field)
+		}
+	}
+	motors := []string{cfg.FrontLeft, cfg.FrontRight, cfg.BackLeft, cfg.BackRight}
+	for i, field := range []string{"front_left", "front_right", "back_left", "back_right"} {
+		if motors[i] == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, field)
+		}
+	}
+	return motors, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(base.API, MecanumModel, resource.Registration[base.Base, *MecanumConfig]{Constructor: newMecanumBase})
+}
+
+// mecanumRows returns the rows of the front left, front right, back left and back right wheels. A wheel on a diagonal
+// turns forward both to move the base forward and to strafe it one way, while the wheels on the other diagonal turn
+// backward to strafe it that way.
+func mecanumRows(widthMM, wheelbaseMM float64) []r3.Vector {
+	spin := (widthMM + wheelbaseMM) / 2
+	return []r3.Vector{
+		{X: 1, Y: 1, Z: -spin},
+		{X: -1, Y: 1, Z: spin},
+		{X: -1, Y: 1, Z: -spin},
+		{X: 1, Y: 1, Z: spin},
+	}
+}
+
+func newMecanumBase(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (base.Base, error) {
+	newConf, err := resource.NativeConfig[*MecanumConfig](conf)
+	if err != nil {
+		return nil, err
+	}
+	motors, err := motorsFromDeps(deps, []string{newConf.FrontLeft, newConf.FrontRight, newConf.BackLeft, newConf.BackRight})
+	if err != nil {
+		return nil, err
+	}
+	width, wheelbase := float64(newConf.WidthMM), float64(newConf.WheelbaseMM)
+	wheels := make([]wheel, 0, len(motors))
+	for i, row := range mecanumRows(width, wheelbase) {
+		wheels = append(wheels, wheel{motor: motors[i], row: row})
+	}
+
+	// The footprint spans the centers of the wheels side to side, and their full diameter front to back.
+	diameter := float64(newConf.WheelCircumferenceMM) / math.Pi
+	footprint, err := spatialmath.NewBox(
+		spatialmath.NewZeroPose(), r3.Vector{X: width, Y: wheelbase + diameter, Z: diameter}, conf.Name)
+	if err != nil {
+		return nil, err
+	}
+	return newHolonomicBase(conf, wheels, float64(newConf.WheelCircumferenceMM), width, footprint, logger)
+}
@@ -0,0 +1,126 @@
+package holonomic
+
+/*
+   The omni model drives three or more omni wheels at arbitrary positions and angles, such as the three wheels of a
+   kiwi drive or the four wheels of an X drive. Each wheel is placed in the frame of the base, with +Y forward and +X to
+   the right, and points in the direction it pushes the base when its motor turns forward, in degrees counterclockwise
+   from +X. SetVelocity accepts a lateral velocity in linear.X, positive to the right.
+   Example Config, a kiwi drive with its first wheel at the front:
+   {
+     "name": "myBase",
+     "type": "base",
+     "model": "omni",
+     "attributes": {
+       "wheels": [
+         {"motor": "front", "x_mm": 0, "y_mm": 150, "angle_degs": 180},
+         {"motor": "back_left", "x_mm": -129.9, "y_mm": -75, "angle_degs": -60},
+         {"motor": "back_right", "x_mm": 129.9, "y_mm": -75, "angle_degs": 60}
+       ],
+       "wheel_circumference_mm": 190
+     }
+   }
+*/
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	rdkutils "go.viam.com/rdk/utils"
+)
+
+// OmniModel is the name of the omni model of a base component.
+var OmniModel = resource.DefaultModelFamily.WithModel("omni")
+
+// OmniWheelConfig places one wheel of an omni base.
+type OmniWheelConfig struct {
+	Motor string  `json:"motor"`
+	XMM   float64 `json:"x_mm"`
+	YMM   float64 `json:"y_mm"`
+	// AngleDegs is the direction the wheel pushes the base when its motor turns forward, counterclockwise from +X.
+	AngleDegs float64 `json:"angle_degs"`
+}
+
+// OmniConfig is how you configure an omni base.
+type OmniConfig struct {
+	Wheels               []OmniWheelConfig `json:"wheels"`
+	WheelCircumferenceMM int               `json:"wheel_circumference_mm"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *OmniConfig) Validate(path string) ([]string, []string, error) {
+	if cfg.WheelCircumferenceMM <= 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "wheel_circumference_mm")
+	}
+	if len(cfg.Wheels) < 3 {
+		return nil, nil, resource.NewConfigValidationError(path,
+			fmt.Errorf("an omni base needs at least 3 wheels, not %d", len(cfg.Wheels)))
+	}
+	deps := make([]string, 0, len(cfg.Wheels))
+	for i, w := range cfg.Wheels {
+		if w.Motor == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(fmt.Sprintf("%s.wheels.%d", path, i), "motor")
+		}
+		deps = append(deps, w.Motor)
+	}
+	if err := checkHolonomic(cfg.rows()); err != nil {
+		return nil, nil, resource.NewConfigValidationError(path, err)
+	}
+	return deps, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(base.API, OmniModel, resource.Registration[base.Base, *OmniConfig]{Constructor: newOmniBase})
+}
+
+// rows returns the row of each wheel. A wheel at r pointing along d moves its surface at d · (v + ω × r).
+func (cfg *OmniConfig) rows() []r3.Vector {
+	rows := make([]r3.Vector, 0, len(cfg.Wheels))
+	for _, w := range cfg.Wheels {
+		sin, cos := math.Sincos(rdkutils.DegToRad(w.AngleDegs))
+		rows = append(rows, r3.Vector{X: cos, Y: sin, Z: w.XMM*sin - w.YMM*cos})
+	}
+	return rows
+}
+
+func newOmniBase(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (base.Base, error) {
+	newConf, err := resource.NativeConfig[*OmniConfig](conf)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(newConf.Wheels))
+	for _, w := range newConf.Wheels {
+		names = append(names, w.Motor)
+	}
+	motors, err := motorsFromDeps(deps, names)
+	if err != nil {
+		return nil, err
+	}
+	wheels := make([]wheel, 0, len(motors))
+	for i, row := range newConf.rows() {
+		wheels = append(wheels, wheel{motor: motors[i], row: row})
+	}
+
+	// The footprint spans the centers of the wheels, padded by the radius of the wheels.
+	minPt := r3.Vector{X: math.Inf(1), Y: math.Inf(1)}
+	maxPt := r3.Vector{X: math.Inf(-1), Y: math.Inf(-1)}
+	for _, w := range newConf.Wheels {
+		minPt = r3.Vector{X: math.Min(minPt.X, w.XMM), Y: math.Min(minPt.Y, w.YMM)}
+		maxPt = r3.Vector{X: math.Max(maxPt.X, w.XMM), Y: math.Max(maxPt.Y, w.YMM)}
+	}
+	diameter := float64(newConf.WheelCircumferenceMM) / math.Pi
+	dims := maxPt.Sub(minPt).Add(r3.Vector{X: diameter, Y: diameter, Z: diameter})
+	center := maxPt.Add(minPt).Mul(0.5)
+	footprint, err := spatialmath.NewBox(spatialmath.NewPoseFromPoint(center), dims, conf.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build the footprint of the base")
+	}
+	return newHolonomicBase(conf, wheels, float64(newConf.WheelCircumferenceMM), maxPt.X-minPt.X, footprint, logger)
+}
@@ -3,7 +3,9 @@ package register
 
 import (
 	// register bases.
+	_ "go.viam.com/rdk/components/base/ackermann"
 	_ "go.viam.com/rdk/components/base/fake"
+	_ "go.viam.com/rdk/components/base/holonomic"
 	_ "go.viam.com/rdk/components/base/sensorcontrolled"
 	_ "go.viam.com/rdk/components/base/wheeled"
 )
@@ -2,6 +2,7 @@ package fake
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 
@@ -54,6 +55,30 @@ func TestPCD(t *testing.T) {
 	test.That(t, err, test.ShouldBeNil)
 }
 
+func TestPLYAndXYZ(t *testing.T) {
+	dir := t.TempDir()
+	plyPath := filepath.Join(dir, "cloud.ply")
+	test.That(t, os.WriteFile(plyPath, []byte("ply\nformat ascii 1.0\nelement vertex 2\n"+
+		"property float x\nproperty float y\nproperty float z\nproperty uchar red\nproperty uchar green\nproperty uchar blue\n"+
+		"end_header\n0 0 1 255 0 0\n0.5 0 1 0 255 0\n"), 0o600), test.ShouldBeNil)
+	xyzPath := filepath.Join(dir, "cloud.xyz")
+	test.That(t, os.WriteFile(xyzPath, []byte("0 0 1\n0.5 0 1\n0 0.5 1\n"), 0o600), test.ShouldBeNil)
+
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	for path, size := range map[string]int{plyPath: 2, xyzPath: 3} {
+		cam, err := newCamera(ctx, resource.Name{API: camera.API}, &fileSourceConfig{PointCloud: path}, logger)
+		test.That(t, err, test.ShouldBeNil)
+		pc, err := cam.NextPointCloud(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, pc.Size(), test.ShouldEqual, size)
+		_, ok := pc.At(500, 0, 1000)
+		test.That(t, ok, test.ShouldBeTrue)
+		test.That(t, pc.MetaData().HasColor, test.ShouldEqual, path == plyPath)
+		test.That(t, cam.Close(ctx), test.ShouldBeNil)
+	}
+}
+
 func TestColor(t *testing.T) {
 	colorImgPath := artifact.MustPath("vision/objectdetection/detection_test.jpg")
 	cfg := &fileSourceConfig{Color: colorImgPath}
@@ -0,0 +1,60 @@
+package replaypcd
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/utils/contextutils"
+)
+
+// nextPointCloudMethod is the method data capture records point clouds from.
+const nextPointCloudMethod = "NextPointCloud"
+
+// initializeLocal replays the point clouds in the capture files in the configured capture directory. It assumes the
+// write lock is being held.
+func (replay *pcdCamera) initializeLocal(cfg *Config) error {
+	keyring, err := cfg.Encryption.Keyring()
+	if err != nil {
+		return err
+	}
+	opts := data.ReplayOptions{Loop: cfg.Loop, PlaybackRate: cfg.PlaybackRate, Keyring: keyring}
+	if cfg.Interval.Start != "" {
+		if opts.Start, err = time.Parse(timeFormat, cfg.Interval.Start); err != nil {
+			return errors.New("invalid time format for start time, missed during config validation")
+		}
+	}
+	if cfg.Interval.End != "" {
+		if opts.End, err = time.Parse(timeFormat, cfg.Interval.End); err != nil {
+			return errors.New("invalid time format for end time, missed during config validation")
+		}
+	}
+
+	replay.local, err = data.NewCaptureReplayer(cfg.CaptureDir, cfg.Source, nextPointCloudMethod, opts)
+	return err
+}
+
+// getDataFromCaptureDir retrieves the next point cloud from the capture directory. It assumes the write lock is
+// being held.
+func (replay *pcdCamera) getDataFromCaptureDir(ctx context.Context) (pointcloud.PointCloud, error) {
+	reading, err := replay.local.Next(ctx)
+	if err != nil {
+		if errors.Is(err, data.ErrEndOfCapture) {
+			return nil, ErrEndOfDataset
+		}
+		return nil, err
+	}
+
+	pc, err := pointcloud.ReadPCD(bytes.NewReader(reading.GetBinary()), "")
+	if err != nil {
+		return nil, err
+	}
+	if err := contextutils.AddTimestampsToGRPCHeader(ctx, reading.GetMetadata().GetTimeRequested(), reading.GetMetadata().GetTimeReceived()); err != nil {
+		return nil, err
+	}
+	return pc, nil
+}
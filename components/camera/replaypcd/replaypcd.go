@@ -13,11 +13,10 @@ import (
 	datapb "go.viam.com/api/app/data/v1"
 	goutils "go.viam.com/utils"
 	"go.viam.com/utils/rpc"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/gostream"
 	"go.viam.com/rdk/internal/cloud"
 	"go.viam.com/rdk/logging"
@@ -58,6 +57,17 @@ type Config struct {
 	BatchSize      *uint64      `json:"batch_size,omitempty"`
 	APIKey         string       `json:"api_key,omitempty"`
 	APIKeyID       string       `json:"api_key_id,omitempty"`
+
+	// CaptureDir, if set, replays the capture files written by data capture to this local directory instead of data
+	// from the cloud.
+	CaptureDir string `json:"capture_dir,omitempty"`
+	// Loop restarts from the beginning of the data in CaptureDir once it has all been replayed.
+	Loop bool `json:"loop,omitempty"`
+	// PlaybackRate, if set, replays the data in CaptureDir at this multiple of the speed it was captured at, returning
+	// the latest point cloud at each call. Otherwise each call returns the next point cloud.
+	PlaybackRate float64 `json:"playback_rate,omitempty"`
+	// Encryption holds the keys the capture files in CaptureDir were encrypted with, if data capture encrypts them.
+	Encryption *data.EncryptionConfig `json:"encryption,omitempty"`
 }
 
 // TimeInterval holds the start and end time used to filter data.
@@ -82,22 +92,35 @@ func (cfg *Config) Validate(path string) ([]string, []string, error) {
 		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "source")
 	}
 
-	if cfg.RobotID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "robot_id")
-	}
+	// Data read from a local capture directory needs no cloud connection.
+	if cfg.CaptureDir == "" {
+		if cfg.RobotID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "robot_id")
+		}
 
-	if cfg.LocationID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "location_id")
-	}
+		if cfg.LocationID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "location_id")
+		}
 
-	if cfg.OrganizationID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "organization_id")
+		if cfg.OrganizationID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "organization_id")
+		}
+		if cfg.APIKey == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key")
+		}
+		if cfg.APIKeyID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key_id")
+		}
 	}
-	if cfg.APIKey == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key")
+
+	if cfg.Encryption != nil {
+		if err := cfg.Encryption.Validate(); err != nil {
+			return nil, nil, resource.NewConfigValidationError(path, err)
+		}
 	}
-	if cfg.APIKeyID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key_id")
+
+	if cfg.PlaybackRate < 0 {
+		return nil, nil, errors.New("playback_rate must not be negative")
 	}
 
 	var err error
@@ -125,6 +148,9 @@ func (cfg *Config) Validate(path string) ([]string, []string, error) {
 		return nil, nil, errors.Errorf("batch_size must be between 1 and %d", maxCacheSize)
 	}
 
+	if cfg.CaptureDir != "" {
+		return nil, nil, nil
+	}
 	return []string{cloud.InternalServiceName.String()}, nil, nil
 }
 
@@ -145,6 +171,8 @@ type pcdCamera struct {
 	filter   *datapb.Filter
 
 	cache []*cacheEntry
+	// local replays point clouds from a local capture directory, if one is configured.
+	local *data.CaptureReplayer
 
 	mu     sync.RWMutex
 	closed bool
@@ -178,6 +206,10 @@ func (replay *pcdCamera) NextPointCloud(ctx context.Context, extra map[string]in
 		return nil, errors.New("session closed")
 	}
 
+	if replay.local != nil {
+		return replay.getDataFromCaptureDir(ctx)
+	}
+
 	// Retrieve next cached data and remove from cache, if no data remains in the cache, download a
 	// new batch
 	if len(replay.cache) != 0 {
@@ -212,7 +244,7 @@ func (replay *pcdCamera) NextPointCloud(ctx context.Context, extra map[string]in
 		if err != nil {
 			return nil, err
 		}
-		if err := addGRPCMetadata(ctx,
+		if err := contextutils.AddTimestampsToGRPCHeader(ctx,
 			resp.GetData()[0].GetMetadata().GetTimeRequested(),
 			resp.GetData()[0].GetMetadata().GetTimeReceived()); err != nil {
 			return nil, err
@@ -302,32 +334,13 @@ func (replay *pcdCamera) getDataFromCache(ctx context.Context) (pointcloud.Point
 		return nil, errors.Wrap(data.err, "cache data contained an error")
 	}
 
-	if err := addGRPCMetadata(ctx, data.timeRequested, data.timeReceived); err != nil {
+	if err := contextutils.AddTimestampsToGRPCHeader(ctx, data.timeRequested, data.timeReceived); err != nil {
 		return nil, err
 	}
 
 	return data.pc, nil
 }
 
-// addGRPCMetadata adds timestamps from the data response to the gRPC response header if one is
-// found in the context.
-func addGRPCMetadata(ctx context.Context, timeRequested, timeReceived *timestamppb.Timestamp) error {
-	if stream := grpc.ServerTransportStreamFromContext(ctx); stream != nil {
-		var grpcMetadata metadata.MD = make(map[string][]string)
-		if timeRequested != nil {
-			grpcMetadata.Set(contextutils.TimeRequestedMetadataKey, timeRequested.AsTime().Format(time.RFC3339Nano))
-		}
-		if timeReceived != nil {
-			grpcMetadata.Set(contextutils.TimeReceivedMetadataKey, timeReceived.AsTime().Format(time.RFC3339Nano))
-		}
-		if err := grpc.SetHeader(ctx, grpcMetadata); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 // Images is a part of the camera interface but is not implemented for replay.
 func (replay *pcdCamera) Images(
 	ctx context.Context,
@@ -379,6 +392,14 @@ func (replay *pcdCamera) reconfigure(ctx context.Context, deps resource.Dependen
 	if err != nil {
 		return err
 	}
+	if replayCamConfig.CaptureDir != "" {
+		replay.closeCloudConnection(ctx)
+		replay.cloudConnSvc = nil
+		replay.cloudConn = nil
+		return replay.initializeLocal(replayCamConfig)
+	}
+	replay.local = nil
+
 	replay.APIKey = replayCamConfig.APIKey
 	replay.APIKeyID = replayCamConfig.APIKeyID
 
@@ -1,16 +1,25 @@
 package replaypcd
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
+	datasyncpb "go.viam.com/api/app/datasync/v1"
 	"go.viam.com/test"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/internal/cloud"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/pointcloud"
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/testutils"
 	"go.viam.com/rdk/utils/contextutils"
@@ -633,6 +642,24 @@ func TestReplayPCDConfigValidation(t *testing.T) {
 			},
 			expectedErr: errors.New("batch_size must be between 1 and 100"),
 		},
+		{
+			description: "Valid config with a capture directory and no cloud credentials",
+			cfg: &Config{
+				Source:       validSource,
+				CaptureDir:   "/tmp/capture",
+				Loop:         true,
+				PlaybackRate: 0.5,
+			},
+		},
+		{
+			description: "Invalid config with a negative playback rate",
+			cfg: &Config{
+				Source:       validSource,
+				CaptureDir:   "/tmp/capture",
+				PlaybackRate: -1,
+			},
+			expectedErr: errors.New("playback_rate must not be negative"),
+		},
 	}
 
 	for _, tt := range cases {
@@ -811,3 +838,95 @@ func TestReplayPCDReconfigure(t *testing.T) {
 
 	test.That(t, serverClose(), test.ShouldBeNil)
 }
+
+func TestReplayPCDCaptureDir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	md, _ := data.BuildCaptureMetadata(camera.API, validSource, "NextPointCloud", nil, nil, nil)
+	f, err := data.NewCaptureFile(dir, md)
+	test.That(t, err, test.ShouldBeNil)
+	start := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		pc := pointcloud.NewBasicEmpty()
+		for j := 0; j <= i; j++ {
+			test.That(t, pc.Set(pointcloud.NewVector(float64(j), 0, 0), nil), test.ShouldBeNil)
+		}
+		var buf bytes.Buffer
+		test.That(t, pointcloud.ToPCD(pc, &buf, pointcloud.PCDBinary), test.ShouldBeNil)
+		ts := timestamppb.New(start.Add(time.Duration(i) * time.Second))
+		test.That(t, f.WriteNext(&datasyncpb.SensorData{
+			Metadata: &datasyncpb.SensorMetadata{TimeRequested: ts, TimeReceived: ts},
+			Data:     &datasyncpb.SensorData_Binary{Binary: buf.Bytes()},
+		}), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	cfg := &Config{Source: validSource, CaptureDir: dir, Interval: TimeInterval{Start: "2000-01-01T12:00:01Z"}}
+	deps, _, err := cfg.Validate("")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldBeEmpty)
+	replayCamera, err := newPCDCamera(ctx, nil, resource.Config{ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+
+	for _, expected := range []int{2, 3} {
+		pc, err := replayCamera.NextPointCloud(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, pc.Size(), test.ShouldEqual, expected)
+	}
+	_, err = replayCamera.NextPointCloud(ctx, nil)
+	test.That(t, err, test.ShouldBeError, ErrEndOfDataset)
+
+	// Reconfiguring to loop starts over from the beginning.
+	cfg.Loop = true
+	test.That(t, replayCamera.(*pcdCamera).reconfigure(ctx, nil, resource.Config{ConvertedAttributes: cfg}), test.ShouldBeNil)
+	for _, expected := range []int{2, 3, 2} {
+		pc, err := replayCamera.NextPointCloud(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, pc.Size(), test.ShouldEqual, expected)
+	}
+	test.That(t, replayCamera.Close(ctx), test.ShouldBeNil)
+}
+
+func TestReplayPCDCaptureDirEncrypted(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{7}, 32)
+	keyring, err := data.NewKeyring("k1", map[string][]byte{"k1": key})
+	test.That(t, err, test.ShouldBeNil)
+	md, _ := data.BuildCaptureMetadata(camera.API, validSource, "NextPointCloud", nil, nil, nil)
+	f, err := data.NewCaptureFileWithKeyring(dir, md, keyring)
+	test.That(t, err, test.ShouldBeNil)
+	pc := pointcloud.NewBasicEmpty()
+	test.That(t, pc.Set(pointcloud.NewVector(1, 2, 3), nil), test.ShouldBeNil)
+	var buf bytes.Buffer
+	test.That(t, pointcloud.ToPCD(pc, &buf, pointcloud.PCDBinary), test.ShouldBeNil)
+	ts := timestamppb.New(time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC))
+	test.That(t, f.WriteNext(&datasyncpb.SensorData{
+		Metadata: &datasyncpb.SensorMetadata{TimeRequested: ts, TimeReceived: ts},
+		Data:     &datasyncpb.SensorData_Binary{Binary: buf.Bytes()},
+	}), test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	// Without the key the capture cannot be read.
+	cfg := &Config{Source: validSource, CaptureDir: dir}
+	_, err = newPCDCamera(ctx, nil, resource.Config{ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, errors.Is(err, data.ErrUnknownEncryptionKey), test.ShouldBeTrue)
+
+	cfg.Encryption = &data.EncryptionConfig{
+		Keys: []data.EncryptionKeyConfig{{ID: "k1", Key: base64.StdEncoding.EncodeToString(key)}},
+	}
+	_, _, err = cfg.Validate("")
+	test.That(t, err, test.ShouldBeNil)
+	replayCamera, err := newPCDCamera(ctx, nil, resource.Config{ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	read, err := replayCamera.NextPointCloud(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read.Size(), test.ShouldEqual, 1)
+	test.That(t, replayCamera.Close(ctx), test.ShouldBeNil)
+
+	// Keys that are not valid are caught by validation.
+	cfg.Encryption = &data.EncryptionConfig{ActiveKeyID: "k2", Keys: cfg.Encryption.Keys}
+	_, _, err = cfg.Validate("")
+	test.That(t, err, test.ShouldNotBeNil)
+}
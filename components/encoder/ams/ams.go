@@ -0,0 +1,158 @@
+// Package ams implements the AS5048 and AS5600 absolute magnetic rotary encoders from ams OSRAM, which measure the
+// angle of a magnet on the end of a shaft. The AS5600 is read over I2C, and the AS5048 over SPI (the AS5048A) or I2C
+// (the AS5048B).
+//
+// The sensors only know the angle within one turn, so the encoder polls them often enough to count whole turns in
+// software. Its ticks are the counts of the sensor, 4096 a turn for the AS5600 and 16384 a turn for the AS5048, so a
+// motor that uses it for feedback must have that many ticks_per_rotation.
+package ams
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+const defaultPollRateHz = 200
+
+// angleSensor reads the angle of the magnet within one turn.
+type angleSensor interface {
+	// readAngle returns the angle as a count from 0 up to the resolution of the sensor.
+	readAngle(ctx context.Context) (uint16, error)
+}
+
+// absoluteEncoder counts the turns of an angle sensor.
+type absoluteEncoder struct {
+	resource.Named
+	resource.AlwaysRebuild
+	logger logging.Logger
+
+	sensor       angleSensor
+	countsPerRev int64
+
+	mu     sync.Mutex
+	last   int64
+	total  int64
+	offset int64
+
+	workers *utils.StoppableWorkers
+}
+
+// newAbsoluteEncoder reads the sensor once, so that the position starts at the absolute angle of the shaft, and then
+// polls it at the given rate.
+func newAbsoluteEncoder(
+	ctx context.Context, name resource.Name, sensor angleSensor, countsPerRev int64, pollRateHz float64, logger logging.Logger,
+) (*absoluteEncoder, error) {
+	angle, err := sensor.readAngle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if pollRateHz == 0 {
+		pollRateHz = defaultPollRateHz
+	}
+	e := &absoluteEncoder{
+		Named:        name.AsNamed(),
+		logger:       logger,
+		sensor:       sensor,
+		countsPerRev: countsPerRev,
+		last:         int64(angle),
+		total:        int64(angle),
+	}
+	interval := time.Duration(float64(time.Second) / pollRateHz)
+	e.workers = utils.NewBackgroundStoppableWorkers(func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := e.update(ctx); err != nil && ctx.Err() == nil {
+				e.logger.CDebugw(ctx, "failed to read the angle", "error", err)
+			}
+		}
+	})
+	return e, nil
+}
+
+// update reads the sensor and adds how far it turned since the last reading to the total. The shaft is assumed to
+// have turned less than half a turn in between, so the encoder must be polled at least twice a turn.
+func (e *absoluteEncoder) update(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	angle, err := e.sensor.readAngle(ctx)
+	if err != nil {
+		return err
+	}
+	delta := int64(angle) - e.last
+	switch {
+	case delta > e.countsPerRev/2:
+		delta -= e.countsPerRev
+	case delta < -e.countsPerRev/2:
+		delta += e.countsPerRev
+	}
+	e.total += delta
+	e.last = int64(angle)
+	return nil
+}
+
+// Position returns the position since the zero in degrees, which may be more than one turn, or in ticks if asked for.
+func (e *absoluteEncoder) Position(
+	ctx context.Context, positionType encoder.PositionType, extra map[string]interface{},
+) (float64, encoder.PositionType, error) {
+	if err := e.update(ctx); err != nil {
+		return 0, encoder.PositionTypeUnspecified, err
+	}
+	e.mu.Lock()
+	ticks := e.total - e.offset
+	e.mu.Unlock()
+	if positionType == encoder.PositionTypeTicks {
+		return float64(ticks), encoder.PositionTypeTicks, nil
+	}
+	return float64(ticks) * 360 / float64(e.countsPerRev), encoder.PositionTypeDegrees, nil
+}
+
+// ResetPosition makes the current position the zero.
+func (e *absoluteEncoder) ResetPosition(ctx context.Context, extra map[string]interface{}) error {
+	if err := e.update(ctx); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.offset = e.total
+	return nil
+}
+
+// Properties returns that the encoder reports both ticks and degrees.
+func (e *absoluteEncoder) Properties(ctx context.Context, extra map[string]interface{}) (encoder.Properties, error) {
+	return encoder.Properties{
+		TicksCountSupported:   true,
+		AngleDegreesSupported: true,
+	}, nil
+}
+
+// DoCommand is not implemented.
+func (e *absoluteEncoder) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	return nil, resource.ErrDoUnimplemented
+}
+
+// Close stops polling the sensor.
+func (e *absoluteEncoder) Close(ctx context.Context) error {
+	e.workers.Stop()
+	return nil
+}
+
+func validatePollRate(path string, pollRateHz float64) error {
+	if pollRateHz < 0 {
+		return resource.NewConfigValidationError(path, errors.New("poll_rate_hz cannot be negative"))
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package ams
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"sync"
+	"testing"
+
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+// magnet is the angle of the magnet over the sensor, in counts of the sensor.
+type magnet struct {
+	mu           sync.Mutex
+	countsPerRev int
+	counts       int
+}
+
+func (m *magnet) turn(counts int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts = ((m.counts+counts)%m.countsPerRev + m.countsPerRev) % m.countsPerRev
+}
+
+func (m *magnet) angle() uint16 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return uint16(m.counts)
+}
+
+// i2cBus answers reads of the angle registers at register with the angle encoded as the sensor does.
+func (m *magnet) i2cBus(addr, register byte, encode func(angle uint16) []byte) buses.I2C {
+	return &inject.I2C{OpenHandleFunc: func(a byte) (buses.I2CHandle, error) {
+		if a != addr {
+			return nil, errors.New("no device at address")
+		}
+		return &inject.I2CHandle{
+			ReadBlockDataFunc: func(ctx context.Context, r byte, numBytes uint8) ([]byte, error) {
+				if r != register || numBytes != 2 {
+					return nil, errors.New("unexpected read")
+				}
+				return encode(m.angle()), nil
+			},
+			CloseFunc: func() error { return nil },
+		}, nil
+	}}
+}
+
+// spiBus emulates an AS5048A, which answers each command in the next frame.
+type spiBus struct {
+	magnet     *magnet
+	chipSelect string
+
+	mu        sync.Mutex
+	response  uint16
+	errFlag   bool
+	badParity bool
+}
+
+func (b *spiBus) OpenHandle() (buses.SPIHandle, error) {
+	b.mu.Lock()
+	return &spiHandle{bus: b}, nil
+}
+
+func (b *spiBus) Close(ctx context.Context) error {
+	return nil
+}
+
+type spiHandle struct {
+	bus *spiBus
+}
+
+func (h *spiHandle) Xfer(ctx context.Context, baud uint, chipSelect string, mode uint, tx []byte) ([]byte, error) {
+	if chipSelect != h.bus.chipSelect || mode != 1 || len(tx) != 2 {
+		return nil, errors.New("unexpected transfer")
+	}
+	frame := h.bus.response
+	if tx[0] == 0xFF && tx[1] == 0xFF {
+		h.bus.response = h.bus.magnet.angle()
+		if h.bus.errFlag {
+			h.bus.response |= as5048SPIErrorFlag
+		}
+		if bits.OnesCount16(h.bus.response)%2 != 0 != h.bus.badParity {
+			h.bus.response |= 1 << 15
+		}
+	}
+	return []byte{byte(frame >> 8), byte(frame)}, nil
+}
+
+func (h *spiHandle) Close() error {
+	h.bus.mu.Unlock()
+	return nil
+}
+
+func position(ctx context.Context, t *testing.T, enc encoder.Encoder, positionType encoder.PositionType) float64 {
+	t.Helper()
+	pos, gotType, err := enc.Position(ctx, positionType, nil)
+	test.That(t, err, test.ShouldBeNil)
+	if positionType == encoder.PositionTypeTicks {
+		test.That(t, gotType, test.ShouldEqual, encoder.PositionTypeTicks)
+	} else {
+		test.That(t, gotType, test.ShouldEqual, encoder.PositionTypeDegrees)
+	}
+	return pos
+}
+
+func TestAS5600(t *testing.T) {
+	ctx := context.Background()
+	m := &magnet{countsPerRev: 4096, counts: 3072}
+	bus := m.i2cBus(as5600DefaultI2CAddr, as5600RawAngle, func(angle uint16) []byte {
+		// The top bits of the register are not part of the angle.
+		return []byte{byte(angle>>8) | 0xF0, byte(angle)}
+	})
+	enc, err := newAS5600(ctx, encoder.Named("enc"), bus, &AS5600Config{I2CBus: "1"}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, enc.Close(ctx), test.ShouldBeNil)
+	}()
+
+	props, err := enc.Properties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.TicksCountSupported, test.ShouldBeTrue)
+	test.That(t, props.AngleDegreesSupported, test.ShouldBeTrue)
+
+	// The position starts at the absolute angle.
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeUnspecified), test.ShouldEqual, 270)
+
+	// Turning forwards across the wrap around keeps counting.
+	for i := 0; i < 10; i++ {
+		m.turn(512)
+		position(ctx, t, enc, encoder.PositionTypeDegrees)
+	}
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeDegrees), test.ShouldEqual, 270+450)
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeTicks), test.ShouldEqual, 3072+5120)
+
+	test.That(t, enc.ResetPosition(ctx, nil), test.ShouldBeNil)
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeDegrees), test.ShouldEqual, 0)
+
+	// And so does turning backwards.
+	for i := 0; i < 20; i++ {
+		m.turn(-1024)
+		position(ctx, t, enc, encoder.PositionTypeTicks)
+	}
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeDegrees), test.ShouldEqual, -1800)
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeTicks), test.ShouldEqual, -20480)
+}
+
+func TestAS5048I2C(t *testing.T) {
+	ctx := context.Background()
+	m := &magnet{countsPerRev: 16384, counts: 8192 + 5}
+	bus := m.i2cBus(0x41, as5048I2CAngle, func(angle uint16) []byte {
+		return []byte{byte(angle >> 6), byte(angle & 0x3F)}
+	})
+	enc, err := newAS5048I2C(ctx, encoder.Named("enc"), bus, &AS5048Config{I2CBus: "1", I2CAddr: 0x41}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, enc.Close(ctx), test.ShouldBeNil)
+	}()
+
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeTicks), test.ShouldEqual, 8197)
+	m.turn(8000)
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeTicks), test.ShouldEqual, 8197+8000)
+	m.turn(8000)
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeTicks), test.ShouldEqual, 8197+16000)
+}
+
+func TestAS5048SPI(t *testing.T) {
+	ctx := context.Background()
+	m := &magnet{countsPerRev: 16384, counts: 4096}
+	bus := &spiBus{magnet: m, chipSelect: "0"}
+	enc, err := newAS5048SPI(ctx, encoder.Named("enc"), bus, &AS5048Config{SPIBus: "0", ChipSelect: "0"}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, enc.Close(ctx), test.ShouldBeNil)
+	}()
+
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeDegrees), test.ShouldEqual, 90)
+	m.turn(-8000)
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeTicks), test.ShouldEqual, 4096-8000)
+
+	bus.mu.Lock()
+	bus.errFlag = true
+	bus.mu.Unlock()
+	_, _, err = enc.Position(ctx, encoder.PositionTypeDegrees, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "flagged an error")
+
+	bus.mu.Lock()
+	bus.errFlag = false
+	bus.badParity = true
+	bus.mu.Unlock()
+	_, _, err = enc.Position(ctx, encoder.PositionTypeDegrees, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "parity")
+
+	// The position is still right once the readings recover.
+	bus.mu.Lock()
+	bus.badParity = false
+	bus.mu.Unlock()
+	test.That(t, position(ctx, t, enc, encoder.PositionTypeTicks), test.ShouldEqual, 4096-8000)
+}
+
+func TestValidate(t *testing.T) {
+	as5600 := AS5600Config{I2CBus: "1"}
+	_, _, err := as5600.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	as5600.I2CBus = ""
+	_, _, err = as5600.Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "i2c_bus"))
+
+	for _, conf := range []AS5048Config{
+		{SPIBus: "0", ChipSelect: "1"},
+		{I2CBus: "1", I2CAddr: 0x43},
+	} {
+		_, _, err := conf.Validate("path")
+		test.That(t, err, test.ShouldBeNil)
+	}
+	for _, conf := range []AS5048Config{
+		{},
+		{SPIBus: "0"},
+		{SPIBus: "0", ChipSelect: "1", I2CBus: "1"},
+		{I2CBus: "1", I2CAddr: 0x100},
+		{I2CBus: "1", PollRateHz: -1},
+	} {
+		_, _, err := conf.Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+	}
+}
@@ -0,0 +1,149 @@
+package ams
+
+import (
+	"context"
+	"math/bits"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+var modelAS5048 = resource.DefaultModelFamily.WithModel("as5048")
+
+const (
+	as5048CountsPerRev   = 1 << 14
+	as5048DefaultI2CAddr = 0x40
+	as5048I2CAngle       = 0xFE
+
+	// The AS5048A answers each SPI command in the next frame, so the angle is read by sending the command twice.
+	as5048SPIReadAngle = 0xFFFF
+	as5048SPIErrorFlag = 1 << 14
+	as5048SPIBaud      = 1000000
+	as5048SPIMode      = 1
+)
+
+// AS5048Config is the config for an AS5048A on an SPI bus, or an AS5048B on an I2C bus.
+type AS5048Config struct {
+	SPIBus     string  `json:"spi_bus,omitempty"`
+	ChipSelect string  `json:"chip_select,omitempty"`
+	I2CBus     string  `json:"i2c_bus,omitempty"`
+	I2CAddr    int     `json:"i2c_addr,omitempty"`
+	PollRateHz float64 `json:"poll_rate_hz,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *AS5048Config) Validate(path string) ([]string, []string, error) {
+	switch {
+	case cfg.SPIBus != "" && cfg.I2CBus != "":
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("only one of spi_bus and i2c_bus can be set"))
+	case cfg.SPIBus != "":
+		if cfg.ChipSelect == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "chip_select")
+		}
+	case cfg.I2CBus != "":
+		if cfg.I2CAddr < 0 || cfg.I2CAddr > 0x7F {
+			return nil, nil, resource.NewConfigValidationError(path, errors.New("i2c_addr must be a 7 bit address"))
+		}
+	default:
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("one of spi_bus and i2c_bus must be set"))
+	}
+	if err := validatePollRate(path, cfg.PollRateHz); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(
+		encoder.API,
+		modelAS5048,
+		resource.Registration[encoder.Encoder, *AS5048Config]{
+			Constructor: func(
+				ctx context.Context, _ resource.Dependencies, conf resource.Config, logger logging.Logger,
+			) (encoder.Encoder, error) {
+				newConf, err := resource.NativeConfig[*AS5048Config](conf)
+				if err != nil {
+					return nil, err
+				}
+				if newConf.SPIBus != "" {
+					bus, err := openSPIBus(newConf.SPIBus)
+					if err != nil {
+						return nil, err
+					}
+					return newAS5048SPI(ctx, conf.ResourceName(), bus, newConf, logger)
+				}
+				bus, err := openI2CBus(newConf.I2CBus)
+				if err != nil {
+					return nil, err
+				}
+				return newAS5048I2C(ctx, conf.ResourceName(), bus, newConf, logger)
+			},
+		})
+}
+
+func newAS5048SPI(
+	ctx context.Context, name resource.Name, bus buses.SPI, conf *AS5048Config, logger logging.Logger,
+) (encoder.Encoder, error) {
+	sensor := &as5048SPI{bus: bus, chipSelect: conf.ChipSelect}
+	return newAbsoluteEncoder(ctx, name, sensor, as5048CountsPerRev, conf.PollRateHz, logger)
+}
+
+func newAS5048I2C(
+	ctx context.Context, name resource.Name, bus buses.I2C, conf *AS5048Config, logger logging.Logger,
+) (encoder.Encoder, error) {
+	addr := conf.I2CAddr
+	if addr == 0 {
+		addr = as5048DefaultI2CAddr
+	}
+	sensor := &i2cAngle{
+		bus:      bus,
+		addr:     byte(addr),
+		register: as5048I2CAngle,
+		decode: func(high, low byte) uint16 {
+			// The first register holds the top 8 bits of the angle, and the second the bottom 6.
+			return uint16(high)<<6 | uint16(low&0x3F)
+		},
+	}
+	return newAbsoluteEncoder(ctx, name, sensor, as5048CountsPerRev, conf.PollRateHz, logger)
+}
+
+// as5048SPI reads the angle of an AS5048A.
+type as5048SPI struct {
+	bus        buses.SPI
+	chipSelect string
+}
+
+func (a *as5048SPI) readAngle(ctx context.Context) (angle uint16, err error) {
+	handle, err := a.bus.OpenHandle()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = multierr.Combine(err, handle.Close())
+	}()
+	command := []byte{as5048SPIReadAngle >> 8, as5048SPIReadAngle & 0xFF}
+	if _, err := handle.Xfer(ctx, as5048SPIBaud, a.chipSelect, as5048SPIMode, command); err != nil {
+		return 0, err
+	}
+	rx, err := handle.Xfer(ctx, as5048SPIBaud, a.chipSelect, as5048SPIMode, command)
+	if err != nil {
+		return 0, err
+	}
+	if len(rx) != 2 {
+		return 0, errors.Errorf("expected 2 bytes from the as5048, got %d", len(rx))
+	}
+	frame := uint16(rx[0])<<8 | uint16(rx[1])
+	// The top bit makes the number of set bits in the frame even.
+	if bits.OnesCount16(frame)%2 != 0 {
+		return 0, errors.New("parity error in the response of the as5048")
+	}
+	if frame&as5048SPIErrorFlag != 0 {
+		return 0, errors.New("the as5048 flagged an error in the previous command")
+	}
+	return frame & (as5048CountsPerRev - 1), nil
+}
@@ -0,0 +1,80 @@
+package ams
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+	"go.viam.com/rdk/components/encoder"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+var modelAS5600 = resource.DefaultModelFamily.WithModel("as5600")
+
+const (
+	as5600CountsPerRev   = 1 << 12
+	as5600DefaultI2CAddr = 0x36
+	as5600RawAngle       = 0x0C
+)
+
+// AS5600Config is the config for an AS5600 on an I2C bus.
+type AS5600Config struct {
+	I2CBus     string  `json:"i2c_bus"`
+	I2CAddr    int     `json:"i2c_addr,omitempty"`
+	PollRateHz float64 `json:"poll_rate_hz,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *AS5600Config) Validate(path string) ([]string, []string, error) {
+	if cfg.I2CBus == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "i2c_bus")
+	}
+	if cfg.I2CAddr < 0 || cfg.I2CAddr > 0x7F {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("i2c_addr must be a 7 bit address"))
+	}
+	if err := validatePollRate(path, cfg.PollRateHz); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(
+		encoder.API,
+		modelAS5600,
+		resource.Registration[encoder.Encoder, *AS5600Config]{
+			Constructor: func(
+				ctx context.Context, _ resource.Dependencies, conf resource.Config, logger logging.Logger,
+			) (encoder.Encoder, error) {
+				newConf, err := resource.NativeConfig[*AS5600Config](conf)
+				if err != nil {
+					return nil, err
+				}
+				bus, err := openI2CBus(newConf.I2CBus)
+				if err != nil {
+					return nil, err
+				}
+				return newAS5600(ctx, conf.ResourceName(), bus, newConf, logger)
+			},
+		})
+}
+
+func newAS5600(
+	ctx context.Context, name resource.Name, bus buses.I2C, conf *AS5600Config, logger logging.Logger,
+) (encoder.Encoder, error) {
+	addr := conf.I2CAddr
+	if addr == 0 {
+		addr = as5600DefaultI2CAddr
+	}
+	sensor := &i2cAngle{
+		bus:      bus,
+		addr:     byte(addr),
+		register: as5600RawAngle,
+		decode: func(high, low byte) uint16 {
+			return (uint16(high)<<8 | uint16(low)) & (as5600CountsPerRev - 1)
+		},
+	}
+	return newAbsoluteEncoder(ctx, name, sensor, as5600CountsPerRev, conf.PollRateHz, logger)
+}
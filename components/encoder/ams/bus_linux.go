@@ -0,0 +1,13 @@
+//go:build linux
+
+package ams
+
+import "go.viam.com/rdk/components/board/genericlinux/buses"
+
+func openI2CBus(name string) (buses.I2C, error) {
+	return buses.NewI2cBus(name)
+}
+
+func openSPIBus(name string) (buses.SPI, error) {
+	return buses.NewSpiBus(name), nil
+}
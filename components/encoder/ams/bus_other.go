@@ -0,0 +1,19 @@
+//go:build !linux
+
+package ams
+
+import (
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+)
+
+// openI2CBus is only implemented on linux, where the sensors are connected to the buses of a board.
+func openI2CBus(name string) (buses.I2C, error) {
+	return nil, errors.New("i2c buses are only supported on linux")
+}
+
+// openSPIBus is only implemented on linux, where the sensors are connected to the buses of a board.
+func openSPIBus(name string) (buses.SPI, error) {
+	return nil, errors.New("spi buses are only supported on linux")
+}
@@ -0,0 +1,37 @@
+package ams
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+)
+
+// i2cAngle reads the two angle registers of a sensor on an I2C bus.
+type i2cAngle struct {
+	bus      buses.I2C
+	addr     byte
+	register byte
+	// decode turns the two registers into the angle.
+	decode func(high, low byte) uint16
+}
+
+func (a *i2cAngle) readAngle(ctx context.Context) (angle uint16, err error) {
+	handle, err := a.bus.OpenHandle(a.addr)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = multierr.Combine(err, handle.Close())
+	}()
+	data, err := handle.ReadBlockData(ctx, a.register, 2)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 2 {
+		return 0, errors.Errorf("expected 2 bytes from register %#02x, got %d", a.register, len(data))
+	}
+	return a.decode(data[0], data[1]), nil
+}
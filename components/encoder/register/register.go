@@ -3,6 +3,7 @@ package register
 
 import (
 	// Load all encoders.
+	_ "go.viam.com/rdk/components/encoder/ams"
 	_ "go.viam.com/rdk/components/encoder/incremental"
 	_ "go.viam.com/rdk/components/encoder/single"
 )
@@ -0,0 +1,536 @@
+// Package motorgantry implements a gantry whose axes are each driven by a motor that reports its position, and homed
+// against limit switches wired to the GPIO pins of a board.
+package motorgantry
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/components/board"
+	"go.viam.com/rdk/components/gantry"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+)
+
+var model = resource.DefaultModelFamily.WithModel("motor-gantry")
+
+const (
+	defaultSpeedMmPerSec = 50
+	limitPollInterval    = 10 * time.Millisecond
+	// limitToleranceMm is how close to its goal an axis can press a limit switch without being stopped, since the
+	// switch at the zero end of an axis is pressed at the zero position.
+	limitToleranceMm = 2
+)
+
+var defaultAxes = []r3.Vector{{X: 1}, {Y: 1}, {Z: 1}}
+
+var errNotHomed = errors.New("gantry is not homed, call Home first")
+
+// AxisConfig describes one axis of the gantry.
+type AxisConfig struct {
+	Motor string `json:"motor"`
+	// Board has the GPIO pins of the limit switches.
+	Board string `json:"board,omitempty"`
+	// LimitPins are the pins of the limit switches, the first at the zero end of the axis and the second, if any, at
+	// the far end. Without limit switches, the zero position of the motor is the zero of the axis.
+	LimitPins []string `json:"limit_pins,omitempty"`
+	// LimitPinEnabledHigh is whether the pins read high when a switch is pressed, which is the default.
+	LimitPinEnabledHigh *bool   `json:"limit_pin_enabled_high,omitempty"`
+	LengthMm            float64 `json:"length_mm"`
+	MmPerRevolution     float64 `json:"mm_per_rev"`
+	// SpeedMmPerSec is the speed of homing and of moves that do not ask for one.
+	SpeedMmPerSec float64 `json:"speed_mm_per_sec,omitempty"`
+	// Axis is the direction of the axis in the frame of the gantry. The first three axes default to x, y and z.
+	Axis *r3.Vector `json:"axis,omitempty"`
+}
+
+// Config is used for converting motor-gantry attributes.
+type Config struct {
+	Axes []AxisConfig `json:"axes"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if len(cfg.Axes) == 0 {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "axes")
+	}
+	var deps []string
+	seen := map[string]bool{}
+	for i, axis := range cfg.Axes {
+		axisPath := fmt.Sprintf("%s.axes.%d", path, i)
+		if axis.Motor == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(axisPath, "motor")
+		}
+		if seen[axis.Motor] {
+			return nil, nil, resource.NewConfigValidationError(axisPath, errors.Errorf("motor %q drives more than one axis", axis.Motor))
+		}
+		seen[axis.Motor] = true
+		deps = append(deps, axis.Motor)
+
+		if len(axis.LimitPins) > 2 {
+			return nil, nil, resource.NewConfigValidationError(axisPath, errors.New("an axis can have at most two limit_pins"))
+		}
+		if len(axis.LimitPins) > 0 {
+			if axis.Board == "" {
+				return nil, nil, resource.NewConfigValidationFieldRequiredError(axisPath, "board")
+			}
+			if !seen[axis.Board] {
+				seen[axis.Board] = true
+				deps = append(deps, axis.Board)
+			}
+		}
+		if axis.LengthMm <= 0 {
+			return nil, nil, resource.NewConfigValidationError(axisPath, errors.New("length_mm must be positive"))
+		}
+		if axis.MmPerRevolution <= 0 {
+			return nil, nil, resource.NewConfigValidationError(axisPath, errors.New("mm_per_rev must be positive"))
+		}
+		if axis.SpeedMmPerSec < 0 {
+			return nil, nil, resource.NewConfigValidationError(axisPath, errors.New("speed_mm_per_sec cannot be negative"))
+		}
+		if axis.Axis == nil && i >= len(defaultAxes) {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(axisPath, "axis")
+		}
+		if axis.Axis != nil && axis.Axis.Norm() == 0 {
+			return nil, nil, resource.NewConfigValidationError(axisPath, errors.New("axis cannot be the zero vector"))
+		}
+	}
+	return deps, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(gantry.API, model, resource.Registration[gantry.Gantry, *Config]{
+		Constructor: newMotorGantry,
+	})
+}
+
+type motorGantry struct {
+	resource.Named
+	resource.AlwaysRebuild
+	logger logging.Logger
+
+	axes  []*axis
+	model referenceframe.Model
+	opMgr *operation.SingleOperationManager
+}
+
+func newMotorGantry(
+	ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger,
+) (gantry.Gantry, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	g := &motorGantry{
+		Named:  conf.ResourceName().AsNamed(),
+		logger: logger,
+		opMgr:  operation.NewSingleOperationManager(),
+	}
+	frames := make([]referenceframe.Frame, 0, len(newConf.Axes))
+	for i, axisConf := range newConf.Axes {
+		a, err := newAxis(ctx, deps, axisConf)
+		if err != nil {
+			return nil, errors.Wrapf(err, "axis %d", i)
+		}
+		g.axes = append(g.axes, a)
+
+		direction := axisConf.Axis
+		if direction == nil {
+			direction = &defaultAxes[i]
+		}
+		frame, err := referenceframe.NewTranslationalFrame(axisConf.Motor, *direction,
+			referenceframe.Limit{Min: 0, Max: axisConf.LengthMm})
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, frame)
+	}
+	g.model, err = referenceframe.NewSerialModel(conf.Name, frames)
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// Position returns the position of each axis in mm from its zero.
+func (g *motorGantry) Position(ctx context.Context, extra map[string]interface{}) ([]float64, error) {
+	positions := make([]float64, len(g.axes))
+	for i, a := range g.axes {
+		position, err := a.position(ctx)
+		if err != nil {
+			return nil, err
+		}
+		positions[i] = position
+	}
+	return positions, nil
+}
+
+// Lengths returns the length of each axis in mm.
+func (g *motorGantry) Lengths(ctx context.Context, extra map[string]interface{}) ([]float64, error) {
+	lengths := make([]float64, len(g.axes))
+	for i, a := range g.axes {
+		lengths[i] = a.lengthMm
+	}
+	return lengths, nil
+}
+
+// MoveToPosition moves all of the axes at once, each at its own speed in mm/s, or at its default speed if the speeds
+// are left out or zero. It stops an axis if it unexpectedly reaches one of its limit switches.
+func (g *motorGantry) MoveToPosition(ctx context.Context, positionsMm, speedsMmPerSec []float64, extra map[string]interface{}) error {
+	if len(positionsMm) != len(g.axes) {
+		return errors.Errorf("need %d positions, got %d", len(g.axes), len(positionsMm))
+	}
+	if len(speedsMmPerSec) != 0 && len(speedsMmPerSec) != len(g.axes) {
+		return errors.Errorf("need %d speeds, got %d", len(g.axes), len(speedsMmPerSec))
+	}
+	for i, position := range positionsMm {
+		if position < 0 || position > g.axes[i].lengthMm {
+			return errors.Errorf("position %v of axis %d is out of range [0, %v]", position, i, g.axes[i].lengthMm)
+		}
+		if _, err := g.axes[i].zero(); err != nil {
+			return err
+		}
+	}
+
+	ctx, done := g.opMgr.New(ctx)
+	defer done()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(g.axes))
+	for i, a := range g.axes {
+		speed := a.speedMmPerSec
+		if len(speedsMmPerSec) != 0 && speedsMmPerSec[i] != 0 {
+			speed = math.Abs(speedsMmPerSec[i])
+		}
+		wg.Add(1)
+		goutils.PanicCapturingGo(func() {
+			defer wg.Done()
+			if err := a.moveTo(ctx, positionsMm[i], speed); err != nil {
+				errs[i] = errors.Wrapf(err, "axis %d", i)
+			}
+		})
+	}
+	wg.Wait()
+	return multierr.Combine(errs...)
+}
+
+// Home homes the axes one after the other.
+func (g *motorGantry) Home(ctx context.Context, extra map[string]interface{}) (bool, error) {
+	ctx, done := g.opMgr.New(ctx)
+	defer done()
+
+	for i, a := range g.axes {
+		if err := a.home(ctx, g.logger); err != nil {
+			return false, errors.Wrapf(err, "failed to home axis %d", i)
+		}
+	}
+	return true, nil
+}
+
+// Stop stops every motor of the gantry.
+func (g *motorGantry) Stop(ctx context.Context, extra map[string]interface{}) error {
+	g.opMgr.CancelRunning(ctx)
+	var err error
+	for _, a := range g.axes {
+		err = multierr.Combine(err, a.motor.Stop(ctx, extra))
+	}
+	return err
+}
+
+// IsMoving returns whether the gantry is moving or homing.
+func (g *motorGantry) IsMoving(ctx context.Context) (bool, error) {
+	return g.opMgr.OpRunning(), nil
+}
+
+// Kinematics returns a model with a translational frame, named after its motor, for each axis.
+func (g *motorGantry) Kinematics(ctx context.Context) (referenceframe.Model, error) {
+	return g.model, nil
+}
+
+// Geometries returns the geometries of the gantry.
+func (g *motorGantry) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	inputs, err := g.CurrentInputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gif, err := g.model.Geometries(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return gif.Geometries(), nil
+}
+
+// CurrentInputs returns the position of each axis in mm.
+func (g *motorGantry) CurrentInputs(ctx context.Context) ([]referenceframe.Input, error) {
+	return g.Position(ctx, nil)
+}
+
+// GoToInputs moves through each of the positions in turn at the default speeds.
+func (g *motorGantry) GoToInputs(ctx context.Context, inputSteps ...[]referenceframe.Input) error {
+	for _, goal := range inputSteps {
+		if err := g.MoveToPosition(ctx, goal, nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DoCommand is not implemented.
+func (g *motorGantry) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	return nil, resource.ErrDoUnimplemented
+}
+
+// Close stops the gantry.
+func (g *motorGantry) Close(ctx context.Context) error {
+	return g.Stop(ctx, nil)
+}
+
+// axis is a motor that moves a carriage between limit switches.
+type axis struct {
+	motor         motor.Motor
+	limits        []board.GPIOPin
+	limitHigh     bool
+	lengthMm      float64
+	mmPerRev      float64
+	speedMmPerSec float64
+
+	mu    sync.Mutex
+	homed bool
+	// zeroRevs is the position of the motor at the zero of the axis.
+	zeroRevs float64
+}
+
+func newAxis(ctx context.Context, deps resource.Dependencies, conf AxisConfig) (*axis, error) {
+	m, err := motor.FromProvider(deps, conf.Motor)
+	if err != nil {
+		return nil, err
+	}
+	props, err := m.Properties(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !props.PositionReporting {
+		return nil, errors.Errorf("motor %q must report its position", conf.Motor)
+	}
+	a := &axis{
+		motor:         m,
+		limitHigh:     conf.LimitPinEnabledHigh == nil || *conf.LimitPinEnabledHigh,
+		lengthMm:      conf.LengthMm,
+		mmPerRev:      conf.MmPerRevolution,
+		speedMmPerSec: conf.SpeedMmPerSec,
+		// Without limit switches, there is nothing to home against.
+		homed: len(conf.LimitPins) == 0,
+	}
+	if a.speedMmPerSec == 0 {
+		a.speedMmPerSec = defaultSpeedMmPerSec
+	}
+	if len(conf.LimitPins) > 0 {
+		b, err := board.FromProvider(deps, conf.Board)
+		if err != nil {
+			return nil, err
+		}
+		for _, pinName := range conf.LimitPins {
+			pin, err := b.GPIOPinByName(pinName)
+			if err != nil {
+				return nil, err
+			}
+			a.limits = append(a.limits, pin)
+		}
+	}
+	return a, nil
+}
+
+func (a *axis) rpm(speedMmPerSec float64) float64 {
+	return speedMmPerSec / a.mmPerRev * 60
+}
+
+func (a *axis) zero() (float64, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.homed {
+		return 0, errNotHomed
+	}
+	return a.zeroRevs, nil
+}
+
+func (a *axis) position(ctx context.Context) (float64, error) {
+	zero, err := a.zero()
+	if err != nil {
+		return 0, err
+	}
+	revs, err := a.motor.Position(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return (revs - zero) * a.mmPerRev, nil
+}
+
+func (a *axis) limitPressed(ctx context.Context, pin board.GPIOPin) (bool, error) {
+	high, err := pin.Get(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	return high == a.limitHigh, nil
+}
+
+// limitTowards returns the limit switch in the direction of travel, if there is one.
+func (a *axis) limitTowards(direction float64) board.GPIOPin {
+	switch {
+	case direction < 0 && len(a.limits) > 0:
+		return a.limits[0]
+	case direction > 0 && len(a.limits) > 1:
+		return a.limits[1]
+	default:
+		return nil
+	}
+}
+
+func (a *axis) moveTo(ctx context.Context, positionMm, speedMmPerSec float64) error {
+	zero, err := a.zero()
+	if err != nil {
+		return err
+	}
+	current, err := a.motor.Position(ctx, nil)
+	if err != nil {
+		return err
+	}
+	target := zero + positionMm/a.mmPerRev
+	if math.Abs(target-current)*a.mmPerRev < 1e-6 {
+		return nil
+	}
+	limit := a.limitTowards(target - current)
+	if limit == nil {
+		return a.motor.GoTo(ctx, a.rpm(speedMmPerSec), target, nil)
+	}
+
+	moveCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	moveErr := make(chan error, 1)
+	goutils.PanicCapturingGo(func() {
+		moveErr <- a.motor.GoTo(moveCtx, a.rpm(speedMmPerSec), target, nil)
+	})
+	ticker := time.NewTicker(limitPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-moveErr:
+			return err
+		case <-ticker.C:
+		}
+		pressed, err := a.limitPressed(ctx, limit)
+		if err == nil && !pressed {
+			continue
+		}
+		var position float64
+		if err == nil {
+			position, err = a.position(ctx)
+		}
+		if err == nil && math.Abs(position-positionMm) <= limitToleranceMm {
+			continue
+		}
+		cancel()
+		err = multierr.Combine(err, a.motor.Stop(ctx, nil))
+		<-moveErr
+		if err != nil {
+			return err
+		}
+		return errors.Errorf("hit a limit switch at %.1f mm while moving to %.1f mm", position, positionMm)
+	}
+}
+
+// seek moves the motor at the given speed until the limit switch is pressed, and returns the position of the motor.
+func (a *axis) seek(ctx context.Context, limit board.GPIOPin, rpm float64) (float64, error) {
+	start, err := a.motor.Position(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	pressed, err := a.limitPressed(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+	if pressed {
+		return start, nil
+	}
+	if err := a.motor.SetRPM(ctx, rpm, nil); err != nil {
+		return 0, err
+	}
+	stop := func(err error) error {
+		// Stop even if the context is done.
+		return multierr.Combine(err, a.motor.Stop(context.Background(), nil))
+	}
+
+	// Give up once the motor has gone twice the length of the axis without finding the switch.
+	maxRevs := 2 * a.lengthMm / a.mmPerRev
+	ticker := time.NewTicker(limitPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, stop(ctx.Err())
+		case <-ticker.C:
+		}
+		pressed, err := a.limitPressed(ctx, limit)
+		if err != nil {
+			return 0, stop(err)
+		}
+		position, err := a.motor.Position(ctx, nil)
+		if err != nil {
+			return 0, stop(err)
+		}
+		if pressed {
+			return position, stop(nil)
+		}
+		if math.Abs(position-start) > maxRevs {
+			return 0, stop(errors.Errorf("did not find a limit switch within %v mm", 2*a.lengthMm))
+		}
+	}
+}
+
+func (a *axis) home(ctx context.Context, logger logging.Logger) error {
+	if len(a.limits) == 0 {
+		return nil
+	}
+	a.mu.Lock()
+	a.homed = false
+	a.mu.Unlock()
+
+	rpm := a.rpm(a.speedMmPerSec)
+	zero, err := a.seek(ctx, a.limits[0], -rpm)
+	if err != nil {
+		return err
+	}
+	if len(a.limits) > 1 {
+		end, err := a.seek(ctx, a.limits[1], rpm)
+		if err != nil {
+			return err
+		}
+		measured := (end - zero) * a.mmPerRev
+		if measured < a.lengthMm-limitToleranceMm {
+			return errors.Errorf("the limit switches are %.1f mm apart, less than the %v mm length of the axis", measured, a.lengthMm)
+		}
+		if measured > a.lengthMm+limitToleranceMm {
+			logger.CWarnf(ctx, "the limit switches are %.1f mm apart, more than the %v mm length of the axis", measured, a.lengthMm)
+		}
+	}
+
+	a.mu.Lock()
+	a.homed = true
+	a.zeroRevs = zero
+	a.mu.Unlock()
+	if len(a.limits) > 1 {
+		return a.moveTo(ctx, 0, a.speedMmPerSec)
+	}
+	return nil
+}
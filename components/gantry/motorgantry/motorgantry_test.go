@@ -0,0 +1,250 @@
+package motorgantry
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/components/board"
+	fakeboard "go.viam.com/rdk/components/board/fake"
+	"go.viam.com/rdk/components/encoder/fake"
+	"go.viam.com/rdk/components/gantry"
+	"go.viam.com/rdk/components/motor"
+	fakemotor "go.viam.com/rdk/components/motor/fake"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/resource"
+)
+
+const (
+	testMmPerRev = 10
+	testSpeed    = 100
+)
+
+// newTestMotor returns a fake motor with an encoder that starts at the given position in revolutions.
+func newTestMotor(ctx context.Context, t *testing.T, name string, startRevs float64) *fakemotor.Motor {
+	t.Helper()
+	logger := logging.NewTestLogger(t)
+	enc, err := fake.NewEncoder(ctx, resource.Config{ConvertedAttributes: &fake.Config{UpdateRate: 1}}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, enc.(fake.Encoder).SetPosition(ctx, startRevs*100), test.ShouldBeNil)
+	return &fakemotor.Motor{
+		Named:             motor.Named(name).AsNamed(),
+		Encoder:           enc.(fake.Encoder),
+		Logger:            logger,
+		PositionReporting: true,
+		MaxRPM:            6000,
+		TicksPerRotation:  100,
+		OpMgr:             operation.NewSingleOperationManager(),
+	}
+}
+
+// simulateSwitches presses the limit switches of a motor whenever it is at or beyond their positions in revolutions.
+func simulateSwitches(t *testing.T, m motor.Motor, switches map[*fakeboard.GPIOPin]func(revs float64) bool) {
+	t.Helper()
+	workers := utils.NewBackgroundStoppableWorkers(func(ctx context.Context) {
+		for utils.SelectContextOrWait(ctx, time.Millisecond) {
+			revs, err := m.Position(ctx, nil)
+			if err != nil {
+				continue
+			}
+			for pin, pressed := range switches {
+				utils.UncheckedError(pin.Set(ctx, pressed(revs), nil))
+			}
+		}
+	})
+	t.Cleanup(workers.Stop)
+}
+
+func newTestGantry(
+	ctx context.Context, t *testing.T, deps resource.Dependencies, conf *Config,
+) gantry.Gantry {
+	t.Helper()
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	g, err := newMotorGantry(ctx, deps, resource.Config{
+		Name:                "gantry",
+		ConvertedAttributes: conf,
+	}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	t.Cleanup(func() { test.That(t, g.Close(context.Background()), test.ShouldBeNil) })
+	return g
+}
+
+func TestHomeAndMove(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b, err := fakeboard.NewBoard(ctx, resource.Config{Name: "board", ConvertedAttributes: &fakeboard.Config{}}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	pins := map[string]*fakeboard.GPIOPin{}
+	for _, name := range []string{"x0", "x1", "y0"} {
+		pin, err := b.GPIOPinByName(name)
+		test.That(t, err, test.ShouldBeNil)
+		pins[name] = pin.(*fakeboard.GPIOPin)
+	}
+
+	// The x axis has switches at -2 and 8.2 revolutions, 102 mm apart, and the y axis one switch at 1 revolution.
+	var farSwitchTenthsOfRevs atomic.Int64
+	farSwitchTenthsOfRevs.Store(82)
+	x := newTestMotor(ctx, t, "x", 3)
+	simulateSwitches(t, x, map[*fakeboard.GPIOPin]func(float64) bool{
+		pins["x0"]: func(revs float64) bool { return revs <= -2 },
+		pins["x1"]: func(revs float64) bool { return revs*10 >= float64(farSwitchTenthsOfRevs.Load()) },
+	})
+	y := newTestMotor(ctx, t, "y", 4)
+	simulateSwitches(t, y, map[*fakeboard.GPIOPin]func(float64) bool{
+		pins["y0"]: func(revs float64) bool { return revs <= 1 },
+	})
+	z := newTestMotor(ctx, t, "z", 0)
+
+	deps := resource.Dependencies{
+		board.Named("board"): b,
+		x.Name():             x,
+		y.Name():             y,
+		z.Name():             z,
+	}
+	g := newTestGantry(ctx, t, deps, &Config{Axes: []AxisConfig{
+		{Motor: "x", Board: "board", LimitPins: []string{"x0", "x1"}, LengthMm: 100, MmPerRevolution: testMmPerRev, SpeedMmPerSec: testSpeed},
+		{Motor: "y", Board: "board", LimitPins: []string{"y0"}, LengthMm: 50, MmPerRevolution: testMmPerRev, SpeedMmPerSec: testSpeed},
+		{Motor: "z", LengthMm: 20, MmPerRevolution: 2},
+	}})
+
+	lengths, err := g.Lengths(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, lengths, test.ShouldResemble, []float64{100, 50, 20})
+
+	_, err = g.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeError, errNotHomed)
+	err = g.MoveToPosition(ctx, []float64{10, 10, 10}, nil, nil)
+	test.That(t, err, test.ShouldBeError, errNotHomed)
+
+	homed, err := g.Home(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, homed, test.ShouldBeTrue)
+	positions, err := g.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, positions[0], test.ShouldAlmostEqual, 0, limitToleranceMm)
+	test.That(t, positions[1], test.ShouldAlmostEqual, 0, limitToleranceMm)
+	test.That(t, positions[2], test.ShouldEqual, 0)
+
+	test.That(t, g.MoveToPosition(ctx, []float64{60, 25, 5}, []float64{200, 0, 10}, nil), test.ShouldBeNil)
+	positions, err = g.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, positions[0], test.ShouldAlmostEqual, 60, 1e-6)
+	test.That(t, positions[1], test.ShouldAlmostEqual, 25, 1e-6)
+	test.That(t, positions[2], test.ShouldAlmostEqual, 5, 1e-6)
+	inputs, err := g.CurrentInputs(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, inputs, test.ShouldResemble, positions)
+
+	// Going back to the zero of an axis presses its switch on the way, which is not an error.
+	test.That(t, g.GoToInputs(ctx, []float64{0, 0, 0}), test.ShouldBeNil)
+	moving, err := g.IsMoving(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, moving, test.ShouldBeFalse)
+
+	err = g.MoveToPosition(ctx, []float64{101, 0, 0}, nil, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "out of range")
+	err = g.MoveToPosition(ctx, []float64{0, 0}, nil, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	t.Run("limit switch stops a move", func(t *testing.T) {
+		// The carriage slipped, so the far switch is pressed before the axis thinks it is at the end.
+		farSwitchTenthsOfRevs.Store(30)
+		err := g.MoveToPosition(ctx, []float64{90, 0, 0}, nil, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "limit switch")
+		positions, err := g.Position(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, positions[0], test.ShouldBeBetween, 45, 60)
+	})
+}
+
+func TestHomeEnabledLow(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b, err := fakeboard.NewBoard(ctx, resource.Config{Name: "board", ConvertedAttributes: &fakeboard.Config{}}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	x := newTestMotor(ctx, t, "x", 0)
+	lowHigh := false
+	g := newTestGantry(ctx, t, resource.Dependencies{board.Named("board"): b, x.Name(): x}, &Config{Axes: []AxisConfig{
+		{Motor: "x", Board: "board", LimitPins: []string{"x0"}, LimitPinEnabledHigh: &lowHigh, LengthMm: 10, MmPerRevolution: 10},
+	}})
+
+	// The switch is enabled low and the pin reads low, so the axis is already at its zero.
+	homed, err := g.Home(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, homed, test.ShouldBeTrue)
+
+	// With the pin high, the switch is never found.
+	pin, err := b.GPIOPinByName("x0")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, pin.Set(ctx, true, nil), test.ShouldBeNil)
+	_, err = g.Home(ctx, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "did not find a limit switch")
+	_, err = g.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeError, errNotHomed)
+}
+
+func TestKinematics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	x := newTestMotor(ctx, t, "x", 0)
+	y := newTestMotor(ctx, t, "y", 0)
+	g := newTestGantry(ctx, t, resource.Dependencies{x.Name(): x, y.Name(): y}, &Config{Axes: []AxisConfig{
+		{Motor: "x", LengthMm: 300, MmPerRevolution: 8},
+		{Motor: "y", LengthMm: 200, MmPerRevolution: 8, Axis: &r3.Vector{Y: -1}},
+	}})
+	m, err := g.Kinematics(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, m.Name(), test.ShouldEqual, "gantry")
+	test.That(t, m.DoF(), test.ShouldHaveLength, 2)
+	test.That(t, m.DoF()[0].Max, test.ShouldEqual, 300)
+	test.That(t, m.DoF()[1].Max, test.ShouldEqual, 200)
+	pose, err := m.Transform([]float64{100, 50})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, pose.Point(), test.ShouldResemble, r3.Vector{X: 100, Y: -50})
+}
+
+func TestValidate(t *testing.T) {
+	valid := AxisConfig{Motor: "x", Board: "board", LimitPins: []string{"1"}, LengthMm: 100, MmPerRevolution: 8}
+	deps, _, err := (&Config{Axes: []AxisConfig{valid}}).Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"x", "board"})
+
+	_, _, err = (&Config{}).Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "axes"))
+	_, _, err = (&Config{Axes: []AxisConfig{valid, valid}}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+
+	for _, modify := range []func(a *AxisConfig){
+		func(a *AxisConfig) { a.Motor = "" },
+		func(a *AxisConfig) { a.Board = "" },
+		func(a *AxisConfig) { a.LimitPins = []string{"1", "2", "3"} },
+		func(a *AxisConfig) { a.LengthMm = 0 },
+		func(a *AxisConfig) { a.MmPerRevolution = -1 },
+		func(a *AxisConfig) { a.Axis = &r3.Vector{} },
+	} {
+		axis := valid
+		modify(&axis)
+		_, _, err := (&Config{Axes: []AxisConfig{axis}}).Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+	}
+
+	axes := []AxisConfig{}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		axes = append(axes, AxisConfig{Motor: name, LengthMm: 1, MmPerRevolution: 1})
+	}
+	_, _, err = (&Config{Axes: axes}).Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path.axes.3", "axis"))
+}
@@ -4,4 +4,5 @@ package register
 import (
 	// for gantries.
 	_ "go.viam.com/rdk/components/gantry/fake"
+	_ "go.viam.com/rdk/components/gantry/motorgantry"
 )
@@ -0,0 +1,565 @@
+// Package paralleljaw implements a gripper with two fingers that a servo or a motor opens and closes together. It
+// closes until the jaws are shut or stall on something, and tells what it is holding from how far the jaws closed
+// and, with a power sensor, from how much current the servo or motor draws. Without a power sensor, a servo can only
+// tell that it is holding something if it reports the angle it actually reached rather than the one it was told to go
+// to.
+package paralleljaw
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/gripper"
+	"go.viam.com/rdk/components/motor"
+	"go.viam.com/rdk/components/powersensor"
+	"go.viam.com/rdk/components/servo"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+)
+
+var model = resource.DefaultModelFamily.WithModel("parallel-jaw")
+
+const (
+	defaultServoSpeedDegsPerSec = 90
+	defaultMotorRPM             = 10
+	defaultClosedToleranceMm    = 1
+
+	pollInterval = 20 * time.Millisecond
+	// A motor has stalled once it has moved less than a quarter of what its speed should have moved it over the
+	// stall window.
+	stallWindow   = 200 * time.Millisecond
+	stallFraction = 0.25
+	// A servo without a power sensor has settled once its angle has not changed over the stall window, or once it has
+	// had this long to get there.
+	servoSettleTimeout = 2 * time.Second
+
+	openingJoint = "opening"
+)
+
+var defaultFingerDimsMm = r3.Vector{X: 10, Y: 20, Z: 50}
+
+// Config is used for converting parallel-jaw gripper attributes. Exactly one of Servo and Motor must be set.
+type Config struct {
+	Servo string `json:"servo,omitempty"`
+	Motor string `json:"motor,omitempty"`
+	// OpenPosition and ClosedPosition are the positions of the servo in degrees, or of the motor in revolutions, with
+	// the jaws all the way open and all the way closed.
+	OpenPosition   float64 `json:"open_position"`
+	ClosedPosition float64 `json:"closed_position"`
+	// Speed is how fast the jaws close when grabbing, in degrees per second for a servo or RPM for a motor.
+	Speed float64 `json:"speed,omitempty"`
+	// HoldingPowerPct is the power, from 0 to 1, that a motor keeps squeezing with once it stalls on something.
+	// Without it, the motor stops.
+	HoldingPowerPct float64 `json:"holding_power_pct,omitempty"`
+
+	// MaxWidthMm is the gap between the fingers when the jaws are all the way open.
+	MaxWidthMm float64 `json:"max_width_mm"`
+	// ClosedToleranceMm is how wide a gap between the fingers still counts as closed on nothing.
+	ClosedToleranceMm float64 `json:"closed_tolerance_mm,omitempty"`
+	// FingerDimsMm is the size of each finger: its thickness along the x axis, along which the jaws open, its depth,
+	// and its length along the z axis, which points out of the gripper.
+	FingerDimsMm *r3.Vector `json:"finger_dims_mm,omitempty"`
+	// FingerOffsetMm is how far along the z axis the fingers start.
+	FingerOffsetMm float64 `json:"finger_offset_mm,omitempty"`
+
+	// PowerSensor measures the current drawn by the servo or motor.
+	PowerSensor string `json:"power_sensor,omitempty"`
+	// CurrentThresholdAmps is the current above which the jaws are squeezing something.
+	CurrentThresholdAmps float64 `json:"current_threshold_amps,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	var deps []string
+	switch {
+	case cfg.Servo == "" && cfg.Motor == "":
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("one of servo or motor is required"))
+	case cfg.Servo != "" && cfg.Motor != "":
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("only one of servo or motor can be set"))
+	case cfg.Servo != "":
+		deps = append(deps, cfg.Servo)
+		for _, angle := range []float64{cfg.OpenPosition, cfg.ClosedPosition} {
+			if angle < 0 || angle > 180 {
+				return nil, nil, resource.NewConfigValidationError(path, errors.New("servo positions must be from 0 to 180 degrees"))
+			}
+		}
+		if cfg.HoldingPowerPct != 0 {
+			return nil, nil, resource.NewConfigValidationError(path, errors.New("holding_power_pct is only for motors"))
+		}
+	default:
+		deps = append(deps, cfg.Motor)
+		if cfg.HoldingPowerPct < 0 || cfg.HoldingPowerPct > 1 {
+			return nil, nil, resource.NewConfigValidationError(path, errors.New("holding_power_pct must be from 0 to 1"))
+		}
+	}
+	if cfg.OpenPosition == cfg.ClosedPosition {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("open_position and closed_position must differ"))
+	}
+	if cfg.Speed < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("speed cannot be negative"))
+	}
+	if cfg.MaxWidthMm <= 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("max_width_mm must be positive"))
+	}
+	if cfg.ClosedToleranceMm < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("closed_tolerance_mm cannot be negative"))
+	}
+	if dims := cfg.FingerDimsMm; dims != nil && (dims.X <= 0 || dims.Y <= 0 || dims.Z <= 0) {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("finger_dims_mm must be positive"))
+	}
+	if cfg.PowerSensor != "" {
+		if cfg.CurrentThresholdAmps <= 0 {
+			return nil, nil, resource.NewConfigValidationError(path,
+				errors.New("current_threshold_amps must be positive to use a power_sensor"))
+		}
+		deps = append(deps, cfg.PowerSensor)
+	}
+	return deps, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(gripper.API, model, resource.Registration[gripper.Gripper, *Config]{
+		Constructor: newParallelJaw,
+	})
+}
+
+type parallelJaw struct {
+	resource.Named
+	resource.AlwaysRebuild
+	logger logging.Logger
+
+	servo   servo.Servo
+	motor   motor.Motor
+	sensor  powersensor.PowerSensor
+	conf    Config
+	model   referenceframe.Model
+	opMgr   *operation.SingleOperationManager
+	closing float64 // the sign of the direction from open to closed
+
+	mu      sync.Mutex
+	holding bool
+}
+
+func newParallelJaw(
+	ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger,
+) (gripper.Gripper, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	g := &parallelJaw{
+		Named:   conf.ResourceName().AsNamed(),
+		logger:  logger,
+		conf:    *newConf,
+		opMgr:   operation.NewSingleOperationManager(),
+		closing: math.Copysign(1, newConf.ClosedPosition-newConf.OpenPosition),
+	}
+	if g.conf.Speed == 0 {
+		g.conf.Speed = defaultMotorRPM
+		if g.conf.Servo != "" {
+			g.conf.Speed = defaultServoSpeedDegsPerSec
+		}
+	}
+	if g.conf.ClosedToleranceMm == 0 {
+		g.conf.ClosedToleranceMm = defaultClosedToleranceMm
+	}
+	if g.conf.FingerDimsMm == nil {
+		g.conf.FingerDimsMm = &defaultFingerDimsMm
+	}
+
+	if g.conf.Servo != "" {
+		if g.servo, err = servo.FromProvider(deps, g.conf.Servo); err != nil {
+			return nil, err
+		}
+	} else {
+		if g.motor, err = motor.FromProvider(deps, g.conf.Motor); err != nil {
+			return nil, err
+		}
+		props, err := g.motor.Properties(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !props.PositionReporting {
+			return nil, errors.Errorf("motor %q must report its position", g.conf.Motor)
+		}
+	}
+	if g.conf.PowerSensor != "" {
+		if g.sensor, err = powersensor.FromProvider(deps, g.conf.PowerSensor); err != nil {
+			return nil, err
+		}
+	}
+	if g.model, err = makeModel(conf.Name, g.conf); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// makeModel returns a model whose one input is the gap between the fingers in mm. Each finger follows half of it,
+// so the fingers and their geometries move apart as the jaws open. The output frame is between the finger tips.
+func makeModel(name string, conf Config) (referenceframe.Model, error) {
+	dims := *conf.FingerDimsMm
+	finger := func(side float64) referenceframe.LinkConfig {
+		// The frame of a finger is at its tip, but its geometry is placed in the frame of its joint. The inner face of
+		// each finger is on the middle of the jaws when they are closed.
+		return referenceframe.LinkConfig{
+			Translation: r3.Vector{Z: conf.FingerOffsetMm + dims.Z},
+			Geometry: &spatialmath.GeometryConfig{
+				Type:              spatialmath.BoxType,
+				X:                 dims.X,
+				Y:                 dims.Y,
+				Z:                 dims.Z,
+				TranslationOffset: r3.Vector{X: side * dims.X / 2, Z: conf.FingerOffsetMm + dims.Z/2},
+			},
+		}
+	}
+	left, right := finger(-1), finger(1)
+	left.ID, left.Parent = "left_finger", "left_joint"
+	right.ID, right.Parent = "right_finger", "right_joint"
+	cfg := &referenceframe.ModelConfigJSON{
+		Name:         name,
+		OutputFrames: []string{"tip"},
+		Links: []referenceframe.LinkConfig{
+			{ID: "base", Parent: referenceframe.World},
+			left,
+			right,
+			{ID: "tip", Parent: "base", Translation: r3.Vector{Z: conf.FingerOffsetMm + dims.Z}},
+		},
+		Joints: []referenceframe.JointConfig{
+			{
+				ID:     openingJoint,
+				Type:   referenceframe.PrismaticJoint,
+				Parent: "base",
+				Axis:   spatialmath.AxisConfig{X: 1},
+				Max:    conf.MaxWidthMm,
+			},
+			{
+				ID:     "left_joint",
+				Type:   referenceframe.PrismaticJoint,
+				Parent: "base",
+				Axis:   spatialmath.AxisConfig{X: -1},
+				Mimic:  &referenceframe.MimicConfig{Joint: openingJoint, ValueMultiplier: 0.5},
+			},
+			{
+				ID:     "right_joint",
+				Type:   referenceframe.PrismaticJoint,
+				Parent: "base",
+				Axis:   spatialmath.AxisConfig{X: 1},
+				Mimic:  &referenceframe.MimicConfig{Joint: openingJoint, ValueMultiplier: 0.5},
+			},
+		},
+	}
+	return cfg.ParseConfig(name)
+}
+
+// widthOf converts a position of the servo or motor to the gap between the fingers.
+func (g *parallelJaw) widthOf(position float64) float64 {
+	fraction := (position - g.conf.ClosedPosition) / (g.conf.OpenPosition - g.conf.ClosedPosition)
+	return math.Max(0, math.Min(1, fraction)) * g.conf.MaxWidthMm
+}
+
+// positionOf converts a gap between the fingers to a position of the servo or motor.
+func (g *parallelJaw) positionOf(widthMm float64) float64 {
+	return g.conf.ClosedPosition + (g.conf.OpenPosition-g.conf.ClosedPosition)*widthMm/g.conf.MaxWidthMm
+}
+
+func (g *parallelJaw) position(ctx context.Context) (float64, error) {
+	if g.servo != nil {
+		angle, err := g.servo.Position(ctx, nil)
+		return float64(angle), err
+	}
+	return g.motor.Position(ctx, nil)
+}
+
+func (g *parallelJaw) width(ctx context.Context) (float64, error) {
+	position, err := g.position(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return g.widthOf(position), nil
+}
+
+func (g *parallelJaw) moveTo(ctx context.Context, position float64) error {
+	if g.servo != nil {
+		return g.servo.Move(ctx, uint32(math.Round(position)), nil)
+	}
+	return g.motor.GoTo(ctx, g.conf.Speed, position, nil)
+}
+
+// overCurrent returns whether the power sensor, if there is one, measures more than the threshold.
+func (g *parallelJaw) overCurrent(ctx context.Context) (bool, float64, error) {
+	if g.sensor == nil {
+		return false, 0, nil
+	}
+	amps, _, err := g.sensor.Current(ctx, nil)
+	if err != nil {
+		return false, 0, err
+	}
+	return math.Abs(amps) >= g.conf.CurrentThresholdAmps, amps, nil
+}
+
+func (g *parallelJaw) setHolding(holding bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.holding = holding
+}
+
+// Open opens the jaws all the way.
+func (g *parallelJaw) Open(ctx context.Context, extra map[string]interface{}) error {
+	ctx, done := g.opMgr.New(ctx)
+	defer done()
+	g.setHolding(false)
+	return g.moveTo(ctx, g.conf.OpenPosition)
+}
+
+// Grab closes the jaws until they are shut or stall on something, and returns whether they are holding something.
+func (g *parallelJaw) Grab(ctx context.Context, extra map[string]interface{}) (bool, error) {
+	ctx, done := g.opMgr.New(ctx)
+	defer done()
+	g.setHolding(false)
+
+	var stalled bool
+	var err error
+	if g.servo != nil {
+		stalled, err = g.closeServo(ctx)
+	} else {
+		stalled, err = g.closeMotor(ctx)
+	}
+	if err != nil {
+		return false, err
+	}
+	width, err := g.width(ctx)
+	if err != nil {
+		return false, err
+	}
+	holding := stalled && width > g.conf.ClosedToleranceMm
+	g.setHolding(holding)
+	return holding, nil
+}
+
+// closeServo steps a servo towards closed at the configured speed. With a power sensor, it stops once the current
+// shows that it has stalled on something. Without one, it commands the closed angle and, once the servo settles,
+// returns whether it stalled short of it. Most servos only report the angle they were told to go to, so they never
+// stall short without a power sensor.
+func (g *parallelJaw) closeServo(ctx context.Context) (bool, error) {
+	angle, err := g.position(ctx)
+	if err != nil {
+		return false, err
+	}
+	if (g.conf.ClosedPosition-angle)*g.closing <= 0 {
+		return false, nil
+	}
+	step := g.conf.Speed * pollInterval.Seconds()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for angle != g.conf.ClosedPosition {
+		angle += g.closing * step
+		// Stop at closed rather than step past it, which also keeps the angle within the range of the servo.
+		if (g.conf.ClosedPosition-angle)*g.closing < 0 {
+			angle = g.conf.ClosedPosition
+		}
+		if err := g.servo.Move(ctx, uint32(math.Round(angle)), nil); err != nil {
+			return false, err
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+		stalled, _, err := g.overCurrent(ctx)
+		if err != nil || stalled {
+			return stalled, err
+		}
+	}
+	if g.sensor != nil {
+		return false, nil
+	}
+	angle, err = g.settleServo(ctx)
+	if err != nil {
+		return false, err
+	}
+	return g.widthOf(angle) > g.conf.ClosedToleranceMm, nil
+}
+
+// settleServo waits for the angle a servo reports to stop changing, and returns it.
+func (g *parallelJaw) settleServo(ctx context.Context) (float64, error) {
+	angle, err := g.position(ctx)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	windowStart := start
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for time.Since(windowStart) < stallWindow && time.Since(start) < servoSettleTimeout {
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-ticker.C:
+		}
+		current, err := g.position(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if current != angle {
+			angle, windowStart = current, time.Now()
+		}
+	}
+	return angle, nil
+}
+
+// closeMotor runs a motor towards closed until it gets there, draws too much current or stops making progress.
+func (g *parallelJaw) closeMotor(ctx context.Context) (stalled bool, err error) {
+	start, err := g.position(ctx)
+	if err != nil {
+		return false, err
+	}
+	if (g.conf.ClosedPosition-start)*g.closing <= 0 {
+		return false, nil
+	}
+	if err := g.motor.SetRPM(ctx, g.closing*g.conf.Speed, nil); err != nil {
+		return false, err
+	}
+	defer func() {
+		if stalled && err == nil && g.conf.HoldingPowerPct > 0 {
+			err = g.motor.SetPower(ctx, g.closing*g.conf.HoldingPowerPct, nil)
+			return
+		}
+		// Stop even if the context is done.
+		err = multierr.Combine(err, g.motor.Stop(context.Background(), nil))
+	}()
+
+	minProgress := stallFraction * g.conf.Speed / 60 * stallWindow.Seconds()
+	windowStart, windowPosition := time.Now(), start
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-ticker.C:
+		}
+		position, err := g.position(ctx)
+		if err != nil {
+			return false, err
+		}
+		if (g.conf.ClosedPosition-position)*g.closing <= 0 {
+			return false, nil
+		}
+		if stalled, _, err := g.overCurrent(ctx); err != nil || stalled {
+			return stalled, err
+		}
+		if time.Since(windowStart) >= stallWindow {
+			if math.Abs(position-windowPosition) < minProgress {
+				return true, nil
+			}
+			windowStart, windowPosition = time.Now(), position
+		}
+	}
+}
+
+// IsHoldingSomething returns whether the last grab caught something that is still there: the jaws have not closed
+// since, and the servo or motor, if it is still squeezing, still draws enough current. The metadata has the gap
+// between the fingers in mm and, with a power sensor, the current in amps.
+func (g *parallelJaw) IsHoldingSomething(ctx context.Context, extra map[string]interface{}) (gripper.HoldingStatus, error) {
+	g.mu.Lock()
+	holding := g.holding
+	g.mu.Unlock()
+
+	width, err := g.width(ctx)
+	if err != nil {
+		return gripper.HoldingStatus{}, err
+	}
+	meta := map[string]interface{}{"width_mm": width}
+	if width <= g.conf.ClosedToleranceMm {
+		holding = false
+	}
+	// A motor that stopped after stalling draws no current, so only a squeezing servo or motor can be checked.
+	if g.sensor != nil && (g.servo != nil || g.conf.HoldingPowerPct > 0) {
+		overCurrent, amps, err := g.overCurrent(ctx)
+		if err != nil {
+			return gripper.HoldingStatus{}, err
+		}
+		meta["current_amps"] = amps
+		holding = holding && overCurrent
+	}
+	return gripper.HoldingStatus{IsHoldingSomething: holding, Meta: meta}, nil
+}
+
+// Stop stops the servo or motor.
+func (g *parallelJaw) Stop(ctx context.Context, extra map[string]interface{}) error {
+	g.opMgr.CancelRunning(ctx)
+	if g.servo != nil {
+		return g.servo.Stop(ctx, extra)
+	}
+	return g.motor.Stop(ctx, extra)
+}
+
+// IsMoving returns whether the jaws are opening, closing or moving to a width.
+func (g *parallelJaw) IsMoving(ctx context.Context) (bool, error) {
+	return g.opMgr.OpRunning(), nil
+}
+
+// Kinematics returns a model whose one input is the gap between the fingers in mm.
+func (g *parallelJaw) Kinematics(ctx context.Context) (referenceframe.Model, error) {
+	return g.model, nil
+}
+
+// CurrentInputs returns the gap between the fingers in mm.
+func (g *parallelJaw) CurrentInputs(ctx context.Context) ([]referenceframe.Input, error) {
+	width, err := g.width(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []referenceframe.Input{width}, nil
+}
+
+// GoToInputs moves the fingers apart to each of the gaps in turn.
+func (g *parallelJaw) GoToInputs(ctx context.Context, inputSteps ...[]referenceframe.Input) error {
+	ctx, done := g.opMgr.New(ctx)
+	defer done()
+	g.setHolding(false)
+	for _, inputs := range inputSteps {
+		if len(inputs) != 1 {
+			return referenceframe.NewIncorrectDoFError(len(inputs), 1)
+		}
+		if inputs[0] < 0 || inputs[0] > g.conf.MaxWidthMm {
+			return errors.Errorf("width %v is out of range [0, %v]", inputs[0], g.conf.MaxWidthMm)
+		}
+		if err := g.moveTo(ctx, g.positionOf(inputs[0])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Geometries returns the fingers where they are, so that they move apart as the jaws open.
+func (g *parallelJaw) Geometries(ctx context.Context, extra map[string]interface{}) ([]spatialmath.Geometry, error) {
+	inputs, err := g.CurrentInputs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gif, err := g.model.Geometries(inputs)
+	if err != nil {
+		return nil, err
+	}
+	return gif.Geometries(), nil
+}
+
+// DoCommand is not implemented.
+func (g *parallelJaw) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	return nil, resource.ErrDoUnimplemented
+}
+
+// Close stops the gripper.
+func (g *parallelJaw) Close(ctx context.Context) error {
+	return g.Stop(ctx, nil)
+}
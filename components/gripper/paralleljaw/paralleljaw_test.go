@@ -0,0 +1,304 @@
+package paralleljaw
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/components/encoder/fake"
+	"go.viam.com/rdk/components/gripper"
+	"go.viam.com/rdk/components/motor"
+	fakemotor "go.viam.com/rdk/components/motor/fake"
+	"go.viam.com/rdk/components/powersensor"
+	"go.viam.com/rdk/components/servo"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+func newTestGripper(ctx context.Context, t *testing.T, deps resource.Dependencies, conf *Config) gripper.Gripper {
+	t.Helper()
+	_, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	g, err := newParallelJaw(ctx, deps, resource.Config{Name: "gripper", ConvertedAttributes: conf}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return g
+}
+
+// fakeServo is a servo that closes from 180 degrees towards 0, and draws a lot of current once it squeezes an object
+// that stops it at objectAngle. If reportsActual is set it reports the angle the object stopped it at, rather than the
+// one it was told to go to.
+type fakeServo struct {
+	mu            sync.Mutex
+	angle         uint32
+	objectAngle   uint32
+	reportsActual bool
+}
+
+func (s *fakeServo) servo() *inject.Servo {
+	injected := inject.NewServo("servo")
+	injected.MoveFunc = func(ctx context.Context, angleDeg uint32, extra map[string]interface{}) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.angle = angleDeg
+		return nil
+	}
+	injected.PositionFunc = func(ctx context.Context, extra map[string]interface{}) (uint32, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.reportsActual {
+			return max(s.angle, s.objectAngle), nil
+		}
+		return s.angle, nil
+	}
+	injected.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+		return nil
+	}
+	return injected
+}
+
+func (s *fakeServo) powerSensor() *inject.PowerSensor {
+	sensor := inject.NewPowerSensor("current")
+	sensor.CurrentFunc = func(ctx context.Context, extra map[string]interface{}) (float64, bool, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.angle < s.objectAngle {
+			return 1.5, false, nil
+		}
+		return 0.2, false, nil
+	}
+	return sensor
+}
+
+func TestServoGripper(t *testing.T) {
+	ctx := context.Background()
+	s := &fakeServo{angle: 90, objectAngle: 100}
+	deps := resource.Dependencies{servo.Named("servo"): s.servo(), powersensor.Named("current"): s.powerSensor()}
+	g := newTestGripper(ctx, t, deps, &Config{
+		Servo:                "servo",
+		OpenPosition:         180,
+		ClosedPosition:       0,
+		Speed:                2000,
+		MaxWidthMm:           90,
+		PowerSensor:          "current",
+		CurrentThresholdAmps: 1,
+	})
+
+	test.That(t, g.Open(ctx, nil), test.ShouldBeNil)
+	inputs, err := g.CurrentInputs(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, inputs, test.ShouldResemble, []float64{90})
+
+	// The servo stops just past the object, once the current jumps.
+	grabbed, err := g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeTrue)
+	test.That(t, s.angle, test.ShouldBeBetween, 50, 100)
+	status, err := g.IsHoldingSomething(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, status.IsHoldingSomething, test.ShouldBeTrue)
+	test.That(t, status.Meta["current_amps"], test.ShouldEqual, 1.5)
+	test.That(t, status.Meta["width_mm"], test.ShouldBeBetween, 25, 50)
+
+	// The object slips out, so the current drops.
+	s.mu.Lock()
+	s.objectAngle = 0
+	s.mu.Unlock()
+	status, err = g.IsHoldingSomething(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, status.IsHoldingSomething, test.ShouldBeFalse)
+
+	// Without an object, the jaws close all the way.
+	test.That(t, g.Open(ctx, nil), test.ShouldBeNil)
+	grabbed, err = g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeFalse)
+	test.That(t, s.angle, test.ShouldEqual, 0)
+
+	test.That(t, g.GoToInputs(ctx, []float64{45}), test.ShouldBeNil)
+	test.That(t, s.angle, test.ShouldEqual, 90)
+	test.That(t, g.GoToInputs(ctx, []float64{100}), test.ShouldNotBeNil)
+}
+
+func TestServoGripperWithoutPowerSensor(t *testing.T) {
+	ctx := context.Background()
+	conf := &Config{Servo: "servo", OpenPosition: 180, ClosedPosition: 0, Speed: 2000, MaxWidthMm: 90}
+
+	// A servo that reports the angle it was stopped at holds what stops it short of closed.
+	s := &fakeServo{angle: 90, objectAngle: 100, reportsActual: true}
+	g := newTestGripper(ctx, t, resource.Dependencies{servo.Named("servo"): s.servo()}, conf)
+	test.That(t, g.Open(ctx, nil), test.ShouldBeNil)
+	grabbed, err := g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeTrue)
+	status, err := g.IsHoldingSomething(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, status.IsHoldingSomething, test.ShouldBeTrue)
+	test.That(t, status.Meta["width_mm"], test.ShouldEqual, 50)
+
+	// Without an object, the jaws close all the way.
+	s.mu.Lock()
+	s.objectAngle = 0
+	s.mu.Unlock()
+	test.That(t, g.Open(ctx, nil), test.ShouldBeNil)
+	grabbed, err = g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeFalse)
+
+	// A servo that is already past closed is not moved, rather than stepped further past it.
+	s = &fakeServo{angle: 5, objectAngle: 0}
+	g = newTestGripper(ctx, t, resource.Dependencies{servo.Named("servo"): s.servo()},
+		&Config{Servo: "servo", OpenPosition: 180, ClosedPosition: 10, Speed: 10, MaxWidthMm: 90})
+	grabbed, err = g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeFalse)
+	test.That(t, s.angle, test.ShouldEqual, 5)
+
+	// A servo that only reports the angle it was told to go to cannot tell that it is holding anything.
+	s = &fakeServo{angle: 90, objectAngle: 100}
+	g = newTestGripper(ctx, t, resource.Dependencies{servo.Named("servo"): s.servo()}, conf)
+	grabbed, err = g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeFalse)
+}
+
+func TestMotorGripper(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logger := logging.NewTestLogger(t)
+
+	enc, err := fake.NewEncoder(ctx, resource.Config{ConvertedAttributes: &fake.Config{UpdateRate: 1}}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	m := &fakemotor.Motor{
+		Named:             motor.Named("motor").AsNamed(),
+		Encoder:           enc.(fake.Encoder),
+		Logger:            logger,
+		PositionReporting: true,
+		MaxRPM:            600,
+		TicksPerRotation:  100,
+		OpMgr:             operation.NewSingleOperationManager(),
+	}
+	// Closing turns the motor forwards, from 0 to 5 revolutions, and an object blocks it at 3 revolutions.
+	var blocked sync.Mutex
+	blockAt := 3.
+	workers := utils.NewBackgroundStoppableWorkers(func(ctx context.Context) {
+		for utils.SelectContextOrWait(ctx, time.Millisecond) {
+			revs, err := m.Position(ctx, nil)
+			blocked.Lock()
+			if err == nil && revs > blockAt {
+				utils.UncheckedError(enc.(fake.Encoder).SetPosition(ctx, blockAt*100))
+			}
+			blocked.Unlock()
+		}
+	})
+	defer workers.Stop()
+
+	g := newTestGripper(ctx, t, resource.Dependencies{m.Name(): m}, &Config{
+		Motor:           "motor",
+		OpenPosition:    0,
+		ClosedPosition:  5,
+		Speed:           300,
+		HoldingPowerPct: 0.2,
+		MaxWidthMm:      50,
+	})
+
+	grabbed, err := g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeTrue)
+	// The motor keeps squeezing.
+	test.That(t, m.PowerPct(), test.ShouldEqual, 0.2)
+	status, err := g.IsHoldingSomething(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, status.IsHoldingSomething, test.ShouldBeTrue)
+	test.That(t, status.Meta["width_mm"], test.ShouldAlmostEqual, 20, 0.5)
+
+	blocked.Lock()
+	blockAt = 10
+	blocked.Unlock()
+	test.That(t, g.Open(ctx, nil), test.ShouldBeNil)
+	inputs, err := g.CurrentInputs(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, inputs[0], test.ShouldAlmostEqual, 50)
+
+	grabbed, err = g.Grab(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grabbed, test.ShouldBeFalse)
+	test.That(t, m.PowerPct(), test.ShouldEqual, 0)
+	status, err = g.IsHoldingSomething(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, status.IsHoldingSomething, test.ShouldBeFalse)
+}
+
+func TestGeometries(t *testing.T) {
+	ctx := context.Background()
+	s := &fakeServo{angle: 180}
+	g := newTestGripper(ctx, t, resource.Dependencies{servo.Named("servo"): s.servo()}, &Config{
+		Servo:          "servo",
+		OpenPosition:   180,
+		ClosedPosition: 0,
+		MaxWidthMm:     40,
+		FingerOffsetMm: 30,
+	})
+
+	m, err := g.Kinematics(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, m.DoF(), test.ShouldHaveLength, 1)
+	test.That(t, m.DoF()[0].Max, test.ShouldEqual, 40)
+	pose, err := m.Transform([]float64{40})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, pose.Point(), test.ShouldResemble, r3.Vector{Z: 80})
+
+	fingerCenters := func() []r3.Vector {
+		geometries, err := g.Geometries(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, geometries, test.ShouldHaveLength, 2)
+		centers := []r3.Vector{geometries[0].Pose().Point(), geometries[1].Pose().Point()}
+		if centers[0].X > centers[1].X {
+			centers[0], centers[1] = centers[1], centers[0]
+		}
+		return centers
+	}
+	open := fingerCenters()
+	test.That(t, open[0].X, test.ShouldAlmostEqual, -25)
+	test.That(t, open[1].X, test.ShouldAlmostEqual, 25)
+	test.That(t, open[0].Z, test.ShouldAlmostEqual, 55)
+
+	test.That(t, g.GoToInputs(ctx, []float64{10}), test.ShouldBeNil)
+	narrow := fingerCenters()
+	test.That(t, narrow[0].X, test.ShouldAlmostEqual, -10, 0.5)
+	test.That(t, narrow[1].X, test.ShouldAlmostEqual, 10, 0.5)
+}
+
+func TestValidate(t *testing.T) {
+	valid := Config{Servo: "servo", OpenPosition: 90, ClosedPosition: 10, MaxWidthMm: 50}
+	deps, _, err := valid.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"servo"})
+
+	withSensor := Config{Motor: "motor", OpenPosition: 0, ClosedPosition: 2, MaxWidthMm: 50, PowerSensor: "ina", CurrentThresholdAmps: 1}
+	deps, _, err = withSensor.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"motor", "ina"})
+
+	for _, modify := range []func(c *Config){
+		func(c *Config) { c.Servo = "" },
+		func(c *Config) { c.Motor = "motor" },
+		func(c *Config) { c.OpenPosition = 200 },
+		func(c *Config) { c.ClosedPosition = c.OpenPosition },
+		func(c *Config) { c.HoldingPowerPct = 0.5 },
+		func(c *Config) { c.MaxWidthMm = 0 },
+		func(c *Config) { c.FingerDimsMm = &r3.Vector{X: 1} },
+		func(c *Config) { c.PowerSensor = "ina" },
+	} {
+		conf := valid
+		modify(&conf)
+		_, _, err := conf.Validate("path")
+		test.That(t, err, test.ShouldNotBeNil)
+	}
+}
@@ -4,4 +4,5 @@ package register
 import (
 	// for grippers.
 	_ "go.viam.com/rdk/components/gripper/fake"
+	_ "go.viam.com/rdk/components/gripper/paralleljaw"
 )
@@ -0,0 +1,444 @@
+// Package ekf implements a movement sensor that fuses an IMU, wheeled odometry and a GPS with an extended Kalman filter.
+package ekf
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+var model = resource.DefaultModelFamily.WithModel("ekf")
+
+const (
+	defaultUpdateRateHz                   = 20.
+	defaultAccelerationStdDevMPerSec2     = 0.5
+	defaultYawAccelerationStdDevDegsPerS2 = 30.
+	defaultGPSStdDevM                     = 3.
+	defaultCompassStdDevDegs              = 5.
+	defaultGyroStdDevDegsPerSec           = 1.
+	defaultOdometryStdDevMPerSec          = 0.05
+	// userEquivalentRangeErrorM converts a GPS horizontal dilution of precision into a position standard deviation.
+	userEquivalentRangeErrorM = 1.5
+	mToMM                     = 1000.
+)
+
+// Accuracy map keys reported by the fused sensor.
+const (
+	positionXStdDevKey = "position_x_std_dev_m"
+	positionYStdDevKey = "position_y_std_dev_m"
+	headingStdDevKey   = "heading_std_dev_degs"
+	speedStdDevKey     = "speed_std_dev_m_per_sec"
+	yawRateStdDevKey   = "yaw_rate_std_dev_degs_per_sec"
+)
+
+var errNoPositionEstimate = errors.New("no position estimate yet, waiting for a GPS fix")
+
+// Config is the config of the ekf movement_sensor model. At least one of the IMU, odometry and GPS must be set.
+// Every sensor is assumed to be mounted with its Z axis pointing up; the odometry and IMU report the yaw rate of the
+// vehicle about Z, the odometry reports its forward speed along Y, and the GPS reports its position and optionally a
+// compass heading. Standard deviations that are not set fall back to the accuracy reported by each sensor, and then
+// to defaults.
+type Config struct {
+	IMU      string `json:"imu,omitempty"`
+	Odometry string `json:"odometry,omitempty"`
+	GPS      string `json:"gps,omitempty"`
+
+	UpdateRateHz float64 `json:"update_rate_hz,omitempty"`
+
+	AccelerationStdDevMPerSec2       float64 `json:"acceleration_std_dev_m_per_sec2,omitempty"`
+	YawAccelerationStdDevDegsPerSec2 float64 `json:"yaw_acceleration_std_dev_degs_per_sec2,omitempty"`
+	GPSStdDevM                       float64 `json:"gps_std_dev_m,omitempty"`
+	CompassStdDevDegs                float64 `json:"compass_std_dev_degs,omitempty"`
+	GyroStdDevDegsPerSec             float64 `json:"gyro_std_dev_degs_per_sec,omitempty"`
+	OdometryStdDevMPerSec            float64 `json:"odometry_std_dev_m_per_sec,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	var deps []string
+	for _, name := range []string{cfg.IMU, cfg.Odometry, cfg.GPS} {
+		if name != "" {
+			deps = append(deps, name)
+		}
+	}
+	if len(deps) == 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("at least one of imu, odometry or gps is required"))
+	}
+	for field, value := range map[string]float64{
+		"update_rate_hz":                         cfg.UpdateRateHz,
+		"acceleration_std_dev_m_per_sec2":        cfg.AccelerationStdDevMPerSec2,
+		"yaw_acceleration_std_dev_degs_per_sec2": cfg.YawAccelerationStdDevDegsPerSec2,
+		"gps_std_dev_m":                          cfg.GPSStdDevM,
+		"compass_std_dev_degs":                   cfg.CompassStdDevDegs,
+		"gyro_std_dev_degs_per_sec":              cfg.GyroStdDevDegsPerSec,
+		"odometry_std_dev_m_per_sec":             cfg.OdometryStdDevMPerSec,
+	} {
+		if value < 0 {
+			return nil, nil, resource.NewConfigValidationError(path, errors.New(field+" cannot be negative"))
+		}
+	}
+	return deps, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(
+		movementsensor.API,
+		model,
+		resource.Registration[movementsensor.MovementSensor, *Config]{Constructor: newEKF})
+}
+
+// source is a sensor feeding the filter, along with the properties it reported when the filter was configured.
+type source struct {
+	movementsensor.MovementSensor
+	props *movementsensor.Properties
+}
+
+type fused struct {
+	resource.Named
+	resource.AlwaysRebuild
+	logger logging.Logger
+
+	imu, odometry, gps *source
+	cfg                Config
+
+	mu          sync.Mutex
+	filter      *filter
+	lastUpdate  time.Time
+	origin      *geo.Point // the point the local frame of the filter is anchored to
+	altitude    float64
+	initialized bool // whether the position has been set by a GPS fix
+	lastFix     *geo.Point
+	nmeaFix     int32
+	lastErr     movementsensor.LastError
+
+	workers *goutils.StoppableWorkers
+}
+
+func newEKF(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (
+	movementsensor.MovementSensor, error,
+) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	sourceFromDeps := func(name string) (*source, error) {
+		if name == "" {
+			return nil, nil
+		}
+		ms, err := movementsensor.FromProvider(deps, name)
+		if err != nil {
+			return nil, err
+		}
+		props, err := ms.Properties(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &source{MovementSensor: ms, props: props}, nil
+	}
+	imu, err := sourceFromDeps(newConf.IMU)
+	if err != nil {
+		return nil, err
+	}
+	odometry, err := sourceFromDeps(newConf.Odometry)
+	if err != nil {
+		return nil, err
+	}
+	gps, err := sourceFromDeps(newConf.GPS)
+	if err != nil {
+		return nil, err
+	}
+
+	f := newFused(conf.ResourceName().AsNamed(), imu, odometry, gps, *newConf, logger)
+	period := time.Duration(float64(time.Second) / f.cfg.UpdateRateHz)
+	f.workers = goutils.NewBackgroundStoppableWorkers(func(ctx context.Context) {
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				f.step(ctx, now)
+			}
+		}
+	})
+	return f, nil
+}
+
+// newFused returns a fused movement sensor that has not started polling its sources. Any source may be nil.
+func newFused(named resource.Named, imu, odometry, gps *source, cfg Config, logger logging.Logger) *fused {
+	setDefault := func(value *float64, def float64) {
+		if *value == 0 {
+			*value = def
+		}
+	}
+	setDefault(&cfg.UpdateRateHz, defaultUpdateRateHz)
+	setDefault(&cfg.AccelerationStdDevMPerSec2, defaultAccelerationStdDevMPerSec2)
+	setDefault(&cfg.YawAccelerationStdDevDegsPerSec2, defaultYawAccelerationStdDevDegsPerS2)
+	setDefault(&cfg.GyroStdDevDegsPerSec, defaultGyroStdDevDegsPerSec)
+	setDefault(&cfg.OdometryStdDevMPerSec, defaultOdometryStdDevMPerSec)
+
+	f := &fused{
+		Named:    named,
+		logger:   logger,
+		imu:      imu,
+		odometry: odometry,
+		gps:      gps,
+		cfg:      cfg,
+		filter: newFilter(0, cfg.AccelerationStdDevMPerSec2,
+			utils.DegToRad(cfg.YawAccelerationStdDevDegsPerSec2)),
+		origin:  geo.NewPoint(0, 0),
+		nmeaFix: -1,
+		lastErr: movementsensor.NewLastError(10, 5),
+	}
+	return f
+}
+
+// positionSupported returns whether a GPS anchors the position of the filter. Without one the filter still dead
+// reckons from its origin to estimate the heading and speed, but that position is not a point on the globe.
+func (f *fused) positionSupported() bool {
+	return f.gps != nil && f.gps.props.PositionSupported
+}
+
+// step predicts the estimate forward to now and corrects it with the latest readings of every source.
+func (f *fused) step(ctx context.Context, now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.lastUpdate.IsZero() {
+		f.filter.predict(now.Sub(f.lastUpdate).Seconds())
+	}
+	f.lastUpdate = now
+
+	var errs []error
+	if f.odometry != nil {
+		errs = append(errs, f.updateFromOdometry(ctx))
+	}
+	if f.imu != nil {
+		errs = append(errs, f.updateFromIMU(ctx))
+	}
+	if f.gps != nil {
+		errs = append(errs, f.updateFromGPS(ctx))
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		f.logger.CDebugf(ctx, "error updating the filter: %v", err)
+	}
+	f.lastErr.Set(err)
+}
+
+func (f *fused) updateFromOdometry(ctx context.Context) error {
+	if f.odometry.props.LinearVelocitySupported {
+		vel, err := f.odometry.LinearVelocity(ctx, nil)
+		if err != nil {
+			return err
+		}
+		variance := f.cfg.OdometryStdDevMPerSec * f.cfg.OdometryStdDevMPerSec
+		if err := f.filter.updateSpeed(vel.Y, variance); err != nil {
+			return err
+		}
+	}
+	if f.odometry.props.AngularVelocitySupported {
+		return f.updateYawRate(ctx, f.odometry)
+	}
+	return nil
+}
+
+func (f *fused) updateFromIMU(ctx context.Context) error {
+	if f.imu.props.AngularVelocitySupported {
+		if err := f.updateYawRate(ctx, f.imu); err != nil {
+			return err
+		}
+	}
+	if f.imu.props.CompassHeadingSupported {
+		return f.updateHeading(ctx, f.imu)
+	}
+	return nil
+}
+
+func (f *fused) updateFromGPS(ctx context.Context) error {
+	if f.gps.props.CompassHeadingSupported {
+		if err := f.updateHeading(ctx, f.gps); err != nil {
+			return err
+		}
+	}
+	if !f.gps.props.PositionSupported {
+		return nil
+	}
+	pt, alt, err := f.gps.Position(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// GPS receivers usually update more slowly than the filter, so a fix is only used once.
+	if pt == nil || movementsensor.IsPositionNaN(pt) || movementsensor.ArePointsEqual(pt, f.lastFix) {
+		return nil
+	}
+	f.lastFix = pt
+	f.altitude = alt
+
+	stdDev := f.cfg.GPSStdDevM
+	if acc, err := f.gps.Accuracy(ctx, nil); err == nil && acc != nil {
+		f.nmeaFix = acc.NmeaFix
+		if stdDev == 0 && acc.Hdop > 0 && !math.IsNaN(float64(acc.Hdop)) {
+			stdDev = float64(acc.Hdop) * userEquivalentRangeErrorM
+		}
+	}
+	if stdDev == 0 {
+		stdDev = defaultGPSStdDevM
+	}
+
+	if !f.initialized {
+		// The first fix anchors the local frame of the filter.
+		f.origin = pt
+		f.filter.state.SetVec(stateX, 0)
+		f.filter.state.SetVec(stateY, 0)
+		f.filter.cov.SetSym(stateX, stateX, stdDev*stdDev)
+		f.filter.cov.SetSym(stateY, stateY, stdDev*stdDev)
+		f.initialized = true
+		return nil
+	}
+	local := spatialmath.GeoPointToPoint(pt, f.origin)
+	return f.filter.updatePosition(local.X/mToMM, local.Y/mToMM, stdDev*stdDev)
+}
+
+func (f *fused) updateYawRate(ctx context.Context, ms movementsensor.MovementSensor) error {
+	angVel, err := ms.AngularVelocity(ctx, nil)
+	if err != nil {
+		return err
+	}
+	stdDev := utils.DegToRad(f.cfg.GyroStdDevDegsPerSec)
+	return f.filter.updateYawRate(utils.DegToRad(angVel.Z), stdDev*stdDev)
+}
+
+func (f *fused) updateHeading(ctx context.Context, ms movementsensor.MovementSensor) error {
+	heading, err := ms.CompassHeading(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if math.IsNaN(heading) {
+		return nil
+	}
+	stdDev := f.cfg.CompassStdDevDegs
+	if stdDev == 0 {
+		if acc, err := ms.Accuracy(ctx, nil); err == nil && acc != nil &&
+			acc.CompassDegreeError > 0 && !math.IsNaN(float64(acc.CompassDegreeError)) {
+			stdDev = float64(acc.CompassDegreeError)
+		} else {
+			stdDev = defaultCompassStdDevDegs
+		}
+	}
+	stdDevRads := utils.DegToRad(stdDev)
+	return f.filter.updateHeading(compassToHeading(heading), stdDevRads*stdDevRads)
+}
+
+func (f *fused) Position(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+	if !f.positionSupported() {
+		return geo.NewPoint(math.NaN(), math.NaN()), math.NaN(), movementsensor.ErrMethodUnimplementedPosition
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.initialized {
+		return geo.NewPoint(math.NaN(), math.NaN()), math.NaN(), errNoPositionEstimate
+	}
+	local := r3.Vector{X: f.filter.state.AtVec(stateX) * mToMM, Y: f.filter.state.AtVec(stateY) * mToMM}
+	pt := spatialmath.PoseToGeoPose(spatialmath.NewGeoPose(f.origin, 0), spatialmath.NewPoseFromPoint(local)).Location()
+	return pt, f.altitude, f.lastErr.Get()
+}
+
+func (f *fused) LinearVelocity(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return r3.Vector{Y: f.filter.state.AtVec(stateSpeed)}, f.lastErr.Get()
+}
+
+func (f *fused) AngularVelocity(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return spatialmath.AngularVelocity{Z: utils.RadToDeg(f.filter.state.AtVec(stateYawRate))}, f.lastErr.Get()
+}
+
+func (f *fused) LinearAcceleration(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	return r3.Vector{X: math.NaN(), Y: math.NaN(), Z: math.NaN()}, movementsensor.ErrMethodUnimplementedLinearAcceleration
+}
+
+func (f *fused) CompassHeading(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return headingToCompass(f.filter.state.AtVec(stateHeading)), f.lastErr.Get()
+}
+
+// Orientation returns the yaw of the vehicle about Z, with a theta of zero facing north.
+func (f *fused) Orientation(ctx context.Context, extra map[string]interface{}) (spatialmath.Orientation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	theta := wrapAngle(f.filter.state.AtVec(stateHeading) - math.Pi/2)
+	return &spatialmath.OrientationVector{OZ: 1, Theta: theta}, f.lastErr.Get()
+}
+
+// Accuracy reports the standard deviations of the fused estimate. CompassDegreeError is the standard deviation of the
+// heading, and NmeaFix is passed through from the GPS, if any.
+func (f *fused) Accuracy(ctx context.Context, extra map[string]interface{}) (*movementsensor.Accuracy, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	headingStdDev := utils.RadToDeg(f.filter.stdDev(stateHeading))
+	acc := movementsensor.UnimplementedOptionalAccuracies()
+	acc.AccuracyMap = map[string]float32{
+		positionXStdDevKey: float32(f.filter.stdDev(stateX)),
+		positionYStdDevKey: float32(f.filter.stdDev(stateY)),
+		headingStdDevKey:   float32(headingStdDev),
+		speedStdDevKey:     float32(f.filter.stdDev(stateSpeed)),
+		yawRateStdDevKey:   float32(utils.RadToDeg(f.filter.stdDev(stateYawRate))),
+	}
+	acc.CompassDegreeError = float32(headingStdDev)
+	acc.NmeaFix = f.nmeaFix
+	return acc, nil
+}
+
+func (f *fused) Properties(ctx context.Context, extra map[string]interface{}) (*movementsensor.Properties, error) {
+	return &movementsensor.Properties{
+		PositionSupported:       f.positionSupported(),
+		OrientationSupported:    true,
+		CompassHeadingSupported: true,
+		LinearVelocitySupported: (f.odometry != nil && f.odometry.props.LinearVelocitySupported) ||
+			f.positionSupported(),
+		AngularVelocitySupported: (f.odometry != nil && f.odometry.props.AngularVelocitySupported) ||
+			(f.imu != nil && f.imu.props.AngularVelocitySupported),
+	}, nil
+}
+
+func (f *fused) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	return movementsensor.DefaultAPIReadings(ctx, f, extra)
+}
+
+func (f *fused) Close(ctx context.Context) error {
+	if f.workers != nil {
+		f.workers.Stop()
+	}
+	return nil
+}
+
+// compassToHeading converts a compass heading in degrees clockwise from north to radians counterclockwise from east.
+func compassToHeading(compass float64) float64 {
+	return wrapAngle(math.Pi/2 - utils.DegToRad(compass))
+}
+
+// headingToCompass converts radians counterclockwise from east to a compass heading in degrees in [0, 360).
+func headingToCompass(heading float64) float64 {
+	compass := math.Mod(utils.RadToDeg(math.Pi/2-heading), 360)
+	if compass < 0 {
+		compass += 360
+	}
+	return compass
+}
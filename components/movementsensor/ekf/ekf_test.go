@@ -0,0 +1,307 @@
+package ekf
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+	"go.viam.com/rdk/utils"
+)
+
+const (
+	testRateHz     = 20
+	testGPSRateHz  = 1
+	testGPSNoiseM  = 2.
+	testSpeed      = 1.  // m/s
+	testYawRateDeg = 10. // deg/s
+)
+
+var testOrigin = geo.NewPoint(40.7, -73.98)
+
+// reading is one recorded sample of every sensor, along with the true state of the vehicle.
+type reading struct {
+	truth      r3.Vector // meters east and north of testOrigin
+	heading    float64   // radians counterclockwise from east
+	gps        *geo.Point
+	speed      float64 // m/s
+	yawRateDeg float64 // deg/s
+}
+
+// record simulates a vehicle driving in a circle for the given duration, starting at testOrigin facing north, and
+// records noisy readings from its sensors. The GPS only updates at testGPSRateHz.
+func record(duration time.Duration) []reading {
+	randSeed := rand.New(rand.NewSource(1))
+	dt := 1. / testRateHz
+	heading := math.Pi / 2
+	var pos r3.Vector
+	var gps *geo.Point
+	var readings []reading
+	for i := 0; i < int(duration.Seconds()*testRateHz); i++ {
+		if i%(testRateHz/testGPSRateHz) == 0 {
+			noisy := pos.Add(r3.Vector{X: randSeed.NormFloat64(), Y: randSeed.NormFloat64()}.Mul(testGPSNoiseM))
+			gps = pointAt(noisy)
+		}
+		readings = append(readings, reading{
+			truth:      pos,
+			heading:    heading,
+			gps:        gps,
+			speed:      testSpeed + 0.05*randSeed.NormFloat64(),
+			yawRateDeg: testYawRateDeg + randSeed.NormFloat64(),
+		})
+		pos = pos.Add(r3.Vector{X: math.Cos(heading), Y: math.Sin(heading)}.Mul(testSpeed * dt))
+		heading += utils.DegToRad(testYawRateDeg) * dt
+	}
+	return readings
+}
+
+// pointAt returns the GPS point at the given position in meters east and north of testOrigin.
+func pointAt(pos r3.Vector) *geo.Point {
+	return spatialmath.PoseToGeoPose(spatialmath.NewGeoPose(testOrigin, 0), spatialmath.NewPoseFromPoint(pos.Mul(mToMM))).Location()
+}
+
+// replayer plays back recorded readings through fake sensors, one reading per step.
+type replayer struct {
+	readings []reading
+	idx      int
+}
+
+func (r *replayer) current() reading {
+	return r.readings[r.idx]
+}
+
+func (r *replayer) gps() *source {
+	ms := inject.NewMovementSensor("gps")
+	ms.PositionFunc = func(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+		return r.current().gps, 10, nil
+	}
+	ms.AccuracyFunc = func(ctx context.Context, extra map[string]interface{}) (*movementsensor.Accuracy, error) {
+		return &movementsensor.Accuracy{Hdop: float32(testGPSNoiseM / userEquivalentRangeErrorM), NmeaFix: 1}, nil
+	}
+	return &source{MovementSensor: ms, props: &movementsensor.Properties{PositionSupported: true}}
+}
+
+func (r *replayer) odometry() *source {
+	ms := inject.NewMovementSensor("odometry")
+	ms.LinearVelocityFunc = func(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+		return r3.Vector{Y: r.current().speed}, nil
+	}
+	return &source{MovementSensor: ms, props: &movementsensor.Properties{LinearVelocitySupported: true}}
+}
+
+func (r *replayer) imu() *source {
+	ms := inject.NewMovementSensor("imu")
+	ms.AngularVelocityFunc = func(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+		return spatialmath.AngularVelocity{Z: r.current().yawRateDeg}, nil
+	}
+	return &source{MovementSensor: ms, props: &movementsensor.Properties{AngularVelocitySupported: true}}
+}
+
+// run steps the fused sensor through every reading, calling check after each step.
+func (r *replayer) run(ctx context.Context, f *fused, check func(reading)) {
+	start := time.Now()
+	for r.idx = range r.readings {
+		f.step(ctx, start.Add(time.Duration(r.idx)*time.Second/testRateHz))
+		if check != nil {
+			check(r.current())
+		}
+	}
+}
+
+// positionError returns the planar distance in meters between the fused position and the truth, which is relative
+// to the given origin.
+func positionError(t *testing.T, f *fused, origin *geo.Point, truth r3.Vector) float64 {
+	t.Helper()
+	pt, _, err := f.Position(context.Background(), nil)
+	test.That(t, err, test.ShouldBeNil)
+	local := spatialmath.GeoPointToPoint(pt, origin).Mul(1 / mToMM)
+	return math.Hypot(local.X-truth.X, local.Y-truth.Y)
+}
+
+func TestFusion(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	readings := record(60 * time.Second)
+
+	t.Run("fusing GPS, odometry and IMU beats the GPS alone", func(t *testing.T) {
+		r := &replayer{readings: readings}
+		f := newFused(resource.NewName(movementsensor.API, "fused").AsNamed(), r.imu(), r.odometry(), r.gps(), Config{}, logger)
+
+		var fusedErr, gpsErr float64
+		var samples int
+		r.run(ctx, f, func(rd reading) {
+			// Give the filter time to find its heading from the GPS track.
+			if r.idx < 20*testRateHz {
+				return
+			}
+			fusedErr += math.Pow(positionError(t, f, testOrigin, rd.truth), 2)
+			gps := spatialmath.GeoPointToPoint(rd.gps, testOrigin).Mul(1 / mToMM)
+			gpsErr += math.Pow(math.Hypot(gps.X-rd.truth.X, gps.Y-rd.truth.Y), 2)
+			samples++
+		})
+		fusedRMS := math.Sqrt(fusedErr / float64(samples))
+		gpsRMS := math.Sqrt(gpsErr / float64(samples))
+		test.That(t, fusedRMS, test.ShouldBeLessThan, gpsRMS/2)
+
+		last := readings[len(readings)-1]
+		heading, err := f.CompassHeading(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		headingErr := math.Abs(utils.RadToDeg(wrapAngle(compassToHeading(heading) - last.heading)))
+		test.That(t, headingErr, test.ShouldBeLessThan, 5)
+
+		vel, err := f.LinearVelocity(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, vel.Y, test.ShouldAlmostEqual, testSpeed, 0.05)
+		angVel, err := f.AngularVelocity(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, angVel.Z, test.ShouldAlmostEqual, testYawRateDeg, 1)
+
+		// The reported accuracy is consistent with the errors of the estimate, and better than a single fix.
+		acc, err := f.Accuracy(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, acc.AccuracyMap[positionXStdDevKey], test.ShouldBeLessThan, testGPSNoiseM)
+		test.That(t, acc.AccuracyMap[positionYStdDevKey], test.ShouldBeLessThan, testGPSNoiseM)
+		test.That(t, float64(acc.AccuracyMap[positionXStdDevKey]), test.ShouldBeGreaterThan, fusedRMS/5)
+		test.That(t, acc.CompassDegreeError, test.ShouldEqual, acc.AccuracyMap[headingStdDevKey])
+		test.That(t, acc.CompassDegreeError, test.ShouldBeLessThan, 5)
+		test.That(t, acc.NmeaFix, test.ShouldEqual, 1)
+	})
+
+	t.Run("dead reckons without a GPS", func(t *testing.T) {
+		r := &replayer{readings: readings}
+		f := newFused(resource.NewName(movementsensor.API, "fused").AsNamed(), r.imu(), r.odometry(), nil, Config{}, logger)
+
+		// Without a GPS the filter dead reckons from its origin at (0, 0). The vehicle starts facing north, so
+		// tell the filter so.
+		test.That(t, f.filter.updateHeading(math.Pi/2, 1e-6), test.ShouldBeNil)
+		// Without a GPS the uncertainty of the position only grows.
+		var stdDevs []float32
+		r.run(ctx, f, func(rd reading) {
+			if r.idx%(10*testRateHz) != 0 {
+				return
+			}
+			acc, err := f.Accuracy(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			stdDevs = append(stdDevs, acc.AccuracyMap[positionXStdDevKey]+acc.AccuracyMap[positionYStdDevKey])
+		})
+		for i := 1; i < len(stdDevs); i++ {
+			test.That(t, stdDevs[i], test.ShouldBeGreaterThan, stdDevs[i-1])
+		}
+		// The dead reckoned position is not a point on the globe, so it is not reported.
+		_, _, err := f.Position(ctx, nil)
+		test.That(t, err, test.ShouldBeError, movementsensor.ErrMethodUnimplementedPosition)
+		truth := readings[len(readings)-1].truth
+		test.That(t, math.Hypot(f.filter.state.AtVec(stateX)-truth.X, f.filter.state.AtVec(stateY)-truth.Y),
+			test.ShouldBeLessThan, 3)
+	})
+
+	t.Run("waits for a GPS fix", func(t *testing.T) {
+		r := &replayer{readings: readings}
+		gps := r.gps()
+		gps.MovementSensor.(*inject.MovementSensor).PositionFunc = func(ctx context.Context, extra map[string]interface{}) (
+			*geo.Point, float64, error,
+		) {
+			return geo.NewPoint(math.NaN(), math.NaN()), math.NaN(), nil
+		}
+		f := newFused(resource.NewName(movementsensor.API, "fused").AsNamed(), nil, nil, gps, Config{}, logger)
+		f.step(ctx, time.Now())
+		_, _, err := f.Position(ctx, nil)
+		test.That(t, err, test.ShouldBeError, errNoPositionEstimate)
+	})
+}
+
+func TestHeadingConversions(t *testing.T) {
+	for _, compass := range []float64{0, 45, 90, 180, 270, 359} {
+		test.That(t, headingToCompass(compassToHeading(compass)), test.ShouldAlmostEqual, compass, 1e-9)
+	}
+	test.That(t, compassToHeading(0), test.ShouldAlmostEqual, math.Pi/2)
+	test.That(t, compassToHeading(90), test.ShouldAlmostEqual, 0)
+}
+
+func TestNewEKF(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+
+	cfg := &Config{}
+	_, _, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	cfg = &Config{GPS: "gps", Odometry: "odometry", GPSStdDevM: -1}
+	_, _, err = cfg.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	cfg.GPSStdDevM = 0
+	deps, _, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"odometry", "gps"})
+
+	r := &replayer{readings: record(time.Second)}
+	gps, odometry := r.gps(), r.odometry()
+	gps.MovementSensor.(*inject.MovementSensor).PropertiesFunc = func(ctx context.Context, extra map[string]interface{}) (
+		*movementsensor.Properties, error,
+	) {
+		return gps.props, nil
+	}
+	odometry.MovementSensor.(*inject.MovementSensor).PropertiesFunc = func(ctx context.Context, extra map[string]interface{}) (
+		*movementsensor.Properties, error,
+	) {
+		return odometry.props, nil
+	}
+	ms, err := newEKF(ctx, resource.Dependencies{
+		movementsensor.Named("gps"):      gps.MovementSensor,
+		movementsensor.Named("odometry"): odometry.MovementSensor,
+	}, resource.Config{Name: "fused", API: movementsensor.API, Model: model, ConvertedAttributes: cfg}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer ms.Close(ctx)
+
+	props, err := ms.Properties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.PositionSupported, test.ShouldBeTrue)
+	test.That(t, props.LinearVelocitySupported, test.ShouldBeTrue)
+	test.That(t, props.AngularVelocitySupported, test.ShouldBeFalse)
+
+	// The background worker anchors the filter at the first fix.
+	waitForPosition(t, ms, r.readings[0].gps)
+
+	// Without a GPS there is no position to report.
+	cfg = &Config{Odometry: "odometry"}
+	_, _, err = cfg.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	noGPS, err := newEKF(ctx, resource.Dependencies{
+		movementsensor.Named("odometry"): odometry.MovementSensor,
+	}, resource.Config{Name: "fused", API: movementsensor.API, Model: model, ConvertedAttributes: cfg}, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer noGPS.Close(ctx)
+	props, err = noGPS.Properties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.PositionSupported, test.ShouldBeFalse)
+	test.That(t, props.LinearVelocitySupported, test.ShouldBeTrue)
+	_, _, err = noGPS.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeError, movementsensor.ErrMethodUnimplementedPosition)
+	readings, err := noGPS.Readings(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings, test.ShouldNotContainKey, "position")
+	test.That(t, readings, test.ShouldContainKey, "linear_velocity")
+}
+
+func waitForPosition(t *testing.T, ms movementsensor.MovementSensor, expected *geo.Point) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		pt, _, err := ms.Position(context.Background(), nil)
+		if err == nil {
+			test.That(t, pt.Lat(), test.ShouldAlmostEqual, expected.Lat(), 1e-6)
+			test.That(t, pt.Lng(), test.ShouldAlmostEqual, expected.Lng(), 1e-6)
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for a position estimate")
+}
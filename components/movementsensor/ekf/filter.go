@@ -0,0 +1,198 @@
+package ekf
+
+import (
+	"errors"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// Indices into the state of the filter.
+const (
+	stateX       = iota // east of the origin, in meters
+	stateY              // north of the origin, in meters
+	stateHeading        // counterclockwise from east, in radians
+	stateSpeed          // forward speed, in meters per second
+	stateYawRate        // counterclockwise yaw rate, in radians per second
+	stateSize
+)
+
+// unknownHeadingVariance is the variance of the heading before anything has measured it. It is large enough that
+// the first heading measurement, or the track of the GPS, effectively sets the heading.
+const unknownHeadingVariance = math.Pi * math.Pi
+
+// filter is an extended Kalman filter estimating the planar pose and velocity of a vehicle with a constant
+// velocity and turn rate motion model. Changes in speed and yaw rate are modeled as white noise accelerations.
+type filter struct {
+	state *mat.VecDense
+	cov   *mat.SymDense
+
+	accelerationVariance    float64 // (m/s^2)^2
+	yawAccelerationVariance float64 // (rad/s^2)^2
+}
+
+// newFilter returns a filter at rest at the origin, with the given initial position variance and an unknown heading.
+func newFilter(positionVariance, accelerationStdDev, yawAccelerationStdDev float64) *filter {
+	cov := mat.NewSymDense(stateSize, nil)
+	cov.SetSym(stateX, stateX, positionVariance)
+	cov.SetSym(stateY, stateY, positionVariance)
+	cov.SetSym(stateHeading, stateHeading, unknownHeadingVariance)
+	return &filter{
+		state:                   mat.NewVecDense(stateSize, nil),
+		cov:                     cov,
+		accelerationVariance:    accelerationStdDev * accelerationStdDev,
+		yawAccelerationVariance: yawAccelerationStdDev * yawAccelerationStdDev,
+	}
+}
+
+// predict advances the estimate by dt seconds.
+func (f *filter) predict(dt float64) {
+	if dt <= 0 {
+		return
+	}
+	heading := f.state.AtVec(stateHeading)
+	speed := f.state.AtVec(stateSpeed)
+	cos, sin := math.Cos(heading), math.Sin(heading)
+
+	f.state.SetVec(stateX, f.state.AtVec(stateX)+speed*cos*dt)
+	f.state.SetVec(stateY, f.state.AtVec(stateY)+speed*sin*dt)
+	f.state.SetVec(stateHeading, wrapAngle(heading+f.state.AtVec(stateYawRate)*dt))
+
+	// Jacobian of the motion model with respect to the state.
+	jac := mat.NewDense(stateSize, stateSize, nil)
+	for i := 0; i < stateSize; i++ {
+		jac.Set(i, i, 1)
+	}
+	jac.Set(stateX, stateHeading, -speed*sin*dt)
+	jac.Set(stateX, stateSpeed, cos*dt)
+	jac.Set(stateY, stateHeading, speed*cos*dt)
+	jac.Set(stateY, stateSpeed, sin*dt)
+	jac.Set(stateHeading, stateYawRate, dt)
+
+	// The accelerations enter the state through their integrals over dt.
+	noiseGain := mat.NewDense(stateSize, 2, []float64{
+		dt * dt / 2 * cos, 0,
+		dt * dt / 2 * sin, 0,
+		0, dt * dt / 2,
+		dt, 0,
+		0, dt,
+	})
+	noise := mat.NewDiagDense(2, []float64{f.accelerationVariance, f.yawAccelerationVariance})
+
+	var propagated, processNoise, jacCov, gainNoise mat.Dense
+	jacCov.Mul(jac, f.cov)
+	propagated.Mul(&jacCov, jac.T())
+	gainNoise.Mul(noiseGain, noise)
+	processNoise.Mul(&gainNoise, noiseGain.T())
+	propagated.Add(&propagated, &processNoise)
+	f.cov = symmetrize(&propagated)
+}
+
+// measurement is a linear measurement of the state. angular marks the rows of z that are angles, whose residuals
+// are wrapped.
+type measurement struct {
+	z        []float64
+	h        *mat.Dense
+	variance []float64
+	angular  []bool
+}
+
+// update corrects the estimate with a measurement, using the Joseph form of the covariance update so that the
+// covariance stays positive definite.
+func (f *filter) update(m measurement) error {
+	rows := len(m.z)
+	noise := mat.NewDiagDense(rows, m.variance)
+
+	var predicted mat.VecDense
+	predicted.MulVec(m.h, f.state)
+	residual := mat.NewVecDense(rows, nil)
+	for i := range m.z {
+		r := m.z[i] - predicted.AtVec(i)
+		if len(m.angular) > i && m.angular[i] {
+			r = wrapAngle(r)
+		}
+		residual.SetVec(i, r)
+	}
+
+	var innovation, hCov mat.Dense
+	hCov.Mul(m.h, f.cov)
+	innovation.Mul(&hCov, m.h.T())
+	innovation.Add(&innovation, noise)
+	var innovationInv mat.Dense
+	if err := innovationInv.Inverse(&innovation); err != nil {
+		return errors.New("measurement innovation is singular")
+	}
+
+	var gain mat.Dense
+	gain.Mul(hCov.T(), &innovationInv)
+
+	var correction mat.VecDense
+	correction.MulVec(&gain, residual)
+	f.state.AddVec(f.state, &correction)
+	f.state.SetVec(stateHeading, wrapAngle(f.state.AtVec(stateHeading)))
+
+	identity := mat.NewDiagDense(stateSize, nil)
+	for i := 0; i < stateSize; i++ {
+		identity.SetDiag(i, 1)
+	}
+	var factor, factorCov, joseph, gainNoise, correctionNoise mat.Dense
+	factor.Mul(&gain, m.h)
+	factor.Sub(identity, &factor)
+	factorCov.Mul(&factor, f.cov)
+	joseph.Mul(&factorCov, factor.T())
+	gainNoise.Mul(&gain, noise)
+	correctionNoise.Mul(&gainNoise, gain.T())
+	joseph.Add(&joseph, &correctionNoise)
+	f.cov = symmetrize(&joseph)
+	return nil
+}
+
+// updatePosition corrects the estimate with a measured position in meters.
+func (f *filter) updatePosition(x, y, variance float64) error {
+	h := mat.NewDense(2, stateSize, nil)
+	h.Set(0, stateX, 1)
+	h.Set(1, stateY, 1)
+	return f.update(measurement{z: []float64{x, y}, h: h, variance: []float64{variance, variance}})
+}
+
+// updateHeading corrects the estimate with a measured heading in radians counterclockwise from east.
+func (f *filter) updateHeading(heading, variance float64) error {
+	h := mat.NewDense(1, stateSize, nil)
+	h.Set(0, stateHeading, 1)
+	return f.update(measurement{z: []float64{heading}, h: h, variance: []float64{variance}, angular: []bool{true}})
+}
+
+// updateSpeed corrects the estimate with a measured forward speed in meters per second.
+func (f *filter) updateSpeed(speed, variance float64) error {
+	h := mat.NewDense(1, stateSize, nil)
+	h.Set(0, stateSpeed, 1)
+	return f.update(measurement{z: []float64{speed}, h: h, variance: []float64{variance}})
+}
+
+// updateYawRate corrects the estimate with a measured yaw rate in radians per second.
+func (f *filter) updateYawRate(yawRate, variance float64) error {
+	h := mat.NewDense(1, stateSize, nil)
+	h.Set(0, stateYawRate, 1)
+	return f.update(measurement{z: []float64{yawRate}, h: h, variance: []float64{variance}})
+}
+
+// stdDev returns the standard deviation of one element of the state.
+func (f *filter) stdDev(idx int) float64 {
+	return math.Sqrt(f.cov.At(idx, idx))
+}
+
+func symmetrize(m *mat.Dense) *mat.SymDense {
+	n, _ := m.Dims()
+	sym := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			sym.SetSym(i, j, (m.At(i, j)+m.At(j, i))/2)
+		}
+	}
+	return sym
+}
+
+// wrapAngle wraps an angle in radians to [-pi, pi).
+func wrapAngle(angle float64) float64 {
+	return math.Mod(math.Mod(angle+math.Pi, 2*math.Pi)+2*math.Pi, 2*math.Pi) - math.Pi
+}
@@ -0,0 +1,298 @@
+// Package gpsnmea implements a GPS movement sensor that reads NMEA-0183 sentences from a serial device or a TCP
+// socket, and optionally streams RTCM corrections to the receiver for RTK positioning.
+package gpsnmea
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/spatialmath"
+)
+
+var model = resource.DefaultModelFamily.WithModel("gps-nmea")
+
+const (
+	defaultBaudRate   = 38400
+	reconnectInterval = time.Second
+)
+
+var errClosed = errors.New("gps is closed")
+
+// Config is used for converting gps-nmea movement sensor attributes. Exactly one of SerialPath and TCPAddress must
+// be set.
+type Config struct {
+	SerialPath     string `json:"serial_path,omitempty"`
+	SerialBaudRate int    `json:"serial_baud_rate,omitempty"`
+	// TCPAddress is the host:port of a receiver, or of a serial to TCP bridge, that serves NMEA sentences.
+	TCPAddress string `json:"tcp_address,omitempty"`
+
+	RTCM *RTCMConfig `json:"rtcm,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if (cfg.SerialPath == "") == (cfg.TCPAddress == "") {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("exactly one of serial_path or tcp_address is required"))
+	}
+	if cfg.SerialBaudRate < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("serial_baud_rate cannot be negative"))
+	}
+	if cfg.RTCM != nil {
+		if err := cfg.RTCM.Validate(path); err != nil {
+			return nil, nil, resource.NewConfigValidationError(path, err)
+		}
+	}
+	return nil, nil, nil
+}
+
+func init() {
+	resource.RegisterComponent(
+		movementsensor.API,
+		model,
+		resource.Registration[movementsensor.MovementSensor, *Config]{Constructor: newGPSNMEA})
+}
+
+type gpsNMEA struct {
+	resource.Named
+	resource.AlwaysRebuild
+	logger logging.Logger
+	cfg    *Config
+
+	mu   sync.Mutex
+	data gpsData
+
+	connMu sync.Mutex
+	conn   io.ReadWriteCloser
+	closed bool
+
+	lastPosition       movementsensor.LastPosition
+	lastCompassHeading movementsensor.LastCompassHeading
+	err                movementsensor.LastError
+
+	workers *goutils.StoppableWorkers
+}
+
+func newGPSNMEA(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (
+	movementsensor.MovementSensor, error,
+) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	g := &gpsNMEA{
+		Named:              conf.ResourceName().AsNamed(),
+		logger:             logger,
+		cfg:                newConf,
+		data:               newGPSData(),
+		lastPosition:       movementsensor.NewLastPosition(),
+		lastCompassHeading: movementsensor.NewLastCompassHeading(),
+		err:                movementsensor.NewLastError(1, 1),
+	}
+	// Fail fast if the receiver cannot be reached at all; later disconnects are retried in the background.
+	conn, err := g.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g.conn = conn
+
+	g.workers = goutils.NewBackgroundStoppableWorkers(g.readNMEA, func(ctx context.Context) {
+		// Closing the connection interrupts a pending read.
+		<-ctx.Done()
+		g.connMu.Lock()
+		defer g.connMu.Unlock()
+		g.closed = true
+		if g.conn != nil {
+			goutils.UncheckedError(g.conn.Close())
+		}
+	})
+	if newConf.RTCM != nil {
+		g.workers.Add(func(ctx context.Context) {
+			g.streamCorrections(ctx, newConf.RTCM, correctionWriter{g})
+		})
+	}
+	return g, nil
+}
+
+func (g *gpsNMEA) open(ctx context.Context) (io.ReadWriteCloser, error) {
+	if g.cfg.TCPAddress != "" {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", g.cfg.TCPAddress)
+	}
+	baudRate := g.cfg.SerialBaudRate
+	if baudRate == 0 {
+		baudRate = defaultBaudRate
+	}
+	return openSerial(g.cfg.SerialPath, baudRate)
+}
+
+// readNMEA parses sentences from the receiver until the context is done, reconnecting whenever the connection drops.
+func (g *gpsNMEA) readNMEA(ctx context.Context) {
+	for {
+		g.connMu.Lock()
+		conn := g.conn
+		g.connMu.Unlock()
+
+		if conn != nil {
+			reader := bufio.NewReader(conn)
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					if ctx.Err() == nil {
+						g.logger.CWarnw(ctx, "lost connection to the gps, reconnecting", "error", err)
+						g.err.Set(err)
+					}
+					break
+				}
+				g.parse(ctx, line)
+			}
+			g.connMu.Lock()
+			goutils.UncheckedError(conn.Close())
+			g.conn = nil
+			g.connMu.Unlock()
+		}
+
+		if !goutils.SelectContextOrWait(ctx, reconnectInterval) {
+			return
+		}
+		conn, err := g.open(ctx)
+		if err != nil {
+			g.err.Set(err)
+			continue
+		}
+		g.connMu.Lock()
+		if g.closed {
+			g.connMu.Unlock()
+			goutils.UncheckedError(conn.Close())
+			return
+		}
+		g.conn = conn
+		g.connMu.Unlock()
+	}
+}
+
+func (g *gpsNMEA) parse(ctx context.Context, line string) {
+	// Receivers that share a port with RTCM output, or that were connected to mid-sentence, send partial lines.
+	if !strings.HasPrefix(strings.TrimSpace(line), "$") {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.data.parse(line); err != nil {
+		g.logger.CDebugw(ctx, "failed to parse NMEA sentence", "error", err)
+		return
+	}
+	if !movementsensor.IsPositionNaN(g.data.location) {
+		g.lastPosition.SetLastPosition(g.data.location)
+	}
+	if !math.IsNaN(g.data.course) {
+		g.lastCompassHeading.SetLastCompassHeading(g.data.course)
+	}
+}
+
+// correctionWriter writes RTCM corrections to the current connection to the receiver.
+type correctionWriter struct {
+	g *gpsNMEA
+}
+
+func (w correctionWriter) Write(p []byte) (int, error) {
+	w.g.connMu.Lock()
+	defer w.g.connMu.Unlock()
+	if w.g.closed {
+		return 0, errClosed
+	}
+	if w.g.conn == nil {
+		// Corrections are only useful while they are fresh, so drop them while reconnecting.
+		return len(p), nil
+	}
+	return w.g.conn.Write(p)
+}
+
+// Position returns the latest position fix. If the receiver loses its fix, the last known position is returned.
+func (g *gpsNMEA) Position(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if movementsensor.IsPositionNaN(g.data.location) {
+		return g.lastPosition.GetLastPosition(), g.data.alt, g.err.Get()
+	}
+	return g.data.location, g.data.alt, g.err.Get()
+}
+
+// LinearVelocity returns the speed over ground along Y.
+func (g *gpsNMEA) LinearVelocity(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return r3.Vector{Y: g.data.speed}, g.err.Get()
+}
+
+func (g *gpsNMEA) LinearAcceleration(ctx context.Context, extra map[string]interface{}) (r3.Vector, error) {
+	return r3.Vector{}, movementsensor.ErrMethodUnimplementedLinearAcceleration
+}
+
+func (g *gpsNMEA) AngularVelocity(ctx context.Context, extra map[string]interface{}) (spatialmath.AngularVelocity, error) {
+	return spatialmath.AngularVelocity{}, movementsensor.ErrMethodUnimplementedAngularVelocity
+}
+
+// CompassHeading returns the last known course over ground, which is NaN until the receiver has moved.
+func (g *gpsNMEA) CompassHeading(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	return g.lastCompassHeading.GetLastCompassHeading(), g.err.Get()
+}
+
+func (g *gpsNMEA) Orientation(ctx context.Context, extra map[string]interface{}) (spatialmath.Orientation, error) {
+	return spatialmath.NewZeroOrientation(), movementsensor.ErrMethodUnimplementedOrientation
+}
+
+// Accuracy returns the dilutions of precision and the GGA fix quality. The GSA fix type, the position dilution of
+// precision and the number of satellites in use are reported in the accuracy map.
+func (g *gpsNMEA) Accuracy(ctx context.Context, extra map[string]interface{}) (*movementsensor.Accuracy, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	acc := movementsensor.UnimplementedOptionalAccuracies()
+	acc.AccuracyMap = map[string]float32{
+		"fix_type":          float32(g.data.fixType),
+		"pdop":              float32(g.data.pdop),
+		"satellites_in_use": float32(g.data.satsInUse),
+	}
+	acc.Hdop = float32(g.data.hdop)
+	acc.Vdop = float32(g.data.vdop)
+	acc.NmeaFix = int32(g.data.fixQuality)
+	return acc, g.err.Get()
+}
+
+func (g *gpsNMEA) Properties(ctx context.Context, extra map[string]interface{}) (*movementsensor.Properties, error) {
+	return &movementsensor.Properties{
+		PositionSupported:       true,
+		LinearVelocitySupported: true,
+		CompassHeadingSupported: true,
+	}, nil
+}
+
+func (g *gpsNMEA) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	readings, err := movementsensor.DefaultAPIReadings(ctx, g, extra)
+	if err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	readings["fix"] = g.data.fixQuality
+	readings["satellites_in_use"] = g.data.satsInUse
+	return readings, nil
+}
+
+func (g *gpsNMEA) Close(ctx context.Context) error {
+	g.workers.Stop()
+	return nil
+}
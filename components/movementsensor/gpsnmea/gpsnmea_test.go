@@ -0,0 +1,302 @@
+package gpsnmea
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+var testCorrections = []byte{0xd3, 0x00, 0x13, 0x3e, 0xd0, 0x00, 0x03, 0x8a, 0x0e, 0xde, 0xef, 0x34, 0xb4, 0xbd}
+
+func newTestGPS(t *testing.T, cfg *Config) movementsensor.MovementSensor {
+	t.Helper()
+	_, _, err := cfg.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	gps, err := newGPSNMEA(context.Background(), nil, resource.Config{
+		Name:                "gps",
+		API:                 movementsensor.API,
+		Model:               model,
+		ConvertedAttributes: cfg,
+	}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return gps
+}
+
+// acceptOne accepts a single connection from the listener.
+func acceptOne(t *testing.T, listener net.Listener) net.Conn {
+	t.Helper()
+	conn, err := listener.Accept()
+	test.That(t, err, test.ShouldBeNil)
+	return conn
+}
+
+// readCorrections reads len(testCorrections) bytes from the receiver end of a connection.
+func readCorrections(t *testing.T, r io.Reader) []byte {
+	t.Helper()
+	received := make([]byte, len(testCorrections))
+	_, err := io.ReadFull(r, received)
+	test.That(t, err, test.ShouldBeNil)
+	return received
+}
+
+// checkPosition sends sentences to the gps and waits for it to report their position.
+func checkPosition(t *testing.T, gps movementsensor.MovementSensor, receiver io.Writer) {
+	t.Helper()
+	_, err := io.WriteString(receiver, testGGA+"\r\n"+testGSA+"\r\n"+testVTG+"\r\n")
+	test.That(t, err, test.ShouldBeNil)
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		pt, alt, err := gps.Position(context.Background(), nil)
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, pt.Lat(), test.ShouldAlmostEqual, testLat)
+		test.That(tb, pt.Lng(), test.ShouldAlmostEqual, testLng)
+		test.That(tb, alt, test.ShouldAlmostEqual, 545.4)
+		heading, err := gps.CompassHeading(context.Background(), nil)
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, heading, test.ShouldAlmostEqual, 54.7)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"serial", Config{SerialPath: "/dev/ttyACM0"}, ""},
+		{"tcp with ntrip", Config{TCPAddress: "localhost:5000", RTCM: &RTCMConfig{NtripURL: "http://u:p@caster:2101/MOUNT"}}, ""},
+		{"no connection", Config{}, "exactly one of serial_path or tcp_address"},
+		{"both connections", Config{SerialPath: "/dev/ttyACM0", TCPAddress: "localhost:5000"}, "exactly one of"},
+		{"negative baud rate", Config{SerialPath: "/dev/ttyACM0", SerialBaudRate: -1}, "serial_baud_rate"},
+		{"no corrections", Config{SerialPath: "/dev/ttyACM0", RTCM: &RTCMConfig{}}, "exactly one of file or ntrip_url"},
+		{"ntrip without mountpoint", Config{SerialPath: "/dev/ttyACM0", RTCM: &RTCMConfig{NtripURL: "http://caster:2101"}}, "mountpoint"},
+		{"ntrip over https", Config{SerialPath: "/dev/ttyACM0", RTCM: &RTCMConfig{NtripURL: "https://caster/MOUNT"}}, "http URL"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := tc.cfg.Validate("path")
+			if tc.err == "" {
+				test.That(t, err, test.ShouldBeNil)
+			} else {
+				test.That(t, err, test.ShouldNotBeNil)
+				test.That(t, err.Error(), test.ShouldContainSubstring, tc.err)
+			}
+		})
+	}
+}
+
+func TestTCP(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "localhost:0")
+	test.That(t, err, test.ShouldBeNil)
+	defer listener.Close()
+
+	gps := newTestGPS(t, &Config{TCPAddress: listener.Addr().String()})
+	defer gps.Close(ctx)
+	receiver := acceptOne(t, listener)
+
+	// Before a fix, the position is unknown.
+	pt, _, err := gps.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, movementsensor.IsPositionNaN(pt), test.ShouldBeTrue)
+
+	// Partial sentences and garbage are skipped.
+	_, err = io.WriteString(receiver, "545.4,M,46.9,M,,*47\r\n\xd3\x00\x13\r\n")
+	test.That(t, err, test.ShouldBeNil)
+	checkPosition(t, gps, receiver)
+
+	vel, err := gps.LinearVelocity(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, vel.Y, test.ShouldAlmostEqual, 10.2/3.6)
+	acc, err := gps.Accuracy(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, acc.Hdop, test.ShouldAlmostEqual, 1.3, 1e-6)
+	test.That(t, acc.Vdop, test.ShouldAlmostEqual, 2.1, 1e-6)
+	test.That(t, acc.NmeaFix, test.ShouldEqual, 1)
+	test.That(t, acc.AccuracyMap["fix_type"], test.ShouldEqual, 3)
+	test.That(t, acc.AccuracyMap["satellites_in_use"], test.ShouldEqual, 8)
+	readings, err := gps.Readings(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readings["fix"], test.ShouldEqual, 1)
+	test.That(t, readings["compass"], test.ShouldAlmostEqual, 54.7)
+
+	// Losing the fix keeps the last known position.
+	_, err = io.WriteString(receiver, withChecksum("GPGGA,123520,,,,,0,00,99.9,,M,,M,,"))
+	test.That(t, err, test.ShouldBeNil)
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		acc, err := gps.Accuracy(ctx, nil)
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, acc.NmeaFix, test.ShouldEqual, 0)
+	})
+	pt, alt, err := gps.Position(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, pt.Lat(), test.ShouldAlmostEqual, testLat)
+	test.That(t, math.IsNaN(alt), test.ShouldBeTrue)
+
+	// Dropped connections are reestablished.
+	test.That(t, receiver.Close(), test.ShouldBeNil)
+	receiver = acceptOne(t, listener)
+	defer receiver.Close()
+	_, err = io.WriteString(receiver, withChecksum("GPGGA,123521,4000.000,S,07000.000,W,2,10,0.8,12.0,M,,M,,"))
+	test.That(t, err, test.ShouldBeNil)
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		pt, _, err := gps.Position(ctx, nil)
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, pt.Lat(), test.ShouldAlmostEqual, -40)
+	})
+}
+
+func TestNewGPSNMEAUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	test.That(t, err, test.ShouldBeNil)
+	addr := listener.Addr().String()
+	test.That(t, listener.Close(), test.ShouldBeNil)
+
+	_, err = newGPSNMEA(context.Background(), nil, resource.Config{
+		Name:                "gps",
+		API:                 movementsensor.API,
+		Model:               model,
+		ConvertedAttributes: &Config{TCPAddress: addr},
+	}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestRTCM(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "corrections.rtcm3")
+		test.That(t, os.WriteFile(file, testCorrections, 0o600), test.ShouldBeNil)
+		listener, err := net.Listen("tcp", "localhost:0")
+		test.That(t, err, test.ShouldBeNil)
+		defer listener.Close()
+
+		gps := newTestGPS(t, &Config{TCPAddress: listener.Addr().String(), RTCM: &RTCMConfig{File: file}})
+		defer gps.Close(ctx)
+		receiver := acceptOne(t, listener)
+		defer receiver.Close()
+		test.That(t, readCorrections(t, receiver), test.ShouldResemble, testCorrections)
+	})
+
+	t.Run("NTRIP 1.0 caster", func(t *testing.T) {
+		caster, err := net.Listen("tcp", "localhost:0")
+		test.That(t, err, test.ShouldBeNil)
+		defer caster.Close()
+		requests := make(chan []string, 2)
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for i := 0; i < 2; i++ {
+				conn, err := caster.Accept()
+				if err != nil {
+					return
+				}
+				var request []string
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil || line == "\r\n" {
+						break
+					}
+					request = append(request, strings.TrimSpace(line))
+				}
+				requests <- request
+				// The first connection drops after a single message, and the client reconnects.
+				if _, err := conn.Write(append([]byte("ICY 200 OK\r\n"), testCorrections...)); err != nil {
+					return
+				}
+				if i == 0 {
+					conn.Close()
+				} else {
+					defer conn.Close()
+				}
+			}
+			<-done
+		}()
+
+		listener, err := net.Listen("tcp", "localhost:0")
+		test.That(t, err, test.ShouldBeNil)
+		defer listener.Close()
+		gps := newTestGPS(t, &Config{
+			TCPAddress: listener.Addr().String(),
+			RTCM:       &RTCMConfig{NtripURL: "http://rover:secret@" + caster.Addr().String() + "/MOUNT1"},
+		})
+		defer gps.Close(ctx)
+		receiver := acceptOne(t, listener)
+		defer receiver.Close()
+
+		for i := 0; i < 2; i++ {
+			request := <-requests
+			test.That(t, request[0], test.ShouldEqual, "GET /MOUNT1 HTTP/1.1")
+			// base64("rover:secret")
+			test.That(t, request, test.ShouldContain, "Authorization: Basic cm92ZXI6c2VjcmV0")
+			test.That(t, readCorrections(t, receiver), test.ShouldResemble, testCorrections)
+		}
+
+		// Corrections do not get in the way of reading sentences.
+		checkPosition(t, gps, receiver)
+	})
+
+	t.Run("NTRIP 2.0 caster", func(t *testing.T) {
+		caster := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			if !ok || user != "rover" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Path != "/MOUNT2" || r.Header.Get("Ntrip-Version") != "Ntrip/2.0" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			// Flushing before the end of the response makes it chunked.
+			for _, b := range testCorrections {
+				if _, err := w.Write([]byte{b}); err != nil {
+					return
+				}
+				w.(http.Flusher).Flush()
+			}
+			<-r.Context().Done()
+		}))
+		defer caster.Close()
+
+		listener, err := net.Listen("tcp", "localhost:0")
+		test.That(t, err, test.ShouldBeNil)
+		defer listener.Close()
+		gps := newTestGPS(t, &Config{
+			TCPAddress: listener.Addr().String(),
+			RTCM:       &RTCMConfig{NtripURL: strings.Replace(caster.URL, "http://", "http://rover:secret@", 1) + "/MOUNT2"},
+		})
+		receiver := acceptOne(t, listener)
+		defer receiver.Close()
+		test.That(t, readCorrections(t, receiver), test.ShouldResemble, testCorrections)
+
+		// Closing stops the stream promptly, even though the caster never ends it.
+		closed := make(chan struct{})
+		go func() {
+			gps.Close(ctx)
+			close(closed)
+		}()
+		select {
+		case <-closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out closing the gps")
+		}
+	})
+}
@@ -0,0 +1,245 @@
+package gpsnmea
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	geo "github.com/kellydunn/golang-geo"
+	"github.com/pkg/errors"
+)
+
+const (
+	knotsToMPerSec = 0.514444
+	kphToMPerSec   = 1 / 3.6
+)
+
+// sentence is a checksummed NMEA-0183 sentence, such as $GNGGA,...*4F.
+type sentence struct {
+	talker string // GP, GN, GL, ...
+	kind   string // GGA, RMC, ...
+	fields []string
+}
+
+// parseSentence splits a line into a sentence, verifying its checksum if it has one.
+func parseSentence(line string) (*sentence, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, errors.Errorf("NMEA sentence %q does not start with $", line)
+	}
+	body := line[1:]
+	if idx := strings.LastIndex(body, "*"); idx >= 0 {
+		expected, err := strconv.ParseUint(body[idx+1:], 16, 8)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid checksum in NMEA sentence %q", line)
+		}
+		body = body[:idx]
+		if checksum := nmeaChecksum(body); checksum != byte(expected) {
+			return nil, errors.Errorf("NMEA sentence %q has checksum %02X, expected %02X", line, checksum, expected)
+		}
+	}
+	fields := strings.Split(body, ",")
+	if len(fields[0]) != 5 {
+		return nil, errors.Errorf("NMEA sentence %q has an invalid address %q", line, fields[0])
+	}
+	return &sentence{talker: fields[0][:2], kind: fields[0][2:], fields: fields[1:]}, nil
+}
+
+// nmeaChecksum XORs together the bytes between the $ and the * of a sentence.
+func nmeaChecksum(body string) byte {
+	var checksum byte
+	for i := 0; i < len(body); i++ {
+		checksum ^= body[i]
+	}
+	return checksum
+}
+
+// field returns the field at idx, or an empty string if the sentence is too short to have it.
+func (s *sentence) field(idx int) string {
+	if idx >= len(s.fields) {
+		return ""
+	}
+	return s.fields[idx]
+}
+
+// float parses the field at idx, returning NaN if it is empty.
+func (s *sentence) float(idx int) (float64, error) {
+	f := s.field(idx)
+	if f == "" {
+		return math.NaN(), nil
+	}
+	v, err := strconv.ParseFloat(f, 64)
+	if err != nil {
+		return math.NaN(), errors.Wrapf(err, "invalid field %d of %s", idx, s.kind)
+	}
+	return v, nil
+}
+
+// location parses the latitude and longitude fields starting at idx, in the form ddmm.mmmm,N,dddmm.mmmm,E.
+func (s *sentence) location(idx int) (*geo.Point, error) {
+	lat, err := parseDegrees(s.field(idx), s.field(idx+1), "N", "S")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid latitude in %s", s.kind)
+	}
+	lng, err := parseDegrees(s.field(idx+2), s.field(idx+3), "E", "W")
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid longitude in %s", s.kind)
+	}
+	return geo.NewPoint(lat, lng), nil
+}
+
+// parseDegrees converts an NMEA angle in degrees and decimal minutes, with its hemisphere, to decimal degrees.
+func parseDegrees(value, hemisphere, positive, negative string) (float64, error) {
+	if value == "" {
+		return math.NaN(), nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return math.NaN(), err
+	}
+	degrees := math.Floor(v / 100)
+	degrees += (v - degrees*100) / 60
+	switch hemisphere {
+	case positive:
+		return degrees, nil
+	case negative:
+		return -degrees, nil
+	default:
+		return math.NaN(), errors.Errorf("invalid hemisphere %q", hemisphere)
+	}
+}
+
+// gpsData is the state of a receiver, accumulated from the sentences it has sent.
+type gpsData struct {
+	location   *geo.Point // NaN without a fix
+	alt        float64    // meters above mean sea level
+	fixQuality int        // from GGA, 0 is no fix, 4 is RTK fixed and 5 is RTK float
+	fixType    int        // from GSA, 1 is no fix, 2 is 2D and 3 is 3D
+	satsInUse  int
+	hdop       float64
+	vdop       float64
+	pdop       float64
+	speed      float64 // m/s over ground
+	course     float64 // degrees clockwise from true north, NaN if unknown
+	ggaSeen    bool    // whether any GGA has been parsed, making it the only source of the location
+}
+
+func newGPSData() gpsData {
+	return gpsData{
+		location: geo.NewPoint(math.NaN(), math.NaN()),
+		alt:      math.NaN(),
+		hdop:     math.NaN(),
+		vdop:     math.NaN(),
+		pdop:     math.NaN(),
+		speed:    math.NaN(),
+		course:   math.NaN(),
+	}
+}
+
+// parse updates the data from a line sent by the receiver. Sentences other than GGA, RMC, VTG and GSA are ignored.
+func (d *gpsData) parse(line string) error {
+	s, err := parseSentence(line)
+	if err != nil {
+		return err
+	}
+	switch s.kind {
+	case "GGA":
+		return d.parseGGA(s)
+	case "RMC":
+		return d.parseRMC(s)
+	case "VTG":
+		return d.parseVTG(s)
+	case "GSA":
+		return d.parseGSA(s)
+	default:
+		return nil
+	}
+}
+
+// parseGGA parses time,lat,N,lon,E,quality,satellites,hdop,alt,M,separation,M,age,station.
+func (d *gpsData) parseGGA(s *sentence) error {
+	d.ggaSeen = true
+	quality, err := strconv.Atoi(s.field(5))
+	if err != nil {
+		return errors.Wrap(err, "invalid fix quality in GGA")
+	}
+	d.fixQuality = quality
+	if sats, err := strconv.Atoi(s.field(6)); err == nil {
+		d.satsInUse = sats
+	}
+	if d.hdop, err = s.float(7); err != nil {
+		return err
+	}
+	if quality == 0 {
+		d.location = geo.NewPoint(math.NaN(), math.NaN())
+		d.alt = math.NaN()
+		return nil
+	}
+	if d.location, err = s.location(1); err != nil {
+		return err
+	}
+	d.alt, err = s.float(8)
+	return err
+}
+
+// parseRMC parses time,status,lat,N,lon,E,speed,course,date,variation,E,mode.
+func (d *gpsData) parseRMC(s *sentence) error {
+	if s.field(1) != "A" {
+		if !d.ggaSeen {
+			d.location = geo.NewPoint(math.NaN(), math.NaN())
+		}
+		return nil
+	}
+	if !d.ggaSeen {
+		location, err := s.location(2)
+		if err != nil {
+			return err
+		}
+		d.location = location
+	}
+	knots, err := s.float(6)
+	if err != nil {
+		return err
+	}
+	d.speed = knots * knotsToMPerSec
+	d.course, err = s.float(7)
+	return err
+}
+
+// parseVTG parses courseTrue,T,courseMagnetic,M,knots,N,kph,K,mode.
+func (d *gpsData) parseVTG(s *sentence) error {
+	course, err := s.float(0)
+	if err != nil {
+		return err
+	}
+	kph, err := s.float(6)
+	if err != nil {
+		return err
+	}
+	if !math.IsNaN(kph) {
+		d.speed = kph * kphToMPerSec
+	}
+	d.course = course
+	return nil
+}
+
+// parseGSA parses mode,fixType, twelve satellite ids, pdop,hdop,vdop.
+func (d *gpsData) parseGSA(s *sentence) error {
+	fixType, err := strconv.Atoi(s.field(1))
+	if err != nil {
+		return errors.Wrap(err, "invalid fix type in GSA")
+	}
+	d.fixType = fixType
+	if d.pdop, err = s.float(14); err != nil {
+		return err
+	}
+	hdop, err := s.float(15)
+	if err != nil {
+		return err
+	}
+	if !math.IsNaN(hdop) {
+		d.hdop = hdop
+	}
+	d.vdop, err = s.float(16)
+	return err
+}
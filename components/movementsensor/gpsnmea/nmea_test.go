@@ -0,0 +1,111 @@
+package gpsnmea
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+// Sentences from the NMEA examples in the gpsd documentation.
+const (
+	testGGA = "$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*47"
+	testRMC = "$GPRMC,123519,A,4807.038,N,01131.000,E,022.4,084.4,230394,003.1,W*6A"
+	testVTG = "$GPVTG,054.7,T,034.4,M,005.5,N,010.2,K*48"
+	testGSA = "$GPGSA,A,3,04,05,,09,12,,,24,,,,,2.5,1.3,2.1*39"
+
+	testLat = 48 + 7.038/60
+	testLng = 11 + 31.0/60
+)
+
+// withChecksum turns the body of a sentence, without its $ and checksum, into a complete sentence.
+func withChecksum(body string) string {
+	return fmt.Sprintf("$%s*%02X\r\n", body, nmeaChecksum(body))
+}
+
+func TestParseSentence(t *testing.T) {
+	s, err := parseSentence(testGGA + "\r\n")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, s.talker, test.ShouldEqual, "GP")
+	test.That(t, s.kind, test.ShouldEqual, "GGA")
+	test.That(t, s.fields, test.ShouldHaveLength, 14)
+
+	// The checksum is optional.
+	s, err = parseSentence("$GNVTG,054.7,T,034.4,M,005.5,N,010.2,K")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, s.talker, test.ShouldEqual, "GN")
+
+	_, err = parseSentence("$GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,*48")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "checksum")
+	_, err = parseSentence("GPGGA,123519")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = parseSentence(withChecksum("GGA,123519"))
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestGPSData(t *testing.T) {
+	t.Run("GGA, GSA and VTG", func(t *testing.T) {
+		d := newGPSData()
+		test.That(t, d.parse(testGGA), test.ShouldBeNil)
+		test.That(t, d.location.Lat(), test.ShouldAlmostEqual, testLat)
+		test.That(t, d.location.Lng(), test.ShouldAlmostEqual, testLng)
+		test.That(t, d.alt, test.ShouldAlmostEqual, 545.4)
+		test.That(t, d.fixQuality, test.ShouldEqual, 1)
+		test.That(t, d.satsInUse, test.ShouldEqual, 8)
+		test.That(t, d.hdop, test.ShouldAlmostEqual, 0.9)
+
+		test.That(t, d.parse(testGSA), test.ShouldBeNil)
+		test.That(t, d.fixType, test.ShouldEqual, 3)
+		test.That(t, d.pdop, test.ShouldAlmostEqual, 2.5)
+		test.That(t, d.hdop, test.ShouldAlmostEqual, 1.3)
+		test.That(t, d.vdop, test.ShouldAlmostEqual, 2.1)
+
+		test.That(t, d.parse(testVTG), test.ShouldBeNil)
+		test.That(t, d.course, test.ShouldAlmostEqual, 54.7)
+		test.That(t, d.speed, test.ShouldAlmostEqual, 10.2/3.6)
+
+		// Once a GGA has been seen, the location comes only from GGA.
+		test.That(t, d.parse(withChecksum("GPRMC,123520,A,4000.000,S,07000.000,W,001.0,090.0,230394,,")), test.ShouldBeNil)
+		test.That(t, d.location.Lat(), test.ShouldAlmostEqual, testLat)
+		test.That(t, d.course, test.ShouldAlmostEqual, 90)
+	})
+
+	t.Run("RMC alone", func(t *testing.T) {
+		d := newGPSData()
+		test.That(t, d.parse(testRMC), test.ShouldBeNil)
+		test.That(t, d.location.Lat(), test.ShouldAlmostEqual, testLat)
+		test.That(t, d.location.Lng(), test.ShouldAlmostEqual, testLng)
+		test.That(t, d.speed, test.ShouldAlmostEqual, 22.4*knotsToMPerSec)
+		test.That(t, d.course, test.ShouldAlmostEqual, 84.4)
+
+		test.That(t, d.parse(withChecksum("GPRMC,123520,V,,,,,,,230394,,")), test.ShouldBeNil)
+		test.That(t, math.IsNaN(d.location.Lat()), test.ShouldBeTrue)
+	})
+
+	t.Run("southern and western hemispheres", func(t *testing.T) {
+		d := newGPSData()
+		test.That(t, d.parse(withChecksum("GNGGA,000000,3352.128,S,15112.558,W,4,12,0.5,10.0,M,0,M,1.0,0000")), test.ShouldBeNil)
+		test.That(t, d.location.Lat(), test.ShouldAlmostEqual, -(33 + 52.128/60))
+		test.That(t, d.location.Lng(), test.ShouldAlmostEqual, -(151 + 12.558/60))
+		test.That(t, d.fixQuality, test.ShouldEqual, 4)
+	})
+
+	t.Run("losing the fix", func(t *testing.T) {
+		d := newGPSData()
+		test.That(t, d.parse(testGGA), test.ShouldBeNil)
+		test.That(t, d.parse(withChecksum("GPGGA,123520,,,,,0,00,99.9,,M,,M,,")), test.ShouldBeNil)
+		test.That(t, d.fixQuality, test.ShouldEqual, 0)
+		test.That(t, math.IsNaN(d.location.Lat()), test.ShouldBeTrue)
+		test.That(t, math.IsNaN(d.alt), test.ShouldBeTrue)
+	})
+
+	t.Run("malformed sentences", func(t *testing.T) {
+		d := newGPSData()
+		test.That(t, d.parse(withChecksum("GPGGA,123519,4807.038,Q,01131.000,E,1,08,0.9,545.4,M,46.9,M,,")), test.ShouldNotBeNil)
+		test.That(t, d.parse(withChecksum("GPGSA,A,,,,")), test.ShouldNotBeNil)
+		// Sentences that are not used are ignored.
+		test.That(t, d.parse(withChecksum("GPGSV,3,1,11,03,03,111,00")), test.ShouldBeNil)
+	})
+}
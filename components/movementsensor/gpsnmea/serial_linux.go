@@ -0,0 +1,66 @@
+//go:build linux
+
+package gpsnmea
+
+import (
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+	460800: unix.B460800,
+	921600: unix.B921600,
+}
+
+// openSerial opens a serial device in raw mode, 8N1, at the given baud rate.
+func openSerial(path string, baudRate int) (io.ReadWriteCloser, error) {
+	rate, ok := baudRates[baudRate]
+	if !ok {
+		return nil, errors.Errorf("unsupported baud rate %d", baudRate)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	rawConn, err := f.SyscallConn()
+	if err != nil {
+		return nil, multierr.Combine(err, f.Close())
+	}
+	// Control, rather than Fd, keeps the file in non-blocking mode so that closing it interrupts a pending read.
+	var termiosErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		var t *unix.Termios
+		t, termiosErr = unix.IoctlGetTermios(int(fd), unix.TCGETS)
+		if termiosErr != nil {
+			return
+		}
+		// The equivalent of cfmakeraw.
+		t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+		t.Oflag &^= unix.OPOST
+		t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+		t.Cflag &^= unix.CSIZE | unix.PARENB | unix.CSTOPB | unix.CBAUD
+		t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | rate
+		t.Ispeed = rate
+		t.Ospeed = rate
+		t.Cc[unix.VMIN] = 1
+		t.Cc[unix.VTIME] = 0
+		termiosErr = unix.IoctlSetTermios(int(fd), unix.TCSETS, t)
+	}); err != nil {
+		return nil, multierr.Combine(err, f.Close())
+	}
+	if termiosErr != nil {
+		return nil, multierr.Combine(errors.Wrapf(termiosErr, "failed to configure serial device %s", path), f.Close())
+	}
+	return f, nil
+}
@@ -0,0 +1,34 @@
+//go:build linux
+
+package gpsnmea
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/x/xpty"
+	"go.viam.com/test"
+)
+
+func TestSerial(t *testing.T) {
+	ctx := context.Background()
+	// The master end of a pty stands in for the receiver, and the gps opens the slave end as its serial device.
+	pty, err := xpty.NewUnixPty(80, 24)
+	test.That(t, err, test.ShouldBeNil)
+	defer pty.Close()
+
+	file := filepath.Join(t.TempDir(), "corrections.rtcm3")
+	test.That(t, os.WriteFile(file, testCorrections, 0o600), test.ShouldBeNil)
+
+	gps := newTestGPS(t, &Config{SerialPath: pty.SlaveName(), SerialBaudRate: 115200, RTCM: &RTCMConfig{File: file}})
+	defer gps.Close(ctx)
+
+	// The device is in raw mode, so binary corrections arrive unmodified.
+	test.That(t, readCorrections(t, pty.Master()), test.ShouldResemble, testCorrections)
+	checkPosition(t, gps, pty.Master())
+
+	_, err = openSerial(pty.SlaveName(), 1234)
+	test.That(t, err, test.ShouldNotBeNil)
+}
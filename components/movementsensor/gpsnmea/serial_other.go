@@ -0,0 +1,14 @@
+//go:build !linux
+
+package gpsnmea
+
+import (
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// openSerial is only implemented on linux. Elsewhere, connect to the receiver over TCP instead.
+func openSerial(path string, baudRate int) (io.ReadWriteCloser, error) {
+	return nil, errors.New("serial devices are only supported on linux, use tcp_address instead")
+}
@@ -0,0 +1,68 @@
+package replay
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/utils/contextutils"
+)
+
+// initializeLocal replays each method from the capture files in the configured capture directory, and sets the
+// properties of the movement sensor to the methods that have data there. It assumes the write lock is being held.
+func (replay *replayMovementSensor) initializeLocal(cfg *Config) error {
+	keyring, err := cfg.Encryption.Keyring()
+	if err != nil {
+		return err
+	}
+	opts := data.ReplayOptions{Loop: cfg.Loop, PlaybackRate: cfg.PlaybackRate, Keyring: keyring}
+	if cfg.Interval.Start != "" {
+		if opts.Start, err = time.Parse(timeFormat, cfg.Interval.Start); err != nil {
+			return errors.New("invalid time format for start time, missed during config validation")
+		}
+	}
+	if cfg.Interval.End != "" {
+		if opts.End, err = time.Parse(timeFormat, cfg.Interval.End); err != nil {
+			return errors.New("invalid time format for end time, missed during config validation")
+		}
+	}
+
+	replay.local = map[method]*data.CaptureReplayer{}
+	anyData := false
+	for _, m := range methodList {
+		r, err := data.NewCaptureReplayer(cfg.CaptureDir, cfg.Source, string(m), opts)
+		if err != nil {
+			return err
+		}
+		replay.local[m] = r
+		anyData = anyData || r.Len() != 0
+		if err := replay.setProperty(m, r.Len() != 0); err != nil {
+			return err
+		}
+	}
+	if !anyData {
+		return errors.Wrap(errors.New(errMessageNoDataAvailable), errPropertiesFailedToInitialize.Error())
+	}
+	return nil
+}
+
+// getDataFromCaptureDir retrieves the next reading of the method from the capture directory. It assumes the write
+// lock is being held.
+func (replay *replayMovementSensor) getDataFromCaptureDir(ctx context.Context, method method) (*structpb.Struct, error) {
+	reading, err := replay.local[method].Next(ctx)
+	if err != nil {
+		if errors.Is(err, data.ErrEndOfCapture) {
+			return nil, ErrEndOfDataset
+		}
+		return nil, err
+	}
+
+	if err := contextutils.AddTimestampsToGRPCHeader(ctx, reading.GetMetadata().GetTimeRequested(), reading.GetMetadata().GetTimeReceived()); err != nil {
+		return nil, errors.Wrapf(err, "adding GRPC metadata failed")
+	}
+
+	return reading.GetStruct(), nil
+}
@@ -15,12 +15,11 @@ import (
 	goutils "go.viam.com/utils"
 	"go.viam.com/utils/rpc"
 	"golang.org/x/exp/maps"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/internal/cloud"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/resource"
@@ -90,22 +89,35 @@ func (cfg *Config) Validate(path string) ([]string, []string, error) {
 		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "source")
 	}
 
-	if cfg.RobotID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "robot_id")
-	}
+	// Data read from a local capture directory needs no cloud connection.
+	if cfg.CaptureDir == "" {
+		if cfg.RobotID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "robot_id")
+		}
 
-	if cfg.LocationID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "location_id")
-	}
+		if cfg.LocationID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "location_id")
+		}
 
-	if cfg.OrganizationID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "organization_id")
+		if cfg.OrganizationID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "organization_id")
+		}
+		if cfg.APIKey == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key")
+		}
+		if cfg.APIKeyID == "" {
+			return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key_id")
+		}
 	}
-	if cfg.APIKey == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key")
+
+	if cfg.Encryption != nil {
+		if err := cfg.Encryption.Validate(); err != nil {
+			return nil, nil, resource.NewConfigValidationError(path, err)
+		}
 	}
-	if cfg.APIKeyID == "" {
-		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "api_key_id")
+
+	if cfg.PlaybackRate < 0 {
+		return nil, nil, errors.New("playback_rate must not be negative")
 	}
 
 	var err error
@@ -133,6 +145,9 @@ func (cfg *Config) Validate(path string) ([]string, []string, error) {
 		return nil, nil, errors.Errorf("batch_size must be between 1 and %d", maxCacheSize)
 	}
 
+	if cfg.CaptureDir != "" {
+		return nil, nil, nil
+	}
 	return []string{cloud.InternalServiceName.String()}, nil, nil
 }
 
@@ -146,6 +161,17 @@ type Config struct {
 	BatchSize      *uint64      `json:"batch_size,omitempty"`
 	APIKey         string       `json:"api_key,omitempty"`
 	APIKeyID       string       `json:"api_key_id,omitempty"`
+
+	// CaptureDir, if set, replays the capture files written by data capture to this local directory instead of data
+	// from the cloud.
+	CaptureDir string `json:"capture_dir,omitempty"`
+	// Loop restarts from the beginning of the data in CaptureDir once it has all been replayed.
+	Loop bool `json:"loop,omitempty"`
+	// PlaybackRate, if set, replays the data in CaptureDir at this multiple of the speed it was captured at, returning
+	// the latest reading at each call. Otherwise each call returns the next reading.
+	PlaybackRate float64 `json:"playback_rate,omitempty"`
+	// Encryption holds the keys the capture files in CaptureDir were encrypted with, if data capture encrypts them.
+	Encryption *data.EncryptionConfig `json:"encryption,omitempty"`
 }
 
 // TimeInterval holds the start and end time used to filter data.
@@ -178,6 +204,8 @@ type replayMovementSensor struct {
 	filter   *datapb.Filter
 
 	cache map[method][]*cacheEntry
+	// local replays each method from a local capture directory, if one is configured.
+	local map[method]*data.CaptureReplayer
 
 	mu         sync.RWMutex
 	closed     bool
@@ -404,6 +432,14 @@ func (replay *replayMovementSensor) reconfigure(ctx context.Context, deps resour
 		return err
 	}
 
+	if replayMovementSensorConfig.CaptureDir != "" {
+		replay.closeCloudConnection(ctx)
+		replay.cloudConnSvc = nil
+		replay.cloudConn = nil
+		return replay.initializeLocal(replayMovementSensorConfig)
+	}
+	replay.local = nil
+
 	replay.APIKey = replayMovementSensorConfig.APIKey
 	replay.APIKeyID = replayMovementSensorConfig.APIKeyID
 
@@ -517,24 +553,6 @@ func (replay *replayMovementSensor) updateCache(ctx context.Context, method meth
 	return nil
 }
 
-// addGRPCMetadata adds timestamps from the data response to the gRPC response header if one is found in the context.
-func addGRPCMetadata(ctx context.Context, timeRequested, timeReceived *timestamppb.Timestamp) error {
-	if stream := grpc.ServerTransportStreamFromContext(ctx); stream != nil {
-		var grpcMetadata metadata.MD = make(map[string][]string)
-		if timeRequested != nil {
-			grpcMetadata.Set(contextutils.TimeRequestedMetadataKey, timeRequested.AsTime().Format(time.RFC3339Nano))
-		}
-		if timeReceived != nil {
-			grpcMetadata.Set(contextutils.TimeReceivedMetadataKey, timeReceived.AsTime().Format(time.RFC3339Nano))
-		}
-		if err := grpc.SetHeader(ctx, grpcMetadata); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func (replay *replayMovementSensor) setProperty(method method, supported bool) error {
 	switch method {
 	case position:
@@ -609,6 +627,10 @@ func (replay *replayMovementSensor) initializeProperties(ctx context.Context) er
 
 // getDataFromCache retrieves the next cached data and removes it from the cache. It assumes the write lock is being held.
 func (replay *replayMovementSensor) getDataFromCache(ctx context.Context, method method) (*structpb.Struct, error) {
+	if replay.local != nil {
+		return replay.getDataFromCaptureDir(ctx, method)
+	}
+
 	// If no data remains in the cache, download a new batch of data
 	if len(replay.cache[method]) == 0 {
 		if err := replay.updateCache(ctx, method); err != nil {
@@ -621,7 +643,7 @@ func (replay *replayMovementSensor) getDataFromCache(ctx context.Context, method
 	entry := methodCache[0]
 	replay.cache[method] = methodCache[1:]
 
-	if err := addGRPCMetadata(ctx, entry.timeRequested, entry.timeReceived); err != nil {
+	if err := contextutils.AddTimestampsToGRPCHeader(ctx, entry.timeRequested, entry.timeReceived); err != nil {
 		return nil, errors.Wrapf(err, "adding GRPC metadata failed")
 	}
 
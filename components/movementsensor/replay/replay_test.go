@@ -9,11 +9,14 @@ import (
 	"github.com/golang/geo/r3"
 	geo "github.com/kellydunn/golang-geo"
 	"github.com/pkg/errors"
+	commonpb "go.viam.com/api/common/v1"
+	pb "go.viam.com/api/component/movementsensor/v1"
 	"go.viam.com/test"
 	"google.golang.org/grpc"
 
 	"go.viam.com/rdk/components/movementsensor"
 	"go.viam.com/rdk/internal/cloud"
+	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/spatialmath"
 	"go.viam.com/rdk/testutils"
@@ -758,6 +761,24 @@ func TestReplayMovementSensorConfigValidation(t *testing.T) {
 			},
 			expectedErr: errors.New("batch_size must be between 1 and 1000"),
 		},
+		{
+			description: "Valid config with a capture directory and no cloud credentials",
+			cfg: &Config{
+				Source:       validSource,
+				CaptureDir:   "/tmp/capture",
+				Loop:         true,
+				PlaybackRate: 2,
+			},
+		},
+		{
+			description: "Invalid config with a negative playback rate",
+			cfg: &Config{
+				Source:       validSource,
+				CaptureDir:   "/tmp/capture",
+				PlaybackRate: -1,
+			},
+			expectedErr: errors.New("playback_rate must not be negative"),
+		},
 	}
 
 	for _, tt := range cases {
@@ -925,3 +946,65 @@ func TestReplayMovementSensorReconfigure(t *testing.T) {
 
 	test.That(t, serverClose(), test.ShouldBeNil)
 }
+
+func TestReplayMovementSensorCaptureDir(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	start := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	writeCaptureFile(t, dir, position, start,
+		pb.GetPositionResponse{Coordinate: &commonpb.GeoPoint{Latitude: 1, Longitude: 2}, AltitudeM: 3},
+		pb.GetPositionResponse{Coordinate: &commonpb.GeoPoint{Latitude: 4, Longitude: 5}, AltitudeM: 6})
+	writeCaptureFile(t, dir, compassHeading, start, pb.GetCompassHeadingResponse{Value: 0})
+
+	newReplay := func(cfg *Config) (movementsensor.MovementSensor, error) {
+		return newReplayMovementSensor(ctx, nil, resource.Config{ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+	}
+
+	t.Run("replays each reading", func(t *testing.T) {
+		replay, err := newReplay(&Config{Source: validSource, CaptureDir: dir})
+		test.That(t, err, test.ShouldBeNil)
+
+		props, err := replay.Properties(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, props, test.ShouldResemble, &movementsensor.Properties{PositionSupported: true, CompassHeadingSupported: true})
+
+		for _, expected := range []*geo.Point{geo.NewPoint(1, 2), geo.NewPoint(4, 5)} {
+			point, _, err := replay.Position(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, point, test.ShouldResemble, expected)
+		}
+		_, _, err = replay.Position(ctx, nil)
+		test.That(t, err, test.ShouldBeError, ErrEndOfDataset)
+
+		heading, err := replay.CompassHeading(ctx, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, heading, test.ShouldEqual, 0)
+
+		_, err = replay.LinearVelocity(ctx, nil)
+		test.That(t, err, test.ShouldBeError, movementsensor.ErrMethodUnimplementedLinearVelocity)
+		test.That(t, replay.Close(ctx), test.ShouldBeNil)
+	})
+
+	t.Run("loops within the time interval", func(t *testing.T) {
+		replay, err := newReplay(&Config{
+			Source:     validSource,
+			CaptureDir: dir,
+			Loop:       true,
+			Interval:   TimeInterval{Start: "2000-01-01T12:00:01Z"},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		for i := 0; i < 3; i++ {
+			point, altitude, err := replay.Position(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, point, test.ShouldResemble, geo.NewPoint(4, 5))
+			test.That(t, altitude, test.ShouldEqual, 6)
+		}
+		test.That(t, replay.Close(ctx), test.ShouldBeNil)
+	})
+
+	t.Run("fails without data for the source", func(t *testing.T) {
+		_, err := newReplay(&Config{Source: "missing", CaptureDir: dir})
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, errMessageNoDataAvailable)
+	})
+}
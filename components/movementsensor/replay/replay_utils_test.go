@@ -19,6 +19,7 @@ import (
 	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/data"
 	viamgrpc "go.viam.com/rdk/grpc"
 	"go.viam.com/rdk/internal/cloud"
 	cloudinject "go.viam.com/rdk/internal/testutils/inject"
@@ -427,3 +428,19 @@ func testReplayMovementSensorMethodError(ctx context.Context, t *testing.T, repl
 		test.That(t, data, test.ShouldBeNil)
 	}
 }
+
+// writeCaptureFile writes a completed capture file of the responses to the given method of the source to dir, one
+// second apart starting at start.
+func writeCaptureFile(t *testing.T, dir string, method method, start time.Time, responses ...interface{}) {
+	t.Helper()
+	md, _ := data.BuildCaptureMetadata(movementsensor.API, validSource, string(method), nil, nil, nil)
+	f, err := data.NewCaptureFile(dir, md)
+	test.That(t, err, test.ShouldBeNil)
+	for i, response := range responses {
+		ts := start.Add(time.Duration(i) * time.Second)
+		result, err := data.NewTabularCaptureResult(data.Timestamps{TimeRequested: ts, TimeReceived: ts}, response)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, f.WriteNext(result.ToProto()[0]), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+}
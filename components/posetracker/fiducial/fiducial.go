@@ -0,0 +1,177 @@
+// Package fiducial implements a pose tracker that tracks AprilTags and ArUco markers seen by a camera.
+package fiducial
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/components/posetracker"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/rimage/transform"
+	"go.viam.com/rdk/vision/fiducial"
+)
+
+var model = resource.DefaultModelFamily.WithModel("fiducial")
+
+func init() {
+	resource.RegisterComponent(posetracker.API, model, resource.Registration[posetracker.PoseTracker, *Config]{
+		Constructor: newFiducialTracker,
+	})
+}
+
+// Config describes the camera that sees the tags and the tags to track.
+type Config struct {
+	Camera string `json:"camera_name"`
+	// TagSizeMM is the width of the black border of each tag.
+	TagSizeMM float64 `json:"tag_size_mm"`
+	// TagSizesMM overrides the size of the tags with the given labels, such as "tag16h5_3".
+	TagSizesMM     map[string]float64      `json:"tag_sizes_mm,omitempty"`
+	Families       []string                `json:"families,omitempty"`
+	CustomFamilies []fiducial.FamilyConfig `json:"custom_families,omitempty"`
+	// The intrinsics and distortion of the camera are taken from its properties unless they are given here.
+	CameraParameters     *transform.PinholeCameraIntrinsics `json:"intrinsic_parameters,omitempty"`
+	DistortionParameters *transform.BrownConrady            `json:"distortion_parameters,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid and returns the camera as a dependency.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	if conf.Camera == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "camera_name")
+	}
+	if conf.TagSizeMM <= 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("tag_size_mm must be positive"))
+	}
+	for label, size := range conf.TagSizesMM {
+		if size <= 0 {
+			return nil, nil, resource.NewConfigValidationError(path, errors.Errorf("the size of tag %q must be positive", label))
+		}
+	}
+	if _, err := fiducial.FamiliesFromConfig(conf.Families, conf.CustomFamilies); err != nil {
+		return nil, nil, resource.NewConfigValidationError(path, err)
+	}
+	if conf.CameraParameters != nil {
+		if err := conf.CameraParameters.CheckValid(); err != nil {
+			return nil, nil, resource.NewConfigValidationError(path, err)
+		}
+	}
+	if conf.DistortionParameters != nil {
+		if err := conf.DistortionParameters.CheckValid(); err != nil {
+			return nil, nil, resource.NewConfigValidationError(path, err)
+		}
+	}
+	return []string{conf.Camera}, nil, nil
+}
+
+// fiducialTracker returns the pose of each tag the camera sees in the frame of the camera, named by the tag's label.
+type fiducialTracker struct {
+	resource.Named
+	resource.AlwaysRebuild
+	resource.TriviallyCloseable
+
+	cam        camera.Camera
+	cameraName string
+	detector   *fiducial.Detector
+	tagSize    float64
+	tagSizes   map[string]float64
+	intrinsics *transform.PinholeCameraIntrinsics
+	distortion transform.Distorter
+	logger     logging.Logger
+}
+
+func newFiducialTracker(
+	ctx context.Context,
+	deps resource.Dependencies,
+	conf resource.Config,
+	logger logging.Logger,
+) (posetracker.PoseTracker, error) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	cam, err := camera.FromProvider(deps, newConf.Camera)
+	if err != nil {
+		return nil, err
+	}
+	families, err := fiducial.FamiliesFromConfig(newConf.Families, newConf.CustomFamilies)
+	if err != nil {
+		return nil, err
+	}
+	ft := &fiducialTracker{
+		Named:      conf.ResourceName().AsNamed(),
+		cam:        cam,
+		cameraName: newConf.Camera,
+		detector:   fiducial.NewDetector(families...),
+		tagSize:    newConf.TagSizeMM,
+		tagSizes:   newConf.TagSizesMM,
+		intrinsics: newConf.CameraParameters,
+		logger:     logger,
+	}
+	if newConf.DistortionParameters != nil {
+		ft.distortion = newConf.DistortionParameters
+	}
+	return ft, nil
+}
+
+// cameraModel returns the configured intrinsics and distortion of the camera, or else those in its properties.
+func (ft *fiducialTracker) cameraModel(ctx context.Context) (*transform.PinholeCameraIntrinsics, transform.Distorter, error) {
+	if ft.intrinsics != nil {
+		return ft.intrinsics, ft.distortion, nil
+	}
+	props, err := ft.cam.Properties(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "could not get the properties of camera %s", ft.cameraName)
+	}
+	if props.IntrinsicParams == nil {
+		return nil, nil, errors.Errorf(
+			"camera %s has no intrinsic parameters, so they must be given as intrinsic_parameters", ft.cameraName)
+	}
+	distortion := ft.distortion
+	if distortion == nil {
+		distortion = props.DistortionParams
+	}
+	return props.IntrinsicParams, distortion, nil
+}
+
+// Poses returns the pose of each of the named tags the camera sees, or of every tag if no names are given. When the
+// camera sees a tag more than once, the pose of the largest is returned.
+func (ft *fiducialTracker) Poses(
+	ctx context.Context,
+	bodyNames []string,
+	extra map[string]interface{},
+) (referenceframe.FrameSystemPoses, error) {
+	intrinsics, distortion, err := ft.cameraModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	img, err := camera.DecodeImageFromCamera(ctx, ft.cam, nil, extra)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get image from %s", ft.cameraName)
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range bodyNames {
+		wanted[name] = true
+	}
+	poses := referenceframe.FrameSystemPoses{}
+	for _, tag := range ft.detector.Detect(img) {
+		label := tag.Label()
+		if _, ok := poses[label]; ok || (len(wanted) != 0 && !wanted[label]) {
+			continue
+		}
+		size, ok := ft.tagSizes[label]
+		if !ok {
+			size = ft.tagSize
+		}
+		pose, err := fiducial.EstimatePose(tag.Corners, size, intrinsics, distortion)
+		if err != nil {
+			ft.logger.CDebugw(ctx, "could not estimate the pose of a tag", "tag", label, "error", err)
+			continue
+		}
+		poses[label] = referenceframe.NewPoseInFrame(ft.cameraName, pose)
+	}
+	return poses, nil
+}
@@ -0,0 +1,125 @@
+package fiducial
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/rimage/transform"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+	"go.viam.com/rdk/utils"
+	"go.viam.com/rdk/vision/fiducial"
+)
+
+func TestValidate(t *testing.T) {
+	conf := &Config{Camera: "cam", TagSizeMM: 100}
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"cam"})
+
+	conf = &Config{TagSizeMM: 100}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "camera_name"))
+
+	conf = &Config{Camera: "cam"}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "tag_size_mm")
+
+	conf = &Config{Camera: "cam", TagSizeMM: 100, TagSizesMM: map[string]float64{"tag16h5_1": -1}}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "tag16h5_1")
+
+	conf = &Config{Camera: "cam", TagSizeMM: 100, Families: []string{"unknown"}}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+
+	conf = &Config{Camera: "cam", TagSizeMM: 100, CameraParameters: &transform.PinholeCameraIntrinsics{Width: 10, Height: 10}}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestPoses(t *testing.T) {
+	ctx := context.Background()
+	// Two tags squarely facing the camera, with their borders 60 pixels wide.
+	family, err := fiducial.FamilyByName(fiducial.Tag16h5)
+	test.That(t, err, test.ShouldBeNil)
+	img := image.NewGray(image.Rect(0, 0, 240, 120))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	for i, id := range []int{2, 5} {
+		tag, err := family.Render(id, 10)
+		test.That(t, err, test.ShouldBeNil)
+		for y := 0; y < tag.Bounds().Dy(); y++ {
+			for x := 0; x < tag.Bounds().Dx(); x++ {
+				img.SetGray(x+20+120*i, y+20, tag.GrayAt(x, y))
+			}
+		}
+	}
+	intrinsics := &transform.PinholeCameraIntrinsics{Width: 240, Height: 120, Fx: 600, Fy: 600, Ppx: 120, Ppy: 60}
+
+	cam := inject.NewCamera("cam")
+	cam.ImagesFunc = func(
+		ctx context.Context, filterSourceNames []string, extra map[string]interface{},
+	) ([]camera.NamedImage, resource.ResponseMetadata, error) {
+		namedImg, err := camera.NamedImageFromImage(img, "", utils.MimeTypePNG, data.Annotations{})
+		return []camera.NamedImage{namedImg}, resource.ResponseMetadata{}, err
+	}
+	cam.PropertiesFunc = func(ctx context.Context) (camera.Properties, error) {
+		return camera.Properties{IntrinsicParams: intrinsics}, nil
+	}
+	deps := resource.Dependencies{cam.Name(): cam}
+	newTracker := func(conf *Config) *fiducialTracker {
+		tracker, err := newFiducialTracker(ctx, deps, resource.Config{Name: "tags", ConvertedAttributes: conf}, logging.NewTestLogger(t))
+		test.That(t, err, test.ShouldBeNil)
+		return tracker.(*fiducialTracker)
+	}
+
+	tracker := newTracker(&Config{Camera: "cam", TagSizeMM: 100, TagSizesMM: map[string]float64{"tag16h5_5": 50}})
+	poses, err := tracker.Poses(ctx, nil, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, poses, test.ShouldHaveLength, 2)
+	// The tags are centered 60 pixels either side of the principal point, so the 100 mm tag is 1000 mm away and the
+	// 50 mm tag 500 mm away.
+	expected := map[string]r3.Vector{
+		"tag16h5_2": {X: -100, Z: 1000},
+		"tag16h5_5": {X: 50, Z: 500},
+	}
+	for label, point := range expected {
+		test.That(t, poses[label].Parent(), test.ShouldEqual, "cam")
+		test.That(t, spatialmath.R3VectorAlmostEqual(poses[label].Pose().Point(), point, 0.5), test.ShouldBeTrue)
+		test.That(t, spatialmath.OrientationAlmostEqualEps(poses[label].Pose().Orientation(), spatialmath.NewZeroOrientation(), 1e-3),
+			test.ShouldBeTrue)
+	}
+
+	poses, err = tracker.Poses(ctx, []string{"tag16h5_5", "tag16h5_7"}, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, poses, test.ShouldHaveLength, 1)
+	test.That(t, poses, test.ShouldContainKey, "tag16h5_5")
+
+	// Intrinsics in the config take precedence over those of the camera.
+	configured := *intrinsics
+	configured.Fx, configured.Fy = 300, 300
+	tracker = newTracker(&Config{Camera: "cam", TagSizeMM: 100, CameraParameters: &configured})
+	poses, err = tracker.Poses(ctx, []string{"tag16h5_2"}, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, poses["tag16h5_2"].Pose().Point().Z, test.ShouldAlmostEqual, 500, 0.5)
+
+	cam.PropertiesFunc = func(ctx context.Context) (camera.Properties, error) {
+		return camera.Properties{}, nil
+	}
+	tracker = newTracker(&Config{Camera: "cam", TagSizeMM: 100})
+	_, err = tracker.Poses(ctx, nil, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "intrinsic_parameters")
+}
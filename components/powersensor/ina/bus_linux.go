@@ -0,0 +1,9 @@
+//go:build linux
+
+package ina
+
+import "go.viam.com/rdk/components/board/genericlinux/buses"
+
+func openBus(name string) (buses.I2C, error) {
+	return buses.NewI2cBus(name)
+}
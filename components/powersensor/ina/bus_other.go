@@ -0,0 +1,14 @@
+//go:build !linux
+
+package ina
+
+import (
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+)
+
+// openBus is only implemented on linux, where the sensors are connected to the I2C bus of a board.
+func openBus(name string) (buses.I2C, error) {
+	return nil, errors.New("i2c buses are only supported on linux")
+}
@@ -0,0 +1,312 @@
+// Package ina implements the INA219 and INA226 power sensors from Texas Instruments, which measure the voltage of a
+// DC bus and the current through a shunt resistor over I2C.
+package ina
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+	"go.viam.com/rdk/components/powersensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+var (
+	modelINA219 = resource.DefaultModelFamily.WithModel("ina219")
+	modelINA226 = resource.DefaultModelFamily.WithModel("ina226")
+)
+
+const (
+	defaultI2CAddr         = 0x40
+	defaultShuntResistance = 0.1 // ohms
+
+	registerConfig      = 0x00
+	registerBusVoltage  = 0x02
+	registerPower       = 0x03
+	registerCurrent     = 0x04
+	registerCalibration = 0x05
+	registerINA226MfgID = 0xFE
+
+	ina226MfgID = 0x5449 // "TI"
+)
+
+// Config is used for converting ina219 and ina226 power sensor attributes.
+type Config struct {
+	I2CBus  string `json:"i2c_bus"`
+	I2CAddr int    `json:"i2c_addr,omitempty"`
+	// MaxCurrent is the largest current in amps that the sensor should measure. It defaults to the current that puts
+	// the largest voltage the sensor can measure across the shunt resistor.
+	MaxCurrent float64 `json:"max_current_amps,omitempty"`
+	// ShuntResistance is in ohms.
+	ShuntResistance float64 `json:"shunt_resistance,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if cfg.I2CBus == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "i2c_bus")
+	}
+	if cfg.I2CAddr != 0 && (cfg.I2CAddr < 0x40 || cfg.I2CAddr > 0x4F) {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("i2c_addr must be from 0x40 to 0x4F"))
+	}
+	if cfg.MaxCurrent < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("max_current_amps cannot be negative"))
+	}
+	if cfg.ShuntResistance < 0 {
+		return nil, nil, resource.NewConfigValidationError(path, errors.New("shunt_resistance cannot be negative"))
+	}
+	return nil, nil, nil
+}
+
+// chip describes how the registers of one model of sensor are laid out and scaled.
+type chip struct {
+	name string
+	// maxShuntVoltage is the largest voltage in volts that the sensor can measure across the shunt resistor.
+	maxShuntVoltage float64
+	// calibrationScale is the fixed constant of the calibration equation in the datasheet.
+	calibrationScale float64
+	// calibrationMask is the bits of the calibration register that are used.
+	calibrationMask uint16
+	// powerLSBRatio is the size of the least significant bit of the power register relative to that of the current.
+	powerLSBRatio float64
+	// busVoltage converts the bus voltage register to volts.
+	busVoltage func(raw uint16) float64
+	// config returns the configuration register for measuring up to the given shunt voltage.
+	config func(maxShuntVoltage float64) uint16
+}
+
+var ina219 = chip{
+	name:             "ina219",
+	maxShuntVoltage:  0.32,
+	calibrationScale: 0.04096,
+	calibrationMask:  0xFFFE,
+	powerLSBRatio:    20,
+	busVoltage: func(raw uint16) float64 {
+		// The lowest three bits are flags, and the rest count 4 mV.
+		return float64(raw>>3) * 0.004
+	},
+	config: func(maxShuntVoltage float64) uint16 {
+		// The smallest range of the programmable gain amplifier that fits the shunt voltage gives the most resolution.
+		gain := uint16(3)
+		for i, limit := range []float64{0.04, 0.08, 0.16} {
+			if maxShuntVoltage <= limit {
+				gain = uint16(i)
+				break
+			}
+		}
+		const (
+			busRange32V    = 1 << 13
+			adc12Bit       = 0x3
+			continuousBoth = 0x7
+		)
+		return busRange32V | gain<<11 | adc12Bit<<7 | adc12Bit<<3 | continuousBoth
+	},
+}
+
+var ina226 = chip{
+	name:             "ina226",
+	maxShuntVoltage:  0.08192,
+	calibrationScale: 0.00512,
+	calibrationMask:  0x7FFF,
+	powerLSBRatio:    25,
+	busVoltage: func(raw uint16) float64 {
+		return float64(raw) * 0.00125
+	},
+	config: func(float64) uint16 {
+		// The default of the datasheet: no averaging, 1.1 ms conversions and continuous shunt and bus measurements.
+		return 0x4127
+	},
+}
+
+func init() {
+	for model, c := range map[resource.Model]chip{modelINA219: ina219, modelINA226: ina226} {
+		c := c
+		resource.RegisterComponent(
+			powersensor.API,
+			model,
+			resource.Registration[powersensor.PowerSensor, *Config]{
+				Constructor: func(
+					ctx context.Context, _ resource.Dependencies, conf resource.Config, logger logging.Logger,
+				) (powersensor.PowerSensor, error) {
+					newConf, err := resource.NativeConfig[*Config](conf)
+					if err != nil {
+						return nil, err
+					}
+					bus, err := openBus(newConf.I2CBus)
+					if err != nil {
+						return nil, err
+					}
+					return newINA(ctx, conf.ResourceName(), c, bus, newConf, logger)
+				},
+			})
+	}
+}
+
+type ina struct {
+	resource.Named
+	resource.AlwaysRebuild
+	resource.TriviallyCloseable
+	logger logging.Logger
+
+	chip       chip
+	bus        buses.I2C
+	addr       byte
+	currentLSB float64 // amps
+	powerLSB   float64 // watts
+
+	mu sync.Mutex
+}
+
+func newINA(
+	ctx context.Context, name resource.Name, c chip, bus buses.I2C, conf *Config, logger logging.Logger,
+) (powersensor.PowerSensor, error) {
+	addr := conf.I2CAddr
+	if addr == 0 {
+		addr = defaultI2CAddr
+	}
+	shunt := conf.ShuntResistance
+	if shunt == 0 {
+		shunt = defaultShuntResistance
+	}
+	maxCurrent := conf.MaxCurrent
+	if maxCurrent == 0 {
+		maxCurrent = c.maxShuntVoltage / shunt
+	}
+	maxShuntVoltage := maxCurrent * shunt
+	if maxShuntVoltage > c.maxShuntVoltage*(1+1e-9) {
+		return nil, errors.Errorf("%v A through %v ohms is %v V, more than the %v V the %s can measure",
+			maxCurrent, shunt, maxShuntVoltage, c.maxShuntVoltage, c.name)
+	}
+
+	// The current register is signed, so its full scale is 2^15 of the least significant bit.
+	exactCalibration := c.calibrationScale / (maxCurrent / (1 << 15) * shunt)
+	var calibration uint16
+	if exactCalibration <= float64(c.calibrationMask) {
+		calibration = uint16(exactCalibration) & c.calibrationMask
+	}
+	if calibration == 0 {
+		return nil, errors.Errorf("cannot calibrate the %s for %v A through %v ohms", c.name, maxCurrent, shunt)
+	}
+	// Truncating the calibration makes each bit of current a little larger than asked for.
+	currentLSB := c.calibrationScale / (float64(calibration) * shunt)
+
+	s := &ina{
+		Named:      name.AsNamed(),
+		logger:     logger,
+		chip:       c,
+		bus:        bus,
+		addr:       byte(addr),
+		currentLSB: currentLSB,
+		powerLSB:   currentLSB * c.powerLSBRatio,
+	}
+	if c.name == ina226.name {
+		id, err := s.readRegister(ctx, registerINA226MfgID)
+		if err != nil {
+			return nil, err
+		}
+		if id != ina226MfgID {
+			return nil, errors.Errorf("unexpected manufacturer ID %#04x at address %#02x, is this an ina226?", id, addr)
+		}
+	}
+	if err := s.writeRegister(ctx, registerConfig, c.config(maxShuntVoltage)); err != nil {
+		return nil, err
+	}
+	if err := s.writeRegister(ctx, registerCalibration, calibration); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// readRegister reads one of the 16 bit, big endian registers of the sensor.
+func (s *ina) readRegister(ctx context.Context, register byte) (value uint16, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handle, err := s.bus.OpenHandle(s.addr)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		err = multierr.Combine(err, handle.Close())
+	}()
+	data, err := handle.ReadBlockData(ctx, register, 2)
+	if err != nil {
+		return 0, err
+	}
+	if len(data) != 2 {
+		return 0, errors.Errorf("expected 2 bytes from register %#02x, got %d", register, len(data))
+	}
+	return binary.BigEndian.Uint16(data), nil
+}
+
+func (s *ina) writeRegister(ctx context.Context, register byte, value uint16) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handle, err := s.bus.OpenHandle(s.addr)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Combine(err, handle.Close())
+	}()
+	return handle.WriteBlockData(ctx, register, binary.BigEndian.AppendUint16(nil, value))
+}
+
+// Voltage returns the voltage of the bus in volts. The sensors only measure DC, so it is never AC.
+func (s *ina) Voltage(ctx context.Context, extra map[string]interface{}) (float64, bool, error) {
+	raw, err := s.readRegister(ctx, registerBusVoltage)
+	if err != nil {
+		return 0, false, err
+	}
+	return s.chip.busVoltage(raw), false, nil
+}
+
+// Current returns the current through the shunt resistor in amps, which is negative when it flows backwards. The
+// sensors only measure DC, so it is never AC.
+func (s *ina) Current(ctx context.Context, extra map[string]interface{}) (float64, bool, error) {
+	raw, err := s.readRegister(ctx, registerCurrent)
+	if err != nil {
+		return 0, false, err
+	}
+	return float64(int16(raw)) * s.currentLSB, false, nil
+}
+
+// Power returns the power in watts.
+func (s *ina) Power(ctx context.Context, extra map[string]interface{}) (float64, error) {
+	raw, err := s.readRegister(ctx, registerPower)
+	if err != nil {
+		return 0, err
+	}
+	return float64(raw) * s.powerLSB, nil
+}
+
+// Readings returns the voltage, current and power.
+func (s *ina) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	volts, isAC, err := s.Voltage(ctx, extra)
+	if err != nil {
+		return nil, err
+	}
+	amps, _, err := s.Current(ctx, extra)
+	if err != nil {
+		return nil, err
+	}
+	watts, err := s.Power(ctx, extra)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"volts": volts,
+		"amps":  amps,
+		"is_ac": isAC,
+		"watts": watts,
+	}, nil
+}
+
+// DoCommand is not implemented.
+func (s *ina) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	return nil, resource.ErrDoUnimplemented
+}
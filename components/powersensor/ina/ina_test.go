@@ -0,0 +1,207 @@
+package ina
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/board/genericlinux/buses"
+	"go.viam.com/rdk/components/powersensor"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+// fakeINA emulates the register map of a sensor with the given voltages across its shunt resistor and on its bus,
+// computing the current and power registers from the calibration the way the datasheets describe.
+type fakeINA struct {
+	chip         chip
+	addr         byte
+	shuntVoltage float64
+	busVoltage   float64
+	registers    map[byte]uint16
+	openHandles  int
+}
+
+func newFakeINA(c chip) *fakeINA {
+	f := &fakeINA{chip: c, addr: defaultI2CAddr, registers: map[byte]uint16{}}
+	if c.name == ina226.name {
+		f.registers[registerINA226MfgID] = ina226MfgID
+	}
+	return f
+}
+
+func (f *fakeINA) read(register byte) uint16 {
+	cal := float64(f.registers[registerCalibration])
+	switch register {
+	case registerBusVoltage:
+		if f.chip.name == ina219.name {
+			// A conversion is ready.
+			return uint16(math.Round(f.busVoltage/0.004))<<3 | 0x2
+		}
+		return uint16(math.Round(f.busVoltage / 0.00125))
+	case registerCurrent, registerPower:
+		var current, power float64
+		if f.chip.name == ina219.name {
+			shunt := math.Round(f.shuntVoltage / 10e-6)
+			current = math.Trunc(shunt * cal / 4096)
+			power = math.Trunc(math.Abs(current) * math.Round(f.busVoltage/0.004) / 5000)
+		} else {
+			shunt := math.Round(f.shuntVoltage / 2.5e-6)
+			current = math.Trunc(shunt * cal / 2048)
+			power = math.Trunc(math.Abs(current) * math.Round(f.busVoltage/0.00125) / 20000)
+		}
+		if register == registerCurrent {
+			return uint16(int16(current))
+		}
+		return uint16(power)
+	default:
+		return f.registers[register]
+	}
+}
+
+func (f *fakeINA) bus() buses.I2C {
+	return &inject.I2C{OpenHandleFunc: func(addr byte) (buses.I2CHandle, error) {
+		if addr != f.addr {
+			return nil, errors.New("no device at address")
+		}
+		f.openHandles++
+		return &inject.I2CHandle{
+			ReadBlockDataFunc: func(ctx context.Context, register byte, numBytes uint8) ([]byte, error) {
+				return binary.BigEndian.AppendUint16(nil, f.read(register))[:numBytes], nil
+			},
+			WriteBlockDataFunc: func(ctx context.Context, register byte, data []byte) error {
+				f.registers[register] = binary.BigEndian.Uint16(data)
+				return nil
+			},
+			CloseFunc: func() error {
+				f.openHandles--
+				return nil
+			},
+		}, nil
+	}}
+}
+
+func TestINA(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	name := powersensor.Named("ina")
+
+	for _, tc := range []struct {
+		chip         chip
+		conf         Config
+		shuntVoltage float64
+		busVoltage   float64
+		config       uint16
+		calibration  uint16
+	}{
+		{
+			chip:         ina219,
+			conf:         Config{I2CBus: "1"},
+			shuntVoltage: 0.1,
+			busVoltage:   12.2,
+			config:       0x399F,
+			calibration:  4194,
+		},
+		{
+			chip:         ina219,
+			conf:         Config{I2CBus: "1", MaxCurrent: 2, ShuntResistance: 0.05},
+			shuntVoltage: -0.035,
+			busVoltage:   5.04,
+			// 2 A through 0.05 ohms is 100 mV, which needs the 160 mV range.
+			config:      0x319F,
+			calibration: 13420,
+		},
+		{
+			chip:         ina226,
+			conf:         Config{I2CBus: "1"},
+			shuntVoltage: 0.05,
+			busVoltage:   24.1,
+			config:       0x4127,
+			calibration:  2048,
+		},
+		{
+			chip:         ina226,
+			conf:         Config{I2CBus: "1", MaxCurrent: 10, ShuntResistance: 0.002},
+			shuntVoltage: 0.0123,
+			busVoltage:   3.3,
+			config:       0x4127,
+			calibration:  8388,
+		},
+	} {
+		t.Run(tc.chip.name, func(t *testing.T) {
+			f := newFakeINA(tc.chip)
+			f.shuntVoltage = tc.shuntVoltage
+			f.busVoltage = tc.busVoltage
+			s, err := newINA(ctx, name, tc.chip, f.bus(), &tc.conf, logger)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, f.registers[registerConfig], test.ShouldEqual, tc.config)
+			test.That(t, f.registers[registerCalibration], test.ShouldEqual, tc.calibration)
+
+			shunt := tc.conf.ShuntResistance
+			if shunt == 0 {
+				shunt = defaultShuntResistance
+			}
+			amps := tc.shuntVoltage / shunt
+
+			volts, isAC, err := s.Voltage(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, isAC, test.ShouldBeFalse)
+			test.That(t, volts, test.ShouldAlmostEqual, tc.busVoltage, 0.005)
+
+			current, isAC, err := s.Current(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, isAC, test.ShouldBeFalse)
+			test.That(t, current, test.ShouldAlmostEqual, amps, math.Abs(amps)*0.001)
+
+			watts, err := s.Power(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, watts, test.ShouldAlmostEqual, math.Abs(amps)*tc.busVoltage, math.Abs(amps)*tc.busVoltage*0.01)
+
+			readings, err := s.Readings(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, readings, test.ShouldResemble, map[string]interface{}{
+				"volts": volts,
+				"amps":  current,
+				"is_ac": false,
+				"watts": watts,
+			})
+			test.That(t, f.openHandles, test.ShouldEqual, 0)
+		})
+	}
+
+	t.Run("shunt voltage out of range", func(t *testing.T) {
+		f := newFakeINA(ina226)
+		_, err := newINA(ctx, name, ina226, f.bus(), &Config{I2CBus: "1", MaxCurrent: 1, ShuntResistance: 0.1}, logger)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "more than")
+	})
+
+	t.Run("wrong chip", func(t *testing.T) {
+		f := newFakeINA(ina219)
+		_, err := newINA(ctx, name, ina226, f.bus(), &Config{I2CBus: "1"}, logger)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "manufacturer ID")
+	})
+
+	t.Run("wrong address", func(t *testing.T) {
+		f := newFakeINA(ina219)
+		_, err := newINA(ctx, name, ina219, f.bus(), &Config{I2CBus: "1", I2CAddr: 0x41}, logger)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
+
+func TestValidate(t *testing.T) {
+	_, _, err := (&Config{}).Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "i2c_bus"))
+	_, _, err = (&Config{I2CBus: "1", I2CAddr: 0x20}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, _, err = (&Config{I2CBus: "1", ShuntResistance: -1}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, _, err = (&Config{I2CBus: "1", I2CAddr: 0x45, MaxCurrent: 2, ShuntResistance: 0.01}).Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+}
@@ -4,4 +4,5 @@ package register
 import (
 	// register all powersensors.
 	_ "go.viam.com/rdk/components/powersensor/fake"
+	_ "go.viam.com/rdk/components/powersensor/ina"
 )
@@ -4,4 +4,5 @@ package register
 import (
 	// for Sensors.
 	_ "go.viam.com/rdk/components/sensor/fake"
+	_ "go.viam.com/rdk/components/sensor/replay"
 )
@@ -0,0 +1,187 @@
+// Package replay implements a replay sensor that plays back the readings data capture wrote to a local capture
+// directory.
+package replay
+
+/*
+   The replay sensor returns the readings captured from another resource's Readings method, read from the capture
+   files data capture wrote to capture_dir, so that anything that consumes readings can be tested offline.
+   Example Config:
+   {
+     "name": "myReplaySensor",
+     "type": "sensor",
+     "model": "replay",
+     "attributes": {
+       "source": "mySensor",
+       "capture_dir": "/home/user/.viam/capture",
+       "time_interval": {"start": "2024-01-01T12:00:00Z"},
+       "loop": true,
+       "playback_rate": 1
+     }
+   }
+*/
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/protoutils"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/utils/contextutils"
+)
+
+const (
+	timeFormat     = time.RFC3339
+	readingsMethod = "Readings"
+)
+
+var (
+	// model is the model of a replay sensor.
+	model = resource.DefaultModelFamily.WithModel("replay")
+
+	// ErrEndOfDataset represents that the replay sensor has reached the end of the dataset.
+	ErrEndOfDataset = errors.New("reached end of dataset")
+
+	// errBadData represents that the replay sensor data does not match the expected format.
+	errBadData = errors.New("data does not match expected format")
+)
+
+func init() {
+	resource.RegisterComponent(sensor.API, model, resource.Registration[sensor.Sensor, *Config]{
+		Constructor: newReplaySensor,
+	})
+}
+
+// Config describes how to configure the replay sensor.
+type Config struct {
+	// Source is the name of the resource whose readings were captured.
+	Source     string       `json:"source"`
+	CaptureDir string       `json:"capture_dir"`
+	Interval   TimeInterval `json:"time_interval,omitempty"`
+	// Loop restarts from the first reading once every reading has been replayed.
+	Loop bool `json:"loop,omitempty"`
+	// PlaybackRate, if set, replays the readings at this multiple of the speed they were captured at, returning the
+	// latest reading at each call. Otherwise each call returns the next reading.
+	PlaybackRate float64 `json:"playback_rate,omitempty"`
+	// Encryption holds the keys the capture files in CaptureDir were encrypted with, if data capture encrypts them.
+	Encryption *data.EncryptionConfig `json:"encryption,omitempty"`
+}
+
+// TimeInterval holds the start and end time used to filter data.
+type TimeInterval struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// Validate checks that the config attributes are valid for a replay sensor.
+func (cfg *Config) Validate(path string) ([]string, []string, error) {
+	if cfg.Source == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "source")
+	}
+	if cfg.CaptureDir == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "capture_dir")
+	}
+	if cfg.PlaybackRate < 0 {
+		return nil, nil, errors.New("playback_rate must not be negative")
+	}
+	if cfg.Encryption != nil {
+		if err := cfg.Encryption.Validate(); err != nil {
+			return nil, nil, resource.NewConfigValidationError(path, err)
+		}
+	}
+	if _, err := cfg.replayOptions(); err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, nil
+}
+
+// replayOptions returns the options to replay the captured readings with.
+func (cfg *Config) replayOptions() (data.ReplayOptions, error) {
+	opts := data.ReplayOptions{Loop: cfg.Loop, PlaybackRate: cfg.PlaybackRate}
+	var err error
+	if cfg.Interval.Start != "" {
+		if opts.Start, err = time.Parse(timeFormat, cfg.Interval.Start); err != nil {
+			return opts, errors.New("invalid time format for start time (UTC), use RFC3339")
+		}
+	}
+	if cfg.Interval.End != "" {
+		if opts.End, err = time.Parse(timeFormat, cfg.Interval.End); err != nil {
+			return opts, errors.New("invalid time format for end time (UTC), use RFC3339")
+		}
+	}
+	if !opts.Start.IsZero() && !opts.End.IsZero() && opts.Start.After(opts.End) {
+		return opts, errors.New("invalid config, end time (UTC) must be after start time (UTC)")
+	}
+	return opts, nil
+}
+
+// replaySensor is a sensor model that plays back pre-captured readings.
+type replaySensor struct {
+	resource.Named
+	resource.AlwaysRebuild
+	resource.TriviallyCloseable
+	logger logging.Logger
+
+	mu     sync.Mutex
+	replay *data.CaptureReplayer
+}
+
+// newReplaySensor creates a new replay sensor based on the inputted config.
+func newReplaySensor(ctx context.Context, deps resource.Dependencies, conf resource.Config, logger logging.Logger) (
+	sensor.Sensor, error,
+) {
+	newConf, err := resource.NativeConfig[*Config](conf)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := newConf.replayOptions()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Keyring, err = newConf.Encryption.Keyring(); err != nil {
+		return nil, err
+	}
+	replay, err := data.NewCaptureReplayer(newConf.CaptureDir, newConf.Source, readingsMethod, opts)
+	if err != nil {
+		return nil, err
+	}
+	if replay.Len() == 0 {
+		logger.Warnf("no readings of %s found in %s", newConf.Source, newConf.CaptureDir)
+	}
+	return &replaySensor{
+		Named:  conf.ResourceName().AsNamed(),
+		logger: logger,
+		replay: replay,
+	}, nil
+}
+
+// Readings returns the next captured readings, with the time they were captured in the gRPC response header.
+func (s *replaySensor) Readings(ctx context.Context, extra map[string]interface{}) (map[string]interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	reading, err := s.replay.Next(ctx)
+	if err != nil {
+		if errors.Is(err, data.ErrEndOfCapture) {
+			return nil, ErrEndOfDataset
+		}
+		return nil, err
+	}
+
+	// Data capture stores readings under a top level key, like GetReadingsResponse.
+	readings, ok := reading.GetStruct().GetFields()["readings"]
+	if !ok {
+		return nil, errBadData
+	}
+
+	if err := contextutils.AddTimestampsToGRPCHeader(
+		ctx, reading.GetMetadata().GetTimeRequested(), reading.GetMetadata().GetTimeReceived()); err != nil {
+		return nil, err
+	}
+
+	return protoutils.ReadingProtoToGo(readings.GetStructValue().GetFields())
+}
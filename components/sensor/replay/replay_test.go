@@ -0,0 +1,110 @@
+package replay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+)
+
+var testStart = time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// writeReadings writes a completed capture file of the readings of the source to dir, one second apart.
+func writeReadings(t *testing.T, dir, source string, readings ...map[string]interface{}) {
+	t.Helper()
+	md, _ := data.BuildCaptureMetadata(sensor.API, source, readingsMethod, nil, nil, nil)
+	f, err := data.NewCaptureFile(dir, md)
+	test.That(t, err, test.ShouldBeNil)
+	for i, reading := range readings {
+		ts := testStart.Add(time.Duration(i) * time.Second)
+		result, err := data.NewTabularCaptureResultReadings(data.Timestamps{TimeRequested: ts, TimeReceived: ts}, reading)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, f.WriteNext(result.ToProto()[0]), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+}
+
+func newTestSensor(t *testing.T, cfg *Config) (sensor.Sensor, error) {
+	t.Helper()
+	return newReplaySensor(context.Background(), nil,
+		resource.Config{Name: "replay", API: sensor.API, Model: model, ConvertedAttributes: cfg}, logging.NewTestLogger(t))
+}
+
+func TestValidate(t *testing.T) {
+	_, _, err := (&Config{CaptureDir: "dir"}).Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "source"))
+	_, _, err = (&Config{Source: "source"}).Validate("path")
+	test.That(t, err, test.ShouldBeError, resource.NewConfigValidationFieldRequiredError("path", "capture_dir"))
+	_, _, err = (&Config{Source: "source", CaptureDir: "dir", Interval: TimeInterval{Start: "yesterday"}}).Validate("path")
+	test.That(t, err, test.ShouldBeError, "invalid time format for start time (UTC), use RFC3339")
+	_, _, err = (&Config{Source: "source", CaptureDir: "dir", PlaybackRate: -1}).Validate("path")
+	test.That(t, err, test.ShouldBeError, "playback_rate must not be negative")
+	_, _, err = (&Config{
+		Source: "source", CaptureDir: "dir", Encryption: &data.EncryptionConfig{Keys: []data.EncryptionKeyConfig{{ID: "k"}}},
+	}).Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	deps, _, err := (&Config{Source: "source", CaptureDir: "dir"}).Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldBeEmpty)
+}
+
+func TestReadings(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeReadings(t, dir, "source",
+		map[string]interface{}{"temperature": 20.5, "label": "a"},
+		map[string]interface{}{"temperature": 21.5, "label": "b"})
+
+	t.Run("replays each reading", func(t *testing.T) {
+		s, err := newTestSensor(t, &Config{Source: "source", CaptureDir: dir})
+		test.That(t, err, test.ShouldBeNil)
+		for _, expected := range []map[string]interface{}{
+			{"temperature": 20.5, "label": "a"},
+			{"temperature": 21.5, "label": "b"},
+		} {
+			readings, err := s.Readings(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, readings, test.ShouldResemble, expected)
+		}
+		_, err = s.Readings(ctx, nil)
+		test.That(t, err, test.ShouldBeError, ErrEndOfDataset)
+	})
+
+	t.Run("replays on a clock", func(t *testing.T) {
+		s, err := newTestSensor(t, &Config{Source: "source", CaptureDir: dir, PlaybackRate: 1, Loop: true})
+		test.That(t, err, test.ShouldBeNil)
+		mockClock := clock.NewMock()
+		s.(*replaySensor).replay, err = data.NewCaptureReplayer(dir, "source", readingsMethod,
+			data.ReplayOptions{PlaybackRate: 1, Loop: true, Clock: mockClock})
+		test.That(t, err, test.ShouldBeNil)
+
+		for _, step := range []struct {
+			advance time.Duration
+			label   string
+		}{
+			{0, "a"},
+			{500 * time.Millisecond, "a"},
+			{500 * time.Millisecond, "b"},
+			{time.Second, "a"},
+		} {
+			mockClock.Add(step.advance)
+			readings, err := s.Readings(ctx, nil)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, readings["label"], test.ShouldEqual, step.label)
+		}
+	})
+
+	t.Run("replays nothing for another source", func(t *testing.T) {
+		s, err := newTestSensor(t, &Config{Source: "other", CaptureDir: dir})
+		test.That(t, err, test.ShouldBeNil)
+		_, err = s.Readings(ctx, nil)
+		test.That(t, err, test.ShouldBeError, ErrEndOfDataset)
+	})
+}
@@ -19,6 +19,7 @@ type CaptureBufferedWriter interface {
 type CaptureBuffer struct {
 	Directory          string
 	MetaData           *v1.DataCaptureMetadata
+	keyring            *Keyring
 	nextFile           *CaptureFile
 	lock               sync.Mutex
 	maxCaptureFileSize int64
@@ -33,6 +34,18 @@ func NewCaptureBuffer(dir string, md *v1.DataCaptureMetadata, maxCaptureFileSize
 	}
 }
 
+// NewCaptureBufferWithKeyring returns a new Buffer whose files are encrypted with the active key of k if it has one.
+func NewCaptureBufferWithKeyring(
+	dir string,
+	md *v1.DataCaptureMetadata,
+	maxCaptureFileSize int64,
+	k *Keyring,
+) *CaptureBuffer {
+	b := NewCaptureBuffer(dir, md, maxCaptureFileSize)
+	b.keyring = k
+	return b
+}
+
 var (
 	// errInvalidBinarySensorData is returned from WriteBinary if the sensor data is the wrong type.
 	errInvalidBinarySensorData = errors.New("CaptureBuffer.WriteBinary called with non binary sensor data")
@@ -55,7 +68,7 @@ func (b *CaptureBuffer) WriteBinary(item *v1.SensorData, mimeType string) error
 
 	// assign mime type to DataCaptureMetadata
 	b.MetaData.MimeType = mimeType
-	binFile, err := NewCaptureFile(b.Directory, b.MetaData)
+	binFile, err := NewCaptureFileWithKeyring(b.Directory, b.MetaData, b.keyring)
 	if err != nil {
 		return err
 	}
@@ -85,7 +98,7 @@ func (b *CaptureBuffer) WriteTabular(item *v1.SensorData) error {
 	}
 
 	if b.nextFile == nil {
-		nextFile, err := NewCaptureFile(b.Directory, b.MetaData)
+		nextFile, err := NewCaptureFileWithKeyring(b.Directory, b.MetaData, b.keyring)
 		if err != nil {
 			return err
 		}
@@ -94,7 +107,7 @@ func (b *CaptureBuffer) WriteTabular(item *v1.SensorData) error {
 		if err := b.nextFile.Close(); err != nil {
 			return err
 		}
-		nextFile, err := NewCaptureFile(b.Directory, b.MetaData)
+		nextFile, err := NewCaptureFileWithKeyring(b.Directory, b.MetaData, b.keyring)
 		if err != nil {
 			return err
 		}
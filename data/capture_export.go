@@ -0,0 +1,609 @@
+package data
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	rutils "go.viam.com/rdk/utils"
+)
+
+// ExportFormat is a file format that ExportCaptureDir writes tabular readings in.
+type ExportFormat string
+
+const (
+	// ExportFormatCSV writes a row for every reading, with a column for every field of the readings.
+	ExportFormatCSV ExportFormat = "csv"
+	// ExportFormatJSONL writes a JSON object for every reading, keeping its nesting.
+	ExportFormatJSONL ExportFormat = "jsonl"
+	// ExportFormatParquet writes a row for every reading, with a column for every field of the readings.
+	ExportFormatParquet ExportFormat = "parquet"
+)
+
+// Names of the columns, or JSON keys, of the timestamps of exported tabular readings.
+const (
+	exportTimeRequested = "time_requested"
+	exportTimeReceived  = "time_received"
+	exportData          = "data"
+)
+
+// exportBinaryTimeFormat names exported binary readings. It sorts by time and has no characters that are reserved in
+// file names.
+const exportBinaryTimeFormat = "2006-01-02T15_04_05.000000Z"
+
+// ExportOptions configures which captured readings ExportCaptureDir exports, and how.
+type ExportOptions struct {
+	// Format is the file format of tabular readings. Defaults to CSV.
+	Format ExportFormat
+	// ComponentType, ComponentName and MethodName, if set, select the resources and methods to export. ComponentType
+	// matches either the whole API, such as rdk:component:sensor, or just its subtype, such as sensor.
+	ComponentType string
+	ComponentName string
+	MethodName    string
+	// Tags, if set, selects the capture files that have all of them.
+	Tags []string
+	// Start and End, if set, bound the time requested of the readings to export.
+	Start time.Time
+	End   time.Time
+	// Keyring decrypts capture files that were encrypted at rest.
+	Keyring *Keyring
+}
+
+// ExportSummary counts what ExportCaptureDir exported.
+type ExportSummary struct {
+	TabularReadings int
+	BinaryReadings  int
+	// Files are the paths of the files written, in the order they were written.
+	Files []string
+}
+
+// exportGroup is a method of a resource, whose tabular readings are exported to one file.
+type exportGroup struct {
+	componentType string
+	componentName string
+	methodName    string
+}
+
+// dir returns the directory under outputDir that the readings of the group are exported to, or in which their file
+// is written.
+func (g exportGroup) dir(outputDir string) string {
+	return filepath.Join(outputDir, strings.ReplaceAll(g.componentType, filePathReservedChars, "_"),
+		strings.ReplaceAll(g.componentName, filePathReservedChars, "_"))
+}
+
+type captureExporter struct {
+	outputDir string
+	opts      ExportOptions
+	// tabular is what the first pass over the capture files found out about the tabular readings of each group.
+	tabular map[exportGroup]*exportTable
+	// binaryNames counts the binary readings exported under each name, to tell apart the ones requested at the same
+	// time.
+	binaryNames map[string]int
+	summary     ExportSummary
+}
+
+// exportTable is what is known about the tabular readings of a group before they are written: the capture files that
+// hold them, and the types of the columns they flatten to.
+type exportTable struct {
+	files []exportSource
+	types map[string]parquetType
+}
+
+// exportSource is a capture file with tabular readings to export, and the earliest time one of them was requested.
+type exportSource struct {
+	path  string
+	first time.Time
+}
+
+// ExportCaptureDir exports the readings in the completed capture files anywhere under captureDir to files under
+// outputDir, so that they can be analyzed without syncing them. The tabular readings of each method of each resource
+// are written in time order to <outputDir>/<api>/<resource name>/<method>.<format>, and every binary reading to its
+// own file in <outputDir>/<api>/<resource name>/<method>/, named by the time it was requested.
+//
+// CSV and parquet files flatten the fields of the readings into columns named by their dotted paths, with the index
+// of each element of a list as part of its path. A column that holds more than one type of value is written as
+// strings.
+//
+// The capture files are read one at a time, twice: once to find the columns of the tabular readings, and once to
+// stream them out, so that an export holds no more than a capture file's worth of readings in memory. The capture
+// files of a method are written out in the order of their earliest reading, as they do not overlap in time.
+func ExportCaptureDir(captureDir, outputDir string, opts ExportOptions) (ExportSummary, error) {
+	switch opts.Format {
+	case "":
+		opts.Format = ExportFormatCSV
+	case ExportFormatCSV, ExportFormatJSONL, ExportFormatParquet:
+	default:
+		return ExportSummary{}, errors.Errorf("unsupported export format %q, expected one of %q, %q or %q",
+			opts.Format, ExportFormatCSV, ExportFormatJSONL, ExportFormatParquet)
+	}
+	e := &captureExporter{
+		outputDir:   outputDir,
+		opts:        opts,
+		tabular:     map[exportGroup]*exportTable{},
+		binaryNames: map[string]int{},
+	}
+	err := filepath.WalkDir(captureDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != CompletedCaptureFileExt {
+			return nil
+		}
+		return e.exportFile(path)
+	})
+	if err != nil {
+		return e.summary, errors.Wrapf(err, "failed to export capture directory %s", captureDir)
+	}
+
+	groups := make([]exportGroup, 0, len(e.tabular))
+	for g := range e.tabular {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		if a.componentType != b.componentType {
+			return a.componentType < b.componentType
+		}
+		if a.componentName != b.componentName {
+			return a.componentName < b.componentName
+		}
+		return a.methodName < b.methodName
+	})
+	for _, g := range groups {
+		if err := e.writeTabular(g); err != nil {
+			return e.summary, err
+		}
+	}
+	return e.summary, nil
+}
+
+// matches returns whether the capture file with the metadata md is selected by the options.
+func (o ExportOptions) matches(md *v1.DataCaptureMetadata) bool {
+	if o.ComponentType != "" && md.GetComponentType() != o.ComponentType &&
+		!strings.HasSuffix(md.GetComponentType(), ":"+o.ComponentType) {
+		return false
+	}
+	if o.ComponentName != "" && md.GetComponentName() != o.ComponentName {
+		return false
+	}
+	if o.MethodName != "" && md.GetMethodName() != o.MethodName {
+		return false
+	}
+	for _, tag := range o.Tags {
+		if !slices.Contains(md.GetTags(), tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// readCaptureFile calls fn with the metadata of the capture file at path, if the options select it, and each of its
+// readings that were requested in the time range of the options.
+func (e *captureExporter) readCaptureFile(
+	path string,
+	fn func(md *v1.DataCaptureMetadata, item *v1.SensorData) error,
+) (err error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Combine(err, f.Close())
+	}()
+	cf, err := ReadCaptureFileWithKeyring(f, e.opts.Keyring)
+	if err != nil {
+		return err
+	}
+	md := cf.ReadMetadata()
+	if !e.opts.matches(md) {
+		return nil
+	}
+	for {
+		next, err := cf.ReadNext()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		timeRequested := next.GetMetadata().GetTimeRequested().AsTime()
+		if (!e.opts.Start.IsZero() && timeRequested.Before(e.opts.Start)) ||
+			(!e.opts.End.IsZero() && timeRequested.After(e.opts.End)) {
+			continue
+		}
+		if err := fn(md, next); err != nil {
+			return err
+		}
+	}
+}
+
+// exportFile exports the binary readings of the capture file at path, or notes the columns of its tabular readings
+// to write them once every file has been read.
+func (e *captureExporter) exportFile(path string) error {
+	var source *exportSource
+	var table *exportTable
+	err := e.readCaptureFile(path, func(md *v1.DataCaptureMetadata, item *v1.SensorData) error {
+		group := exportGroup{
+			componentType: md.GetComponentType(),
+			componentName: md.GetComponentName(),
+			methodName:    md.GetMethodName(),
+		}
+		if md.GetType() == v1.DataType_DATA_TYPE_BINARY_SENSOR {
+			return e.writeBinary(group, md, item)
+		}
+		if table == nil {
+			if table = e.tabular[group]; table == nil {
+				table = &exportTable{types: map[string]parquetType{}}
+				e.tabular[group] = table
+			}
+			source = &exportSource{path: path, first: item.GetMetadata().GetTimeRequested().AsTime()}
+		}
+		if timeRequested := item.GetMetadata().GetTimeRequested().AsTime(); timeRequested.Before(source.first) {
+			source.first = timeRequested
+		}
+		for name, value := range flattenRow(item) {
+			if typ, ok := leafType(value); ok {
+				if existing, ok := table.types[name]; ok && existing != typ {
+					typ = parquetString
+				}
+				table.types[name] = typ
+			}
+		}
+		return nil
+	})
+	if source != nil {
+		table.files = append(table.files, *source)
+	}
+	return err
+}
+
+func (e *captureExporter) writeBinary(group exportGroup, md *v1.DataCaptureMetadata, item *v1.SensorData) error {
+	dir := filepath.Join(group.dir(e.outputDir), strings.ReplaceAll(group.methodName, filePathReservedChars, "_"))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, item.GetMetadata().GetTimeRequested().AsTime().UTC().Format(exportBinaryTimeFormat))
+	if n := e.binaryNames[name]; n > 0 {
+		e.binaryNames[name]++
+		name += "_" + strconv.Itoa(n)
+	} else {
+		e.binaryNames[name] = 1
+	}
+	name += exportFileExt(md, item)
+	if err := os.WriteFile(name, item.GetBinary(), 0o600); err != nil {
+		return err
+	}
+	e.summary.BinaryReadings++
+	e.summary.Files = append(e.summary.Files, name)
+	return nil
+}
+
+// exportFileExt returns the file extension of an exported binary reading, from its mime type if it has one, or else
+// from the capture file it was read from.
+func exportFileExt(md *v1.DataCaptureMetadata, item *v1.SensorData) string {
+	switch item.GetMetadata().GetMimeType() {
+	case v1.MimeType_MIME_TYPE_IMAGE_JPEG:
+		return ExtJpeg
+	case v1.MimeType_MIME_TYPE_IMAGE_PNG:
+		return ExtPng
+	case v1.MimeType_MIME_TYPE_APPLICATION_PCD:
+		return ExtPcd
+	case v1.MimeType_MIME_TYPE_VIDEO_MP4:
+		return ExtMP4
+	case v1.MimeType_MIME_TYPE_UNSPECIFIED:
+	}
+	switch md.GetMimeType() {
+	case rutils.MimeTypeJPEG:
+		return ExtJpeg
+	case rutils.MimeTypePNG:
+		return ExtPng
+	case rutils.MimeTypePCD:
+		return ExtPcd
+	case rutils.MimeTypeVideoMP4:
+		return ExtMP4
+	case rutils.MimeTypeAudioWAV:
+		return ExtWav
+	case rutils.MimeTypeAudioMPEG:
+		return ExtMP3
+	}
+	return md.GetFileExtension()
+}
+
+// writeTabular writes the tabular readings of group, in the order they were requested, to one file.
+func (e *captureExporter) writeTabular(group exportGroup) (err error) {
+	table := e.tabular[group]
+	sort.SliceStable(table.files, func(i, j int) bool {
+		return table.files[i].first.Before(table.files[j].first)
+	})
+	dir := group.dir(e.outputDir)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	name := filepath.Join(dir, strings.ReplaceAll(group.methodName, filePathReservedChars, "_")+"."+string(e.opts.Format))
+	//nolint:gosec
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Combine(err, f.Close())
+	}()
+
+	buffered := bufio.NewWriter(f)
+	columns := exportColumns(table.types)
+	var w tabularWriter
+	switch e.opts.Format {
+	case ExportFormatJSONL:
+		w = newJSONLTabularWriter(buffered)
+	case ExportFormatParquet:
+		w, err = newParquetTabularWriter(buffered, columns, exportParquetRowGroupRows)
+	case ExportFormatCSV:
+		fallthrough
+	default:
+		w, err = newCSVTabularWriter(buffered, columns)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "failed to write %s", name)
+	}
+	var rows int
+	for _, source := range table.files {
+		if err := e.readCaptureFile(source.path, func(_ *v1.DataCaptureMetadata, item *v1.SensorData) error {
+			rows++
+			return w.write(item)
+		}); err != nil {
+			return errors.Wrapf(err, "failed to write %s", name)
+		}
+	}
+	if err := multierr.Combine(w.close(), buffered.Flush()); err != nil {
+		return errors.Wrapf(err, "failed to write %s", name)
+	}
+	e.summary.TabularReadings += rows
+	e.summary.Files = append(e.summary.Files, name)
+	return nil
+}
+
+// A tabularWriter writes tabular readings to a file of some format, one at a time.
+type tabularWriter interface {
+	write(row *v1.SensorData) error
+	// close finishes the file, without closing what it is written to.
+	close() error
+}
+
+// exportColumn is a flattened field of the tabular readings of an export.
+type exportColumn struct {
+	name string
+	typ  parquetType
+}
+
+// flattenRow returns the leaves of a reading by their dotted paths.
+func flattenRow(row *v1.SensorData) map[string]*structpb.Value {
+	fields := map[string]*structpb.Value{}
+	flattenLeaves("", row.GetStruct(), fields)
+	return fields
+}
+
+// leafType returns the type of the column a leaf of a reading belongs in, or false for a null, which fits any column.
+func leafType(value *structpb.Value) (parquetType, bool) {
+	switch value.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		return parquetDouble, true
+	case *structpb.Value_BoolValue:
+		return parquetBoolean, true
+	case *structpb.Value_StringValue:
+		return parquetString, true
+	default:
+		return 0, false
+	}
+}
+
+// exportColumns returns the columns of the given types, sorted by name.
+func exportColumns(types map[string]parquetType) []exportColumn {
+	columns := make([]exportColumn, 0, len(types))
+	for name, typ := range types {
+		columns = append(columns, exportColumn{name: name, typ: typ})
+	}
+	sort.Slice(columns, func(i, j int) bool {
+		return columns[i].name < columns[j].name
+	})
+	return columns
+}
+
+// exportString formats a leaf of a reading, or returns an empty string for a null.
+func exportString(value *structpb.Value) string {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		return strconv.FormatFloat(kind.NumberValue, 'f', -1, 64)
+	case *structpb.Value_BoolValue:
+		return strconv.FormatBool(kind.BoolValue)
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	default:
+		return ""
+	}
+}
+
+func exportTimes(row *v1.SensorData) (time.Time, time.Time) {
+	return row.GetMetadata().GetTimeRequested().AsTime().UTC(), row.GetMetadata().GetTimeReceived().AsTime().UTC()
+}
+
+type csvTabularWriter struct {
+	w       *csv.Writer
+	columns []exportColumn
+}
+
+// newCSVTabularWriter returns a writer of readings as CSV rows, after writing the header of the given columns.
+func newCSVTabularWriter(w io.Writer, columns []exportColumn) (*csvTabularWriter, error) {
+	csvWriter := csv.NewWriter(w)
+	header := []string{exportTimeRequested, exportTimeReceived}
+	for _, c := range columns {
+		header = append(header, c.name)
+	}
+	if err := csvWriter.Write(header); err != nil {
+		return nil, err
+	}
+	return &csvTabularWriter{w: csvWriter, columns: columns}, nil
+}
+
+func (c *csvTabularWriter) write(row *v1.SensorData) error {
+	fields := flattenRow(row)
+	timeRequested, timeReceived := exportTimes(row)
+	record := []string{timeRequested.Format(time.RFC3339Nano), timeReceived.Format(time.RFC3339Nano)}
+	for _, column := range c.columns {
+		record = append(record, exportString(fields[column.name]))
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvTabularWriter) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type jsonlTabularWriter struct {
+	encoder *json.Encoder
+}
+
+// newJSONLTabularWriter returns a writer of readings as JSON objects, one to a line.
+func newJSONLTabularWriter(w io.Writer) *jsonlTabularWriter {
+	return &jsonlTabularWriter{encoder: json.NewEncoder(w)}
+}
+
+func (j *jsonlTabularWriter) write(row *v1.SensorData) error {
+	timeRequested, timeReceived := exportTimes(row)
+	return j.encoder.Encode(map[string]interface{}{
+		exportTimeRequested: timeRequested,
+		exportTimeReceived:  timeReceived,
+		exportData:          jsonValue(structpb.NewStructValue(row.GetStruct())),
+	})
+}
+
+func (j *jsonlTabularWriter) close() error {
+	return nil
+}
+
+// jsonValue converts a value of a reading to one that encoding/json can encode, writing the numbers that JSON has no
+// representation for, NaN and the infinities, as null.
+func jsonValue(value *structpb.Value) interface{} {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_NumberValue:
+		if math.IsNaN(kind.NumberValue) || math.IsInf(kind.NumberValue, 0) {
+			return nil
+		}
+		return kind.NumberValue
+	case *structpb.Value_BoolValue:
+		return kind.BoolValue
+	case *structpb.Value_StringValue:
+		return kind.StringValue
+	case *structpb.Value_StructValue:
+		m := make(map[string]interface{}, len(kind.StructValue.GetFields()))
+		for name, field := range kind.StructValue.GetFields() {
+			m[name] = jsonValue(field)
+		}
+		return m
+	case *structpb.Value_ListValue:
+		l := make([]interface{}, 0, len(kind.ListValue.GetValues()))
+		for _, element := range kind.ListValue.GetValues() {
+			l = append(l, jsonValue(element))
+		}
+		return l
+	default:
+		return nil
+	}
+}
+
+// exportParquetRowGroupRows is the number of readings exported to each row group of a parquet file, which bounds how
+// many are held in memory at once.
+const exportParquetRowGroupRows = 10000
+
+type parquetTabularWriter struct {
+	w            *parquetWriter
+	columns      []exportColumn
+	rowGroupRows int
+	// rowGroup holds the values of the readings of the row group being filled, with a column for each of the time
+	// requested, the time received and the columns.
+	rowGroup []parquetColumn
+	rows     int
+}
+
+// newParquetTabularWriter returns a writer of readings as parquet rows of the given columns, which writes a row
+// group every rowGroupRows readings.
+func newParquetTabularWriter(w io.Writer, columns []exportColumn, rowGroupRows int) (*parquetTabularWriter, error) {
+	rowGroup := []parquetColumn{
+		{name: exportTimeRequested, typ: parquetTimestamp},
+		{name: exportTimeReceived, typ: parquetTimestamp},
+	}
+	for _, c := range columns {
+		rowGroup = append(rowGroup, parquetColumn{name: c.name, typ: c.typ})
+	}
+	pw, err := newParquetWriter(w, rowGroup)
+	if err != nil {
+		return nil, err
+	}
+	return &parquetTabularWriter{w: pw, columns: columns, rowGroupRows: rowGroupRows, rowGroup: rowGroup}, nil
+}
+
+func (p *parquetTabularWriter) write(row *v1.SensorData) error {
+	fields := flattenRow(row)
+	requested, received := exportTimes(row)
+	p.rowGroup[0].values = append(p.rowGroup[0].values, requested)
+	p.rowGroup[1].values = append(p.rowGroup[1].values, received)
+	for i, c := range p.columns {
+		value := fields[c.name]
+		var v interface{}
+		switch kind := value.GetKind().(type) {
+		case *structpb.Value_NumberValue:
+			v = kind.NumberValue
+		case *structpb.Value_BoolValue:
+			v = kind.BoolValue
+		case *structpb.Value_StringValue:
+			v = kind.StringValue
+		}
+		if v != nil && c.typ == parquetString {
+			v = exportString(value)
+		}
+		p.rowGroup[i+2].values = append(p.rowGroup[i+2].values, v)
+	}
+	p.rows++
+	if p.rows < p.rowGroupRows {
+		return nil
+	}
+	return p.flush()
+}
+
+// flush writes the readings of the row group being filled as a row group, and starts a new one.
+func (p *parquetTabularWriter) flush() error {
+	if p.rows == 0 {
+		return nil
+	}
+	if err := p.w.writeRowGroup(p.rows, p.rowGroup); err != nil {
+		return err
+	}
+	for i := range p.rowGroup {
+		p.rowGroup[i].values = p.rowGroup[i].values[:0]
+	}
+	p.rows = 0
+	return nil
+}
+
+func (p *parquetTabularWriter) close() error {
+	if err := p.flush(); err != nil {
+		return err
+	}
+	return p.w.close()
+}
@@ -0,0 +1,421 @@
+package data
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	rutils "go.viam.com/rdk/utils"
+)
+
+// writeExportFile writes a completed capture file to dir with the metadata md and a reading of each of the values at
+// one second intervals from replayStart, starting at the second offset.
+func writeExportFile(t *testing.T, dir string, md *v1.DataCaptureMetadata, offset int, values ...interface{}) {
+	t.Helper()
+	test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+	f, err := NewCaptureFile(dir, md)
+	test.That(t, err, test.ShouldBeNil)
+	for i, value := range values {
+		requested := replayStart.Add(time.Duration(offset+i) * time.Second)
+		reading := &v1.SensorData{Metadata: &v1.SensorMetadata{
+			TimeRequested: timestamppb.New(requested),
+			TimeReceived:  timestamppb.New(requested.Add(time.Millisecond)),
+		}}
+		if b, ok := value.([]byte); ok {
+			reading.Data = &v1.SensorData_Binary{Binary: b}
+		} else {
+			s, err := structpb.NewStruct(value.(map[string]interface{}))
+			test.That(t, err, test.ShouldBeNil)
+			reading.Data = &v1.SensorData_Struct{Struct: s}
+		}
+		test.That(t, f.WriteNext(reading), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+}
+
+func exportReadings(values map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"readings": values}
+}
+
+// writeExportCaptureDir writes capture files for a sensor split across two files, the readings of another sensor, and
+// the images of a camera.
+func writeExportCaptureDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	tabular := v1.DataType_DATA_TYPE_TABULAR_SENSOR
+	sensorMetadata := &v1.DataCaptureMetadata{
+		ComponentType: "rdk:component:sensor", ComponentName: "imu", MethodName: "Readings", Type: tabular,
+		Tags: []string{"field", "test"},
+	}
+	sensorDir := filepath.Join(dir, "rdk_component_sensor", "imu", "Readings")
+	writeExportFile(t, sensorDir, sensorMetadata, 2,
+		exportReadings(map[string]interface{}{"temp": 21, "ok": false, "mode": 2, "pos": []interface{}{3, 4}}))
+	writeExportFile(t, sensorDir, sensorMetadata, 0,
+		exportReadings(map[string]interface{}{"temp": 20.5, "ok": true, "mode": "auto", "pos": []interface{}{1, 2}}),
+		exportReadings(map[string]interface{}{"temp": math.NaN(), "mode": "manual"}))
+	writeExportFile(t, filepath.Join(dir, "other"),
+		&v1.DataCaptureMetadata{
+			ComponentType: "rdk:component:sensor", ComponentName: "gps", MethodName: "Readings", Type: tabular,
+			Tags: []string{"field"},
+		}, 0,
+		exportReadings(map[string]interface{}{"lat": 40.7}))
+	writeExportFile(t, filepath.Join(dir, "camera"),
+		&v1.DataCaptureMetadata{
+			ComponentType: "rdk:component:camera", ComponentName: "cam", MethodName: "ReadImage",
+			Type: v1.DataType_DATA_TYPE_BINARY_SENSOR, MimeType: rutils.MimeTypeJPEG,
+		}, 0,
+		[]byte("first"), []byte("second"))
+	return dir
+}
+
+func readExportCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	//nolint:gosec
+	f, err := os.Open(path)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	test.That(t, err, test.ShouldBeNil)
+	return records
+}
+
+func TestExportCaptureDir(t *testing.T) {
+	captureDir := writeExportCaptureDir(t)
+
+	t.Run("csv", func(t *testing.T) {
+		out := t.TempDir()
+		summary, err := ExportCaptureDir(captureDir, out, ExportOptions{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, summary.TabularReadings, test.ShouldEqual, 4)
+		test.That(t, summary.BinaryReadings, test.ShouldEqual, 2)
+		test.That(t, summary.Files, test.ShouldHaveLength, 4)
+
+		// Readings from both files come out in order, flattened, with the mixed type mode column as strings.
+		records := readExportCSV(t, filepath.Join(out, "rdk_component_sensor", "imu", "Readings.csv"))
+		test.That(t, records, test.ShouldResemble, [][]string{
+			{"time_requested", "time_received", "readings.mode", "readings.ok", "readings.pos.0", "readings.pos.1", "readings.temp"},
+			{"2024-01-01T12:00:00Z", "2024-01-01T12:00:00.001Z", "auto", "true", "1", "2", "20.5"},
+			{"2024-01-01T12:00:01Z", "2024-01-01T12:00:01.001Z", "manual", "", "", "", "NaN"},
+			{"2024-01-01T12:00:02Z", "2024-01-01T12:00:02.001Z", "2", "false", "3", "4", "21"},
+		})
+		records = readExportCSV(t, filepath.Join(out, "rdk_component_sensor", "gps", "Readings.csv"))
+		test.That(t, records, test.ShouldHaveLength, 2)
+		test.That(t, records[1][2], test.ShouldEqual, "40.7")
+
+		// Binary readings are named by their time requested, with the extension of their mime type.
+		imageDir := filepath.Join(out, "rdk_component_camera", "cam", "ReadImage")
+		for name, contents := range map[string]string{
+			"2024-01-01T12_00_00.000000Z.jpeg": "first",
+			"2024-01-01T12_00_01.000000Z.jpeg": "second",
+		} {
+			//nolint:gosec
+			b, err := os.ReadFile(filepath.Join(imageDir, name))
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, string(b), test.ShouldEqual, contents)
+		}
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		out := t.TempDir()
+		_, err := ExportCaptureDir(captureDir, out, ExportOptions{Format: ExportFormatJSONL, ComponentName: "imu"})
+		test.That(t, err, test.ShouldBeNil)
+		//nolint:gosec
+		b, err := os.ReadFile(filepath.Join(out, "rdk_component_sensor", "imu", "Readings.jsonl"))
+		test.That(t, err, test.ShouldBeNil)
+		lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+		test.That(t, lines, test.ShouldHaveLength, 3)
+		var first, second map[string]interface{}
+		test.That(t, json.Unmarshal([]byte(lines[0]), &first), test.ShouldBeNil)
+		test.That(t, json.Unmarshal([]byte(lines[1]), &second), test.ShouldBeNil)
+		test.That(t, first, test.ShouldResemble, map[string]interface{}{
+			"time_requested": "2024-01-01T12:00:00Z",
+			"time_received":  "2024-01-01T12:00:00.001Z",
+			"data": exportReadings(map[string]interface{}{
+				"temp": 20.5, "ok": true, "mode": "auto", "pos": []interface{}{1.0, 2.0},
+			}),
+		})
+		// NaN has no representation in JSON.
+		test.That(t, second["data"], test.ShouldResemble, exportReadings(map[string]interface{}{"temp": nil, "mode": "manual"}))
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		out := t.TempDir()
+		_, err := ExportCaptureDir(captureDir, out, ExportOptions{Format: ExportFormatParquet, ComponentName: "imu"})
+		test.That(t, err, test.ShouldBeNil)
+		//nolint:gosec
+		b, err := os.ReadFile(filepath.Join(out, "rdk_component_sensor", "imu", "Readings.parquet"))
+		test.That(t, err, test.ShouldBeNil)
+		columns, rowGroups := readParquet(t, b)
+		test.That(t, rowGroups, test.ShouldEqual, 1)
+		test.That(t, columns, test.ShouldHaveLength, 7)
+		test.That(t, columns[0].name, test.ShouldEqual, "time_requested")
+		test.That(t, columns[0].values, test.ShouldResemble, []interface{}{
+			replayStart.UnixMicro(), replayStart.Add(time.Second).UnixMicro(), replayStart.Add(2 * time.Second).UnixMicro(),
+		})
+		test.That(t, columns[2].name, test.ShouldEqual, "readings.mode")
+		test.That(t, columns[2].values, test.ShouldResemble, []interface{}{"auto", "manual", "2"})
+		test.That(t, columns[3].name, test.ShouldEqual, "readings.ok")
+		test.That(t, columns[3].values, test.ShouldResemble, []interface{}{true, nil, false})
+		test.That(t, columns[6].name, test.ShouldEqual, "readings.temp")
+		test.That(t, columns[6].values[0], test.ShouldEqual, 20.5)
+		test.That(t, math.IsNaN(columns[6].values[1].(float64)), test.ShouldBeTrue)
+		test.That(t, columns[6].values[2], test.ShouldEqual, 21.0)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		for _, tc := range []struct {
+			name    string
+			opts    ExportOptions
+			tabular int
+			binary  int
+		}{
+			{"component type api", ExportOptions{ComponentType: "rdk:component:camera"}, 0, 2},
+			{"component type subtype", ExportOptions{ComponentType: "sensor"}, 4, 0},
+			{"component name", ExportOptions{ComponentName: "gps"}, 1, 0},
+			{"method", ExportOptions{MethodName: "ReadImage"}, 0, 2},
+			{"one tag", ExportOptions{Tags: []string{"field"}}, 4, 0},
+			{"all tags", ExportOptions{Tags: []string{"field", "test"}}, 3, 0},
+			{"time", ExportOptions{Start: replayStart.Add(time.Second), End: replayStart.Add(time.Second)}, 1, 1},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				summary, err := ExportCaptureDir(captureDir, t.TempDir(), tc.opts)
+				test.That(t, err, test.ShouldBeNil)
+				test.That(t, summary.TabularReadings, test.ShouldEqual, tc.tabular)
+				test.That(t, summary.BinaryReadings, test.ShouldEqual, tc.binary)
+			})
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := ExportCaptureDir(captureDir, t.TempDir(), ExportOptions{Format: "xlsx"})
+		test.That(t, err, test.ShouldBeError,
+			`unsupported export format "xlsx", expected one of "csv", "jsonl" or "parquet"`)
+	})
+}
+
+func TestExportBinaryNames(t *testing.T) {
+	captureDir := t.TempDir()
+	md := &v1.DataCaptureMetadata{
+		ComponentType: "rdk:component:camera", ComponentName: "cam", MethodName: "GetImages",
+		Type: v1.DataType_DATA_TYPE_BINARY_SENSOR,
+	}
+	// Images of a single capture share their time requested, and carry their own mime types.
+	f, err := NewCaptureFile(captureDir, md)
+	test.That(t, err, test.ShouldBeNil)
+	ts := timestamppb.New(replayStart)
+	for _, mimeType := range []v1.MimeType{v1.MimeType_MIME_TYPE_IMAGE_JPEG, v1.MimeType_MIME_TYPE_IMAGE_PNG} {
+		test.That(t, f.WriteNext(&v1.SensorData{
+			Metadata: &v1.SensorMetadata{TimeRequested: ts, TimeReceived: ts, MimeType: mimeType},
+			Data:     &v1.SensorData_Binary{Binary: []byte{1}},
+		}), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+
+	out := t.TempDir()
+	summary, err := ExportCaptureDir(captureDir, out, ExportOptions{})
+	test.That(t, err, test.ShouldBeNil)
+	dir := filepath.Join(out, "rdk_component_camera", "cam", "GetImages")
+	test.That(t, summary.Files, test.ShouldResemble, []string{
+		filepath.Join(dir, "2024-01-01T12_00_00.000000Z.jpeg"),
+		filepath.Join(dir, "2024-01-01T12_00_00.000000Z_1.png"),
+	})
+}
+
+func TestParquetTabularWriterRowGroups(t *testing.T) {
+	var b bytes.Buffer
+	columns := []exportColumn{{name: "readings.mode", typ: parquetString}, {name: "readings.temp", typ: parquetDouble}}
+	w, err := newParquetTabularWriter(&b, columns, 2)
+	test.That(t, err, test.ShouldBeNil)
+	var expectedTimes, expectedTemps []interface{}
+	for i := 0; i < 5; i++ {
+		requested := replayStart.Add(time.Duration(i) * time.Second)
+		s, err := structpb.NewStruct(exportReadings(map[string]interface{}{"temp": float64(i)}))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, w.write(&v1.SensorData{
+			Metadata: &v1.SensorMetadata{TimeRequested: timestamppb.New(requested), TimeReceived: timestamppb.New(requested)},
+			Data:     &v1.SensorData_Struct{Struct: s},
+		}), test.ShouldBeNil)
+		expectedTimes = append(expectedTimes, requested.UnixMicro())
+		expectedTemps = append(expectedTemps, float64(i))
+	}
+	// Full row groups are written as they fill, and the rest when the writer is closed.
+	test.That(t, w.close(), test.ShouldBeNil)
+
+	read, rowGroups := readParquet(t, b.Bytes())
+	test.That(t, rowGroups, test.ShouldEqual, 3)
+	test.That(t, read, test.ShouldHaveLength, 4)
+	test.That(t, read[0].values, test.ShouldResemble, expectedTimes)
+	test.That(t, read[2].name, test.ShouldEqual, "readings.mode")
+	test.That(t, read[2].values, test.ShouldResemble, []interface{}{nil, nil, nil, nil, nil})
+	test.That(t, read[3].name, test.ShouldEqual, "readings.temp")
+	test.That(t, read[3].values, test.ShouldResemble, expectedTemps)
+}
+
+// parquetTestColumn is a column read back from a parquet file, with its values as bools, int64s, float64s or
+// strings, or nil for nulls.
+type parquetTestColumn struct {
+	name   string
+	values []interface{}
+}
+
+// readParquet reads back the columns of a parquet file written by a parquetWriter, with the values of all of its row
+// groups, and the number of row groups.
+func readParquet(t *testing.T, b []byte) ([]parquetTestColumn, int) {
+	t.Helper()
+	test.That(t, string(b[:4]), test.ShouldEqual, "PAR1")
+	test.That(t, string(b[len(b)-4:]), test.ShouldEqual, "PAR1")
+	footerLen := int(b[len(b)-8]) | int(b[len(b)-7])<<8 | int(b[len(b)-6])<<16 | int(b[len(b)-5])<<24
+	footer := (&thriftCompactReader{t: t, b: b[len(b)-8-footerLen : len(b)-8]}).readStruct()
+	schema := footer[2].([]interface{})
+	rowGroups := footer[4].([]interface{})
+
+	columns := make([]parquetTestColumn, len(schema)-1)
+	var totalRows int
+	for _, rowGroup := range rowGroups {
+		numRows := int(rowGroup.(map[int16]interface{})[3].(int64))
+		totalRows += numRows
+		chunks := rowGroup.(map[int16]interface{})[1].([]interface{})
+		test.That(t, chunks, test.ShouldHaveLength, len(columns))
+		for i, chunk := range chunks {
+			md := chunk.(map[int16]interface{})[3].(map[int16]interface{})
+			physicalType := md[1].(int64)
+			columns[i].name = string(md[3].([]interface{})[0].([]byte))
+			test.That(t, columns[i].name, test.ShouldEqual, string(schema[i+1].(map[int16]interface{})[4].([]byte)))
+			test.That(t, md[5].(int64), test.ShouldEqual, int64(numRows))
+
+			r := &thriftCompactReader{t: t, b: b[md[9].(int64):]}
+			header := r.readStruct()
+			page := r.b[:header[3].(int64)]
+			levelsLen := int(page[0]) | int(page[1])<<8 | int(page[2])<<16 | int(page[3])<<24
+			levels := page[4 : 4+levelsLen]
+			values := page[4+levelsLen:]
+
+			var bit, read int
+			for read < numRows {
+				// Each run of definition levels is its length, shifted left by one, followed by a byte of the level.
+				runLen, n := readUvarint(levels)
+				defined := levels[n] == 1
+				levels = levels[n+1:]
+				for j := 0; j < int(runLen>>1); j++ {
+					read++
+					if !defined {
+						columns[i].values = append(columns[i].values, nil)
+						continue
+					}
+					switch physicalType {
+					case parquetPhysicalBoolean:
+						columns[i].values = append(columns[i].values, values[bit/8]&(1<<(bit%8)) != 0)
+						bit++
+					case parquetPhysicalInt64:
+						columns[i].values = append(columns[i].values, int64(leUint64(values)))
+						values = values[8:]
+					case parquetPhysicalDouble:
+						columns[i].values = append(columns[i].values, math.Float64frombits(leUint64(values)))
+						values = values[8:]
+					case parquetPhysicalByteArray:
+						n := int(values[0]) | int(values[1])<<8 | int(values[2])<<16 | int(values[3])<<24
+						columns[i].values = append(columns[i].values, string(values[4:4+n]))
+						values = values[4+n:]
+					}
+				}
+			}
+		}
+	}
+	test.That(t, int64(totalRows), test.ShouldEqual, footer[3].(int64))
+	return columns, len(rowGroups)
+}
+
+func leUint64(b []byte) uint64 {
+	var v uint64
+	for i := 7; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+func readUvarint(b []byte) (uint64, int) {
+	var v uint64
+	for i, c := range b {
+		v |= uint64(c&0x7F) << (7 * i)
+		if c&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, len(b)
+}
+
+// thriftCompactReader reads structs in the thrift compact protocol into maps from field ID to value.
+type thriftCompactReader struct {
+	t *testing.T
+	b []byte
+}
+
+func (r *thriftCompactReader) varint() uint64 {
+	v, n := readUvarint(r.b)
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *thriftCompactReader) zigzag() int64 {
+	v := r.varint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *thriftCompactReader) readStruct() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	var last int16
+	for {
+		header := r.b[0]
+		r.b = r.b[1:]
+		if header == 0 {
+			return fields
+		}
+		typ := header & 0x0F
+		if delta := int16(header >> 4); delta != 0 {
+			last += delta
+		} else {
+			last = int16(r.zigzag())
+		}
+		fields[last] = r.readValue(typ)
+	}
+}
+
+func (r *thriftCompactReader) readValue(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.zigzag()
+	case thriftBinary:
+		n := r.varint()
+		v := bytes.Clone(r.b[:n])
+		r.b = r.b[n:]
+		return v
+	case thriftList:
+		header := r.b[0]
+		r.b = r.b[1:]
+		n := uint64(header >> 4)
+		if n == 15 {
+			n = r.varint()
+		}
+		list := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			list = append(list, r.readValue(header&0x0F))
+		}
+		return list
+	case thriftStruct:
+		return r.readStruct()
+	default:
+		r.t.Fatalf("unexpected thrift type %d", typ)
+		return nil
+	}
+}
@@ -2,6 +2,7 @@ package data
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -64,7 +65,8 @@ const (
 
 // CaptureFile is the data structure containing data captured by collectors. It is backed by a file on disk containing
 // length delimited protobuf messages, where the first message is the CaptureMetadata for the file, and ensuing
-// messages contain the captured data.
+// messages contain the captured data. When it is written with a keyring that has an active key, the file is encrypted
+// and each message is a sealed record instead (see encryption.go).
 type CaptureFile struct {
 	path     string
 	lock     sync.Mutex
@@ -72,14 +74,22 @@ type CaptureFile struct {
 	writer   *bufio.Writer
 	size     int64
 	metadata *v1.DataCaptureMetadata
+	// cipher is nil for plaintext files.
+	cipher *fileCipher
 
 	initialReadOffset int64
 	readOffset        int64
 	writeOffset       int64
 }
 
-// ReadCaptureFile creates a File struct from a passed os.File previously constructed using NewFile.
+// ReadCaptureFile creates a File struct from a passed os.File previously constructed using NewFile. It returns an error
+// wrapping ErrUnknownEncryptionKey if the file is encrypted; use ReadCaptureFileWithKeyring to read those.
 func ReadCaptureFile(f *os.File) (*CaptureFile, error) {
+	return ReadCaptureFileWithKeyring(f, nil)
+}
+
+// ReadCaptureFileWithKeyring is ReadCaptureFile for a file which may have been encrypted with one of the keys in k.
+func ReadCaptureFileWithKeyring(f *os.File, k *Keyring) (*CaptureFile, error) {
 	if !IsDataCaptureFile(f) {
 		return nil, errors.Errorf("%s is not a data capture file", f.Name())
 	}
@@ -89,9 +99,27 @@ func ReadCaptureFile(f *os.File) (*CaptureFile, error) {
 	}
 
 	md := &v1.DataCaptureMetadata{}
-	initOffset, err := pbutil.ReadDelimited(f, md)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to read DataCaptureMetadata from %s", f.Name())
+	var fc *fileCipher
+	var initOffset int64
+	if IsEncryptedFile(f) {
+		fc, err = k.readFileCipher(f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "capture file %s", f.Name())
+		}
+		mdBytes, n, err := fc.openRecord(f, fc.bodyStart)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read DataCaptureMetadata from %s", f.Name())
+		}
+		if err := proto.Unmarshal(mdBytes, md); err != nil {
+			return nil, errors.Wrapf(err, "failed to read DataCaptureMetadata from %s", f.Name())
+		}
+		initOffset = fc.bodyStart + n
+	} else {
+		n, err := pbutil.ReadDelimited(f, md)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read DataCaptureMetadata from %s", f.Name())
+		}
+		initOffset = int64(n)
 	}
 
 	ret := CaptureFile{
@@ -100,9 +128,10 @@ func ReadCaptureFile(f *os.File) (*CaptureFile, error) {
 		writer:            bufio.NewWriter(f),
 		size:              finfo.Size(),
 		metadata:          md,
-		initialReadOffset: int64(initOffset),
-		readOffset:        int64(initOffset),
-		writeOffset:       int64(initOffset),
+		cipher:            fc,
+		initialReadOffset: initOffset,
+		readOffset:        initOffset,
+		writeOffset:       initOffset,
 	}
 
 	return &ret, nil
@@ -110,6 +139,16 @@ func ReadCaptureFile(f *os.File) (*CaptureFile, error) {
 
 // NewCaptureFile creates a new *CaptureFile with the specified md in the specified directory.
 func NewCaptureFile(dir string, md *v1.DataCaptureMetadata) (*CaptureFile, error) {
+	return NewCaptureFileWithKeyring(dir, md, nil)
+}
+
+// NewCaptureFileWithKeyring is NewCaptureFile for a file which is encrypted with the active key of k if it has one.
+func NewCaptureFileWithKeyring(dir string, md *v1.DataCaptureMetadata, k *Keyring) (*CaptureFile, error) {
+	header, fc, err := k.newFileCipher()
+	if err != nil {
+		return nil, err
+	}
+
 	fileName := CaptureFilePathWithReplacedReservedChars(
 		filepath.Join(dir, getFileTimestampName()) + InProgressCaptureFileExt)
 	//nolint:gosec
@@ -118,19 +157,38 @@ func NewCaptureFile(dir string, md *v1.DataCaptureMetadata) (*CaptureFile, error
 		return nil, err
 	}
 
-	// Then write first metadata message to the file.
-	n, err := pbutil.WriteDelimited(f, md)
-	if err != nil {
-		return nil, err
+	// Then write first metadata message to the file, after the encryption header if it is encrypted.
+	var n int64
+	if fc != nil {
+		mdBytes, err := proto.Marshal(md)
+		if err != nil {
+			return nil, err
+		}
+		record, err := fc.sealRecord(fc.bodyStart, mdBytes)
+		if err != nil {
+			return nil, err
+		}
+		written, err := f.Write(append(header, record...))
+		if err != nil {
+			return nil, err
+		}
+		n = int64(written)
+	} else {
+		written, err := pbutil.WriteDelimited(f, md)
+		if err != nil {
+			return nil, err
+		}
+		n = int64(written)
 	}
 	return &CaptureFile{
 		path:              f.Name(),
 		writer:            bufio.NewWriter(f),
 		file:              f,
-		size:              int64(n),
-		initialReadOffset: int64(n),
-		readOffset:        int64(n),
-		writeOffset:       int64(n),
+		size:              n,
+		cipher:            fc,
+		initialReadOffset: n,
+		readOffset:        n,
+		writeOffset:       n,
 	}, nil
 }
 
@@ -152,6 +210,17 @@ func (f *CaptureFile) ReadNext() (*v1.SensorData, error) {
 		return nil, err
 	}
 	r := v1.SensorData{}
+	if f.cipher != nil {
+		b, read, err := f.cipher.openRecord(f.file, f.readOffset)
+		if err != nil {
+			return nil, err
+		}
+		if err := proto.Unmarshal(b, &r); err != nil {
+			return nil, err
+		}
+		f.readOffset += read
+		return &r, nil
+	}
 	read, err := pbutil.ReadDelimited(f.file, &r)
 	if err != nil {
 		return nil, err
@@ -169,6 +238,22 @@ func (f *CaptureFile) WriteNext(data *v1.SensorData) error {
 	if _, err := f.file.Seek(f.writeOffset, 0); err != nil {
 		return err
 	}
+	if f.cipher != nil {
+		b, err := proto.Marshal(data)
+		if err != nil {
+			return err
+		}
+		record, err := f.cipher.sealRecord(f.writeOffset, b)
+		if err != nil {
+			return err
+		}
+		if _, err := f.writer.Write(record); err != nil {
+			return err
+		}
+		f.size += int64(len(record))
+		f.writeOffset += int64(len(record))
+		return nil
+	}
 	n, err := pbutil.WriteDelimited(f.writer, data)
 	if err != nil {
 		return err
@@ -192,6 +277,21 @@ func (f *CaptureFile) Reset() {
 	f.readOffset = f.initialReadOffset
 }
 
+// ReadOffset returns the offset in the file of the reading that ReadNext returns next.
+func (f *CaptureFile) ReadOffset() int64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.readOffset
+}
+
+// SeekRead moves the read pointer of f to offset, which must be an offset returned by ReadOffset, so that ReadNext
+// returns the reading there next.
+func (f *CaptureFile) SeekRead(offset int64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.readOffset = offset
+}
+
 // Size returns the size of the file.
 func (f *CaptureFile) Size() int64 {
 	f.lock.Lock()
@@ -326,6 +426,23 @@ func (f *CaptureFile) BinaryPayloadReader() (*v1.SensorMetadata, int64, io.Reade
 		return nil, 0, nil, err
 	}
 
+	// Encrypted records can only be authenticated as a whole, so the payload is decrypted into memory.
+	if f.cipher != nil {
+		b, read, err := f.cipher.openRecord(f.file, seekOffset)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		f.readOffset += read
+		var sd v1.SensorData
+		if err := proto.Unmarshal(b, &sd); err != nil {
+			return nil, 0, nil, err
+		}
+		if !IsBinary(&sd) {
+			return nil, 0, nil, ErrNoBinaryField
+		}
+		return sd.GetMetadata(), int64(len(sd.GetBinary())), bytes.NewReader(sd.GetBinary()), nil
+	}
+
 	// The capture file is a sequence of length-delimited protobuf records. Each record
 	// is: [outerLen varint] [SensorData proto bytes].
 	// Read outerLen so we know where this message ends and the next begins.
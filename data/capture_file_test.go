@@ -156,6 +156,33 @@ func TestBuildCaptureMetadata(t *testing.T) {
 	}
 }
 
+func TestCaptureFileSeekRead(t *testing.T) {
+	cf, err := NewCaptureFile(t.TempDir(), &v1.DataCaptureMetadata{Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR})
+	test.That(t, err, test.ShouldBeNil)
+	for i := 0; i < 3; i++ {
+		s, err := structpb.NewStruct(map[string]interface{}{"i": i})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, cf.WriteNext(&v1.SensorData{Data: &v1.SensorData_Struct{Struct: s}}), test.ShouldBeNil)
+	}
+
+	var offsets []int64
+	for i := 0; i < 3; i++ {
+		offsets = append(offsets, cf.ReadOffset())
+		_, err := cf.ReadNext()
+		test.That(t, err, test.ShouldBeNil)
+	}
+	_, err = cf.ReadNext()
+	test.That(t, err, test.ShouldBeError, io.EOF)
+
+	for _, i := range []int{1, 0, 2} {
+		cf.SeekRead(offsets[i])
+		next, err := cf.ReadNext()
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, next.GetStruct().GetFields()["i"].GetNumberValue(), test.ShouldEqual, i)
+	}
+	test.That(t, cf.Delete(), test.ShouldBeNil)
+}
+
 func TestBinaryPayloadReader(t *testing.T) {
 	// captureFileFromSensorData writes msgs to a new capture file, closes it (which
 	// renames it from .prog to .capture), then reopens it for reading.
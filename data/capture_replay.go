@@ -0,0 +1,221 @@
+package data
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	v1 "go.viam.com/api/app/datasync/v1"
+)
+
+// ErrEndOfCapture is returned by a CaptureReplayer that has played back all of its readings and does not loop.
+var ErrEndOfCapture = errors.New("reached end of captured data")
+
+// ReplayOptions configures how a CaptureReplayer plays back captured readings.
+type ReplayOptions struct {
+	// Start and End, if set, bound the time requested of the readings to play back.
+	Start time.Time
+	End   time.Time
+	// Loop starts playback over from the first reading once the last one has been played.
+	Loop bool
+	// PlaybackRate, if positive, plays the readings back on a clock running at that multiple of real time, so that
+	// each call to Next returns the latest reading as of the replay clock, as the resource would have returned at that
+	// moment. If zero, each call to Next returns the reading after the previous one, no matter when it is called.
+	PlaybackRate float64
+	// Clock is the clock playback runs on. Defaults to the real clock.
+	Clock clock.Clock
+	// Keyring decrypts capture files that were encrypted at rest.
+	Keyring *Keyring
+}
+
+// replayEntry is one reading found in a capture directory.
+type replayEntry struct {
+	path          string
+	offset        int64
+	timeRequested time.Time
+	// data is kept for tabular readings, which are small. Binary readings are read from disk when they are played.
+	data *v1.SensorData
+}
+
+// CaptureReplayer plays back the readings that data capture wrote to a local capture directory for one method of
+// one resource, in the order they were requested.
+type CaptureReplayer struct {
+	entries []replayEntry
+	opts    ReplayOptions
+
+	mu      sync.Mutex
+	next    int
+	started time.Time
+}
+
+// NewCaptureReplayer indexes the completed capture files anywhere under captureDir whose metadata matches the
+// component name and method.
+func NewCaptureReplayer(captureDir, componentName, methodName string, opts ReplayOptions) (*CaptureReplayer, error) {
+	if opts.PlaybackRate < 0 {
+		return nil, errors.Errorf("playback rate must not be negative, got %v", opts.PlaybackRate)
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.New()
+	}
+	r := &CaptureReplayer{opts: opts}
+	err := filepath.WalkDir(captureDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != CompletedCaptureFileExt {
+			return nil
+		}
+		return r.indexFile(path, componentName, methodName)
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read capture directory %s", captureDir)
+	}
+	sort.SliceStable(r.entries, func(i, j int) bool {
+		return r.entries[i].timeRequested.Before(r.entries[j].timeRequested)
+	})
+	return r, nil
+}
+
+// indexFile adds the readings in the capture file at path, if its metadata matches, to the entries of r.
+func (r *CaptureReplayer) indexFile(path, componentName, methodName string) (err error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = multierr.Combine(err, f.Close())
+	}()
+	cf, err := ReadCaptureFileWithKeyring(f, r.opts.Keyring)
+	if err != nil {
+		return err
+	}
+	md := cf.ReadMetadata()
+	if md.GetComponentName() != componentName || md.GetMethodName() != methodName {
+		return nil
+	}
+	binary := md.GetType() == v1.DataType_DATA_TYPE_BINARY_SENSOR
+
+	for {
+		offset := cf.ReadOffset()
+		next, err := cf.ReadNext()
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil
+			}
+			return errors.Wrapf(err, "failed to read %s", path)
+		}
+		timeRequested := next.GetMetadata().GetTimeRequested().AsTime()
+		if (!r.opts.Start.IsZero() && timeRequested.Before(r.opts.Start)) ||
+			(!r.opts.End.IsZero() && timeRequested.After(r.opts.End)) {
+			continue
+		}
+		entry := replayEntry{path: path, offset: offset, timeRequested: timeRequested}
+		if !binary {
+			entry.data = next
+		}
+		r.entries = append(r.entries, entry)
+	}
+}
+
+// Len returns the number of readings to play back.
+func (r *CaptureReplayer) Len() int {
+	return len(r.entries)
+}
+
+// Next returns the next reading to play back, or ErrEndOfCapture if there is none.
+func (r *CaptureReplayer) Next(ctx context.Context) (*v1.SensorData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) == 0 {
+		return nil, ErrEndOfCapture
+	}
+
+	var i int
+	var err error
+	if r.opts.PlaybackRate > 0 {
+		i, err = r.nextOnClock()
+	} else {
+		i, err = r.nextInOrder()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.read(r.entries[i])
+}
+
+// nextInOrder returns the index of the reading after the previous one.
+func (r *CaptureReplayer) nextInOrder() (int, error) {
+	if r.next == len(r.entries) {
+		if !r.opts.Loop {
+			return 0, ErrEndOfCapture
+		}
+		r.next = 0
+	}
+	r.next++
+	return r.next - 1, nil
+}
+
+// nextOnClock returns the index of the latest reading as of the replay clock, which starts at the first reading on
+// the first call.
+func (r *CaptureReplayer) nextOnClock() (int, error) {
+	now := r.opts.Clock.Now()
+	if r.started.IsZero() {
+		r.started = now
+	}
+	first := r.entries[0].timeRequested
+	duration := r.entries[len(r.entries)-1].timeRequested.Sub(first)
+	elapsed := time.Duration(float64(now.Sub(r.started)) * r.opts.PlaybackRate)
+
+	if elapsed > duration {
+		switch {
+		case !r.opts.Loop:
+			// Play the last reading once, however late it is asked for.
+			if r.next == len(r.entries) {
+				return 0, ErrEndOfCapture
+			}
+		case duration > 0:
+			// Leave the average time between readings after the last reading before playing the first one again.
+			period := duration + duration/time.Duration(len(r.entries)-1)
+			elapsed %= period
+		}
+	}
+	// The latest reading requested no later than the replay clock.
+	i := sort.Search(len(r.entries), func(i int) bool {
+		return r.entries[i].timeRequested.Sub(first) > elapsed
+	}) - 1
+	r.next = i + 1
+	return i, nil
+}
+
+// read returns the reading of the entry, reading it from disk if it was not kept in memory.
+func (r *CaptureReplayer) read(entry replayEntry) (data *v1.SensorData, err error) {
+	if entry.data != nil {
+		return entry.data, nil
+	}
+	//nolint:gosec
+	f, err := os.Open(entry.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		err = multierr.Combine(err, f.Close())
+	}()
+	cf, err := ReadCaptureFileWithKeyring(f, r.opts.Keyring)
+	if err != nil {
+		return nil, err
+	}
+	cf.SeekRead(entry.offset)
+	return cf.ReadNext()
+}
@@ -0,0 +1,164 @@
+package data
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var replayStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// writeReplayFile writes a completed capture file to dir with a reading at each of the offsets from replayStart.
+func writeReplayFile(t *testing.T, dir, name, method string, dataType v1.DataType, offsets ...time.Duration) {
+	t.Helper()
+	test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+	f, err := NewCaptureFile(dir, &v1.DataCaptureMetadata{ComponentName: name, MethodName: method, Type: dataType})
+	test.That(t, err, test.ShouldBeNil)
+	for _, offset := range offsets {
+		ts := timestamppb.New(replayStart.Add(offset))
+		reading := &v1.SensorData{Metadata: &v1.SensorMetadata{TimeRequested: ts, TimeReceived: ts}}
+		if dataType == v1.DataType_DATA_TYPE_BINARY_SENSOR {
+			reading.Data = &v1.SensorData_Binary{Binary: []byte(offset.String())}
+		} else {
+			reading.Data = &v1.SensorData_Struct{Struct: &structpb.Struct{Fields: map[string]*structpb.Value{
+				"offset": structpb.NewNumberValue(offset.Seconds()),
+			}}}
+		}
+		test.That(t, f.WriteNext(reading), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+}
+
+// nextOffset returns the offset from replayStart of the next reading of r.
+func nextOffset(t *testing.T, r *CaptureReplayer) (time.Duration, error) {
+	t.Helper()
+	reading, err := r.Next(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	return reading.GetMetadata().GetTimeRequested().AsTime().Sub(replayStart), nil
+}
+
+func TestCaptureReplayer(t *testing.T) {
+	dir := t.TempDir()
+	tabular := v1.DataType_DATA_TYPE_TABULAR_SENSOR
+	// Readings are split across files in nested directories, out of order, alongside readings of other methods and
+	// resources.
+	writeReplayFile(t, filepath.Join(dir, "a"), "sensor", "Readings", tabular, 2*time.Second, 3*time.Second)
+	writeReplayFile(t, filepath.Join(dir, "b", "c"), "sensor", "Readings", tabular, 0, time.Second)
+	writeReplayFile(t, filepath.Join(dir, "d"), "sensor", "DoCommand", tabular, 0)
+	writeReplayFile(t, filepath.Join(dir, "e"), "other", "Readings", tabular, 0)
+
+	t.Run("plays readings in order", func(t *testing.T) {
+		r, err := NewCaptureReplayer(dir, "sensor", "Readings", ReplayOptions{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, r.Len(), test.ShouldEqual, 4)
+		for i := 0; i < 4; i++ {
+			offset, err := nextOffset(t, r)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, offset, test.ShouldEqual, time.Duration(i)*time.Second)
+		}
+		_, err = r.Next(context.Background())
+		test.That(t, err, test.ShouldBeError, ErrEndOfCapture)
+	})
+
+	t.Run("loops", func(t *testing.T) {
+		r, err := NewCaptureReplayer(dir, "sensor", "Readings", ReplayOptions{Loop: true})
+		test.That(t, err, test.ShouldBeNil)
+		for i := 0; i < 9; i++ {
+			offset, err := nextOffset(t, r)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, offset, test.ShouldEqual, time.Duration(i%4)*time.Second)
+		}
+	})
+
+	t.Run("filters by time", func(t *testing.T) {
+		r, err := NewCaptureReplayer(dir, "sensor", "Readings", ReplayOptions{
+			Start: replayStart.Add(time.Second),
+			End:   replayStart.Add(2 * time.Second),
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, r.Len(), test.ShouldEqual, 2)
+		offset, err := nextOffset(t, r)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, offset, test.ShouldEqual, time.Second)
+	})
+
+	t.Run("plays on a clock", func(t *testing.T) {
+		mockClock := clock.NewMock()
+		r, err := NewCaptureReplayer(dir, "sensor", "Readings", ReplayOptions{PlaybackRate: 2, Clock: mockClock})
+		test.That(t, err, test.ShouldBeNil)
+
+		for _, step := range []struct {
+			advance time.Duration
+			offset  time.Duration
+		}{
+			{0, 0},
+			// Readings repeat until the replay clock reaches the next one.
+			{200 * time.Millisecond, 0},
+			// At twice real time, half a second reaches the second reading.
+			{300 * time.Millisecond, time.Second},
+			// Readings are skipped when asked for late.
+			{time.Second, 3 * time.Second},
+		} {
+			mockClock.Add(step.advance)
+			offset, err := nextOffset(t, r)
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, offset, test.ShouldEqual, step.offset)
+		}
+		// Playback ends once the replay clock passes the last reading.
+		mockClock.Add(time.Millisecond)
+		_, err = r.Next(context.Background())
+		test.That(t, err, test.ShouldBeError, ErrEndOfCapture)
+	})
+
+	t.Run("loops on a clock", func(t *testing.T) {
+		mockClock := clock.NewMock()
+		r, err := NewCaptureReplayer(dir, "sensor", "Readings", ReplayOptions{PlaybackRate: 1, Loop: true, Clock: mockClock})
+		test.That(t, err, test.ShouldBeNil)
+		_, err = r.Next(context.Background())
+		test.That(t, err, test.ShouldBeNil)
+
+		// The first reading plays again a second after the last one.
+		mockClock.Add(3500 * time.Millisecond)
+		offset, err := nextOffset(t, r)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, offset, test.ShouldEqual, 3*time.Second)
+		mockClock.Add(time.Second)
+		offset, err = nextOffset(t, r)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, offset, test.ShouldEqual, 0)
+	})
+
+	t.Run("reads binary readings when played", func(t *testing.T) {
+		binaryDir := t.TempDir()
+		writeReplayFile(t, binaryDir, "camera", "NextPointCloud", v1.DataType_DATA_TYPE_BINARY_SENSOR, 0, time.Second)
+		r, err := NewCaptureReplayer(binaryDir, "camera", "NextPointCloud", ReplayOptions{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, r.entries[1].data, test.ShouldBeNil)
+		_, err = r.Next(context.Background())
+		test.That(t, err, test.ShouldBeNil)
+		reading, err := r.Next(context.Background())
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, string(reading.GetBinary()), test.ShouldEqual, "1s")
+	})
+
+	t.Run("finds nothing to play", func(t *testing.T) {
+		r, err := NewCaptureReplayer(dir, "missing", "Readings", ReplayOptions{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, r.Len(), test.ShouldEqual, 0)
+		_, err = r.Next(context.Background())
+		test.That(t, err, test.ShouldBeError, ErrEndOfCapture)
+
+		_, err = NewCaptureReplayer(filepath.Join(dir, "missing"), "sensor", "Readings", ReplayOptions{})
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
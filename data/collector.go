@@ -82,6 +82,8 @@ type collector struct {
 	target           CaptureBufferedWriter
 	lastLoggedErrors map[string]int64
 	dataType         CaptureType
+	// deadband is only used by the capture goroutine.
+	deadband *deadbandFilter
 }
 
 // Close closes the channels backing the Collector. It should always be called before disposing of a Collector to avoid
@@ -228,6 +230,10 @@ func (c *collector) getAndPushNextReading() {
 		return
 	}
 
+	if c.deadband != nil && !c.deadband.shouldWrite(result.TabularData.Payload, c.clock.Now()) {
+		return
+	}
+
 	select {
 	// If c.captureResults is full, c.captureResults <- a can block indefinitely.
 	// This additional select block allows cancel to
@@ -251,6 +257,10 @@ func NewCollector(captureFunc CaptureFunc, params CollectorParams) (Collector, e
 	} else {
 		c = params.Clock
 	}
+	var deadband *deadbandFilter
+	if params.Deadband != nil {
+		deadband = &deadbandFilter{Deadband: *params.Deadband}
+	}
 	return &collector{
 		componentName:    params.ComponentName,
 		componentType:    params.ComponentType,
@@ -268,6 +278,7 @@ func NewCollector(captureFunc CaptureFunc, params CollectorParams) (Collector, e
 		target:           params.Target,
 		clock:            c,
 		lastLoggedErrors: make(map[string]int64, 0),
+		deadband:         deadband,
 	}, nil
 }
 
@@ -0,0 +1,108 @@
+package data
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Deadband makes a tabular collector write a reading only when it changes from the last one written, or when
+// MaxInterval has passed since then. A number changes when it moves by more than both Absolute and Relative times
+// its last written value, so leaving either at zero uses only the other, and leaving both at zero writes every
+// change. Any change to a value that is not a number, or to which fields are present, counts as a change.
+type Deadband struct {
+	Absolute float64
+	Relative float64
+	// MaxInterval, if not zero, writes a reading even without a change once this long has passed.
+	MaxInterval time.Duration
+}
+
+// Validate ensures the deadband is valid.
+func (d Deadband) Validate() error {
+	if d.Absolute < 0 {
+		return errors.New("deadband absolute can't be less than 0")
+	}
+	if d.Relative < 0 {
+		return errors.New("deadband relative can't be less than 0")
+	}
+	if d.MaxInterval < 0 {
+		return errors.New("deadband max interval can't be less than 0")
+	}
+	return nil
+}
+
+// deadbandFilter remembers the last reading a collector wrote, to decide whether to write the next one.
+type deadbandFilter struct {
+	Deadband
+	last      map[string]*structpb.Value
+	lastWrite time.Time
+}
+
+// shouldWrite returns whether payload, captured at now, should be written, and remembers it if so.
+func (f *deadbandFilter) shouldWrite(payload *structpb.Struct, now time.Time) bool {
+	fields := map[string]*structpb.Value{}
+	flattenLeaves("", payload, fields)
+	if f.last != nil && !f.changed(fields) && (f.MaxInterval == 0 || now.Sub(f.lastWrite) < f.MaxInterval) {
+		return false
+	}
+	f.last = fields
+	f.lastWrite = now
+	return true
+}
+
+func (f *deadbandFilter) changed(fields map[string]*structpb.Value) bool {
+	if len(fields) != len(f.last) {
+		return true
+	}
+	for path, value := range fields {
+		last, ok := f.last[path]
+		if !ok {
+			return true
+		}
+		number, isNumber := value.GetKind().(*structpb.Value_NumberValue)
+		lastNumber, lastIsNumber := last.GetKind().(*structpb.Value_NumberValue)
+		if isNumber && lastIsNumber {
+			if f.numberChanged(lastNumber.NumberValue, number.NumberValue) {
+				return true
+			}
+			continue
+		}
+		if !proto.Equal(value, last) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *deadbandFilter) numberChanged(last, next float64) bool {
+	diff := math.Abs(next - last)
+	if math.IsNaN(diff) {
+		// NaN compares unequal to everything, so only a change to or from NaN is a change.
+		return math.IsNaN(last) != math.IsNaN(next)
+	}
+	return diff > f.Absolute && diff > f.Relative*math.Abs(last)
+}
+
+// flattenLeaves collects the leaves of s into fields, keyed by their dotted path, with list elements keyed by index.
+func flattenLeaves(prefix string, s *structpb.Struct, fields map[string]*structpb.Value) {
+	for name, value := range s.GetFields() {
+		flattenLeaf(prefix+name, value, fields)
+	}
+}
+
+func flattenLeaf(path string, value *structpb.Value, fields map[string]*structpb.Value) {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StructValue:
+		flattenLeaves(path+".", kind.StructValue, fields)
+	case *structpb.Value_ListValue:
+		for i, element := range kind.ListValue.GetValues() {
+			flattenLeaf(path+"."+strconv.Itoa(i), element, fields)
+		}
+	default:
+		fields[path] = value
+	}
+}
@@ -0,0 +1,149 @@
+package data
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/logging"
+)
+
+func deadbandReading(t *testing.T, readings map[string]interface{}) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(map[string]interface{}{"readings": readings})
+	test.That(t, err, test.ShouldBeNil)
+	return s
+}
+
+func TestDeadbandFilter(t *testing.T) {
+	start := time.Now()
+	for _, tc := range []struct {
+		name     string
+		deadband Deadband
+		next     map[string]interface{}
+		write    bool
+	}{
+		{"unchanged", Deadband{}, map[string]interface{}{"a": 10, "b": "on", "c": []interface{}{1, 2}}, false},
+		{"any change without a deadband", Deadband{}, map[string]interface{}{"a": 10.001, "b": "on", "c": []interface{}{1, 2}}, true},
+		{"inside absolute", Deadband{Absolute: 0.5}, map[string]interface{}{"a": 10.4, "b": "on", "c": []interface{}{1, 2.3}}, false},
+		{"outside absolute", Deadband{Absolute: 0.5}, map[string]interface{}{"a": 10, "b": "on", "c": []interface{}{1, 2.6}}, true},
+		{"inside relative", Deadband{Relative: 0.1}, map[string]interface{}{"a": 10.9, "b": "on", "c": []interface{}{1, 2}}, false},
+		{"outside relative", Deadband{Relative: 0.1}, map[string]interface{}{"a": 11.1, "b": "on", "c": []interface{}{1, 2}}, true},
+		{"inside one band", Deadband{Absolute: 2, Relative: 0.1}, map[string]interface{}{"a": 11.5, "b": "on", "c": []interface{}{1, 2}}, false},
+		{"outside both bands", Deadband{Absolute: 2, Relative: 0.1}, map[string]interface{}{"a": 12.5, "b": "on", "c": []interface{}{1, 2}}, true},
+		{"string change", Deadband{Absolute: 100}, map[string]interface{}{"a": 10, "b": "off", "c": []interface{}{1, 2}}, true},
+		{"list grows", Deadband{Absolute: 100}, map[string]interface{}{"a": 10, "b": "on", "c": []interface{}{1, 2, 3}}, true},
+		{"field missing", Deadband{Absolute: 100}, map[string]interface{}{"a": 10, "b": "on"}, true},
+		{"number becomes a string", Deadband{Absolute: 100}, map[string]interface{}{"a": "10", "b": "on", "c": []interface{}{1, 2}}, true},
+		{"number becomes NaN", Deadband{Absolute: 100}, map[string]interface{}{"a": math.NaN(), "b": "on", "c": []interface{}{1, 2}}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := &deadbandFilter{Deadband: tc.deadband}
+			first := deadbandReading(t, map[string]interface{}{"a": 10, "b": "on", "c": []interface{}{1, 2}})
+			test.That(t, f.shouldWrite(first, start), test.ShouldBeTrue)
+			test.That(t, f.shouldWrite(deadbandReading(t, tc.next), start.Add(time.Second)), test.ShouldEqual, tc.write)
+		})
+	}
+}
+
+func TestDeadbandFilterComparesToLastWritten(t *testing.T) {
+	start := time.Now()
+	f := &deadbandFilter{Deadband: Deadband{Absolute: 1, MaxInterval: 10 * time.Second}}
+	reading := func(a float64) *structpb.Struct {
+		return deadbandReading(t, map[string]interface{}{"a": a})
+	}
+	test.That(t, f.shouldWrite(reading(0), start), test.ShouldBeTrue)
+	// A slow drift is written once it has moved far enough from the last written reading.
+	test.That(t, f.shouldWrite(reading(0.6), start.Add(time.Second)), test.ShouldBeFalse)
+	test.That(t, f.shouldWrite(reading(1.2), start.Add(2*time.Second)), test.ShouldBeTrue)
+	test.That(t, f.shouldWrite(reading(1.8), start.Add(3*time.Second)), test.ShouldBeFalse)
+	// The heartbeat writes an unchanged reading once the max interval has passed since the last write.
+	test.That(t, f.shouldWrite(reading(1.2), start.Add(11*time.Second)), test.ShouldBeFalse)
+	test.That(t, f.shouldWrite(reading(1.2), start.Add(12*time.Second)), test.ShouldBeTrue)
+	test.That(t, f.shouldWrite(reading(1.2), start.Add(13*time.Second)), test.ShouldBeFalse)
+}
+
+func TestDeadbandCollector(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tmpDir := t.TempDir()
+	target := newSignalingBuffer(ctx, tmpDir)
+	mockClock := clock.NewMock()
+	interval := 10 * time.Millisecond
+
+	values := []float64{5, 5.1, 5.2, 7, 7, 6.9, 3}
+	written := []bool{true, false, false, true, false, false, true}
+	captured := make(chan struct{})
+	i := 0
+	captureFunc := CaptureFunc(func(ctx context.Context, _ map[string]*anypb.Any) (CaptureResult, error) {
+		defer func() {
+			select {
+			case captured <- struct{}{}:
+			case <-ctx.Done():
+			}
+		}()
+		value := values[i]
+		i++
+		ts := Timestamps{TimeRequested: mockClock.Now(), TimeReceived: mockClock.Now()}
+		return NewTabularCaptureResultReadings(ts, map[string]interface{}{"value": value})
+	})
+
+	_, err := NewCollector(captureFunc, CollectorParams{
+		DataType:      CaptureTypeBinary,
+		ComponentName: "sensor",
+		Logger:        logging.NewTestLogger(t),
+		Target:        target,
+		Deadband:      &Deadband{},
+	})
+	test.That(t, err, test.ShouldNotBeNil)
+
+	c, err := NewCollector(captureFunc, CollectorParams{
+		DataType:      CaptureTypeTabular,
+		ComponentName: "sensor",
+		Interval:      interval,
+		QueueSize:     queueSize,
+		BufferSize:    bufferSize,
+		Logger:        logging.NewTestLogger(t),
+		Clock:         mockClock,
+		Target:        target,
+		Deadband:      &Deadband{Absolute: 0.5},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	c.Collect()
+	for _, write := range written {
+		mockClock.Add(interval)
+		select {
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for a capture")
+		case <-captured:
+		}
+		if write {
+			select {
+			case <-ctx.Done():
+				t.Fatal("timed out waiting for a write")
+			case <-target.wrote:
+			}
+		}
+	}
+	c.Close()
+
+	var readings []*v1.SensorData
+	for _, file := range getAllFiles(tmpDir) {
+		fileReadings, err := SensorDataFromCaptureFilePath(filepath.Join(tmpDir, file.Name()))
+		test.That(t, err, test.ShouldBeNil)
+		readings = append(readings, fileReadings...)
+	}
+	var got []float64
+	for _, reading := range readings {
+		got = append(got, reading.GetStruct().GetFields()["readings"].GetStructValue().GetFields()["value"].GetNumberValue())
+	}
+	test.That(t, got, test.ShouldResemble, []float64{5, 7, 3})
+}
@@ -0,0 +1,533 @@
+package data
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"maps"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Files written with a keyring that has an active key are encrypted with envelope encryption: each file is
+// encrypted with its own random data key, which is stored in the file's header encrypted (wrapped) by one of the
+// keyring's keys. The header is followed by the file's records, each sealed with AES-GCM under the data key:
+//
+//	header: encryptionMagic | version | uvarint len | key ID | uvarint len | wrapped data key
+//	record: uvarint len | nonce | ciphertext
+//
+// Each record is authenticated along with its offset from the end of the header, so records cannot be reordered. For
+// capture files a record holds one protobuf message; for other files it holds a chunk of the file's contents, and each
+// chunk is also authenticated along with whether it is the last one, so a file cut short at a chunk boundary is
+// detected rather than read as complete.
+//
+// Rotating keys only needs the new key to be made active, as old files name the key they were wrapped with. Old keys
+// must stay in the keyring until every file written with them has synced.
+
+// encryptionMagic starts every encrypted file. The leading zero byte keeps it from being mistaken for a plaintext
+// capture file, which starts with the varint length of its metadata.
+var encryptionMagic = []byte("\x00viamenc")
+
+const (
+	encryptionVersion = 1
+	dataKeySize       = 32
+	// maxEncryptedRecordSize bounds the length read from a record header so a corrupt file cannot cause a huge
+	// allocation.
+	maxEncryptedRecordSize = 1 << 31
+	// encryptedChunkSize is the size of the plaintext chunks that NewEncryptedWriter seals.
+	encryptedChunkSize = 64 * 1024
+)
+
+var (
+	// ErrUnknownEncryptionKey is returned when reading a file that was encrypted with a key that is not in the keyring,
+	// or without a keyring.
+	ErrUnknownEncryptionKey = errors.New("encrypted with an unknown key")
+	// ErrDecryptionFailed is returned when an encrypted file fails authentication, either because it was modified or
+	// because it was encrypted with different key material under the same key ID.
+	ErrDecryptionFailed = errors.New("failed to decrypt")
+)
+
+// EncryptionKeyConfig configures a key used to encrypt files at rest. The key is 16, 24 or 32 bytes, for AES-128,
+// AES-192 or AES-256, encoded as base64 either in Key, which may be an environment placeholder such as
+// "${environment.VIAM_CAPTURE_KEY}", or in the file at KeyFile.
+type EncryptionKeyConfig struct {
+	ID      string `json:"id"`
+	Key     string `json:"key,omitempty"`
+	KeyFile string `json:"key_file,omitempty"`
+}
+
+// Validate returns an error if c does not have an ID and exactly one of Key and KeyFile.
+func (c EncryptionKeyConfig) Validate() error {
+	if c.ID == "" {
+		return errors.New("encryption key must have an id")
+	}
+	if (c.Key == "") == (c.KeyFile == "") {
+		return errors.Errorf("encryption key %q must set exactly one of key and key_file", c.ID)
+	}
+	return nil
+}
+
+// Load returns the decoded key.
+func (c EncryptionKeyConfig) Load() ([]byte, error) {
+	encoded := c.Key
+	if c.KeyFile != "" {
+		//nolint:gosec
+		contents, err := os.ReadFile(c.KeyFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read encryption key %q", c.ID)
+		}
+		encoded = string(contents)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, errors.Wrapf(err, "encryption key %q is not valid base64", c.ID)
+	}
+	return key, nil
+}
+
+// EncryptionConfig configures encryption at rest of the files data manager writes. New files are encrypted with the
+// key named by ActiveKeyID, and files encrypted with any of Keys are decrypted when read and synced. To rotate keys, add
+// a new key and make it active, keeping the old key until the files written with it have synced. Leaving ActiveKeyID
+// empty stops encrypting new files while still syncing the files that are already encrypted. Anything that only reads
+// files, like replay, needs just the keys they were written with.
+type EncryptionConfig struct {
+	ActiveKeyID string                `json:"active_key_id"`
+	Keys        []EncryptionKeyConfig `json:"keys"`
+}
+
+// Validate returns an error if the keys are invalid or the active key is not one of them.
+func (c *EncryptionConfig) Validate() error {
+	ids := map[string]bool{}
+	for _, k := range c.Keys {
+		if err := k.Validate(); err != nil {
+			return err
+		}
+		if ids[k.ID] {
+			return errors.Errorf("duplicate encryption key %q", k.ID)
+		}
+		ids[k.ID] = true
+	}
+	if c.ActiveKeyID != "" && !ids[c.ActiveKeyID] {
+		return errors.Errorf("encryption active_key_id %q is not one of the keys", c.ActiveKeyID)
+	}
+	return nil
+}
+
+// Keyring loads the configured keys, returning nil if c is nil, meaning encryption is not configured.
+func (c *EncryptionConfig) Keyring() (*Keyring, error) {
+	if c == nil {
+		return nil, nil
+	}
+	return LoadKeyring(c.ActiveKeyID, c.Keys)
+}
+
+// Keyring holds the keys that files are encrypted with at rest.
+type Keyring struct {
+	activeKeyID string
+	keys        map[string]cipher.AEAD
+	// rawKeys is kept so that keyrings can be compared.
+	rawKeys map[string][]byte
+}
+
+// NewKeyring returns a keyring holding keys, keyed by ID. New files are encrypted with the key named by activeKeyID;
+// if activeKeyID is empty new files are written in plaintext, while files encrypted with any of keys can still be read.
+func NewKeyring(activeKeyID string, keys map[string][]byte) (*Keyring, error) {
+	k := &Keyring{
+		activeKeyID: activeKeyID,
+		keys:        make(map[string]cipher.AEAD, len(keys)),
+		rawKeys:     make(map[string][]byte, len(keys)),
+	}
+	for id, key := range keys {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid encryption key %q", id)
+		}
+		k.keys[id] = aead
+		k.rawKeys[id] = bytes.Clone(key)
+	}
+	if _, ok := k.keys[activeKeyID]; activeKeyID != "" && !ok {
+		return nil, errors.Errorf("active encryption key %q is not one of the keys", activeKeyID)
+	}
+	return k, nil
+}
+
+// LoadKeyring loads each of keys and returns a keyring holding them.
+func LoadKeyring(activeKeyID string, keys []EncryptionKeyConfig) (*Keyring, error) {
+	loaded := make(map[string][]byte, len(keys))
+	for _, c := range keys {
+		if err := c.Validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := loaded[c.ID]; ok {
+			return nil, errors.Errorf("duplicate encryption key %q", c.ID)
+		}
+		key, err := c.Load()
+		if err != nil {
+			return nil, err
+		}
+		loaded[c.ID] = key
+	}
+	return NewKeyring(activeKeyID, loaded)
+}
+
+// ActiveKeyID returns the ID of the key new files are encrypted with, or an empty string if they are not encrypted.
+func (k *Keyring) ActiveKeyID() string {
+	if k == nil {
+		return ""
+	}
+	return k.activeKeyID
+}
+
+// Equal returns whether k and o hold the same keys and have the same active key.
+func (k *Keyring) Equal(o *Keyring) bool {
+	if k == nil || o == nil {
+		return k == o
+	}
+	return k.activeKeyID == o.activeKeyID && maps.EqualFunc(k.rawKeys, o.rawKeys, bytes.Equal)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// fileCipher seals and opens the records of a single encrypted file.
+type fileCipher struct {
+	aead cipher.AEAD
+	// bodyStart is the offset of the first record, just past the header.
+	bodyStart int64
+}
+
+// newFileCipher returns the header of a new file encrypted with the active key, and the cipher for its records. It
+// returns a nil cipher if k is nil or has no active key.
+func (k *Keyring) newFileCipher() ([]byte, *fileCipher, error) {
+	if k == nil || k.activeKeyID == "" {
+		return nil, nil, nil
+	}
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := aeadSeal(k.keys[k.activeKeyID], dataKey, []byte(k.activeKeyID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := append([]byte{}, encryptionMagic...)
+	header = append(header, encryptionVersion)
+	header = binary.AppendUvarint(header, uint64(len(k.activeKeyID)))
+	header = append(header, k.activeKeyID...)
+	header = binary.AppendUvarint(header, uint64(len(wrapped)))
+	header = append(header, wrapped...)
+	return header, &fileCipher{aead: aead, bodyStart: int64(len(header))}, nil
+}
+
+// readFileCipher reads the header of an encrypted file from r, which must be positioned at the start of the file,
+// and returns the cipher for its records.
+func (k *Keyring) readFileCipher(r io.Reader) (*fileCipher, error) {
+	cr := &countingByteReader{r: r}
+	magic := make([]byte, len(encryptionMagic)+1)
+	if _, err := io.ReadFull(cr, magic); err != nil {
+		return nil, errors.Wrap(err, "failed to read encryption header")
+	}
+	if !bytes.Equal(magic[:len(encryptionMagic)], encryptionMagic) {
+		return nil, errors.New("not an encrypted file")
+	}
+	if version := magic[len(encryptionMagic)]; version != encryptionVersion {
+		return nil, errors.Errorf("unsupported encryption version %d", version)
+	}
+	keyID, err := readUvarintBytes(cr, 1024)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read encryption key ID")
+	}
+	wrapped, err := readUvarintBytes(cr, 1024)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read wrapped data key")
+	}
+
+	var kek cipher.AEAD
+	if k != nil {
+		kek = k.keys[string(keyID)]
+	}
+	if kek == nil {
+		return nil, errors.Wrapf(ErrUnknownEncryptionKey, "key %q", keyID)
+	}
+	dataKey, err := aeadOpen(kek, wrapped, keyID)
+	if err != nil {
+		return nil, errors.Wrapf(ErrDecryptionFailed, "data key wrapped with key %q", keyID)
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &fileCipher{aead: aead, bodyStart: cr.count}, nil
+}
+
+// sealRecord returns plaintext sealed as the record at offset in the file.
+func (c *fileCipher) sealRecord(offset int64, plaintext []byte) ([]byte, error) {
+	return c.seal(c.recordAAD(offset), plaintext)
+}
+
+// sealChunk returns plaintext sealed as the chunk at offset in the file, which is the last chunk if last is true.
+func (c *fileCipher) sealChunk(offset int64, plaintext []byte, last bool) ([]byte, error) {
+	return c.seal(c.chunkAAD(offset, last), plaintext)
+}
+
+func (c *fileCipher) seal(aad, plaintext []byte) ([]byte, error) {
+	sealed, err := aeadSeal(c.aead, plaintext, aad)
+	if err != nil {
+		return nil, err
+	}
+	return append(binary.AppendUvarint(nil, uint64(len(sealed))), sealed...), nil
+}
+
+// openRecord reads the record at offset in the file from r and returns its plaintext along with the number of bytes
+// read. It returns io.EOF if r has no more records.
+func (c *fileCipher) openRecord(r io.Reader, offset int64) ([]byte, int64, error) {
+	sealed, n, err := readSealed(r)
+	if err != nil {
+		return nil, n, err
+	}
+	plaintext, err := aeadOpen(c.aead, sealed, c.recordAAD(offset))
+	if err != nil {
+		return nil, n, errors.Wrapf(ErrDecryptionFailed, "record at offset %d", offset)
+	}
+	return plaintext, n, nil
+}
+
+// openChunk reads the chunk at offset in the file from r and returns its plaintext, the number of bytes read and
+// whether it is the last chunk. It returns io.EOF if r has no more chunks.
+func (c *fileCipher) openChunk(r io.Reader, offset int64) ([]byte, int64, bool, error) {
+	sealed, n, err := readSealed(r)
+	if err != nil {
+		return nil, n, false, err
+	}
+	for _, last := range []bool{false, true} {
+		if plaintext, err := aeadOpen(c.aead, sealed, c.chunkAAD(offset, last)); err == nil {
+			return plaintext, n, last, nil
+		}
+	}
+	return nil, n, false, errors.Wrapf(ErrDecryptionFailed, "chunk at offset %d", offset)
+}
+
+func readSealed(r io.Reader) ([]byte, int64, error) {
+	cr := &countingByteReader{r: r}
+	sealed, err := readUvarintBytes(cr, maxEncryptedRecordSize)
+	return sealed, cr.count, err
+}
+
+func (c *fileCipher) recordAAD(offset int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(offset-c.bodyStart))
+}
+
+func (c *fileCipher) chunkAAD(offset int64, last bool) []byte {
+	var lastFlag byte
+	if last {
+		lastFlag = 1
+	}
+	return append(c.recordAAD(offset), lastFlag)
+}
+
+func aeadSeal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func aeadOpen(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("sealed data too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, aad)
+}
+
+// readUvarintBytes reads a uvarint length of at most maxLen followed by that many bytes. It returns io.EOF only if r
+// is empty, and io.ErrUnexpectedEOF if r ends part way through.
+func readUvarintBytes(r *countingByteReader, maxLen uint64) ([]byte, error) {
+	start := r.count
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		if errors.Is(err, io.EOF) && r.count > start {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if n > maxLen {
+		return nil, errors.Errorf("encrypted length %d exceeds limit %d", n, maxLen)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// IsEncryptedFile returns whether f starts with the header of an encrypted file.
+func IsEncryptedFile(f io.ReaderAt) bool {
+	magic := make([]byte, len(encryptionMagic))
+	if _, err := f.ReadAt(magic, 0); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, encryptionMagic)
+}
+
+// encryptedWriter seals everything written to it in chunks.
+type encryptedWriter struct {
+	w      io.Writer
+	cipher *fileCipher
+	offset int64
+	buf    []byte
+	closed bool
+}
+
+// NewEncryptedWriter returns a writer which encrypts what is written to it with the active key of k and writes it to
+// w. Close must be called to write the last chunk, without which the file cannot be read, and does not close w.
+func NewEncryptedWriter(w io.Writer, k *Keyring) (io.WriteCloser, error) {
+	if k.ActiveKeyID() == "" {
+		return nil, errors.New("keyring has no active encryption key")
+	}
+	header, fc, err := k.newFileCipher()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &encryptedWriter{w: w, cipher: fc, offset: fc.bodyStart}, nil
+}
+
+func (w *encryptedWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encrypted writer")
+	}
+	written := len(p)
+	for len(p) > 0 {
+		n := min(len(p), encryptedChunkSize-len(w.buf))
+		w.buf = append(w.buf, p[:n]...)
+		p = p[n:]
+		if len(w.buf) == encryptedChunkSize {
+			if err := w.flush(false); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (w *encryptedWriter) flush(last bool) error {
+	record, err := w.cipher.sealChunk(w.offset, w.buf, last)
+	if err != nil {
+		return err
+	}
+	if _, err := w.w.Write(record); err != nil {
+		return err
+	}
+	w.offset += int64(len(record))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close writes the last chunk, which is written even if it is empty.
+func (w *encryptedWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+// decryptingReader reads the plaintext of a file written by NewEncryptedWriter.
+type decryptingReader struct {
+	r      *bufio.Reader
+	cipher *fileCipher
+	offset int64
+	buf    []byte
+	// last is set once the last chunk has been read.
+	last bool
+}
+
+// NewDecryptingReader returns a reader of the plaintext of the encrypted file r, which must be positioned at the
+// start of the file. It returns an error wrapping ErrUnknownEncryptionKey if the file's key is not in k. Reads return an
+// error wrapping ErrDecryptionFailed if the file was modified or does not end with its last chunk.
+func NewDecryptingReader(r io.Reader, k *Keyring) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	fc, err := k.readFileCipher(br)
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{r: br, cipher: fc, offset: fc.bodyStart}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.last {
+			_, err := r.r.Peek(1)
+			switch {
+			case errors.Is(err, io.EOF):
+				return 0, io.EOF
+			case err != nil:
+				return 0, err
+			default:
+				return 0, errors.Wrap(ErrDecryptionFailed, "data after last chunk")
+			}
+		}
+		plaintext, n, last, err := r.cipher.openChunk(r.r, r.offset)
+		if errors.Is(err, io.EOF) {
+			return 0, errors.Wrap(ErrDecryptionFailed, "file ends before its last chunk")
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.offset += n
+		r.buf = plaintext
+		r.last = last
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// WriteEncryptedFile writes contents to the file at path, encrypted with the active key of k if it has one.
+func WriteEncryptedFile(path string, contents []byte, k *Keyring) (err error) {
+	if k.ActiveKeyID() == "" {
+		return os.WriteFile(path, contents, 0o600)
+	}
+	//nolint:gosec
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	w, err := NewEncryptedWriter(f, k)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(contents); err != nil {
+		return err
+	}
+	return w.Close()
+}
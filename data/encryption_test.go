@@ -0,0 +1,318 @@
+package data
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func newTestKey(t *testing.T) []byte {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	test.That(t, err, test.ShouldBeNil)
+	return key
+}
+
+func newTestKeyring(t *testing.T, activeKeyID string, keys map[string][]byte) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(activeKeyID, keys)
+	test.That(t, err, test.ShouldBeNil)
+	return k
+}
+
+// writeTestCaptureFile writes readings to a new completed capture file in dir, encrypted with the active key of k if
+// it has one, and returns its path.
+func writeTestCaptureFile(
+	t *testing.T, dir string, md *v1.DataCaptureMetadata, readings []*v1.SensorData, k *Keyring,
+) string {
+	t.Helper()
+	f, err := NewCaptureFileWithKeyring(dir, md, k)
+	test.That(t, err, test.ShouldBeNil)
+	for _, r := range readings {
+		test.That(t, f.WriteNext(r), test.ShouldBeNil)
+	}
+	test.That(t, f.Close(), test.ShouldBeNil)
+	return strings.TrimSuffix(f.GetPath(), InProgressCaptureFileExt) + CompletedCaptureFileExt
+}
+
+// readTestCaptureFile returns all readings in the capture file at path, decrypting it with k.
+func readTestCaptureFile(t *testing.T, path string, k *Keyring) ([]*v1.SensorData, error) {
+	t.Helper()
+	//nolint:gosec
+	f, err := os.Open(path)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	cf, err := ReadCaptureFileWithKeyring(f, k)
+	if err != nil {
+		return nil, err
+	}
+	return SensorDataFromCaptureFile(cf)
+}
+
+func TestEncryptedCaptureFile(t *testing.T) {
+	keyA, keyB := newTestKey(t), newTestKey(t)
+	md := &v1.DataCaptureMetadata{
+		ComponentName: "sensor",
+		MethodName:    "Readings",
+		Type:          v1.DataType_DATA_TYPE_TABULAR_SENSOR,
+		Tags:          []string{"secret-tag"},
+	}
+	var readings []*v1.SensorData
+	for i := 0; i < 3; i++ {
+		s, err := structpb.NewStruct(map[string]interface{}{"secret-field": float64(i)})
+		test.That(t, err, test.ShouldBeNil)
+		readings = append(readings, &v1.SensorData{Metadata: &v1.SensorMetadata{}, Data: &v1.SensorData_Struct{Struct: s}})
+	}
+
+	keyringA := newTestKeyring(t, "a", map[string][]byte{"a": keyA})
+	path := writeTestCaptureFile(t, t.TempDir(), md, readings, keyringA)
+
+	t.Run("is encrypted on disk", func(t *testing.T) {
+		//nolint:gosec
+		contents, err := os.ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, bytes.HasPrefix(contents, encryptionMagic), test.ShouldBeTrue)
+		test.That(t, bytes.Contains(contents, []byte("secret-tag")), test.ShouldBeFalse)
+		test.That(t, bytes.Contains(contents, []byte("secret-field")), test.ShouldBeFalse)
+	})
+
+	t.Run("reads transparently", func(t *testing.T) {
+		//nolint:gosec
+		f, err := os.Open(path)
+		test.That(t, err, test.ShouldBeNil)
+		defer f.Close()
+		cf, err := ReadCaptureFileWithKeyring(f, keyringA)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, proto.Equal(cf.ReadMetadata(), md), test.ShouldBeTrue)
+		got, err := SensorDataFromCaptureFile(cf)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(got), test.ShouldEqual, len(readings))
+		for i := range readings {
+			test.That(t, proto.Equal(got[i], readings[i]), test.ShouldBeTrue)
+		}
+	})
+
+	t.Run("reads after rotating keys", func(t *testing.T) {
+		got, err := readTestCaptureFile(t, path, newTestKeyring(t, "b", map[string][]byte{"a": keyA, "b": keyB}))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(got), test.ShouldEqual, len(readings))
+	})
+
+	t.Run("fails with an unknown key", func(t *testing.T) {
+		_, err := readTestCaptureFile(t, path, newTestKeyring(t, "b", map[string][]byte{"b": keyB}))
+		test.That(t, errors.Is(err, ErrUnknownEncryptionKey), test.ShouldBeTrue)
+		test.That(t, err.Error(), test.ShouldContainSubstring, `"a"`)
+
+		_, err = SensorDataFromCaptureFilePath(path)
+		test.That(t, errors.Is(err, ErrUnknownEncryptionKey), test.ShouldBeTrue)
+	})
+
+	t.Run("fails with different key material", func(t *testing.T) {
+		_, err := readTestCaptureFile(t, path, newTestKeyring(t, "a", map[string][]byte{"a": keyB}))
+		test.That(t, errors.Is(err, ErrDecryptionFailed), test.ShouldBeTrue)
+	})
+
+	t.Run("fails when modified", func(t *testing.T) {
+		//nolint:gosec
+		contents, err := os.ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		contents[len(contents)-1] ^= 1
+		modified := filepath.Join(t.TempDir(), "modified"+CompletedCaptureFileExt)
+		test.That(t, os.WriteFile(modified, contents, 0o600), test.ShouldBeNil)
+		_, err = readTestCaptureFile(t, modified, keyringA)
+		test.That(t, errors.Is(err, ErrDecryptionFailed), test.ShouldBeTrue)
+	})
+
+	t.Run("decrypt only keyrings write plaintext", func(t *testing.T) {
+		decryptOnly := newTestKeyring(t, "", map[string][]byte{"a": keyA})
+		plainPath := writeTestCaptureFile(t, t.TempDir(), md, readings, decryptOnly)
+		//nolint:gosec
+		f, err := os.Open(plainPath)
+		test.That(t, err, test.ShouldBeNil)
+		defer f.Close()
+		test.That(t, IsEncryptedFile(f), test.ShouldBeFalse)
+		got, err := readTestCaptureFile(t, plainPath, decryptOnly)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(got), test.ShouldEqual, len(readings))
+		got, err = readTestCaptureFile(t, path, decryptOnly)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(got), test.ShouldEqual, len(readings))
+	})
+}
+
+func TestEncryptedBinaryPayloadReader(t *testing.T) {
+	k := newTestKeyring(t, "a", map[string][]byte{"a": newTestKey(t)})
+	payload := bytes.Repeat([]byte("image"), 1000)
+	sensorMD := &v1.SensorMetadata{MimeType: v1.MimeType_MIME_TYPE_IMAGE_JPEG}
+	path := writeTestCaptureFile(t, t.TempDir(),
+		&v1.DataCaptureMetadata{Type: v1.DataType_DATA_TYPE_BINARY_SENSOR},
+		[]*v1.SensorData{{Metadata: sensorMD, Data: &v1.SensorData_Binary{Binary: payload}}}, k)
+
+	//nolint:gosec
+	f, err := os.Open(path)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	cf, err := ReadCaptureFileWithKeyring(f, k)
+	test.That(t, err, test.ShouldBeNil)
+	gotMD, n, r, err := cf.BinaryPayloadReader()
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, proto.Equal(gotMD, sensorMD), test.ShouldBeTrue)
+	test.That(t, n, test.ShouldEqual, len(payload))
+	got, err := io.ReadAll(r)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, got, test.ShouldResemble, payload)
+	_, _, _, err = cf.BinaryPayloadReader()
+	test.That(t, errors.Is(err, io.EOF), test.ShouldBeTrue)
+}
+
+func TestEncryptedCaptureBuffer(t *testing.T) {
+	k := newTestKeyring(t, "a", map[string][]byte{"a": newTestKey(t)})
+	dir := t.TempDir()
+	b := NewCaptureBufferWithKeyring(dir, &v1.DataCaptureMetadata{Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR}, 1<<20, k)
+	s, err := structpb.NewStruct(map[string]interface{}{"secret-field": 1.0})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, b.WriteTabular(&v1.SensorData{Metadata: &v1.SensorMetadata{}, Data: &v1.SensorData_Struct{Struct: s}}),
+		test.ShouldBeNil)
+	test.That(t, b.Flush(), test.ShouldBeNil)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+CompletedCaptureFileExt))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, paths, test.ShouldHaveLength, 1)
+	_, err = SensorDataFromCaptureFilePath(paths[0])
+	test.That(t, errors.Is(err, ErrUnknownEncryptionKey), test.ShouldBeTrue)
+	got, err := readTestCaptureFile(t, paths[0], k)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, got, test.ShouldHaveLength, 1)
+}
+
+func TestEncryptedWriterAndReader(t *testing.T) {
+	key := newTestKey(t)
+	k := newTestKeyring(t, "a", map[string][]byte{"a": key})
+	contents := make([]byte, 3*encryptedChunkSize+100)
+	_, err := rand.Read(contents)
+	test.That(t, err, test.ShouldBeNil)
+
+	path := filepath.Join(t.TempDir(), "file.bin")
+	test.That(t, WriteEncryptedFile(path, contents, k), test.ShouldBeNil)
+	//nolint:gosec
+	f, err := os.Open(path)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	test.That(t, IsEncryptedFile(f), test.ShouldBeTrue)
+
+	r, err := NewDecryptingReader(f, k)
+	test.That(t, err, test.ShouldBeNil)
+	got, err := io.ReadAll(r)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, got, test.ShouldResemble, contents)
+
+	_, err = f.Seek(0, io.SeekStart)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = NewDecryptingReader(f, newTestKeyring(t, "b", map[string][]byte{"b": key}))
+	test.That(t, errors.Is(err, ErrUnknownEncryptionKey), test.ShouldBeTrue)
+
+	t.Run("fails when truncated at a chunk boundary", func(t *testing.T) {
+		//nolint:gosec
+		encrypted, err := os.ReadFile(path)
+		test.That(t, err, test.ShouldBeNil)
+		// Find the end of the first chunk by reading the header and one record.
+		br := bytes.NewReader(encrypted)
+		fc, err := k.readFileCipher(br)
+		test.That(t, err, test.ShouldBeNil)
+		_, n, last, err := fc.openChunk(br, fc.bodyStart)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, last, test.ShouldBeFalse)
+
+		truncated, err := NewDecryptingReader(bytes.NewReader(encrypted[:fc.bodyStart+n]), k)
+		test.That(t, err, test.ShouldBeNil)
+		_, err = io.ReadAll(truncated)
+		test.That(t, errors.Is(err, ErrDecryptionFailed), test.ShouldBeTrue)
+
+		extended, err := NewDecryptingReader(bytes.NewReader(append(slices.Clone(encrypted), 0)), k)
+		test.That(t, err, test.ShouldBeNil)
+		_, err = io.ReadAll(extended)
+		test.That(t, errors.Is(err, ErrDecryptionFailed), test.ShouldBeTrue)
+	})
+
+	t.Run("reads empty files", func(t *testing.T) {
+		emptyPath := filepath.Join(t.TempDir(), "empty.bin")
+		test.That(t, WriteEncryptedFile(emptyPath, nil, k), test.ShouldBeNil)
+		//nolint:gosec
+		f, err := os.Open(emptyPath)
+		test.That(t, err, test.ShouldBeNil)
+		defer f.Close()
+		r, err := NewDecryptingReader(f, k)
+		test.That(t, err, test.ShouldBeNil)
+		got, err := io.ReadAll(r)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(got), test.ShouldEqual, 0)
+	})
+
+	// Without an active key, files are written in plaintext.
+	plainPath := filepath.Join(t.TempDir(), "plain.bin")
+	test.That(t, WriteEncryptedFile(plainPath, contents, nil), test.ShouldBeNil)
+	//nolint:gosec
+	plain, err := os.ReadFile(plainPath)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, plain, test.ShouldResemble, contents)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	keyA, keyB := newTestKey(t), newTestKey(t)
+	keyFile := filepath.Join(t.TempDir(), "key")
+	test.That(t, os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(keyB)+"\n"), 0o600), test.ShouldBeNil)
+
+	k, err := LoadKeyring("b", []EncryptionKeyConfig{
+		{ID: "a", Key: base64.StdEncoding.EncodeToString(keyA)},
+		{ID: "b", KeyFile: keyFile},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, k.ActiveKeyID(), test.ShouldEqual, "b")
+	test.That(t, len(k.keys), test.ShouldEqual, 2)
+
+	reloaded, err := LoadKeyring("b", []EncryptionKeyConfig{
+		{ID: "b", KeyFile: keyFile},
+		{ID: "a", Key: base64.StdEncoding.EncodeToString(keyA)},
+	})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, k.Equal(reloaded), test.ShouldBeTrue)
+	test.That(t, k.Equal(newTestKeyring(t, "a", map[string][]byte{"a": keyA, "b": keyB})), test.ShouldBeFalse)
+	test.That(t, k.Equal(newTestKeyring(t, "b", map[string][]byte{"a": keyB, "b": keyB})), test.ShouldBeFalse)
+	test.That(t, k.Equal(nil), test.ShouldBeFalse)
+	test.That(t, (*Keyring)(nil).Equal(nil), test.ShouldBeTrue)
+
+	for _, tc := range []struct {
+		name        string
+		activeKeyID string
+		keys        []EncryptionKeyConfig
+		err         string
+	}{
+		{"missing id", "", []EncryptionKeyConfig{{Key: "a"}}, "must have an id"},
+		{"key and key file", "", []EncryptionKeyConfig{{ID: "a", Key: "a", KeyFile: keyFile}}, "exactly one of"},
+		{"neither key nor key file", "", []EncryptionKeyConfig{{ID: "a"}}, "exactly one of"},
+		{"duplicate id", "", []EncryptionKeyConfig{{ID: "a", KeyFile: keyFile}, {ID: "a", KeyFile: keyFile}}, "duplicate"},
+		{"missing key file", "", []EncryptionKeyConfig{{ID: "a", KeyFile: keyFile + "-missing"}}, "failed to read"},
+		{"invalid base64", "", []EncryptionKeyConfig{{ID: "a", Key: "not base64!"}}, "not valid base64"},
+		{"invalid key size", "", []EncryptionKeyConfig{{ID: "a", Key: base64.StdEncoding.EncodeToString([]byte("short"))}}, "invalid"},
+		{"unknown active key", "c", []EncryptionKeyConfig{{ID: "a", KeyFile: keyFile}}, "not one of the keys"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadKeyring(tc.activeKeyID, tc.keys)
+			test.That(t, err, test.ShouldNotBeNil)
+			test.That(t, err.Error(), test.ShouldContainSubstring, tc.err)
+		})
+	}
+}
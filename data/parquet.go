@@ -0,0 +1,354 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// This file writes the small subset of the parquet format that exporting captured readings needs: row groups of
+// optional, flat columns, each column of a row group in one uncompressed, plainly encoded data page. See
+// https://parquet.apache.org/docs/file-format/ for the format.
+
+var parquetMagic = []byte("PAR1")
+
+// parquetType is the type of a parquet column.
+type parquetType int
+
+const (
+	parquetBoolean parquetType = iota
+	parquetDouble
+	parquetString
+	parquetTimestamp
+)
+
+// Values of the parquet Type, ConvertedType, FieldRepetitionType, Encoding and PageType enums.
+const (
+	parquetPhysicalBoolean   = 0
+	parquetPhysicalInt64     = 2
+	parquetPhysicalDouble    = 5
+	parquetPhysicalByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMicros = 10
+
+	parquetRepetitionOptional = 1
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetPageTypeData = 0
+)
+
+// parquetColumn is a column to write, with one value for every row: a bool, float64, string or time.Time for a
+// parquetBoolean, parquetDouble, parquetString or parquetTimestamp column, or nil for a null.
+type parquetColumn struct {
+	name   string
+	typ    parquetType
+	values []interface{}
+}
+
+func (c parquetColumn) physicalType() int32 {
+	switch c.typ {
+	case parquetBoolean:
+		return parquetPhysicalBoolean
+	case parquetDouble:
+		return parquetPhysicalDouble
+	case parquetTimestamp:
+		return parquetPhysicalInt64
+	case parquetString:
+		fallthrough
+	default:
+		return parquetPhysicalByteArray
+	}
+}
+
+// parquetWriter writes a parquet file a row group at a time, keeping only the metadata of the row groups it has
+// written until the footer that holds it.
+type parquetWriter struct {
+	w io.Writer
+	// schema holds the columns of the file, without values.
+	schema []parquetColumn
+	// offset is the number of bytes written so far, where the next row group starts.
+	offset    int64
+	numRows   int64
+	rowGroups [][]byte
+}
+
+// newParquetWriter starts a parquet file with the given columns on w.
+func newParquetWriter(w io.Writer, columns []parquetColumn) (*parquetWriter, error) {
+	schema := make([]parquetColumn, 0, len(columns))
+	for _, c := range columns {
+		schema = append(schema, parquetColumn{name: c.name, typ: c.typ})
+	}
+	if _, err := w.Write(parquetMagic); err != nil {
+		return nil, err
+	}
+	return &parquetWriter{w: w, schema: schema, offset: int64(len(parquetMagic))}, nil
+}
+
+// writeRowGroup writes the columns, which must be those the file was started with, in order, and must all have
+// numRows values, as a row group.
+func (p *parquetWriter) writeRowGroup(numRows int, columns []parquetColumn) error {
+	if len(columns) != len(p.schema) {
+		return errors.Errorf("parquet row group has %d columns, expected %d", len(columns), len(p.schema))
+	}
+	out := &bytes.Buffer{}
+	rowGroup := newThriftCompactWriter()
+	rowGroup.writeListHeader(1, thriftStruct, len(columns))
+	var totalSize int64
+	for i, c := range columns {
+		if c.name != p.schema[i].name || c.typ != p.schema[i].typ {
+			return errors.Errorf("parquet row group column %q does not match column %q of the file", c.name, p.schema[i].name)
+		}
+		if len(c.values) != numRows {
+			return errors.Errorf("parquet column %q has %d values, expected %d", c.name, len(c.values), numRows)
+		}
+		page, err := encodeParquetPage(c)
+		if err != nil {
+			return err
+		}
+		header := newThriftCompactWriter()
+		header.writeI32(1, parquetPageTypeData)
+		header.writeI32(2, int32(len(page)))
+		header.writeI32(3, int32(len(page)))
+		header.writeStructField(5)
+		header.writeI32(1, int32(numRows))
+		header.writeI32(2, parquetEncodingPlain)
+		header.writeI32(3, parquetEncodingRLE)
+		header.writeI32(4, parquetEncodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		offset := p.offset + int64(out.Len())
+		size := int64(header.buf.Len() + len(page))
+		totalSize += size
+		out.Write(header.buf.Bytes())
+		out.Write(page)
+
+		rowGroup.beginStruct()
+		rowGroup.writeI64(2, offset)
+		rowGroup.writeStructField(3)
+		rowGroup.writeI32(1, c.physicalType())
+		rowGroup.writeListHeader(2, thriftI32, 2)
+		rowGroup.writeListI32(parquetEncodingPlain)
+		rowGroup.writeListI32(parquetEncodingRLE)
+		rowGroup.writeListHeader(3, thriftBinary, 1)
+		rowGroup.writeListString(c.name)
+		rowGroup.writeI32(4, 0) // uncompressed
+		rowGroup.writeI64(5, int64(numRows))
+		rowGroup.writeI64(6, size)
+		rowGroup.writeI64(7, size)
+		rowGroup.writeI64(9, offset)
+		rowGroup.endStruct()
+		rowGroup.endStruct()
+	}
+	rowGroup.writeI64(2, totalSize)
+	rowGroup.writeI64(3, int64(numRows))
+	rowGroup.endStruct()
+
+	if _, err := p.w.Write(out.Bytes()); err != nil {
+		return err
+	}
+	p.offset += int64(out.Len())
+	p.numRows += int64(numRows)
+	p.rowGroups = append(p.rowGroups, rowGroup.buf.Bytes())
+	return nil
+}
+
+// close writes the footer that ends the file, without closing w.
+func (p *parquetWriter) close() error {
+	footer := newThriftCompactWriter()
+	footer.writeI32(1, 1) // version
+	footer.writeListHeader(2, thriftStruct, len(p.schema)+1)
+	footer.beginStruct()
+	footer.writeString(4, "schema")
+	footer.writeI32(5, int32(len(p.schema)))
+	footer.endStruct()
+	for _, c := range p.schema {
+		footer.beginStruct()
+		footer.writeI32(1, c.physicalType())
+		footer.writeI32(3, parquetRepetitionOptional)
+		footer.writeString(4, c.name)
+		switch c.typ {
+		case parquetString:
+			footer.writeI32(6, parquetConvertedUTF8)
+		case parquetTimestamp:
+			footer.writeI32(6, parquetConvertedTimestampMicros)
+		case parquetBoolean, parquetDouble:
+		}
+		footer.endStruct()
+	}
+	footer.writeI64(3, p.numRows)
+	footer.writeListHeader(4, thriftStruct, len(p.rowGroups))
+	for _, rowGroup := range p.rowGroups {
+		footer.buf.Write(rowGroup)
+	}
+	footer.writeString(6, "viam rdk")
+	footer.endStruct()
+
+	out := footer.buf.Bytes()
+	out = binary.LittleEndian.AppendUint32(out, uint32(footer.buf.Len()))
+	out = append(out, parquetMagic...)
+	_, err := p.w.Write(out)
+	return err
+}
+
+// encodeParquetPage encodes the values of c as the body of a data page: the definition levels, which say which rows
+// are null, followed by the values of the rows that are not.
+func encodeParquetPage(c parquetColumn) ([]byte, error) {
+	var levels []byte
+	for i := 0; i < len(c.values); {
+		defined := c.values[i] != nil
+		j := i
+		for j < len(c.values) && (c.values[j] != nil) == defined {
+			j++
+		}
+		// An RLE run of the same level, which takes one byte at a bit width of one.
+		levels = binary.AppendUvarint(levels, uint64(j-i)<<1)
+		if defined {
+			levels = append(levels, 1)
+		} else {
+			levels = append(levels, 0)
+		}
+		i = j
+	}
+	page := binary.LittleEndian.AppendUint32(nil, uint32(len(levels)))
+	page = append(page, levels...)
+
+	var bit int
+	for _, v := range c.values {
+		if v == nil {
+			continue
+		}
+		switch c.typ {
+		case parquetBoolean:
+			b, ok := v.(bool)
+			if !ok {
+				return nil, errors.Errorf("parquet column %q expected a bool, got %T", c.name, v)
+			}
+			// Booleans are packed eight to a byte, starting from the least significant bit.
+			if bit%8 == 0 {
+				page = append(page, 0)
+			}
+			if b {
+				page[len(page)-1] |= 1 << (bit % 8)
+			}
+			bit++
+		case parquetDouble:
+			f, ok := v.(float64)
+			if !ok {
+				return nil, errors.Errorf("parquet column %q expected a float64, got %T", c.name, v)
+			}
+			page = binary.LittleEndian.AppendUint64(page, math.Float64bits(f))
+		case parquetString:
+			s, ok := v.(string)
+			if !ok {
+				return nil, errors.Errorf("parquet column %q expected a string, got %T", c.name, v)
+			}
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(s)))
+			page = append(page, s...)
+		case parquetTimestamp:
+			t, ok := v.(time.Time)
+			if !ok {
+				return nil, errors.Errorf("parquet column %q expected a time, got %T", c.name, v)
+			}
+			page = binary.LittleEndian.AppendUint64(page, uint64(t.UnixMicro()))
+		}
+	}
+	return page, nil
+}
+
+// Types of the thrift compact protocol, which parquet metadata is written in.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftCompactWriter writes structs in the thrift compact protocol. It starts inside a struct, which endStruct ends.
+type thriftCompactWriter struct {
+	buf bytes.Buffer
+	// lastFields holds the ID of the last field written in each open struct, which field IDs are written relative to.
+	lastFields []int16
+}
+
+func newThriftCompactWriter() *thriftCompactWriter {
+	return &thriftCompactWriter{lastFields: []int16{0}}
+}
+
+func (w *thriftCompactWriter) writeFieldHeader(id int16, typ byte) {
+	last := &w.lastFields[len(w.lastFields)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.writeVarint(zigzag(int64(id)))
+	}
+	*last = id
+}
+
+func (w *thriftCompactWriter) writeVarint(v uint64) {
+	w.buf.Write(binary.AppendUvarint(nil, v))
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (w *thriftCompactWriter) writeI32(id int16, v int32) {
+	w.writeFieldHeader(id, thriftI32)
+	w.writeVarint(zigzag(int64(v)))
+}
+
+func (w *thriftCompactWriter) writeI64(id int16, v int64) {
+	w.writeFieldHeader(id, thriftI64)
+	w.writeVarint(zigzag(v))
+}
+
+func (w *thriftCompactWriter) writeString(id int16, s string) {
+	w.writeFieldHeader(id, thriftBinary)
+	w.writeListString(s)
+}
+
+// writeStructField starts a struct field, which endStruct ends.
+func (w *thriftCompactWriter) writeStructField(id int16) {
+	w.writeFieldHeader(id, thriftStruct)
+	w.beginStruct()
+}
+
+// writeListHeader starts a list field of n elements, which follow it.
+func (w *thriftCompactWriter) writeListHeader(id int16, elemType byte, n int) {
+	w.writeFieldHeader(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elemType)
+		return
+	}
+	w.buf.WriteByte(0xF0 | elemType)
+	w.writeVarint(uint64(n))
+}
+
+func (w *thriftCompactWriter) writeListI32(v int32) {
+	w.writeVarint(zigzag(int64(v)))
+}
+
+func (w *thriftCompactWriter) writeListString(s string) {
+	w.writeVarint(uint64(len(s)))
+	w.buf.WriteString(s)
+}
+
+// beginStruct starts a struct that is an element of a list.
+func (w *thriftCompactWriter) beginStruct() {
+	w.lastFields = append(w.lastFields, 0)
+}
+
+func (w *thriftCompactWriter) endStruct() {
+	w.buf.WriteByte(0)
+	w.lastFields = w.lastFields[:len(w.lastFields)-1]
+}
@@ -25,6 +25,7 @@ type CollectorParams struct {
 	ComponentName   string
 	ComponentType   string
 	DataType        CaptureType
+	Deadband        *Deadband
 	FrameSystem     framesystem.Service
 	Interval        time.Duration
 	Logger          logging.Logger
@@ -49,6 +50,14 @@ func (p CollectorParams) Validate() error {
 	if p.DataType != CaptureTypeBinary && p.DataType != CaptureTypeTabular {
 		return errors.New("invalid DataType")
 	}
+	if p.Deadband != nil {
+		if p.DataType != CaptureTypeTabular {
+			return errors.New("deadband only applies to tabular data")
+		}
+		if err := p.Deadband.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
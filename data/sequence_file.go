@@ -5,6 +5,9 @@ import "time"
 // SequencesDir is the subdir under captureDir that holds sequence files (.progseq, .seq).
 const SequencesDir = "sequences"
 
+// SequenceIDTagPrefix prefixes the tag that names the sequence on capture files written by a trigger.
+const SequenceIDTagPrefix = "sequence_id:"
+
 // SequenceFile is the on-disk representation of a sequence.
 type SequenceFile struct {
 	StartTime    time.Time          `json:"start_time"`
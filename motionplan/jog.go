@@ -0,0 +1,202 @@
+package motionplan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"gonum.org/v1/gonum/mat"
+
+	"go.viam.com/rdk/referenceframe"
+)
+
+const (
+	// defaultJogSingularityThreshold is the smallest singular value of the weighted Jacobian, in meters or radians
+	// per unit input, below which a JogController starts damping its solution.
+	defaultJogSingularityThreshold = 0.05
+	// defaultJogMaxDamping is the damping factor a JogController applies at an exact singularity.
+	defaultJogMaxDamping = 0.05
+	// jogLinearWeight converts the linear rows of a Jacobian from mm to meters, so that translational and rotational
+	// velocities are weighted comparably when solving for joint velocities.
+	jogLinearWeight = 1e-3
+	// jogMinSingularValue is the singular value below which a direction is treated as unreachable.
+	jogMinSingularValue = 1e-9
+)
+
+// Twist is a Cartesian velocity of the primary output frame of a model, expressed in the base frame of the model.
+type Twist struct {
+	Linear  r3.Vector // mm per second
+	Angular r3.Vector // radians per second
+}
+
+// JogOptions configures a JogController.
+type JogOptions struct {
+	// VelocityLimits holds the maximum speed of each input, in units per second. Commanded joint velocities that
+	// would exceed any limit are scaled down uniformly, so that the direction of motion is preserved. If empty,
+	// velocities are not limited.
+	VelocityLimits []float64
+	// SingularityThreshold is the smallest singular value of the Jacobian below which the solution is damped.
+	// The linear rows of the Jacobian are weighted in meters for this comparison.
+	// Defaults to defaultJogSingularityThreshold.
+	SingularityThreshold float64
+	// MaxDamping is the damping factor applied at a singularity. Damping ramps up smoothly from zero at the
+	// singularity threshold. Defaults to defaultJogMaxDamping.
+	MaxDamping float64
+	// LimitMargin is the distance, in input units, from a position limit within which a joint will not be driven
+	// further towards that limit.
+	LimitMargin float64
+}
+
+// JogController is a damped least squares resolved-rate controller. It converts Cartesian velocity commands for
+// the primary output frame of a model into joint velocities, without running inverse kinematics.
+// Near singularities the solution is damped, trading tracking accuracy for bounded joint velocities, and joints
+// at their position limits are excluded from the solution rather than driven through them.
+type JogController struct {
+	model  referenceframe.Model
+	limits []referenceframe.Limit
+	opts   JogOptions
+}
+
+// NewJogController returns a JogController for the given model. opts may be nil.
+func NewJogController(model referenceframe.Model, opts *JogOptions) (*JogController, error) {
+	if model == nil {
+		return nil, errors.New("cannot jog a nil model")
+	}
+	limits := model.DoF()
+	if len(limits) == 0 {
+		return nil, fmt.Errorf("cannot jog %s, it has no degrees of freedom", model.Name())
+	}
+	jc := &JogController{model: model, limits: limits}
+	if opts != nil {
+		jc.opts = *opts
+	}
+	if len(jc.opts.VelocityLimits) != 0 && len(jc.opts.VelocityLimits) != len(limits) {
+		return nil, fmt.Errorf("got %d velocity limits for %s, which has %d degrees of freedom",
+			len(jc.opts.VelocityLimits), model.Name(), len(limits))
+	}
+	for i, limit := range jc.opts.VelocityLimits {
+		if limit <= 0 {
+			return nil, fmt.Errorf("velocity limit of input %d must be positive, got %f", i, limit)
+		}
+	}
+	if jc.opts.SingularityThreshold <= 0 {
+		jc.opts.SingularityThreshold = defaultJogSingularityThreshold
+	}
+	if jc.opts.MaxDamping <= 0 {
+		jc.opts.MaxDamping = defaultJogMaxDamping
+	}
+	return jc, nil
+}
+
+// JointVelocities returns the velocity of each input, in units per second, that best produces the commanded twist
+// from the given inputs.
+func (jc *JogController) JointVelocities(inputs []referenceframe.Input, twist Twist) ([]float64, error) {
+	jac, err := jc.model.Jacobian(inputs)
+	if err != nil {
+		return nil, err
+	}
+	for col := 0; col < len(inputs); col++ {
+		for row := 0; row < 3; row++ {
+			jac.Set(row, col, jac.At(row, col)*jogLinearWeight)
+		}
+	}
+	target := mat.NewVecDense(6, []float64{
+		twist.Linear.X * jogLinearWeight, twist.Linear.Y * jogLinearWeight, twist.Linear.Z * jogLinearWeight,
+		twist.Angular.X, twist.Angular.Y, twist.Angular.Z,
+	})
+
+	// Joints at a position limit that the solution would push further into it are locked, and the remaining
+	// joints are solved again. Each pass locks at least one joint, so this terminates.
+	locked := make([]bool, len(inputs))
+	var velocities []float64
+	for {
+		velocities, err = jc.solve(jac, target)
+		if err != nil {
+			return nil, err
+		}
+		lockedAny := false
+		for i, vel := range velocities {
+			if locked[i] {
+				continue
+			}
+			if (vel < 0 && inputs[i] <= jc.limits[i].Min+jc.opts.LimitMargin) ||
+				(vel > 0 && inputs[i] >= jc.limits[i].Max-jc.opts.LimitMargin) {
+				locked[i] = true
+				lockedAny = true
+				for row := 0; row < 6; row++ {
+					jac.Set(row, i, 0)
+				}
+			}
+		}
+		if !lockedAny {
+			break
+		}
+	}
+
+	scale := 1.
+	for i, limit := range jc.opts.VelocityLimits {
+		if speed := math.Abs(velocities[i]); speed > limit {
+			scale = math.Min(scale, limit/speed)
+		}
+	}
+	for i := range velocities {
+		velocities[i] *= scale
+	}
+	return velocities, nil
+}
+
+// Step integrates the joint velocities that produce the commanded twist over dt, and returns the resulting inputs
+// clamped to the limits of the model.
+func (jc *JogController) Step(inputs []referenceframe.Input, twist Twist, dt time.Duration) ([]referenceframe.Input, error) {
+	velocities, err := jc.JointVelocities(inputs, twist)
+	if err != nil {
+		return nil, err
+	}
+	next := make([]referenceframe.Input, len(inputs))
+	for i, vel := range velocities {
+		next[i] = math.Max(jc.limits[i].Min, math.Min(jc.limits[i].Max, inputs[i]+vel*dt.Seconds()))
+	}
+	return next, nil
+}
+
+// solve returns the damped least squares solution of jac * velocities = target, computed through the singular
+// value decomposition of jac. The damping is zero away from singularities and grows quadratically as the smallest
+// singular value falls below the singularity threshold, following Nakamura and Hanafusa.
+func (jc *JogController) solve(jac *mat.Dense, target *mat.VecDense) ([]float64, error) {
+	var svd mat.SVD
+	if !svd.Factorize(jac, mat.SVDThin) {
+		return nil, errors.New("failed to factorize jacobian")
+	}
+	values := svd.Values(nil)
+	var u, v mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+
+	// Zero singular values come from locked joints or from directions no joint can move in. They are skipped rather
+	// than damped, so locking a joint does not by itself damp the motion of the others.
+	minValue := math.Inf(1)
+	for _, value := range values {
+		if value > jogMinSingularValue {
+			minValue = math.Min(minValue, value)
+		}
+	}
+	dampingSq := 0.
+	if ratio := minValue / jc.opts.SingularityThreshold; ratio < 1 {
+		dampingSq = (1 - ratio*ratio) * jc.opts.MaxDamping * jc.opts.MaxDamping
+	}
+
+	_, cols := jac.Dims()
+	velocities := make([]float64, cols)
+	for i, value := range values {
+		if value <= jogMinSingularValue {
+			continue
+		}
+		gain := value / (value*value + dampingSq) * mat.Dot(u.ColView(i), target)
+		for j := range velocities {
+			velocities[j] += gain * v.At(j, i)
+		}
+	}
+	return velocities, nil
+}
@@ -0,0 +1,128 @@
+package motionplan
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+	"gonum.org/v1/gonum/mat"
+
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+// appliedTwist returns the twist produced by the joint velocities, in mm/s and rad/s.
+func appliedTwist(t *testing.T, m referenceframe.Model, inputs []referenceframe.Input, velocities []float64) Twist {
+	t.Helper()
+	jac, err := m.Jacobian(inputs)
+	test.That(t, err, test.ShouldBeNil)
+	var twist mat.VecDense
+	twist.MulVec(jac, mat.NewVecDense(len(velocities), velocities))
+	return Twist{
+		Linear:  r3.Vector{X: twist.AtVec(0), Y: twist.AtVec(1), Z: twist.AtVec(2)},
+		Angular: r3.Vector{X: twist.AtVec(3), Y: twist.AtVec(4), Z: twist.AtVec(5)},
+	}
+}
+
+func TestJogController(t *testing.T) {
+	m, err := referenceframe.ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "")
+	test.That(t, err, test.ShouldBeNil)
+	home := []referenceframe.Input{0, -0.5, -0.5, 0, 1, 0}
+	twist := Twist{Linear: r3.Vector{X: 50, Z: -20}, Angular: r3.Vector{Z: 0.1}}
+
+	t.Run("tracks the commanded twist", func(t *testing.T) {
+		jc, err := NewJogController(m, nil)
+		test.That(t, err, test.ShouldBeNil)
+		velocities, err := jc.JointVelocities(home, twist)
+		test.That(t, err, test.ShouldBeNil)
+		applied := appliedTwist(t, m, home, velocities)
+		test.That(t, spatialmath.R3VectorAlmostEqual(applied.Linear, twist.Linear, 1e-6), test.ShouldBeTrue)
+		test.That(t, spatialmath.R3VectorAlmostEqual(applied.Angular, twist.Angular, 1e-6), test.ShouldBeTrue)
+
+		// Integrating the commanded twist for one second moves the tip by as much.
+		start, err := m.Transform(home)
+		test.That(t, err, test.ShouldBeNil)
+		inputs := home
+		for i := 0; i < 100; i++ {
+			inputs, err = jc.Step(inputs, Twist{Linear: twist.Linear}, 10*time.Millisecond)
+			test.That(t, err, test.ShouldBeNil)
+		}
+		end, err := m.Transform(inputs)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, spatialmath.R3VectorAlmostEqual(end.Point().Sub(start.Point()), twist.Linear, 0.5), test.ShouldBeTrue)
+		test.That(t, spatialmath.OrientationAlmostEqual(end.Orientation(), start.Orientation()), test.ShouldBeTrue)
+	})
+
+	t.Run("damps near singularities", func(t *testing.T) {
+		jc, err := NewJogController(m, nil)
+		test.That(t, err, test.ShouldBeNil)
+		// With the fifth joint straight, the fourth and sixth joints are aligned and the wrist is singular.
+		nearSingular := []referenceframe.Input{0, -0.5, -0.5, 0, 1e-4, 0}
+		velocities, err := jc.JointVelocities(nearSingular, Twist{Angular: r3.Vector{X: 0.1, Y: 0.1}})
+		test.That(t, err, test.ShouldBeNil)
+		for _, vel := range velocities {
+			test.That(t, math.Abs(vel), test.ShouldBeLessThan, 10)
+		}
+	})
+
+	t.Run("scales velocities uniformly to the limits", func(t *testing.T) {
+		jc, err := NewJogController(m, nil)
+		test.That(t, err, test.ShouldBeNil)
+		unlimited, err := jc.JointVelocities(home, twist)
+		test.That(t, err, test.ShouldBeNil)
+
+		fastest := 0
+		for i := range unlimited {
+			if math.Abs(unlimited[i]) > math.Abs(unlimited[fastest]) {
+				fastest = i
+			}
+		}
+		limits := []float64{1, 1, 1, 1, 1, 1}
+		limits[fastest] = math.Abs(unlimited[fastest]) / 2
+		jc, err = NewJogController(m, &JogOptions{VelocityLimits: limits})
+		test.That(t, err, test.ShouldBeNil)
+		limited, err := jc.JointVelocities(home, twist)
+		test.That(t, err, test.ShouldBeNil)
+		scale := 0.5
+		for i := range limited {
+			test.That(t, math.Abs(limited[i]), test.ShouldBeLessThanOrEqualTo, limits[i]+1e-9)
+			test.That(t, limited[i], test.ShouldAlmostEqual, scale*unlimited[i], 1e-9)
+		}
+	})
+
+	t.Run("does not drive joints past their limits", func(t *testing.T) {
+		jc, err := NewJogController(m, nil)
+		test.That(t, err, test.ShouldBeNil)
+		// Rotating about Z is done mostly by the first joint, so put it at its limit in that direction.
+		spin := Twist{Angular: r3.Vector{Z: 0.5}}
+		velocities, err := jc.JointVelocities(home, spin)
+		test.That(t, err, test.ShouldBeNil)
+		atLimit := append([]referenceframe.Input{}, home...)
+		if velocities[0] > 0 {
+			atLimit[0] = m.DoF()[0].Max
+		} else {
+			atLimit[0] = m.DoF()[0].Min
+		}
+		velocities, err = jc.JointVelocities(atLimit, spin)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, velocities[0], test.ShouldEqual, 0)
+
+		next, err := jc.Step(atLimit, spin, time.Second)
+		test.That(t, err, test.ShouldBeNil)
+		for i, limit := range m.DoF() {
+			test.That(t, next[i], test.ShouldBeBetweenOrEqual, limit.Min, limit.Max)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		_, err := NewJogController(m, &JogOptions{VelocityLimits: []float64{1}})
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = NewJogController(m, &JogOptions{VelocityLimits: []float64{1, 1, 1, 1, 1, 0}})
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = NewJogController(referenceframe.NewSimpleModel("empty"), nil)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
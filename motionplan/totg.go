@@ -0,0 +1,693 @@
+package motionplan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"go.viam.com/rdk/referenceframe"
+)
+
+const (
+	// DefaultTimingSampleRateHz is the rate at which timed trajectories are sampled if none is specified.
+	DefaultTimingSampleRateHz = 100.
+	// defaultTimingStep is the step, in joint units, of the path parameter used by the phase plane integration.
+	defaultTimingStep = 1e-4
+	// maxTimingSteps bounds the number of phase plane integration steps. Long paths use a coarser step.
+	maxTimingSteps = 200000
+	// timingDedupTolerance is the max per-joint distance under which adjacent waypoints are collapsed.
+	timingDedupTolerance = 1e-5
+)
+
+// JointLimits holds the per-joint kinematic limits of a frame, in the units of its inputs (e.g. radians or mm)
+// per second, per second squared and per second cubed. Each slice has one entry per degree of freedom.
+type JointLimits struct {
+	Velocity     []float64
+	Acceleration []float64
+	// Jerk limits are optional. If set, every waypoint is treated as a stop and each segment follows a
+	// jerk limited (double S) velocity profile.
+	Jerk []float64
+}
+
+// TimingOptions configures how a trajectory is time parameterized.
+type TimingOptions struct {
+	// SampleRateHz is the rate at which the timed trajectory is sampled. Defaults to DefaultTimingSampleRateHz.
+	SampleRateHz float64
+	// PathTolerance is the maximum distance, in joint units, the timed trajectory may deviate from the interior
+	// waypoints of the trajectory. When positive, corners are blended with circular arcs so that the robot does
+	// not need to stop at each waypoint. Zero stops at every waypoint.
+	PathTolerance float64
+}
+
+// TimedTrajectory is a Trajectory sampled uniformly in time. Times[i] is the time, in seconds since the
+// start of the motion, at which the inputs in Trajectory[i] should be reached.
+type TimedTrajectory struct {
+	Times      []float64
+	Trajectory Trajectory
+}
+
+// Duration returns the total duration of the timed trajectory in seconds.
+func (tt *TimedTrajectory) Duration() float64 {
+	if len(tt.Times) == 0 {
+		return 0
+	}
+	return tt.Times[len(tt.Times)-1]
+}
+
+// TimeParameterize computes a time optimal timing for the trajectory subject to the per-joint limits of each
+// frame that moves, and samples it uniformly. The trajectory passes through every waypoint, or within
+// PathTolerance of the interior ones, and starts and ends at rest. Every frame in the trajectory must be present
+// in every step; limits must be given for every frame with degrees of freedom.
+//
+// The implementation follows "Time-Optimal Trajectory Generation for Path Following with Bounded Acceleration
+// and Velocity" (Kunz & Stilman, 2012), integrating the phase plane numerically rather than searching for
+// switching points analytically.
+func TimeParameterize(traj Trajectory, limits map[string]JointLimits, opts *TimingOptions) (*TimedTrajectory, error) {
+	if len(traj) == 0 {
+		return nil, errors.New("cannot time parameterize an empty trajectory")
+	}
+	frames := make([]string, 0, len(traj[0]))
+	for name := range traj[0] {
+		frames = append(frames, name)
+	}
+	slices.Sort(frames)
+
+	var flatLimits JointLimits
+	dofs := make([]int, len(frames))
+	moving, withJerk := 0, 0
+	for i, name := range frames {
+		dofs[i] = len(traj[0][name])
+		if dofs[i] == 0 {
+			continue
+		}
+		frameLimits, ok := limits[name]
+		if !ok {
+			return nil, fmt.Errorf("no joint limits given for frame %s", name)
+		}
+		if len(frameLimits.Velocity) != dofs[i] || len(frameLimits.Acceleration) != dofs[i] ||
+			(frameLimits.Jerk != nil && len(frameLimits.Jerk) != dofs[i]) {
+			return nil, fmt.Errorf("joint limits for frame %s do not match its %d degrees of freedom", name, dofs[i])
+		}
+		moving++
+		flatLimits.Velocity = append(flatLimits.Velocity, frameLimits.Velocity...)
+		flatLimits.Acceleration = append(flatLimits.Acceleration, frameLimits.Acceleration...)
+		if frameLimits.Jerk != nil {
+			withJerk++
+			flatLimits.Jerk = append(flatLimits.Jerk, frameLimits.Jerk...)
+		}
+	}
+	if withJerk != 0 && withJerk != moving {
+		return nil, errors.New("jerk limits must be given for all frames or for none")
+	}
+
+	waypoints := make([][]float64, 0, len(traj))
+	for i, step := range traj {
+		wp := make([]float64, 0, len(flatLimits.Velocity))
+		for j, name := range frames {
+			inputs, ok := step[name]
+			if !ok || len(inputs) != dofs[j] {
+				return nil, fmt.Errorf("step %d of the trajectory does not have %d inputs for frame %s", i, dofs[j], name)
+			}
+			wp = append(wp, inputs...)
+		}
+		waypoints = append(waypoints, wp)
+	}
+
+	times, configs, err := TimeParameterizeWaypoints(waypoints, flatLimits, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	timed := &TimedTrajectory{Times: times, Trajectory: make(Trajectory, 0, len(configs))}
+	for _, config := range configs {
+		step := make(referenceframe.FrameSystemInputs, len(frames))
+		offset := 0
+		for j, name := range frames {
+			step[name] = config[offset : offset+dofs[j]]
+			offset += dofs[j]
+		}
+		timed.Trajectory = append(timed.Trajectory, step)
+	}
+	return timed, nil
+}
+
+// TimeParameterizeWaypoints is TimeParameterize for a list of waypoints of a single set of joints. It returns
+// uniformly spaced sample times, in seconds, and the joint configuration at each of them.
+func TimeParameterizeWaypoints(
+	waypoints [][]float64,
+	limits JointLimits,
+	opts *TimingOptions,
+) ([]float64, [][]float64, error) {
+	if opts == nil {
+		opts = &TimingOptions{}
+	}
+	sampleRate := opts.SampleRateHz
+	if sampleRate == 0 {
+		sampleRate = DefaultTimingSampleRateHz
+	}
+	if sampleRate < 0 || opts.PathTolerance < 0 {
+		return nil, nil, errors.New("sample rate and path tolerance may not be negative")
+	}
+	if len(waypoints) == 0 {
+		return nil, nil, errors.New("at least one waypoint is required")
+	}
+	nDof := len(waypoints[0])
+	for _, wp := range waypoints {
+		if len(wp) != nDof {
+			return nil, nil, errors.New("all waypoints must have the same number of joints")
+		}
+	}
+	if len(limits.Velocity) != nDof || len(limits.Acceleration) != nDof || (limits.Jerk != nil && len(limits.Jerk) != nDof) {
+		return nil, nil, fmt.Errorf("joint limits do not match the %d joints of the waypoints", nDof)
+	}
+	for i := 0; i < nDof; i++ {
+		if limits.Velocity[i] <= 0 || limits.Acceleration[i] <= 0 || (limits.Jerk != nil && limits.Jerk[i] <= 0) {
+			return nil, nil, fmt.Errorf("joint %d has non-positive limits", i)
+		}
+	}
+	if limits.Jerk != nil && opts.PathTolerance > 0 {
+		return nil, nil, errors.New("jerk limited timing does not support a path tolerance")
+	}
+
+	waypoints = dedupTimingWaypoints(waypoints)
+	if len(waypoints) == 1 {
+		return []float64{0}, [][]float64{slices.Clone(waypoints[0])}, nil
+	}
+
+	path := newTimingPath(waypoints, opts.PathTolerance)
+	var profile timingProfile
+	if limits.Jerk != nil {
+		profile = newDoubleSProfile(path, limits)
+	} else {
+		profile = newPhasePlaneProfile(path, limits)
+	}
+
+	duration := profile.duration()
+	nSamples := int(math.Ceil(duration*sampleRate)) + 1
+	dt := duration / float64(nSamples-1)
+	times := make([]float64, nSamples)
+	configs := make([][]float64, nSamples)
+	for k := 0; k < nSamples; k++ {
+		t := float64(k) * dt
+		if k == nSamples-1 {
+			t = duration
+		}
+		times[k] = t
+		configs[k] = path.position(profile.pathPosition(t))
+	}
+	// Pin the ends to the exact waypoints to avoid floating point drift.
+	configs[0] = slices.Clone(waypoints[0])
+	configs[nSamples-1] = slices.Clone(waypoints[len(waypoints)-1])
+	return times, configs, nil
+}
+
+func dedupTimingWaypoints(waypoints [][]float64) [][]float64 {
+	out := [][]float64{waypoints[0]}
+	for _, wp := range waypoints[1:] {
+		prev := out[len(out)-1]
+		for j := range wp {
+			if math.Abs(wp[j]-prev[j]) > timingDedupTolerance {
+				out = append(out, wp)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// timingPathSegment is a piece of a path in joint space, parameterized by arc length.
+type timingPathSegment interface {
+	length() float64
+	position(s float64) []float64
+	// tangent returns the first derivative of the position with respect to s, which is a unit vector.
+	tangent(s float64) []float64
+	// curvature returns the second derivative of the position with respect to s.
+	curvature(s float64) []float64
+}
+
+type linearTimingSegment struct {
+	start, dir []float64
+	len        float64
+}
+
+func newLinearTimingSegment(start, end []float64) *linearTimingSegment {
+	dir := make([]float64, len(start))
+	for i := range start {
+		dir[i] = end[i] - start[i]
+	}
+	l := norm(dir)
+	for i := range dir {
+		dir[i] /= l
+	}
+	return &linearTimingSegment{start: start, dir: dir, len: l}
+}
+
+func (seg *linearTimingSegment) length() float64 { return seg.len }
+
+func (seg *linearTimingSegment) position(s float64) []float64 {
+	out := make([]float64, len(seg.start))
+	for i := range out {
+		out[i] = seg.start[i] + s*seg.dir[i]
+	}
+	return out
+}
+
+func (seg *linearTimingSegment) tangent(float64) []float64 { return seg.dir }
+
+func (seg *linearTimingSegment) curvature(float64) []float64 { return make([]float64, len(seg.start)) }
+
+// circularTimingSegment is a circular arc in the plane spanned by two unit vectors x and y around center.
+type circularTimingSegment struct {
+	center, x, y []float64
+	radius       float64
+	angle        float64
+}
+
+func (seg *circularTimingSegment) length() float64 { return seg.radius * seg.angle }
+
+func (seg *circularTimingSegment) position(s float64) []float64 {
+	c, sn := math.Cos(s/seg.radius), math.Sin(s/seg.radius)
+	out := make([]float64, len(seg.center))
+	for i := range out {
+		out[i] = seg.center[i] + seg.radius*(seg.x[i]*c+seg.y[i]*sn)
+	}
+	return out
+}
+
+func (seg *circularTimingSegment) tangent(s float64) []float64 {
+	c, sn := math.Cos(s/seg.radius), math.Sin(s/seg.radius)
+	out := make([]float64, len(seg.center))
+	for i := range out {
+		out[i] = -seg.x[i]*sn + seg.y[i]*c
+	}
+	return out
+}
+
+func (seg *circularTimingSegment) curvature(s float64) []float64 {
+	c, sn := math.Cos(s/seg.radius), math.Sin(s/seg.radius)
+	out := make([]float64, len(seg.center))
+	for i := range out {
+		out[i] = -(seg.x[i]*c + seg.y[i]*sn) / seg.radius
+	}
+	return out
+}
+
+// timingPath is a continuous path through a list of waypoints made of linear segments, optionally joined by
+// circular blends.
+type timingPath struct {
+	segments []timingPathSegment
+	// starts[i] is the arc length at which segments[i] starts. starts has one more entry than segments,
+	// which is the total length.
+	starts []float64
+	// stops[i] is true if the path has a corner, and so the motion must come to rest, at starts[i].
+	stops []bool
+}
+
+func newTimingPath(waypoints [][]float64, tolerance float64) *timingPath {
+	path := &timingPath{}
+	add := func(seg timingPathSegment) {
+		if seg.length() > 0 {
+			path.segments = append(path.segments, seg)
+		}
+	}
+
+	start := waypoints[0]
+	for i := 1; i < len(waypoints)-1; i++ {
+		blend, blendStart, blendEnd := circularBlend(start, waypoints[i], waypoints[i+1], tolerance)
+		if blend == nil {
+			add(newLinearTimingSegment(start, waypoints[i]))
+			start = waypoints[i]
+			continue
+		}
+		add(newLinearTimingSegment(start, blendStart))
+		add(blend)
+		start = blendEnd
+	}
+	add(newLinearTimingSegment(start, waypoints[len(waypoints)-1]))
+
+	// The motion only needs to stop where consecutive segments meet at a corner, which is where a linear
+	// segment follows another linear segment. The first segment always starts at rest.
+	for i, seg := range path.segments {
+		_, linear := seg.(*linearTimingSegment)
+		prevLinear := false
+		if i > 0 {
+			_, prevLinear = path.segments[i-1].(*linearTimingSegment)
+		}
+		path.stops = append(path.stops, i == 0 || (prevLinear && linear))
+	}
+	path.stops = append(path.stops, true)
+
+	total := 0.
+	for _, seg := range path.segments {
+		path.starts = append(path.starts, total)
+		total += seg.length()
+	}
+	path.starts = append(path.starts, total)
+	return path
+}
+
+// circularBlend returns a circular arc which deviates at most tolerance from the corner at q1 between the
+// lines q0-q1 and q1-q2, along with the points where the arc meets those lines. It returns a nil arc if no
+// blend is needed or possible.
+func circularBlend(q0, q1, q2 []float64, tolerance float64) (*circularTimingSegment, []float64, []float64) {
+	if tolerance <= 0 {
+		return nil, nil, nil
+	}
+	n := len(q1)
+	y1, y2 := make([]float64, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		y1[i], y2[i] = q1[i]-q0[i], q2[i]-q1[i]
+	}
+	l1, l2 := norm(y1), norm(y2)
+	for i := 0; i < n; i++ {
+		y1[i] /= l1
+		y2[i] /= l2
+	}
+	cosAngle := math.Max(-1, math.Min(1, dot(y1, y2)))
+	angle := math.Acos(cosAngle)
+	// Collinear segments need no blend, and a full reversal cannot be blended.
+	if angle < 1e-6 || math.Pi-angle < 1e-6 {
+		return nil, nil, nil
+	}
+
+	// Each line gives up at most half of its length to the blends at either of its ends.
+	dist := math.Min(math.Min(l1/2, l2/2), tolerance*math.Sin(angle/2)/(1-math.Cos(angle/2)))
+	radius := dist / math.Tan(angle/2)
+
+	toCenter := make([]float64, n)
+	for i := 0; i < n; i++ {
+		toCenter[i] = y2[i] - y1[i]
+	}
+	toCenterLen := norm(toCenter)
+	center := make([]float64, n)
+	blendStart, blendEnd := make([]float64, n), make([]float64, n)
+	x := make([]float64, n)
+	for i := 0; i < n; i++ {
+		center[i] = q1[i] + toCenter[i]/toCenterLen*radius/math.Cos(angle/2)
+		blendStart[i] = q1[i] - dist*y1[i]
+		blendEnd[i] = q1[i] + dist*y2[i]
+		x[i] = blendStart[i] - center[i]
+	}
+	xLen := norm(x)
+	for i := 0; i < n; i++ {
+		x[i] /= xLen
+	}
+	return &circularTimingSegment{center: center, x: x, y: y1, radius: radius, angle: angle}, blendStart, blendEnd
+}
+
+func (p *timingPath) length() float64 {
+	return p.starts[len(p.starts)-1]
+}
+
+// segmentIndex returns the index of the segment containing arc length s.
+func (p *timingPath) segmentIndex(s float64) int {
+	i, _ := slices.BinarySearch(p.starts, s)
+	// BinarySearch returns the index of the first start >= s, so step back unless s is exactly at a start.
+	if i == len(p.starts) || p.starts[i] > s {
+		i--
+	}
+	return max(0, min(i, len(p.segments)-1))
+}
+
+func (p *timingPath) position(s float64) []float64 {
+	s = math.Max(0, math.Min(s, p.length()))
+	i := p.segmentIndex(s)
+	return p.segments[i].position(s - p.starts[i])
+}
+
+// timingProfile is a timing law s(t) along a timingPath.
+type timingProfile interface {
+	duration() float64
+	pathPosition(t float64) float64
+}
+
+// phasePlaneProfile is the time optimal timing law along a path, computed by integrating the maximum
+// acceleration forward from the start and the minimum acceleration backward from the end, and taking the
+// lower of the two velocities at each step, bounded by the maximum velocity curve.
+type phasePlaneProfile struct {
+	// s, sDot and t hold the path position, path velocity and time at each integration step.
+	s, sDot, t []float64
+}
+
+func newPhasePlaneProfile(path *timingPath, limits JointLimits) *phasePlaneProfile {
+	// Discretize each segment separately so that every segment boundary is an integration step.
+	step := math.Max(defaultTimingStep, path.length()/maxTimingSteps)
+	var s []float64
+	var segs []int
+	for i, seg := range path.segments {
+		n := max(1, int(math.Ceil(seg.length()/step)))
+		for k := 0; k < n; k++ {
+			s = append(s, path.starts[i]+seg.length()*float64(k)/float64(n))
+			segs = append(segs, i)
+		}
+	}
+	s = append(s, path.length())
+	segs = append(segs, len(path.segments)-1)
+
+	// The maximum velocity at each step is the lowest of the velocity limits of the segments it touches.
+	maxSDot := make([]float64, len(s))
+	for k := range s {
+		i := segs[k]
+		local := s[k] - path.starts[i]
+		maxSDot[k] = maxPathVelocity(path.segments[i], local, limits)
+		if k > 0 && segs[k-1] != i {
+			prev := path.segments[segs[k-1]]
+			maxSDot[k] = math.Min(maxSDot[k], maxPathVelocity(prev, prev.length(), limits))
+			if path.stops[i] {
+				maxSDot[k] = 0
+			}
+		}
+	}
+	maxSDot[0], maxSDot[len(s)-1] = 0, 0
+
+	// Forward pass using the maximum path acceleration.
+	sDot := make([]float64, len(s))
+	for k := 0; k < len(s)-1; k++ {
+		seg := path.segments[segs[k]]
+		_, accel := pathAccelerationBounds(seg, s[k]-path.starts[segs[k]], sDot[k], limits)
+		next := sDot[k]*sDot[k] + 2*accel*(s[k+1]-s[k])
+		sDot[k+1] = math.Sqrt(math.Max(0, math.Min(next, maxSDot[k+1]*maxSDot[k+1])))
+	}
+	// Backward pass using the minimum path acceleration.
+	sDot[len(s)-1] = 0
+	for k := len(s) - 2; k >= 0; k-- {
+		seg := path.segments[segs[k]]
+		decel, _ := pathAccelerationBounds(seg, s[k+1]-path.starts[segs[k]], sDot[k+1], limits)
+		prev := sDot[k+1]*sDot[k+1] - 2*decel*(s[k+1]-s[k])
+		sDot[k] = math.Min(sDot[k], math.Sqrt(math.Max(0, prev)))
+	}
+
+	t := make([]float64, len(s))
+	for k := 0; k < len(s)-1; k++ {
+		avg := (sDot[k] + sDot[k+1]) / 2
+		if avg <= 0 {
+			// Both ends of the step are at rest, which only happens when rounding leaves a zero length step.
+			t[k+1] = t[k]
+			continue
+		}
+		t[k+1] = t[k] + (s[k+1]-s[k])/avg
+	}
+	return &phasePlaneProfile{s: s, sDot: sDot, t: t}
+}
+
+func (p *phasePlaneProfile) duration() float64 {
+	return p.t[len(p.t)-1]
+}
+
+func (p *phasePlaneProfile) pathPosition(t float64) float64 {
+	if t <= 0 {
+		return p.s[0]
+	}
+	if t >= p.duration() {
+		return p.s[len(p.s)-1]
+	}
+	k, _ := slices.BinarySearch(p.t, t)
+	k = max(1, k) - 1
+	dt := p.t[k+1] - p.t[k]
+	if dt <= 0 {
+		return p.s[k]
+	}
+	// The path acceleration is constant over each step.
+	accel := (p.sDot[k+1]*p.sDot[k+1] - p.sDot[k]*p.sDot[k]) / (2 * (p.s[k+1] - p.s[k]))
+	tau := t - p.t[k]
+	return math.Min(p.s[k+1], p.s[k]+p.sDot[k]*tau+accel*tau*tau/2)
+}
+
+// maxPathVelocity returns the highest path velocity at s for which every joint is within its velocity limit and
+// a path acceleration exists which keeps every joint within its acceleration limit.
+func maxPathVelocity(seg timingPathSegment, s float64, limits JointLimits) float64 {
+	tangent, curvature := seg.tangent(s), seg.curvature(s)
+	maxVel := math.Inf(1)
+	for j := range tangent {
+		if math.Abs(tangent[j]) > 1e-12 {
+			maxVel = math.Min(maxVel, limits.Velocity[j]/math.Abs(tangent[j]))
+		}
+	}
+	// Kunz & Stilman equation 31.
+	for j := range tangent {
+		if math.Abs(tangent[j]) <= 1e-12 {
+			if math.Abs(curvature[j]) > 1e-12 {
+				maxVel = math.Min(maxVel, math.Sqrt(limits.Acceleration[j]/math.Abs(curvature[j])))
+			}
+			continue
+		}
+		for k := j + 1; k < len(tangent); k++ {
+			if math.Abs(tangent[k]) <= 1e-12 {
+				continue
+			}
+			diff := math.Abs(curvature[j]/tangent[j] - curvature[k]/tangent[k])
+			if diff > 1e-12 {
+				bound := (limits.Acceleration[j]/math.Abs(tangent[j]) + limits.Acceleration[k]/math.Abs(tangent[k])) / diff
+				maxVel = math.Min(maxVel, math.Sqrt(bound))
+			}
+		}
+	}
+	return maxVel
+}
+
+// pathAccelerationBounds returns the lowest and highest path accelerations at s and path velocity sDot for which
+// every joint is within its acceleration limit.
+func pathAccelerationBounds(seg timingPathSegment, s, sDot float64, limits JointLimits) (float64, float64) {
+	tangent, curvature := seg.tangent(s), seg.curvature(s)
+	lo, hi := math.Inf(-1), math.Inf(1)
+	for j := range tangent {
+		if math.Abs(tangent[j]) <= 1e-12 {
+			continue
+		}
+		a := (-limits.Acceleration[j] - curvature[j]*sDot*sDot) / tangent[j]
+		b := (limits.Acceleration[j] - curvature[j]*sDot*sDot) / tangent[j]
+		lo = math.Max(lo, math.Min(a, b))
+		hi = math.Min(hi, math.Max(a, b))
+	}
+	// Rounding may leave the velocity marginally above the limit curve, where the bounds cross.
+	if lo > hi {
+		mid := (lo + hi) / 2
+		return mid, mid
+	}
+	return lo, hi
+}
+
+// doubleSProfile is a jerk limited timing law which stops at every waypoint. Each linear segment follows a
+// rest to rest double S velocity profile, as described in "Trajectory Planning for Automatic Machines and
+// Robots" (Biagiotti & Melchiorri, 2008), section 3.4.
+type doubleSProfile struct {
+	// starts[i] is the time at which segments[i] starts, and pathStarts[i] its arc length along the path.
+	starts     []float64
+	pathStarts []float64
+	segments   []doubleSSegment
+}
+
+type doubleSSegment struct {
+	length, jerk     float64
+	tj, ta, tv       float64
+	accelLim, velLim float64
+}
+
+func newDoubleSProfile(path *timingPath, limits JointLimits) *doubleSProfile {
+	profile := &doubleSProfile{}
+	elapsed := 0.
+	for i, seg := range path.segments {
+		tangent := seg.tangent(0)
+		vMax, aMax, jMax := math.Inf(1), math.Inf(1), math.Inf(1)
+		for j, d := range tangent {
+			if math.Abs(d) > 1e-12 {
+				vMax = math.Min(vMax, limits.Velocity[j]/math.Abs(d))
+				aMax = math.Min(aMax, limits.Acceleration[j]/math.Abs(d))
+				jMax = math.Min(jMax, limits.Jerk[j]/math.Abs(d))
+			}
+		}
+		ds := newDoubleSSegment(seg.length(), vMax, aMax, jMax)
+		profile.starts = append(profile.starts, elapsed)
+		profile.segments = append(profile.segments, ds)
+		profile.pathStarts = append(profile.pathStarts, path.starts[i])
+		elapsed += 2*ds.ta + ds.tv
+	}
+	profile.starts = append(profile.starts, elapsed)
+	return profile
+}
+
+func newDoubleSSegment(length, vMax, aMax, jMax float64) doubleSSegment {
+	var tj, ta, tv float64
+	if vMax*jMax >= aMax*aMax {
+		tj = aMax / jMax
+		ta = tj + vMax/aMax
+	} else {
+		tj = math.Sqrt(vMax / jMax)
+		ta = 2 * tj
+	}
+	tv = length/vMax - ta
+	if tv < 0 {
+		// The maximum velocity is not reached.
+		tv = 0
+		if length >= 2*aMax*aMax*aMax/(jMax*jMax) {
+			tj = aMax / jMax
+			ta = tj/2 + math.Sqrt(tj*tj/4+length/aMax)
+		} else {
+			tj = math.Cbrt(length / (2 * jMax))
+			ta = 2 * tj
+		}
+	}
+	accelLim := jMax * tj
+	return doubleSSegment{
+		length:   length,
+		jerk:     jMax,
+		tj:       tj,
+		ta:       ta,
+		tv:       tv,
+		accelLim: accelLim,
+		velLim:   accelLim * (ta - tj),
+	}
+}
+
+// accelPosition returns the distance travelled t seconds into the acceleration phase.
+func (seg doubleSSegment) accelPosition(t float64) float64 {
+	switch {
+	case t < seg.tj:
+		return seg.jerk * t * t * t / 6
+	case t < seg.ta-seg.tj:
+		return seg.accelLim / 6 * (3*t*t - 3*seg.tj*t + seg.tj*seg.tj)
+	default:
+		rem := seg.ta - t
+		return seg.velLim*seg.ta/2 - seg.velLim*rem + seg.jerk*rem*rem*rem/6
+	}
+}
+
+func (seg doubleSSegment) position(t float64) float64 {
+	total := 2*seg.ta + seg.tv
+	switch {
+	case t <= 0:
+		return 0
+	case t >= total:
+		return seg.length
+	case t < seg.ta:
+		return seg.accelPosition(t)
+	case t < seg.ta+seg.tv:
+		return seg.velLim*seg.ta/2 + seg.velLim*(t-seg.ta)
+	default:
+		// The deceleration phase mirrors the acceleration phase.
+		return seg.length - seg.accelPosition(total-t)
+	}
+}
+
+func (p *doubleSProfile) duration() float64 {
+	return p.starts[len(p.starts)-1]
+}
+
+func (p *doubleSProfile) pathPosition(t float64) float64 {
+	i, _ := slices.BinarySearch(p.starts, t)
+	i = max(1, min(i, len(p.segments))) - 1
+	return p.pathStarts[i] + p.segments[i].position(t-p.starts[i])
+}
+
+func dot(a, b []float64) float64 {
+	out := 0.
+	for i := range a {
+		out += a[i] * b[i]
+	}
+	return out
+}
+
+func norm(v []float64) float64 {
+	return math.Sqrt(dot(v, v))
+}
@@ -0,0 +1,147 @@
+package motionplan
+
+import (
+	"math"
+	"testing"
+
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/referenceframe"
+)
+
+// checkTimingLimits differentiates the uniformly sampled configurations and checks that every joint stays within
+// its velocity and acceleration limits, with some slack for the finite differences.
+func checkTimingLimits(t *testing.T, times []float64, configs [][]float64, limits JointLimits) {
+	t.Helper()
+	dt := times[1] - times[0]
+	for k := 1; k < len(configs); k++ {
+		for j := range configs[k] {
+			vel := (configs[k][j] - configs[k-1][j]) / dt
+			test.That(t, math.Abs(vel), test.ShouldBeLessThanOrEqualTo, limits.Velocity[j]*1.01)
+			if k < len(configs)-1 {
+				accel := (configs[k+1][j] - 2*configs[k][j] + configs[k-1][j]) / (dt * dt)
+				test.That(t, math.Abs(accel), test.ShouldBeLessThanOrEqualTo, limits.Acceleration[j]*1.05)
+			}
+		}
+	}
+}
+
+func TestTimeParameterizeWaypoints(t *testing.T) {
+	t.Run("single segment follows a trapezoidal profile", func(t *testing.T) {
+		limits := JointLimits{Velocity: []float64{1, 1}, Acceleration: []float64{1, 1}}
+		times, configs, err := TimeParameterizeWaypoints([][]float64{{0, 0}, {2, 1}}, limits, nil)
+		test.That(t, err, test.ShouldBeNil)
+
+		// The first joint limits the motion: 1s to accelerate, 1s at 1 rad/s and 1s to decelerate.
+		test.That(t, times[len(times)-1], test.ShouldAlmostEqual, 3, 1e-2)
+		test.That(t, times[1]-times[0], test.ShouldAlmostEqual, 1/DefaultTimingSampleRateHz, 1e-3)
+		test.That(t, configs[0], test.ShouldResemble, []float64{0, 0})
+		test.That(t, configs[len(configs)-1], test.ShouldResemble, []float64{2, 1})
+		mid := configs[len(configs)/2]
+		test.That(t, mid[0], test.ShouldAlmostEqual, 1, 1e-2)
+		test.That(t, mid[1], test.ShouldAlmostEqual, 0.5, 1e-2)
+		checkTimingLimits(t, times, configs, limits)
+	})
+
+	t.Run("blending corners is faster and stays within the tolerance", func(t *testing.T) {
+		limits := JointLimits{Velocity: []float64{1, 2}, Acceleration: []float64{2, 4}}
+		waypoints := [][]float64{{0, 0}, {1, 0}, {1, 1}, {2, 1}}
+
+		stopTimes, stopConfigs, err := TimeParameterizeWaypoints(waypoints, limits, nil)
+		test.That(t, err, test.ShouldBeNil)
+		checkTimingLimits(t, stopTimes, stopConfigs, limits)
+
+		tolerance := 0.1
+		times, configs, err := TimeParameterizeWaypoints(waypoints, limits, &TimingOptions{PathTolerance: tolerance})
+		test.That(t, err, test.ShouldBeNil)
+		checkTimingLimits(t, times, configs, limits)
+		test.That(t, times[len(times)-1], test.ShouldBeLessThan, stopTimes[len(stopTimes)-1])
+
+		// Every sample stays within the tolerance of the piecewise linear path.
+		for _, config := range configs {
+			dist := math.Inf(1)
+			for i := 0; i < len(waypoints)-1; i++ {
+				dist = math.Min(dist, distToSegment(config, waypoints[i], waypoints[i+1]))
+			}
+			test.That(t, dist, test.ShouldBeLessThanOrEqualTo, tolerance+1e-6)
+		}
+	})
+
+	t.Run("jerk limited segments follow a double S profile", func(t *testing.T) {
+		limits := JointLimits{Velocity: []float64{1}, Acceleration: []float64{1}, Jerk: []float64{2}}
+		times, configs, err := TimeParameterizeWaypoints([][]float64{{0}, {2}}, limits, nil)
+		test.That(t, err, test.ShouldBeNil)
+		// 0.5s of jerk, 0.5s at the acceleration limit and 0.5s of jerk to reach cruise, 0.5s of cruise,
+		// then the same in reverse.
+		test.That(t, times[len(times)-1], test.ShouldAlmostEqual, 3.5, 1e-2)
+		test.That(t, configs[len(configs)-1], test.ShouldResemble, []float64{2})
+		checkTimingLimits(t, times, configs, limits)
+
+		_, _, err = TimeParameterizeWaypoints([][]float64{{0}, {2}}, limits, &TimingOptions{PathTolerance: 0.1})
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("single waypoint", func(t *testing.T) {
+		limits := JointLimits{Velocity: []float64{1}, Acceleration: []float64{1}}
+		times, configs, err := TimeParameterizeWaypoints([][]float64{{1}, {1}}, limits, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, times, test.ShouldResemble, []float64{0})
+		test.That(t, configs, test.ShouldResemble, [][]float64{{1}})
+	})
+
+	t.Run("invalid limits", func(t *testing.T) {
+		_, _, err := TimeParameterizeWaypoints([][]float64{{0}, {1}}, JointLimits{Velocity: []float64{1}}, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, _, err = TimeParameterizeWaypoints(
+			[][]float64{{0}, {1}}, JointLimits{Velocity: []float64{0}, Acceleration: []float64{1}}, nil,
+		)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
+
+func TestTimeParameterize(t *testing.T) {
+	traj := Trajectory{
+		{"arm": {0, 0}, "gantry": {0}, "static": {}},
+		{"arm": {1, 0}, "gantry": {100}, "static": {}},
+	}
+	limits := map[string]JointLimits{
+		"arm":    {Velocity: []float64{1, 1}, Acceleration: []float64{10, 10}},
+		"gantry": {Velocity: []float64{50}, Acceleration: []float64{500}},
+	}
+	timed, err := TimeParameterize(traj, limits, &TimingOptions{SampleRateHz: 10})
+	test.That(t, err, test.ShouldBeNil)
+
+	// The gantry needs 2s to travel 100mm at 50mm/s, plus 0.1s to accelerate and decelerate.
+	test.That(t, timed.Duration(), test.ShouldAlmostEqual, 2.1, 1e-2)
+	test.That(t, len(timed.Times), test.ShouldEqual, len(timed.Trajectory))
+	test.That(t, timed.Times[1], test.ShouldAlmostEqual, 0.1, 1e-2)
+	last := timed.Trajectory[len(timed.Trajectory)-1]
+	test.That(t, last["arm"], test.ShouldResemble, []referenceframe.Input{1, 0})
+	test.That(t, last["gantry"], test.ShouldResemble, []referenceframe.Input{100})
+	test.That(t, last["static"], test.ShouldBeEmpty)
+	for _, step := range timed.Trajectory {
+		// Both frames move along a straight line in joint space, so they stay proportional.
+		test.That(t, step["gantry"][0], test.ShouldAlmostEqual, 100*step["arm"][0], 1e-6)
+	}
+
+	delete(limits, "gantry")
+	_, err = TimeParameterize(traj, limits, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "gantry")
+
+	limits["gantry"] = JointLimits{Velocity: []float64{50}, Acceleration: []float64{500}, Jerk: []float64{1000}}
+	_, err = TimeParameterize(traj, limits, nil)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func distToSegment(p, a, b []float64) float64 {
+	ab, ap := make([]float64, len(p)), make([]float64, len(p))
+	for i := range p {
+		ab[i], ap[i] = b[i]-a[i], p[i]-a[i]
+	}
+	frac := math.Max(0, math.Min(1, dot(ap, ab)/dot(ab, ab)))
+	for i := range ap {
+		ap[i] -= frac * ab[i]
+	}
+	return norm(ap)
+}
@@ -0,0 +1,272 @@
+package pointcloud
+
+import (
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/mat"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+// ICPMethod is the error that iterative closest point minimizes.
+type ICPMethod int
+
+const (
+	// PointToPoint minimizes the distances between the points of the source and their closest points in the target.
+	PointToPoint ICPMethod = iota
+	// PointToPlane minimizes the distances between the points of the source and the planes of their closest points in
+	// the target, which converges faster on surfaces that slide past each other.
+	PointToPlane
+)
+
+// ICPConfig describes how to register two point clouds.
+type ICPConfig struct {
+	Method ICPMethod
+	// MaxCorrespondenceDistance is the furthest in mm that the closest point in the target can be for a point of the
+	// source to be used.
+	MaxCorrespondenceDistance float64
+	MaxIterations             int
+	// Tolerance stops the iterations once the RMSE changes by less than this many mm.
+	Tolerance float64
+	// NormalNeighbors is the number of neighbors used to estimate the normals of the target for PointToPlane.
+	NormalNeighbors int
+}
+
+// DefaultICPConfig returns a config that corrects errors of up to about the given distance in mm.
+func DefaultICPConfig(method ICPMethod, maxCorrespondenceDistance float64) ICPConfig {
+	return ICPConfig{
+		Method:                    method,
+		MaxCorrespondenceDistance: maxCorrespondenceDistance,
+		MaxIterations:             50,
+		Tolerance:                 1e-4,
+		NormalNeighbors:           10,
+	}
+}
+
+// ICPResult is the pose that best aligns the source with the target and how well they agree with each other.
+type ICPResult struct {
+	// Pose transforms the source into the frame of the target.
+	Pose spatialmath.Pose
+	// Fitness is the fraction of the points of the source that are within the maximum correspondence distance of the
+	// target.
+	Fitness float64
+	// RMSE is the root mean square distance in mm between those points and their closest points in the target.
+	RMSE       float64
+	Iterations int
+	Converged  bool
+}
+
+// RegisterICP finds the pose that aligns the source with the target by iterative closest point, starting from the
+// initial guess, which may be nil.
+func RegisterICP(source, target PointCloud, initial spatialmath.Pose, config ICPConfig) (*ICPResult, error) {
+	if source.Size() == 0 || target.Size() == 0 {
+		return nil, errors.New("cannot register empty point clouds")
+	}
+	if config.MaxCorrespondenceDistance <= 0 {
+		return nil, errors.New("the maximum correspondence distance must be positive")
+	}
+	if config.MaxIterations <= 0 {
+		config.MaxIterations = 1
+	}
+	if initial == nil {
+		initial = spatialmath.NewZeroPose()
+	}
+	kd := ToKDTree(target)
+	var normals map[r3.Vector]r3.Vector
+	if config.Method == PointToPlane {
+		normals = map[r3.Vector]r3.Vector{}
+	}
+	points := make([]r3.Vector, 0, source.Size())
+	source.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		points = append(points, p)
+		return true
+	})
+
+	result := &ICPResult{Pose: initial}
+	previousRMSE := math.Inf(1)
+	for result.Iterations < config.MaxIterations {
+		sources, targets, rmse := icpCorrespondences(kd, points, result.Pose, config.MaxCorrespondenceDistance)
+		if len(sources) < 3 {
+			return nil, errors.Errorf("only %d points of the source are within %v mm of the target",
+				len(sources), config.MaxCorrespondenceDistance)
+		}
+		if math.Abs(previousRMSE-rmse) < config.Tolerance {
+			result.Converged = true
+			break
+		}
+		previousRMSE = rmse
+
+		var step spatialmath.Pose
+		var err error
+		if config.Method == PointToPlane {
+			targetNormals := make([]r3.Vector, len(targets))
+			for i, t := range targets {
+				n, ok := normals[t]
+				if !ok {
+					n, _ = EstimateNormal(kd, t, config.NormalNeighbors)
+					normals[t] = n
+				}
+				targetNormals[i] = n
+			}
+			step, err = pointToPlaneStep(sources, targets, targetNormals)
+		} else {
+			step, err = pointToPointStep(sources, targets)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Pose = spatialmath.Compose(step, result.Pose)
+		result.Iterations++
+	}
+
+	sources, _, rmse := icpCorrespondences(kd, points, result.Pose, config.MaxCorrespondenceDistance)
+	result.Fitness = float64(len(sources)) / float64(len(points))
+	result.RMSE = rmse
+	return result, nil
+}
+
+// icpCorrespondences moves the points by the pose and pairs them with their closest points in the target, if those
+// are close enough. It returns the moved points, their pairs and the root mean square distance between them.
+func icpCorrespondences(kd *KDTree, points []r3.Vector, pose spatialmath.Pose, maxDistance float64) ([]r3.Vector, []r3.Vector, float64) {
+	rotation := pose.Orientation().RotationMatrix()
+	translation := pose.Point()
+	sources := make([]r3.Vector, 0, len(points))
+	targets := make([]r3.Vector, 0, len(points))
+	var sum float64
+	for _, p := range points {
+		moved := rotation.Mul(p).Add(translation)
+		closest, _, dist, ok := kd.NearestNeighbor(moved)
+		if !ok || dist > maxDistance {
+			continue
+		}
+		sources = append(sources, moved)
+		targets = append(targets, closest)
+		sum += dist * dist
+	}
+	if len(sources) == 0 {
+		return nil, nil, math.Inf(1)
+	}
+	return sources, targets, math.Sqrt(sum / float64(len(sources)))
+}
+
+// pointToPointStep returns the rigid transform that best moves the sources onto the targets, by the method of Kabsch.
+func pointToPointStep(sources, targets []r3.Vector) (spatialmath.Pose, error) {
+	var sourceCentroid, targetCentroid r3.Vector
+	for i := range sources {
+		sourceCentroid = sourceCentroid.Add(sources[i])
+		targetCentroid = targetCentroid.Add(targets[i])
+	}
+	sourceCentroid = sourceCentroid.Mul(1 / float64(len(sources)))
+	targetCentroid = targetCentroid.Mul(1 / float64(len(targets)))
+
+	covariance := mat.NewDense(3, 3, nil)
+	for i := range sources {
+		s, t := sources[i].Sub(sourceCentroid), targets[i].Sub(targetCentroid)
+		for r, tv := range []float64{t.X, t.Y, t.Z} {
+			for c, sv := range []float64{s.X, s.Y, s.Z} {
+				covariance.Set(r, c, covariance.At(r, c)+tv*sv)
+			}
+		}
+	}
+	var svd mat.SVD
+	if !svd.Factorize(covariance, mat.SVDFull) {
+		return nil, errors.New("could not align the point clouds")
+	}
+	var u, v, rotation mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+	rotation.Mul(&u, v.T())
+	if mat.Det(&rotation) < 0 {
+		// Flip the axis of least variance so that the result is a rotation rather than a reflection.
+		for r := 0; r < 3; r++ {
+			u.Set(r, 2, -u.At(r, 2))
+		}
+		rotation.Mul(&u, v.T())
+	}
+	rm, err := spatialmath.NewRotationMatrix(rotation.RawMatrix().Data)
+	if err != nil {
+		return nil, err
+	}
+	return spatialmath.NewPose(targetCentroid.Sub(rm.Mul(sourceCentroid)), rm), nil
+}
+
+// pointToPlaneStep returns the small rigid transform that best moves the sources onto the planes through the targets
+// with the given normals, linearizing the rotation.
+func pointToPlaneStep(sources, targets, normals []r3.Vector) (spatialmath.Pose, error) {
+	ata := mat.NewSymDense(6, nil)
+	atb := mat.NewVecDense(6, nil)
+	for i := range sources {
+		n := normals[i]
+		if n.Norm2() == 0 {
+			continue
+		}
+		c := sources[i].Cross(n)
+		row := []float64{c.X, c.Y, c.Z, n.X, n.Y, n.Z}
+		b := targets[i].Sub(sources[i]).Dot(n)
+		for r := 0; r < 6; r++ {
+			for col := r; col < 6; col++ {
+				ata.SetSym(r, col, ata.At(r, col)+row[r]*row[col])
+			}
+			atb.SetVec(r, atb.AtVec(r)+row[r]*b)
+		}
+	}
+	var x mat.VecDense
+	if err := x.SolveVec(ata, atb); err != nil {
+		return nil, errors.Wrap(err, "the target is too flat to align the point clouds to")
+	}
+	rotation := r3.Vector{X: x.AtVec(0), Y: x.AtVec(1), Z: x.AtVec(2)}
+	translation := r3.Vector{X: x.AtVec(3), Y: x.AtVec(4), Z: x.AtVec(5)}
+	theta := rotation.Norm()
+	if theta < 1e-12 {
+		return spatialmath.NewPoseFromPoint(translation), nil
+	}
+	axis := rotation.Mul(1 / theta)
+	return spatialmath.NewPose(translation, &spatialmath.R4AA{Theta: theta, RX: axis.X, RY: axis.Y, RZ: axis.Z}), nil
+}
+
+// EstimateNormal returns the unit normal of the surface at a point, from the plane that best fits its k nearest
+// neighbors. It returns false if there are too few neighbors to fit a plane.
+func EstimateNormal(kd *KDTree, p r3.Vector, k int) (r3.Vector, bool) {
+	neighbors := kd.KNearestNeighbors(p, max(k, 3), true)
+	if len(neighbors) < 3 {
+		return r3.Vector{}, false
+	}
+	var centroid r3.Vector
+	for _, neighbor := range neighbors {
+		centroid = centroid.Add(neighbor.P)
+	}
+	centroid = centroid.Mul(1 / float64(len(neighbors)))
+	covariance := mat.NewSymDense(3, nil)
+	for _, neighbor := range neighbors {
+		d := neighbor.P.Sub(centroid)
+		v := []float64{d.X, d.Y, d.Z}
+		for r := 0; r < 3; r++ {
+			for c := r; c < 3; c++ {
+				covariance.SetSym(r, c, covariance.At(r, c)+v[r]*v[c])
+			}
+		}
+	}
+	var eigen mat.EigenSym
+	if !eigen.Factorize(covariance, true) {
+		return r3.Vector{}, false
+	}
+	var vectors mat.Dense
+	eigen.VectorsTo(&vectors)
+	// The eigenvalues are in ascending order, so the first vector is the direction of least variance.
+	return r3.Vector{X: vectors.At(0, 0), Y: vectors.At(1, 0), Z: vectors.At(2, 0)}.Normalize(), true
+}
+
+// EstimateNormals returns the normal of every point in the cloud that has enough neighbors, keyed by the point.
+func EstimateNormals(cloud PointCloud, k int) map[r3.Vector]r3.Vector {
+	kd := ToKDTree(cloud)
+	normals := make(map[r3.Vector]r3.Vector, kd.Size())
+	kd.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		if n, ok := EstimateNormal(kd, p, k); ok {
+			normals[p] = n
+		}
+		return true
+	})
+	return normals
+}
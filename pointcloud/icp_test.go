@@ -0,0 +1,129 @@
+package pointcloud
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+// makeCorner returns the inside corner of a box, three walls of different sizes that meet at the origin.
+func makeCorner(t *testing.T) PointCloud {
+	t.Helper()
+	pc := NewBasicEmpty()
+	for a := 0.; a <= 300; a += 10 {
+		for b := 0.; b <= 200; b += 10 {
+			test.That(t, pc.Set(r3.Vector{X: a, Y: b}, nil), test.ShouldBeNil)
+			if b <= 150 {
+				test.That(t, pc.Set(r3.Vector{X: a, Z: b + 10}, nil), test.ShouldBeNil)
+				if a <= 200 {
+					test.That(t, pc.Set(r3.Vector{Y: a + 10, Z: b + 10}, nil), test.ShouldBeNil)
+				}
+			}
+		}
+	}
+	return pc
+}
+
+func moveCloud(t *testing.T, pc PointCloud, pose spatialmath.Pose) PointCloud {
+	t.Helper()
+	moved := NewBasicEmpty()
+	test.That(t, ApplyOffset(pc, pose, moved), test.ShouldBeNil)
+	return moved
+}
+
+func TestRegisterICP(t *testing.T) {
+	target := makeCorner(t)
+	truth := spatialmath.NewPose(r3.Vector{X: 12, Y: -8, Z: 5}, &spatialmath.OrientationVectorDegrees{OX: 0.05, OY: 0.03, OZ: 1, Theta: 4})
+	source := moveCloud(t, target, spatialmath.PoseInverse(truth))
+
+	for _, method := range []ICPMethod{PointToPoint, PointToPlane} {
+		result, err := RegisterICP(source, target, nil, DefaultICPConfig(method, 50))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, result.Converged, test.ShouldBeTrue)
+		test.That(t, spatialmath.PoseAlmostEqualEps(result.Pose, truth, 0.1), test.ShouldBeTrue)
+		test.That(t, result.Fitness, test.ShouldAlmostEqual, 1)
+		test.That(t, result.RMSE, test.ShouldBeLessThan, 0.1)
+	}
+
+	// A good initial guess only needs to be refined.
+	result, err := RegisterICP(source, target, truth, DefaultICPConfig(PointToPlane, 5))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, result.Iterations, test.ShouldBeLessThanOrEqualTo, 2)
+	test.That(t, spatialmath.PoseAlmostEqualEps(result.Pose, truth, 0.1), test.ShouldBeTrue)
+
+	// Only part of the source overlaps the target.
+	partial := NewBasicEmpty()
+	target.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		if p.X <= 150 {
+			test.That(t, partial.Set(p, d), test.ShouldBeNil)
+		}
+		return true
+	})
+	result, err = RegisterICP(source, partial, nil, DefaultICPConfig(PointToPlane, 50))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, spatialmath.PoseAlmostEqualEps(result.Pose, truth, 0.5), test.ShouldBeTrue)
+	test.That(t, result.Fitness, test.ShouldBeBetween, 0.3, 0.8)
+
+	_, err = RegisterICP(NewBasicEmpty(), target, nil, DefaultICPConfig(PointToPoint, 50))
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = RegisterICP(source, target, nil, DefaultICPConfig(PointToPoint, 0))
+	test.That(t, err, test.ShouldNotBeNil)
+	far := moveCloud(t, source, spatialmath.NewPoseFromPoint(r3.Vector{X: 5000}))
+	_, err = RegisterICP(far, target, nil, DefaultICPConfig(PointToPoint, 50))
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestEstimateNormals(t *testing.T) {
+	pc := NewBasicEmpty()
+	for x := 0.; x < 100; x += 10 {
+		for y := 0.; y < 100; y += 10 {
+			test.That(t, pc.Set(r3.Vector{X: x, Y: y, Z: 0.5*x + 20}, nil), test.ShouldBeNil)
+		}
+	}
+	normals := EstimateNormals(pc, 8)
+	test.That(t, normals, test.ShouldHaveLength, 100)
+	expected := r3.Vector{X: -0.5, Z: 1}.Normalize()
+	for _, n := range normals {
+		test.That(t, math.Abs(n.Dot(expected)), test.ShouldAlmostEqual, 1, 1e-9)
+	}
+
+	_, ok := EstimateNormal(ToKDTree(NewBasicEmpty()), r3.Vector{}, 8)
+	test.That(t, ok, test.ShouldBeFalse)
+}
+
+func TestMergePointCloudsWithICP(t *testing.T) {
+	scene := makeCorner(t)
+	// The second camera is slightly off from where its offset says it is.
+	offset := spatialmath.NewPose(r3.Vector{X: 500, Z: 300}, &spatialmath.OrientationVectorDegrees{OX: -1, Theta: 90})
+	truth := spatialmath.Compose(
+		spatialmath.NewPose(r3.Vector{X: 6, Y: 4, Z: -5}, &spatialmath.OrientationVectorDegrees{OZ: 1, Theta: 2}), offset)
+	seen := moveCloud(t, scene, spatialmath.PoseInverse(truth))
+	clouds := []CloudAndOffsetFunc{
+		func(ctx context.Context) (PointCloud, spatialmath.Pose, error) {
+			return scene, nil, nil
+		},
+		func(ctx context.Context) (PointCloud, spatialmath.Pose, error) {
+			return seen, offset, nil
+		},
+	}
+
+	out := NewBasicEmpty()
+	results, err := MergePointCloudsWithICP(context.Background(), clouds, out, DefaultICPConfig(PointToPlane, 50))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, results, test.ShouldHaveLength, 2)
+	test.That(t, spatialmath.PoseAlmostEqual(results[0].Pose, spatialmath.NewZeroPose()), test.ShouldBeTrue)
+	test.That(t, spatialmath.PoseAlmostEqualEps(results[1].Pose, truth, 0.1), test.ShouldBeTrue)
+	test.That(t, results[1].Fitness, test.ShouldAlmostEqual, 1)
+	// Every point of the second cloud lands on a point of the first.
+	kd := ToKDTree(scene)
+	out.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		_, _, dist, _ := kd.NearestNeighbor(p)
+		test.That(t, dist, test.ShouldBeLessThan, 0.1)
+		return true
+	})
+}
@@ -66,3 +66,36 @@ func MergePointClouds(ctx context.Context, cloudFuncs []CloudAndOffsetFunc, out
 	}
 	return nil
 }
+
+// MergePointCloudsWithICP merges point clouds like MergePointClouds, but first refines the offset of every cloud
+// after the first by registering it against the clouds merged before it, starting from its offset. It returns the
+// registration of each cloud, whose poses are the refined offsets, so they can also be used to check the offsets.
+func MergePointCloudsWithICP(ctx context.Context, cloudFuncs []CloudAndOffsetFunc, out PointCloud, config ICPConfig) ([]*ICPResult, error) {
+	reference := NewBasicEmpty()
+	results := make([]*ICPResult, 0, len(cloudFuncs))
+	for i, f := range cloudFuncs {
+		in, offset, err := f(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if offset == nil {
+			offset = spatialmath.NewZeroPose()
+		}
+
+		result := &ICPResult{Pose: offset, Fitness: 1, Converged: true}
+		if i > 0 {
+			if result, err = RegisterICP(in, reference, offset, config); err != nil {
+				return nil, err
+			}
+		}
+		results = append(results, result)
+
+		if err := ApplyOffset(in, result.Pose, reference); err != nil {
+			return nil, err
+		}
+		if err := ApplyOffset(in, result.Pose, out); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,830 @@
+package pointcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/bits"
+	"sync"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	commonpb "go.viam.com/api/common/v1"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+// maxOccupancyDepth bounds the depth of an occupancy octree, which is 2^21 voxels along each side.
+const maxOccupancyDepth = 21
+
+// OccupancyParams describes the sensor model of an occupancy octree and the size of its voxels.
+type OccupancyParams struct {
+	// Resolution is the side length of the smallest voxel in mm.
+	Resolution float64
+	// ProbHit and ProbMiss are the probabilities that a voxel is occupied given that a ray ended in it or passed
+	// through it.
+	ProbHit  float64
+	ProbMiss float64
+	// ClampMin and ClampMax bound the probability of every voxel, so that it can change its mind quickly when the
+	// world changes.
+	ClampMin float64
+	ClampMax float64
+	// OccupiedThreshold is the probability above which a voxel is occupied.
+	OccupiedThreshold float64
+}
+
+// DefaultOccupancyParams returns the sensor model commonly used for depth cameras and lidars, with voxels of the
+// given size.
+func DefaultOccupancyParams(resolution float64) OccupancyParams {
+	return OccupancyParams{
+		Resolution:        resolution,
+		ProbHit:           0.7,
+		ProbMiss:          0.4,
+		ClampMin:          0.12,
+		ClampMax:          0.97,
+		OccupiedThreshold: 0.5,
+	}
+}
+
+// CheckValid checks that the probabilities are consistent with each other.
+func (params OccupancyParams) CheckValid() error {
+	if params.Resolution <= 0 {
+		return errors.New("the resolution of an occupancy octree must be positive")
+	}
+	if params.ProbHit <= 0.5 || params.ProbHit >= 1 {
+		return errors.Errorf("the probability of a hit must be between 0.5 and 1, got %v", params.ProbHit)
+	}
+	if params.ProbMiss <= 0 || params.ProbMiss >= 0.5 {
+		return errors.Errorf("the probability of a miss must be between 0 and 0.5, got %v", params.ProbMiss)
+	}
+	if params.ClampMin <= 0 || params.ClampMin > params.OccupiedThreshold ||
+		params.OccupiedThreshold > params.ClampMax || params.ClampMax >= 1 {
+		return errors.New("the occupancy probabilities must satisfy 0 < clamp min <= occupied threshold <= clamp max < 1")
+	}
+	return nil
+}
+
+// occupancyKey is the index of a voxel of the finest resolution along each axis.
+type occupancyKey [3]int
+
+// occupancyNode is a voxel whose occupancy is stored as log-odds. A node without children is a leaf, which is either
+// a voxel of the finest resolution or a pruned node whose voxels all have the same occupancy. Unknown space has no
+// node at all. The log-odds of an internal node are the maximum of those of its children.
+type occupancyNode struct {
+	logOdds  float64
+	children *[8]*occupancyNode
+}
+
+func (n *occupancyNode) copy() *occupancyNode {
+	if n == nil {
+		return nil
+	}
+	c := &occupancyNode{logOdds: n.logOdds}
+	if n.children != nil {
+		c.children = &[8]*occupancyNode{}
+		for i, child := range n.children {
+			c.children[i] = child.copy()
+		}
+	}
+	return c
+}
+
+// OccupancyOctree is a probabilistic occupancy map. Each voxel holds the log-odds that it is occupied, which rises
+// when a sensor sees a point in it and falls when a ray from the sensor passes through it, so obstacles that move away
+// are cleared. Voxels whose children all agree are pruned into one.
+//
+// It is a PointCloud of the centers of its occupied voxels at the finest resolution, with values that are their
+// probability of being occupied as a percentage, and a Geometry whose occupied voxels collide with other geometries,
+// so it can be added to a WorldState as an obstacle.
+type OccupancyOctree struct {
+	mu     sync.RWMutex
+	root   *occupancyNode
+	params OccupancyParams
+	// min is the corner of the octree with the smallest coordinates and depth is the number of levels below the root.
+	min   r3.Vector
+	depth int
+	label string
+
+	logOddsHit, logOddsMiss      float64
+	clampMin, clampMax, occupied float64
+}
+
+// NewOccupancyOctree returns an empty occupancy octree centered at the given point that is at least the given side
+// length in mm. The side length is rounded up to the resolution times a power of two.
+func NewOccupancyOctree(center r3.Vector, sideLength float64, params OccupancyParams) (*OccupancyOctree, error) {
+	if err := params.CheckValid(); err != nil {
+		return nil, err
+	}
+	if sideLength <= 0 {
+		return nil, errors.New("the side length of an occupancy octree must be positive")
+	}
+	octree := newOccupancyOctree(center, sideLength, params)
+	if octree.depth > maxOccupancyDepth {
+		return nil, errors.Errorf("an occupancy octree with side length %v can have a resolution of at least %v",
+			sideLength, sideLength/math.Exp2(maxOccupancyDepth))
+	}
+	return octree, nil
+}
+
+func newOccupancyOctree(center r3.Vector, sideLength float64, params OccupancyParams) *OccupancyOctree {
+	depth := max(0, int(math.Ceil(math.Log2(sideLength/params.Resolution)-floatEpsilon)))
+	half := params.Resolution * math.Exp2(float64(depth)) / 2
+	return &OccupancyOctree{
+		params:      params,
+		min:         center.Sub(r3.Vector{X: half, Y: half, Z: half}),
+		depth:       depth,
+		logOddsHit:  logOdds(params.ProbHit),
+		logOddsMiss: logOdds(params.ProbMiss),
+		clampMin:    logOdds(params.ClampMin),
+		clampMax:    logOdds(params.ClampMax),
+		occupied:    logOdds(params.OccupiedThreshold),
+	}
+}
+
+func logOdds(p float64) float64 {
+	return math.Log(p / (1 - p))
+}
+
+func probability(logOdds float64) float64 {
+	return 1 - 1/(1+math.Exp(logOdds))
+}
+
+// Params returns the sensor model and resolution of the octree.
+func (octree *OccupancyOctree) Params() OccupancyParams {
+	return octree.params
+}
+
+// SideLength returns the side length of the octree in mm.
+func (octree *OccupancyOctree) SideLength() float64 {
+	return octree.params.Resolution * math.Exp2(float64(octree.depth))
+}
+
+func (octree *OccupancyOctree) center() r3.Vector {
+	half := octree.SideLength() / 2
+	return octree.min.Add(r3.Vector{X: half, Y: half, Z: half})
+}
+
+// key returns the voxel that contains a point, which is only in the octree if the boolean is true.
+func (octree *OccupancyOctree) key(p r3.Vector) (occupancyKey, bool) {
+	offset := p.Sub(octree.min).Mul(1 / octree.params.Resolution)
+	k := occupancyKey{int(math.Floor(offset.X)), int(math.Floor(offset.Y)), int(math.Floor(offset.Z))}
+	return k, octree.contains(k)
+}
+
+func (octree *OccupancyOctree) contains(k occupancyKey) bool {
+	n := 1 << octree.depth
+	return k[0] >= 0 && k[1] >= 0 && k[2] >= 0 && k[0] < n && k[1] < n && k[2] < n
+}
+
+// voxelCenter returns the center of a cube of voxels that starts at the given key and is span voxels wide.
+func (octree *OccupancyOctree) voxelCenter(k occupancyKey, span int) r3.Vector {
+	res := octree.params.Resolution
+	half := float64(span) / 2
+	return octree.min.Add(r3.Vector{
+		X: (float64(k[0]) + half) * res,
+		Y: (float64(k[1]) + half) * res,
+		Z: (float64(k[2]) + half) * res,
+	})
+}
+
+// childIndex returns which child of a node at the given depth holds the key, in the same order as BasicOctree.
+func (octree *OccupancyOctree) childIndex(k occupancyKey, depth int) int {
+	bit := octree.depth - depth - 1
+	return (k[0]>>bit&1)<<2 | (k[1]>>bit&1)<<1 | k[2]>>bit&1
+}
+
+// InsertPointCloud integrates a scan from a sensor at the given pose, such as a cloud from camera.NextPointCloud in
+// the frame of the camera. The voxel of every point is marked as hit, and the voxels between the sensor and each
+// point are marked as missed. Points further than maxRange from the sensor only clear space up to maxRange, unless
+// maxRange is not positive. Points outside the octree are ignored.
+func (octree *OccupancyOctree) InsertPointCloud(cloud PointCloud, sensorPose spatialmath.Pose, maxRange float64) {
+	origin := sensorPose.Point()
+	rotation := sensorPose.Orientation().RotationMatrix()
+	hits := map[occupancyKey]struct{}{}
+	misses := map[occupancyKey]struct{}{}
+	cloud.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		end := rotation.Mul(p).Add(origin)
+		direction := end.Sub(origin)
+		hit := true
+		if distance := direction.Norm(); maxRange > 0 && distance > maxRange {
+			end = origin.Add(direction.Mul(maxRange / distance))
+			hit = false
+		}
+		octree.castRay(origin, end, func(k occupancyKey) {
+			misses[k] = struct{}{}
+		})
+		if k, ok := octree.key(end); ok && hit {
+			hits[k] = struct{}{}
+		}
+		return true
+	})
+
+	octree.mu.Lock()
+	defer octree.mu.Unlock()
+	for k := range misses {
+		if _, ok := hits[k]; !ok {
+			octree.update(k, octree.logOddsMiss)
+		}
+	}
+	for k := range hits {
+		octree.update(k, octree.logOddsHit)
+	}
+}
+
+// castRay calls visit with every voxel in the octree that the segment from origin to end passes through, except the
+// voxel that contains end, using the traversal of Amanatides and Woo.
+func (octree *OccupancyOctree) castRay(origin, end r3.Vector, visit func(occupancyKey)) {
+	current, _ := octree.key(origin)
+	last, _ := octree.key(end)
+	if current == last {
+		return
+	}
+	res := octree.params.Resolution
+	direction := end.Sub(origin)
+	start := [3]float64{origin.X, origin.Y, origin.Z}
+	dir := [3]float64{direction.X, direction.Y, direction.Z}
+	lower := [3]float64{octree.min.X, octree.min.Y, octree.min.Z}
+	var step [3]int
+	var tMax, tDelta [3]float64
+	steps := 0
+	for axis := 0; axis < 3; axis++ {
+		switch {
+		case dir[axis] > 0:
+			step[axis] = 1
+			boundary := lower[axis] + float64(current[axis]+1)*res
+			tMax[axis] = (boundary - start[axis]) / dir[axis]
+			tDelta[axis] = res / dir[axis]
+		case dir[axis] < 0:
+			step[axis] = -1
+			boundary := lower[axis] + float64(current[axis])*res
+			tMax[axis] = (boundary - start[axis]) / dir[axis]
+			tDelta[axis] = -res / dir[axis]
+		default:
+			tMax[axis] = math.Inf(1)
+			tDelta[axis] = math.Inf(1)
+		}
+		steps += absInt(last[axis] - current[axis])
+	}
+	for i := 0; i < steps && current != last; i++ {
+		if octree.contains(current) {
+			visit(current)
+		}
+		axis := 0
+		if tMax[1] < tMax[axis] {
+			axis = 1
+		}
+		if tMax[2] < tMax[axis] {
+			axis = 2
+		}
+		if tMax[axis] > 1 {
+			break
+		}
+		current[axis] += step[axis]
+		tMax[axis] += tDelta[axis]
+	}
+}
+
+func absInt(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+// UpdateVoxel marks the voxel that contains the point as hit if occupied is true, or as missed otherwise. It returns
+// false if the point is outside the octree.
+func (octree *OccupancyOctree) UpdateVoxel(p r3.Vector, occupied bool) bool {
+	k, ok := octree.key(p)
+	if !ok {
+		return false
+	}
+	octree.mu.Lock()
+	defer octree.mu.Unlock()
+	if occupied {
+		octree.update(k, octree.logOddsHit)
+	} else {
+		octree.update(k, octree.logOddsMiss)
+	}
+	return true
+}
+
+// Occupancy returns the probability that the voxel containing the point is occupied, and false if nothing is known
+// about it.
+func (octree *OccupancyOctree) Occupancy(p r3.Vector) (float64, bool) {
+	k, ok := octree.key(p)
+	if !ok {
+		return 0, false
+	}
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	n := octree.root
+	for depth := 0; n != nil && n.children != nil; depth++ {
+		n = n.children[octree.childIndex(k, depth)]
+	}
+	if n == nil {
+		return 0, false
+	}
+	return probability(n.logOdds), true
+}
+
+// IsOccupied returns whether the voxel containing the point is known to be occupied.
+func (octree *OccupancyOctree) IsOccupied(p r3.Vector) bool {
+	prob, known := octree.Occupancy(p)
+	return known && prob >= octree.params.OccupiedThreshold
+}
+
+// update adds to the log-odds of a voxel, clamping them, and prunes the nodes above it.
+func (octree *OccupancyOctree) update(k occupancyKey, delta float64) {
+	octree.root = octree.updateNode(octree.root, k, 0, octree.depth, func(current float64) float64 {
+		return math.Max(octree.clampMin, math.Min(octree.clampMax, current+delta))
+	})
+}
+
+// setLogOdds sets the log-odds of a voxel.
+func (octree *OccupancyOctree) setLogOdds(k occupancyKey, value float64) {
+	octree.setLeaf(k, octree.depth, value)
+}
+
+// setLeaf replaces the node at leafDepth that holds the key, and everything below it, with a leaf of the given
+// log-odds.
+func (octree *OccupancyOctree) setLeaf(k occupancyKey, leafDepth int, value float64) {
+	octree.root = octree.updateNode(octree.root, k, 0, leafDepth, func(float64) float64 { return value })
+}
+
+// updateNode applies fn to the log-odds of the node at leafDepth that holds the key, which becomes a leaf, and prunes
+// the nodes above it.
+func (octree *OccupancyOctree) updateNode(
+	n *occupancyNode,
+	k occupancyKey,
+	depth, leafDepth int,
+	fn func(float64) float64,
+) *occupancyNode {
+	created := n == nil
+	if created {
+		n = &occupancyNode{}
+	}
+	if depth == leafDepth {
+		n.logOdds = fn(n.logOdds)
+		n.children = nil
+		return n
+	}
+	if n.children == nil {
+		if !created {
+			// A pruned node that the update would not change stays pruned.
+			if fn(n.logOdds) == n.logOdds {
+				return n
+			}
+			n.children = &[8]*occupancyNode{}
+			for i := range n.children {
+				n.children[i] = &occupancyNode{logOdds: n.logOdds}
+			}
+		} else {
+			n.children = &[8]*occupancyNode{}
+		}
+	}
+	i := octree.childIndex(k, depth)
+	n.children[i] = octree.updateNode(n.children[i], k, depth+1, leafDepth, fn)
+
+	prunable := true
+	n.logOdds = math.Inf(-1)
+	for _, child := range n.children {
+		if child == nil {
+			prunable = false
+			continue
+		}
+		if child.children != nil || child.logOdds != n.children[i].logOdds {
+			prunable = false
+		}
+		n.logOdds = math.Max(n.logOdds, child.logOdds)
+	}
+	if prunable {
+		n.children = nil
+	}
+	return n
+}
+
+// walkLeaves calls fn with every leaf under a node, the key of its first voxel and how many voxels wide it is, until
+// fn returns false.
+func (octree *OccupancyOctree) walkLeaves(
+	n *occupancyNode,
+	depth int,
+	base occupancyKey,
+	fn func(n *occupancyNode, base occupancyKey, span int) bool,
+) bool {
+	if n == nil {
+		return true
+	}
+	span := 1 << (octree.depth - depth)
+	if n.children == nil {
+		return fn(n, base, span)
+	}
+	half := span / 2
+	for i, child := range n.children {
+		childBase := occupancyKey{base[0] + (i>>2&1)*half, base[1] + (i>>1&1)*half, base[2] + (i&1)*half}
+		if !octree.walkLeaves(child, depth+1, childBase, fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// iterateOccupied calls fn with the center and log-odds of every occupied voxel of the finest resolution, until fn
+// returns false. The caller must hold the lock.
+func (octree *OccupancyOctree) iterateOccupied(fn func(p r3.Vector, logOdds float64) bool) {
+	octree.walkLeaves(octree.root, 0, occupancyKey{}, func(n *occupancyNode, base occupancyKey, span int) bool {
+		if n.logOdds < octree.occupied {
+			return true
+		}
+		for x := 0; x < span; x++ {
+			for y := 0; y < span; y++ {
+				for z := 0; z < span; z++ {
+					if !fn(octree.voxelCenter(occupancyKey{base[0] + x, base[1] + y, base[2] + z}, 1), n.logOdds) {
+						return false
+					}
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (octree *OccupancyOctree) occupancyData(logOdds float64) Data {
+	return NewValueData(int(math.Round(100 * probability(logOdds))))
+}
+
+// numNodes returns the number of nodes in the tree.
+func (octree *OccupancyOctree) numNodes() int {
+	var count func(n *occupancyNode) int
+	count = func(n *occupancyNode) int {
+		if n == nil {
+			return 0
+		}
+		total := 1
+		if n.children != nil {
+			for _, child := range n.children {
+				total += count(child)
+			}
+		}
+		return total
+	}
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	return count(octree.root)
+}
+
+// Size returns the number of occupied voxels of the finest resolution.
+func (octree *OccupancyOctree) Size() int {
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	return octree.size()
+}
+
+func (octree *OccupancyOctree) size() int {
+	size := 0
+	octree.walkLeaves(octree.root, 0, occupancyKey{}, func(n *occupancyNode, base occupancyKey, span int) bool {
+		if n.logOdds >= octree.occupied {
+			size += span * span * span
+		}
+		return true
+	})
+	return size
+}
+
+// MetaData returns the metadata of the centers of the occupied voxels.
+func (octree *OccupancyOctree) MetaData() MetaData {
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	meta := NewMetaData()
+	octree.iterateOccupied(func(p r3.Vector, logOdds float64) bool {
+		meta.Merge(p, octree.occupancyData(logOdds))
+		return true
+	})
+	return meta
+}
+
+// Set marks the voxel containing the point as hit. The data is not stored.
+func (octree *OccupancyOctree) Set(p r3.Vector, d Data) error {
+	if !octree.UpdateVoxel(p, true) {
+		return errors.New("error point is outside the bounds of this octree")
+	}
+	return nil
+}
+
+// At returns the occupancy of the voxel containing the point as a percentage, if that voxel is occupied.
+func (octree *OccupancyOctree) At(x, y, z float64) (Data, bool) {
+	prob, known := octree.Occupancy(r3.Vector{X: x, Y: y, Z: z})
+	if !known || prob < octree.params.OccupiedThreshold {
+		return nil, false
+	}
+	return NewValueData(int(math.Round(100 * prob))), true
+}
+
+// Iterate calls fn with the center of every occupied voxel and its occupancy as a percentage, divided into batches
+// the same way as the other point clouds.
+func (octree *OccupancyOctree) Iterate(numBatches, currentBatch int, fn func(p r3.Vector, d Data) bool) {
+	if numBatches < 0 || currentBatch < 0 || (numBatches > 0 && currentBatch >= numBatches) {
+		return
+	}
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+
+	lowerBound, upperBound := 0, math.MaxInt
+	if numBatches > 0 {
+		size := octree.size()
+		batchSize := (size + numBatches - 1) / numBatches
+		lowerBound = currentBatch * batchSize
+		upperBound = (currentBatch + 1) * batchSize
+	}
+	idx := -1
+	octree.iterateOccupied(func(p r3.Vector, logOdds float64) bool {
+		idx++
+		if idx < lowerBound {
+			return true
+		}
+		if idx >= upperBound {
+			return false
+		}
+		return fn(p, octree.occupancyData(logOdds))
+	})
+}
+
+// FinalizeAfterReading returns the octree, which needs no finalizing.
+func (octree *OccupancyOctree) FinalizeAfterReading() (PointCloud, error) {
+	return octree, nil
+}
+
+// CreateNewRecentered returns an empty occupancy octree with the same parameters, moved by the offset.
+func (octree *OccupancyOctree) CreateNewRecentered(offset spatialmath.Pose) PointCloud {
+	return newOccupancyOctree(offset.Point().Add(octree.center()), octree.SideLength(), octree.params)
+}
+
+// Pose returns the pose of the center of the octree.
+func (octree *OccupancyOctree) Pose() spatialmath.Pose {
+	return spatialmath.NewPoseFromPoint(octree.center())
+}
+
+// Transform returns a copy of the octree moved by the pose. A translated octree keeps every voxel, free or occupied.
+// Since voxels are aligned with the axes, a rotated octree is instead resampled from the centers of its occupied
+// voxels only: its free space becomes unknown, so Occupancy reports nothing about voxels that were free.
+func (octree *OccupancyOctree) Transform(pose spatialmath.Pose) spatialmath.Geometry {
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	if spatialmath.OrientationAlmostEqual(pose.Orientation(), spatialmath.NewZeroOrientation()) {
+		moved := newOccupancyOctree(octree.center().Add(pose.Point()), octree.SideLength(), octree.params)
+		moved.root = octree.root.copy()
+		moved.label = octree.label
+		return moved
+	}
+	center := spatialmath.Compose(pose, spatialmath.NewPoseFromPoint(octree.center())).Point()
+	rotated := newOccupancyOctree(center, octree.SideLength()*math.Sqrt(3), octree.params)
+	rotated.label = octree.label
+	octree.iterateOccupied(func(p r3.Vector, logOdds float64) bool {
+		if k, ok := rotated.key(spatialmath.Compose(pose, spatialmath.NewPoseFromPoint(p)).Point()); ok {
+			rotated.setLogOdds(k, logOdds)
+		}
+		return true
+	})
+	return rotated
+}
+
+// voxelBox returns a box around a cube of voxels, grown by the buffer.
+func (octree *OccupancyOctree) voxelBox(base occupancyKey, span int, buffer float64) (spatialmath.Geometry, error) {
+	side := float64(span)*octree.params.Resolution + buffer
+	return spatialmath.NewBox(
+		spatialmath.NewPoseFromPoint(octree.voxelCenter(base, span)),
+		r3.Vector{X: side, Y: side, Z: side},
+		"",
+	)
+}
+
+// CollidesWith checks whether the geometry is within collisionBufferMM of an occupied voxel. If there's no collision,
+// it returns a lower bound of the distance between the octree and the geometry.
+func (octree *OccupancyOctree) CollidesWith(geom spatialmath.Geometry, collisionBufferMM float64) (bool, float64, error) {
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	if octree.root == nil || octree.root.logOdds < octree.occupied {
+		return false, collisionBufferMM, nil
+	}
+	return octree.collidesWith(octree.root, 0, occupancyKey{}, geom, collisionBufferMM)
+}
+
+func (octree *OccupancyOctree) collidesWith(
+	n *occupancyNode,
+	depth int,
+	base occupancyKey,
+	geom spatialmath.Geometry,
+	collisionBufferMM float64,
+) (bool, float64, error) {
+	if n == nil || n.logOdds < octree.occupied {
+		return false, math.Inf(1), nil
+	}
+	span := 1 << (octree.depth - depth)
+	if n.children == nil {
+		// Every voxel of a leaf is occupied.
+		box, err := octree.voxelBox(base, span, 0)
+		if err != nil {
+			return false, collisionBufferMM, err
+		}
+		return geom.CollidesWith(box, collisionBufferMM)
+	}
+
+	// Skip the children if the geometry doesn't reach the region of the node.
+	box, err := octree.voxelBox(base, span, collisionBufferMM)
+	if err != nil {
+		return false, collisionBufferMM, err
+	}
+	collide, dist, err := geom.CollidesWith(box, collisionBufferMM)
+	if err != nil {
+		return false, collisionBufferMM, err
+	}
+	if !collide {
+		return false, dist, nil
+	}
+	minDist := math.Inf(1)
+	half := span / 2
+	for i, child := range n.children {
+		childBase := occupancyKey{base[0] + (i>>2&1)*half, base[1] + (i>>1&1)*half, base[2] + (i&1)*half}
+		collide, dist, err := octree.collidesWith(child, depth+1, childBase, geom, collisionBufferMM)
+		if err != nil {
+			return false, collisionBufferMM, err
+		}
+		if collide {
+			return true, -1, nil
+		}
+		minDist = min(minDist, dist)
+	}
+	return false, minDist, nil
+}
+
+// DistanceFrom returns the distance from the occupied voxels of the octree to the given geometry.
+func (octree *OccupancyOctree) DistanceFrom(geom spatialmath.Geometry) (float64, error) {
+	collides, dist, err := octree.CollidesWith(geom, floatEpsilon)
+	if err != nil {
+		return math.Inf(1), err
+	}
+	if collides {
+		return -1, nil
+	}
+	return dist, nil
+}
+
+// EncompassedBy returns true if every occupied voxel of the octree is within the given geometry.
+func (octree *OccupancyOctree) EncompassedBy(geom spatialmath.Geometry) (bool, error) {
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	encompassed := true
+	var err error
+	octree.walkLeaves(octree.root, 0, occupancyKey{}, func(n *occupancyNode, base occupancyKey, span int) bool {
+		if n.logOdds < octree.occupied {
+			return true
+		}
+		var box spatialmath.Geometry
+		if box, err = octree.voxelBox(base, span, 0); err != nil {
+			return false
+		}
+		encompassed, err = box.EncompassedBy(geom)
+		return err == nil && encompassed
+	})
+	return encompassed, err
+}
+
+// SetLabel sets the label of this octree.
+func (octree *OccupancyOctree) SetLabel(label string) {
+	octree.label = label
+}
+
+// Label returns the label of this octree.
+func (octree *OccupancyOctree) Label() string {
+	return octree.label
+}
+
+// ToPoints returns the centers of the occupied voxels.
+func (octree *OccupancyOctree) ToPoints(resolution float64) []r3.Vector {
+	points := []r3.Vector{}
+	octree.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		points = append(points, p)
+		return true
+	})
+	return points
+}
+
+// ToProtobuf converts the occupied voxels of the octree to a point cloud Geometry proto message.
+func (octree *OccupancyOctree) ToProtobuf() *commonpb.Geometry {
+	bytes, err := ToBytes(octree)
+	if err != nil {
+		return nil
+	}
+	return &commonpb.Geometry{
+		Center: spatialmath.PoseToProtobuf(octree.Pose()),
+		GeometryType: &commonpb.Geometry_Pointcloud{
+			Pointcloud: &commonpb.PointCloud{
+				PointCloud: bytes,
+			},
+		},
+		Label: octree.Label(),
+	}
+}
+
+// Hash returns a hash value for this octree.
+func (octree *OccupancyOctree) Hash() int {
+	center := octree.center()
+	hash := 0
+	hash += (5 * (int(center.X*10) + 1000)) * 2
+	hash += (6 * (int(center.Y*10) + 2000)) * 3
+	hash += (7 * (int(center.Z*10) + 3000)) * 4
+	hash += (8 * (int(octree.SideLength()*10) + 4000)) * 5
+	hash += (9 * octree.Size()) * 6
+	hash += (10 * int(octree.params.Resolution*10)) * 7
+	hash += hashString(octree.label) * 11
+	return hash
+}
+
+// String returns a human readable string that represents this octree.
+func (octree *OccupancyOctree) String() string {
+	return fmt.Sprintf("occupancy octree. center: %v, side length: %v, resolution: %v, occupied voxels: %v",
+		octree.center(), octree.SideLength(), octree.params.Resolution, octree.Size())
+}
+
+// occupancyOctreeJSON is the JSON form of an occupancy octree, which holds every known voxel, free or occupied.
+type occupancyOctreeJSON struct {
+	Min    r3.Vector           `json:"min"`
+	Depth  int                 `json:"depth"`
+	Label  string              `json:"label,omitempty"`
+	Params occupancyParamsJSON `json:"params"`
+	Leaves []occupancyLeafJSON `json:"leaves"`
+}
+
+type occupancyParamsJSON struct {
+	Resolution        float64 `json:"resolution"`
+	ProbHit           float64 `json:"prob_hit"`
+	ProbMiss          float64 `json:"prob_miss"`
+	ClampMin          float64 `json:"clamp_min"`
+	ClampMax          float64 `json:"clamp_max"`
+	OccupiedThreshold float64 `json:"occupied_threshold"`
+}
+
+// occupancyLeafJSON is a leaf that starts at the voxel Key and is Span voxels wide.
+type occupancyLeafJSON struct {
+	Key     occupancyKey `json:"key"`
+	Span    int          `json:"span"`
+	LogOdds float64      `json:"log_odds"`
+}
+
+// MarshalJSON marshals JSON from the octree.
+func (octree *OccupancyOctree) MarshalJSON() ([]byte, error) {
+	octree.mu.RLock()
+	defer octree.mu.RUnlock()
+	out := occupancyOctreeJSON{
+		Min:    octree.min,
+		Depth:  octree.depth,
+		Label:  octree.label,
+		Params: occupancyParamsJSON(octree.params),
+		Leaves: []occupancyLeafJSON{},
+	}
+	octree.walkLeaves(octree.root, 0, occupancyKey{}, func(n *occupancyNode, base occupancyKey, span int) bool {
+		out.Leaves = append(out.Leaves, occupancyLeafJSON{Key: base, Span: span, LogOdds: n.logOdds})
+		return true
+	})
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON unmarshals an octree from the JSON written by MarshalJSON.
+func (octree *OccupancyOctree) UnmarshalJSON(data []byte) error {
+	var in occupancyOctreeJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	params := OccupancyParams(in.Params)
+	if err := params.CheckValid(); err != nil {
+		return err
+	}
+	if in.Depth < 0 || in.Depth > maxOccupancyDepth {
+		return errors.Errorf("occupancy octree depth must be between 0 and %d, got %d", maxOccupancyDepth, in.Depth)
+	}
+	sideLength := params.Resolution * math.Exp2(float64(in.Depth))
+	unmarshaled := newOccupancyOctree(in.Min.Add(r3.Vector{X: sideLength / 2, Y: sideLength / 2, Z: sideLength / 2}),
+		sideLength, params)
+	unmarshaled.min = in.Min
+	unmarshaled.depth = in.Depth
+	unmarshaled.label = in.Label
+	for _, leaf := range in.Leaves {
+		// A leaf is a power of two voxels wide, and starts at a multiple of its width.
+		if leaf.Span <= 0 || leaf.Span > 1<<in.Depth || bits.OnesCount(uint(leaf.Span)) != 1 ||
+			!unmarshaled.contains(leaf.Key) ||
+			leaf.Key[0]%leaf.Span != 0 || leaf.Key[1]%leaf.Span != 0 || leaf.Key[2]%leaf.Span != 0 {
+			return errors.Errorf("invalid occupancy octree leaf at %v, %d voxels wide", leaf.Key, leaf.Span)
+		}
+		unmarshaled.setLeaf(leaf.Key, in.Depth-bits.TrailingZeros(uint(leaf.Span)), leaf.LogOdds)
+	}
+
+	octree.mu.Lock()
+	defer octree.mu.Unlock()
+	octree.root = unmarshaled.root
+	octree.params = unmarshaled.params
+	octree.min = unmarshaled.min
+	octree.depth = unmarshaled.depth
+	octree.label = unmarshaled.label
+	octree.logOddsHit, octree.logOddsMiss = unmarshaled.logOddsHit, unmarshaled.logOddsMiss
+	octree.clampMin, octree.clampMax, octree.occupied = unmarshaled.clampMin, unmarshaled.clampMax, unmarshaled.occupied
+	return nil
+}
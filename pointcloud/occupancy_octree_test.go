@@ -0,0 +1,309 @@
+package pointcloud
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+var (
+	_ PointCloud           = &OccupancyOctree{}
+	_ spatialmath.Geometry = &OccupancyOctree{}
+)
+
+func TestOccupancyOctreeNew(t *testing.T) {
+	octree, err := NewOccupancyOctree(r3.Vector{}, 1000, DefaultOccupancyParams(10))
+	test.That(t, err, test.ShouldBeNil)
+	// The side length is rounded up to a power of two voxels.
+	test.That(t, octree.SideLength(), test.ShouldAlmostEqual, 1280)
+	test.That(t, octree.Size(), test.ShouldEqual, 0)
+	test.That(t, octree.Pose().Point(), test.ShouldResemble, r3.Vector{})
+
+	_, err = NewOccupancyOctree(r3.Vector{}, 0, DefaultOccupancyParams(10))
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewOccupancyOctree(r3.Vector{}, 1e9, DefaultOccupancyParams(1e-3))
+	test.That(t, err, test.ShouldNotBeNil)
+	params := DefaultOccupancyParams(10)
+	params.ProbHit = 0.4
+	_, err = NewOccupancyOctree(r3.Vector{}, 1000, params)
+	test.That(t, err, test.ShouldNotBeNil)
+	params = DefaultOccupancyParams(10)
+	params.ClampMax = 0.3
+	_, err = NewOccupancyOctree(r3.Vector{}, 1000, params)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestOccupancyOctreeUpdates(t *testing.T) {
+	params := DefaultOccupancyParams(10)
+	octree, err := NewOccupancyOctree(r3.Vector{}, 1000, params)
+	test.That(t, err, test.ShouldBeNil)
+	p := r3.Vector{X: 101, Y: -42, Z: 7}
+
+	_, known := octree.Occupancy(p)
+	test.That(t, known, test.ShouldBeFalse)
+	test.That(t, octree.UpdateVoxel(p, true), test.ShouldBeTrue)
+	prob, known := octree.Occupancy(p)
+	test.That(t, known, test.ShouldBeTrue)
+	test.That(t, prob, test.ShouldAlmostEqual, params.ProbHit)
+	test.That(t, octree.IsOccupied(p), test.ShouldBeTrue)
+	// Everything in the same voxel shares its occupancy.
+	test.That(t, octree.IsOccupied(r3.Vector{X: 109, Y: -49, Z: 1}), test.ShouldBeTrue)
+	test.That(t, octree.IsOccupied(r3.Vector{X: 111, Y: -42, Z: 7}), test.ShouldBeFalse)
+
+	// Many hits are clamped, so a few misses clear the voxel.
+	for i := 0; i < 100; i++ {
+		octree.UpdateVoxel(p, true)
+	}
+	prob, _ = octree.Occupancy(p)
+	test.That(t, prob, test.ShouldAlmostEqual, params.ClampMax)
+	misses := 0
+	for octree.IsOccupied(p) {
+		octree.UpdateVoxel(p, false)
+		misses++
+	}
+	test.That(t, misses, test.ShouldEqual, 9)
+	for i := 0; i < 100; i++ {
+		octree.UpdateVoxel(p, false)
+	}
+	prob, known = octree.Occupancy(p)
+	test.That(t, known, test.ShouldBeTrue)
+	test.That(t, prob, test.ShouldAlmostEqual, params.ClampMin)
+
+	test.That(t, octree.UpdateVoxel(r3.Vector{X: 1000}, true), test.ShouldBeFalse)
+	test.That(t, octree.Set(r3.Vector{X: 1000}, nil), test.ShouldNotBeNil)
+}
+
+func TestOccupancyOctreePruning(t *testing.T) {
+	octree, err := NewOccupancyOctree(r3.Vector{}, 80, DefaultOccupancyParams(10))
+	test.That(t, err, test.ShouldBeNil)
+	// Fill the octant of the octree with positive coordinates until every voxel is clamped.
+	for i := 0; i < 10; i++ {
+		for x := 5.; x < 40; x += 10 {
+			for y := 5.; y < 40; y += 10 {
+				for z := 5.; z < 40; z += 10 {
+					octree.UpdateVoxel(r3.Vector{X: x, Y: y, Z: z}, true)
+				}
+			}
+		}
+	}
+	test.That(t, octree.Size(), test.ShouldEqual, 64)
+	// The octant is a single leaf under the root.
+	test.That(t, octree.numNodes(), test.ShouldEqual, 2)
+
+	// Changing one voxel expands the octant again.
+	octree.UpdateVoxel(r3.Vector{X: 5, Y: 5, Z: 5}, false)
+	test.That(t, octree.numNodes(), test.ShouldEqual, 2+8+8)
+	test.That(t, octree.Size(), test.ShouldEqual, 64)
+
+	count := 0
+	octree.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		test.That(t, p.X > 0 && p.Y > 0 && p.Z > 0, test.ShouldBeTrue)
+		test.That(t, d.Value(), test.ShouldBeGreaterThanOrEqualTo, 50)
+		count++
+		return true
+	})
+	test.That(t, count, test.ShouldEqual, 64)
+	batched := 0
+	for batch := 0; batch < 3; batch++ {
+		octree.Iterate(3, batch, func(p r3.Vector, d Data) bool {
+			batched++
+			return true
+		})
+	}
+	test.That(t, batched, test.ShouldEqual, 64)
+	meta := octree.MetaData()
+	test.That(t, meta.HasValue, test.ShouldBeTrue)
+	test.That(t, meta.MinX, test.ShouldAlmostEqual, 5)
+	test.That(t, meta.MaxZ, test.ShouldAlmostEqual, 35)
+}
+
+func TestOccupancyOctreeInsertPointCloud(t *testing.T) {
+	octree, err := NewOccupancyOctree(r3.Vector{}, 2000, DefaultOccupancyParams(20))
+	test.That(t, err, test.ShouldBeNil)
+	// The sensor looks along its z axis, which is the x axis of the world, and every point is at the center of a voxel.
+	sensor := spatialmath.NewPose(r3.Vector{X: -500, Y: 10, Z: 10}, &spatialmath.OrientationVectorDegrees{OX: 1})
+
+	wall := NewBasicPointCloud(0)
+	far := NewBasicPointCloud(0)
+	for y := -100.; y <= 100; y += 20 {
+		test.That(t, wall.Set(r3.Vector{X: 0, Y: y, Z: 510}, nil), test.ShouldBeNil)
+		test.That(t, far.Set(r3.Vector{X: 0, Y: y, Z: 1010}, nil), test.ShouldBeNil)
+	}
+	for i := 0; i < 3; i++ {
+		octree.InsertPointCloud(wall, sensor, 0)
+	}
+	for _, y := range []float64{-90, 10, 110} {
+		test.That(t, octree.IsOccupied(r3.Vector{X: 10, Y: y, Z: 10}), test.ShouldBeTrue)
+	}
+	prob, known := octree.Occupancy(r3.Vector{X: -250, Y: 10, Z: 10})
+	test.That(t, known, test.ShouldBeTrue)
+	test.That(t, prob, test.ShouldBeLessThan, 0.5)
+	_, known = octree.Occupancy(r3.Vector{X: 250, Y: 10, Z: 10})
+	test.That(t, known, test.ShouldBeFalse)
+
+	// Collides with a box touching the wall, but not one between the sensor and the wall.
+	box, err := spatialmath.NewBox(spatialmath.NewPoseFromPoint(r3.Vector{X: 25, Y: 10, Z: 10}), r3.Vector{X: 20, Y: 20, Z: 20}, "")
+	test.That(t, err, test.ShouldBeNil)
+	collides, _, err := octree.CollidesWith(box, 0)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, collides, test.ShouldBeTrue)
+	box, err = spatialmath.NewBox(spatialmath.NewPoseFromPoint(r3.Vector{X: -200, Y: 10, Z: 10}), r3.Vector{X: 20, Y: 20, Z: 20}, "")
+	test.That(t, err, test.ShouldBeNil)
+	collides, _, err = octree.CollidesWith(box, 0)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, collides, test.ShouldBeFalse)
+	dist, err := octree.DistanceFrom(box)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, dist, test.ShouldBeGreaterThan, 0)
+
+	// The wall moves away, so the rays through it clear it.
+	for i := 0; i < 10; i++ {
+		octree.InsertPointCloud(far, sensor, 0)
+	}
+	test.That(t, octree.IsOccupied(r3.Vector{X: 10, Y: 10, Z: 10}), test.ShouldBeFalse)
+	test.That(t, octree.IsOccupied(r3.Vector{X: 510, Y: 10, Z: 10}), test.ShouldBeTrue)
+	collides, _, err = octree.CollidesWith(spatialmath.NewPoint(r3.Vector{X: 10, Y: 10, Z: 10}, ""), 0)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, collides, test.ShouldBeFalse)
+
+	// Points beyond the maximum range only clear space.
+	octree.InsertPointCloud(far, sensor, 600)
+	prob, _ = octree.Occupancy(r3.Vector{X: 510, Y: 10, Z: 10})
+	test.That(t, prob, test.ShouldAlmostEqual, octree.Params().ClampMax)
+	prob, _ = octree.Occupancy(r3.Vector{X: 90, Y: 10, Z: 10})
+	test.That(t, prob, test.ShouldBeLessThan, 0.5)
+}
+
+func TestOccupancyOctreeGeometry(t *testing.T) {
+	octree, err := NewOccupancyOctree(r3.Vector{}, 100, DefaultOccupancyParams(10))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, octree.Set(r3.Vector{X: 15, Y: 15, Z: 15}, nil), test.ShouldBeNil)
+	octree.SetLabel("map")
+
+	data, ok := octree.At(15, 15, 15)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, data.Value(), test.ShouldEqual, 70)
+	_, ok = octree.At(-15, 15, 15)
+	test.That(t, ok, test.ShouldBeFalse)
+
+	moved, ok := octree.Transform(spatialmath.NewPoseFromPoint(r3.Vector{X: 1000})).(*OccupancyOctree)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, moved.Label(), test.ShouldEqual, "map")
+	test.That(t, moved.IsOccupied(r3.Vector{X: 1015, Y: 15, Z: 15}), test.ShouldBeTrue)
+	test.That(t, moved.ToPoints(0), test.ShouldHaveLength, 1)
+	// The copy is independent of the original.
+	moved.UpdateVoxel(r3.Vector{X: 1015, Y: 15, Z: 15}, false)
+	test.That(t, octree.IsOccupied(r3.Vector{X: 15, Y: 15, Z: 15}), test.ShouldBeTrue)
+
+	rotated, ok := octree.Transform(spatialmath.NewPoseFromOrientation(&spatialmath.OrientationVectorDegrees{OZ: 1, Theta: 90})).(*OccupancyOctree)
+	test.That(t, ok, test.ShouldBeTrue)
+	points := rotated.ToPoints(0)
+	test.That(t, points, test.ShouldHaveLength, 1)
+	test.That(t, spatialmath.R3VectorAlmostEqual(points[0], r3.Vector{X: -15, Y: 15, Z: 15}, 10), test.ShouldBeTrue)
+
+	// Free space is kept when the octree is translated, but forgotten when it is rotated.
+	free := r3.Vector{X: -15, Y: -15, Z: -15}
+	octree.UpdateVoxel(free, false)
+	moved = octree.Transform(spatialmath.NewPoseFromPoint(r3.Vector{X: 1000})).(*OccupancyOctree)
+	prob, known := moved.Occupancy(free.Add(r3.Vector{X: 1000}))
+	test.That(t, known, test.ShouldBeTrue)
+	test.That(t, prob, test.ShouldAlmostEqual, octree.Params().ProbMiss)
+	rotated = octree.Transform(spatialmath.NewPoseFromOrientation(&spatialmath.OrientationVectorDegrees{OZ: 1, Theta: 90})).(*OccupancyOctree)
+	_, known = rotated.Occupancy(r3.Vector{X: 15, Y: -15, Z: -15})
+	test.That(t, known, test.ShouldBeFalse)
+	test.That(t, rotated.ToPoints(0), test.ShouldHaveLength, 1)
+
+	sphere, err := spatialmath.NewSphere(spatialmath.NewZeroPose(), 100, "")
+	test.That(t, err, test.ShouldBeNil)
+	encompassed, err := octree.EncompassedBy(sphere)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, encompassed, test.ShouldBeTrue)
+	sphere, err = spatialmath.NewSphere(spatialmath.NewPoseFromPoint(r3.Vector{X: -50}), 20, "")
+	test.That(t, err, test.ShouldBeNil)
+	encompassed, err = octree.EncompassedBy(sphere)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, encompassed, test.ShouldBeFalse)
+
+	// The occupied voxels are sent as a point cloud.
+	proto := octree.ToProtobuf()
+	test.That(t, proto.Label, test.ShouldEqual, "map")
+	cloud, err := NewPointCloudFromProto(proto.GetPointcloud(), proto.Label)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, cloud.Size(), test.ShouldEqual, 1)
+	_, ok = cloud.At(15, 15, 15)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, math.IsInf(float64(octree.Hash()), 0), test.ShouldBeFalse)
+}
+
+// occupancyLeaves returns every leaf of the octree keyed by its first voxel and width.
+func occupancyLeaves(octree *OccupancyOctree) map[[4]int]float64 {
+	leaves := map[[4]int]float64{}
+	octree.walkLeaves(octree.root, 0, occupancyKey{}, func(n *occupancyNode, base occupancyKey, span int) bool {
+		leaves[[4]int{base[0], base[1], base[2], span}] = n.logOdds
+		return true
+	})
+	return leaves
+}
+
+func TestOccupancyOctreeJSON(t *testing.T) {
+	params := DefaultOccupancyParams(10)
+	params.ProbHit = 0.8
+	octree, err := NewOccupancyOctree(r3.Vector{X: 5, Y: -3, Z: 1}, 640, params)
+	test.That(t, err, test.ShouldBeNil)
+	octree.SetLabel("map")
+	wall := NewBasicEmpty()
+	for y := -100.; y <= 100; y += 10 {
+		for z := -100.; z <= 100; z += 10 {
+			test.That(t, wall.Set(r3.Vector{X: 200, Y: y, Z: z}, nil), test.ShouldBeNil)
+		}
+	}
+	octree.InsertPointCloud(wall, spatialmath.NewZeroPose(), 0)
+	// Clear a block of voxels so that a free leaf is pruned.
+	for x := 0.; x < 40; x += 10 {
+		for y := 0.; y < 40; y += 10 {
+			for z := 0.; z < 40; z += 10 {
+				octree.UpdateVoxel(r3.Vector{X: -315 + x, Y: -323 + y, Z: -319 + z}, false)
+			}
+		}
+	}
+	leaves := occupancyLeaves(octree)
+	pruned := false
+	for k := range leaves {
+		pruned = pruned || k[3] > 1
+	}
+	test.That(t, pruned, test.ShouldBeTrue)
+
+	bytes, err := json.Marshal(octree)
+	test.That(t, err, test.ShouldBeNil)
+	var unmarshaled OccupancyOctree
+	test.That(t, json.Unmarshal(bytes, &unmarshaled), test.ShouldBeNil)
+	test.That(t, unmarshaled.Label(), test.ShouldEqual, "map")
+	test.That(t, unmarshaled.Params(), test.ShouldResemble, params)
+	test.That(t, unmarshaled.Pose().Point(), test.ShouldResemble, octree.Pose().Point())
+	test.That(t, unmarshaled.SideLength(), test.ShouldEqual, octree.SideLength())
+	test.That(t, unmarshaled.numNodes(), test.ShouldEqual, octree.numNodes())
+	test.That(t, occupancyLeaves(&unmarshaled), test.ShouldResemble, leaves)
+	test.That(t, unmarshaled.ToPoints(0), test.ShouldResemble, octree.ToPoints(0))
+	// The unmarshaled octree keeps integrating scans with the same sensor model.
+	test.That(t, unmarshaled.UpdateVoxel(r3.Vector{X: 205}, true), test.ShouldBeTrue)
+	octree.UpdateVoxel(r3.Vector{X: 205}, true)
+	test.That(t, occupancyLeaves(&unmarshaled), test.ShouldResemble, occupancyLeaves(octree))
+
+	for _, invalid := range []string{
+		`{"depth": 3, "params": {"resolution": 10, "prob_hit": 0.4, "prob_miss": 0.4, "clamp_min": 0.1, ` +
+			`"clamp_max": 0.9, "occupied_threshold": 0.5}}`,
+		`{"depth": 30, "params": {"resolution": 10, "prob_hit": 0.7, "prob_miss": 0.4, "clamp_min": 0.1, ` +
+			`"clamp_max": 0.9, "occupied_threshold": 0.5}}`,
+		`{"depth": 3, "params": {"resolution": 10, "prob_hit": 0.7, "prob_miss": 0.4, "clamp_min": 0.1, ` +
+			`"clamp_max": 0.9, "occupied_threshold": 0.5}, "leaves": [{"key": [1, 0, 0], "span": 2}]}`,
+		`{"depth": 3, "params": {"resolution": 10, "prob_hit": 0.7, "prob_miss": 0.4, "clamp_min": 0.1, ` +
+			`"clamp_max": 0.9, "occupied_threshold": 0.5}, "leaves": [{"key": [8, 0, 0], "span": 1}]}`,
+	} {
+		test.That(t, json.Unmarshal([]byte(invalid), &unmarshaled), test.ShouldNotBeNil)
+	}
+}
@@ -0,0 +1,436 @@
+package pointcloud
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+)
+
+// PLYType is the format of a ply file.
+type PLYType int
+
+const (
+	// PLYAscii ascii format for ply.
+	PLYAscii PLYType = iota
+	// PLYBinaryLittleEndian little endian binary format for ply.
+	PLYBinaryLittleEndian
+	// PLYBinaryBigEndian big endian binary format for ply.
+	PLYBinaryBigEndian
+)
+
+var plyFormats = map[string]PLYType{
+	"ascii":                PLYAscii,
+	"binary_little_endian": PLYBinaryLittleEndian,
+	"binary_big_endian":    PLYBinaryBigEndian,
+}
+
+// maxPLYPreallocatedPoints caps how many points are allocated up front from the vertex count in a ply header, so a
+// bad header can't make the reader allocate more than the file holds. Larger clouds grow as their points are read.
+const maxPLYPreallocatedPoints = 1 << 20
+
+// plyTypeSizes are the sizes in bytes of the scalar types of ply properties, under both of their names.
+var plyTypeSizes = map[string]int{
+	"char": 1, "int8": 1, "uchar": 1, "uint8": 1,
+	"short": 2, "int16": 2, "ushort": 2, "uint16": 2,
+	"int": 4, "int32": 4, "uint": 4, "uint32": 4, "float": 4, "float32": 4,
+	"double": 8, "float64": 8,
+}
+
+type plyProperty struct {
+	name      string
+	valueType string
+	// countType is the type of the length of a list property, and empty for scalar properties.
+	countType string
+}
+
+type plyElement struct {
+	name       string
+	count      int
+	properties []plyProperty
+}
+
+type plyHeader struct {
+	format   PLYType
+	elements []plyElement
+}
+
+func parsePLYHeader(in *bufio.Reader) (*plyHeader, error) {
+	magic, err := in.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(magic) != "ply" {
+		return nil, errors.New("ply file must start with ply")
+	}
+	header := &plyHeader{format: -1}
+	for {
+		line, err := in.ReadString('\n')
+		if err != nil {
+			return nil, errors.Wrap(err, "ply header has no end_header")
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) == 0 {
+			continue
+		}
+		switch tokens[0] {
+		case "comment", "obj_info":
+		case "format":
+			if len(tokens) != 3 {
+				return nil, errors.Errorf("invalid ply format %q", strings.TrimSpace(line))
+			}
+			format, ok := plyFormats[tokens[1]]
+			if !ok {
+				return nil, errors.Errorf("unsupported ply format %q", strings.TrimSpace(line))
+			}
+			header.format = format
+		case "element":
+			if len(tokens) != 3 {
+				return nil, errors.Errorf("invalid ply element %q", strings.TrimSpace(line))
+			}
+			count, err := strconv.Atoi(tokens[2])
+			if err != nil || count < 0 {
+				return nil, errors.Errorf("invalid ply element count %q", tokens[2])
+			}
+			header.elements = append(header.elements, plyElement{name: tokens[1], count: count})
+		case "property":
+			if len(header.elements) == 0 {
+				return nil, errors.New("ply property before any element")
+			}
+			var property plyProperty
+			switch {
+			case len(tokens) == 5 && tokens[1] == "list":
+				property = plyProperty{countType: tokens[2], valueType: tokens[3], name: tokens[4]}
+				if _, ok := plyTypeSizes[property.countType]; !ok {
+					return nil, errors.Errorf("unsupported ply type %q", property.countType)
+				}
+			case len(tokens) == 3:
+				property = plyProperty{valueType: tokens[1], name: tokens[2]}
+			default:
+				return nil, errors.Errorf("invalid ply property %q", strings.TrimSpace(line))
+			}
+			if _, ok := plyTypeSizes[property.valueType]; !ok {
+				return nil, errors.Errorf("unsupported ply type %q", property.valueType)
+			}
+			element := &header.elements[len(header.elements)-1]
+			element.properties = append(element.properties, property)
+		case "end_header":
+			if header.format < 0 {
+				return nil, errors.New("ply header has no format")
+			}
+			return header, nil
+		default:
+			return nil, errors.Errorf("unexpected line in ply header %q", strings.TrimSpace(line))
+		}
+	}
+}
+
+// plyVertexFields finds the properties of a vertex that become a point and its data.
+type plyVertexFields struct {
+	x, y, z          int
+	red, green, blue int
+	intensity        int
+	value            int
+	// colorScale converts the values of the colors to the range 0 to 255.
+	colorScale float64
+}
+
+func newPLYVertexFields(element plyElement) (plyVertexFields, error) {
+	fields := plyVertexFields{x: -1, y: -1, z: -1, red: -1, green: -1, blue: -1, intensity: -1, value: -1}
+	for i, property := range element.properties {
+		if property.countType != "" {
+			continue
+		}
+		switch strings.ToLower(property.name) {
+		case "x":
+			fields.x = i
+		case "y":
+			fields.y = i
+		case "z":
+			fields.z = i
+		case "red", "r", "diffuse_red":
+			fields.red = i
+			switch property.valueType {
+			case "float", "float32", "double", "float64":
+				fields.colorScale = 255
+			case "ushort", "uint16":
+				fields.colorScale = 1. / 257
+			default:
+				fields.colorScale = 1
+			}
+		case "green", "g", "diffuse_green":
+			fields.green = i
+		case "blue", "b", "diffuse_blue":
+			fields.blue = i
+		case "intensity", "scalar_intensity":
+			fields.intensity = i
+		case "value":
+			fields.value = i
+		}
+	}
+	if fields.x < 0 || fields.y < 0 || fields.z < 0 {
+		return fields, errors.New("ply vertices must have x, y and z properties")
+	}
+	return fields, nil
+}
+
+func (fields plyVertexFields) toPoint(values []float64) (r3.Vector, Data) {
+	// Converts ply units (meters) to millimeters for RDK, the same as PCD.
+	p := r3.Vector{X: 1000. * values[fields.x], Y: 1000. * values[fields.y], Z: 1000. * values[fields.z]}
+	d := NewBasicData()
+	if fields.red >= 0 && fields.green >= 0 && fields.blue >= 0 {
+		channel := func(v float64) uint8 {
+			return uint8(math.Max(0, math.Min(255, math.Round(v*fields.colorScale))))
+		}
+		d = NewColoredData(color.NRGBA{channel(values[fields.red]), channel(values[fields.green]), channel(values[fields.blue]), 255})
+	}
+	if fields.intensity >= 0 {
+		d.SetIntensity(uint16(math.Max(0, math.Min(math.MaxUint16, math.Round(values[fields.intensity])))))
+	}
+	if fields.value >= 0 {
+		d.SetValue(int(values[fields.value]))
+	}
+	return p, d
+}
+
+// ReadPLY reads a point cloud from the vertices of an ascii or binary ply file. Colors, intensities and values of
+// the vertices are kept in their data, and other elements such as faces are ignored.
+func ReadPLY(inRaw io.Reader, pcStructureType string) (PointCloud, error) {
+	cfg, err := Find(pcStructureType)
+	if err != nil {
+		return nil, err
+	}
+	return readPLY(inRaw, cfg)
+}
+
+func readPLY(inRaw io.Reader, cfg TypeConfig) (PointCloud, error) {
+	in := bufio.NewReader(inRaw)
+	header, err := parsePLYHeader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, element := range header.elements {
+		if element.name != "vertex" {
+			if err := skipPLYElement(in, header.format, element); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		fields, err := newPLYVertexFields(element)
+		if err != nil {
+			return nil, err
+		}
+		pc := cfg.NewWithParams(min(element.count, maxPLYPreallocatedPoints))
+		values := make([]float64, len(element.properties))
+		for i := 0; i < element.count; i++ {
+			if err := readPLYRow(in, header.format, element, values); err != nil {
+				return nil, errors.Wrapf(err, "could not read vertex %d", i)
+			}
+			p, d := fields.toPoint(values)
+			if err := pc.Set(p, d); err != nil {
+				return nil, err
+			}
+		}
+		return pc.FinalizeAfterReading()
+	}
+	return nil, errors.New("ply file has no vertex element")
+}
+
+// readPLYRow reads the scalar properties of one row of an element into values, skipping its lists.
+func readPLYRow(in *bufio.Reader, format PLYType, element plyElement, values []float64) error {
+	if format == PLYAscii {
+		line, err := in.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+			return err
+		}
+		tokens := strings.Fields(line)
+		index := 0
+		for i, property := range element.properties {
+			if index >= len(tokens) {
+				return errors.New("too few values")
+			}
+			if property.countType != "" {
+				count, err := strconv.Atoi(tokens[index])
+				if err != nil {
+					return err
+				}
+				if count < 0 || count > len(tokens)-index-1 {
+					return errors.Errorf("list %s has %d values, but the row has %d left", property.name, count, len(tokens)-index-1)
+				}
+				index += 1 + count
+				continue
+			}
+			if values[i], err = strconv.ParseFloat(tokens[index], 64); err != nil {
+				return err
+			}
+			index++
+		}
+		return nil
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if format == PLYBinaryBigEndian {
+		order = binary.BigEndian
+	}
+	for i, property := range element.properties {
+		if property.countType != "" {
+			count, err := readPLYBinaryValue(in, order, property.countType)
+			if err != nil {
+				return err
+			}
+			if _, err := in.Discard(int(count) * plyTypeSizes[property.valueType]); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := readPLYBinaryValue(in, order, property.valueType)
+		if err != nil {
+			return err
+		}
+		values[i] = value
+	}
+	return nil
+}
+
+func readPLYBinaryValue(in *bufio.Reader, order binary.ByteOrder, valueType string) (float64, error) {
+	buf := make([]byte, plyTypeSizes[valueType])
+	if _, err := io.ReadFull(in, buf); err != nil {
+		return 0, err
+	}
+	switch valueType {
+	case "char", "int8":
+		return float64(int8(buf[0])), nil
+	case "uchar", "uint8":
+		return float64(buf[0]), nil
+	case "short", "int16":
+		return float64(int16(order.Uint16(buf))), nil
+	case "ushort", "uint16":
+		return float64(order.Uint16(buf)), nil
+	case "int", "int32":
+		return float64(int32(order.Uint32(buf))), nil
+	case "uint", "uint32":
+		return float64(order.Uint32(buf)), nil
+	case "float", "float32":
+		return float64(math.Float32frombits(order.Uint32(buf))), nil
+	case "double", "float64":
+		return math.Float64frombits(order.Uint64(buf)), nil
+	default:
+		return 0, errors.Errorf("unsupported ply type %q", valueType)
+	}
+}
+
+func skipPLYElement(in *bufio.Reader, format PLYType, element plyElement) error {
+	values := make([]float64, len(element.properties))
+	for i := 0; i < element.count; i++ {
+		if err := readPLYRow(in, format, element, values); err != nil {
+			return errors.Wrapf(err, "could not read %s %d", element.name, i)
+		}
+	}
+	return nil
+}
+
+// ToPLY writes out a point cloud to a ply file of the specified type, in meters. Colors, intensities and values are
+// written as vertex properties when the cloud has them.
+func ToPLY(cloud PointCloud, out io.Writer, outputType PLYType) error {
+	var format string
+	var order binary.AppendByteOrder = binary.LittleEndian
+	switch outputType {
+	case PLYAscii:
+		format = "ascii"
+	case PLYBinaryLittleEndian:
+		format = "binary_little_endian"
+	case PLYBinaryBigEndian:
+		format = "binary_big_endian"
+		order = binary.BigEndian
+	default:
+		return errors.Errorf("unsupported ply type %v", outputType)
+	}
+
+	meta := cloud.MetaData()
+	hasIntensity := false
+	cloud.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		hasIntensity = d != nil && d.Intensity() != 0
+		return !hasIntensity
+	})
+
+	header := fmt.Sprintf("ply\nformat %s 1.0\nelement vertex %d\nproperty float x\nproperty float y\nproperty float z\n",
+		format, cloud.Size())
+	if meta.HasColor {
+		header += "property uchar red\nproperty uchar green\nproperty uchar blue\n"
+	}
+	if hasIntensity {
+		header += "property ushort intensity\n"
+	}
+	if meta.HasValue {
+		header += "property int value\n"
+	}
+	header += "end_header\n"
+	if _, err := io.WriteString(out, header); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(out)
+	var err error
+	buf := make([]byte, 0, 24)
+	cloud.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		if d == nil {
+			d = NewBasicData()
+		}
+		// Converts RDK units (millimeters) to meters for ply.
+		x, y, z := float32(p.X/1000.), float32(p.Y/1000.), float32(p.Z/1000.)
+		r, g, b := uint8(255), uint8(255), uint8(255)
+		if d.HasColor() {
+			r, g, b = d.RGB255()
+		}
+		if outputType == PLYAscii {
+			line := fmt.Sprintf("%g %g %g", x, y, z)
+			if meta.HasColor {
+				line += fmt.Sprintf(" %d %d %d", r, g, b)
+			}
+			if hasIntensity {
+				line += fmt.Sprintf(" %d", d.Intensity())
+			}
+			if meta.HasValue {
+				line += fmt.Sprintf(" %d", plyValue(d))
+			}
+			_, err = w.WriteString(line + "\n")
+			return err == nil
+		}
+
+		buf = buf[:0]
+		buf = order.AppendUint32(buf, math.Float32bits(x))
+		buf = order.AppendUint32(buf, math.Float32bits(y))
+		buf = order.AppendUint32(buf, math.Float32bits(z))
+		if meta.HasColor {
+			buf = append(buf, r, g, b)
+		}
+		if hasIntensity {
+			buf = order.AppendUint16(buf, d.Intensity())
+		}
+		if meta.HasValue {
+			buf = order.AppendUint32(buf, uint32(int32(plyValue(d))))
+		}
+		_, err = w.Write(buf)
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func plyValue(d Data) int {
+	if d.HasValue() {
+		return d.Value()
+	}
+	return 0
+}
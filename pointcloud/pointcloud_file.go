@@ -16,6 +16,7 @@ import (
 	"github.com/golang/geo/r3"
 	"github.com/pkg/errors"
 	lzf "github.com/zhuyie/golzf"
+	"go.viam.com/utils"
 	"gonum.org/v1/gonum/num/quat"
 
 	"go.viam.com/rdk/spatialmath"
@@ -33,25 +34,32 @@ const (
 	PCDCompressed PCDType = 2
 )
 
-// NewFromFile returns a pointcloud read in from the given file.
+// NewFromFile returns a pointcloud read in from the given file, which may be a las, pcd, ply, xyz or xyzrgb file.
 func NewFromFile(filename, pcStructureType string) (PointCloud, error) {
 	cfg, err := Find(pcStructureType)
 	if err != nil {
 		return nil, err
 	}
 
-	switch filepath.Ext(filename) {
+	var read func(io.Reader, TypeConfig) (PointCloud, error)
+	switch strings.ToLower(filepath.Ext(filename)) {
 	case ".las":
 		return newFromLASFile(filename, cfg)
 	case ".pcd":
-		f, err := os.Open(filepath.Clean(filename))
-		if err != nil {
-			return nil, err
-		}
-		return readPCD(f, cfg)
+		read = readPCD
+	case ".ply":
+		read = readPLY
+	case ".xyz", ".xyzrgb":
+		read = readXYZ
 	default:
 		return nil, errors.Errorf("do not know how to read file %q", filename)
 	}
+	f, err := os.Open(filepath.Clean(filename))
+	if err != nil {
+		return nil, err
+	}
+	defer utils.UncheckedErrorFunc(f.Close)
+	return read(f, cfg)
 }
 
 func _colorToPCDInt(pt Data) int {
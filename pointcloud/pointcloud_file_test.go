@@ -638,3 +638,147 @@ func BenchmarkPCDCompressedRead(b *testing.B) {
 		test.That(b, err, test.ShouldBeNil)
 	}
 }
+
+// testCloudsAlmostEqual checks that two clouds have the same data at the same points, up to the precision of a float32
+// in meters.
+func testCloudsAlmostEqual(t *testing.T, actual, expected PointCloud) {
+	t.Helper()
+	test.That(t, actual.Size(), test.ShouldEqual, expected.Size())
+	kd := ToKDTree(actual)
+	expected.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		_, actualData, dist, _ := kd.NearestNeighbor(p)
+		test.That(t, dist, test.ShouldBeLessThan, 1e-3)
+		test.That(t, actualData, test.ShouldResemble, d)
+		return true
+	})
+}
+
+func TestPLY(t *testing.T) {
+	cloud := NewBasicPointCloud(0)
+	test.That(t, cloud.Set(NewVector(-1000, -2000, 500), NewColoredData(color.NRGBA{255, 1, 2, 255}).SetValue(5)), test.ShouldBeNil)
+	test.That(t, cloud.Set(NewVector(1500, 12, 0), NewColoredData(color.NRGBA{3, 200, 4, 255}).SetValue(-1)), test.ShouldBeNil)
+	test.That(t, cloud.Set(NewVector(7, 6, 1), NewColoredData(color.NRGBA{0, 0, 255, 255}).SetValue(0).SetIntensity(300)), test.ShouldBeNil)
+
+	for _, plyType := range []PLYType{PLYAscii, PLYBinaryLittleEndian, PLYBinaryBigEndian} {
+		var buf bytes.Buffer
+		test.That(t, ToPLY(cloud, &buf, plyType), test.ShouldBeNil)
+		read, err := ReadPLY(&buf, BasicType)
+		test.That(t, err, test.ShouldBeNil)
+		testCloudsAlmostEqual(t, read, cloud)
+	}
+
+	// Colors may be floats, and other elements such as faces are skipped.
+	ascii := "ply\nformat ascii 1.0\ncomment made by hand\nelement face 1\nproperty list uchar int vertex_indices\n" +
+		"element vertex 2\nproperty double x\nproperty double y\nproperty double z\n" +
+		"property float red\nproperty float green\nproperty float blue\nend_header\n" +
+		"3 0 1 1\n0.001 0.002 0.003 1 0.5 0\n-1 0 1 0 0 1\n"
+	read, err := ReadPLY(strings.NewReader(ascii), BasicType)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read.Size(), test.ShouldEqual, 2)
+	d, ok := read.At(1, 2, 3)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, d.Color(), test.ShouldResemble, &color.NRGBA{255, 128, 0, 255})
+	_, ok = read.At(-1000, 0, 1000)
+	test.That(t, ok, test.ShouldBeTrue)
+
+	// A big endian file with 16 bit colors and intensity, whose faces come after its vertices.
+	var buf bytes.Buffer
+	buf.WriteString("ply\nformat binary_big_endian 1.0\nelement vertex 1\nproperty float x\nproperty float y\nproperty float z\n" +
+		"property ushort red\nproperty ushort green\nproperty ushort blue\nproperty float intensity\n" +
+		"element face 1\nproperty list uchar int vertex_indices\nend_header\n")
+	for _, v := range []float32{0.5, -0.25, 2} {
+		test.That(t, binary.Write(&buf, binary.BigEndian, v), test.ShouldBeNil)
+	}
+	test.That(t, binary.Write(&buf, binary.BigEndian, []uint16{65535, 0, 257}), test.ShouldBeNil)
+	test.That(t, binary.Write(&buf, binary.BigEndian, float32(42)), test.ShouldBeNil)
+	buf.Write([]byte{1, 0, 0, 0, 0})
+	read, err = ReadPLY(&buf, BasicType)
+	test.That(t, err, test.ShouldBeNil)
+	d, ok = read.At(500, -250, 2000)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, d.Color(), test.ShouldResemble, &color.NRGBA{255, 0, 1, 255})
+	test.That(t, d.Intensity(), test.ShouldEqual, 42)
+
+	for _, bad := range []string{
+		"",
+		"pcd\n",
+		"ply\nelement vertex 1\nproperty float x\nend_header\n",
+		"ply\nformat binary_middle_endian 1.0\nend_header\n",
+		"ply\nformat ascii 1.0\nelement vertex 1\nproperty float x\nproperty float y\nend_header\n0 0\n",
+		"ply\nformat ascii 1.0\nelement vertex 1\nproperty half x\nend_header\n",
+		"ply\nformat ascii 1.0\nelement face 0\nproperty list uchar int vertex_indices\nend_header\n",
+		"ply\nformat ascii 1.0\nelement vertex 2\nproperty float x\nproperty float y\nproperty float z\nend_header\n0 0 0\n",
+		"ply\nformat ascii 1.0\nelement vertex 1\nproperty list uchar int idx\nproperty float x\nproperty float y\n" +
+			"property float z\nend_header\n-3 0 0 0\n",
+		"ply\nformat ascii 1.0\nelement vertex 1\nproperty list uchar int idx\nproperty float x\nproperty float y\n" +
+			"property float z\nend_header\n9 0 0 0\n",
+		"ply\nformat ascii 1.0\nelement face 1\nproperty list uchar int vertex_indices\nelement vertex 1\n" +
+			"property float x\nproperty float y\nproperty float z\nend_header\n-2 0 1\n0 0 0\n",
+		"ply\nformat ascii 1.0\nelement vertex 2000000000\nproperty float x\nproperty float y\nproperty float z\nend_header\n0 0 0\n",
+	} {
+		_, err := ReadPLY(strings.NewReader(bad), BasicType)
+		test.That(t, err, test.ShouldNotBeNil)
+	}
+}
+
+func TestXYZ(t *testing.T) {
+	cloud := NewBasicPointCloud(0)
+	test.That(t, cloud.Set(NewVector(-1000, -2000, 500), NewColoredData(color.NRGBA{255, 1, 2, 255})), test.ShouldBeNil)
+	test.That(t, cloud.Set(NewVector(1500, 12, 0), NewColoredData(color.NRGBA{3, 200, 4, 255})), test.ShouldBeNil)
+	var buf bytes.Buffer
+	test.That(t, ToXYZ(cloud, &buf), test.ShouldBeNil)
+	read, err := ReadXYZ(&buf, BasicType)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read, test.ShouldResemble, cloud)
+
+	text := "//X Y Z R G B\n# comment\n\n1,2,3\n0.001;0.002;0.003;7\n1\t0\t0\t1.0\t0.5\t0.0\n0 1 0 10 20 30 400\n"
+	read, err = ReadXYZ(strings.NewReader(text), BasicType)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read.Size(), test.ShouldEqual, 4)
+	d, ok := read.At(1000, 2000, 3000)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, d.HasColor(), test.ShouldBeFalse)
+	d, ok = read.At(1, 2, 3)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, d.Intensity(), test.ShouldEqual, 7)
+	d, ok = read.At(1000, 0, 0)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, d.Color(), test.ShouldResemble, &color.NRGBA{255, 128, 0, 255})
+	d, ok = read.At(0, 1000, 0)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, d.Color(), test.ShouldResemble, &color.NRGBA{10, 20, 30, 255})
+	test.That(t, d.Intensity(), test.ShouldEqual, 400)
+
+	// Lines of only separators are skipped.
+	read, err = ReadXYZ(strings.NewReader(",,\n ; \n1 2 3\n"), BasicType)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, read.Size(), test.ShouldEqual, 1)
+
+	_, err = ReadXYZ(strings.NewReader("1 2\n"), BasicType)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = ReadXYZ(strings.NewReader("1 2 x\n"), BasicType)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestNewFromFileFormats(t *testing.T) {
+	cloud := NewBasicPointCloud(0)
+	test.That(t, cloud.Set(NewVector(-1000, -2000, 500), NewColoredData(color.NRGBA{255, 1, 2, 255})), test.ShouldBeNil)
+	test.That(t, cloud.Set(NewVector(1500, 12, 0), NewColoredData(color.NRGBA{3, 200, 4, 255})), test.ShouldBeNil)
+	dir := t.TempDir()
+	for name, write := range map[string]func(*os.File) error{
+		"cloud.ply":    func(f *os.File) error { return ToPLY(cloud, f, PLYBinaryLittleEndian) },
+		"cloud.PLY":    func(f *os.File) error { return ToPLY(cloud, f, PLYAscii) },
+		"cloud.xyz":    func(f *os.File) error { return ToXYZ(cloud, f) },
+		"cloud.xyzrgb": func(f *os.File) error { return ToXYZ(cloud, f) },
+	} {
+		f, err := os.Create(dir + "/" + name)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, write(f), test.ShouldBeNil)
+		test.That(t, f.Close(), test.ShouldBeNil)
+		read, err := NewFromFile(f.Name(), BasicType)
+		test.That(t, err, test.ShouldBeNil)
+		testCloudsAlmostEqual(t, read, cloud)
+	}
+	_, err := NewFromFile(dir+"/cloud.obj", BasicType)
+	test.That(t, err, test.ShouldNotBeNil)
+}
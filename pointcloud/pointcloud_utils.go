@@ -5,6 +5,7 @@ import (
 
 	"github.com/golang/geo/r3"
 	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/stat"
 
 	"go.viam.com/rdk/spatialmath"
@@ -33,6 +34,55 @@ func BoundingBoxFromPointCloudWithLabel(cloud PointCloud, label string) (spatial
 	return spatialmath.NewBox(spatialmath.NewPoseFromPoint(mean), dims, label)
 }
 
+// OrientedBoundingBoxFromPointCloudWithLabel returns a box that encompasses all the points in the given point cloud,
+// rotated to the principal axes of the points so that it fits objects that are not aligned with the axes of the cloud
+// more tightly than BoundingBoxFromPointCloudWithLabel. The box's x axis is the direction the points spread out the most.
+func OrientedBoundingBoxFromPointCloudWithLabel(cloud PointCloud, label string) (spatialmath.Geometry, error) {
+	if cloud.Size() == 0 {
+		return nil, nil
+	}
+	meta := cloud.MetaData()
+	n := float64(cloud.Size())
+	mean := r3.Vector{meta.TotalX() / n, meta.TotalY() / n, meta.TotalZ() / n}
+	covariance := mat.NewSymDense(3, nil)
+	cloud.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		v := []float64{p.X - mean.X, p.Y - mean.Y, p.Z - mean.Z}
+		for r := 0; r < 3; r++ {
+			for c := r; c < 3; c++ {
+				covariance.SetSym(r, c, covariance.At(r, c)+v[r]*v[c])
+			}
+		}
+		return true
+	})
+	var eigen mat.EigenSym
+	if !eigen.Factorize(covariance, true) {
+		return nil, errors.New("could not find the principal axes of the point cloud")
+	}
+	var vectors mat.Dense
+	eigen.VectorsTo(&vectors)
+	// The eigenvalues are in ascending order, and the third axis is made from the other two so that the axes are
+	// right handed.
+	x := r3.Vector{vectors.At(0, 2), vectors.At(1, 2), vectors.At(2, 2)}.Normalize()
+	y := r3.Vector{vectors.At(0, 1), vectors.At(1, 1), vectors.At(2, 1)}.Normalize()
+	z := x.Cross(y)
+	rotation, err := spatialmath.NewRotationMatrix([]float64{x.X, y.X, z.X, x.Y, y.Y, z.Y, x.Z, y.Z, z.Z})
+	if err != nil {
+		return nil, err
+	}
+
+	minLocal := r3.Vector{math.Inf(1), math.Inf(1), math.Inf(1)}
+	maxLocal := r3.Vector{math.Inf(-1), math.Inf(-1), math.Inf(-1)}
+	cloud.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		v := p.Sub(mean)
+		local := r3.Vector{v.Dot(x), v.Dot(y), v.Dot(z)}
+		minLocal = r3.Vector{math.Min(minLocal.X, local.X), math.Min(minLocal.Y, local.Y), math.Min(minLocal.Z, local.Z)}
+		maxLocal = r3.Vector{math.Max(maxLocal.X, local.X), math.Max(maxLocal.Y, local.Y), math.Max(maxLocal.Z, local.Z)}
+		return true
+	})
+	center := mean.Add(rotation.Mul(minLocal.Add(maxLocal).Mul(0.5)))
+	return spatialmath.NewBox(spatialmath.NewPose(center, rotation), maxLocal.Sub(minLocal), label)
+}
+
 // PrunePointClouds removes point clouds from a slice if the point cloud has less than nMin points.
 func PrunePointClouds(clouds []PointCloud, nMin int) []PointCloud {
 	pruned := make([]PointCloud, 0, len(clouds))
@@ -1,6 +1,7 @@
 package pointcloud
 
 import (
+	"math"
 	"testing"
 
 	"github.com/golang/geo/r3"
@@ -93,3 +94,47 @@ func TestToOctree(t *testing.T) {
 		return true
 	})
 }
+
+func TestOrientedBoundingBoxFromPointCloud(t *testing.T) {
+	// the points of a 200 x 100 x 40 mm slab, turned 30 degrees about z
+	pose := spatialmath.NewPose(r3.Vector{X: 500, Y: -200, Z: 20}, &spatialmath.OrientationVectorDegrees{OZ: 1, Theta: 30})
+	cloud := NewBasicEmpty()
+	for x := -100.; x <= 100; x += 10 {
+		for y := -50.; y <= 50; y += 10 {
+			for z := -20.; z <= 20; z += 10 {
+				test.That(t, cloud.Set(spatialmath.Compose(pose, spatialmath.NewPoseFromPoint(r3.Vector{x, y, z})).Point(), nil),
+					test.ShouldBeNil)
+			}
+		}
+	}
+
+	box, err := OrientedBoundingBoxFromPointCloudWithLabel(cloud, "slab")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, box.Label(), test.ShouldEqual, "slab")
+	dims := box.ToProtobuf().GetBox().GetDimsMm()
+	test.That(t, dims.X, test.ShouldAlmostEqual, 200, 1e-6)
+	test.That(t, dims.Y, test.ShouldAlmostEqual, 100, 1e-6)
+	test.That(t, dims.Z, test.ShouldAlmostEqual, 40, 1e-6)
+	// the axes of the box may point either way along those of the slab
+	axis := func(p spatialmath.Pose, v r3.Vector) r3.Vector {
+		return p.Orientation().RotationMatrix().Mul(v)
+	}
+	for _, v := range []r3.Vector{{X: 1}, {Y: 1}, {Z: 1}} {
+		test.That(t, math.Abs(axis(box.Pose(), v).Dot(axis(pose, v))), test.ShouldAlmostEqual, 1, 1e-9)
+	}
+	test.That(t, box.Pose().Point().Distance(pose.Point()), test.ShouldBeLessThan, 1e-6)
+
+	// the box is much smaller than the one aligned with the axes
+	aligned, err := BoundingBoxFromPointCloud(cloud)
+	test.That(t, err, test.ShouldBeNil)
+	volume := func(g spatialmath.Geometry) float64 {
+		dims := g.ToProtobuf().GetBox().GetDimsMm()
+		return dims.X * dims.Y * dims.Z
+	}
+	test.That(t, volume(box), test.ShouldAlmostEqual, 200*100*40, 1e-3)
+	test.That(t, volume(aligned), test.ShouldBeGreaterThan, 1.5*volume(box))
+
+	box, err = OrientedBoundingBoxFromPointCloudWithLabel(NewBasicEmpty(), "")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, box, test.ShouldBeNil)
+}
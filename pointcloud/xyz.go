@@ -0,0 +1,120 @@
+package pointcloud
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+)
+
+// ReadXYZ reads a point cloud from a text file with one point per line, whose values are separated by spaces, tabs,
+// commas or semicolons. The values of a line are one of
+//
+//	x y z
+//	x y z intensity
+//	x y z r g b
+//	x y z r g b intensity
+//
+// in meters, the same as PCD. Colors are from 0 to 255, or from 0 to 1 if they are written with a decimal point.
+// Lines that are empty, start with # or // or do not start with a number, such as a header of column names, are
+// skipped.
+func ReadXYZ(inRaw io.Reader, pcStructureType string) (PointCloud, error) {
+	cfg, err := Find(pcStructureType)
+	if err != nil {
+		return nil, err
+	}
+	return readXYZ(inRaw, cfg)
+}
+
+func readXYZ(inRaw io.Reader, cfg TypeConfig) (PointCloud, error) {
+	pc := cfg.NewWithParams(0)
+	scanner := bufio.NewScanner(inRaw)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		tokens := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ',' || r == ';'
+		})
+		// A line of only separators has no values, and a line that doesn't start with a number is a header.
+		if len(tokens) == 0 {
+			continue
+		}
+		if _, err := strconv.ParseFloat(tokens[0], 64); err != nil {
+			continue
+		}
+		values := make([]float64, len(tokens))
+		for i, token := range tokens {
+			value, err := strconv.ParseFloat(token, 64)
+			if err != nil {
+				return nil, errors.Errorf("invalid value %q on line %d", token, lineNumber)
+			}
+			values[i] = value
+		}
+		if len(values) != 3 && len(values) != 4 && len(values) != 6 && len(values) != 7 {
+			return nil, errors.Errorf("line %d has %d values, but must have 3, 4, 6 or 7", lineNumber, len(values))
+		}
+
+		// Converts XYZ units (meters) to millimeters for RDK.
+		p := r3.Vector{X: 1000. * values[0], Y: 1000. * values[1], Z: 1000. * values[2]}
+		d := NewBasicData()
+		switch len(values) {
+		case 4:
+			d.SetIntensity(xyzIntensity(values[3]))
+		case 6, 7:
+			scale := 1.
+			if strings.ContainsAny(tokens[3]+tokens[4]+tokens[5], ".eE") {
+				scale = 255
+			}
+			channel := func(v float64) uint8 {
+				return uint8(math.Max(0, math.Min(255, math.Round(v*scale))))
+			}
+			d = NewColoredData(color.NRGBA{channel(values[3]), channel(values[4]), channel(values[5]), 255})
+			if len(values) == 7 {
+				d.SetIntensity(xyzIntensity(values[6]))
+			}
+		}
+		if err := pc.Set(p, d); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pc.FinalizeAfterReading()
+}
+
+func xyzIntensity(v float64) uint16 {
+	return uint16(math.Max(0, math.Min(math.MaxUint16, math.Round(v))))
+}
+
+// ToXYZ writes out a point cloud as a text file of x y z in meters, followed by r g b if the cloud has colors.
+func ToXYZ(cloud PointCloud, out io.Writer) error {
+	hasColor := cloud.MetaData().HasColor
+	w := bufio.NewWriter(out)
+	var err error
+	cloud.Iterate(0, 0, func(p r3.Vector, d Data) bool {
+		// Converts RDK units (millimeters) to meters for XYZ.
+		line := fmt.Sprintf("%f %f %f", p.X/1000., p.Y/1000., p.Z/1000.)
+		if hasColor {
+			r, g, b := uint8(255), uint8(255), uint8(255)
+			if d != nil && d.HasColor() {
+				r, g, b = d.RGB255()
+			}
+			line += fmt.Sprintf(" %d %d %d", r, g, b)
+		}
+		_, err = w.WriteString(line + "\n")
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return w.Flush()
+}
@@ -0,0 +1,174 @@
+package referenceframe
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/num/dualquat"
+	"gonum.org/v1/gonum/num/quat"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+// jacobianStep is the input perturbation used to differentiate frames that have no analytic Jacobian.
+const jacobianStep = 1e-6
+
+// Jacobian returns the 6xN geometric Jacobian of the primary output frame at the given inputs, where N is the
+// number of inputs of the model. Rows 0-2 hold the linear velocity of the origin of the primary output frame in mm
+// per unit input, and rows 3-5 hold its angular velocity in radians per unit input, both expressed in the base frame
+// of the model. Inputs that do not move the primary output frame, such as those on other branches of a tree, have
+// zero columns. Mimic frames contribute to the column of the input they follow, scaled by their multiplier.
+// Unlike Transform, Jacobian does not check the inputs of revolute and prismatic joints against their limits.
+func (m *SimpleModel) Jacobian(inputs []Input) (*mat.Dense, error) {
+	if len(m.DoF()) != len(inputs) {
+		return nil, NewIncorrectDoFError(len(inputs), len(m.DoF()))
+	}
+	jac := mat.NewDense(6, len(inputs), nil)
+	if len(m.transformChain) == 0 {
+		return jac, nil
+	}
+
+	// A joint contributes a linear velocity that does not depend on where the tip is, and an angular velocity that
+	// also moves the tip about a point on the joint. The lever arms are only known once the whole chain has been
+	// walked, so the contributions are collected first and summed into the Jacobian at the end.
+	type contribution struct {
+		column  int
+		scale   float64
+		linear  r3.Vector
+		angular r3.Vector
+		pivot   r3.Vector
+	}
+	var contributions []contribution
+
+	composed := spatialmath.DualQuaternion{Number: dualquat.Number{Real: quat.Number{Real: 1}}}
+	for i, chainFrame := range m.transformChain {
+		dof := len(chainFrame.DoF())
+		if dof == 0 {
+			pose, err := chainFrame.Transform(emptyInputs)
+			if err != nil {
+				return nil, err
+			}
+			composed = spatialmath.DualQuaternion{Number: composed.Transformation(pose.(*spatialmath.DualQuaternion).Number)}
+			continue
+		}
+
+		// Resolve which entry of the input vector drives this frame, and how.
+		frameInputs := make([]Input, dof)
+		columns := make([]int, dof)
+		scale := 1.
+		if offset := m.transformChainInputOffsets[i]; offset == -1 {
+			mm := m.mimicMappings[chainFrame.Name()]
+			frameInputs[0] = mm.valueMultiplier*inputs[mm.sourceInputIdx] + mm.valueOffset
+			columns[0] = mm.sourceInputIdx
+			scale = mm.valueMultiplier
+		} else {
+			copy(frameInputs, inputs[offset:offset+dof])
+			for j := range columns {
+				columns[j] = offset + j
+			}
+		}
+
+		parentRotation := composed.Real
+		parentOrigin := composed.Point()
+		var pose spatialmath.Pose
+		switch frame := chainFrame.(type) {
+		case *rotationalFrame:
+			axis := frame.InputToOrientation(frameInputs[0])
+			pose = &spatialmath.DualQuaternion{Number: dualquat.Number{Real: axis.Quaternion()}}
+			contributions = append(contributions, contribution{
+				column:  columns[0],
+				scale:   scale,
+				angular: spatialmath.TransformPoint(parentRotation, r3.Vector{}, frame.rotAxis),
+				pivot:   parentOrigin,
+			})
+		case *translationalFrame:
+			pose = spatialmath.NewPoseFromPoint(frame.transAxis.Mul(frameInputs[0]))
+			contributions = append(contributions, contribution{
+				column: columns[0],
+				scale:  scale,
+				linear: spatialmath.TransformPoint(parentRotation, r3.Vector{}, frame.transAxis),
+			})
+		default:
+			var err error
+			pose, err = chainFrame.Transform(frameInputs)
+			if err != nil {
+				return nil, fmt.Errorf("Frame: %v.%v: %w", m.Name(), chainFrame.Name(), err)
+			}
+			// Any other frame is differentiated numerically. The twist of the frame is found in its parent frame
+			// and then rotated into the base frame, pivoting about the origin of the frame after it has moved.
+			pivot := spatialmath.TransformPoint(parentRotation, parentOrigin, pose.Point())
+			for j := range frameInputs {
+				linear, angular, err := frameTwist(chainFrame, frameInputs, j)
+				if err != nil {
+					return nil, fmt.Errorf("Frame: %v.%v: %w", m.Name(), chainFrame.Name(), err)
+				}
+				contributions = append(contributions, contribution{
+					column:  columns[j],
+					scale:   scale,
+					linear:  spatialmath.TransformPoint(parentRotation, r3.Vector{}, linear),
+					angular: spatialmath.TransformPoint(parentRotation, r3.Vector{}, angular),
+					pivot:   pivot,
+				})
+			}
+		}
+		composed = spatialmath.DualQuaternion{Number: composed.Transformation(pose.(*spatialmath.DualQuaternion).Number)}
+	}
+
+	tip := composed.Point()
+	for _, c := range contributions {
+		linear := c.linear.Add(c.angular.Cross(tip.Sub(c.pivot))).Mul(c.scale)
+		angular := c.angular.Mul(c.scale)
+		for row, v := range []float64{linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z} {
+			jac.Set(row, c.column, jac.At(row, c.column)+v)
+		}
+	}
+	return jac, nil
+}
+
+// frameTwist numerically differentiates the transform of a frame with respect to one of its inputs. It returns the
+// linear velocity of the origin of the frame and its angular velocity, both expressed in the parent frame. The
+// perturbation is kept within the limits of the frame so that frames which validate their inputs can be
+// differentiated at their limits.
+func frameTwist(frame Frame, inputs []Input, idx int) (r3.Vector, r3.Vector, error) {
+	limit := frame.DoF()[idx]
+	hi := math.Min(inputs[idx]+jacobianStep, math.Max(inputs[idx], limit.Max))
+	lo := math.Max(inputs[idx]-jacobianStep, math.Min(inputs[idx], limit.Min))
+	if hi <= lo {
+		return r3.Vector{}, r3.Vector{}, nil
+	}
+
+	perturbed := make([]Input, len(inputs))
+	copy(perturbed, inputs)
+	perturbed[idx] = hi
+	hiPose, err := frame.Transform(perturbed)
+	if err != nil {
+		return r3.Vector{}, r3.Vector{}, err
+	}
+	perturbed[idx] = lo
+	loPose, err := frame.Transform(perturbed)
+	if err != nil {
+		return r3.Vector{}, r3.Vector{}, err
+	}
+
+	step := hi - lo
+	linear := hiPose.Point().Sub(loPose.Point()).Mul(1 / step)
+	delta := quat.Mul(hiPose.Orientation().Quaternion(), quat.Conj(loPose.Orientation().Quaternion()))
+	angular := rotationVector(delta).Mul(1 / step)
+	return linear, angular, nil
+}
+
+// rotationVector returns the axis-angle rotation vector of a unit quaternion. Unlike spatialmath.QuatToR3AA, it
+// stays accurate for the very small rotations produced when differentiating.
+func rotationVector(q quat.Number) r3.Vector {
+	if q.Real < 0 {
+		q = quat.Scale(-1, q)
+	}
+	imag := r3.Vector{X: q.Imag, Y: q.Jmag, Z: q.Kmag}
+	norm := imag.Norm()
+	if norm < 1e-12 {
+		return imag.Mul(2)
+	}
+	return imag.Mul(2 * math.Atan2(norm, q.Real) / norm)
+}
@@ -0,0 +1,113 @@
+package referenceframe
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/num/quat"
+
+	spatial "go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+// numericJacobian differentiates the transform of the model with central differences.
+func numericJacobian(t *testing.T, m Model, inputs []Input) *mat.Dense {
+	t.Helper()
+	const step = 1e-4
+	jac := mat.NewDense(6, len(inputs), nil)
+	for i := range inputs {
+		perturbed := append([]Input{}, inputs...)
+		perturbed[i] = inputs[i] + step
+		hi, err := m.Transform(perturbed)
+		test.That(t, err, test.ShouldBeNil)
+		perturbed[i] = inputs[i] - step
+		lo, err := m.Transform(perturbed)
+		test.That(t, err, test.ShouldBeNil)
+
+		linear := hi.Point().Sub(lo.Point()).Mul(1 / (2 * step))
+		delta := quat.Mul(hi.Orientation().Quaternion(), quat.Conj(lo.Orientation().Quaternion()))
+		angular := spatial.QuatToR3AA(delta).Mul(1 / (2 * step))
+		jac.SetCol(i, []float64{linear.X, linear.Y, linear.Z, angular.X, angular.Y, angular.Z})
+	}
+	return jac
+}
+
+func checkJacobian(t *testing.T, m Model, inputs []Input) {
+	t.Helper()
+	jac, err := m.Jacobian(inputs)
+	test.That(t, err, test.ShouldBeNil)
+	expected := numericJacobian(t, m, inputs)
+	rows, cols := jac.Dims()
+	test.That(t, rows, test.ShouldEqual, 6)
+	test.That(t, cols, test.ShouldEqual, len(inputs))
+	for r := 0; r < rows; r++ {
+		for c := 0; c < cols; c++ {
+			test.That(t, jac.At(r, c), test.ShouldAlmostEqual, expected.At(r, c), 1e-4)
+		}
+	}
+}
+
+func TestJacobian(t *testing.T) {
+	t.Run("serial arms", func(t *testing.T) {
+		for _, file := range []string{
+			"components/arm/fake/kinematics/xarm6.json",
+			"referenceframe/testfiles/ur5e.json",
+			"referenceframe/testfiles/ur5e.urdf",
+		} {
+			m, err := KinematicModelFromFile(utils.ResolveFile(file), "")
+			test.That(t, err, test.ShouldBeNil)
+			randSeed := rand.New(rand.NewSource(1))
+			for i := 0; i < 10; i++ {
+				checkJacobian(t, m, GenerateRandomConfiguration(m, randSeed))
+			}
+		}
+	})
+
+	t.Run("gantry", func(t *testing.T) {
+		m, err := ParseModelJSONFile(utils.ResolveFile("referenceframe/testfiles/example_gantry.json"), "")
+		test.That(t, err, test.ShouldBeNil)
+		checkJacobian(t, m, GenerateRandomConfiguration(m, rand.New(rand.NewSource(1))))
+	})
+
+	t.Run("mimic joints", func(t *testing.T) {
+		m, err := ParseModelXMLFile(utils.ResolveFile("referenceframe/testfiles/test_mimic_serial.urdf"), "", nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(m.DoF()), test.ShouldEqual, 2)
+		checkJacobian(t, m, []Input{0.3, -0.2})
+
+		// The mimic joint turns back by as much as the first joint, so the first joint does not rotate the tip.
+		jac, err := m.Jacobian([]Input{0.3, -0.2})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, jac.At(4, 0), test.ShouldAlmostEqual, 0, 1e-9)
+	})
+
+	t.Run("frames without an analytic jacobian", func(t *testing.T) {
+		joint, err := NewRotationalFrame("joint", spatial.R4AA{RZ: 1}, Limit{Min: -3, Max: 3})
+		test.That(t, err, test.ShouldBeNil)
+		link, err := NewStaticFrame("link", spatial.NewPoseFromPoint(r3.Vector{X: 100}))
+		test.That(t, err, test.ShouldBeNil)
+		pose, err := NewPoseFrame("pose", nil)
+		test.That(t, err, test.ShouldBeNil)
+		tool, err := NewStaticFrame("tool", spatial.NewPoseFromPoint(r3.Vector{Y: 50, Z: 20}))
+		test.That(t, err, test.ShouldBeNil)
+		m, err := NewSerialModel("test", []Frame{NewNamedFrame(joint, "named"), link, pose, tool})
+		test.That(t, err, test.ShouldBeNil)
+		checkJacobian(t, m, []Input{0.5, 10, -20, 30, 0.2, 0.3, 0.9, 1.1})
+	})
+
+	t.Run("branches off the primary output frame", func(t *testing.T) {
+		m, err := ParseModelJSONFile(utils.ResolveFile("referenceframe/testfiles/test_gripper.json"), "")
+		test.That(t, err, test.ShouldBeNil)
+		checkJacobian(t, m, GenerateRandomConfiguration(m, rand.New(rand.NewSource(1))))
+	})
+
+	t.Run("wrong number of inputs", func(t *testing.T) {
+		m, err := ParseModelJSONFile(utils.ResolveFile("components/arm/fake/kinematics/xarm6.json"), "")
+		test.That(t, err, test.ShouldBeNil)
+		_, err = m.Jacobian([]Input{0, 0})
+		test.That(t, err, test.ShouldBeError, NewIncorrectDoFError(2, 6))
+	})
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/pkg/errors"
 	commonpb "go.viam.com/api/common/v1"
 	pb "go.viam.com/api/component/arm/v1"
+	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/num/dualquat"
 	"gonum.org/v1/gonum/num/quat"
 
@@ -21,6 +22,7 @@ import (
 type Model interface {
 	Frame
 	ModelConfig() *ModelConfigJSON
+	Jacobian(inputs []Input) (*mat.Dense, error)
 }
 
 // KinematicModelFromProtobuf returns a model from a protobuf message representing it.
@@ -4,9 +4,11 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/golang/geo/r3"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"go.viam.com/test"
 
+	"go.viam.com/rdk/pointcloud"
 	"go.viam.com/rdk/spatialmath"
 )
 
@@ -78,3 +80,38 @@ func TestString(t *testing.T) {
 
 	test.That(t, fmt.Sprint(ws), test.ShouldEqual, testTable.Render())
 }
+
+func TestWorldStateOccupancyOctree(t *testing.T) {
+	fs := NewEmptyFrameSystem("test")
+	cam, err := NewStaticFrame("cam", spatialmath.NewPoseFromPoint(r3.Vector{X: 1000}))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fs.AddFrame(cam, fs.World()), test.ShouldBeNil)
+	// Geometries are placed relative to the parent of their frame, so the map hangs from a frame under the camera.
+	mapFrame, err := NewStaticFrame("map", spatialmath.NewZeroPose())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fs.AddFrame(mapFrame, cam), test.ShouldBeNil)
+
+	octree, err := pointcloud.NewOccupancyOctree(r3.Vector{}, 200, pointcloud.DefaultOccupancyParams(10))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, octree.Set(r3.Vector{X: 5, Y: 5, Z: 5}, nil), test.ShouldBeNil)
+	octree.SetLabel("map")
+	ws, err := NewWorldState([]*GeometriesInFrame{NewGeometriesInFrame("map", []spatialmath.Geometry{octree})}, nil)
+	test.That(t, err, test.ShouldBeNil)
+
+	obstacles, err := ws.ObstaclesInWorldFrame(fs, NewZeroInputs(fs))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, obstacles.Geometries(), test.ShouldHaveLength, 1)
+	inWorld := obstacles.Geometries()[0]
+	test.That(t, inWorld.Label(), test.ShouldEqual, "map")
+	for _, tc := range []struct {
+		point    r3.Vector
+		collides bool
+	}{
+		{r3.Vector{X: 1005, Y: 5, Z: 5}, true},
+		{r3.Vector{X: 5, Y: 5, Z: 5}, false},
+	} {
+		collides, _, err := inWorld.CollidesWith(spatialmath.NewPoint(tc.point, ""), 0)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, collides, test.ShouldEqual, tc.collides)
+	}
+}
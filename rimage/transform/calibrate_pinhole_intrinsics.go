@@ -0,0 +1,384 @@
+package transform
+
+import (
+	"math"
+
+	"github.com/golang/geo/r2"
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/mat"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+// IntrinsicCalibration is the result of calibrating a camera from views of a planar target.
+type IntrinsicCalibration struct {
+	Intrinsics *PinholeCameraIntrinsics
+	Distortion *BrownConrady
+	// RMSError is the root mean square distance in pixels between where the points of every view were seen and where
+	// the calibration projects them.
+	RMSError float64
+	// ViewErrors are the root mean square errors of each view.
+	ViewErrors []float64
+	// Poses are the poses of the target in the camera frame for each view.
+	Poses []spatialmath.Pose
+}
+
+// CalibratePinholeIntrinsics finds the intrinsics and Brown-Conrady distortion of a camera from images of a planar
+// target, such as a checkerboard, taken from different angles. The object points are the points of the target on
+// the plane z = 0, and each view is where those points were seen in an image of the given size. It uses Zhang's
+// method to estimate the intrinsics without distortion, and then refines all the parameters together to minimize
+// the reprojection error.
+//
+// Z. Zhang, "A flexible new technique for camera calibration", IEEE Transactions on Pattern Analysis and Machine
+// Intelligence, 22(11):1330-1334, 2000.
+func CalibratePinholeIntrinsics(objectPoints []r2.Point, views [][]r2.Point, width, height int) (*IntrinsicCalibration, error) {
+	if len(objectPoints) < 4 {
+		return nil, errors.New("the target must have at least 4 points")
+	}
+	targetViews := make([]TargetView, len(views))
+	for i, view := range views {
+		if len(view) != len(objectPoints) {
+			return nil, errors.Errorf("view %d has %d points, but the target has %d", i, len(view), len(objectPoints))
+		}
+		targetViews[i] = TargetView{ObjectPoints: objectPoints, ImagePoints: view}
+	}
+	return CalibratePinholeIntrinsicsFromViews(targetViews, width, height)
+}
+
+// A TargetView is the part of a planar target seen in one image: the points of the target on the plane z = 0, and
+// where each of them was seen.
+type TargetView struct {
+	ObjectPoints []r2.Point
+	ImagePoints  []r2.Point
+}
+
+// CalibratePinholeIntrinsicsFromViews is CalibratePinholeIntrinsics for views that may each see different points of
+// the target, such as the corners of a ChArUco board that were identified in each image.
+func CalibratePinholeIntrinsicsFromViews(views []TargetView, width, height int) (*IntrinsicCalibration, error) {
+	if len(views) < 3 {
+		return nil, errors.Errorf("calibration needs at least 3 views of the target, got %d", len(views))
+	}
+	if width <= 0 || height <= 0 {
+		return nil, errors.Errorf("invalid image size %d by %d", width, height)
+	}
+	for i, view := range views {
+		if len(view.ImagePoints) != len(view.ObjectPoints) {
+			return nil, errors.Errorf("view %d has %d points, but %d points of the target",
+				i, len(view.ImagePoints), len(view.ObjectPoints))
+		}
+		if len(view.ObjectPoints) < 4 {
+			return nil, errors.Errorf("view %d has %d points of the target, but at least 4 are needed",
+				i, len(view.ObjectPoints))
+		}
+	}
+
+	homographies := make([]*mat.Dense, len(views))
+	for i, view := range views {
+		h, err := planeHomography(view.ObjectPoints, view.ImagePoints)
+		if err != nil {
+			return nil, errors.Wrapf(err, "view %d", i)
+		}
+		homographies[i] = h
+	}
+	k, err := zhangIntrinsics(homographies, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	// The parameters are the intrinsics, the distortion, and a rotation vector and translation for each view.
+	params := []float64{k.Fx, k.Fy, k.Ppx, k.Ppy, 0, 0, 0, 0, 0}
+	for _, h := range homographies {
+		rotation, translation := viewPose(k, h)
+		params = append(params, rotation.X, rotation.Y, rotation.Z, translation.X, translation.Y, translation.Z)
+	}
+	c := &intrinsicCalibrationProblem{views: views}
+	params = c.refine(params)
+
+	calibration := &IntrinsicCalibration{
+		Intrinsics: &PinholeCameraIntrinsics{
+			Width: width, Height: height,
+			Fx: params[0], Fy: params[1], Ppx: params[2], Ppy: params[3],
+		},
+		Distortion: &BrownConrady{
+			RadialK1: params[4], RadialK2: params[5], RadialK3: params[6],
+			TangentialP1: params[7], TangentialP2: params[8],
+		},
+	}
+	var total float64
+	var nPoints int
+	for v, view := range views {
+		var sum float64
+		for _, r := range c.viewResiduals(params, v) {
+			sum += r * r
+		}
+		total += sum
+		nPoints += len(view.ObjectPoints)
+		calibration.ViewErrors = append(calibration.ViewErrors, math.Sqrt(sum/float64(len(view.ObjectPoints))))
+		p := params[intrinsicParams+6*v:]
+		calibration.Poses = append(calibration.Poses, spatialmath.NewPose(
+			r3.Vector{X: p[3], Y: p[4], Z: p[5]},
+			rotationFromVector(r3.Vector{X: p[0], Y: p[1], Z: p[2]}),
+		))
+	}
+	calibration.RMSError = math.Sqrt(total / float64(nPoints))
+	return calibration, nil
+}
+
+// intrinsicParams is the number of parameters shared by every view: fx, fy, ppx, ppy, k1, k2, k3, p1 and p2.
+const intrinsicParams = 9
+
+type intrinsicCalibrationProblem struct {
+	views []TargetView
+}
+
+// viewResiduals returns the differences between where the points of a view project and where they were seen.
+func (c *intrinsicCalibrationProblem) viewResiduals(params []float64, view int) []float64 {
+	distortion := &BrownConrady{
+		RadialK1: params[4], RadialK2: params[5], RadialK3: params[6],
+		TangentialP1: params[7], TangentialP2: params[8],
+	}
+	p := params[intrinsicParams+6*view:]
+	rotation := rotationFromVector(r3.Vector{X: p[0], Y: p[1], Z: p[2]})
+	residuals := make([]float64, 0, 2*len(c.views[view].ObjectPoints))
+	for i, object := range c.views[view].ObjectPoints {
+		camera := rotation.Mul(r3.Vector{X: object.X, Y: object.Y}).Add(r3.Vector{X: p[3], Y: p[4], Z: p[5]})
+		x, y := distortion.Transform(camera.X/camera.Z, camera.Y/camera.Z)
+		seen := c.views[view].ImagePoints[i]
+		residuals = append(residuals, params[0]*x+params[2]-seen.X, params[1]*y+params[3]-seen.Y)
+	}
+	return residuals
+}
+
+// refine minimizes the reprojection error of every view with Levenberg-Marquardt.
+func (c *intrinsicCalibrationProblem) refine(params []float64) []float64 {
+	// offsets are where the residuals of each view start.
+	offsets := make([]int, len(c.views)+1)
+	for v, view := range c.views {
+		offsets[v+1] = offsets[v] + 2*len(view.ObjectPoints)
+	}
+	nResiduals := offsets[len(c.views)]
+	residuals := func(params []float64) []float64 {
+		all := make([]float64, 0, nResiduals)
+		for v := range c.views {
+			all = append(all, c.viewResiduals(params, v)...)
+		}
+		return all
+	}
+	cost := func(r []float64) float64 {
+		return mat.Dot(mat.NewVecDense(len(r), r), mat.NewVecDense(len(r), r))
+	}
+
+	current := residuals(params)
+	damping := 1e-3
+	for iteration := 0; iteration < 100; iteration++ {
+		// Each view's parameters only move that view's points, so only those are differenced.
+		jacobian := mat.NewDense(nResiduals, len(params), nil)
+		perturbed := append([]float64(nil), params...)
+		for j := range params {
+			step := 1e-6 * math.Max(1, math.Abs(params[j]))
+			perturbed[j] = params[j] + step
+			if j < intrinsicParams {
+				for i, r := range residuals(perturbed) {
+					jacobian.Set(i, j, (r-current[i])/step)
+				}
+			} else {
+				v := (j - intrinsicParams) / 6
+				for i, r := range c.viewResiduals(perturbed, v) {
+					jacobian.Set(offsets[v]+i, j, (r-current[offsets[v]+i])/step)
+				}
+			}
+			perturbed[j] = params[j]
+		}
+
+		var normal mat.Dense
+		normal.Mul(jacobian.T(), jacobian)
+		var gradient mat.VecDense
+		gradient.MulVec(jacobian.T(), mat.NewVecDense(nResiduals, current))
+		improved := false
+		for attempt := 0; attempt < 10 && !improved; attempt++ {
+			damped := mat.DenseCopyOf(&normal)
+			for j := range params {
+				damped.Set(j, j, normal.At(j, j)*(1+damping))
+			}
+			var step mat.VecDense
+			if err := step.SolveVec(damped, &gradient); err != nil {
+				damping *= 10
+				continue
+			}
+			next := make([]float64, len(params))
+			for j := range next {
+				next[j] = params[j] - step.AtVec(j)
+			}
+			nextResiduals := residuals(next)
+			if nextCost := cost(nextResiduals); nextCost < cost(current) {
+				converged := cost(current)-nextCost < 1e-12*cost(current)
+				params, current, improved = next, nextResiduals, true
+				damping = math.Max(damping/10, 1e-12)
+				if converged {
+					return params
+				}
+			} else {
+				damping *= 10
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return params
+}
+
+// planeHomography returns the homography from points on a plane to where they were seen, by the normalized direct
+// linear transform.
+func planeHomography(from, to []r2.Point) (*mat.Dense, error) {
+	normalization := func(points []r2.Point) *mat.Dense {
+		var centroid r2.Point
+		for _, p := range points {
+			centroid = centroid.Add(p)
+		}
+		centroid = centroid.Mul(1 / float64(len(points)))
+		var spread float64
+		for _, p := range points {
+			spread += p.Sub(centroid).Norm()
+		}
+		scale := math.Sqrt2 / math.Max(spread/float64(len(points)), 1e-12)
+		return mat.NewDense(3, 3, []float64{scale, 0, -scale * centroid.X, 0, scale, -scale * centroid.Y, 0, 0, 1})
+	}
+	apply := func(t *mat.Dense, p r2.Point) r2.Point {
+		return r2.Point{X: t.At(0, 0)*p.X + t.At(0, 2), Y: t.At(1, 1)*p.Y + t.At(1, 2)}
+	}
+	tFrom, tTo := normalization(from), normalization(to)
+	a := mat.NewDense(2*len(from), 9, nil)
+	for i := range from {
+		p, q := apply(tFrom, from[i]), apply(tTo, to[i])
+		a.SetRow(2*i, []float64{p.X, p.Y, 1, 0, 0, 0, -q.X * p.X, -q.X * p.Y, -q.X})
+		a.SetRow(2*i+1, []float64{0, 0, 0, p.X, p.Y, 1, -q.Y * p.X, -q.Y * p.Y, -q.Y})
+	}
+	var svd mat.SVD
+	if !svd.Factorize(a, mat.SVDFullV) {
+		return nil, errors.New("could not estimate the homography of the target")
+	}
+	var v mat.Dense
+	svd.VTo(&v)
+	normalized := mat.NewDense(3, 3, mat.Col(nil, 8, &v))
+
+	// Undo the normalization: H = tTo⁻¹ * normalized * tFrom.
+	var toInverse, h mat.Dense
+	if err := toInverse.Inverse(tTo); err != nil {
+		return nil, err
+	}
+	h.Product(&toInverse, normalized, tFrom)
+	if math.Abs(h.At(2, 2)) < 1e-12 {
+		return nil, errors.New("the target's homography is degenerate")
+	}
+	h.Scale(1/h.At(2, 2), &h)
+	return &h, nil
+}
+
+// zhangIntrinsics estimates the intrinsics of a camera without skew from the homographies of views of a plane.
+func zhangIntrinsics(homographies []*mat.Dense, width, height int) (*PinholeCameraIntrinsics, error) {
+	// Work in pixel coordinates scaled to about one, for a well conditioned system.
+	scale := float64(max(width, height))
+	normalize := mat.NewDense(3, 3, []float64{1 / scale, 0, -float64(width) / 2 / scale, 0, 1 / scale, -float64(height) / 2 / scale, 0, 0, 1})
+	v := mat.NewDense(2*len(homographies)+1, 6, nil)
+	for i, homography := range homographies {
+		var h mat.Dense
+		h.Mul(normalize, homography)
+		row := func(a, b int) []float64 {
+			return []float64{
+				h.At(0, a) * h.At(0, b),
+				h.At(0, a)*h.At(1, b) + h.At(1, a)*h.At(0, b),
+				h.At(1, a) * h.At(1, b),
+				h.At(2, a)*h.At(0, b) + h.At(0, a)*h.At(2, b),
+				h.At(2, a)*h.At(1, b) + h.At(1, a)*h.At(2, b),
+				h.At(2, a) * h.At(2, b),
+			}
+		}
+		v.SetRow(2*i, row(0, 1))
+		v11, v22 := row(0, 0), row(1, 1)
+		for j := range v11 {
+			v11[j] -= v22[j]
+		}
+		v.SetRow(2*i+1, v11)
+	}
+	// There is no skew.
+	v.SetRow(2*len(homographies), []float64{0, 1, 0, 0, 0, 0})
+
+	var svd mat.SVD
+	if !svd.Factorize(v, mat.SVDFullV) {
+		return nil, errors.New("could not estimate the intrinsics")
+	}
+	var vectors mat.Dense
+	svd.VTo(&vectors)
+	b := mat.Col(nil, 5, &vectors)
+	if b[0] < 0 {
+		for i := range b {
+			b[i] = -b[i]
+		}
+	}
+	b11, b12, b22, b13, b23, b33 := b[0], b[1], b[2], b[3], b[4], b[5]
+	denominator := b11*b22 - b12*b12
+	v0 := (b12*b13 - b11*b23) / denominator
+	lambda := b33 - (b13*b13+v0*(b12*b13-b11*b23))/b11
+	alpha := math.Sqrt(lambda / b11)
+	beta := math.Sqrt(lambda * b11 / denominator)
+	u0 := -b13 * alpha * alpha / lambda
+	for _, value := range []float64{alpha, beta, u0, v0} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, errors.New("could not estimate the intrinsics, the views must tilt the target in different directions")
+		}
+	}
+	return &PinholeCameraIntrinsics{
+		Width:  width,
+		Height: height,
+		Fx:     alpha * scale,
+		Fy:     beta * scale,
+		Ppx:    u0*scale + float64(width)/2,
+		Ppy:    v0*scale + float64(height)/2,
+	}, nil
+}
+
+// viewPose returns the rotation vector and translation of a plane seen through the homography by a camera with the
+// given intrinsics.
+func viewPose(k *PinholeCameraIntrinsics, h *mat.Dense) (r3.Vector, r3.Vector) {
+	column := func(c int) r3.Vector {
+		return r3.Vector{
+			X: (h.At(0, c) - k.Ppx*h.At(2, c)) / k.Fx,
+			Y: (h.At(1, c) - k.Ppy*h.At(2, c)) / k.Fy,
+			Z: h.At(2, c),
+		}
+	}
+	x, y, t := column(0), column(1), column(2)
+	lambda := 1 / x.Norm()
+	if t.Z < 0 {
+		lambda = -lambda
+	}
+	x, y, t = x.Mul(lambda), y.Mul(lambda), t.Mul(lambda)
+	z := x.Cross(y)
+
+	// Find the nearest rotation to the estimate.
+	var svd mat.SVD
+	estimate := mat.NewDense(3, 3, []float64{x.X, y.X, z.X, x.Y, y.Y, z.Y, x.Z, y.Z, z.Z})
+	if !svd.Factorize(estimate, mat.SVDFull) {
+		return r3.Vector{}, t
+	}
+	var u, v, rotation mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+	rotation.Mul(&u, v.T())
+	rm, err := spatialmath.NewRotationMatrix(rotation.RawMatrix().Data)
+	if err != nil {
+		return r3.Vector{}, t
+	}
+	return rm.AxisAngles().ToR3(), t
+}
+
+// rotationFromVector returns the rotation matrix of a rotation vector.
+func rotationFromVector(v r3.Vector) *spatialmath.RotationMatrix {
+	theta := v.Norm()
+	if theta < 1e-15 {
+		return spatialmath.NewZeroOrientation().RotationMatrix()
+	}
+	return (&spatialmath.R4AA{Theta: theta, RX: v.X / theta, RY: v.Y / theta, RZ: v.Z / theta}).RotationMatrix()
+}
@@ -0,0 +1,98 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/golang/geo/r2"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+func TestCalibratePinholeIntrinsics(t *testing.T) {
+	views := testViews()
+	object := CheckerboardObjectPoints(views[0].cols, views[0].rows, views[0].square)
+	expected := views[0].intrinsics
+
+	t.Run("exact points", func(t *testing.T) {
+		var seen [][]r2.Point
+		for _, view := range views {
+			var points []r2.Point
+			for _, p := range object {
+				points = append(points, view.project(p))
+			}
+			seen = append(seen, points)
+		}
+		calibration, err := CalibratePinholeIntrinsics(object, seen, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, calibration.RMSError, test.ShouldBeLessThan, 1e-3)
+		test.That(t, calibration.Intrinsics.Fx, test.ShouldAlmostEqual, expected.Fx, 1e-2)
+		test.That(t, calibration.Intrinsics.Fy, test.ShouldAlmostEqual, expected.Fy, 1e-2)
+		test.That(t, calibration.Intrinsics.Ppx, test.ShouldAlmostEqual, expected.Ppx, 1e-2)
+		test.That(t, calibration.Intrinsics.Ppy, test.ShouldAlmostEqual, expected.Ppy, 1e-2)
+		test.That(t, calibration.Distortion.RadialK1, test.ShouldAlmostEqual, views[0].distortion.RadialK1, 1e-3)
+		test.That(t, calibration.Distortion.TangentialP1, test.ShouldAlmostEqual, views[0].distortion.TangentialP1, 1e-4)
+		test.That(t, calibration.ViewErrors, test.ShouldHaveLength, len(views))
+		for i, pose := range calibration.Poses {
+			test.That(t, spatialmath.PoseAlmostEqualEps(pose, views[i].pose, 1e-2), test.ShouldBeTrue)
+		}
+	})
+
+	t.Run("detected corners", func(t *testing.T) {
+		var seen [][]r2.Point
+		for i, view := range views {
+			corners, err := FindCheckerboardCorners(renderedTestViews()[i], view.cols, view.rows)
+			test.That(t, err, test.ShouldBeNil)
+			seen = append(seen, corners)
+		}
+		calibration, err := CalibratePinholeIntrinsics(object, seen, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, calibration.RMSError, test.ShouldBeLessThan, 0.2)
+		test.That(t, calibration.Intrinsics.Fx, test.ShouldAlmostEqual, expected.Fx, 0.01*expected.Fx)
+		test.That(t, calibration.Intrinsics.Fy, test.ShouldAlmostEqual, expected.Fy, 0.01*expected.Fy)
+		test.That(t, calibration.Intrinsics.Ppx, test.ShouldAlmostEqual, expected.Ppx, 3)
+		test.That(t, calibration.Intrinsics.Ppy, test.ShouldAlmostEqual, expected.Ppy, 3)
+		test.That(t, calibration.Distortion.RadialK1, test.ShouldAlmostEqual, views[0].distortion.RadialK1, 0.03)
+	})
+
+	t.Run("partial views", func(t *testing.T) {
+		// Each view sees a different part of the board.
+		var targetViews []TargetView
+		for i, view := range views {
+			var targetView TargetView
+			for j, p := range object {
+				if (j+i)%3 != 0 {
+					targetView.ObjectPoints = append(targetView.ObjectPoints, p)
+					targetView.ImagePoints = append(targetView.ImagePoints, view.project(p))
+				}
+			}
+			targetViews = append(targetViews, targetView)
+		}
+		calibration, err := CalibratePinholeIntrinsicsFromViews(targetViews, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, calibration.RMSError, test.ShouldBeLessThan, 1e-3)
+		test.That(t, calibration.Intrinsics.Fx, test.ShouldAlmostEqual, expected.Fx, 1e-2)
+		test.That(t, calibration.Intrinsics.Ppy, test.ShouldAlmostEqual, expected.Ppy, 1e-2)
+		test.That(t, calibration.Distortion.RadialK1, test.ShouldAlmostEqual, views[0].distortion.RadialK1, 1e-3)
+
+		targetViews[1].ImagePoints = targetViews[1].ImagePoints[1:]
+		_, err = CalibratePinholeIntrinsicsFromViews(targetViews, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = CalibratePinholeIntrinsicsFromViews([]TargetView{
+			targetViews[0], targetViews[2], {ObjectPoints: object[:3], ImagePoints: object[:3]},
+		}, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := CalibratePinholeIntrinsics(object, nil, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = CalibratePinholeIntrinsics(object[:3], [][]r2.Point{{}, {}, {}}, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = CalibratePinholeIntrinsics(object, [][]r2.Point{object, object, object[1:]}, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldNotBeNil)
+		// Views that all face the camera squarely cannot tell the focal length from the distance.
+		_, err = CalibratePinholeIntrinsics(object, [][]r2.Point{object, object, object}, expected.Width, expected.Height)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,424 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/r2"
+	"github.com/pkg/errors"
+)
+
+// ErrCheckerboardNotFound is returned when the whole checkerboard cannot be found in an image.
+var ErrCheckerboardNotFound = errors.New("checkerboard not found")
+
+// FindCheckerboardCorners returns the inner corners of a checkerboard that has cols by rows inner corners, that is
+// cols+1 by rows+1 squares, to sub-pixel accuracy with the center of the top left pixel at (0, 0). The corners are
+// in row major order, so that they match the points of CheckerboardObjectPoints, starting from the corner closest to
+// the top left of the image. Rows run along the side of the board with cols corners. The whole board must be
+// visible, and its squares at least 10 pixels wide.
+func FindCheckerboardCorners(img image.Image, cols, rows int) ([]r2.Point, error) {
+	if cols < 2 || rows < 2 {
+		return nil, errors.Errorf("a checkerboard must have at least 2 by 2 inner corners, not %d by %d", cols, rows)
+	}
+	gray := newGrayPlane(img)
+	candidates := saddlePoints(gray.blur(1.5), gray.blur(1))
+	if len(candidates) < cols*rows {
+		return nil, ErrCheckerboardNotFound
+	}
+	grid, ok := growGrid(candidates)
+	if !ok {
+		return nil, ErrCheckerboardNotFound
+	}
+	return orderGrid(grid, cols, rows)
+}
+
+// CheckerboardObjectPoints returns the inner corners of a checkerboard on the plane z = 0, in row major order, with
+// the given distance between corners.
+func CheckerboardObjectPoints(cols, rows int, squareSize float64) []r2.Point {
+	points := make([]r2.Point, 0, cols*rows)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			points = append(points, r2.Point{X: float64(col) * squareSize, Y: float64(row) * squareSize})
+		}
+	}
+	return points
+}
+
+// RefineCheckerboardCorners moves guesses of where four squares of a checkerboard meet in an image to sub-pixel
+// accuracy, as FindCheckerboardCorners does for the corners it finds. It reports for each guess whether such a corner
+// was found within a few pixels of it; the corners that were not found are left where they were guessed.
+func RefineCheckerboardCorners(img image.Image, guesses []r2.Point) ([]r2.Point, []bool) {
+	gray := newGrayPlane(img)
+	blurred, sharp := gray.blur(1.5), gray.blur(1)
+	corners := make([]r2.Point, len(guesses))
+	found := make([]bool, len(guesses))
+	for i, guess := range guesses {
+		p, ok := sharp.refineSaddle(guess)
+		if ok && blurred.isCheckerCorner(p) {
+			corners[i], found[i] = p, true
+		} else {
+			corners[i] = guess
+		}
+	}
+	return corners, found
+}
+
+// grayPlane is the brightness of each pixel of an image.
+type grayPlane struct {
+	width, height int
+	pix           []float64
+}
+
+func newGrayPlane(img image.Image) *grayPlane {
+	bounds := img.Bounds()
+	g := &grayPlane{width: bounds.Dx(), height: bounds.Dy(), pix: make([]float64, bounds.Dx()*bounds.Dy())}
+	for y := 0; y < g.height; y++ {
+		for x := 0; x < g.width; x++ {
+			g.pix[y*g.width+x] = float64(color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y)
+		}
+	}
+	return g
+}
+
+// blur returns the image after a Gaussian blur.
+func (g *grayPlane) blur(sigma float64) *grayPlane {
+	w, h := g.width, g.height
+	radius := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*radius+1)
+	var sum float64
+	for i := range kernel {
+		d := float64(i - radius)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+	for i := range kernel {
+		kernel[i] /= sum
+	}
+	clamp := func(v, hi int) int {
+		return min(max(v, 0), hi-1)
+	}
+	horizontal := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float64
+			for i, k := range kernel {
+				v += k * g.pix[y*w+clamp(x+i-radius, w)]
+			}
+			horizontal[y*w+x] = v
+		}
+	}
+	blurred := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v float64
+			for i, k := range kernel {
+				v += k * horizontal[clamp(y+i-radius, h)*w+x]
+			}
+			blurred[y*w+x] = v
+		}
+	}
+	return &grayPlane{width: w, height: h, pix: blurred}
+}
+
+func (g *grayPlane) at(x, y int) float64 {
+	return g.pix[min(max(y, 0), g.height-1)*g.width+min(max(x, 0), g.width-1)]
+}
+
+// interpolate returns the bilinearly interpolated brightness at a point, with pixel centers at integer coordinates.
+func (g *grayPlane) interpolate(p r2.Point) float64 {
+	x0, y0 := math.Floor(p.X), math.Floor(p.Y)
+	fx, fy := p.X-x0, p.Y-y0
+	ix, iy := int(x0), int(y0)
+	top := g.at(ix, iy)*(1-fx) + g.at(ix+1, iy)*fx
+	bottom := g.at(ix, iy+1)*(1-fx) + g.at(ix+1, iy+1)*fx
+	return top*(1-fy) + bottom*fy
+}
+
+const (
+	// saddleRadius is the radius in pixels of the circle around a corner whose brightness must alternate dark, light,
+	// dark, light like the squares of a checkerboard.
+	saddleRadius = 4
+	// minSaddleContrast is the smallest difference in brightness between the squares around a corner.
+	minSaddleContrast = 25
+)
+
+// saddlePoints returns the points where the blurred image curves up one way and down the other, as it does where
+// four squares of a checkerboard meet, refined to sub-pixel accuracy on the sharp image.
+func saddlePoints(g, sharp *grayPlane) []r2.Point {
+	w, h := g.width, g.height
+	response := make([]float64, w*h)
+	var maxResponse float64
+	for y := 1; y < h-1; y++ {
+		for x := 1; x < w-1; x++ {
+			dxx := g.at(x+1, y) - 2*g.at(x, y) + g.at(x-1, y)
+			dyy := g.at(x, y+1) - 2*g.at(x, y) + g.at(x, y-1)
+			dxy := (g.at(x+1, y+1) - g.at(x+1, y-1) - g.at(x-1, y+1) + g.at(x-1, y-1)) / 4
+			response[y*w+x] = dxy*dxy - dxx*dyy
+			maxResponse = math.Max(maxResponse, response[y*w+x])
+		}
+	}
+	if maxResponse <= 0 {
+		return nil
+	}
+
+	var points []r2.Point
+	const suppression = 3
+	margin := saddleRadius + 2
+	for y := margin; y < h-margin; y++ {
+		for x := margin; x < w-margin; x++ {
+			r := response[y*w+x]
+			if r < 0.05*maxResponse {
+				continue
+			}
+			isMax := true
+			for dy := -suppression; dy <= suppression && isMax; dy++ {
+				for dx := -suppression; dx <= suppression; dx++ {
+					other := response[(y+dy)*w+x+dx]
+					if other > r || (other == r && (dy < 0 || (dy == 0 && dx < 0))) {
+						isMax = false
+						break
+					}
+				}
+			}
+			if !isMax {
+				continue
+			}
+			p, ok := sharp.refineSaddle(r2.Point{X: float64(x), Y: float64(y)})
+			if ok && g.isCheckerCorner(p) {
+				points = append(points, p)
+			}
+		}
+	}
+
+	// Refinement can move neighboring maxima onto the same corner.
+	var merged []r2.Point
+	for _, p := range points {
+		duplicate := false
+		for _, q := range merged {
+			if p.Sub(q).Norm() < 2 {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			merged = append(merged, p)
+		}
+	}
+	return merged
+}
+
+// isCheckerCorner returns whether the brightness on a circle around the point alternates between dark and light
+// exactly twice, with enough contrast.
+func (g *grayPlane) isCheckerCorner(p r2.Point) bool {
+	const samples = 32
+	values := make([]float64, samples)
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := range values {
+		angle := 2 * math.Pi * float64(i) / samples
+		values[i] = g.interpolate(p.Add(r2.Point{X: math.Cos(angle), Y: math.Sin(angle)}.Mul(saddleRadius)))
+		lo, hi = math.Min(lo, values[i]), math.Max(hi, values[i])
+	}
+	if hi-lo < minSaddleContrast {
+		return false
+	}
+	threshold := (lo + hi) / 2
+	changes := 0
+	for i, v := range values {
+		if (v > threshold) != (values[(i+1)%samples] > threshold) {
+			changes++
+		}
+	}
+	return changes == 4
+}
+
+// refineSaddle moves the point to where the image gradients around it are all perpendicular to the directions
+// from it, as they are at the corner between four squares.
+func (g *grayPlane) refineSaddle(p r2.Point) (r2.Point, bool) {
+	const window = 5
+	start := p
+	for iteration := 0; iteration < 10; iteration++ {
+		cx, cy := int(math.Round(p.X)), int(math.Round(p.Y))
+		var a11, a12, a22, b1, b2 float64
+		for dy := -window; dy <= window; dy++ {
+			for dx := -window; dx <= window; dx++ {
+				x, y := cx+dx, cy+dy
+				gx := (g.at(x+1, y) - g.at(x-1, y)) / 2
+				gy := (g.at(x, y+1) - g.at(x, y-1)) / 2
+				weight := math.Exp(-float64(dx*dx+dy*dy) / (window * window))
+				a11 += weight * gx * gx
+				a12 += weight * gx * gy
+				a22 += weight * gy * gy
+				b1 += weight * (gx*gx*float64(x) + gx*gy*float64(y))
+				b2 += weight * (gx*gy*float64(x) + gy*gy*float64(y))
+			}
+		}
+		det := a11*a22 - a12*a12
+		if det <= 1e-9 {
+			return p, false
+		}
+		next := r2.Point{X: (a22*b1 - a12*b2) / det, Y: (a11*b2 - a12*b1) / det}
+		moved := next.Sub(p).Norm()
+		p = next
+		if moved < 0.01 {
+			break
+		}
+	}
+	return p, p.Sub(start).Norm() < window
+}
+
+// gridCell is a corner placed at integer coordinates on the grid of the checkerboard, with the steps to its
+// neighbors along each axis of the grid.
+type gridCell struct {
+	point     r2.Point
+	stepI     r2.Point
+	stepJ     r2.Point
+	candidate int
+}
+
+// growGrid places the candidates on a grid, starting from the one nearest their middle and repeatedly looking for
+// a neighbor of a placed corner one step away along either axis. It returns the placed corners keyed by grid
+// coordinates, if every candidate near the grid was placed on it.
+func growGrid(candidates []r2.Point) (map[[2]int]*gridCell, bool) {
+	var centroid r2.Point
+	for _, p := range candidates {
+		centroid = centroid.Add(p)
+	}
+	centroid = centroid.Mul(1 / float64(len(candidates)))
+	byDistance := func(from r2.Point) []int {
+		order := make([]int, len(candidates))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool {
+			return candidates[order[a]].Sub(from).Norm() < candidates[order[b]].Sub(from).Norm()
+		})
+		return order
+	}
+	seed := byDistance(centroid)[0]
+	neighbors := byDistance(candidates[seed])
+	if len(neighbors) < 3 {
+		return nil, false
+	}
+	// The nearest corners to a corner are its neighbors along the grid, so the nearest gives one axis and the next
+	// nearest that is not along it gives the other.
+	stepI := candidates[neighbors[1]].Sub(candidates[seed])
+	var stepJ r2.Point
+	for _, n := range neighbors[2:] {
+		step := candidates[n].Sub(candidates[seed])
+		if math.Abs(step.Normalize().Dot(stepI.Normalize())) < 0.5 {
+			stepJ = step
+			break
+		}
+	}
+	if stepJ.Norm() == 0 {
+		return nil, false
+	}
+
+	grid := map[[2]int]*gridCell{{0, 0}: {point: candidates[seed], stepI: stepI, stepJ: stepJ, candidate: seed}}
+	used := map[int]bool{seed: true}
+	queue := [][2]int{{0, 0}}
+	for len(queue) > 0 {
+		at := queue[0]
+		queue = queue[1:]
+		cell := grid[at]
+		for _, move := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			next := [2]int{at[0] + move[0], at[1] + move[1]}
+			if _, ok := grid[next]; ok {
+				continue
+			}
+			step := cell.stepI.Mul(float64(move[0])).Add(cell.stepJ.Mul(float64(move[1])))
+			predicted := cell.point.Add(step)
+			// Continue straight lines, which bend with perspective, from the corner on the other side.
+			if opposite, ok := grid[[2]int{at[0] - move[0], at[1] - move[1]}]; ok {
+				predicted = cell.point.Mul(2).Sub(opposite.point)
+			}
+			tolerance := 0.3 * math.Min(cell.stepI.Norm(), cell.stepJ.Norm())
+			best, bestDistance := -1, tolerance
+			for i, p := range candidates {
+				if d := p.Sub(predicted).Norm(); d < bestDistance {
+					best, bestDistance = i, d
+				}
+			}
+			if best < 0 {
+				continue
+			}
+			if used[best] {
+				// The grid closed on a corner already placed elsewhere, so it is not a grid.
+				return nil, false
+			}
+			found := candidates[best]
+			placed := &gridCell{point: found, stepI: cell.stepI, stepJ: cell.stepJ, candidate: best}
+			if move[0] != 0 {
+				placed.stepI = found.Sub(cell.point).Mul(float64(move[0]))
+			} else {
+				placed.stepJ = found.Sub(cell.point).Mul(float64(move[1]))
+			}
+			grid[next] = placed
+			used[best] = true
+			queue = append(queue, next)
+		}
+	}
+	return grid, true
+}
+
+// orderGrid returns the corners of the grid in row major order if the grid is the size of the checkerboard.
+func orderGrid(grid map[[2]int]*gridCell, cols, rows int) ([]r2.Point, error) {
+	minI, minJ, maxI, maxJ := math.MaxInt, math.MaxInt, math.MinInt, math.MinInt
+	for at := range grid {
+		minI, minJ = min(minI, at[0]), min(minJ, at[1])
+		maxI, maxJ = max(maxI, at[0]), max(maxJ, at[1])
+	}
+	width, height := maxI-minI+1, maxJ-minJ+1
+	if len(grid) != width*height {
+		return nil, ErrCheckerboardNotFound
+	}
+	if width*height != cols*rows || (width != cols && width != rows) {
+		return nil, errors.Wrapf(ErrCheckerboardNotFound, "found a %d by %d grid of corners instead of %d by %d",
+			width, height, cols, rows)
+	}
+
+	// Each way of laying the board's rows and columns over the grid is a function from board coordinates to grid
+	// coordinates. Those that mirror the board are ruled out, and of the rest the one whose first corner is closest
+	// to the top left of the image is used.
+	type layout func(col, row int) [2]int
+	var layouts []layout
+	if width == cols {
+		layouts = append(layouts,
+			func(col, row int) [2]int { return [2]int{minI + col, minJ + row} },
+			func(col, row int) [2]int { return [2]int{maxI - col, maxJ - row} },
+			func(col, row int) [2]int { return [2]int{maxI - col, minJ + row} },
+			func(col, row int) [2]int { return [2]int{minI + col, maxJ - row} },
+		)
+	}
+	if height == cols {
+		layouts = append(layouts,
+			func(col, row int) [2]int { return [2]int{minI + row, minJ + col} },
+			func(col, row int) [2]int { return [2]int{maxI - row, maxJ - col} },
+			func(col, row int) [2]int { return [2]int{maxI - row, minJ + col} },
+			func(col, row int) [2]int { return [2]int{minI + row, maxJ - col} },
+		)
+	}
+	var best []r2.Point
+	for _, l := range layouts {
+		at := func(col, row int) r2.Point { return grid[l(col, row)].point }
+		// The board's columns run to the right of its rows when it is seen from the front.
+		if at(1, 0).Sub(at(0, 0)).Cross(at(0, 1).Sub(at(0, 0))) <= 0 {
+			continue
+		}
+		if best != nil && at(0, 0).X+at(0, 0).Y >= best[0].X+best[0].Y {
+			continue
+		}
+		best = make([]r2.Point, 0, cols*rows)
+		for row := 0; row < rows; row++ {
+			for col := 0; col < cols; col++ {
+				best = append(best, at(col, row))
+			}
+		}
+	}
+	if best == nil {
+		return nil, ErrCheckerboardNotFound
+	}
+	return best, nil
+}
@@ -0,0 +1,169 @@
+package transform
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/golang/geo/r2"
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/spatialmath"
+)
+
+// checkerboardView is a checkerboard seen by a camera.
+type checkerboardView struct {
+	intrinsics *PinholeCameraIntrinsics
+	distortion *BrownConrady
+	cols, rows int
+	square     float64
+	pose       spatialmath.Pose
+}
+
+// project returns where the camera sees a point on the board.
+func (v *checkerboardView) project(p r2.Point) r2.Point {
+	camera := spatialmath.Compose(v.pose, spatialmath.NewPoseFromPoint(r3.Vector{X: p.X, Y: p.Y})).Point()
+	x, y := v.distortion.Transform(camera.X/camera.Z, camera.Y/camera.Z)
+	return r2.Point{X: v.intrinsics.Fx*x + v.intrinsics.Ppx, Y: v.intrinsics.Fy*y + v.intrinsics.Ppy}
+}
+
+// renderOnce caches the rendering of each view, which is slow.
+var (
+	renderOnce sync.Once
+	rendered   []*image.Gray
+)
+
+// renderedTestViews returns the renderings of testViews.
+func renderedTestViews() []*image.Gray {
+	renderOnce.Do(func() {
+		for _, view := range testViews() {
+			rendered = append(rendered, view.render())
+		}
+	})
+	return rendered
+}
+
+// render draws the board with a white margin one square wide on a gray background.
+func (v *checkerboardView) render() *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, v.intrinsics.Width, v.intrinsics.Height))
+	undistort := &InverseBrownConrady{
+		RadialK1: v.distortion.RadialK1, RadialK2: v.distortion.RadialK2, RadialK3: v.distortion.RadialK3,
+		TangentialP1: v.distortion.TangentialP1, TangentialP2: v.distortion.TangentialP2,
+	}
+	rotation := v.pose.Orientation().RotationMatrix()
+	normal := rotation.Col(2)
+	for py := 0; py < v.intrinsics.Height; py++ {
+		for px := 0; px < v.intrinsics.Width; px++ {
+			var sum float64
+			for sample := 0; sample < 16; sample++ {
+				x, y := undistort.Transform(
+					(float64(px)+(float64(sample%4)-1.5)/4-v.intrinsics.Ppx)/v.intrinsics.Fx,
+					(float64(py)+(float64(sample/4)-1.5)/4-v.intrinsics.Ppy)/v.intrinsics.Fy,
+				)
+				ray := r3.Vector{X: x, Y: y, Z: 1}
+				onBoard := ray.Mul(normal.Dot(v.pose.Point()) / normal.Dot(ray)).Sub(v.pose.Point())
+				board := rotation.Transpose().Mul(onBoard)
+				col := int(math.Floor(board.X/v.square)) + 1
+				row := int(math.Floor(board.Y/v.square)) + 1
+				value := 120.
+				switch {
+				case col >= 0 && row >= 0 && col <= v.cols && row <= v.rows:
+					value = 220
+					if (col+row)%2 == 0 {
+						value = 30
+					}
+				case col >= -1 && row >= -1 && col <= v.cols+1 && row <= v.rows+1:
+					value = 220
+				}
+				sum += value
+			}
+			img.SetGray(px, py, color.Gray{Y: uint8(sum / 16)})
+		}
+	}
+	return img
+}
+
+// testViews returns views of a 9 by 6 checkerboard from different angles.
+func testViews() []*checkerboardView {
+	intrinsics := &PinholeCameraIntrinsics{Width: 640, Height: 480, Fx: 520, Fy: 515, Ppx: 322, Ppy: 238}
+	distortion := &BrownConrady{RadialK1: -0.15, RadialK2: 0.05, TangentialP1: 0.001, TangentialP2: -0.0005}
+	center := r3.Vector{X: 4 * 25, Y: 2.5 * 25}
+	var views []*checkerboardView
+	for _, placement := range []struct{ rotation, position r3.Vector }{
+		{r3.Vector{X: 0.3}, r3.Vector{Z: 500}},
+		{r3.Vector{X: -0.3, Y: 0.1}, r3.Vector{X: 40, Z: 520}},
+		{r3.Vector{Y: 0.35, Z: 0.1}, r3.Vector{Y: -30, Z: 480}},
+		{r3.Vector{X: 0.1, Y: -0.35}, r3.Vector{X: -40, Y: 20, Z: 500}},
+		{r3.Vector{X: 0.25, Y: 0.25, Z: 0.2}, r3.Vector{Z: 550}},
+		{r3.Vector{X: -0.2, Y: -0.2, Z: -0.1}, r3.Vector{X: 20, Y: 30, Z: 450}},
+	} {
+		rotation := rotationFromVector(placement.rotation)
+		views = append(views, &checkerboardView{
+			intrinsics: intrinsics,
+			distortion: distortion,
+			cols:       9,
+			rows:       6,
+			square:     25,
+			pose:       spatialmath.NewPose(placement.position.Sub(rotation.Mul(center)), rotation),
+		})
+	}
+	return views
+}
+
+func TestFindCheckerboardCorners(t *testing.T) {
+	for i, view := range testViews() {
+		corners, err := FindCheckerboardCorners(renderedTestViews()[i], view.cols, view.rows)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, corners, test.ShouldHaveLength, view.cols*view.rows)
+		for j, object := range CheckerboardObjectPoints(view.cols, view.rows, view.square) {
+			expected := view.project(object)
+			if d := corners[j].Sub(expected).Norm(); d > 0.3 {
+				t.Fatalf("view %d corner %d is at %v, %.2f pixels from %v", i, j, corners[j], d, expected)
+			}
+		}
+	}
+
+	view := testViews()[0]
+	img := image.NewGray(renderedTestViews()[0].Bounds())
+	copy(img.Pix, renderedTestViews()[0].Pix)
+	_, err := FindCheckerboardCorners(img, 8, 6)
+	test.That(t, err, test.ShouldWrap, ErrCheckerboardNotFound)
+	corners, err := FindCheckerboardCorners(img, 6, 9)
+	test.That(t, err, test.ShouldBeNil)
+	// Read the other way without mirroring it, the first row runs up the left side of the board.
+	test.That(t, corners[0].Sub(view.project(r2.Point{X: 0, Y: 125})).Norm(), test.ShouldBeLessThan, 0.3)
+	test.That(t, corners[1].Sub(view.project(r2.Point{X: 0, Y: 100})).Norm(), test.ShouldBeLessThan, 0.3)
+
+	// Hide part of the board.
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < 320; x++ {
+			img.SetGray(x, y, color.Gray{Y: 120})
+		}
+	}
+	_, err = FindCheckerboardCorners(img, 9, 6)
+	test.That(t, err, test.ShouldWrap, ErrCheckerboardNotFound)
+	_, err = FindCheckerboardCorners(image.NewGray(image.Rect(0, 0, 100, 100)), 9, 6)
+	test.That(t, err, test.ShouldWrap, ErrCheckerboardNotFound)
+	_, err = FindCheckerboardCorners(img, 1, 6)
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestRefineCheckerboardCorners(t *testing.T) {
+	view := testViews()[0]
+	expected := []r2.Point{view.project(r2.Point{}), view.project(r2.Point{X: 100, Y: 50})}
+	guesses := []r2.Point{
+		expected[0].Add(r2.Point{X: 1.5, Y: -1}),
+		expected[1].Add(r2.Point{X: -1, Y: 1.5}),
+		// The middle of a square.
+		view.project(r2.Point{X: 12.5, Y: 12.5}),
+	}
+	corners, found := RefineCheckerboardCorners(renderedTestViews()[0], guesses)
+	test.That(t, found, test.ShouldResemble, []bool{true, true, false})
+	for i, p := range expected {
+		test.That(t, corners[i].Sub(p).Norm(), test.ShouldBeLessThan, 0.3)
+	}
+	test.That(t, corners[2], test.ShouldResemble, guesses[2])
+}
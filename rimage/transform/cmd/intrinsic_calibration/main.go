@@ -0,0 +1,225 @@
+// Given a folder of images of a checkerboard or ChArUco board taken from different angles, computes the intrinsics
+// and Brown-Conrady distortion of the camera that took them, and writes them as the intrinsic_parameters and
+// distortion_parameters blocks that camera configs accept. The size of the board is the number of inner
+// corners along each side. A plain checkerboard must be wholly visible in an image to be used, while a ChArUco
+// board, laid out as OpenCV draws them with tags of the given fiducial family, may be partly hidden.
+// $./intrinsic_calibration -images=/path/to/images -cols=9 -rows=6 -square_mm=25 -out=/path/to/output.json
+// $./intrinsic_calibration -images=/path/to/images -board=charuco -cols=6 -rows=4 -square_mm=40 -marker_mm=28
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.viam.com/utils"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/rimage"
+	"go.viam.com/rdk/rimage/transform"
+	"go.viam.com/rdk/vision/fiducial"
+)
+
+func main() {
+	imagesPtr := flag.String("images", "", "folder of jpeg or png images of the board")
+	boardPtr := flag.String("board", boardCheckerboard, "kind of board, checkerboard or charuco")
+	colsPtr := flag.Int("cols", 9, "number of inner corners along a row of the board")
+	rowsPtr := flag.Int("rows", 6, "number of inner corners along a column of the board")
+	squarePtr := flag.Float64("square_mm", 25, "width of a square of the board in mm")
+	markerPtr := flag.Float64("marker_mm", 0, "width of the black border of the tags of a charuco board in mm")
+	familyPtr := flag.String("family", fiducial.ArucoOriginal, "fiducial family of the tags of a charuco board")
+	outPtr := flag.String("out", "", "path to write the parameters to, instead of stdout")
+	flag.Parse()
+	logger := logging.NewLogger("intrinsic_calibration")
+	board, err := newBoard(*boardPtr, *colsPtr, *rowsPtr, *squarePtr, *markerPtr, *familyPtr)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if err := run(*imagesPtr, board, *outPtr, logger); err != nil {
+		logger.Fatal(err)
+	}
+}
+
+const (
+	boardCheckerboard = "checkerboard"
+	boardCharuco      = "charuco"
+)
+
+// A board is a calibration target that can be found in images.
+type board interface {
+	// find returns the points of the board seen in the image, or false if too little of the board was found.
+	find(img image.Image) (transform.TargetView, bool, error)
+}
+
+// newBoard returns the board of the given kind with cols by rows inner corners.
+func newBoard(kind string, cols, rows int, squareSize, markerSize float64, familyName string) (board, error) {
+	if squareSize <= 0 {
+		return nil, errors.New("the squares of the board must have a positive size")
+	}
+	switch kind {
+	case boardCheckerboard:
+		return &checkerboard{cols: cols, rows: rows, squareSize: squareSize}, nil
+	case boardCharuco:
+		family, err := fiducial.FamilyByName(familyName)
+		if err != nil {
+			return nil, err
+		}
+		b, err := fiducial.NewCharucoBoard(family, cols+1, rows+1, squareSize, markerSize)
+		if err != nil {
+			return nil, err
+		}
+		return &charucoBoard{b}, nil
+	default:
+		return nil, errors.Errorf("unknown board %q, expected %s or %s", kind, boardCheckerboard, boardCharuco)
+	}
+}
+
+// checkerboard is a plain checkerboard, which is only found when all of its corners are seen.
+type checkerboard struct {
+	cols, rows int
+	squareSize float64
+}
+
+func (c *checkerboard) find(img image.Image) (transform.TargetView, bool, error) {
+	corners, err := transform.FindCheckerboardCorners(img, c.cols, c.rows)
+	if errors.Is(err, transform.ErrCheckerboardNotFound) {
+		return transform.TargetView{}, false, nil
+	}
+	if err != nil {
+		return transform.TargetView{}, false, err
+	}
+	return transform.TargetView{
+		ObjectPoints: transform.CheckerboardObjectPoints(c.cols, c.rows, c.squareSize),
+		ImagePoints:  corners,
+	}, true, nil
+}
+
+// minCharucoCorners is the fewest corners of a ChArUco board an image must show to be used.
+const minCharucoCorners = 6
+
+// charucoBoard is a ChArUco board, whose corners are identified by the tags next to them.
+type charucoBoard struct {
+	*fiducial.CharucoBoard
+}
+
+func (c *charucoBoard) find(img image.Image) (transform.TargetView, bool, error) {
+	ids, corners := c.FindCorners(img)
+	if len(ids) < minCharucoCorners {
+		return transform.TargetView{}, false, nil
+	}
+	return transform.TargetView{ObjectPoints: c.CornerPoints(ids), ImagePoints: corners}, true, nil
+}
+
+// run calibrates from the images in folder and writes the parameters to outPath, or to stdout if it is empty. The
+// parameters are written to a temporary file next to outPath which replaces it only once they are all written, so a
+// failed run never leaves a partial file behind.
+func run(folder string, b board, outPath string, logger logging.Logger) (err error) {
+	if outPath == "" {
+		return calibrate(folder, b, os.Stdout, logger)
+	}
+	f, err := os.CreateTemp(filepath.Dir(outPath), "."+filepath.Base(outPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			// Closing twice after a failed Close or Rename only returns an error, which is ignored.
+			utils.UncheckedError(f.Close())
+			utils.UncheckedError(os.Remove(f.Name()))
+		}
+	}()
+	if err := calibrate(folder, b, f, logger); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), outPath)
+}
+
+// cameraParameters is the part of a camera config that describes the camera model.
+type cameraParameters struct {
+	Intrinsics *transform.PinholeCameraIntrinsics `json:"intrinsic_parameters"`
+	Distortion *transform.BrownConrady            `json:"distortion_parameters"`
+}
+
+func calibrate(folder string, b board, out io.Writer, logger logging.Logger) error {
+	paths, err := imagePaths(folder)
+	if err != nil {
+		return err
+	}
+
+	var views []transform.TargetView
+	var used []string
+	width, height := 0, 0
+	for _, path := range paths {
+		img, err := rimage.ReadImageFromFile(path)
+		if err != nil {
+			return err
+		}
+		bounds := img.Bounds()
+		if width == 0 {
+			width, height = bounds.Dx(), bounds.Dy()
+		} else if bounds.Dx() != width || bounds.Dy() != height {
+			return errors.Errorf("%s is %d by %d, but the other images are %d by %d",
+				path, bounds.Dx(), bounds.Dy(), width, height)
+		}
+		view, found, err := b.find(img)
+		if err != nil {
+			return err
+		}
+		if !found {
+			logger.Warnf("skipping %s, the board was not found", path)
+			continue
+		}
+		views = append(views, view)
+		used = append(used, path)
+	}
+	if len(views) < 3 {
+		return errors.Errorf("the board was found in %d of %d images, but at least 3 are needed", len(views), len(paths))
+	}
+
+	calibration, err := transform.CalibratePinholeIntrinsicsFromViews(views, width, height)
+	if err != nil {
+		return err
+	}
+	for i, path := range used {
+		logger.Infof("%s: reprojection error %.3f px", path, calibration.ViewErrors[i])
+	}
+	logger.Infof("calibrated from %d images with a reprojection error of %.3f px", len(used), calibration.RMSError)
+
+	params, err := json.MarshalIndent(cameraParameters{calibration.Intrinsics, calibration.Distortion}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(append(params, '\n'))
+	return err
+}
+
+// imagePaths returns the jpeg and png images in a folder in order.
+func imagePaths(folder string) ([]string, error) {
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, errors.Wrapf(err, "path=%q", folder)
+	}
+	var paths []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".jpg", ".jpeg", ".png":
+			if !entry.IsDir() {
+				paths = append(paths, filepath.Join(folder, entry.Name()))
+			}
+		}
+	}
+	sort.Strings(paths)
+	if len(paths) == 0 {
+		return nil, errors.Errorf("no jpeg or png images in %q", folder)
+	}
+	return paths, nil
+}
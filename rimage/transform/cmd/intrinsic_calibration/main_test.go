@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/rimage"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/vision/fiducial"
+)
+
+// renderBoard draws a 9 by 6 checkerboard with 25 mm squares, centered at the position and tilted by the rotation
+// vector, as seen by a camera without distortion.
+func renderBoard(fx, fy, ppx, ppy float64, rotation, position r3.Vector) *image.Gray {
+	return renderView(fx, fy, ppx, ppy, rotation, position, r3.Vector{X: 100, Y: 62.5}, func(x, y float64) float64 {
+		col, row := int(math.Floor(x/25))+1, int(math.Floor(y/25))+1
+		switch {
+		case col >= 0 && row >= 0 && col <= 9 && row <= 6 && (col+row)%2 == 0:
+			return 30
+		case col >= -1 && row >= -1 && col <= 10 && row <= 7:
+			return 220
+		default:
+			return 120
+		}
+	})
+}
+
+// renderCharucoBoard draws a ChArUco board of 7 by 5 squares of 40 mm, with 28 mm tags of the original ArUco
+// dictionary, centered at the position and tilted by the rotation vector, as seen by a camera without distortion.
+func renderCharucoBoard(t *testing.T, fx, fy, ppx, ppy float64, rotation, position r3.Vector) *image.Gray {
+	t.Helper()
+	family, err := fiducial.FamilyByName(fiducial.ArucoOriginal)
+	test.That(t, err, test.ShouldBeNil)
+	const cellPixels = 8
+	// The tags in the white squares, row by row.
+	tags := map[[2]int]*image.Gray{}
+	id := 0
+	for row := 0; row < 5; row++ {
+		for col := 0; col < 7; col++ {
+			if (col+row)%2 == 1 {
+				tags[[2]int{col, row}], err = family.Render(id, cellPixels)
+				test.That(t, err, test.ShouldBeNil)
+				id++
+			}
+		}
+	}
+	return renderView(fx, fy, ppx, ppy, rotation, position, r3.Vector{X: 140, Y: 100}, func(x, y float64) float64 {
+		col, row := int(math.Floor(x/40)), int(math.Floor(y/40))
+		if col < -1 || row < -1 || col > 7 || row > 5 {
+			return 120
+		}
+		tag, ok := tags[[2]int{col, row}]
+		if !ok {
+			if col >= 0 && row >= 0 && col < 7 && row < 5 {
+				return 30
+			}
+			return 220
+		}
+		// Where the point is on the tag, in cells from the outer corner of its black border.
+		cellX, cellY := (x-float64(col)*40-6)/4, (y-float64(row)*40-6)/4
+		if cellX < 0 || cellY < 0 || cellX >= 7 || cellY >= 7 {
+			return 220
+		}
+		if tag.GrayAt(int((cellX+1)*cellPixels), int((cellY+1)*cellPixels)).Y == 0 {
+			return 30
+		}
+		return 220
+	})
+}
+
+// renderView draws a plane, shaded by its x and y in mm, whose center is at the position and tilted by the rotation
+// vector, as seen by a camera without distortion.
+func renderView(
+	fx, fy, ppx, ppy float64, rotation, position, center r3.Vector, shade func(x, y float64) float64,
+) *image.Gray {
+	orientation := spatialmath.NewZeroOrientation()
+	if theta := rotation.Norm(); theta > 0 {
+		orientation = &spatialmath.R4AA{Theta: theta, RX: rotation.X / theta, RY: rotation.Y / theta, RZ: rotation.Z / theta}
+	}
+	r := orientation.RotationMatrix()
+	normal := r.Col(2)
+	origin := position.Sub(r.Mul(center))
+	img := image.NewGray(image.Rect(0, 0, 640, 480))
+	for py := 0; py < 480; py++ {
+		for px := 0; px < 640; px++ {
+			var sum float64
+			for sample := 0; sample < 16; sample++ {
+				ray := r3.Vector{
+					X: (float64(px) + (float64(sample%4)-1.5)/4 - ppx) / fx,
+					Y: (float64(py) + (float64(sample/4)-1.5)/4 - ppy) / fy,
+					Z: 1,
+				}
+				board := r.Transpose().Mul(ray.Mul(normal.Dot(origin) / normal.Dot(ray)).Sub(origin))
+				sum += shade(board.X, board.Y)
+			}
+			img.SetGray(px, py, color.Gray{Y: uint8(sum / 16)})
+		}
+	}
+	return img
+}
+
+func TestMainCalibrate(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	dir := t.TempDir()
+	for i, placement := range []struct{ rotation, position r3.Vector }{
+		{r3.Vector{X: 0.3}, r3.Vector{Z: 500}},
+		{r3.Vector{X: -0.3, Y: 0.1}, r3.Vector{X: 40, Z: 520}},
+		{r3.Vector{Y: 0.35, Z: 0.1}, r3.Vector{Y: -30, Z: 480}},
+		{r3.Vector{X: 0.25, Y: 0.25, Z: 0.2}, r3.Vector{Z: 550}},
+	} {
+		img := renderBoard(520, 515, 322, 238, placement.rotation, placement.position)
+		test.That(t, rimage.WriteImageToFile(filepath.Join(dir, fmt.Sprintf("view%d.png", i)), img), test.ShouldBeNil)
+	}
+	// An image without the board is skipped.
+	test.That(t, rimage.WriteImageToFile(filepath.Join(dir, "blank.png"), image.NewGray(image.Rect(0, 0, 640, 480))),
+		test.ShouldBeNil)
+
+	board, err := newBoard(boardCheckerboard, 9, 6, 25, 0, "")
+	test.That(t, err, test.ShouldBeNil)
+	var out bytes.Buffer
+	test.That(t, calibrate(dir, board, &out, logger), test.ShouldBeNil)
+	var params cameraParameters
+	test.That(t, json.Unmarshal(out.Bytes(), &params), test.ShouldBeNil)
+	test.That(t, params.Intrinsics.Width, test.ShouldEqual, 640)
+	test.That(t, params.Intrinsics.Height, test.ShouldEqual, 480)
+	test.That(t, params.Intrinsics.Fx, test.ShouldAlmostEqual, 520, 5)
+	test.That(t, params.Intrinsics.Fy, test.ShouldAlmostEqual, 515, 5)
+	test.That(t, params.Intrinsics.Ppx, test.ShouldAlmostEqual, 322, 3)
+	test.That(t, params.Intrinsics.Ppy, test.ShouldAlmostEqual, 238, 3)
+	test.That(t, params.Distortion.RadialK1, test.ShouldAlmostEqual, 0, 0.03)
+
+	// Too few of the images show the board.
+	wrongBoard, err := newBoard(boardCheckerboard, 8, 6, 25, 0, "")
+	test.That(t, err, test.ShouldBeNil)
+	err = calibrate(dir, wrongBoard, &out, logger)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "at least 3")
+
+	err = calibrate(t.TempDir(), board, &out, logger)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = newBoard(boardCheckerboard, 9, 6, 0, 0, "")
+	test.That(t, err, test.ShouldNotBeNil)
+	// run only leaves a file behind when it succeeds.
+	outDir := t.TempDir()
+	outPath := filepath.Join(outDir, "params.json")
+	test.That(t, run(dir, wrongBoard, outPath, logger), test.ShouldNotBeNil)
+	entries, err := os.ReadDir(outDir)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, entries, test.ShouldBeEmpty)
+	test.That(t, run(dir, board, outPath, logger), test.ShouldBeNil)
+	written, err := os.ReadFile(outPath)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, written, test.ShouldResemble, out.Bytes())
+	entries, err = os.ReadDir(outDir)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, entries, test.ShouldHaveLength, 1)
+}
+
+func TestMainCalibrateCharuco(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	dir := t.TempDir()
+	for i, placement := range []struct{ rotation, position r3.Vector }{
+		{r3.Vector{X: 0.3}, r3.Vector{Z: 420}},
+		{r3.Vector{X: -0.3, Y: 0.1}, r3.Vector{X: 30, Z: 440}},
+		{r3.Vector{Y: 0.35, Z: 0.1}, r3.Vector{Y: -20, Z: 410}},
+		// Part of the board is out of view.
+		{r3.Vector{X: 0.25, Y: 0.25, Z: 0.2}, r3.Vector{X: 150, Y: 60, Z: 400}},
+	} {
+		img := renderCharucoBoard(t, 520, 515, 322, 238, placement.rotation, placement.position)
+		test.That(t, rimage.WriteImageToFile(filepath.Join(dir, fmt.Sprintf("view%d.png", i)), img), test.ShouldBeNil)
+	}
+
+	board, err := newBoard(boardCharuco, 6, 4, 40, 28, fiducial.ArucoOriginal)
+	test.That(t, err, test.ShouldBeNil)
+	var out bytes.Buffer
+	test.That(t, calibrate(dir, board, &out, logger), test.ShouldBeNil)
+	var params cameraParameters
+	test.That(t, json.Unmarshal(out.Bytes(), &params), test.ShouldBeNil)
+	test.That(t, params.Intrinsics.Fx, test.ShouldAlmostEqual, 520, 5)
+	test.That(t, params.Intrinsics.Fy, test.ShouldAlmostEqual, 515, 5)
+	test.That(t, params.Intrinsics.Ppx, test.ShouldAlmostEqual, 322, 3)
+	test.That(t, params.Intrinsics.Ppy, test.ShouldAlmostEqual, 238, 3)
+
+	// The partly hidden board is not found as a plain checkerboard.
+	checkerboard, err := newBoard(boardCheckerboard, 6, 4, 40, 0, "")
+	test.That(t, err, test.ShouldBeNil)
+	view, found, err := checkerboard.find(renderCharucoBoard(t, 520, 515, 322, 238,
+		r3.Vector{X: 0.25, Y: 0.25, Z: 0.2}, r3.Vector{X: 150, Y: 60, Z: 400}))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, found, test.ShouldBeFalse)
+	test.That(t, view.ImagePoints, test.ShouldBeEmpty)
+
+	_, err = newBoard(boardCharuco, 6, 4, 40, 40, fiducial.ArucoOriginal)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = newBoard(boardCharuco, 6, 4, 40, 28, "tag36h11")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = newBoard("circles", 6, 4, 40, 28, fiducial.ArucoOriginal)
+	test.That(t, err, test.ShouldNotBeNil)
+}
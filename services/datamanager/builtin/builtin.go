@@ -162,6 +162,34 @@ func (b *builtIn) Sync(ctx context.Context, extra map[string]interface{}) error
 	return b.sync.Sync(ctx, extra)
 }
 
+// DoCommand handles trigger_capture, which writes the pre-trigger window of the collectors that capture around
+// triggers, and then their post-trigger window, under a new sequence.
+func (b *builtIn) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	raw, ok := cmd[datamanager.TriggerCaptureKey]
+	if !ok {
+		return nil, resource.ErrDoUnimplemented
+	}
+	jsonBytes, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s: %w", datamanager.TriggerCaptureKey, err)
+	}
+	var trigger datamanager.SequenceReading
+	if err := json.Unmarshal(jsonBytes, &trigger); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", datamanager.TriggerCaptureKey, err)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id, triggered := b.capture.TriggerCapture(trigger.Resources, trigger.SequenceTags)
+	if len(triggered) == 0 {
+		return nil, errors.New("no collectors with pre_trigger_seconds or post_trigger_seconds match the trigger")
+	}
+	resources := make([]interface{}, 0, len(triggered))
+	for _, r := range triggered {
+		resources = append(resources, map[string]interface{}{"resource_name": r.ResourceName, "method": r.MethodName})
+	}
+	return map[string]interface{}{"sequence_id": id, "resources": resources}, nil
+}
+
 // Reconfigure updates the data manager service when the config has changed.
 // At time of writing Reconfigure only returns an error in one of the following unrecoverable error cases:
 //  1. There is some static (aka compile time) error which we currently are only able to detected at runtime:
@@ -199,7 +227,13 @@ func (b *builtIn) BuiltInReconfigure(ctx context.Context, deps resource.Dependen
 		return err
 	}
 
+	keyring, err := c.keyring()
+	if err != nil {
+		return err
+	}
+
 	captureConfig := c.captureConfig(b.logger)
+	captureConfig.Keyring = keyring
 	collectorConfigsByResource, err := lookupCollectorConfigsByResource(deps, conf, captureConfig.CaptureDir, b.logger)
 	if err != nil {
 		// If this error occurs it's a resource graph error
@@ -218,6 +252,7 @@ func (b *builtIn) BuiltInReconfigure(ctx context.Context, deps resource.Dependen
 	}
 	syncSensor, syncSensorEnabled := syncSensorFromDeps(c.SelectiveSyncerName, deps, b.logger)
 	syncConfig := c.syncConfig(syncSensor, syncSensorEnabled, b.logger)
+	syncConfig.Keyring = keyring
 
 	controlSensor, controlSensorKey := captureControlSensorFromDeps(c.CaptureControlSensor, deps, b.logger)
 
@@ -828,6 +828,22 @@ func TestLookupCollectorConfigsByResource(t *testing.T) {
 	})
 }
 
+func TestDoCommand(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	b, closeFunc := builtinWithEmptyConfig(t, logger)
+	defer closeFunc()
+
+	_, err := b.DoCommand(context.Background(), map[string]interface{}{"unknown": true})
+	test.That(t, err, test.ShouldBeError, resource.ErrDoUnimplemented)
+
+	// Without any collectors that capture around triggers, there is nothing to trigger.
+	_, err = b.DoCommand(context.Background(), map[string]interface{}{
+		datamanager.TriggerCaptureKey: map[string]interface{}{"sequence_tags": []interface{}{"near-miss"}},
+	})
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "no collectors")
+}
+
 func builtinWithEmptyConfig(t *testing.T, logger logging.Logger) (datamanager.Service, func()) {
 	mockDeps := mockDeps(nil, nil)
 	b, err := New(
@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/benbjohnson/clock"
 	"github.com/pkg/errors"
@@ -64,8 +65,10 @@ type Capture struct {
 	captureDir string
 	// maxCaptureFileSize is only stored on Capture so that we can detect when it changes
 	maxCaptureFileSize int64
-	mongoMU            sync.Mutex
-	mongo              captureMongo
+	// keyring is only stored on Capture so that we can detect when it changes
+	keyring *data.Keyring
+	mongoMU sync.Mutex
+	mongo   captureMongo
 
 	// defaultCollectorConfigs are the default as specified in the machine config.
 	// These are stored in order to be compared to any capture override readings.
@@ -102,6 +105,8 @@ type collectorAndConfig struct {
 	Resource  resource.Resource
 	Collector data.Collector
 	Config    datamanager.DataCaptureConfig
+	// Trigger is set when the collector only writes around triggers.
+	Trigger *triggerBuffer
 }
 
 // Identifier for a particular collector: component name, component model, component type,
@@ -121,8 +126,10 @@ func New(
 
 func format(c datamanager.DataCaptureConfig) string {
 	return fmt.Sprintf("datamanager.DataCaptureConfig{"+
-		"Name: %s, Method: %s, CaptureFrequencyHz: %f, CaptureQueueSize: %d, AdditionalParams:	%v, Disabled: %t, Tags: %v, CaptureDirectory: %s}",
-		c.Name, c.Method, c.CaptureFrequencyHz, c.CaptureQueueSize, c.AdditionalParams, c.Disabled, c.Tags, c.CaptureDirectory)
+		"Name: %s, Method: %s, CaptureFrequencyHz: %f, CaptureQueueSize: %d, AdditionalParams:	%v, Disabled: %t, Tags: %v, CaptureDirectory: %s, "+
+		"PreTriggerSeconds: %f, PostTriggerSeconds: %f, Deadband: %+v}",
+		c.Name, c.Method, c.CaptureFrequencyHz, c.CaptureQueueSize, c.AdditionalParams, c.Disabled, c.Tags, c.CaptureDirectory,
+		c.PreTriggerSeconds, c.PostTriggerSeconds, c.Deadband)
 }
 
 func (c *Capture) newCollectors(
@@ -269,6 +276,7 @@ func (c *Capture) Reconfigure(
 	c.defaultTags = config.Tags
 	c.captureDir = config.CaptureDir
 	c.maxCaptureFileSize = config.MaximumCaptureFileSizeBytes
+	c.keyring = config.Keyring
 }
 
 // Close closes the capture manager.
@@ -307,10 +315,11 @@ func (c *Capture) initializeOrUpdateCollector(
 	collection *mongo.Collection,
 ) (*collectorAndConfig, error) {
 	maxFileSizeChanged := c.maxCaptureFileSize != config.MaximumCaptureFileSizeBytes
+	keyringChanged := !c.keyring.Equal(config.Keyring)
 	if storedCollectorAndConfig, ok := c.collectors[md]; ok {
 		if storedCollectorAndConfig.Config.Equals(&collectorConfig) &&
 			res == storedCollectorAndConfig.Resource &&
-			!maxFileSizeChanged {
+			!maxFileSizeChanged && !keyringChanged {
 			// If the attributes have not changed, do nothing and leave the existing collector.
 			return c.collectors[md], nil
 		}
@@ -326,6 +335,14 @@ func (c *Capture) initializeOrUpdateCollector(
 		return nil, errors.Errorf("capture_buffer_size can't be less than 0, current value: %d", collectorConfig.CaptureBufferSize)
 	}
 
+	if collectorConfig.PreTriggerSeconds < 0 {
+		return nil, errors.Errorf("pre_trigger_seconds can't be less than 0, current value: %f", collectorConfig.PreTriggerSeconds)
+	}
+
+	if collectorConfig.PostTriggerSeconds < 0 {
+		return nil, errors.Errorf("post_trigger_seconds can't be less than 0, current value: %f", collectorConfig.PostTriggerSeconds)
+	}
+
 	metadataKey := generateMetadataKey(md.MethodMetadata.API.String(), md.MethodMetadata.MethodName)
 	if additionalParamKey, ok := metadataToAdditionalParamFields[metadataKey]; ok {
 		if _, ok := collectorConfig.AdditionalParams[additionalParamKey]; !ok {
@@ -334,7 +351,7 @@ func (c *Capture) initializeOrUpdateCollector(
 		}
 	}
 
-	return c.buildCollector(res, md, collectorConfig, config.MaximumCaptureFileSizeBytes, collection)
+	return c.buildCollector(res, md, collectorConfig, config.MaximumCaptureFileSizeBytes, config.Keyring, collection)
 }
 
 // buildCollector constructs and starts a new collector, assuming the base config was already validated.
@@ -344,6 +361,7 @@ func (c *Capture) buildCollector(
 	md collectorMetadata,
 	collectorConfig datamanager.DataCaptureConfig,
 	maxCaptureFileSize int64,
+	keyring *data.Keyring,
 	collection *mongo.Collection,
 ) (*collectorAndConfig, error) {
 	interval := data.GetDurationFromHz(collectorConfig.CaptureFrequencyHz)
@@ -382,16 +400,32 @@ func (c *Capture) buildCollector(
 	// Parameters to initialize collector.
 	queueSize := defaultIfZeroVal(collectorConfig.CaptureQueueSize, defaultCaptureQueueSize)
 	bufferSize := defaultIfZeroVal(collectorConfig.CaptureBufferSize, defaultCaptureBufferSize)
+	var target data.CaptureBufferedWriter = data.NewCaptureBufferWithKeyring(targetDir, captureMetadata, maxCaptureFileSize, keyring)
+	var trigger *triggerBuffer
+	if collectorConfig.PreTriggerSeconds > 0 || collectorConfig.PostTriggerSeconds > 0 {
+		trigger = newTriggerBuffer(c.clk, targetDir, captureMetadata, maxCaptureFileSize, keyring,
+			secondsToDuration(collectorConfig.PreTriggerSeconds), secondsToDuration(collectorConfig.PostTriggerSeconds))
+		target = trigger
+	}
+	var deadband *data.Deadband
+	if collectorConfig.Deadband != nil {
+		deadband = &data.Deadband{
+			Absolute:    collectorConfig.Deadband.Absolute,
+			Relative:    collectorConfig.Deadband.Relative,
+			MaxInterval: time.Duration(collectorConfig.Deadband.MaxIntervalSeconds * float64(time.Second)),
+		}
+	}
 	collector, err := collectorConstructor(res, data.CollectorParams{
 		MongoCollection: collection,
 		DataType:        dataType,
+		Deadband:        deadband,
 		ComponentName:   collectorConfig.Name.ShortName(),
 		ComponentType:   collectorConfig.Name.API.String(),
 		FrameSystem:     c.frameSystem,
 		MethodName:      collectorConfig.Method,
 		Interval:        interval,
 		MethodParams:    methodParams,
-		Target:          data.NewCaptureBuffer(targetDir, captureMetadata, maxCaptureFileSize),
+		Target:          target,
 		// Set queue size to defaultCaptureQueueSize if it was not set in the config.
 		QueueSize:  queueSize,
 		BufferSize: bufferSize,
@@ -407,7 +441,11 @@ func (c *Capture) buildCollector(
 		md, collectorConfigDescription(collectorConfig, targetDir, maxCaptureFileSize, queueSize, bufferSize))
 	collector.Collect()
 
-	return &collectorAndConfig{res, collector, collectorConfig}, nil
+	return &collectorAndConfig{Resource: res, Collector: collector, Config: collectorConfig, Trigger: trigger}, nil
+}
+
+func secondsToDuration(seconds float32) time.Duration {
+	return time.Duration(float64(seconds) * float64(time.Second))
 }
 
 func collectorConfigDescription(
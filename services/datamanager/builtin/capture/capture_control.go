@@ -115,7 +115,7 @@ func (c *Capture) updateCollectors(effectiveCollectors map[collectorMetadata]eff
 
 		// Rebuild collectors to reflect override changes.
 		c.logCaptureConfigChange(key, existing, effectiveCfg)
-		coll, err := c.buildCollector(res, metadata, effectiveCfg, c.maxCaptureFileSize, c.mongo.collection)
+		coll, err := c.buildCollector(res, metadata, effectiveCfg, c.maxCaptureFileSize, c.keyring, c.mongo.collection)
 		if err != nil {
 			c.logger.Errorw("failed to build collector", "error", err, "key", key)
 			continue
@@ -61,6 +61,7 @@ func (c *Capture) SetActiveSequences(active []datamanager.SequenceReading) {
 				c.logger.Errorw("failed to persist open sequence",
 					"error", err, "id", seq.ID, "start_at", seq.StartAt)
 			}
+			c.triggerCollectors(seq.ID, seq.Resources)
 		}
 	}
 
@@ -117,15 +118,68 @@ func (c *Capture) flushOpenSequences() {
 	}
 }
 
+// TriggerCapture triggers the collectors of resources that capture around triggers, or all of them if resources is
+// empty, under a new sequence. The sequence is written as closed straight away, spanning the longest pre- and
+// post-trigger windows of the triggered collectors. Returns the ID of the sequence and the resources triggered.
+func (c *Capture) TriggerCapture(resources []datamanager.ResourceMethod, tags []string) (string, []datamanager.ResourceMethod) {
+	now := c.clk.Now()
+	id := uuid.NewString()
+	triggered, pre, post := c.triggerCollectors(id, resources)
+	if len(triggered) == 0 {
+		return id, nil
+	}
+	closed := ClosedSequence{
+		ID:           id,
+		StartAt:      now.Add(-pre),
+		EndAt:        now.Add(post),
+		Resources:    triggered,
+		SequenceTags: slices.Clone(tags),
+	}
+	c.logger.Infow("capture triggered",
+		"start_at", closed.StartAt,
+		"end_at", closed.EndAt,
+		"resources", closed.Resources,
+		"tags", closed.SequenceTags,
+	)
+	if err := writeClosedSequence(c.captureDir, closed); err != nil {
+		c.logger.Errorw("failed to persist triggered sequence",
+			"error", err, "id", closed.ID, "start_at", closed.StartAt, "end_at", closed.EndAt)
+	}
+	return id, triggered
+}
+
+// triggerCollectors triggers the collectors of resources that capture around triggers, or all of them if resources
+// is empty. Returns the resources triggered and their longest pre- and post-trigger windows.
+func (c *Capture) triggerCollectors(
+	sequenceID string, resources []datamanager.ResourceMethod,
+) ([]datamanager.ResourceMethod, time.Duration, time.Duration) {
+	c.collectorsMu.Lock()
+	defer c.collectorsMu.Unlock()
+	var triggered []datamanager.ResourceMethod
+	var pre, post time.Duration
+	for md, collAndConfig := range c.collectors {
+		if collAndConfig.Trigger == nil {
+			continue
+		}
+		resourceMethod := datamanager.ResourceMethod{ResourceName: md.ResourceName, MethodName: md.MethodMetadata.MethodName}
+		if len(resources) > 0 && !slices.Contains(resources, resourceMethod) {
+			continue
+		}
+		if err := collAndConfig.Trigger.Trigger(sequenceID); err != nil {
+			c.logger.Errorw("failed to write the pre-trigger window", "error", err, "collector", md, "id", sequenceID)
+		}
+		triggered = append(triggered, resourceMethod)
+		pre = max(pre, collAndConfig.Trigger.preTrigger)
+		post = max(post, collAndConfig.Trigger.postTrigger)
+	}
+	slices.SortFunc(triggered, compareResourceMethods)
+	return triggered, pre, post
+}
+
 // newOpenSequenceKey returns a comparable identity for s, sorted so input order doesn't matter
 func newOpenSequenceKey(s datamanager.SequenceReading) openSequenceKey {
 	resources := slices.Clone(s.Resources)
-	slices.SortFunc(resources, func(a, b datamanager.ResourceMethod) int {
-		if c := cmp.Compare(a.ResourceName, b.ResourceName); c != 0 {
-			return c
-		}
-		return cmp.Compare(a.MethodName, b.MethodName)
-	})
+	slices.SortFunc(resources, compareResourceMethods)
 	tags := slices.Clone(s.SequenceTags)
 	slices.Sort(tags)
 	resourcesJSON, _ := json.Marshal(resources) //nolint:errcheck,errchkjson
@@ -135,3 +189,10 @@ func newOpenSequenceKey(s datamanager.SequenceReading) openSequenceKey {
 		tags:      string(tagsJSON),
 	}
 }
+
+func compareResourceMethods(a, b datamanager.ResourceMethod) int {
+	if c := cmp.Compare(a.ResourceName, b.ResourceName); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.MethodName, b.MethodName)
+}
@@ -0,0 +1,183 @@
+package capture
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"go.uber.org/multierr"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"google.golang.org/protobuf/proto"
+
+	"go.viam.com/rdk/data"
+)
+
+// maxTriggerBufferBytes caps the memory the pre-trigger window of a collector can take, so that a long window on a
+// method with large readings cannot run the machine out of memory. The oldest readings are dropped past it.
+const maxTriggerBufferBytes = 64 << 20
+
+func sequenceIDTag(id string) string {
+	return data.SequenceIDTagPrefix + id
+}
+
+// bufferedReading is a reading held in memory until a trigger, along with when it arrived.
+type bufferedReading struct {
+	at       time.Time
+	item     *v1.SensorData
+	mimeType string
+	size     int
+}
+
+// triggerBuffer is the target of a collector that only writes to disk around triggers. It keeps the readings of the
+// last preTrigger in memory, up to maxBufferBytes of them, and when triggered writes them, and then every reading until postTrigger has passed, to
+// new capture files tagged with the ID of the sequence that triggered it.
+//
+// A window that has passed is only closed on the next write or flush, so that the collector's calls are the only
+// thing that touches the files.
+type triggerBuffer struct {
+	clk                clock.Clock
+	dir                string
+	md                 *v1.DataCaptureMetadata
+	maxCaptureFileSize int64
+	keyring            *data.Keyring
+	preTrigger         time.Duration
+	postTrigger        time.Duration
+	maxBufferBytes     int
+
+	mu       sync.Mutex
+	readings []bufferedReading
+	// bufferedBytes is the total size of readings.
+	bufferedBytes int
+	// target is non-nil while a trigger's window is open.
+	target *data.CaptureBuffer
+	until  time.Time
+}
+
+func newTriggerBuffer(
+	clk clock.Clock,
+	dir string,
+	md *v1.DataCaptureMetadata,
+	maxCaptureFileSize int64,
+	keyring *data.Keyring,
+	preTrigger, postTrigger time.Duration,
+) *triggerBuffer {
+	return &triggerBuffer{
+		clk:                clk,
+		dir:                dir,
+		md:                 md,
+		maxCaptureFileSize: maxCaptureFileSize,
+		keyring:            keyring,
+		preTrigger:         preTrigger,
+		postTrigger:        postTrigger,
+		maxBufferBytes:     maxTriggerBufferBytes,
+	}
+}
+
+// WriteBinary buffers item, and writes it if a trigger's window is open.
+func (b *triggerBuffer) WriteBinary(item *v1.SensorData, mimeType string) error {
+	return b.write(item, mimeType)
+}
+
+// WriteTabular buffers item, and writes it if a trigger's window is open.
+func (b *triggerBuffer) WriteTabular(item *v1.SensorData) error {
+	return b.write(item, "")
+}
+
+func (b *triggerBuffer) write(item *v1.SensorData, mimeType string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clk.Now()
+	reading := bufferedReading{at: now, item: item, mimeType: mimeType}
+	var err error
+	if b.target != nil {
+		if now.After(b.until) {
+			err = b.closeWindow()
+		} else {
+			err = writeReading(b.target, reading)
+		}
+	}
+	if b.preTrigger > 0 {
+		reading.size = proto.Size(item)
+		b.readings = append(b.readings, reading)
+		b.bufferedBytes += reading.size
+		b.prune(now)
+	}
+	return err
+}
+
+// prune drops the readings that are older than the pre-trigger window, and then the oldest readings until the rest
+// fit in maxBufferBytes.
+func (b *triggerBuffer) prune(now time.Time) {
+	cutoff := now.Add(-b.preTrigger)
+	i, _ := slices.BinarySearchFunc(b.readings, cutoff, func(r bufferedReading, t time.Time) int {
+		return r.at.Compare(t)
+	})
+	for _, r := range b.readings[:i] {
+		b.bufferedBytes -= r.size
+	}
+	for ; i < len(b.readings) && b.bufferedBytes > b.maxBufferBytes; i++ {
+		b.bufferedBytes -= b.readings[i].size
+	}
+	if i > 0 {
+		b.readings = append(b.readings[:0], b.readings[i:]...)
+	}
+}
+
+// Trigger writes the pre-trigger window to new capture files tagged with sequenceID, and keeps writing to them until
+// the post-trigger window passes. A window that is still open from an earlier trigger is closed first, so the files of
+// each sequence hold its whole window.
+func (b *triggerBuffer) Trigger(sequenceID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var err error
+	if b.target != nil {
+		err = b.closeWindow()
+	}
+	now := b.clk.Now()
+	md := proto.Clone(b.md).(*v1.DataCaptureMetadata)
+	md.Tags = append(slices.Clone(md.Tags), sequenceIDTag(sequenceID))
+	b.target = data.NewCaptureBufferWithKeyring(b.dir, md, b.maxCaptureFileSize, b.keyring)
+	b.until = now.Add(b.postTrigger)
+	b.prune(now)
+	for _, reading := range b.readings {
+		if writeErr := writeReading(b.target, reading); writeErr != nil {
+			return multierr.Combine(err, writeErr, b.closeWindow())
+		}
+	}
+	if b.postTrigger == 0 {
+		err = multierr.Combine(err, b.closeWindow())
+	}
+	return err
+}
+
+func (b *triggerBuffer) closeWindow() error {
+	err := b.target.Flush()
+	b.target = nil
+	return err
+}
+
+// Flush completes the capture files of an open window. Readings that arrive before the window passes go to new files.
+func (b *triggerBuffer) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.target == nil {
+		return nil
+	}
+	if b.clk.Now().After(b.until) {
+		return b.closeWindow()
+	}
+	return b.target.Flush()
+}
+
+// Path returns the directory the capture files are written to.
+func (b *triggerBuffer) Path() string {
+	return b.dir
+}
+
+func writeReading(target data.CaptureBufferedWriter, reading bufferedReading) error {
+	if data.IsBinary(reading.item) {
+		return target.WriteBinary(reading.item, reading.mimeType)
+	}
+	return target.WriteTabular(reading.item)
+}
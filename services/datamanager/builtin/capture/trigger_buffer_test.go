@@ -0,0 +1,208 @@
+package capture
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/services/datamanager"
+)
+
+func tabularReading(t *testing.T, i int) *v1.SensorData {
+	t.Helper()
+	s, err := structpb.NewStruct(map[string]interface{}{"i": i})
+	test.That(t, err, test.ShouldBeNil)
+	return &v1.SensorData{Metadata: &v1.SensorMetadata{}, Data: &v1.SensorData_Struct{Struct: s}}
+}
+
+// readTriggeredFiles returns the readings of every completed capture file in dir by their sequence ID tag, and the
+// number of files still in progress.
+func readTriggeredFiles(t *testing.T, dir string) (map[string][]float64, int) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	test.That(t, err, test.ShouldBeNil)
+	readings := map[string][]float64{}
+	var inProgress int
+	for _, e := range entries {
+		if filepath.Ext(e.Name()) == data.InProgressCaptureFileExt {
+			inProgress++
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, e.Name()))
+		test.That(t, err, test.ShouldBeNil)
+		captureFile, err := data.ReadCaptureFile(f)
+		test.That(t, err, test.ShouldBeNil)
+		tags := captureFile.ReadMetadata().GetTags()
+		test.That(t, tags, test.ShouldHaveLength, 2)
+		test.That(t, tags[0], test.ShouldEqual, "default")
+		items, err := data.SensorDataFromCaptureFile(captureFile)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, f.Close(), test.ShouldBeNil)
+		for _, item := range items {
+			readings[tags[1]] = append(readings[tags[1]], item.GetStruct().GetFields()["i"].GetNumberValue())
+		}
+	}
+	return readings, inProgress
+}
+
+func newTriggerBufferForTest(t *testing.T, pre, post time.Duration) (*triggerBuffer, *clock.Mock, string) {
+	t.Helper()
+	mockClk := clock.NewMock()
+	dir := t.TempDir()
+	md := &v1.DataCaptureMetadata{Tags: []string{"default"}}
+	return newTriggerBuffer(mockClk, dir, md, 1<<20, nil, pre, post), mockClk, dir
+}
+
+func TestTriggerBuffer(t *testing.T) {
+	b, clk, dir := newTriggerBufferForTest(t, 3*time.Second, 2*time.Second)
+
+	// Nothing is written without a trigger.
+	for i := 0; i < 10; i++ {
+		test.That(t, b.WriteTabular(tabularReading(t, i)), test.ShouldBeNil)
+		clk.Add(time.Second)
+	}
+	test.That(t, b.Flush(), test.ShouldBeNil)
+	readings, inProgress := readTriggeredFiles(t, dir)
+	test.That(t, readings, test.ShouldBeEmpty)
+	test.That(t, inProgress, test.ShouldEqual, 0)
+
+	// The trigger writes the last 3 seconds, and then the next 2.
+	test.That(t, b.Trigger("a"), test.ShouldBeNil)
+	for i := 10; i < 15; i++ {
+		test.That(t, b.WriteTabular(tabularReading(t, i)), test.ShouldBeNil)
+		clk.Add(time.Second)
+	}
+	test.That(t, b.Flush(), test.ShouldBeNil)
+	readings, inProgress = readTriggeredFiles(t, dir)
+	test.That(t, inProgress, test.ShouldEqual, 0)
+	test.That(t, readings, test.ShouldResemble, map[string][]float64{
+		sequenceIDTag("a"): {7, 8, 9, 10, 11, 12},
+	})
+}
+
+func TestTriggerBufferOverlappingTriggers(t *testing.T) {
+	b, clk, dir := newTriggerBufferForTest(t, 2*time.Second, 3*time.Second)
+
+	for i := 0; i < 5; i++ {
+		test.That(t, b.WriteTabular(tabularReading(t, i)), test.ShouldBeNil)
+		clk.Add(time.Second)
+	}
+	test.That(t, b.Trigger("a"), test.ShouldBeNil)
+	test.That(t, b.WriteTabular(tabularReading(t, 5)), test.ShouldBeNil)
+	clk.Add(time.Second)
+	// A second trigger inside the first's window closes it, and starts its own with its whole pre-trigger window.
+	test.That(t, b.Trigger("b"), test.ShouldBeNil)
+	for i := 6; i < 10; i++ {
+		test.That(t, b.WriteTabular(tabularReading(t, i)), test.ShouldBeNil)
+		clk.Add(time.Second)
+	}
+	// The window has passed, but stays open until the next write or flush.
+	_, inProgress := readTriggeredFiles(t, dir)
+	test.That(t, inProgress, test.ShouldEqual, 1)
+	test.That(t, b.Flush(), test.ShouldBeNil)
+
+	readings, inProgress := readTriggeredFiles(t, dir)
+	test.That(t, inProgress, test.ShouldEqual, 0)
+	test.That(t, readings, test.ShouldResemble, map[string][]float64{
+		sequenceIDTag("a"): {3, 4, 5},
+		sequenceIDTag("b"): {4, 5, 6, 7, 8, 9},
+	})
+}
+
+func TestTriggerBufferMaxBytes(t *testing.T) {
+	b, clk, dir := newTriggerBufferForTest(t, 10*time.Second, 0)
+	// Room for 3 of the readings, which are all the same size.
+	b.maxBufferBytes = 3 * proto.Size(tabularReading(t, 0))
+
+	for i := 0; i < 5; i++ {
+		test.That(t, b.WriteTabular(tabularReading(t, i)), test.ShouldBeNil)
+		clk.Add(time.Second)
+	}
+	// All 5 are inside the pre-trigger window, but only the newest 3 fit.
+	test.That(t, b.readings, test.ShouldHaveLength, 3)
+	test.That(t, b.bufferedBytes, test.ShouldEqual, b.maxBufferBytes)
+	test.That(t, b.Trigger("a"), test.ShouldBeNil)
+
+	// Readings that drop out of the window free up their room.
+	clk.Add(20 * time.Second)
+	test.That(t, b.WriteTabular(tabularReading(t, 5)), test.ShouldBeNil)
+	test.That(t, b.readings, test.ShouldHaveLength, 1)
+	test.That(t, b.bufferedBytes, test.ShouldEqual, proto.Size(tabularReading(t, 5)))
+
+	readings, inProgress := readTriggeredFiles(t, dir)
+	test.That(t, inProgress, test.ShouldEqual, 0)
+	test.That(t, readings, test.ShouldResemble, map[string][]float64{
+		sequenceIDTag("a"): {2, 3, 4},
+	})
+}
+
+func TestTriggerBufferPostTriggerOnly(t *testing.T) {
+	b, clk, dir := newTriggerBufferForTest(t, 0, time.Second)
+	test.That(t, b.WriteTabular(tabularReading(t, 0)), test.ShouldBeNil)
+	clk.Add(time.Second)
+	test.That(t, b.Trigger("a"), test.ShouldBeNil)
+	test.That(t, b.WriteTabular(tabularReading(t, 1)), test.ShouldBeNil)
+	test.That(t, b.readings, test.ShouldBeEmpty)
+
+	// Flushing inside the window completes the file so far, and later readings go to a new one.
+	test.That(t, b.Flush(), test.ShouldBeNil)
+	clk.Add(500 * time.Millisecond)
+	test.That(t, b.WriteTabular(tabularReading(t, 2)), test.ShouldBeNil)
+	clk.Add(time.Second)
+	test.That(t, b.WriteTabular(tabularReading(t, 3)), test.ShouldBeNil)
+
+	readings, inProgress := readTriggeredFiles(t, dir)
+	test.That(t, inProgress, test.ShouldEqual, 0)
+	test.That(t, readings, test.ShouldResemble, map[string][]float64{sequenceIDTag("a"): {1, 2}})
+}
+
+func TestTriggerCapture(t *testing.T) {
+	c, clk := newCaptureForTest(t)
+	triggered, _, cameraDir := newTriggerBufferForTest(t, time.Second, 0)
+	triggered.clk = clk
+	camera := datamanager.DataCaptureConfig{Name: fakeRes.Name(), Method: "GetImages"}
+	arm := datamanager.DataCaptureConfig{Name: fakeRes.Name(), Method: "JointPositions"}
+	c.collectors = collectors{
+		newCollectorMetadata(camera): {Resource: fakeRes, Collector: &mockCollector{}, Config: camera, Trigger: triggered},
+		newCollectorMetadata(arm): {
+			Resource: fakeRes, Collector: &mockCollector{}, Config: arm,
+			Trigger: newTriggerBuffer(clk, t.TempDir(), &v1.DataCaptureMetadata{}, 1<<20, nil, 0, 2*time.Second),
+		},
+	}
+	test.That(t, triggered.WriteTabular(tabularReading(t, 0)), test.ShouldBeNil)
+
+	// A sequence that opens triggers its resources under its ID.
+	c.SetActiveSequences([]datamanager.SequenceReading{sequence([]string{"crash"}, resources("fake-1", "GetImages"))})
+	var seq *OpenSequence
+	for _, s := range c.openSequences {
+		seq = s
+	}
+	readings, _ := readTriggeredFiles(t, cameraDir)
+	test.That(t, readings, test.ShouldResemble, map[string][]float64{sequenceIDTag(seq.ID): {0}})
+
+	// Triggering with no resources triggers all of them, under a sequence spanning the widest windows.
+	start := clk.Now()
+	id, triggeredResources := c.TriggerCapture(nil, []string{"near-miss"})
+	test.That(t, triggeredResources, test.ShouldResemble, []datamanager.ResourceMethod{
+		resources("fake-1", "GetImages"),
+		resources("fake-1", "JointPositions"),
+	})
+	files := readSeqFiles(t, c)
+	test.That(t, files, test.ShouldHaveLength, 1)
+	test.That(t, files[0].StartTime, test.ShouldEqual, start.Add(-time.Second))
+	test.That(t, files[0].EndTime, test.ShouldEqual, start.Add(2*time.Second))
+	test.That(t, files[0].SequenceTags, test.ShouldResemble, []string{"near-miss"})
+	readings, _ = readTriggeredFiles(t, cameraDir)
+	test.That(t, readings[sequenceIDTag(id)], test.ShouldResemble, []float64{0})
+
+	_, triggeredResources = c.TriggerCapture([]datamanager.ResourceMethod{resources("fake-1", "Readings")}, nil)
+	test.That(t, triggeredResources, test.ShouldBeEmpty)
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/internal/cloud"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/robot/framesystem"
@@ -73,9 +74,25 @@ type Config struct {
 	ScheduledSyncDisabled  bool     `json:"sync_disabled"`
 	SelectiveSyncerName    string   `json:"selective_syncer_name"`
 	SyncIntervalMins       float64  `json:"sync_interval_mins"`
+	// UploadRateLimit caps the upload rate of each data type in bytes per second.
+	UploadRateLimit *datasync.UploadRateLimit `json:"upload_rate_limit,omitempty"`
+	// SyncWindows restricts scheduled sync to the given windows of time.
+	SyncWindows []datasync.SyncWindow `json:"sync_windows,omitempty"`
+	// SyncPriorityTags uploads files with these tags first, in order.
+	SyncPriorityTags []string `json:"sync_priority_tags,omitempty"`
 	// CaptureControlSensor when set specifies a sensor to poll for dynamic
 	// capture configurations.
 	CaptureControlSensor *CaptureControlSensorConfig `json:"capture_control_sensor,omitempty"`
+	// Encryption when set encrypts capture files and dataset uploads at rest.
+	Encryption *EncryptionConfig `json:"encryption,omitempty"`
+}
+
+// EncryptionConfig configures encryption at rest of the files data manager writes. See data.EncryptionConfig.
+type EncryptionConfig = data.EncryptionConfig
+
+// keyring loads the configured keys, returning nil if encryption is not configured.
+func (c *Config) keyring() (*data.Keyring, error) {
+	return c.Encryption.Keyring()
 }
 
 // Validate returns components which will be depended upon weakly due to the above matcher.
@@ -101,6 +118,23 @@ func (c *Config) Validate(path string) ([]string, []string, error) {
 	if c.CaptureDirDeletionThreshold < 0 {
 		return nil, nil, errors.New("capture_dir_deletion_threshold can't be negative")
 	}
+	if c.UploadRateLimit != nil {
+		if c.UploadRateLimit.BinaryBytesPerSec < 0 ||
+			c.UploadRateLimit.TabularBytesPerSec < 0 ||
+			c.UploadRateLimit.ArbitraryBytesPerSec < 0 {
+			return nil, nil, errors.New("upload_rate_limit can't be negative")
+		}
+	}
+	for _, w := range c.SyncWindows {
+		if err := w.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.Encryption != nil {
+		if err := c.Encryption.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
 	return []string{cloud.InternalServiceName.String()}, []string{framesystem.InternalServiceName.String()}, nil
 }
 
@@ -174,6 +208,11 @@ func (c *Config) syncConfig(syncSensor sensor.Sensor, syncSensorEnabled bool, lo
 			c.SyncIntervalMins, syncIntervalMinsEpsilon, defaultSyncIntervalMins)
 	}
 
+	var uploadRateLimit datasync.UploadRateLimit
+	if c.UploadRateLimit != nil {
+		uploadRateLimit = *c.UploadRateLimit
+	}
+
 	return datasync.Config{
 		AdditionalSyncPaths:         c.AdditionalSyncPaths,
 		Tags:                        c.Tags,
@@ -189,5 +228,8 @@ func (c *Config) syncConfig(syncSensor sensor.Sensor, syncSensorEnabled bool, lo
 		SyncIntervalMins:            syncIntervalMins,
 		SelectiveSyncSensor:         syncSensor,
 		SelectiveSyncSensorEnabled:  syncSensorEnabled,
+		UploadRateLimit:             uploadRateLimit,
+		SyncWindows:                 c.SyncWindows,
+		PriorityTags:                c.SyncPriorityTags,
 	}
 }
@@ -9,6 +9,7 @@ import (
 
 	"go.viam.com/test"
 
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/internal/cloud"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/services/datamanager/builtin/capture"
@@ -84,6 +85,55 @@ func TestConfig(t *testing.T) {
 				config: Config{CaptureDirDeletionThreshold: -1},
 				err:    errors.New("capture_dir_deletion_threshold can't be negative"),
 			},
+			{
+				name:   "returns an error if UploadRateLimit is negative",
+				config: Config{UploadRateLimit: &sync.UploadRateLimit{BinaryBytesPerSec: 100, TabularBytesPerSec: -1}},
+				err:    errors.New("upload_rate_limit can't be negative"),
+			},
+			{
+				name:   "returns an error if a SyncWindow has no duration",
+				config: Config{SyncWindows: []sync.SyncWindow{{Schedule: "0 22 * * *"}}},
+				err:    errors.New("sync window \"0 22 * * *\" must have a positive duration_mins"),
+			},
+			{
+				name: "returns an error if an encryption key is invalid",
+				config: Config{Encryption: &EncryptionConfig{
+					Keys: []data.EncryptionKeyConfig{{ID: "a", Key: "a", KeyFile: "/a"}},
+				}},
+				err: errors.New(`encryption key "a" must set exactly one of key and key_file`),
+			},
+			{
+				name: "returns an error if encryption keys are duplicated",
+				config: Config{Encryption: &EncryptionConfig{
+					Keys: []data.EncryptionKeyConfig{{ID: "a", Key: "a"}, {ID: "a", KeyFile: "/a"}},
+				}},
+				err: errors.New(`duplicate encryption key "a"`),
+			},
+			{
+				name: "returns an error if the active encryption key is not one of the keys",
+				config: Config{Encryption: &EncryptionConfig{
+					ActiveKeyID: "b",
+					Keys:        []data.EncryptionKeyConfig{{ID: "a", Key: "a"}},
+				}},
+				err: errors.New(`encryption active_key_id "b" is not one of the keys`),
+			},
+			{
+				name: "returns the internal cloud service name when encryption is valid",
+				config: Config{Encryption: &EncryptionConfig{
+					ActiveKeyID: "b",
+					Keys:        []data.EncryptionKeyConfig{{ID: "a", Key: "a"}, {ID: "b", KeyFile: "/b"}},
+				}},
+				deps: []string{cloud.InternalServiceName.String()},
+			},
+			{
+				name: "returns the internal cloud service name when upload limits are valid",
+				config: Config{
+					UploadRateLimit:  &sync.UploadRateLimit{BinaryBytesPerSec: 1000},
+					SyncWindows:      []sync.SyncWindow{{Schedule: "0 22 * * *", DurationMins: 480}},
+					SyncPriorityTags: []string{"critical"},
+				},
+				deps: []string{cloud.InternalServiceName.String()},
+			},
 		}
 
 		for _, tc := range tcs {
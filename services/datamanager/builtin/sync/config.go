@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"go.viam.com/rdk/components/sensor"
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/services/datamanager/builtin/shared"
 )
@@ -95,6 +96,17 @@ type Config struct {
 	// unil the Readings method of the SelectiveSyncSensor (when called on the SyncIntervalMins interval) returns
 	// the a key of datamanager.ShouldSyncKey and a value of `true`
 	SelectiveSyncSensor sensor.Sensor
+	// UploadRateLimit caps the bytes per second uploaded for each data type. Zero values are unlimited.
+	UploadRateLimit UploadRateLimit
+	// SyncWindows, when non empty, restricts scheduled sync to the times when at least one of the windows is open.
+	// Manual syncs ignore the windows.
+	SyncWindows []SyncWindow
+	// PriorityTags orders the files sent to sync on each pass: files with the first tag upload first, then files with
+	// the second tag, and so on, followed by untagged files.
+	PriorityTags []string
+	// Keyring decrypts capture files and arbitrary files that were encrypted at rest, and encrypts the files written
+	// by UploadBinaryDataToDatasets with its active key if it has one.
+	Keyring *data.Keyring
 }
 
 // SchedulerEnabled returns true if the sync scheduler should be running.
@@ -121,7 +133,11 @@ func (c Config) Equal(o Config) bool {
 		c.SyncIntervalMins == o.SyncIntervalMins &&
 		reflect.DeepEqual(c.Tags, o.Tags) &&
 		c.SelectiveSyncSensorEnabled == o.SelectiveSyncSensorEnabled &&
-		c.SelectiveSyncSensor == o.SelectiveSyncSensor
+		c.SelectiveSyncSensor == o.SelectiveSyncSensor &&
+		c.UploadRateLimit == o.UploadRateLimit &&
+		reflect.DeepEqual(c.SyncWindows, o.SyncWindows) &&
+		reflect.DeepEqual(c.PriorityTags, o.PriorityTags) &&
+		c.Keyring.Equal(o.Keyring)
 }
 
 func (c *Config) logDiff(o Config, logger logging.Logger) {
@@ -188,6 +204,22 @@ func (c *Config) logDiff(o Config, logger logging.Logger) {
 		}
 		logger.Infof("SelectiveSyncSensor: old: %s, new: %s", oldName, newName)
 	}
+
+	if c.UploadRateLimit != o.UploadRateLimit {
+		logger.Infof("upload_rate_limit: old: %+v, new: %+v", c.UploadRateLimit, o.UploadRateLimit)
+	}
+
+	if !reflect.DeepEqual(c.SyncWindows, o.SyncWindows) {
+		logger.Infof("sync_windows: old: %+v, new: %+v", c.SyncWindows, o.SyncWindows)
+	}
+
+	if !reflect.DeepEqual(c.PriorityTags, o.PriorityTags) {
+		logger.Infof("sync_priority_tags: old: %s, new: %s", strings.Join(c.PriorityTags, " "), strings.Join(o.PriorityTags, " "))
+	}
+
+	if !c.Keyring.Equal(o.Keyring) {
+		logger.Infof("encryption active_key_id: old: %q, new: %q", c.Keyring.ActiveKeyID(), o.Keyring.ActiveKeyID())
+	}
 }
 
 // SyncPaths returns the capture directory and additional sync paths as a slice.
@@ -201,6 +201,36 @@ func TestConfig(t *testing.T) {
 				},
 				equal: false,
 			},
+			{
+				name: "different UploadRateLimit are not equal",
+				a: Config{
+					UploadRateLimit: UploadRateLimit{BinaryBytesPerSec: 100},
+				},
+				b: Config{
+					UploadRateLimit: UploadRateLimit{BinaryBytesPerSec: 200},
+				},
+				equal: false,
+			},
+			{
+				name: "different SyncWindows are not equal",
+				a: Config{
+					SyncWindows: []SyncWindow{{Schedule: "0 22 * * *", DurationMins: 60}},
+				},
+				b: Config{
+					SyncWindows: []SyncWindow{{Schedule: "0 22 * * *", DurationMins: 120}},
+				},
+				equal: false,
+			},
+			{
+				name: "different PriorityTags are not equal",
+				a: Config{
+					PriorityTags: []string{"a", "b"},
+				},
+				b: Config{
+					PriorityTags: []string{"b", "a"},
+				},
+				equal: false,
+			},
 		}
 
 		for _, tc := range tcs {
@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+)
+
+// sortByPriority stably orders paths so that files tagged with earlier priority tags come first, followed by files
+// with none of the priority tags.
+func sortByPriority(paths []string, config Config, logger logging.Logger) {
+	sequenceTags := readSequenceTags(config.SyncPaths(), logger)
+	ranks := make(map[string]int, len(paths))
+	for _, path := range paths {
+		ranks[path] = priorityRank(fileTags(path, config, sequenceTags, logger), config.PriorityTags)
+	}
+	slices.SortStableFunc(paths, func(a, b string) int {
+		return ranks[a] - ranks[b]
+	})
+}
+
+// priorityRank returns the index of the first priority tag found in tags, or len(priorityTags) if there is none.
+func priorityRank(tags, priorityTags []string) int {
+	for i, priorityTag := range priorityTags {
+		if slices.Contains(tags, priorityTag) {
+			return i
+		}
+	}
+	return len(priorityTags)
+}
+
+// fileTags returns the tags the file at path will be uploaded with. Capture files written by a trigger also take on
+// the tags of their sequence, so that a critical sequence is uploaded along with all of its data.
+func fileTags(path string, config Config, sequenceTags map[string][]string, logger logging.Logger) []string {
+	switch {
+	case isSequenceFile(path):
+		return sequenceTags[sequenceID(path)]
+	case isCompletedCaptureFile(path):
+		//nolint:gosec
+		f, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer func() {
+			if err := f.Close(); err != nil {
+				logger.Debugw("failed to close capture file", "path", path, "error", err)
+			}
+		}()
+		captureFile, err := data.ReadCaptureFileWithKeyring(f, config.Keyring)
+		if err != nil {
+			return nil
+		}
+		tags := slices.Clone(captureFile.ReadMetadata().GetTags())
+		for _, tag := range tags {
+			if id, ok := strings.CutPrefix(tag, data.SequenceIDTagPrefix); ok {
+				tags = append(tags, sequenceTags[id]...)
+			}
+		}
+		return tags
+	default:
+		pathTags, _ := inferTagsAndDatasetIDsFromPath(path)
+		return append(slices.Clone(config.Tags), pathTags...)
+	}
+}
+
+// readSequenceTags returns the tags of every open and completed sequence under dirs, keyed by sequence ID.
+func readSequenceTags(dirs []string, logger logging.Logger) map[string][]string {
+	tags := map[string][]string{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(filepath.Join(dir, data.SequencesDir))
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != data.CompletedSequenceFileExt && ext != data.InProgressSequenceFileExt) {
+				continue
+			}
+			path := filepath.Join(dir, data.SequencesDir, entry.Name())
+			//nolint:gosec
+			bytes, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			var sf data.SequenceFile
+			if err := json.Unmarshal(bytes, &sf); err != nil {
+				logger.Debugw("failed to parse sequence file for sync priority", "path", path, "error", err)
+				continue
+			}
+			tags[sequenceID(path)] = sf.SequenceTags
+		}
+	}
+	return tags
+}
+
+func sequenceID(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
@@ -0,0 +1,177 @@
+package sync
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// UploadRateLimit caps the rate at which each type of data is uploaded, in bytes per second.
+// A zero value leaves that type of data unlimited.
+type UploadRateLimit struct {
+	BinaryBytesPerSec    float64 `json:"binary_bytes_per_sec,omitempty"`
+	TabularBytesPerSec   float64 `json:"tabular_bytes_per_sec,omitempty"`
+	ArbitraryBytesPerSec float64 `json:"arbitrary_bytes_per_sec,omitempty"`
+}
+
+// uploadRateLimiter holds a token bucket for each type of data, shared by every sync worker.
+type uploadRateLimiter struct {
+	binary    rateLimit
+	tabular   rateLimit
+	arbitrary rateLimit
+}
+
+func newUploadRateLimiter() *uploadRateLimiter {
+	return &uploadRateLimiter{
+		binary:    newRateLimit(),
+		tabular:   newRateLimit(),
+		arbitrary: newRateLimit(),
+	}
+}
+
+// setLimits updates the limits in place so that uploads already waiting pick up the new rates.
+func (l *uploadRateLimiter) setLimits(limits UploadRateLimit) {
+	l.binary.setLimit(limits.BinaryBytesPerSec)
+	l.tabular.setLimit(limits.TabularBytesPerSec)
+	l.arbitrary.setLimit(limits.ArbitraryBytesPerSec)
+}
+
+type rateLimit struct {
+	limiter *rate.Limiter
+	// waitedNanos is the total time uploads have spent waiting on the limit.
+	waitedNanos *atomic.Int64
+}
+
+func newRateLimit() rateLimit {
+	return rateLimit{limiter: rate.NewLimiter(rate.Inf, 0), waitedNanos: &atomic.Int64{}}
+}
+
+// setLimit sets the limit to bytesPerSec, allowing a burst of up to one second of data. A bytesPerSec of zero or less
+// removes the limit.
+func (r rateLimit) setLimit(bytesPerSec float64) {
+	if bytesPerSec <= 0 {
+		r.limiter.SetLimit(rate.Inf)
+		return
+	}
+	r.limiter.SetBurst(max(int(bytesPerSec), 1))
+	r.limiter.SetLimit(rate.Limit(bytesPerSec))
+}
+
+// bytesPerSec returns the current limit, or 0 if unlimited.
+func (r rateLimit) bytesPerSec() float64 {
+	limit := r.limiter.Limit()
+	if limit == rate.Inf {
+		return 0
+	}
+	return float64(limit)
+}
+
+func (r rateLimit) waitedMillis() uint64 {
+	return uint64(time.Duration(r.waitedNanos.Load()).Milliseconds())
+}
+
+// wait blocks until n bytes may be uploaded or ctx is done. Messages larger than the burst are let through a burst at a
+// time, so a single large message is spread out rather than rejected.
+func (r rateLimit) wait(ctx context.Context, n int) error {
+	if r.limiter.Limit() == rate.Inf {
+		return nil
+	}
+	start := time.Now()
+	defer func() {
+		r.waitedNanos.Add(int64(time.Since(start)))
+	}()
+	for n > 0 {
+		chunk := min(n, r.limiter.Burst())
+		if err := r.limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// rateLimitedClient is a v1.DataSyncServiceClient which waits on the upload rate limits before sending each message.
+type rateLimitedClient struct {
+	v1.DataSyncServiceClient
+	limiter *uploadRateLimiter
+}
+
+func newRateLimitedClient(client v1.DataSyncServiceClient, limiter *uploadRateLimiter) v1.DataSyncServiceClient {
+	return &rateLimitedClient{DataSyncServiceClient: client, limiter: limiter}
+}
+
+func (c *rateLimitedClient) DataCaptureUpload(
+	ctx context.Context,
+	in *v1.DataCaptureUploadRequest,
+	opts ...grpc.CallOption,
+) (*v1.DataCaptureUploadResponse, error) {
+	limit := c.limiter.tabular
+	if in.GetMetadata().GetType() == v1.DataType_DATA_TYPE_BINARY_SENSOR {
+		limit = c.limiter.binary
+	}
+	if err := limit.wait(ctx, proto.Size(in)); err != nil {
+		return nil, err
+	}
+	return c.DataSyncServiceClient.DataCaptureUpload(ctx, in, opts...)
+}
+
+func (c *rateLimitedClient) StreamingDataCaptureUpload(
+	ctx context.Context,
+	opts ...grpc.CallOption,
+) (v1.DataSyncService_StreamingDataCaptureUploadClient, error) {
+	stream, err := c.DataSyncServiceClient.StreamingDataCaptureUpload(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedStreamingDataCaptureUploadClient{
+		DataSyncService_StreamingDataCaptureUploadClient: stream,
+		ctx:   ctx,
+		limit: c.limiter.binary,
+	}, nil
+}
+
+func (c *rateLimitedClient) FileUpload(
+	ctx context.Context,
+	opts ...grpc.CallOption,
+) (v1.DataSyncService_FileUploadClient, error) {
+	stream, err := c.DataSyncServiceClient.FileUpload(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &rateLimitedFileUploadClient{
+		DataSyncService_FileUploadClient: stream,
+		ctx:                              ctx,
+		limit:                            c.limiter.arbitrary,
+	}, nil
+}
+
+type rateLimitedStreamingDataCaptureUploadClient struct {
+	v1.DataSyncService_StreamingDataCaptureUploadClient
+	ctx   context.Context
+	limit rateLimit
+}
+
+func (c *rateLimitedStreamingDataCaptureUploadClient) Send(req *v1.StreamingDataCaptureUploadRequest) error {
+	if err := c.limit.wait(c.ctx, proto.Size(req)); err != nil {
+		return err
+	}
+	return c.DataSyncService_StreamingDataCaptureUploadClient.Send(req)
+}
+
+type rateLimitedFileUploadClient struct {
+	v1.DataSyncService_FileUploadClient
+	ctx   context.Context
+	limit rateLimit
+}
+
+func (c *rateLimitedFileUploadClient) Send(req *v1.FileUploadRequest) error {
+	if err := c.limit.wait(c.ctx, proto.Size(req)); err != nil {
+		return err
+	}
+	return c.DataSyncService_FileUploadClient.Send(req)
+}
@@ -0,0 +1,103 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+	"google.golang.org/grpc"
+)
+
+func TestRateLimit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unlimited by default", func(t *testing.T) {
+		r := newRateLimit()
+		start := time.Now()
+		test.That(t, r.wait(ctx, 1<<30), test.ShouldBeNil)
+		test.That(t, time.Since(start), test.ShouldBeLessThan, 100*time.Millisecond)
+		test.That(t, r.bytesPerSec(), test.ShouldEqual, 0)
+		test.That(t, r.waitedMillis(), test.ShouldEqual, 0)
+	})
+
+	t.Run("waits for bytes beyond the burst", func(t *testing.T) {
+		r := newRateLimit()
+		r.setLimit(1e6)
+		test.That(t, r.bytesPerSec(), test.ShouldEqual, 1e6)
+		start := time.Now()
+		// Larger than the burst, so it is let through a burst at a time.
+		test.That(t, r.wait(ctx, 1.3e6), test.ShouldBeNil)
+		test.That(t, time.Since(start), test.ShouldBeGreaterThanOrEqualTo, 250*time.Millisecond)
+		test.That(t, r.waitedMillis(), test.ShouldBeGreaterThanOrEqualTo, 250)
+	})
+
+	t.Run("removing the limit", func(t *testing.T) {
+		r := newRateLimit()
+		r.setLimit(10)
+		r.setLimit(0)
+		test.That(t, r.bytesPerSec(), test.ShouldEqual, 0)
+		test.That(t, r.wait(ctx, 1<<20), test.ShouldBeNil)
+	})
+}
+
+func TestRateLimitedClient(t *testing.T) {
+	cancelledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sendFunc := func(*v1.StreamingDataCaptureUploadRequest) error { return nil }
+	mock := MockDataSyncServiceClient{
+		T: t,
+		DataCaptureUploadFunc: func(context.Context, *v1.DataCaptureUploadRequest, ...grpc.CallOption) (
+			*v1.DataCaptureUploadResponse, error,
+		) {
+			return &v1.DataCaptureUploadResponse{}, nil
+		},
+		StreamingDataCaptureUploadFunc: func(context.Context, ...grpc.CallOption) (
+			v1.DataSyncService_StreamingDataCaptureUploadClient, error,
+		) {
+			return &ClientStreamingMock[*v1.StreamingDataCaptureUploadRequest, *v1.StreamingDataCaptureUploadResponse]{
+				T:        t,
+				SendFunc: sendFunc,
+			}, nil
+		},
+		FileUploadFunc: func(context.Context, ...grpc.CallOption) (v1.DataSyncService_FileUploadClient, error) {
+			return &ClientStreamingMock[*v1.FileUploadRequest, *v1.FileUploadResponse]{
+				T:        t,
+				SendFunc: func(*v1.FileUploadRequest) error { return nil },
+			}, nil
+		},
+	}
+	streamReq := &v1.StreamingDataCaptureUploadRequest{
+		UploadPacket: &v1.StreamingDataCaptureUploadRequest_Data{Data: []byte("data")},
+	}
+	fileReq := &v1.FileUploadRequest{
+		UploadPacket: &v1.FileUploadRequest_FileContents{FileContents: &v1.FileData{Data: []byte("data")}},
+	}
+	binaryReq := &v1.DataCaptureUploadRequest{Metadata: &v1.UploadMetadata{Type: v1.DataType_DATA_TYPE_BINARY_SENSOR}}
+	tabularReq := &v1.DataCaptureUploadRequest{Metadata: &v1.UploadMetadata{Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR}}
+
+	// With the context already cancelled, only uploads of limited types fail, as only they wait on the limit.
+	limiter := newUploadRateLimiter()
+	limiter.setLimits(UploadRateLimit{BinaryBytesPerSec: 1})
+	client := newRateLimitedClient(mock, limiter)
+	_, err := client.DataCaptureUpload(cancelledCtx, binaryReq)
+	test.That(t, err, test.ShouldBeError, context.Canceled)
+	_, err = client.DataCaptureUpload(cancelledCtx, tabularReq)
+	test.That(t, err, test.ShouldBeNil)
+	stream, err := client.StreamingDataCaptureUpload(cancelledCtx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, stream.Send(streamReq), test.ShouldBeError, context.Canceled)
+	fileStream, err := client.FileUpload(cancelledCtx)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, fileStream.Send(fileReq), test.ShouldBeNil)
+
+	limiter.setLimits(UploadRateLimit{TabularBytesPerSec: 1, ArbitraryBytesPerSec: 1})
+	_, err = client.DataCaptureUpload(cancelledCtx, binaryReq)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = client.DataCaptureUpload(cancelledCtx, tabularReq)
+	test.That(t, err, test.ShouldBeError, context.Canceled)
+	test.That(t, stream.Send(streamReq), test.ShouldBeNil)
+	test.That(t, fileStream.Send(fileReq), test.ShouldBeError, context.Canceled)
+}
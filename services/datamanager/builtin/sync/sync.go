@@ -47,6 +47,9 @@ const (
 	durationBetweenAcquireConnection = time.Second
 )
 
+// errWindowClosed stops walking the sync paths once the sync window closes.
+var errWindowClosed = errors.New("sync window closed")
+
 // uploadStats tracks cumulative upload statistics.
 type uploadStats struct {
 	binary    dataTypeUploadStats
@@ -86,6 +89,16 @@ type FTDCUploadStats struct {
 	TabularSensorUploadedFileCount     uint64
 	TabularSensorCompletedUploadBytes  uint64 // bytes successfully uploaded (completed files)
 	TabularSensorUploadFailedFileCount uint64
+
+	// Upload limits - whether scheduled sync is inside a sync window, and the rate limit on each data type in bytes
+	// per second (0 when unlimited) along with the total time uploads have waited on it.
+	SyncWindowOpen                    bool
+	ArbitraryRateLimitBytesPerSec     float64
+	ArbitraryRateLimitWaitMillis      uint64
+	BinarySensorRateLimitBytesPerSec  float64
+	BinarySensorRateLimitWaitMillis   uint64
+	TabularSensorRateLimitBytesPerSec float64
+	TabularSensorRateLimitWaitMillis  uint64
 }
 
 // Sync manages uploading files (both written by data capture and by 3rd party applications)
@@ -108,9 +121,11 @@ type Sync struct {
 	clock             clock.Clock
 	uploadStats       *uploadStats
 	deletedFileCount  atomic.Int64
+	rateLimiter       *uploadRateLimiter
 
-	configMu sync.Mutex
-	config   Config
+	configMu    sync.Mutex
+	config      Config
+	syncWindows syncWindows
 
 	configCtx        context.Context
 	configCancelFunc func()
@@ -147,6 +162,7 @@ func New(
 		cloudConn:           cloudConn{ready: make(chan struct{})},
 		FileDeletingWorkers: goutils.NewBackgroundStoppableWorkers(),
 		uploadStats:         &uploadStats,
+		rateLimiter:         newUploadRateLimiter(),
 	}
 	return &s
 }
@@ -189,8 +205,10 @@ func (s *Sync) Reconfigure(_ context.Context, config Config, cloudConnSvc cloud.
 	// update config and reset config context
 	s.configMu.Lock()
 	s.config = config
+	s.syncWindows = parseSyncWindows(config.SyncWindows)
 	s.configCtx, s.configCancelFunc = context.WithCancel(context.Background())
 	s.configMu.Unlock()
+	s.rateLimiter.setLimits(config.UploadRateLimit)
 
 	// start workers
 	s.startWorkers(config)
@@ -231,6 +249,9 @@ func (s *Sync) Reconfigure(_ context.Context, config Config, cloudConnSvc cloud.
 
 // GetStats returns cumulative file deletion and upload metrics.
 func (s *Sync) GetStats() FTDCStats {
+	s.configMu.Lock()
+	syncWindowOpen := s.syncWindows.open(s.clock.Now())
+	s.configMu.Unlock()
 	return FTDCStats{
 		// File deletion metric.
 		FilesDeletedToFreeSpace: s.deletedFileCount.Load(),
@@ -252,6 +273,15 @@ func (s *Sync) GetStats() FTDCStats {
 			TabularSensorCompletedUploadBytes:  s.uploadStats.tabular.completedUploadBytes.Load(),
 			TabularSensorUploadedFileCount:     s.uploadStats.tabular.uploadedFileCount.Load(),
 			TabularSensorUploadFailedFileCount: s.uploadStats.tabular.uploadFailedFileCount.Load(),
+
+			// Upload limits.
+			SyncWindowOpen:                    syncWindowOpen,
+			ArbitraryRateLimitBytesPerSec:     s.rateLimiter.arbitrary.bytesPerSec(),
+			ArbitraryRateLimitWaitMillis:      s.rateLimiter.arbitrary.waitedMillis(),
+			BinarySensorRateLimitBytesPerSec:  s.rateLimiter.binary.bytesPerSec(),
+			BinarySensorRateLimitWaitMillis:   s.rateLimiter.binary.waitedMillis(),
+			TabularSensorRateLimitBytesPerSec: s.rateLimiter.tabular.bytesPerSec(),
+			TabularSensorRateLimitWaitMillis:  s.rateLimiter.tabular.waitedMillis(),
 		},
 	}
 }
@@ -277,7 +307,7 @@ func (s *Sync) CloudConnReady() chan struct{} {
 
 // Sync performs a non-scheduled sync of the data in the capture directory.
 // If automated sync is also enabled, calling Sync will upload the files,
-// regardless of whether or not is the scheduled time.
+// regardless of whether or not is the scheduled time or inside a sync window.
 func (s *Sync) Sync(ctx context.Context, _ map[string]interface{}) error {
 	select {
 	case <-s.cloudConn.ready:
@@ -287,7 +317,7 @@ func (s *Sync) Sync(ctx context.Context, _ map[string]interface{}) error {
 	s.configMu.Lock()
 	config := s.config
 	s.configMu.Unlock()
-	return s.walkDirsAndSendFilesToSync(ctx, config)
+	return s.walkDirsAndSendFilesToSync(ctx, config, nil)
 }
 
 type cloudConn struct {
@@ -359,7 +389,7 @@ func (s *Sync) runCloudConnManager(
 			return
 		}
 		s.cloudConn.conn = checker
-		s.cloudConn.client = s.clientConstructor(conn)
+		s.cloudConn.client = newRateLimitedClient(s.clientConstructor(conn), s.rateLimiter)
 		s.cloudConn.dataClient = datapb.NewDataServiceClient(conn)
 		s.logger.Info("cloud connection ready")
 		close(s.cloudConn.ready)
@@ -446,18 +476,19 @@ func (s *Sync) syncFile(config Config, filePath string) {
 	}
 
 	if data.IsDataCaptureFile(f) {
-		s.syncDataCaptureFile(f, config.CaptureDir, s.logger)
+		s.syncDataCaptureFile(f, config.CaptureDir, config.Keyring, s.logger)
 	} else {
 		//nolint:errcheck
-		s.syncArbitraryFile(s.configCtx, f, config.Tags, []string{}, config.FileLastModifiedMillis, s.logger)
+		s.syncArbitraryFile(s.configCtx, f, config.Tags, []string{}, config.FileLastModifiedMillis, config.Keyring, s.logger)
 	}
 }
 
-func (s *Sync) syncDataCaptureFile(f *os.File, captureDir string, logger logging.Logger) {
-	captureFile, err := data.ReadCaptureFile(f)
+func (s *Sync) syncDataCaptureFile(f *os.File, captureDir string, keyring *data.Keyring, logger logging.Logger) {
+	captureFile, err := data.ReadCaptureFileWithKeyring(f, keyring)
 	// if you can't read the capture file's metadata field, close & move it to the failed directory
 	if err != nil {
 		cause := errors.Wrap(err, "ReadCaptureFile failed")
+		logIfUnknownEncryptionKey(err, f.Name(), logger)
 
 		if err := f.Close(); err != nil {
 			logger.Error(errors.Wrapf(err, "failed to close file %s", f.Name()).Error())
@@ -529,14 +560,15 @@ func (s *Sync) syncDataCaptureFile(f *os.File, captureDir string, logger logging
 }
 
 func (s *Sync) syncArbitraryFile(
-	ctx context.Context, f *os.File, tags, datasetIDs []string, fileLastModifiedMillis int,
+	ctx context.Context, f *os.File, tags, datasetIDs []string, fileLastModifiedMillis int, keyring *data.Keyring,
 	logger logging.Logger,
 ) (string, error) {
 	var uploadedID string
 	retry := newExponentialRetry(ctx, s.clock, s.logger, f.Name(), func(ctx context.Context) (uint64, error) {
 		errMetadata := fmt.Sprintf("error uploading arbitrary file %s", f.Name())
 		bytesUploaded, id, err := uploadArbitraryFile(
-			ctx, f, s.cloudConn, tags, datasetIDs, fileLastModifiedMillis, s.clock, logger, &s.uploadStats.arbitrary.uploadingBytes,
+			ctx, f, s.cloudConn, tags, datasetIDs, fileLastModifiedMillis, keyring, s.clock, logger,
+			&s.uploadStats.arbitrary.uploadingBytes,
 		)
 		if err != nil {
 			return 0, errors.Wrap(err, errMetadata)
@@ -559,6 +591,7 @@ func (s *Sync) syncArbitraryFile(
 		}
 
 		// otherwise we hit a terminal error, and we should move the file to the failed directory
+		logIfUnknownEncryptionKey(err, f.Name(), logger)
 		if err := moveFailedData(f.Name(), path.Dir(f.Name()), err, logger); err != nil {
 			logger.Error(err.Error())
 		}
@@ -580,6 +613,9 @@ func (s *Sync) syncArbitraryFile(
 
 // UploadBinaryDataToDatasets simultaneously uploads binary data and adds it to a dataset.
 func (s *Sync) UploadBinaryDataToDatasets(ctx context.Context, binaryData []byte, datasetIDs, tags []string, mimeType v1.MimeType) error {
+	s.configMu.Lock()
+	keyring := s.config.Keyring
+	s.configMu.Unlock()
 	errChan := make(chan error, 1)
 	go func() {
 		defer close(errChan)
@@ -595,7 +631,7 @@ func (s *Sync) UploadBinaryDataToDatasets(ctx context.Context, binaryData []byte
 			filename += fileExtensionFromMimeType
 		}
 		filename = filepath.Join(s.config.CaptureDir, DatasetDir, filepath.Clean(filename))
-		err := os.WriteFile(filename, binaryData, 0o600)
+		err := data.WriteEncryptedFile(filename, binaryData, keyring)
 		if err != nil {
 			s.logger.Errorw("error writing file", "err", err)
 			errChan <- err
@@ -609,12 +645,21 @@ func (s *Sync) UploadBinaryDataToDatasets(ctx context.Context, binaryData []byte
 		}
 		// Since we wrote to the file, the file last modified time should be 0, indicating we should wait no time
 		// before deciding this file is ready for upload and is not still being written to.
-		s.syncArbitraryFile(ctx, f, tags, datasetIDs, 0, s.logger) //nolint:errcheck
+		s.syncArbitraryFile(ctx, f, tags, datasetIDs, 0, keyring, s.logger) //nolint:errcheck
 	}()
 
 	return <-errChan
 }
 
+// logIfUnknownEncryptionKey explains how to recover a file that is moved to the failed directory because it was
+// encrypted with a key that is not configured.
+func logIfUnknownEncryptionKey(err error, path string, logger logging.Logger) {
+	if errors.Is(err, data.ErrUnknownEncryptionKey) {
+		logger.Errorw("file is encrypted with a key that is not configured; add the key to the data manager's "+
+			"encryption keys and move the file out of the failed directory to sync it", "path", path, "error", err)
+	}
+}
+
 // moveFailedData takes any data that could not be synced in the parentDir and
 // moves it to a new subdirectory "failed" that will not be synced.
 func moveFailedData(path, parentDir string, cause error, logger logging.Logger) error {
@@ -643,6 +688,7 @@ func moveFailedData(path, parentDir string, cause error, logger logging.Logger)
 func (s *Sync) runScheduler(ctx context.Context, tkr *clock.Ticker, config Config) {
 	defer tkr.Stop()
 	var readyLogged bool
+	windows := parseSyncWindows(config.SyncWindows)
 
 	// lastNotSyncedLog throttles the noisy "not syncing" logs
 	var lastNotSyncedLog time.Time
@@ -688,10 +734,17 @@ func (s *Sync) runScheduler(ctx context.Context, tkr *clock.Ticker, config Confi
 				}
 				continue
 			}
+			if !windows.open(s.clock.Now()) {
+				if now := s.clock.Now(); now.Sub(lastNotSyncedLog) >= time.Minute {
+					lastNotSyncedLog = now
+					s.logger.Info("data manager: NOT syncing data to the cloud as it is outside of the configured sync windows")
+				}
+				continue
+			}
 			// syncing; reset the throttle so a future not-synced reason logs immediately.
 			lastNotSyncedLog = time.Time{}
 
-			if err := s.walkDirsAndSendFilesToSync(ctx, config); err != nil && !errors.Is(err, context.Canceled) {
+			if err := s.walkDirsAndSendFilesToSync(ctx, config, windows); err != nil && !errors.Is(err, context.Canceled) {
 				goutils.UncheckedError(err)
 			}
 		}
@@ -700,8 +753,21 @@ func (s *Sync) runScheduler(ctx context.Context, tkr *clock.Ticker, config Confi
 
 // returns early with an error if either ctx is cancelled or if the reconfigure is called
 // while walkDirsAndSendFilesToSync.
-func (s *Sync) walkDirsAndSendFilesToSync(ctx context.Context, config Config) error {
+// Stops sending files once windows closes. When config has priority tags, the files are
+// collected and sorted by priority before any are sent.
+func (s *Sync) walkDirsAndSendFilesToSync(ctx context.Context, config Config, windows syncWindows) error {
 	s.flushCollectors()
+	prioritize := len(config.PriorityTags) > 0
+	var prioritized []string
+	// send returns false once the sync window has closed.
+	send := func(path string) bool {
+		if !windows.open(s.clock.Now()) {
+			s.logger.Info("data manager: sync window closed, remaining files will sync in the next window")
+			return false
+		}
+		s.sendToSync(ctx, path)
+		return true
+	}
 	var errs []error
 	for _, dir := range config.SyncPaths() {
 		s.logger.Debugf("syncing from: %s", dir)
@@ -743,12 +809,27 @@ func (s *Sync) walkDirsAndSendFilesToSync(ctx context.Context, config Config) er
 					loggedDirPaths[dirPath] = true
 					s.logger.Debugf("syncing from subdirectory: %s", dirPath)
 				}
-				s.sendToSync(ctx, path)
+				if prioritize {
+					prioritized = append(prioritized, path)
+				} else if !send(path) {
+					return errWindowClosed
+				}
 			}
 			return nil
 		})
+		if errors.Is(err, errWindowClosed) {
+			break
+		}
 		errs = append(errs, err)
 	}
+	if prioritize {
+		sortByPriority(prioritized, config, s.logger)
+		for _, path := range prioritized {
+			if ctx.Err() != nil || s.configCtx.Err() != nil || !send(path) {
+				break
+			}
+		}
+	}
 	errs = append(errs, ctx.Err(), s.configCtx.Err())
 	return multierr.Combine(errs...)
 }
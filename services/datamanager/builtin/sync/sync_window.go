@@ -0,0 +1,73 @@
+package sync
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// SyncWindow is a period during which scheduled sync is allowed to upload. The window opens at each time matched by
+// Schedule and stays open for DurationMins.
+type SyncWindow struct {
+	// Schedule is a cron expression, with optional seconds, evaluated in local time unless prefixed with CRON_TZ=.
+	// For example "0 22 * * *" opens the window at 10pm every day.
+	Schedule string `json:"schedule"`
+	// DurationMins is how long the window stays open after each time Schedule matches.
+	DurationMins float64 `json:"duration_mins"`
+}
+
+var syncWindowParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// Validate returns an error if the window's schedule cannot be parsed or its duration is not positive.
+func (w SyncWindow) Validate() error {
+	_, err := w.parse()
+	return err
+}
+
+func (w SyncWindow) parse() (syncWindow, error) {
+	schedule, err := syncWindowParser.Parse(w.Schedule)
+	if err != nil {
+		return syncWindow{}, errors.Wrapf(err, "invalid sync window schedule %q", w.Schedule)
+	}
+	if w.DurationMins <= 0 {
+		return syncWindow{}, errors.Errorf("sync window %q must have a positive duration_mins", w.Schedule)
+	}
+	return syncWindow{schedule: schedule, duration: time.Duration(w.DurationMins * float64(time.Minute))}, nil
+}
+
+type syncWindow struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// syncWindows is a parsed set of sync windows. An empty set is always open.
+type syncWindows []syncWindow
+
+// parseSyncWindows parses windows, skipping any that are invalid, as those are rejected when the config is validated.
+func parseSyncWindows(windows []SyncWindow) syncWindows {
+	var parsed syncWindows
+	for _, w := range windows {
+		if p, err := w.parse(); err == nil {
+			parsed = append(parsed, p)
+		}
+	}
+	return parsed
+}
+
+// open returns true if t falls inside any of the windows, or if there are no windows.
+func (ws syncWindows) open(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	for _, w := range ws {
+		// The window is open if it was last opened no more than its duration ago, which is when the first activation
+		// after t-duration is not after t.
+		if !w.schedule.Next(t.Add(-w.duration)).After(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package sync
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "go.viam.com/api/app/datasync/v1"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+)
+
+func TestSyncWindows(t *testing.T) {
+	t.Run("Validate()", func(t *testing.T) {
+		test.That(t, SyncWindow{Schedule: "0 22 * * *", DurationMins: 60}.Validate(), test.ShouldBeNil)
+		test.That(t, SyncWindow{Schedule: "30 0 22 * * *", DurationMins: 60}.Validate(), test.ShouldBeNil)
+		test.That(t, SyncWindow{Schedule: "@daily", DurationMins: 60}.Validate(), test.ShouldBeNil)
+		test.That(t, SyncWindow{Schedule: "not a schedule", DurationMins: 60}.Validate(), test.ShouldNotBeNil)
+		test.That(t, SyncWindow{Schedule: "0 22 * * *"}.Validate(), test.ShouldNotBeNil)
+	})
+
+	t.Run("open()", func(t *testing.T) {
+		at := func(hour, minute int) time.Time {
+			return time.Date(2024, time.March, 4, hour, minute, 0, 0, time.Local)
+		}
+		test.That(t, syncWindows(nil).open(at(12, 0)), test.ShouldBeTrue)
+
+		// 10pm for 8 hours, which crosses midnight, and 12pm for 30 minutes.
+		windows := parseSyncWindows([]SyncWindow{
+			{Schedule: "0 22 * * *", DurationMins: 480},
+			{Schedule: "0 12 * * *", DurationMins: 30},
+		})
+		test.That(t, len(windows), test.ShouldEqual, 2)
+		for _, tc := range []struct {
+			t    time.Time
+			open bool
+		}{
+			{at(21, 59), false},
+			{at(22, 0), true},
+			{at(23, 30), true},
+			{at(2, 0), true},
+			{at(5, 59), true},
+			{at(6, 1), false},
+			{at(11, 59), false},
+			{at(12, 0), true},
+			{at(12, 29), true},
+			{at(12, 31), false},
+		} {
+			test.That(t, windows.open(tc.t), test.ShouldEqual, tc.open)
+		}
+	})
+}
+
+func TestSortByPriority(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	captureDir := t.TempDir()
+	sequencesDir := filepath.Join(captureDir, data.SequencesDir)
+	test.That(t, os.MkdirAll(sequencesDir, 0o700), test.ShouldBeNil)
+
+	writeSequence := func(name string, tags []string) string {
+		bytes, err := json.Marshal(data.SequenceFile{SequenceTags: tags})
+		test.That(t, err, test.ShouldBeNil)
+		return writeTestFile(t, sequencesDir, name, bytes)
+	}
+	writeCapture := func(dir string, tags []string) string {
+		test.That(t, os.MkdirAll(dir, 0o700), test.ShouldBeNil)
+		f, err := data.NewCaptureFile(dir, &v1.DataCaptureMetadata{Type: v1.DataType_DATA_TYPE_TABULAR_SENSOR, Tags: tags})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, f.Close(), test.ShouldBeNil)
+		return f.GetPath()[:len(f.GetPath())-len(data.InProgressCaptureFileExt)] + data.CompletedCaptureFileExt
+	}
+
+	criticalSeq := writeSequence("crit"+data.CompletedSequenceFileExt, []string{"critical"})
+	writeSequence("open"+data.InProgressSequenceFileExt, []string{"critical"})
+	untagged := writeCapture(filepath.Join(captureDir, "a"), nil)
+	debug := writeCapture(filepath.Join(captureDir, "b"), []string{"debug"})
+	// Capture files take on the tags of the sequence they belong to, even while it is open.
+	inOpenSequence := writeCapture(filepath.Join(captureDir, "c"), []string{data.SequenceIDTagPrefix + "open"})
+	arbitraryUntagged := writeTestFile(t, captureDir, "notes.txt", []byte("hi"))
+	debugDir := filepath.Join(captureDir, "tag=debug")
+	test.That(t, os.MkdirAll(debugDir, 0o700), test.ShouldBeNil)
+	arbitraryDebug := writeTestFile(t, debugDir, "log.txt", []byte("hi"))
+
+	paths := []string{untagged, arbitraryUntagged, debug, arbitraryDebug, inOpenSequence, criticalSeq}
+	sortByPriority(paths, Config{CaptureDir: captureDir, PriorityTags: []string{"critical", "debug"}}, logger)
+	test.That(t, paths, test.ShouldResemble, []string{
+		inOpenSequence, criticalSeq, debug, arbitraryDebug, untagged, arbitraryUntagged,
+	})
+
+	// Config tags apply to arbitrary files.
+	paths = []string{untagged, arbitraryUntagged}
+	sortByPriority(paths, Config{CaptureDir: captureDir, Tags: []string{"debug"}, PriorityTags: []string{"debug"}}, logger)
+	test.That(t, paths, test.ShouldResemble, []string{arbitraryUntagged, untagged})
+}
@@ -16,6 +16,7 @@ import (
 	goutils "go.viam.com/utils"
 	"google.golang.org/protobuf/types/known/timestamppb"
 
+	"go.viam.com/rdk/data"
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/utils"
 )
@@ -38,6 +39,7 @@ func uploadArbitraryFile(
 	conn cloudConn,
 	tags, datasetIDs []string,
 	fileLastModifiedMillis int,
+	keyring *data.Keyring,
 	clock clock.Clock,
 	logger logging.Logger,
 	bytesUploadingCounter *atomic.Uint64,
@@ -77,6 +79,14 @@ func uploadArbitraryFile(
 		return 0, "", fmt.Errorf("error trying to seek to beginning of file %s: expected position 0, instead got to position %d", path, pos)
 	}
 
+	// Files encrypted at rest are decrypted as they are uploaded.
+	var r io.Reader = f
+	if data.IsEncryptedFile(f) {
+		if r, err = data.NewDecryptingReader(f, keyring); err != nil {
+			return 0, "", errors.Wrapf(err, "failed to decrypt %s", path)
+		}
+	}
+
 	// Get file timestamps
 	fileTimes, err := utils.GetFileTimes(path)
 	if err != nil {
@@ -126,7 +136,7 @@ func uploadArbitraryFile(
 		return 0, "", errors.Wrap(err, "FileUpload failed sending metadata")
 	}
 
-	if err := sendFileUploadRequests(ctx, stream, f, path, logger, bytesUploadingCounter); err != nil {
+	if err := sendFileUploadRequests(ctx, stream, r, path, logger, bytesUploadingCounter); err != nil {
 		return 0, "", errors.Wrap(err, "FileUpload failed to sync")
 	}
 
@@ -141,7 +151,7 @@ func uploadArbitraryFile(
 func sendFileUploadRequests(
 	ctx context.Context,
 	stream v1.DataSyncService_FileUploadClient,
-	f *os.File,
+	r io.Reader,
 	path string,
 	logger logging.Logger,
 	bytesUploadingCounter *atomic.Uint64,
@@ -153,7 +163,7 @@ func sendFileUploadRequests(
 			return err
 		}
 		// Get the next UploadRequest from the file.
-		uploadReq, err := getNextFileUploadRequest(f)
+		uploadReq, err := getNextFileUploadRequest(r)
 
 		// EOF means we've completed successfully.
 		if errors.Is(err, io.EOF) {
@@ -181,9 +191,9 @@ func sendFileUploadRequests(
 	}
 }
 
-func getNextFileUploadRequest(f *os.File) (*v1.FileUploadRequest, error) {
+func getNextFileUploadRequest(r io.Reader) (*v1.FileUploadRequest, error) {
 	// Get the next file data reading from file, check for an error.
-	next, err := readNextFileChunk(f)
+	next, err := readNextFileChunk(r)
 	if err != nil {
 		return nil, err
 	}
@@ -195,9 +205,9 @@ func getNextFileUploadRequest(f *os.File) (*v1.FileUploadRequest, error) {
 	}, nil
 }
 
-func readNextFileChunk(f *os.File) (*v1.FileData, error) {
+func readNextFileChunk(r io.Reader) (*v1.FileData, error) {
 	byteArr := make([]byte, UploadChunkSize)
-	numBytesRead, err := f.Read(byteArr)
+	numBytesRead, err := r.Read(byteArr)
 	if err != nil {
 		return nil, err
 	}
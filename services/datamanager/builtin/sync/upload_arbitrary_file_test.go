@@ -1,11 +1,23 @@
 package sync
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"os"
+	"path/filepath"
 	"runtime"
 	"testing"
 
+	"github.com/benbjohnson/clock"
+	"github.com/pkg/errors"
+	v1 "go.viam.com/api/app/datasync/v1"
 	"go.viam.com/test"
 	goutils "go.viam.com/utils"
+	"google.golang.org/grpc"
+
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
 )
 
 func TestInferTagsAndDatasetIDsFromFilename(t *testing.T) {
@@ -47,3 +59,46 @@ func TestInferTagsAndDatasetIDsFromFilename(t *testing.T) {
 		test.That(t, goutils.NewStringSet(gotDatasetIDs...), test.ShouldResemble, goutils.NewStringSet("1023"))
 	})
 }
+
+func TestUploadEncryptedArbitraryFile(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	test.That(t, err, test.ShouldBeNil)
+	keyring, err := data.NewKeyring("a", map[string][]byte{"a": key})
+	test.That(t, err, test.ShouldBeNil)
+
+	contents := bytes.Repeat([]byte("arbitrary file contents "), UploadChunkSize/8)
+	path := filepath.Join(t.TempDir(), "file.txt")
+	test.That(t, data.WriteEncryptedFile(path, contents, keyring), test.ShouldBeNil)
+
+	var uploaded []byte
+	conn := cloudConn{partID: "part", client: MockDataSyncServiceClient{
+		T: t,
+		FileUploadFunc: func(context.Context, ...grpc.CallOption) (v1.DataSyncService_FileUploadClient, error) {
+			return &ClientStreamingMock[*v1.FileUploadRequest, *v1.FileUploadResponse]{
+				T: t,
+				SendFunc: func(req *v1.FileUploadRequest) error {
+					uploaded = append(uploaded, req.GetFileContents().GetData()...)
+					return nil
+				},
+				CloseAndRecvFunc: func() (*v1.FileUploadResponse, error) { return &v1.FileUploadResponse{}, nil },
+			}, nil
+		},
+	}}
+
+	//nolint:gosec
+	f, err := os.Open(path)
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	_, _, err = uploadArbitraryFile(context.Background(), f, conn, nil, nil, 0, keyring, clock.New(), logger, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, uploaded, test.ShouldResemble, contents)
+
+	// Files encrypted with a key that is not configured fail terminally.
+	otherKeyring, err := data.NewKeyring("b", map[string][]byte{"b": key})
+	test.That(t, err, test.ShouldBeNil)
+	_, _, err = uploadArbitraryFile(context.Background(), f, conn, nil, nil, 0, otherKeyring, clock.New(), logger, nil)
+	test.That(t, errors.Is(err, data.ErrUnknownEncryptionKey), test.ShouldBeTrue)
+	test.That(t, terminalError(err), test.ShouldBeTrue)
+}
@@ -32,6 +32,8 @@ var (
 		errMultipleReadingTypes,
 		errSensorDataTypesDontMatchUploadMetadata,
 		errInvalidCaptureFileType,
+		data.ErrUnknownEncryptionKey,
+		data.ErrDecryptionFailed,
 	}
 )
 
@@ -2,6 +2,7 @@ package sync
 
 import (
 	"context"
+	crand "crypto/rand"
 	"os"
 	"slices"
 	"sort"
@@ -921,3 +922,43 @@ func compareSensorData(t *testing.T, dataType v1.DataType, act, exp []*v1.Sensor
 		}
 	}
 }
+
+func TestUploadEncryptedDataCaptureFile(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	key := make([]byte, 32)
+	_, err := crand.Read(key)
+	test.That(t, err, test.ShouldBeNil)
+	keyring, err := data.NewKeyring("a", map[string][]byte{"a": key})
+	test.That(t, err, test.ShouldBeNil)
+
+	payload := []byte("encrypted image")
+	md := &v1.DataCaptureMetadata{ComponentName: "cam", MethodName: "ReadImage", Type: v1.DataType_DATA_TYPE_BINARY_SENSOR}
+	w, err := data.NewCaptureFileWithKeyring(t.TempDir(), md, keyring)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, w.WriteNext(&v1.SensorData{Metadata: &v1.SensorMetadata{}, Data: &v1.SensorData_Binary{Binary: payload}}),
+		test.ShouldBeNil)
+	test.That(t, w.Close(), test.ShouldBeNil)
+
+	//nolint:gosec
+	f, err := os.Open(strings.Replace(w.GetPath(), data.InProgressCaptureFileExt, data.CompletedCaptureFileExt, 1))
+	test.That(t, err, test.ShouldBeNil)
+	defer f.Close()
+	cf, err := data.ReadCaptureFileWithKeyring(f, keyring)
+	test.That(t, err, test.ShouldBeNil)
+
+	var uploaded [][]byte
+	cc := cloudConn{partID: "part", client: MockDataSyncServiceClient{
+		T: t,
+		DataCaptureUploadFunc: func(
+			_ context.Context, in *v1.DataCaptureUploadRequest, _ ...grpc.CallOption,
+		) (*v1.DataCaptureUploadResponse, error) {
+			for _, sd := range in.GetSensorContents() {
+				uploaded = append(uploaded, sd.GetBinary())
+			}
+			return &v1.DataCaptureUploadResponse{}, nil
+		},
+	}}
+	_, err = uploadDataCaptureFile(context.Background(), cf, cc, logger, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, uploaded, test.ShouldResemble, [][]byte{payload})
+}
@@ -27,6 +27,7 @@ func (s *Sync) UploadDataFromPath(ctx context.Context, path string, uploadMetada
 
 	s.configMu.Lock()
 	configCtx := s.configCtx
+	keyring := s.config.Keyring
 	s.configMu.Unlock()
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -70,7 +71,7 @@ func (s *Sync) UploadDataFromPath(ctx context.Context, path string, uploadMetada
 		result.BytesTotal += uint64(fi.Size())
 
 		uploadedBytes, id, uploadErr := uploadArbitraryFile(ctx, f, s.cloudConn,
-			tags, datasetIDs, 0, s.clock, s.logger, &s.uploadStats.arbitrary.uploadingBytes)
+			tags, datasetIDs, 0, keyring, s.clock, s.logger, &s.uploadStats.arbitrary.uploadingBytes)
 		if closeErr := f.Close(); closeErr != nil {
 			s.logger.Warnw("failed to close file after upload", "path", filePath, "error", closeErr)
 		}
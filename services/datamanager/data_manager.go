@@ -172,6 +172,23 @@ type DataCaptureConfig struct {
 	Disabled           bool                   `json:"disabled"`
 	Tags               []string               `json:"tags,omitempty"`
 	CaptureDirectory   string                 `json:"capture_directory"`
+	// PreTriggerSeconds and PostTriggerSeconds, when either is set, keep the readings in memory instead of writing
+	// them to disk, and only write the readings from PreTriggerSeconds before a trigger until PostTriggerSeconds
+	// after it. A trigger is a sequence that includes the resource opening, or a trigger_capture DoCommand. At most
+	// 64 MiB of readings are kept from before a trigger; the oldest are dropped past that.
+	PreTriggerSeconds  float32 `json:"pre_trigger_seconds,omitempty"`
+	PostTriggerSeconds float32 `json:"post_trigger_seconds,omitempty"`
+	// Deadband, when set on a tabular method, only writes a reading when it changes.
+	Deadband *DeadbandConfig `json:"deadband,omitempty"`
+}
+
+// DeadbandConfig makes a collector write a reading only when a number in it moves by more than both Absolute and
+// Relative times its last written value, when anything else in it changes, or when MaxIntervalSeconds have passed
+// since the last written reading.
+type DeadbandConfig struct {
+	Absolute           float64 `json:"absolute,omitempty"`
+	Relative           float64 `json:"relative,omitempty"`
+	MaxIntervalSeconds float64 `json:"max_interval_seconds,omitempty"`
 }
 
 // Equals checks if one capture config is equal to another.
@@ -184,7 +201,10 @@ func (c *DataCaptureConfig) Equals(other *DataCaptureConfig) bool {
 		c.Disabled == other.Disabled &&
 		slices.Compare(c.Tags, other.Tags) == 0 &&
 		reflect.DeepEqual(c.AdditionalParams, other.AdditionalParams) &&
-		c.CaptureDirectory == other.CaptureDirectory
+		c.CaptureDirectory == other.CaptureDirectory &&
+		c.PreTriggerSeconds == other.PreTriggerSeconds &&
+		c.PostTriggerSeconds == other.PostTriggerSeconds &&
+		reflect.DeepEqual(c.Deadband, other.Deadband)
 }
 
 // ShouldSyncKey is a special key we use within a modular sensor to pass a boolean
@@ -194,6 +214,10 @@ var ShouldSyncKey = "should_sync"
 // SequencesKey is the key under which a capture control sensor returns sequence readings.
 var SequencesKey = "sequences"
 
+// TriggerCaptureKey is the DoCommand key that triggers the collectors that capture around triggers. Its value is a
+// SequenceReading, whose resources may be left empty to trigger all of them.
+var TriggerCaptureKey = "trigger_capture"
+
 // CreateShouldSyncReading is a helper for creating the expected reading for a modular sensor
 // that passes a bool to the datamanager to indicate whether or not we want to sync.
 func CreateShouldSyncReading(toSync bool) map[string]interface{} {
@@ -0,0 +1,394 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/services/motion"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+// baseMotionConfig is a MotionConfiguration with every unset value replaced by its default.
+type baseMotionConfig struct {
+	planDeviationMM    float64
+	linearMMPerSec     float64
+	angularDegsPerSec  float64
+	positionPollingHz  float64
+	obstaclePollingHz  float64
+	obstacleDetectors  []motion.ObstacleDetectorName
+	collisionBufferMM  float64
+	maxReplans         int
+	planningResolution float64
+}
+
+func newBaseMotionConfig(
+	motionCfg *motion.MotionConfiguration,
+	defaultPlanDeviationM float64,
+	extra map[string]interface{},
+) (baseMotionConfig, error) {
+	cfg := baseMotionConfig{
+		planDeviationMM:    1e3 * defaultPlanDeviationM,
+		linearMMPerSec:     1e3 * defaultLinearMPerSec,
+		angularDegsPerSec:  defaultAngularDegsPerSec,
+		positionPollingHz:  defaultPositionPollingHz,
+		obstaclePollingHz:  defaultObstaclePollingHz,
+		collisionBufferMM:  defaultCollisionBuffer,
+		maxReplans:         defaultMaxReplans,
+		planningResolution: defaultBaseResolutionMM,
+	}
+	if motionCfg != nil {
+		if motionCfg.PlanDeviationMM < 0 || motionCfg.LinearMPerSec < 0 || motionCfg.AngularDegsPerSec < 0 {
+			return baseMotionConfig{}, errors.New("PlanDeviationMM, LinearMPerSec and AngularDegsPerSec may not be negative")
+		}
+		if motionCfg.PlanDeviationMM != 0 {
+			cfg.planDeviationMM = motionCfg.PlanDeviationMM
+		}
+		if motionCfg.LinearMPerSec != 0 {
+			cfg.linearMMPerSec = 1e3 * motionCfg.LinearMPerSec
+		}
+		if motionCfg.AngularDegsPerSec != 0 {
+			cfg.angularDegsPerSec = motionCfg.AngularDegsPerSec
+		}
+		if hz := motionCfg.PositionPollingFreqHz; hz != nil {
+			if *hz < 0 {
+				return baseMotionConfig{}, errors.New("PositionPollingFreqHz may not be negative")
+			}
+			cfg.positionPollingHz = *hz
+		}
+		if hz := motionCfg.ObstaclePollingFreqHz; hz != nil {
+			if *hz < 0 {
+				return baseMotionConfig{}, errors.New("ObstaclePollingFreqHz may not be negative")
+			}
+			cfg.obstaclePollingHz = *hz
+		}
+		cfg.obstacleDetectors = motionCfg.ObstacleDetectors
+	}
+
+	for key, dst := range map[string]*float64{
+		"collision_buffer_mm":    &cfg.collisionBufferMM,
+		"planning_resolution_mm": &cfg.planningResolution,
+	} {
+		if v, ok := extra[key]; ok {
+			f, ok := v.(float64)
+			if !ok || f < 0 {
+				return baseMotionConfig{}, fmt.Errorf("extra %s must be a non-negative number, got %v", key, v)
+			}
+			*dst = f
+		}
+	}
+	// Paths are sampled every planning_resolution_mm, so it must be positive.
+	if cfg.planningResolution == 0 {
+		return baseMotionConfig{}, errors.New("extra planning_resolution_mm must be positive")
+	}
+	if v, ok := extra["max_replans"]; ok {
+		switch n := v.(type) {
+		case float64:
+			cfg.maxReplans = int(n)
+		case int:
+			cfg.maxReplans = n
+		default:
+			return baseMotionConfig{}, fmt.Errorf("extra max_replans must be a number, got %v", v)
+		}
+	}
+	return cfg, nil
+}
+
+// basePlanner returns a list of waypoints in the planning frame from the given start position to the
+// destination. It is called once when a base move starts and again every time the base replans.
+type basePlanner func(ctx context.Context, start r3.Vector) ([]r3.Vector, error)
+
+// baseMove describes a single MoveOnMap or MoveOnGlobe request once it has been resolved against the
+// resources known to the motion service.
+type baseMove struct {
+	componentName string
+	base          base.Base
+	localizer     motion.Localizer
+	planner       basePlanner
+	destination   r3.Vector
+	// heading is the orientation theta the base should finish at, in radians in the planning frame.
+	// NaN if the request does not specify a final heading.
+	heading float64
+	cfg     baseMotionConfig
+	// obstructed, if set, is polled during execution and reports whether newly detected obstacles
+	// intersect the remainder of the path.
+	obstructed func(ctx context.Context, path []r3.Vector) (bool, error)
+	// anchor, if set, is the GPS pose of the planning frame origin and is used to render plans.
+	anchor *spatialmath.GeoPose
+}
+
+// baseRadius returns the radius of a disc in the XY plane which contains the base.
+func baseRadius(ctx context.Context, b base.Base) (float64, error) {
+	geoms, err := b.Geometries(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	radius := 0.
+	for _, g := range geoms {
+		center := g.Pose().Point()
+		radius = math.Max(radius, math.Hypot(center.X, center.Y)+geometryExtent(g))
+	}
+	if radius > 0 {
+		return radius, nil
+	}
+	props, err := b.Properties(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	return 1e3 * props.WidthMeters / 2, nil
+}
+
+// startBaseMove plans the initial path for the move and then begins executing it in the background.
+// Planning errors are returned directly; any error after that is reported through the plan status.
+func (ms *builtIn) startBaseMove(ctx context.Context, bm *baseMove) (motion.ExecutionID, error) {
+	current, err := bm.localizer.CurrentPosition(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if dist := planarDistance(current.Pose().Point(), bm.destination); dist <= bm.cfg.planDeviationMM {
+		return uuid.Nil, fmt.Errorf("%s is already within %.0fmm of the destination (%.0fmm away)",
+			bm.componentName, bm.cfg.planDeviationMM, dist)
+	}
+	path, err := bm.planner(ctx, current.Pose().Point())
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	execCtx, cancel := context.WithCancel(context.Background())
+	e, err := ms.executions.startExecution(bm.componentName, cancel)
+	if err != nil {
+		cancel()
+		return uuid.Nil, err
+	}
+	ms.executions.addPlan(e, bm.planWithMetadata(e.id, path), "")
+
+	ms.activeBackgroundWorkers.Add(1)
+	go func() {
+		defer ms.activeBackgroundWorkers.Done()
+		defer close(e.done)
+		defer cancel()
+		err := ms.executeBaseMove(execCtx, e, bm, path)
+		if stopErr := bm.base.Stop(context.Background(), nil); stopErr != nil && err == nil {
+			err = stopErr
+		}
+		switch {
+		case execCtx.Err() != nil:
+			ms.executions.finish(e, motion.PlanStateStopped, nil)
+		case err != nil:
+			ms.logger.Debugf("execution %s of %s failed: %v", e.id, bm.componentName, err)
+			ms.executions.finish(e, motion.PlanStateFailed, err)
+		default:
+			ms.executions.finish(e, motion.PlanStateSucceeded, nil)
+		}
+	}()
+	return e.id, nil
+}
+
+// executeBaseMove follows the path, replanning whenever the base deviates from it, new obstacles
+// obstruct it, or the base finishes the path without reaching the destination.
+func (ms *builtIn) executeBaseMove(ctx context.Context, e *execution, bm *baseMove, path []r3.Vector) error {
+	for replans := 0; ; replans++ {
+		reason, err := ms.followPath(ctx, bm, path)
+		if err != nil {
+			return err
+		}
+		current, err := bm.localizer.CurrentPosition(ctx)
+		if err != nil {
+			return err
+		}
+		if reason == "" {
+			dist := planarDistance(current.Pose().Point(), bm.destination)
+			if dist <= bm.cfg.planDeviationMM {
+				return turnToHeading(ctx, bm, current.Pose(), bm.heading)
+			}
+			reason = fmt.Sprintf("finished the plan %.0fmm from the destination", dist)
+		}
+		if bm.cfg.maxReplans >= 0 && replans >= bm.cfg.maxReplans {
+			return fmt.Errorf("exceeded maximum number of replans (%d), last reason: %s", bm.cfg.maxReplans, reason)
+		}
+		ms.logger.CDebugf(ctx, "replanning execution %s of %s: %s", e.id, bm.componentName, reason)
+		if err := bm.base.Stop(ctx, nil); err != nil {
+			return err
+		}
+		path, err = bm.planner(ctx, current.Pose().Point())
+		if err != nil {
+			return err
+		}
+		ms.executions.addPlan(e, bm.planWithMetadata(e.id, path), reason)
+	}
+}
+
+// followPath drives the base along each segment of the path. It returns a non-empty reason if the
+// base needs to replan before the end of the path is reached.
+func (ms *builtIn) followPath(ctx context.Context, bm *baseMove, path []r3.Vector) (string, error) {
+	for i := 0; i < len(path)-1; i++ {
+		current, err := bm.localizer.CurrentPosition(ctx)
+		if err != nil {
+			return "", err
+		}
+		if reason := bm.deviationReason(current.Pose().Point(), path[i], path[i+1]); reason != "" {
+			return reason, nil
+		}
+		reason, err := ms.driveSegment(ctx, bm, current.Pose(), path[i:])
+		if err != nil || reason != "" {
+			return reason, err
+		}
+	}
+	return "", nil
+}
+
+// driveSegment turns the base towards the next waypoint of the remaining path and drives to it while
+// monitoring the position of the base and, if configured, newly detected obstacles.
+func (ms *builtIn) driveSegment(ctx context.Context, bm *baseMove, current spatialmath.Pose, remaining []r3.Vector) (string, error) {
+	segCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var reason string
+	var monitorErr error
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		reason, monitorErr = bm.monitor(segCtx, remaining)
+		if reason != "" || monitorErr != nil {
+			cancel()
+		}
+	}()
+
+	from, to := current.Point(), remaining[1]
+	err := turnToHeading(segCtx, bm, current, headingBetween(from, to))
+	if err == nil {
+		dist := planarDistance(from, to)
+		err = bm.base.MoveStraight(segCtx, int(math.Round(dist)), bm.cfg.linearMMPerSec, nil)
+	}
+	cancel()
+	<-monitorDone
+
+	if monitorErr != nil {
+		return "", monitorErr
+	}
+	if reason != "" {
+		return reason, nil
+	}
+	if err != nil && ctx.Err() == nil {
+		return "", err
+	}
+	return "", ctx.Err()
+}
+
+// monitor polls the base's position and the obstacle detectors until ctx is done, returning a reason
+// to replan if the base strays too far from the segment it is on or the path becomes obstructed.
+func (bm *baseMove) monitor(ctx context.Context, remaining []r3.Vector) (string, error) {
+	var positionC, obstacleC <-chan time.Time
+	if bm.cfg.positionPollingHz > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / bm.cfg.positionPollingHz))
+		defer ticker.Stop()
+		positionC = ticker.C
+	}
+	if bm.obstructed != nil && bm.cfg.obstaclePollingHz > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / bm.cfg.obstaclePollingHz))
+		defer ticker.Stop()
+		obstacleC = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return "", nil
+		case <-positionC:
+			current, err := bm.localizer.CurrentPosition(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return "", nil
+				}
+				return "", err
+			}
+			if reason := bm.deviationReason(current.Pose().Point(), remaining[0], remaining[1]); reason != "" {
+				return reason, nil
+			}
+		case <-obstacleC:
+			obstructed, err := bm.obstructed(ctx, remaining)
+			if err != nil {
+				if ctx.Err() != nil {
+					return "", nil
+				}
+				return "", err
+			}
+			if obstructed {
+				return "obstacle detected along the path", nil
+			}
+		}
+	}
+}
+
+// deviationReason returns a non-empty reason if the position is further than the allowed plan
+// deviation from the segment between from and to.
+func (bm *baseMove) deviationReason(position, from, to r3.Vector) string {
+	flatten := func(v r3.Vector) r3.Vector { return r3.Vector{X: v.X, Y: v.Y} }
+	deviation := spatialmath.DistToLineSegment(flatten(from), flatten(to), flatten(position))
+	if deviation > bm.cfg.planDeviationMM {
+		return fmt.Sprintf("deviated %.0fmm from the plan, which exceeds the allowed %.0fmm", deviation, bm.cfg.planDeviationMM)
+	}
+	return ""
+}
+
+// planWithMetadata converts a path of waypoints into a plan for the base. Each step of the plan's
+// trajectory holds the X and Y position in mm and the heading theta in radians of the base.
+func (bm *baseMove) planWithMetadata(executionID motion.ExecutionID, path []r3.Vector) motion.PlanWithMetadata {
+	planPath := make(motionplan.Path, 0, len(path))
+	traj := make(motionplan.Trajectory, 0, len(path))
+	for i, pt := range path {
+		var theta float64
+		switch {
+		case i < len(path)-1:
+			theta = headingBetween(pt, path[i+1])
+		case !math.IsNaN(bm.heading):
+			theta = bm.heading
+		case i > 0:
+			theta = headingBetween(path[i-1], pt)
+		}
+		pose := spatialmath.NewPose(pt, &spatialmath.OrientationVector{OZ: 1, Theta: theta})
+		planPath = append(planPath, referenceframe.FrameSystemPoses{
+			bm.componentName: referenceframe.NewPoseInFrame(referenceframe.World, pose),
+		})
+		traj = append(traj, referenceframe.FrameSystemInputs{bm.componentName: {pt.X, pt.Y, theta}})
+	}
+	return motion.PlanWithMetadata{
+		ID:            uuid.New(),
+		ComponentName: bm.componentName,
+		ExecutionID:   executionID,
+		Plan:          motionplan.NewSimplePlan(planPath, traj),
+		AnchorGeoPose: bm.anchor,
+	}
+}
+
+// turnToHeading spins the base in place so that its orientation theta matches the given heading.
+// A NaN heading is ignored.
+func turnToHeading(ctx context.Context, bm *baseMove, current spatialmath.Pose, heading float64) error {
+	if math.IsNaN(heading) {
+		return nil
+	}
+	theta := current.Orientation().OrientationVectorRadians().Theta
+	delta := math.Remainder(heading-theta, 2*math.Pi)
+	if math.Abs(delta) < headingToleranceRads {
+		return nil
+	}
+	return bm.base.Spin(ctx, utils.RadToDeg(delta), bm.cfg.angularDegsPerSec, nil)
+}
+
+// headingBetween returns the orientation theta of a base at from facing to. Bases drive along their
+// +Y axis, so a theta of zero faces +Y in the planning frame.
+func headingBetween(from, to r3.Vector) float64 {
+	return math.Atan2(to.Y-from.Y, to.X-from.X) - math.Pi/2
+}
+
+func planarDistance(a, b r3.Vector) float64 {
+	return math.Hypot(a.X-b.X, a.Y-b.Y)
+}
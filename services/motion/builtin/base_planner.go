@@ -0,0 +1,319 @@
+package builtin
+
+import (
+	"container/heap"
+	"context"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/spatialmath"
+)
+
+const (
+	// maxPlanningGridCells bounds the memory used by the base planner's occupancy grid. The grid
+	// resolution is coarsened until the planning area fits.
+	maxPlanningGridCells    = 4e6
+	defaultBaseResolutionMM = 50.
+)
+
+var errNoBasePath = errors.New("no collision-free path to the destination could be found")
+
+// occupancyGrid is a 2D grid in the XY plane of the planning frame. It is used by the base
+// planner to find collision-free paths for a base, which is modeled as a disc. Obstacles are
+// inflated by the radius of that disc, so a cell is free if and only if the base can be centered
+// on it without colliding.
+type occupancyGrid struct {
+	resolution float64
+	minX, minY float64
+	width      int
+	height     int
+	occupied   []bool
+}
+
+// newOccupancyGrid creates an empty grid covering the given X and Y limits. The resolution is
+// coarsened if needed so that the grid contains at most maxPlanningGridCells cells.
+func newOccupancyGrid(limits []referenceframe.Limit, resolution float64) (*occupancyGrid, error) {
+	if len(limits) != 2 {
+		return nil, errors.New("occupancy grid requires exactly two limits")
+	}
+	if resolution <= 0 {
+		resolution = defaultBaseResolutionMM
+	}
+	spanX := limits[0].Max - limits[0].Min
+	spanY := limits[1].Max - limits[1].Min
+	if spanX <= 0 || spanY <= 0 {
+		return nil, errors.New("occupancy grid limits must have a positive extent")
+	}
+	if cells := (spanX / resolution) * (spanY / resolution); cells > maxPlanningGridCells {
+		resolution *= math.Sqrt(cells / maxPlanningGridCells)
+	}
+	width := int(math.Ceil(spanX/resolution)) + 1
+	height := int(math.Ceil(spanY/resolution)) + 1
+	return &occupancyGrid{
+		resolution: resolution,
+		minX:       limits[0].Min,
+		minY:       limits[1].Min,
+		width:      width,
+		height:     height,
+		occupied:   make([]bool, width*height),
+	}, nil
+}
+
+func (g *occupancyGrid) cell(pt r3.Vector) (int, int) {
+	return int(math.Round((pt.X - g.minX) / g.resolution)), int(math.Round((pt.Y - g.minY) / g.resolution))
+}
+
+func (g *occupancyGrid) point(x, y int) r3.Vector {
+	return r3.Vector{X: g.minX + float64(x)*g.resolution, Y: g.minY + float64(y)*g.resolution}
+}
+
+func (g *occupancyGrid) inBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < g.width && y < g.height
+}
+
+func (g *occupancyGrid) isOccupied(x, y int) bool {
+	if !g.inBounds(x, y) {
+		return true
+	}
+	return g.occupied[y*g.width+x]
+}
+
+// addPoint marks every cell within radius of the given point as occupied.
+func (g *occupancyGrid) addPoint(pt r3.Vector, radius float64) {
+	g.setDisc(pt, radius, true)
+}
+
+// addPointCloud marks every point of the cloud as an obstacle, inflated by radius.
+func (g *occupancyGrid) addPointCloud(pc pointcloud.PointCloud, radius float64) {
+	pc.Iterate(0, 0, func(p r3.Vector, d pointcloud.Data) bool {
+		g.addPoint(p, radius)
+		return true
+	})
+}
+
+// addGeometry marks every cell whose center is within radius of the geometry as occupied.
+func (g *occupancyGrid) addGeometry(geom spatialmath.Geometry, radius float64) error {
+	return g.forEachCellNear(geom, radius, func(x, y int, collides bool) {
+		if collides {
+			g.occupied[y*g.width+x] = true
+		}
+	})
+}
+
+// restrictTo marks every cell whose center lies outside of all the given regions as occupied.
+func (g *occupancyGrid) restrictTo(regions []spatialmath.Geometry) error {
+	if len(regions) == 0 {
+		return nil
+	}
+	inside := make([]bool, len(g.occupied))
+	for _, region := range regions {
+		if err := g.forEachCellNear(region, 0, func(x, y int, collides bool) {
+			if collides {
+				inside[y*g.width+x] = true
+			}
+		}); err != nil {
+			return err
+		}
+	}
+	for i, in := range inside {
+		if !in {
+			g.occupied[i] = true
+		}
+	}
+	return nil
+}
+
+// forEachCellNear calls fn for every cell which may be within radius of geom, reporting whether the
+// cell center is within radius of the geometry.
+func (g *occupancyGrid) forEachCellNear(geom spatialmath.Geometry, radius float64, fn func(x, y int, collides bool)) error {
+	center := geom.Pose().Point()
+	extent := radius + geometryExtent(geom)
+	minX, minY := g.cell(r3.Vector{X: center.X - extent, Y: center.Y - extent})
+	maxX, maxY := g.cell(r3.Vector{X: center.X + extent, Y: center.Y + extent})
+	for y := max(minY, 0); y <= min(maxY, g.height-1); y++ {
+		for x := max(minX, 0); x <= min(maxX, g.width-1); x++ {
+			c := g.point(x, y)
+			// Cells are tested at the height of the geometry so that obstacles are treated as infinitely tall.
+			c.Z = center.Z
+			collides, _, err := spatialmath.NewPoint(c, "").CollidesWith(geom, radius)
+			if err != nil {
+				return err
+			}
+			fn(x, y, collides)
+		}
+	}
+	return nil
+}
+
+// clearAround marks every cell within radius of the point as free. This lets a base whose current
+// position is within the inflated region of an obstacle plan its way out.
+func (g *occupancyGrid) clearAround(pt r3.Vector, radius float64) {
+	g.setDisc(pt, radius, false)
+}
+
+func (g *occupancyGrid) setDisc(pt r3.Vector, radius float64, occupied bool) {
+	cx, cy := g.cell(pt)
+	span := int(math.Ceil(radius / g.resolution))
+	for y := cy - span; y <= cy+span; y++ {
+		for x := cx - span; x <= cx+span; x++ {
+			if !g.inBounds(x, y) {
+				continue
+			}
+			c := g.point(x, y)
+			if math.Hypot(c.X-pt.X, c.Y-pt.Y) <= radius {
+				g.occupied[y*g.width+x] = occupied
+			}
+		}
+	}
+}
+
+// segmentFree reports whether the straight line between the two points only crosses free cells.
+func (g *occupancyGrid) segmentFree(from, to r3.Vector) bool {
+	dist := math.Hypot(to.X-from.X, to.Y-from.Y)
+	steps := int(math.Ceil(2*dist/g.resolution)) + 1
+	for i := 0; i <= steps; i++ {
+		frac := float64(i) / float64(steps)
+		x, y := g.cell(r3.Vector{X: from.X + frac*(to.X-from.X), Y: from.Y + frac*(to.Y-from.Y)})
+		if g.isOccupied(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+type gridNode struct {
+	index int
+	cost  float64
+	score float64
+}
+
+type gridNodeHeap []gridNode
+
+func (h gridNodeHeap) Len() int           { return len(h) }
+func (h gridNodeHeap) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h gridNodeHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *gridNodeHeap) Push(x any)        { *h = append(*h, x.(gridNode)) }
+
+func (h *gridNodeHeap) Pop() any {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// plan runs A* over the 8-connected grid from start to goal and returns a shortcut path of
+// waypoints, beginning at start and ending at goal.
+func (g *occupancyGrid) plan(ctx context.Context, start, goal r3.Vector) ([]r3.Vector, error) {
+	sx, sy := g.cell(start)
+	gx, gy := g.cell(goal)
+	if !g.inBounds(sx, sy) {
+		return nil, errors.New("start position is outside of the planning area")
+	}
+	if g.isOccupied(gx, gy) {
+		return nil, errors.New("destination is outside of the planning area or in collision with an obstacle")
+	}
+
+	startIdx := sy*g.width + sx
+	goalIdx := gy*g.width + gx
+	heuristic := func(idx int) float64 {
+		return math.Hypot(float64(idx%g.width-gx), float64(idx/g.width-gy))
+	}
+
+	costs := map[int]float64{startIdx: 0}
+	parents := map[int]int{startIdx: -1}
+	open := &gridNodeHeap{{index: startIdx, score: heuristic(startIdx)}}
+	closed := map[int]bool{}
+	for open.Len() > 0 {
+		if len(closed)%10000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		curr := heap.Pop(open).(gridNode)
+		if closed[curr.index] {
+			continue
+		}
+		if curr.index == goalIdx {
+			return g.shortcut(g.reconstruct(parents, goalIdx, start, goal)), nil
+		}
+		closed[curr.index] = true
+
+		cx, cy := curr.index%g.width, curr.index/g.width
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				nx, ny := cx+dx, cy+dy
+				if (dx == 0 && dy == 0) || g.isOccupied(nx, ny) {
+					continue
+				}
+				// Do not cut corners between two occupied cells.
+				if dx != 0 && dy != 0 && (g.isOccupied(cx+dx, cy) || g.isOccupied(cx, cy+dy)) {
+					continue
+				}
+				next := ny*g.width + nx
+				cost := curr.cost + math.Hypot(float64(dx), float64(dy))
+				if prev, ok := costs[next]; ok && prev <= cost {
+					continue
+				}
+				costs[next] = cost
+				parents[next] = curr.index
+				heap.Push(open, gridNode{index: next, cost: cost, score: cost + heuristic(next)})
+			}
+		}
+	}
+	return nil, errNoBasePath
+}
+
+func (g *occupancyGrid) reconstruct(parents map[int]int, goalIdx int, start, goal r3.Vector) []r3.Vector {
+	reversed := []r3.Vector{goal}
+	for idx := parents[goalIdx]; idx >= 0; idx = parents[idx] {
+		if parents[idx] < 0 {
+			break
+		}
+		reversed = append(reversed, g.point(idx%g.width, idx/g.width))
+	}
+	reversed = append(reversed, start)
+
+	path := make([]r3.Vector, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		path = append(path, r3.Vector{X: reversed[i].X, Y: reversed[i].Y})
+	}
+	return path
+}
+
+// shortcut greedily removes waypoints which can be skipped by driving in a straight line.
+func (g *occupancyGrid) shortcut(path []r3.Vector) []r3.Vector {
+	if len(path) <= 2 {
+		return path
+	}
+	smoothed := []r3.Vector{path[0]}
+	for i := 0; i < len(path)-1; {
+		next := i + 1
+		for j := len(path) - 1; j > i+1; j-- {
+			if g.segmentFree(path[i], path[j]) {
+				next = j
+				break
+			}
+		}
+		smoothed = append(smoothed, path[next])
+		i = next
+	}
+	return smoothed
+}
+
+// geometryExtent returns a distance from the center of the geometry which bounds all of its points.
+func geometryExtent(geom spatialmath.Geometry) float64 {
+	center := geom.Pose().Point()
+	local := geom.Transform(spatialmath.PoseInverse(geom.Pose()))
+	if bounding, err := spatialmath.BoundingSphere(local); err == nil {
+		return bounding.ToProtobuf().GetSphere().GetRadiusMm()
+	}
+	extent := 0.
+	for _, pt := range geom.ToPoints(0) {
+		extent = math.Max(extent, pt.Sub(center).Norm())
+	}
+	return extent
+}
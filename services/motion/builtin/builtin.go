@@ -62,6 +62,7 @@ const (
 	DoTeleopMove   = "teleop_move"
 	DoTeleopStop   = "teleop_stop"
 	DoTeleopStatus = "teleop_status"
+	DoTeleopJog    = "teleop_jog"
 )
 
 const (
@@ -75,6 +76,10 @@ const (
 	defaultGlobePlanDeviationM         = 2.6
 	defaultCollisionBuffer             = 150. // mm
 	defaultExecuteEpsilon              = 0.01 // rad or mm
+	defaultPositionPollingHz           = 1.
+	defaultObstaclePollingHz           = 1.
+	defaultMaxReplans                  = 20
+	headingToleranceRads               = 0.02
 )
 
 // inputEnabledActuator is an actuator that interacts with the frame system.
@@ -163,6 +168,10 @@ type builtIn struct {
 	// Teleop pipeline. Protected by teleopMu (separate from mu to simplify lock ordering).
 	teleopMu       sync.RWMutex
 	teleopPipeline *teleopPipeline
+
+	// Executions started by MoveOnMap and MoveOnGlobe.
+	executions              *executionState
+	activeBackgroundWorkers sync.WaitGroup
 }
 
 // NewBuiltIn returns a new move and grab service for the given robot.
@@ -173,6 +182,7 @@ func NewBuiltIn(
 		Named:                   conf.ResourceName().AsNamed(),
 		logger:                  logger,
 		configuredDefaultExtras: make(map[string]any),
+		executions:              newExecutionState(),
 	}
 
 	if err := ms.BuiltInReconfigure(ctx, deps, conf); err != nil {
@@ -197,6 +207,10 @@ func (ms *builtIn) BuiltInReconfigure(
 
 	ms.mu.Lock()
 	defer ms.mu.Unlock()
+	// In progress executions hold on to resources which may be replaced by this reconfiguration. They are stopped
+	// under the lock, which MoveOnGlobe and MoveOnMap hold while starting executions, so none can start in between.
+	// Executions never take the lock themselves, so waiting for them here cannot deadlock.
+	ms.executions.stopAll()
 	config, err := resource.NativeConfig[*Config](conf)
 	if err != nil {
 		return err
@@ -245,6 +259,8 @@ func (ms *builtIn) Close(ctx context.Context) error {
 	}
 	ms.teleopMu.Unlock()
 
+	ms.executions.stopAll()
+	ms.activeBackgroundWorkers.Wait()
 	return nil
 }
 
@@ -262,14 +278,6 @@ func (ms *builtIn) Move(ctx context.Context, req motion.MoveReq) (bool, error) {
 	return err == nil, err
 }
 
-func (ms *builtIn) MoveOnMap(ctx context.Context, req motion.MoveOnMapReq) (motion.ExecutionID, error) {
-	return uuid.Nil, fmt.Errorf("MoveOnMap not supported by builtin")
-}
-
-func (ms *builtIn) MoveOnGlobe(ctx context.Context, req motion.MoveOnGlobeReq) (motion.ExecutionID, error) {
-	return uuid.Nil, fmt.Errorf("MoveOnGlobeReqe not supported by builtin")
-}
-
 // GetPose is deprecated.
 func (ms *builtIn) GetPose(
 	ctx context.Context,
@@ -288,21 +296,21 @@ func (ms *builtIn) StopPlan(
 	ctx context.Context,
 	req motion.StopPlanReq,
 ) error {
-	return fmt.Errorf("StopPlan not supported by builtin")
+	return ms.executions.stop(ctx, req.ComponentName)
 }
 
 func (ms *builtIn) ListPlanStatuses(
 	ctx context.Context,
 	req motion.ListPlanStatusesReq,
 ) ([]motion.PlanStatusWithID, error) {
-	return nil, fmt.Errorf("ListPlanStatuses not supported by builtin")
+	return ms.executions.listPlanStatuses(req.OnlyActivePlans), nil
 }
 
 func (ms *builtIn) PlanHistory(
 	ctx context.Context,
 	req motion.PlanHistoryReq,
 ) ([]motion.PlanWithStatus, error) {
-	return nil, fmt.Errorf("PlanHistory not supported by builtin")
+	return ms.executions.planHistory(req)
 }
 
 // DoCommand supports two commands which are specified through the command map
@@ -0,0 +1,202 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/golang/geo/r3"
+	"github.com/google/uuid"
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/components/movementsensor"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/motion"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+// MoveOnGlobe plans a collision-free path for a base to a GPS destination and starts executing it in
+// the background, localizing with the movement sensor. Plans are made in a local frame anchored at the
+// position of the base when the request is made, with +X pointing east and +Y pointing north.
+// If obstacle detectors are configured, the base replans whenever a detected obstacle obstructs the
+// remainder of its path.
+func (ms *builtIn) MoveOnGlobe(ctx context.Context, req motion.MoveOnGlobeReq) (motion.ExecutionID, error) {
+	ctx, span := trace.StartSpan(ctx, "motion::builtin::MoveOnGlobe")
+	defer span.End()
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	operation.CancelOtherWithLabel(ctx, builtinOpLabel)
+
+	if req.Destination == nil || math.IsNaN(req.Destination.Lat()) || math.IsNaN(req.Destination.Lng()) {
+		return uuid.Nil, fmt.Errorf("MoveOnGlobe requires a valid destination for %s", req.ComponentName)
+	}
+	b, err := ms.base(req.ComponentName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	movementSensor, ok := ms.movementSensors[req.MovementSensorName]
+	if !ok {
+		return uuid.Nil, resource.DependencyNotFoundError(movementsensor.Named(req.MovementSensorName))
+	}
+	cfg, err := newBaseMotionConfig(req.MotionCfg, defaultGlobePlanDeviationM, req.Extra)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	detectors := make([]vision.Service, 0, len(cfg.obstacleDetectors))
+	for _, detector := range cfg.obstacleDetectors {
+		visionSvc, ok := ms.visionServices[detector.VisionServiceName]
+		if !ok {
+			return uuid.Nil, resource.DependencyNotFoundError(vision.Named(detector.VisionServiceName))
+		}
+		detectors = append(detectors, visionSvc)
+	}
+	radius, err := baseRadius(ctx, b)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	origin, _, err := movementSensor.Position(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	localizer := motion.TwoDLocalizer(motion.NewMovementSensorLocalizer(
+		movementSensor, origin, ms.frameOffset(ctx, req.ComponentName, req.MovementSensorName),
+	))
+
+	destination := spatialmath.GeoPointToPoint(req.Destination, origin)
+	heading := math.NaN()
+	if !math.IsNaN(req.Heading) {
+		// Compass headings are left handed, while the theta of the base is right handed.
+		heading = -utils.DegToRad(req.Heading)
+	}
+	obstacles := spatialmath.GeoGeometriesToGeometries(req.Obstacles, origin)
+	boundingRegions := spatialmath.GeoGeometriesToGeometries(req.BoundingRegions, origin)
+	inflation := radius + cfg.collisionBufferMM
+
+	// Obstacles found by the detectors during execution are kept so that later plans avoid them.
+	var detectedMu sync.Mutex
+	var detected []spatialmath.Geometry
+
+	planner := func(ctx context.Context, start r3.Vector) ([]r3.Vector, error) {
+		detectedMu.Lock()
+		obstacles := append(append([]spatialmath.Geometry{}, obstacles...), detected...)
+		detectedMu.Unlock()
+		limits := globePlanningLimits(start, destination, obstacles, boundingRegions, 2*inflation)
+		grid, err := newOccupancyGrid(limits, cfg.planningResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, obstacle := range obstacles {
+			if err := grid.addGeometry(obstacle, inflation); err != nil {
+				return nil, err
+			}
+		}
+		grid.clearAround(start, inflation)
+		if err := grid.restrictTo(boundingRegions); err != nil {
+			return nil, err
+		}
+		return grid.plan(ctx, start, destination)
+	}
+
+	bm := &baseMove{
+		componentName: req.ComponentName,
+		base:          b,
+		localizer:     localizer,
+		planner:       planner,
+		destination:   destination,
+		heading:       heading,
+		cfg:           cfg,
+		anchor:        spatialmath.NewGeoPose(origin, 0),
+	}
+	if len(detectors) > 0 {
+		bm.obstructed = func(ctx context.Context, path []r3.Vector) (bool, error) {
+			current, err := localizer.CurrentPosition(ctx)
+			if err != nil {
+				return false, err
+			}
+			for i, detector := range cfg.obstacleDetectors {
+				objects, err := detectors[i].GetObjectPointClouds(ctx, detector.CameraName, nil)
+				if err != nil {
+					return false, err
+				}
+				cameraToBase := ms.frameOffset(ctx, detector.CameraName, req.ComponentName)
+				cameraPose := spatialmath.Compose(current.Pose(), cameraToBase)
+				for _, object := range objects {
+					if object.Geometry == nil {
+						continue
+					}
+					obstacle := object.Geometry.Transform(cameraPose)
+					if pathObstructed(path, obstacle, radius, cfg.planningResolution) {
+						detectedMu.Lock()
+						detected = append(detected, obstacle)
+						detectedMu.Unlock()
+						return true, nil
+					}
+				}
+			}
+			return false, nil
+		}
+	}
+	return ms.startBaseMove(ctx, bm)
+}
+
+// frameOffset returns the pose of the origin of frame in the parent frame, as reported by the frame
+// system service. If the transform is not available the two frames are assumed to be coincident.
+func (ms *builtIn) frameOffset(ctx context.Context, frame, parent string) spatialmath.Pose {
+	if ms.fsService == nil {
+		return spatialmath.NewZeroPose()
+	}
+	origin := referenceframe.NewPoseInFrame(frame, spatialmath.NewZeroPose())
+	pif, err := ms.fsService.TransformPose(ctx, origin, parent, nil)
+	if err != nil {
+		ms.logger.CDebugf(ctx, "assuming %s is coincident with %s: %v", frame, parent, err)
+		return spatialmath.NewZeroPose()
+	}
+	return pif.Pose()
+}
+
+// globePlanningLimits returns X and Y limits covering the start, the destination, the obstacles and
+// the bounding regions, padded by margin on every side.
+func globePlanningLimits(
+	start, destination r3.Vector,
+	obstacles, boundingRegions []spatialmath.Geometry,
+	margin float64,
+) []referenceframe.Limit {
+	minX, maxX := math.Min(start.X, destination.X), math.Max(start.X, destination.X)
+	minY, maxY := math.Min(start.Y, destination.Y), math.Max(start.Y, destination.Y)
+	for _, geom := range append(append([]spatialmath.Geometry{}, obstacles...), boundingRegions...) {
+		center := geom.Pose().Point()
+		extent := geometryExtent(geom)
+		minX, maxX = math.Min(minX, center.X-extent), math.Max(maxX, center.X+extent)
+		minY, maxY = math.Min(minY, center.Y-extent), math.Max(maxY, center.Y+extent)
+	}
+	return []referenceframe.Limit{
+		{Min: minX - margin, Max: maxX + margin},
+		{Min: minY - margin, Max: maxY + margin},
+	}
+}
+
+// pathObstructed reports whether any point along the path, sampled every step mm, is within
+// inflation of the obstacle in the XY plane. Detections are checked against the footprint of the base
+// rather than the planning inflation so that plans made around them are not immediately obstructed.
+func pathObstructed(path []r3.Vector, obstacle spatialmath.Geometry, inflation, step float64) bool {
+	z := obstacle.Pose().Point().Z
+	for i := 0; i < len(path)-1; i++ {
+		from, to := path[i], path[i+1]
+		samples := int(math.Ceil(planarDistance(from, to)/step)) + 1
+		for j := 0; j <= samples; j++ {
+			frac := float64(j) / float64(samples)
+			pt := r3.Vector{X: from.X + frac*(to.X-from.X), Y: from.Y + frac*(to.Y-from.Y), Z: z}
+			if collides, _, err := spatialmath.NewPoint(pt, "").CollidesWith(obstacle, inflation); err == nil && collides {
+				return true
+			}
+		}
+	}
+	return false
+}
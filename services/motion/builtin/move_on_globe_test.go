@@ -0,0 +1,198 @@
+package builtin
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	geo "github.com/kellydunn/golang-geo"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	fakebase "go.viam.com/rdk/components/base/fake"
+	"go.viam.com/rdk/components/movementsensor"
+	fakemovementsensor "go.viam.com/rdk/components/movementsensor/fake"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/motion"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+	viz "go.viam.com/rdk/vision"
+)
+
+var gpsOrigin = geo.NewPoint(40.7, -73.98)
+
+// newSimulatedGPS returns a movement sensor whose position and compass heading track the simulated
+// base, which starts at gpsOrigin facing north.
+func newSimulatedGPS(name string, sb *simulatedBase) *inject.MovementSensor {
+	ms := inject.NewMovementSensor(name)
+	ms.PositionFunc = func(ctx context.Context, extra map[string]interface{}) (*geo.Point, float64, error) {
+		return spatialmath.PoseToGeoPose(spatialmath.NewGeoPose(gpsOrigin, 0), sb.currentPose()).Location(), 0, nil
+	}
+	ms.CompassHeadingFunc = func(ctx context.Context, extra map[string]interface{}) (float64, error) {
+		heading := math.Mod(-sb.currentPose().Orientation().OrientationVectorDegrees().Theta, 360)
+		if heading < 0 {
+			heading += 360
+		}
+		return heading, nil
+	}
+	ms.PropertiesFunc = func(ctx context.Context, extra map[string]interface{}) (*movementsensor.Properties, error) {
+		return &movementsensor.Properties{PositionSupported: true, CompassHeadingSupported: true}, nil
+	}
+	return ms
+}
+
+// geoPointAt returns the GPS point at the given position relative to gpsOrigin, with +Y pointing north.
+func geoPointAt(pt r3.Vector) *geo.Point {
+	return spatialmath.PoseToGeoPose(spatialmath.NewGeoPose(gpsOrigin, 0), spatialmath.NewPoseFromPoint(pt)).Location()
+}
+
+func TestMoveOnGlobe(t *testing.T) {
+	ctx := context.Background()
+	destination := geoPointAt(r3.Vector{Y: 5000})
+
+	t.Run("plans around obstacles and reaches the destination", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		gps := newSimulatedGPS("test_gps", sb)
+		ms := newMotionServiceWithDeps(t, sb, gps)
+		defer ms.Close(ctx)
+
+		box, err := spatialmath.NewBox(spatialmath.NewZeroPose(), r3.Vector{X: 2000, Y: 200, Z: 200}, "")
+		test.That(t, err, test.ShouldBeNil)
+		id, err := ms.MoveOnGlobe(ctx, motion.MoveOnGlobeReq{
+			ComponentName:      "test_base",
+			MovementSensorName: "test_gps",
+			Destination:        destination,
+			Heading:            90,
+			Obstacles:          []*spatialmath.GeoGeometry{spatialmath.NewGeoGeometry(geoPointAt(r3.Vector{Y: 2500}), []spatialmath.Geometry{box})},
+			MotionCfg:          &motion.MotionConfiguration{PlanDeviationMM: 100},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, waitForExecution(t, ms, "test_base", id), test.ShouldBeNil)
+
+		final := sb.currentPose()
+		test.That(t, planarDistance(final.Point(), r3.Vector{Y: 5000}), test.ShouldBeLessThan, 100)
+		// A compass heading of 90 degrees faces east, which is a right handed theta of -90 degrees.
+		test.That(t, final.Orientation().OrientationVectorDegrees().Theta, test.ShouldAlmostEqual, -90, 2)
+
+		history, err := ms.PlanHistory(ctx, motion.PlanHistoryReq{ComponentName: "test_base"})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(history), test.ShouldEqual, 1)
+		test.That(t, history[0].StatusHistory[0].State, test.ShouldEqual, motion.PlanStateSucceeded)
+
+		// Plans are rendered in GPS coordinates, with the longitude in X and the latitude in Y.
+		poses, err := history[0].Plan.Path().GetFramePoses("test_base")
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(poses), test.ShouldBeGreaterThan, 2)
+		test.That(t, poses[0].Point().X, test.ShouldAlmostEqual, gpsOrigin.Lng(), 1e-6)
+		test.That(t, poses[0].Point().Y, test.ShouldAlmostEqual, gpsOrigin.Lat(), 1e-6)
+		last := poses[len(poses)-1].Point()
+		test.That(t, last.X, test.ShouldAlmostEqual, destination.Lng(), 1e-6)
+		test.That(t, last.Y, test.ShouldAlmostEqual, destination.Lat(), 1e-6)
+	})
+
+	t.Run("replans around detected obstacles", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		sb.moveDuration = 100 * time.Millisecond
+		gps := newSimulatedGPS("test_gps", sb)
+
+		// The detector sees a wall across the direct route, reported in the frame of the base.
+		wall, err := spatialmath.NewBox(spatialmath.NewPoseFromPoint(r3.Vector{Y: 2500}), r3.Vector{X: 2000, Y: 200, Z: 200}, "")
+		test.That(t, err, test.ShouldBeNil)
+		visionSvc := inject.NewVisionService("test_vision")
+		visionSvc.GetObjectPointCloudsFunc = func(ctx context.Context, cameraName string, extra map[string]interface{}) ([]*viz.Object, error) {
+			geom := wall.Transform(spatialmath.PoseInverse(sb.currentPose()))
+			return []*viz.Object{{PointCloud: pointcloud.NewBasicEmpty(), Geometry: geom}}, nil
+		}
+		ms := newMotionServiceWithDeps(t, sb, gps, visionSvc)
+		defer ms.Close(ctx)
+
+		pollingHz := 50.
+		id, err := ms.MoveOnGlobe(ctx, motion.MoveOnGlobeReq{
+			ComponentName:      "test_base",
+			MovementSensorName: "test_gps",
+			Destination:        destination,
+			Heading:            math.NaN(),
+			MotionCfg: &motion.MotionConfiguration{
+				PlanDeviationMM:       100,
+				ObstaclePollingFreqHz: &pollingHz,
+				ObstacleDetectors:     []motion.ObstacleDetectorName{{VisionServiceName: "test_vision", CameraName: "test_camera"}},
+			},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, waitForExecution(t, ms, "test_base", id), test.ShouldBeNil)
+		test.That(t, planarDistance(sb.currentPose().Point(), r3.Vector{Y: 5000}), test.ShouldBeLessThan, 100)
+
+		history, err := ms.PlanHistory(ctx, motion.PlanHistoryReq{ComponentName: "test_base"})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(history), test.ShouldEqual, 2)
+		test.That(t, history[0].StatusHistory[0].State, test.ShouldEqual, motion.PlanStateSucceeded)
+		test.That(t, history[1].StatusHistory[0].State, test.ShouldEqual, motion.PlanStateFailed)
+		test.That(t, *history[1].StatusHistory[0].Reason, test.ShouldContainSubstring, "obstacle detected")
+	})
+
+	t.Run("fails once out of replans with a fake base and movement sensor", func(t *testing.T) {
+		logger := logging.NewTestLogger(t)
+		fakeBase, err := fakebase.NewBase(ctx, nil, resource.Config{Name: "test_base", API: base.API}, logger)
+		test.That(t, err, test.ShouldBeNil)
+		fakeGPS, err := fakemovementsensor.NewMovementSensor(ctx, nil, resource.Config{Name: "test_gps", API: movementsensor.API}, logger)
+		test.That(t, err, test.ShouldBeNil)
+		ms := newMotionServiceWithDeps(t, fakeBase, fakeGPS)
+		defer ms.Close(ctx)
+
+		// The fake movement sensor never moves, so the base can never reach the destination.
+		id, err := ms.MoveOnGlobe(ctx, motion.MoveOnGlobeReq{
+			ComponentName:      "test_base",
+			MovementSensorName: "test_gps",
+			Destination:        geo.NewPoint(40.7001, -73.98),
+			Heading:            math.NaN(),
+			Extra:              map[string]interface{}{"max_replans": 1.},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		err = waitForExecution(t, ms, "test_base", id)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "exceeded maximum number of replans")
+	})
+
+	t.Run("fails for invalid requests", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		gps := newSimulatedGPS("test_gps", sb)
+		ms := newMotionServiceWithDeps(t, sb, gps)
+		defer ms.Close(ctx)
+
+		req := motion.MoveOnGlobeReq{ComponentName: "test_base", MovementSensorName: "test_gps", Heading: math.NaN()}
+		_, err := ms.MoveOnGlobe(ctx, req)
+		test.That(t, err, test.ShouldNotBeNil)
+
+		req.Destination = destination
+		req.MovementSensorName = "other_gps"
+		_, err = ms.MoveOnGlobe(ctx, req)
+		test.That(t, err, test.ShouldBeError, resource.DependencyNotFoundError(movementsensor.Named("other_gps")))
+
+		req.MovementSensorName = "test_gps"
+		req.MotionCfg = &motion.MotionConfiguration{
+			ObstacleDetectors: []motion.ObstacleDetectorName{{VisionServiceName: "test_vision", CameraName: "test_camera"}},
+		}
+		_, err = ms.MoveOnGlobe(ctx, req)
+		test.That(t, err, test.ShouldBeError, resource.DependencyNotFoundError(vision.Named("test_vision")))
+
+		req.MotionCfg = nil
+		req.BoundingRegions = []*spatialmath.GeoGeometry{spatialmath.NewGeoGeometry(gpsOrigin, []spatialmath.Geometry{
+			mustBox(t, r3.Vector{X: 1000, Y: 1000, Z: 10}),
+		})}
+		_, err = ms.MoveOnGlobe(ctx, req)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "outside of the planning area")
+	})
+}
+
+func mustBox(t *testing.T, dims r3.Vector) spatialmath.Geometry {
+	t.Helper()
+	box, err := spatialmath.NewBox(spatialmath.NewZeroPose(), dims, "")
+	test.That(t, err, test.ShouldBeNil)
+	return box
+}
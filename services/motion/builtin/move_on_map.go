@@ -0,0 +1,125 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/golang/geo/r3"
+	"github.com/google/uuid"
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/operation"
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/motion"
+	"go.viam.com/rdk/services/slam"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/utils"
+)
+
+// MoveOnMap plans a collision-free path for a base over the occupancy of the SLAM service's point
+// cloud map and starts executing it in the background, localizing with the SLAM service.
+// The destination is a pose in the SLAM map frame; the base finishes facing the destination's heading.
+func (ms *builtIn) MoveOnMap(ctx context.Context, req motion.MoveOnMapReq) (motion.ExecutionID, error) {
+	ctx, span := trace.StartSpan(ctx, "motion::builtin::MoveOnMap")
+	defer span.End()
+
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	operation.CancelOtherWithLabel(ctx, builtinOpLabel)
+
+	if req.Destination == nil {
+		return uuid.Nil, fmt.Errorf("MoveOnMap requires a destination for %s", req.ComponentName)
+	}
+	b, err := ms.base(req.ComponentName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	slamSvc, ok := ms.slamServices[req.SlamName]
+	if !ok {
+		return uuid.Nil, resource.DependencyNotFoundError(slam.Named(req.SlamName))
+	}
+	cfg, err := newBaseMotionConfig(req.MotionCfg, defaultSlamPlanDeviationM, req.Extra)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	radius, err := baseRadius(ctx, b)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// The SLAM localizer reports poses with +Y pointing along the base's direction of travel, so the
+	// destination is adjusted in the same way.
+	destination, err := spatialmath.ProjectOrientationTo2dRotation(
+		spatialmath.Compose(req.Destination, motion.SLAMOrientationAdjustment),
+	)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	data, err := slam.PointCloudMapFull(ctx, slamSvc, false)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	pc, err := pointcloud.ReadPCD(bytes.NewReader(data), "")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	obstacles := append([]spatialmath.Geometry{}, req.Obstacles...)
+	inflation := radius + cfg.collisionBufferMM
+
+	planner := func(ctx context.Context, start r3.Vector) ([]r3.Vector, error) {
+		limits := mapPlanningLimits(pc, start, destination.Point(), 2*inflation)
+		grid, err := newOccupancyGrid(limits, cfg.planningResolution)
+		if err != nil {
+			return nil, err
+		}
+		grid.addPointCloud(pc, inflation)
+		for _, obstacle := range obstacles {
+			if err := grid.addGeometry(obstacle, inflation); err != nil {
+				return nil, err
+			}
+		}
+		grid.clearAround(start, inflation)
+		return grid.plan(ctx, start, destination.Point())
+	}
+
+	return ms.startBaseMove(ctx, &baseMove{
+		componentName: req.ComponentName,
+		base:          b,
+		localizer:     motion.TwoDLocalizer(motion.NewSLAMLocalizer(slamSvc)),
+		planner:       planner,
+		destination:   destination.Point(),
+		heading:       destination.Orientation().OrientationVectorRadians().Theta,
+		cfg:           cfg,
+	})
+}
+
+// base returns the base with the given name from the motion service's dependencies.
+func (ms *builtIn) base(name string) (base.Base, error) {
+	r, ok := ms.components[name]
+	if !ok {
+		return nil, resource.DependencyNotFoundError(base.Named(name))
+	}
+	return utils.AssertType[base.Base](r)
+}
+
+// mapPlanningLimits returns X and Y limits covering the map, the start and the destination,
+// padded by margin on every side.
+func mapPlanningLimits(pc pointcloud.PointCloud, start, destination r3.Vector, margin float64) []referenceframe.Limit {
+	minX, maxX := math.Min(start.X, destination.X), math.Max(start.X, destination.X)
+	minY, maxY := math.Min(start.Y, destination.Y), math.Max(start.Y, destination.Y)
+	if pc.Size() > 0 {
+		md := pc.MetaData()
+		minX, maxX = math.Min(minX, md.MinX), math.Max(maxX, md.MaxX)
+		minY, maxY = math.Min(minY, md.MinY), math.Max(maxY, md.MaxY)
+	}
+	return []referenceframe.Limit{
+		{Min: minX - margin, Max: maxX + margin},
+		{Min: minY - margin, Max: maxY + margin},
+	}
+}
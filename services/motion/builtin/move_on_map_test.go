@@ -0,0 +1,357 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/base"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/motion"
+	"go.viam.com/rdk/services/slam"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+// simulatedBase is an injected base which tracks its own pose in the planning frame as it is
+// commanded, optionally drifting sideways as it drives straight.
+type simulatedBase struct {
+	*inject.Base
+	mu       sync.Mutex
+	pose     spatialmath.Pose
+	driftMM  float64
+	blocking bool
+	// moveDuration is how long each MoveStraight takes. The base does not move if it is interrupted.
+	moveDuration time.Duration
+}
+
+func newSimulatedBase(name string, start spatialmath.Pose) *simulatedBase {
+	sb := &simulatedBase{Base: inject.NewBase(name), pose: start}
+	sb.GeometriesFunc = func(ctx context.Context) ([]spatialmath.Geometry, error) {
+		sphere, err := spatialmath.NewSphere(spatialmath.NewZeroPose(), 100, name)
+		return []spatialmath.Geometry{sphere}, err
+	}
+	sb.PropertiesFunc = func(ctx context.Context, extra map[string]interface{}) (base.Properties, error) {
+		return base.Properties{WidthMeters: 0.2}, nil
+	}
+	sb.StopFunc = func(ctx context.Context, extra map[string]interface{}) error {
+		return nil
+	}
+	sb.SpinFunc = func(ctx context.Context, angleDeg, degsPerSec float64, extra map[string]interface{}) error {
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+		sb.pose = spatialmath.Compose(sb.pose, spatialmath.NewPoseFromOrientation(
+			&spatialmath.OrientationVectorDegrees{OZ: 1, Theta: angleDeg}))
+		return nil
+	}
+	sb.MoveStraightFunc = func(ctx context.Context, distanceMm int, mmPerSec float64, extra map[string]interface{}) error {
+		sb.mu.Lock()
+		blocking := sb.blocking
+		sb.mu.Unlock()
+		if blocking {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(sb.moveDuration):
+		}
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+		sb.pose = spatialmath.Compose(sb.pose, spatialmath.NewPoseFromPoint(r3.Vector{X: sb.driftMM, Y: float64(distanceMm)}))
+		// Only drift on the first segment so that replanning can converge.
+		sb.driftMM = 0
+		return nil
+	}
+	return sb
+}
+
+func (sb *simulatedBase) currentPose() spatialmath.Pose {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.pose
+}
+
+// newSimulatedSLAM returns a SLAM service whose map is the given point cloud and whose position
+// tracks the simulated base.
+func newSimulatedSLAM(t *testing.T, name string, sb *simulatedBase, pc pointcloud.PointCloud) *inject.SLAMService {
+	t.Helper()
+	var buf bytes.Buffer
+	test.That(t, pointcloud.ToPCD(pc, &buf, pointcloud.PCDBinary), test.ShouldBeNil)
+	mapBytes := buf.Bytes()
+
+	slamSvc := inject.NewSLAMService(name)
+	slamSvc.PositionFunc = func(ctx context.Context) (spatialmath.Pose, error) {
+		// The SLAM localizer rotates positions by SLAMOrientationAdjustment, so undo that here.
+		return spatialmath.Compose(sb.currentPose(), spatialmath.PoseInverse(motion.SLAMOrientationAdjustment)), nil
+	}
+	slamSvc.PointCloudMapFunc = func(ctx context.Context, returnEditedMap bool) (func() ([]byte, error), error) {
+		reader := bytes.NewReader(mapBytes)
+		return func() ([]byte, error) {
+			chunk := make([]byte, 1024)
+			n, err := reader.Read(chunk)
+			if err != nil {
+				return nil, io.EOF
+			}
+			return chunk[:n], nil
+		}, nil
+	}
+	return slamSvc
+}
+
+// wallPointCloud returns a wall of points along the X axis at the given Y, spanning [minX, maxX].
+func wallPointCloud(t *testing.T, y, minX, maxX float64) pointcloud.PointCloud {
+	t.Helper()
+	pc := pointcloud.NewBasicEmpty()
+	for x := minX; x <= maxX; x += 25 {
+		test.That(t, pc.Set(r3.Vector{X: x, Y: y}, nil), test.ShouldBeNil)
+	}
+	return pc
+}
+
+func newMotionServiceWithDeps(t *testing.T, deps ...resource.Resource) motion.Service {
+	t.Helper()
+	depMap := resource.Dependencies{}
+	for _, dep := range deps {
+		depMap[dep.Name()] = dep
+	}
+	ms, err := NewBuiltIn(context.Background(), depMap, resource.Config{ConvertedAttributes: &Config{}}, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	return ms
+}
+
+func waitForExecution(t *testing.T, ms motion.Service, componentName string, id motion.ExecutionID) error {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return motion.PollHistoryUntilSuccessOrError(ctx, ms, 10*time.Millisecond, motion.PlanHistoryReq{
+		ComponentName: componentName,
+		ExecutionID:   id,
+	})
+}
+
+func TestMoveOnMap(t *testing.T) {
+	ctx := context.Background()
+	destination := spatialmath.NewPoseFromPoint(r3.Vector{Y: 3000})
+
+	t.Run("plans around the map and reaches the destination", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		slamSvc := newSimulatedSLAM(t, "test_slam", sb, wallPointCloud(t, 1500, -1000, 1000))
+		ms := newMotionServiceWithDeps(t, sb, slamSvc)
+		defer ms.Close(ctx)
+
+		id, err := ms.MoveOnMap(ctx, motion.MoveOnMapReq{
+			ComponentName: "test_base",
+			SlamName:      "test_slam",
+			Destination:   destination,
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, waitForExecution(t, ms, "test_base", id), test.ShouldBeNil)
+
+		// The SLAM localizer reports the base pose rotated into the +Y forwards convention.
+		expected := spatialmath.Compose(destination, motion.SLAMOrientationAdjustment)
+		test.That(t, spatialmath.PoseAlmostEqualEps(sb.currentPose(), expected, 5), test.ShouldBeTrue)
+
+		history, err := ms.PlanHistory(ctx, motion.PlanHistoryReq{ComponentName: "test_base"})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(history), test.ShouldEqual, 1)
+		test.That(t, history[0].StatusHistory[0].State, test.ShouldEqual, motion.PlanStateSucceeded)
+		test.That(t, history[0].Plan.ExecutionID, test.ShouldEqual, id)
+
+		// The wall blocks the direct route, so the plan must go around one of its ends.
+		poses, err := history[0].Plan.Path().GetFramePoses("test_base")
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(poses), test.ShouldBeGreaterThan, 2)
+		wallStart, wallEnd := r3.Vector{X: -1000, Y: 1500}, r3.Vector{X: 1000, Y: 1500}
+		for i := 0; i < len(poses)-1; i++ {
+			from, to := poses[i].Point(), poses[i+1].Point()
+			for frac := 0.; frac <= 1; frac += 0.01 {
+				pt := from.Add(to.Sub(from).Mul(frac))
+				test.That(t, spatialmath.DistToLineSegment(wallStart, wallEnd, pt), test.ShouldBeGreaterThan, 100)
+			}
+		}
+
+		statuses, err := ms.ListPlanStatuses(ctx, motion.ListPlanStatusesReq{})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(statuses), test.ShouldEqual, 1)
+		statuses, err = ms.ListPlanStatuses(ctx, motion.ListPlanStatusesReq{OnlyActivePlans: true})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, statuses, test.ShouldBeEmpty)
+	})
+
+	t.Run("replans when the base deviates from the plan", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		sb.driftMM = 800
+		slamSvc := newSimulatedSLAM(t, "test_slam", sb, wallPointCloud(t, 5000, -1000, 1000))
+		ms := newMotionServiceWithDeps(t, sb, slamSvc)
+		defer ms.Close(ctx)
+
+		id, err := ms.MoveOnMap(ctx, motion.MoveOnMapReq{
+			ComponentName: "test_base",
+			SlamName:      "test_slam",
+			Destination:   destination,
+			MotionCfg:     &motion.MotionConfiguration{PlanDeviationMM: 500},
+		})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, waitForExecution(t, ms, "test_base", id), test.ShouldBeNil)
+
+		history, err := ms.PlanHistory(ctx, motion.PlanHistoryReq{ComponentName: "test_base"})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(history), test.ShouldEqual, 2)
+		test.That(t, history[0].StatusHistory[0].State, test.ShouldEqual, motion.PlanStateSucceeded)
+		test.That(t, history[1].StatusHistory[0].State, test.ShouldEqual, motion.PlanStateFailed)
+		test.That(t, *history[1].StatusHistory[0].Reason, test.ShouldContainSubstring, "from the destination")
+		test.That(t, history[1].StatusHistory[1].State, test.ShouldEqual, motion.PlanStateInProgress)
+	})
+
+	t.Run("StopPlan stops an in progress execution", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		sb.blocking = true
+		slamSvc := newSimulatedSLAM(t, "test_slam", sb, wallPointCloud(t, 5000, -1000, 1000))
+		ms := newMotionServiceWithDeps(t, sb, slamSvc)
+		defer ms.Close(ctx)
+
+		req := motion.MoveOnMapReq{ComponentName: "test_base", SlamName: "test_slam", Destination: destination}
+		id, err := ms.MoveOnMap(ctx, req)
+		test.That(t, err, test.ShouldBeNil)
+
+		_, err = ms.MoveOnMap(ctx, req)
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "already an active executionID")
+
+		statuses, err := ms.ListPlanStatuses(ctx, motion.ListPlanStatusesReq{OnlyActivePlans: true})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, len(statuses), test.ShouldEqual, 1)
+		test.That(t, statuses[0].ExecutionID, test.ShouldEqual, id)
+
+		test.That(t, ms.StopPlan(ctx, motion.StopPlanReq{ComponentName: "test_base"}), test.ShouldBeNil)
+		err = waitForExecution(t, ms, "test_base", id)
+		test.That(t, err, test.ShouldBeError, "plan stopped")
+		test.That(t, ms.StopPlan(ctx, motion.StopPlanReq{ComponentName: "test_base"}), test.ShouldNotBeNil)
+	})
+
+	t.Run("Reconfigure stops in progress executions", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		sb.blocking = true
+		slamSvc := newSimulatedSLAM(t, "test_slam", sb, wallPointCloud(t, 5000, -1000, 1000))
+		ms := newMotionServiceWithDeps(t, sb, slamSvc)
+		defer ms.Close(ctx)
+
+		req := motion.MoveOnMapReq{ComponentName: "test_base", SlamName: "test_slam", Destination: destination}
+		id, err := ms.MoveOnMap(ctx, req)
+		test.That(t, err, test.ShouldBeNil)
+
+		deps := resource.Dependencies{sb.Name(): sb, slamSvc.Name(): slamSvc}
+		err = ms.(*builtIn).BuiltInReconfigure(ctx, deps, resource.Config{ConvertedAttributes: &Config{}})
+		test.That(t, err, test.ShouldBeNil)
+		statuses, err := ms.ListPlanStatuses(ctx, motion.ListPlanStatusesReq{OnlyActivePlans: true})
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, statuses, test.ShouldBeEmpty)
+		test.That(t, waitForExecution(t, ms, "test_base", id), test.ShouldBeError, "plan stopped")
+
+		// New executions start against the reconfigured dependencies.
+		id2, err := ms.MoveOnMap(ctx, req)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, id2, test.ShouldNotEqual, id)
+		test.That(t, ms.StopPlan(ctx, motion.StopPlanReq{ComponentName: "test_base"}), test.ShouldBeNil)
+	})
+
+	t.Run("fails when already at the destination", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.Compose(destination, motion.SLAMOrientationAdjustment))
+		slamSvc := newSimulatedSLAM(t, "test_slam", sb, wallPointCloud(t, 5000, -1000, 1000))
+		ms := newMotionServiceWithDeps(t, sb, slamSvc)
+		defer ms.Close(ctx)
+
+		_, err := ms.MoveOnMap(ctx, motion.MoveOnMapReq{ComponentName: "test_base", SlamName: "test_slam", Destination: destination})
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "already within")
+	})
+
+	t.Run("fails when the destination is blocked", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		slamSvc := newSimulatedSLAM(t, "test_slam", sb, wallPointCloud(t, 3000, -1000, 1000))
+		ms := newMotionServiceWithDeps(t, sb, slamSvc)
+		defer ms.Close(ctx)
+
+		_, err := ms.MoveOnMap(ctx, motion.MoveOnMapReq{ComponentName: "test_base", SlamName: "test_slam", Destination: destination})
+		test.That(t, err, test.ShouldNotBeNil)
+		test.That(t, err.Error(), test.ShouldContainSubstring, "in collision")
+	})
+
+	t.Run("fails for a planning resolution that is not positive", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		slamSvc := newSimulatedSLAM(t, "test_slam", sb, wallPointCloud(t, 5000, -1000, 1000))
+		ms := newMotionServiceWithDeps(t, sb, slamSvc)
+		defer ms.Close(ctx)
+
+		for _, resolution := range []interface{}{0., -1., "50"} {
+			_, err := ms.MoveOnMap(ctx, motion.MoveOnMapReq{
+				ComponentName: "test_base",
+				SlamName:      "test_slam",
+				Destination:   destination,
+				Extra:         map[string]interface{}{"planning_resolution_mm": resolution},
+			})
+			test.That(t, err, test.ShouldNotBeNil)
+			test.That(t, err.Error(), test.ShouldContainSubstring, "planning_resolution_mm")
+		}
+	})
+
+	t.Run("fails for unknown resources", func(t *testing.T) {
+		sb := newSimulatedBase("test_base", spatialmath.NewZeroPose())
+		ms := newMotionServiceWithDeps(t, sb)
+		defer ms.Close(ctx)
+
+		_, err := ms.MoveOnMap(ctx, motion.MoveOnMapReq{ComponentName: "test_base", SlamName: "test_slam", Destination: destination})
+		test.That(t, err, test.ShouldBeError, resource.DependencyNotFoundError(slam.Named("test_slam")))
+		_, err = ms.MoveOnMap(ctx, motion.MoveOnMapReq{ComponentName: "other_base", SlamName: "test_slam", Destination: destination})
+		test.That(t, err, test.ShouldBeError, resource.DependencyNotFoundError(base.Named("other_base")))
+	})
+}
+
+func TestOccupancyGridPlan(t *testing.T) {
+	grid, err := newOccupancyGrid([]referenceframe.Limit{{Min: -2000, Max: 2000}, {Min: -2000, Max: 2000}}, 50)
+	test.That(t, err, test.ShouldBeNil)
+	box, err := spatialmath.NewBox(spatialmath.NewZeroPose(), r3.Vector{X: 2000, Y: 200, Z: 200}, "")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, grid.addGeometry(box, 100), test.ShouldBeNil)
+
+	start, goal := r3.Vector{Y: -1500}, r3.Vector{Y: 1500}
+	test.That(t, grid.segmentFree(start, goal), test.ShouldBeFalse)
+	path, err := grid.plan(context.Background(), start, goal)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, path[0], test.ShouldResemble, start)
+	test.That(t, path[len(path)-1], test.ShouldResemble, goal)
+	for i := 0; i < len(path)-1; i++ {
+		test.That(t, grid.segmentFree(path[i], path[i+1]), test.ShouldBeTrue)
+	}
+
+	t.Run("no path when the goal is enclosed", func(t *testing.T) {
+		ring, err := spatialmath.NewBox(spatialmath.NewPoseFromPoint(r3.Vector{Y: 1500}), r3.Vector{X: 4000, Y: 100, Z: 100}, "")
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, grid.addGeometry(ring, 0), test.ShouldBeNil)
+		_, err = grid.plan(context.Background(), start, r3.Vector{Y: 1900})
+		test.That(t, err, test.ShouldBeError, errNoBasePath)
+	})
+
+	t.Run("bounded by regions", func(t *testing.T) {
+		grid, err := newOccupancyGrid([]referenceframe.Limit{{Min: -2000, Max: 2000}, {Min: -2000, Max: 2000}}, 50)
+		test.That(t, err, test.ShouldBeNil)
+		region, err := spatialmath.NewBox(spatialmath.NewZeroPose(), r3.Vector{X: 1000, Y: 1000, Z: 10}, "")
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, grid.restrictTo([]spatialmath.Geometry{region}), test.ShouldBeNil)
+		_, err = grid.plan(context.Background(), r3.Vector{}, r3.Vector{X: 1500})
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = grid.plan(context.Background(), r3.Vector{}, r3.Vector{X: 400})
+		test.That(t, err, test.ShouldBeNil)
+	})
+}
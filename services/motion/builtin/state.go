@@ -0,0 +1,234 @@
+package builtin
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"go.viam.com/rdk/services/motion"
+)
+
+// planHistoryTTL is how long an execution is retained after it reaches a terminal state.
+const planHistoryTTL = 24 * time.Hour
+
+// execution tracks a single MoveOnMap or MoveOnGlobe call. Plans are stored most recent first,
+// and each plan's status history is also stored most recent first.
+type execution struct {
+	id            motion.ExecutionID
+	componentName string
+	plans         []motion.PlanWithStatus
+	cancel        context.CancelFunc
+	done          chan struct{}
+}
+
+func (e *execution) currentStatus() motion.PlanStatus {
+	if len(e.plans) == 0 || len(e.plans[0].StatusHistory) == 0 {
+		return motion.PlanStatus{State: motion.PlanStateInProgress}
+	}
+	return e.plans[0].StatusHistory[0]
+}
+
+func (e *execution) active() bool {
+	_, terminal := motion.TerminalStateSet[e.currentStatus().State]
+	return !terminal
+}
+
+// executionState stores the executions started by the builtin motion service so that they can be
+// queried by ListPlanStatuses and PlanHistory and stopped by StopPlan.
+type executionState struct {
+	mu sync.Mutex
+	// executions maps a component name to its executions, most recent first.
+	executions map[string][]*execution
+}
+
+func newExecutionState() *executionState {
+	return &executionState{executions: map[string][]*execution{}}
+}
+
+// startExecution registers a new execution for the given component. It returns an error if the
+// component already has an execution in progress.
+func (s *executionState) startExecution(componentName string, cancel context.CancelFunc) (*execution, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+
+	if execs := s.executions[componentName]; len(execs) > 0 && execs[0].active() {
+		return nil, fmt.Errorf("there is already an active executionID: %s for component %s", execs[0].id, componentName)
+	}
+	e := &execution{
+		id:            uuid.New(),
+		componentName: componentName,
+		cancel:        cancel,
+		done:          make(chan struct{}),
+	}
+	s.executions[componentName] = append([]*execution{e}, s.executions[componentName]...)
+	return e, nil
+}
+
+// addPlan records a new plan for the execution. If a previous plan is still in progress it is
+// marked as failed with the given reason, as replans always supersede the plan being executed.
+func (s *executionState) addPlan(e *execution, plan motion.PlanWithMetadata, replanReason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(e.plans) > 0 && e.currentStatus().State == motion.PlanStateInProgress {
+		reason := replanReason
+		e.plans[0].StatusHistory = append(
+			[]motion.PlanStatus{{State: motion.PlanStateFailed, Timestamp: now, Reason: &reason}},
+			e.plans[0].StatusHistory...,
+		)
+	}
+	e.plans = append([]motion.PlanWithStatus{{
+		Plan:          plan,
+		StatusHistory: []motion.PlanStatus{{State: motion.PlanStateInProgress, Timestamp: now}},
+	}}, e.plans...)
+}
+
+// finish moves the execution's current plan into a terminal state. If the execution never
+// produced a plan an empty one is recorded so that the outcome can still be queried.
+func (s *executionState) finish(e *execution, state motion.PlanState, reason error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(e.plans) == 0 {
+		e.plans = []motion.PlanWithStatus{{
+			Plan: motion.PlanWithMetadata{ID: uuid.New(), ComponentName: e.componentName, ExecutionID: e.id},
+		}}
+	}
+	if !e.active() {
+		return
+	}
+	status := motion.PlanStatus{State: state, Timestamp: time.Now()}
+	if reason != nil {
+		r := reason.Error()
+		status.Reason = &r
+	}
+	e.plans[0].StatusHistory = append([]motion.PlanStatus{status}, e.plans[0].StatusHistory...)
+}
+
+// stop cancels the in progress execution of the given component and waits for it to return.
+func (s *executionState) stop(ctx context.Context, componentName string) error {
+	s.mu.Lock()
+	execs := s.executions[componentName]
+	if len(execs) == 0 || !execs[0].active() {
+		s.mu.Unlock()
+		return fmt.Errorf("no active execution for component: %s", componentName)
+	}
+	e := execs[0]
+	s.mu.Unlock()
+
+	e.cancel()
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.finish(e, motion.PlanStateStopped, nil)
+	return nil
+}
+
+// stopAll cancels every in progress execution and waits for them to return.
+func (s *executionState) stopAll() {
+	s.mu.Lock()
+	var active []*execution
+	for _, execs := range s.executions {
+		if len(execs) > 0 && execs[0].active() {
+			active = append(active, execs[0])
+		}
+	}
+	s.mu.Unlock()
+
+	for _, e := range active {
+		e.cancel()
+		<-e.done
+		s.finish(e, motion.PlanStateStopped, nil)
+	}
+}
+
+// listPlanStatuses returns the status of every plan of every retained execution, or only of the
+// in progress plans if onlyActive is set.
+func (s *executionState) listPlanStatuses(onlyActive bool) []motion.PlanStatusWithID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+
+	statuses := []motion.PlanStatusWithID{}
+	for componentName, execs := range s.executions {
+		for _, e := range execs {
+			if onlyActive && !e.active() {
+				continue
+			}
+			for _, p := range e.plans {
+				if onlyActive && p.StatusHistory[0].State != motion.PlanStateInProgress {
+					continue
+				}
+				statuses = append(statuses, motion.PlanStatusWithID{
+					PlanID:        p.Plan.ID,
+					ComponentName: componentName,
+					ExecutionID:   e.id,
+					Status:        p.StatusHistory[0],
+				})
+			}
+		}
+	}
+	return statuses
+}
+
+// planHistory returns the plans of the requested execution, defaulting to the most recent
+// execution of the component.
+func (s *executionState) planHistory(req motion.PlanHistoryReq) ([]motion.PlanWithStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.purgeLocked()
+
+	execs := s.executions[req.ComponentName]
+	if len(execs) == 0 {
+		return nil, fmt.Errorf("no plan history for component: %s", req.ComponentName)
+	}
+	e := execs[0]
+	if req.ExecutionID != uuid.Nil {
+		e = nil
+		for _, candidate := range execs {
+			if candidate.id == req.ExecutionID {
+				e = candidate
+				break
+			}
+		}
+		if e == nil {
+			return nil, fmt.Errorf("no plan history for executionID: %s", req.ExecutionID)
+		}
+	}
+
+	plans := e.plans
+	if req.LastPlanOnly && len(plans) > 0 {
+		plans = plans[:1]
+	}
+	history := make([]motion.PlanWithStatus, 0, len(plans))
+	for _, p := range plans {
+		history = append(history, motion.PlanWithStatus{
+			Plan:          p.Plan.Renderable(),
+			StatusHistory: append([]motion.PlanStatus{}, p.StatusHistory...),
+		})
+	}
+	return history, nil
+}
+
+// purgeLocked drops executions which reached a terminal state longer than planHistoryTTL ago.
+func (s *executionState) purgeLocked() {
+	cutoff := time.Now().Add(-planHistoryTTL)
+	for componentName, execs := range s.executions {
+		kept := execs[:0]
+		for _, e := range execs {
+			if e.active() || e.currentStatus().Timestamp.After(cutoff) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.executions, componentName)
+			continue
+		}
+		s.executions[componentName] = kept
+	}
+}
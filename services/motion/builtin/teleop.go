@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/golang/geo/r3"
 	commonpb "go.viam.com/api/common/v1"
 	pb "go.viam.com/api/service/motion/v1"
 	goutils "go.viam.com/utils"
@@ -20,16 +21,24 @@ import (
 	"go.viam.com/rdk/resource"
 	"go.viam.com/rdk/robot/framesystem"
 	"go.viam.com/rdk/services/motion"
+	"go.viam.com/rdk/spatialmath"
 	"go.viam.com/rdk/utils"
 )
 
-const defaultTeleopSmoothAlpha = 0.5
+const (
+	defaultTeleopSmoothAlpha = 0.5
+	defaultTeleopJogDuration = 100 * time.Millisecond
+)
 
 // teleopComponent tracks a single component being teleop'd within the pipeline.
 type teleopComponent struct {
 	name        string
 	moveReqBase motion.MoveReq
 	latestPose  atomic.Pointer[referenceframe.PoseInFrame]
+
+	// Resolved-rate controller for jog commands, created on first use. Protected by jogMu.
+	jogMu  sync.Mutex
+	jogger *motionplan.JogController
 }
 
 // emaSmooth applies an exponential moving average to the commanded joint positions:
@@ -421,11 +430,18 @@ func (tp *teleopPipeline) runExecutor(ctx context.Context, ms *builtIn) {
 			} else {
 				tp.lastErr.Store(nil)
 				// Update planning head to smoothed positions (what was actually
-				// sent to the arm) so the next plan starts from reality.
+				// sent to the arm) so the next plan starts from reality. The head is
+				// replaced rather than written in place, because it is also the last
+				// step of any trajectory the planner or a jog has already queued.
 				tp.planningHeadMu.Lock()
+				head := make(referenceframe.FrameSystemInputs, len(tp.planningHead))
+				for name, joints := range tp.planningHead {
+					head[name] = joints
+				}
 				for name, joints := range tp.smoothedJoints {
-					tp.planningHead[name] = joints
+					head[name] = joints
 				}
+				tp.planningHead = head
 				tp.planningHeadMu.Unlock()
 			}
 		}
@@ -453,6 +469,85 @@ func (tp *teleopPipeline) resetPlanningHead(ctx context.Context, ms *builtIn) {
 	tp.planningHeadMu.Unlock()
 }
 
+// jog advances a component's planning head along a Cartesian twist for dt using its resolved-rate
+// controller, and enqueues the step for the executor directly, without running the planner.
+// The component's goal pose is moved to the end of the step so that the planner does not pull it back.
+// Returns false if the executor already has a trajectory queued, in which case the jog is dropped.
+func (tp *teleopPipeline) jog(
+	ctx context.Context,
+	ms *builtIn,
+	comp *teleopComponent,
+	twist motionplan.Twist,
+	dt time.Duration,
+) (bool, error) {
+	comp.jogMu.Lock()
+	defer comp.jogMu.Unlock()
+	if comp.jogger == nil {
+		ms.mu.RLock()
+		r, ok := ms.components[comp.name]
+		ms.mu.RUnlock()
+		if !ok {
+			return false, fmt.Errorf("component %q is not known to the motion service", comp.name)
+		}
+		ie, err := utils.AssertType[framesystem.InputEnabled](r)
+		if err != nil {
+			return false, err
+		}
+		model, err := ie.Kinematics(ctx)
+		if err != nil {
+			return false, err
+		}
+		if comp.jogger, err = motionplan.NewJogController(model, nil); err != nil {
+			return false, err
+		}
+	}
+
+	tp.planningHeadMu.Lock()
+	defer tp.planningHeadMu.Unlock()
+	start, ok := tp.planningHead[comp.name]
+	if !ok {
+		return false, fmt.Errorf("no inputs known for component %q", comp.name)
+	}
+	next, err := comp.jogger.Step(start, twist, dt)
+	if err != nil {
+		return false, err
+	}
+	prev := make(referenceframe.FrameSystemInputs, len(tp.planningHead))
+	head := make(referenceframe.FrameSystemInputs, len(tp.planningHead))
+	for k, v := range tp.planningHead {
+		prev[k] = v
+		head[k] = v
+	}
+	head[comp.name] = next
+
+	select {
+	case tp.trajCh <- motionplan.Trajectory{prev, head}:
+		tp.planningHead = head
+	default:
+		return false, nil
+	}
+
+	if goal := comp.latestPose.Load(); goal != nil {
+		merged := make(referenceframe.FrameSystemInputs, len(tp.cachedBaseInputs))
+		for k, v := range tp.cachedBaseInputs {
+			merged[k] = v
+		}
+		for k, v := range head {
+			merged[k] = v
+		}
+		tf, err := tp.cachedFrameSys.Transform(
+			merged.ToLinearInputs(),
+			referenceframe.NewPoseInFrame(comp.name, spatialmath.NewZeroPose()),
+			goal.Parent(),
+		)
+		if err != nil {
+			return true, err
+		}
+		comp.latestPose.Store(tf.(*referenceframe.PoseInFrame))
+	}
+	return true, nil
+}
+
 // stop shuts down the pipeline goroutines.
 func (tp *teleopPipeline) stop(ctx context.Context, ms *builtIn) {
 	tp.workers.Stop()
@@ -655,6 +750,52 @@ func (ms *builtIn) handleTeleopCommand(
 		return resp, true, nil
 	}
 
+	if req, ok := cmd[DoTeleopJog]; ok {
+		componentName, _ := cmd["component_name"].(string)
+
+		ms.teleopMu.RLock()
+		tp := ms.teleopPipeline
+		ms.teleopMu.RUnlock()
+		if tp == nil {
+			return nil, true, fmt.Errorf("teleop pipeline is not running; call %s first", DoTeleopStart)
+		}
+
+		jogReq, err := utils.AssertType[map[string]interface{}](req)
+		if err != nil {
+			return nil, true, err
+		}
+		var twist motionplan.Twist
+		if twist.Linear, err = vectorFromCommand(jogReq, "linear_mm_per_sec"); err != nil {
+			return nil, true, err
+		}
+		if twist.Angular, err = vectorFromCommand(jogReq, "angular_rads_per_sec"); err != nil {
+			return nil, true, err
+		}
+		dt := defaultTeleopJogDuration
+		if durationMs, ok := jogReq["duration_ms"].(float64); ok && durationMs > 0 {
+			dt = time.Duration(durationMs * float64(time.Millisecond))
+		}
+
+		tp.componentsMu.RLock()
+		comp := tp.components[componentName]
+		if comp == nil && componentName == "" && len(tp.components) == 1 {
+			for _, c := range tp.components {
+				comp = c
+			}
+		}
+		tp.componentsMu.RUnlock()
+		if comp == nil {
+			return nil, true, fmt.Errorf("component %q not registered in teleop pipeline", componentName)
+		}
+
+		queued, err := tp.jog(ctx, ms, comp, twist, dt)
+		if err != nil {
+			return nil, true, err
+		}
+		resp[DoTeleopJog] = queued
+		return resp, true, nil
+	}
+
 	if _, ok := cmd[DoTeleopStop]; ok {
 		componentName, _ := cmd["component_name"].(string)
 
@@ -712,3 +853,25 @@ func (ms *builtIn) handleTeleopCommand(
 
 	return resp, false, nil
 }
+
+// vectorFromCommand reads an optional [x, y, z] vector from a DoCommand request.
+func vectorFromCommand(cmd map[string]interface{}, key string) (r3.Vector, error) {
+	raw, ok := cmd[key]
+	if !ok {
+		return r3.Vector{}, nil
+	}
+	values, err := utils.AssertType[[]interface{}](raw)
+	if err != nil {
+		return r3.Vector{}, err
+	}
+	if len(values) != 3 {
+		return r3.Vector{}, fmt.Errorf("%s must have 3 elements, got %d", key, len(values))
+	}
+	var xyz [3]float64
+	for i, v := range values {
+		if xyz[i], err = utils.AssertType[float64](v); err != nil {
+			return r3.Vector{}, err
+		}
+	}
+	return r3.Vector{X: xyz[0], Y: xyz[1], Z: xyz[2]}, nil
+}
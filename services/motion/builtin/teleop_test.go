@@ -0,0 +1,196 @@
+package builtin
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"go.viam.com/rdk/components/arm"
+	"go.viam.com/rdk/components/arm/sim"
+	"go.viam.com/rdk/config"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/motionplan"
+	"go.viam.com/rdk/referenceframe"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/robot/framesystem"
+	robotimpl "go.viam.com/rdk/robot/impl"
+	"go.viam.com/rdk/services/motion"
+)
+
+func TestVectorFromCommand(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		cmd      map[string]interface{}
+		expected r3.Vector
+		err      string
+	}{
+		{
+			name:     "vector",
+			cmd:      map[string]interface{}{"linear_mm_per_sec": []interface{}{10., -2.5, 0.}},
+			expected: r3.Vector{X: 10, Y: -2.5},
+		},
+		{
+			name: "missing is the zero vector",
+			cmd:  map[string]interface{}{"angular_rads_per_sec": []interface{}{0., 0., 1.}},
+		},
+		{
+			name: "zero vector",
+			cmd:  map[string]interface{}{"linear_mm_per_sec": []interface{}{0., 0., 0.}},
+		},
+		{
+			name: "not a list",
+			cmd:  map[string]interface{}{"linear_mm_per_sec": "10, 0, 0"},
+			err:  "expected []interface {}",
+		},
+		{
+			name: "too few elements",
+			cmd:  map[string]interface{}{"linear_mm_per_sec": []interface{}{10., 0.}},
+			err:  "linear_mm_per_sec must have 3 elements, got 2",
+		},
+		{
+			name: "too many elements",
+			cmd:  map[string]interface{}{"linear_mm_per_sec": []interface{}{10., 0., 0., 0.}},
+			err:  "linear_mm_per_sec must have 3 elements, got 4",
+		},
+		{
+			name: "not a number",
+			cmd:  map[string]interface{}{"linear_mm_per_sec": []interface{}{10., "0", 0.}},
+			err:  "expected float64",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := vectorFromCommand(tc.cmd, "linear_mm_per_sec")
+			if tc.err != "" {
+				test.That(t, err, test.ShouldNotBeNil)
+				test.That(t, err.Error(), test.ShouldContainSubstring, tc.err)
+				return
+			}
+			test.That(t, err, test.ShouldBeNil)
+			test.That(t, v, test.ShouldResemble, tc.expected)
+		})
+	}
+}
+
+func TestTeleopJog(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	cfg := &config.Config{
+		Components: []resource.Config{
+			{
+				Name:                "arm",
+				API:                 arm.API,
+				Model:               sim.Model,
+				ConvertedAttributes: &sim.Config{Model: "ur5e", Speed: 10, SimulateTime: true},
+				Frame:               &referenceframe.LinkConfig{Parent: referenceframe.World},
+			},
+		},
+		Services: []resource.Config{
+			{
+				Name:      "builtin",
+				API:       motion.API,
+				Model:     resource.DefaultServiceModel,
+				DependsOn: []string{framesystem.InternalServiceName.String()},
+				// Send the joints of each step as they are, without smoothing.
+				ConvertedAttributes: &Config{TeleopSmoothAlpha: 1},
+			},
+		},
+	}
+	myRobot, err := robotimpl.New(ctx, cfg, nil, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer myRobot.Close(ctx)
+	ms, err := motion.FromProvider(myRobot, "builtin")
+	test.That(t, err, test.ShouldBeNil)
+	a, err := arm.FromProvider(myRobot, "arm")
+	test.That(t, err, test.ShouldBeNil)
+
+	jog := func(jogReq map[string]interface{}) (map[string]interface{}, error) {
+		return ms.DoCommand(ctx, map[string]interface{}{DoTeleopJog: jogReq, "component_name": "arm"})
+	}
+	_, err = jog(map[string]interface{}{})
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "call "+DoTeleopStart+" first")
+
+	// Start away from the singularity of the outstretched arm.
+	start := []referenceframe.Input{0, -math.Pi / 2, math.Pi / 2, -math.Pi / 2, -math.Pi / 2, 0}
+	test.That(t, a.MoveToJointPositions(ctx, start, nil), test.ShouldBeNil)
+	destination, err := ms.GetPose(ctx, "arm", referenceframe.World, nil, nil)
+	test.That(t, err, test.ShouldBeNil)
+	moveReq, err := (motion.MoveReq{ComponentName: "arm", Destination: destination}).ToProto(ms.Name().Name)
+	test.That(t, err, test.ShouldBeNil)
+	startReq, err := protojson.Marshal(moveReq)
+	test.That(t, err, test.ShouldBeNil)
+	resp, err := ms.DoCommand(ctx, map[string]interface{}{DoTeleopStart: string(startReq)})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp[DoTeleopStart], test.ShouldEqual, true)
+	defer func() {
+		_, err := ms.DoCommand(ctx, map[string]interface{}{DoTeleopStop: true})
+		test.That(t, err, test.ShouldBeNil)
+	}()
+
+	_, err = jog(map[string]interface{}{"linear_mm_per_sec": []interface{}{50., 0.}})
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = jog(map[string]interface{}{"angular_rads_per_sec": "up"})
+	test.That(t, err, test.ShouldNotBeNil)
+
+	// A jog without a twist leaves the planning head where it was, once the first plan to where the arm already is
+	// has settled.
+	tp := ms.(*builtIn).teleopPipeline
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		test.That(tb, tp.planCount.Load(), test.ShouldBeGreaterThan, 0)
+	})
+	headOf := func() []referenceframe.Input {
+		tp.planningHeadMu.RLock()
+		defer tp.planningHeadMu.RUnlock()
+		return tp.planningHead["arm"]
+	}
+	before := headOf()
+	resp, err = jog(map[string]interface{}{})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp, test.ShouldContainKey, DoTeleopJog)
+	test.That(t, headOf(), test.ShouldResemble, before)
+
+	// Jog along +X, retrying while the executor is busy, and expect the joints to move the way the resolved-rate
+	// controller says they should for that twist.
+	twist := motionplan.Twist{Linear: r3.Vector{X: 50}}
+	model, err := a.Kinematics(ctx)
+	test.That(t, err, test.ShouldBeNil)
+	jc, err := motionplan.NewJogController(model, nil)
+	test.That(t, err, test.ShouldBeNil)
+	expected, err := jc.JointVelocities(start, twist)
+	test.That(t, err, test.ShouldBeNil)
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		resp, err := jog(map[string]interface{}{"linear_mm_per_sec": []interface{}{50., 0., 0.}, "duration_ms": 200.})
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, resp[DoTeleopJog], test.ShouldEqual, true)
+	})
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		tb.Helper()
+		inputs, err := a.CurrentInputs(ctx)
+		test.That(tb, err, test.ShouldBeNil)
+		var dot, deltaNorm, expectedNorm float64
+		for i := range inputs {
+			delta := inputs[i] - start[i]
+			dot += delta * expected[i]
+			deltaNorm += delta * delta
+			expectedNorm += expected[i] * expected[i]
+		}
+		deltaNorm, expectedNorm = math.Sqrt(deltaNorm), math.Sqrt(expectedNorm)
+		// The step lasts 0.2 s.
+		test.That(tb, deltaNorm, test.ShouldAlmostEqual, 0.2*expectedNorm, 0.02*expectedNorm)
+		test.That(tb, dot/(deltaNorm*expectedNorm), test.ShouldBeGreaterThan, 0.99)
+	})
+
+	// The end of the arm moved along +X.
+	moved, err := ms.GetPose(ctx, "arm", referenceframe.World, nil, nil)
+	test.That(t, err, test.ShouldBeNil)
+	step := moved.Pose().Point().Sub(destination.Pose().Point())
+	test.That(t, step.X, test.ShouldAlmostEqual, 10, 1)
+	test.That(t, math.Hypot(step.Y, step.Z), test.ShouldBeLessThan, 1)
+}
@@ -0,0 +1,156 @@
+package builtin
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"io"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// The sizes of the parts of the header of an AVI with one MJPEG stream.
+const (
+	aviMainHeaderSize   = 56
+	aviStreamHeaderSize = 56
+	aviBitmapInfoSize   = 40
+	aviStreamListSize   = 4 + 8 + aviStreamHeaderSize + 8 + aviBitmapInfoSize
+	aviHeaderListSize   = 4 + 8 + aviMainHeaderSize + 8 + aviStreamListSize
+	aviIndexEntrySize   = 16
+	// aviHasIndex is the flag of the main header that says the file has an index, and aviKeyframe the flag of an
+	// index entry that says its frame is a keyframe, which every JPEG is.
+	aviHasIndex = 0x10
+	aviKeyframe = 0x10
+)
+
+// writeMJPEGAVI writes JPEG frames as an MJPEG AVI, reading the data of each frame with read. The frame rate is the
+// average over the frames, since they are recorded as fast as the camera gives them rather than exactly on time.
+func writeMJPEGAVI(w io.Writer, frames []frame, read func(fn func(f frame, data []byte) error) error) error {
+	if len(frames) == 0 {
+		return errors.New("no frames to write")
+	}
+	var first []byte
+	if err := read(func(f frame, data []byte) error {
+		if first == nil {
+			first = data
+			return errStopReading
+		}
+		return nil
+	}); err != nil && !errors.Is(err, errStopReading) {
+		return err
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(first))
+	if err != nil {
+		return errors.Wrap(err, "could not read the size of the first frame")
+	}
+
+	frameInterval := time.Second
+	if len(frames) > 1 {
+		frameInterval = frames[len(frames)-1].time.Sub(frames[0].time) / time.Duration(len(frames)-1)
+	}
+	if frameInterval <= 0 {
+		frameInterval = time.Millisecond
+	}
+	var moviSize, maxFrameSize int
+	for _, f := range frames {
+		moviSize += 8 + f.length + f.length%2
+		maxFrameSize = max(maxFrameSize, f.length)
+	}
+	moviListSize := 4 + moviSize
+	indexSize := aviIndexEntrySize * len(frames)
+	riffSize := 4 + 8 + aviHeaderListSize + 8 + moviListSize + 8 + indexSize
+	if riffSize > math.MaxUint32 {
+		return errors.New("video is too large for an AVI")
+	}
+
+	header := &bytes.Buffer{}
+	le := func(values ...any) {
+		for _, v := range values {
+			//nolint:errcheck
+			binary.Write(header, binary.LittleEndian, v)
+		}
+	}
+	header.WriteString("RIFF")
+	le(uint32(riffSize))
+	header.WriteString("AVI LIST")
+	le(uint32(aviHeaderListSize))
+	header.WriteString("hdrlavih")
+	le(uint32(aviMainHeaderSize),
+		uint32(frameInterval.Microseconds()), // microseconds per frame
+		uint32(0),                            // max bytes per second
+		uint32(0),                            // padding granularity
+		uint32(aviHasIndex),
+		uint32(len(frames)),
+		uint32(0), // initial frames
+		uint32(1), // streams
+		uint32(maxFrameSize),
+		uint32(config.Width), uint32(config.Height),
+		[4]uint32{})
+	header.WriteString("LIST")
+	le(uint32(aviStreamListSize))
+	header.WriteString("strlstrh")
+	le(uint32(aviStreamHeaderSize))
+	header.WriteString("vidsMJPG")
+	le(uint32(0), // flags
+		uint16(0), uint16(0), // priority and language
+		uint32(0),                                         // initial frames
+		uint32(frameInterval.Microseconds()), uint32(1e6), // scale and rate, so frames per second is rate / scale
+		uint32(0), // start
+		uint32(len(frames)),
+		uint32(maxFrameSize),
+		int32(-1), // quality
+		uint32(0), // sample size
+		[4]int16{0, 0, int16(config.Width), int16(config.Height)})
+	header.WriteString("strf")
+	le(uint32(aviBitmapInfoSize),
+		uint32(aviBitmapInfoSize), int32(config.Width), int32(config.Height),
+		uint16(1), uint16(24)) // planes and bits per pixel
+	header.WriteString("MJPG")
+	le(uint32(config.Width*config.Height*3), [4]uint32{})
+	header.WriteString("LIST")
+	le(uint32(moviListSize))
+	header.WriteString("movi")
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return err
+	}
+
+	chunkHeader := make([]byte, 8)
+	copy(chunkHeader, "00dc")
+	index := &bytes.Buffer{}
+	index.WriteString("idx1")
+	//nolint:errcheck
+	binary.Write(index, binary.LittleEndian, uint32(indexSize))
+	offset := 4 // from the start of "movi"
+	written := 0
+	if err := read(func(f frame, data []byte) error {
+		if written == len(frames) || len(data) != frames[written].length {
+			return errors.New("frames changed while being written")
+		}
+		binary.LittleEndian.PutUint32(chunkHeader[4:], uint32(len(data)))
+		if _, err := w.Write(chunkHeader); err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+		if len(data)%2 == 1 {
+			if _, err := w.Write([]byte{0}); err != nil {
+				return err
+			}
+		}
+		index.WriteString("00dc")
+		//nolint:errcheck
+		binary.Write(index, binary.LittleEndian, [3]uint32{aviKeyframe, uint32(offset), uint32(len(data))})
+		offset += 8 + len(data) + len(data)%2
+		written++
+		return nil
+	}); err != nil {
+		return err
+	}
+	_, err = w.Write(index.Bytes())
+	return err
+}
+
+var errStopReading = errors.New("stop reading")
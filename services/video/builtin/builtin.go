@@ -0,0 +1,392 @@
+// Package builtin implements a video service that continuously records a camera to a rolling buffer of segments on
+// disk, so that the video around an incident can be fetched after the fact. Cameras that pass their H.264 stream
+// through over RTP are recorded as that stream. Other cameras are recorded as JPEGs at a fixed frame rate and
+// returned as MJPEG in an AVI.
+package builtin
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/pion/rtp"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/components/camera/rtppassthrough"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/rimage"
+	"go.viam.com/rdk/services/video"
+	"go.viam.com/rdk/utils"
+)
+
+// Defaults of the config.
+const (
+	defaultFrameRate      = 10.
+	defaultSegmentSeconds = 60.
+	defaultMaxStorageMB   = 1024.
+	// chunkSize is the most data sent in one chunk of a video.
+	chunkSize = 1 << 20
+	// rtpBufferSize is the number of packets buffered for the RTP subscription.
+	rtpBufferSize = 512
+)
+
+// The containers a video can be returned in.
+const (
+	containerAVI  = "avi"
+	containerH264 = "h264"
+)
+
+func init() {
+	resource.RegisterService(video.API, resource.DefaultServiceModel, resource.Registration[video.Service, *Config]{
+		Constructor: func(
+			ctx context.Context, deps resource.Dependencies, c resource.Config, logger logging.Logger,
+		) (video.Service, error) {
+			conf, err := resource.NativeConfig[*Config](c)
+			if err != nil {
+				return nil, err
+			}
+			return newBuiltIn(ctx, c.ResourceName(), conf, deps, clock.New(), logger)
+		},
+	})
+}
+
+// Config describes the camera to record and how much of it to keep.
+type Config struct {
+	Camera string `json:"camera"`
+	// StoragePath is the directory the segments are kept in, ~/.viam/video/<service name> if not given.
+	StoragePath string `json:"storage_path,omitempty"`
+	// FrameRate is the number of images a second recorded from cameras that are not recorded as H.264.
+	FrameRate float64 `json:"framerate,omitempty"`
+	// SegmentSeconds is how long each segment on disk is. Whole segments are deleted at a time.
+	SegmentSeconds float64 `json:"segment_seconds,omitempty"`
+	// MaxStorageMB is the most space the segments can take up before the oldest are deleted.
+	MaxStorageMB float64 `json:"max_storage_mb,omitempty"`
+	// MaxAgeHours is how long segments are kept for, forever if not given.
+	MaxAgeHours float64 `json:"max_age_hours,omitempty"`
+	// DisablePassthrough records JPEGs even from cameras that could pass through H.264.
+	DisablePassthrough bool `json:"disable_passthrough,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid and returns the camera as a dependency.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	if conf.Camera == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "camera")
+	}
+	for name, value := range map[string]float64{
+		"framerate":       conf.FrameRate,
+		"segment_seconds": conf.SegmentSeconds,
+		"max_storage_mb":  conf.MaxStorageMB,
+		"max_age_hours":   conf.MaxAgeHours,
+	} {
+		if value < 0 {
+			return nil, nil, resource.NewConfigValidationError(path, errors.Errorf("%s cannot be negative, got %v", name, value))
+		}
+	}
+	return []string{conf.Camera}, nil, nil
+}
+
+type builtIn struct {
+	resource.Named
+	resource.AlwaysRebuild
+	logger logging.Logger
+	clock  clock.Clock
+
+	cam       camera.Camera
+	store     *segmentStore
+	frameRate float64
+	workers   *goutils.StoppableWorkers
+
+	mu sync.Mutex
+	// codec is what the camera is being recorded as, and subscription the RTP subscription it is recorded through
+	// for H.264.
+	codec        string
+	subscription rtppassthrough.Subscription
+}
+
+func newBuiltIn(
+	ctx context.Context,
+	name resource.Name,
+	conf *Config,
+	deps resource.Dependencies,
+	clk clock.Clock,
+	logger logging.Logger,
+) (video.Service, error) {
+	cam, err := camera.FromProvider(deps, conf.Camera)
+	if err != nil {
+		return nil, err
+	}
+	dir := conf.StoragePath
+	if dir == "" {
+		dir = filepath.Join(utils.ViamDotDir, "video", name.ShortName())
+	}
+	segmentSeconds, maxStorageMB, frameRate := conf.SegmentSeconds, conf.MaxStorageMB, conf.FrameRate
+	if segmentSeconds == 0 {
+		segmentSeconds = defaultSegmentSeconds
+	}
+	if maxStorageMB == 0 {
+		maxStorageMB = defaultMaxStorageMB
+	}
+	if frameRate == 0 {
+		frameRate = defaultFrameRate
+	}
+	store, err := newSegmentStore(
+		dir,
+		time.Duration(segmentSeconds*float64(time.Second)),
+		int64(maxStorageMB*1024*1024),
+		time.Duration(conf.MaxAgeHours*float64(time.Hour)),
+		logger,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open video storage %q", dir)
+	}
+
+	svc := &builtIn{
+		Named:     name.AsNamed(),
+		logger:    logger,
+		clock:     clk,
+		cam:       cam,
+		store:     store,
+		frameRate: frameRate,
+		codec:     codecMJPEG,
+	}
+	svc.workers = goutils.NewBackgroundStoppableWorkers()
+	if source, ok := cam.(rtppassthrough.Source); ok && !conf.DisablePassthrough {
+		sub, err := svc.subscribeH264(ctx, source)
+		if err == nil {
+			svc.workers.Add(func(ctx context.Context) {
+				svc.recordH264(ctx, source, sub)
+			})
+			return svc, nil
+		}
+		logger.CInfow(ctx, "camera cannot pass through H.264, recording JPEGs instead", "camera", conf.Camera, "reason", err)
+	}
+	svc.workers.Add(svc.recordJPEGs)
+	return svc, nil
+}
+
+// recordH264 keeps recording the RTP stream of the camera through sub until stopped. A subscription ends when, for
+// example, a remote camera reconnects, so it then subscribes again, and records JPEGs instead if it cannot.
+func (svc *builtIn) recordH264(ctx context.Context, source rtppassthrough.Source, sub rtppassthrough.Subscription) {
+	for {
+		var terminated <-chan struct{}
+		if sub.Terminated != nil {
+			terminated = sub.Terminated.Done()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-terminated:
+		}
+		svc.logger.CInfo(ctx, "H.264 subscription ended, subscribing again")
+		var err error
+		sub, err = svc.subscribeH264(ctx, source)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			svc.logger.CWarnw(ctx, "camera cannot pass through H.264 anymore, recording JPEGs instead", "reason", err)
+			svc.mu.Lock()
+			svc.codec = codecMJPEG
+			svc.subscription = rtppassthrough.NilSubscription
+			svc.mu.Unlock()
+			svc.recordJPEGs(ctx)
+			return
+		}
+	}
+}
+
+// subscribeH264 subscribes to the RTP stream of the camera and records its access units as they arrive.
+func (svc *builtIn) subscribeH264(ctx context.Context, source rtppassthrough.Source) (rtppassthrough.Subscription, error) {
+	assembler := &accessUnitAssembler{}
+	sub, err := source.SubscribeRTP(ctx, rtpBufferSize, func(pkts []*rtp.Packet) {
+		for _, pkt := range pkts {
+			accessUnit, keyframe, err := assembler.push(pkt)
+			if err != nil {
+				svc.logger.Debugw("dropping H.264 packet", "error", err)
+				continue
+			}
+			if accessUnit == nil {
+				continue
+			}
+			if err := svc.store.write(codecH264, svc.clock.Now(), accessUnit, keyframe); err != nil {
+				svc.logger.Warnw("failed to record video", "error", err)
+			}
+		}
+	})
+	if err != nil {
+		return rtppassthrough.NilSubscription, err
+	}
+	svc.mu.Lock()
+	svc.codec = codecH264
+	svc.subscription = sub
+	svc.mu.Unlock()
+	return sub, nil
+}
+
+// recordJPEGs records the images of the camera at the frame rate until stopped.
+func (svc *builtIn) recordJPEGs(ctx context.Context) {
+	ticker := svc.clock.Ticker(time.Duration(float64(time.Second) / svc.frameRate))
+	defer ticker.Stop()
+	failing := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := svc.recordJPEG(ctx)
+		switch {
+		case err != nil && !failing && ctx.Err() == nil:
+			svc.logger.CWarnw(ctx, "failed to record video", "error", err)
+		case err == nil && failing:
+			svc.logger.CInfo(ctx, "recording video again")
+		}
+		failing = err != nil
+	}
+}
+
+func (svc *builtIn) recordJPEG(ctx context.Context) error {
+	now := svc.clock.Now()
+	images, _, err := svc.cam.Images(ctx, nil, nil)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return errors.New("camera returned no images")
+	}
+	var data []byte
+	if mimeType, _ := utils.CheckLazyMIMEType(images[0].MimeType()); mimeType == utils.MimeTypeJPEG {
+		data, err = images[0].Bytes(ctx)
+	} else {
+		img, decodeErr := images[0].Image(ctx)
+		if decodeErr != nil {
+			return decodeErr
+		}
+		data, err = rimage.EncodeImage(ctx, img, utils.MimeTypeJPEG)
+	}
+	if err != nil {
+		return err
+	}
+	return svc.store.write(codecMJPEG, now, data, true)
+}
+
+// GetVideo returns the recorded video between the start and end times, which default to the start of the recording
+// and now. The codec and container default to what the camera is recorded as, which is the only codec available.
+func (svc *builtIn) GetVideo(
+	ctx context.Context,
+	startTime, endTime time.Time,
+	videoCodec, videoContainer string,
+	extra map[string]interface{},
+) (chan *video.Chunk, error) {
+	svc.mu.Lock()
+	codec := svc.codec
+	svc.mu.Unlock()
+
+	videoCodec, videoContainer = strings.ToLower(videoCodec), strings.ToLower(videoContainer)
+	if videoCodec != "" && videoCodec != codec {
+		return nil, errors.Errorf("the camera is recorded as %s, not %s", codec, videoCodec)
+	}
+	container := containerAVI
+	if codec == codecH264 {
+		container = containerH264
+	}
+	if videoContainer != "" && videoContainer != container {
+		return nil, errors.Errorf("%s video can only be returned in the %s container, not %s", codec, container, videoContainer)
+	}
+	if endTime.IsZero() {
+		endTime = svc.clock.Now()
+	}
+	if endTime.Before(startTime) {
+		return nil, errors.Errorf("end time %v is before start time %v", endTime, startTime)
+	}
+
+	frames, err := svc.store.frames(codec, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if len(frames) == 0 {
+		return nil, errors.Errorf("no video was recorded between %v and %v", startTime, endTime)
+	}
+
+	ch := make(chan *video.Chunk, 1)
+	w := &chunkWriter{ctx: ctx, ch: ch, container: container}
+	go func() {
+		defer close(ch)
+		read := func(fn func(f frame, data []byte) error) error {
+			return readFrames(frames, fn)
+		}
+		var err error
+		if codec == codecH264 {
+			err = read(func(f frame, data []byte) error {
+				_, err := w.Write(data)
+				return err
+			})
+		} else {
+			err = writeMJPEGAVI(w, frames, read)
+		}
+		if err == nil {
+			err = w.flush()
+		}
+		if err != nil && ctx.Err() == nil {
+			svc.logger.CWarnw(ctx, "failed to send video", "error", err)
+		}
+	}()
+	return ch, nil
+}
+
+// chunkWriter sends what is written to it as chunks of at most chunkSize bytes.
+type chunkWriter struct {
+	ctx       context.Context
+	ch        chan *video.Chunk
+	container string
+	buf       []byte
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := min(len(p), chunkSize-len(w.buf))
+		w.buf = append(w.buf, p[:take]...)
+		p = p[take:]
+		if len(w.buf) == chunkSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *chunkWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	select {
+	case <-w.ctx.Done():
+		return w.ctx.Err()
+	case w.ch <- &video.Chunk{Data: w.buf, Container: w.container}:
+	}
+	w.buf = nil
+	return nil
+}
+
+func (svc *builtIn) Close(ctx context.Context) error {
+	svc.workers.Stop()
+	svc.mu.Lock()
+	sub := svc.subscription
+	svc.mu.Unlock()
+	var err error
+	if sub.ID != rtppassthrough.NilSubscription.ID {
+		if source, ok := svc.cam.(rtppassthrough.Source); ok {
+			err = source.Unsubscribe(ctx, sub.ID)
+		}
+	}
+	return multierr.Combine(err, svc.store.close())
+}
@@ -0,0 +1,317 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/google/uuid"
+	"github.com/pion/rtp"
+	"github.com/pkg/errors"
+	"go.viam.com/test"
+	"go.viam.com/utils/testutils"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/components/camera/rtppassthrough"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/video"
+	"go.viam.com/rdk/testutils/inject"
+	"go.viam.com/rdk/utils"
+)
+
+func TestValidate(t *testing.T) {
+	conf := &Config{Camera: "cam"}
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"cam"})
+
+	conf = &Config{}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "camera")
+
+	conf = &Config{Camera: "cam", MaxStorageMB: -1}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "max_storage_mb")
+}
+
+func receive(t *testing.T, ch chan *video.Chunk, container string) []byte {
+	t.Helper()
+	var out []byte
+	for chunk := range ch {
+		test.That(t, chunk.Container, test.ShouldEqual, container)
+		out = append(out, chunk.Data...)
+	}
+	return out
+}
+
+// parseAVI checks the structure of an MJPEG AVI and returns its frames and microseconds per frame.
+func parseAVI(t *testing.T, avi []byte) ([][]byte, uint32) {
+	t.Helper()
+	test.That(t, string(avi[:4]), test.ShouldEqual, "RIFF")
+	test.That(t, binary.LittleEndian.Uint32(avi[4:]), test.ShouldEqual, len(avi)-8)
+	test.That(t, string(avi[8:12]), test.ShouldEqual, "AVI ")
+	usPerFrame := binary.LittleEndian.Uint32(avi[32:])
+	totalFrames := binary.LittleEndian.Uint32(avi[48:])
+
+	movi := bytes.Index(avi, []byte("movi"))
+	test.That(t, movi, test.ShouldBeGreaterThan, 0)
+	moviSize := binary.LittleEndian.Uint32(avi[movi-4:])
+	idx := movi + int(moviSize)
+	test.That(t, string(avi[idx:idx+4]), test.ShouldEqual, "idx1")
+	entries := int(binary.LittleEndian.Uint32(avi[idx+4:])) / aviIndexEntrySize
+	test.That(t, entries, test.ShouldEqual, totalFrames)
+	test.That(t, idx+8+entries*aviIndexEntrySize, test.ShouldEqual, len(avi))
+
+	frames := make([][]byte, 0, entries)
+	for i := 0; i < entries; i++ {
+		entry := avi[idx+8+i*aviIndexEntrySize:]
+		test.That(t, string(entry[:4]), test.ShouldEqual, "00dc")
+		offset := movi + int(binary.LittleEndian.Uint32(entry[8:]))
+		size := int(binary.LittleEndian.Uint32(entry[12:]))
+		test.That(t, string(avi[offset:offset+4]), test.ShouldEqual, "00dc")
+		test.That(t, binary.LittleEndian.Uint32(avi[offset+4:]), test.ShouldEqual, size)
+		frames = append(frames, avi[offset+8:offset+8+size])
+	}
+	return frames, usPerFrame
+}
+
+func TestRecordJPEGs(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Unix(1000, 0))
+
+	// The camera gives raw images, which are recorded as JPEGs, with a color that changes every image.
+	var mu sync.Mutex
+	shade := 0
+	cam := inject.NewCamera("cam")
+	cam.ImagesFunc = func(
+		ctx context.Context, filterSourceNames []string, extra map[string]interface{},
+	) ([]camera.NamedImage, resource.ResponseMetadata, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+		for i := range img.Pix {
+			img.Pix[i] = byte(shade)
+		}
+		shade += 20
+		namedImg, err := camera.NamedImageFromImage(img, "", utils.MimeTypeRawRGBA, data.Annotations{})
+		return []camera.NamedImage{namedImg}, resource.ResponseMetadata{}, err
+	}
+	deps := resource.Dependencies{cam.Name(): cam}
+	conf := &Config{Camera: "cam", StoragePath: t.TempDir(), FrameRate: 10}
+	svc, err := newBuiltIn(ctx, video.Named("recorder"), conf, deps, mockClock, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, svc.Close(ctx), test.ShouldBeNil)
+	}()
+	store := svc.(*builtIn).store
+
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		mockClock.Add(100 * time.Millisecond)
+		frames, err := store.frames(codecMJPEG, time.Time{}, mockClock.Now())
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, len(frames), test.ShouldBeGreaterThanOrEqualTo, 5)
+	})
+
+	ch, err := svc.GetVideo(ctx, time.Time{}, time.Time{}, "", "", nil)
+	test.That(t, err, test.ShouldBeNil)
+	frames, usPerFrame := parseAVI(t, receive(t, ch, containerAVI))
+	test.That(t, len(frames), test.ShouldBeGreaterThanOrEqualTo, 5)
+	test.That(t, usPerFrame, test.ShouldEqual, 100000)
+	for i, frame := range frames {
+		img, err := jpeg.Decode(bytes.NewReader(frame))
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, img.Bounds().Dx(), test.ShouldEqual, 32)
+		r, _, _, _ := img.At(0, 0).RGBA()
+		test.That(t, float64(r>>8), test.ShouldAlmostEqual, 20*i, 2)
+	}
+
+	// Only the requested window is returned.
+	recorded, err := store.frames(codecMJPEG, time.Time{}, mockClock.Now())
+	test.That(t, err, test.ShouldBeNil)
+	ch, err = svc.GetVideo(ctx, recorded[1].time, recorded[2].time, "MJPEG", "avi", nil)
+	test.That(t, err, test.ShouldBeNil)
+	frames, _ = parseAVI(t, receive(t, ch, containerAVI))
+	test.That(t, frames, test.ShouldHaveLength, 2)
+
+	_, err = svc.GetVideo(ctx, time.Time{}, time.Time{}, "h264", "", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = svc.GetVideo(ctx, time.Time{}, time.Time{}, "", "mp4", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = svc.GetVideo(ctx, mockClock.Now(), recorded[0].time, "", "", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = svc.GetVideo(ctx, time.Unix(0, 0), time.Unix(1, 0), "", "", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "no video")
+}
+
+// fakeRTPSource passes through the packets sent to its callback. terminate ends the current subscription, and
+// subscribeErr, if set, fails new ones.
+type fakeRTPSource struct {
+	mu            sync.Mutex
+	callback      rtppassthrough.PacketCallback
+	terminate     context.CancelFunc
+	subscriptions int
+	subscribeErr  error
+}
+
+func (s *fakeRTPSource) SubscribeRTP(
+	ctx context.Context, bufferSize int, packetsCB rtppassthrough.PacketCallback,
+) (rtppassthrough.Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribeErr != nil {
+		return rtppassthrough.NilSubscription, s.subscribeErr
+	}
+	s.callback = packetsCB
+	s.subscriptions++
+	terminated, terminate := context.WithCancel(context.Background())
+	s.terminate = terminate
+	return rtppassthrough.Subscription{ID: uuid.New(), Terminated: terminated}, nil
+}
+
+// endSubscription terminates the current subscription, as a remote camera that reconnects does.
+func (s *fakeRTPSource) endSubscription(subscribeErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.callback = nil
+	s.subscribeErr = subscribeErr
+	s.terminate()
+}
+
+func (s *fakeRTPSource) Unsubscribe(ctx context.Context, id rtppassthrough.SubscriptionID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.callback = nil
+	return nil
+}
+
+func TestRecordH264(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Unix(1000, 0))
+	source := &fakeRTPSource{}
+	cam := inject.NewCamera("cam")
+	cam.RTPPassthroughSource = source
+	deps := resource.Dependencies{cam.Name(): cam}
+	conf := &Config{Camera: "cam", StoragePath: t.TempDir()}
+	svc, err := newBuiltIn(ctx, video.Named("recorder"), conf, deps, mockClock, logger)
+	test.That(t, err, test.ShouldBeNil)
+
+	sps, pps, idr, slice := []byte{0x67, 1}, []byte{0x68, 2}, []byte{0x65, 3}, []byte{0x41, 4}
+	send := func(nals ...[]byte) {
+		var pkts []*rtp.Packet
+		for i, nal := range nals {
+			pkts = append(pkts, &rtp.Packet{Header: rtp.Header{Marker: i == len(nals)-1}, Payload: nal})
+		}
+		source.mu.Lock()
+		source.callback(pkts)
+		source.mu.Unlock()
+		mockClock.Add(100 * time.Millisecond)
+	}
+	// The slice before the first keyframe cannot be decoded, so it is not recorded.
+	send(slice)
+	send(sps, pps, idr)
+	send(slice)
+	send(slice)
+
+	ch, err := svc.GetVideo(ctx, time.Time{}, time.Time{}, "H264", "", nil)
+	test.That(t, err, test.ShouldBeNil)
+	var expected []byte
+	for _, nal := range [][]byte{sps, pps, idr, slice, slice} {
+		expected = append(append(expected, annexBStartCode...), nal...)
+	}
+	test.That(t, receive(t, ch, containerH264), test.ShouldResemble, expected)
+
+	_, err = svc.GetVideo(ctx, time.Time{}, time.Time{}, "mjpeg", "", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	test.That(t, svc.Close(ctx), test.ShouldBeNil)
+	test.That(t, source.callback, test.ShouldBeNil)
+}
+
+func TestRecordH264Resubscribes(t *testing.T) {
+	ctx := context.Background()
+	logger := logging.NewTestLogger(t)
+	mockClock := clock.NewMock()
+	mockClock.Set(time.Unix(1000, 0))
+	source := &fakeRTPSource{}
+	cam := inject.NewCamera("cam")
+	cam.RTPPassthroughSource = source
+	cam.ImagesFunc = func(
+		ctx context.Context, filterSourceNames []string, extra map[string]interface{},
+	) ([]camera.NamedImage, resource.ResponseMetadata, error) {
+		namedImg, err := camera.NamedImageFromImage(image.NewRGBA(image.Rect(0, 0, 8, 8)), "", utils.MimeTypeRawRGBA,
+			data.Annotations{})
+		return []camera.NamedImage{namedImg}, resource.ResponseMetadata{}, err
+	}
+	deps := resource.Dependencies{cam.Name(): cam}
+	conf := &Config{Camera: "cam", StoragePath: t.TempDir()}
+	svc, err := newBuiltIn(ctx, video.Named("recorder"), conf, deps, mockClock, logger)
+	test.That(t, err, test.ShouldBeNil)
+	defer func() {
+		test.That(t, svc.Close(ctx), test.ShouldBeNil)
+	}()
+	store := svc.(*builtIn).store
+
+	sendKeyframe := func(tb testing.TB) {
+		source.mu.Lock()
+		defer source.mu.Unlock()
+		test.That(tb, source.callback, test.ShouldNotBeNil)
+		source.callback([]*rtp.Packet{
+			{Payload: []byte{0x67, 1}}, {Payload: []byte{0x68, 2}}, {Header: rtp.Header{Marker: true}, Payload: []byte{0x65, 3}},
+		})
+	}
+	sendKeyframe(t)
+
+	// When the subscription ends, it subscribes again and keeps recording.
+	source.endSubscription(nil)
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		source.mu.Lock()
+		test.That(tb, source.subscriptions, test.ShouldEqual, 2)
+		source.mu.Unlock()
+	})
+	mockClock.Add(time.Second)
+	sendKeyframe(t)
+	frames, err := store.frames(codecH264, time.Time{}, mockClock.Now())
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, frames, test.ShouldHaveLength, 2)
+
+	// When it cannot subscribe again, it records JPEGs instead.
+	source.endSubscription(errors.New("no longer passes through"))
+	testutils.WaitForAssertion(t, func(tb testing.TB) {
+		mockClock.Add(100 * time.Millisecond)
+		frames, err := store.frames(codecMJPEG, time.Time{}, mockClock.Now())
+		test.That(tb, err, test.ShouldBeNil)
+		test.That(tb, frames, test.ShouldNotBeEmpty)
+	})
+	ch, err := svc.GetVideo(ctx, time.Time{}, time.Time{}, "", "", nil)
+	test.That(t, err, test.ShouldBeNil)
+	frames2, _ := parseAVI(t, receive(t, ch, containerAVI))
+	test.That(t, frames2, test.ShouldNotBeEmpty)
+}
+
+func TestChunkWriter(t *testing.T) {
+	ch := make(chan *video.Chunk, 3)
+	w := &chunkWriter{ctx: context.Background(), ch: ch, container: containerH264}
+	data := bytes.Repeat([]byte{7}, chunkSize+10)
+	n, err := w.Write(data)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, n, test.ShouldEqual, len(data))
+	test.That(t, w.flush(), test.ShouldBeNil)
+	close(ch)
+	test.That(t, len((<-ch).Data), test.ShouldEqual, chunkSize)
+	test.That(t, len((<-ch).Data), test.ShouldEqual, 10)
+}
@@ -0,0 +1,95 @@
+package builtin
+
+import (
+	"bytes"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+)
+
+// The types of the H.264 NAL units that matter for recording.
+const (
+	nalTypeIDR = 5
+	nalTypeSPS = 7
+	nalTypePPS = 8
+)
+
+var annexBStartCode = []byte{0, 0, 0, 1}
+
+// accessUnitAssembler turns the RTP packets of an H.264 stream into access units in Annex B format, the frames of
+// the stream. Keyframes are given the latest parameter sets if they do not have their own, so that a recording can
+// start at any keyframe.
+type accessUnitAssembler struct {
+	depacketizer codecs.H264Packet
+	accessUnit   []byte
+	sps, pps     []byte
+}
+
+// push adds a packet, returning the access unit it completes, if any, and whether the unit is a keyframe.
+func (a *accessUnitAssembler) push(pkt *rtp.Packet) ([]byte, bool, error) {
+	data, err := a.depacketizer.Unmarshal(pkt.Payload)
+	if err != nil {
+		a.accessUnit = a.accessUnit[:0]
+		return nil, false, err
+	}
+	a.accessUnit = append(a.accessUnit, data...)
+	if !pkt.Marker || len(a.accessUnit) == 0 {
+		return nil, false, nil
+	}
+
+	accessUnit := a.accessUnit
+	a.accessUnit = nil
+	var keyframe, hasSPS, hasPPS bool
+	for _, nal := range nalUnits(accessUnit) {
+		switch nal[0] & 0x1f {
+		case nalTypeIDR:
+			keyframe = true
+		case nalTypeSPS:
+			hasSPS = true
+			a.sps = append([]byte(nil), nal...)
+		case nalTypePPS:
+			hasPPS = true
+			a.pps = append([]byte(nil), nal...)
+		}
+	}
+	if keyframe && (!hasSPS || !hasPPS) {
+		if a.sps == nil || a.pps == nil {
+			// Without parameter sets the keyframe cannot be decoded on its own.
+			return accessUnit, false, nil
+		}
+		var withParams []byte
+		if !hasSPS {
+			withParams = append(append(withParams, annexBStartCode...), a.sps...)
+		}
+		if !hasPPS {
+			withParams = append(append(withParams, annexBStartCode...), a.pps...)
+		}
+		accessUnit = append(withParams, accessUnit...)
+	}
+	return accessUnit, keyframe, nil
+}
+
+// nalUnits splits Annex B data into its NAL units, without their start codes.
+func nalUnits(data []byte) [][]byte {
+	var units [][]byte
+	for {
+		start := bytes.Index(data, annexBStartCode[1:])
+		if start < 0 {
+			return units
+		}
+		data = data[start+3:]
+		end := bytes.Index(data, annexBStartCode[1:])
+		if end < 0 {
+			if len(data) > 0 {
+				units = append(units, data)
+			}
+			return units
+		}
+		// A four byte start code leaves a zero at the end of the previous unit.
+		unit := bytes.TrimRight(data[:end], "\x00")
+		if len(unit) > 0 {
+			units = append(units, unit)
+		}
+		data = data[end:]
+	}
+}
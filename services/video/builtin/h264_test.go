@@ -0,0 +1,66 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"go.viam.com/test"
+)
+
+func TestAccessUnitAssembler(t *testing.T) {
+	sps := []byte{0x67, 1, 2}
+	pps := []byte{0x68, 3}
+	idr := []byte{0x65, 4, 5, 6}
+	slice := []byte{0x41, 7}
+	annexB := func(nals ...[]byte) []byte {
+		var out []byte
+		for _, nal := range nals {
+			out = append(append(out, annexBStartCode...), nal...)
+		}
+		return out
+	}
+	// stapA aggregates NAL units into one packet.
+	stapA := func(nals ...[]byte) []byte {
+		out := []byte{24}
+		for _, nal := range nals {
+			out = append(out, byte(len(nal)>>8), byte(len(nal)))
+			out = append(out, nal...)
+		}
+		return out
+	}
+
+	a := &accessUnitAssembler{}
+	// A keyframe before any parameter sets cannot be decoded on its own.
+	au, keyframe, err := a.push(&rtp.Packet{Header: rtp.Header{Marker: true}, Payload: idr})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, au, test.ShouldResemble, annexB(idr))
+	test.That(t, keyframe, test.ShouldBeFalse)
+
+	au, keyframe, err = a.push(&rtp.Packet{Payload: stapA(sps, pps)})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, au, test.ShouldBeNil)
+	au, keyframe, err = a.push(&rtp.Packet{Header: rtp.Header{Marker: true}, Payload: idr})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, au, test.ShouldResemble, annexB(sps, pps, idr))
+	test.That(t, keyframe, test.ShouldBeTrue)
+
+	au, keyframe, err = a.push(&rtp.Packet{Header: rtp.Header{Marker: true}, Payload: slice})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, au, test.ShouldResemble, annexB(slice))
+	test.That(t, keyframe, test.ShouldBeFalse)
+
+	// A later keyframe without its own parameter sets gets the last ones.
+	au, keyframe, err = a.push(&rtp.Packet{Header: rtp.Header{Marker: true}, Payload: idr})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, au, test.ShouldResemble, annexB(sps, pps, idr))
+	test.That(t, keyframe, test.ShouldBeTrue)
+
+	_, _, err = a.push(&rtp.Packet{Header: rtp.Header{Marker: true}})
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestNALUnits(t *testing.T) {
+	test.That(t, nalUnits([]byte{0, 0, 0, 1, 0x67, 1, 0, 0, 1, 0x68, 2, 0, 0, 0, 1, 0x65}), test.ShouldResemble,
+		[][]byte{{0x67, 1}, {0x68, 2}, {0x65}})
+	test.That(t, nalUnits([]byte{1, 2}), test.ShouldBeEmpty)
+}
@@ -0,0 +1,327 @@
+package builtin
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	goutils "go.viam.com/utils"
+
+	"go.viam.com/rdk/logging"
+)
+
+// The codecs that frames are recorded in, which are also the extensions of the segment files.
+const (
+	codecMJPEG = "mjpeg"
+	codecH264  = "h264"
+)
+
+// Each frame of a segment is recorded as its time in unix nanoseconds, a byte of flags and the length of its data,
+// followed by the data.
+const (
+	frameHeaderSize = 8 + 1 + 4
+	flagKeyframe    = 1
+)
+
+// segment is a file of consecutive frames in one codec, named by the time of its first frame.
+type segment struct {
+	path       string
+	codec      string
+	start, end time.Time
+	size       int64
+}
+
+// frame is where a recorded frame is in a segment.
+type frame struct {
+	path     string
+	offset   int64
+	length   int
+	time     time.Time
+	keyframe bool
+}
+
+// segmentStore records frames to segments on disk, starting a new segment once the current one is long enough and
+// deleting the oldest segments to stay within its limits.
+type segmentStore struct {
+	dir             string
+	segmentDuration time.Duration
+	// maxBytes and maxAge bound the segments kept, and are ignored if zero.
+	maxBytes int64
+	maxAge   time.Duration
+	logger   logging.Logger
+
+	mu       sync.Mutex
+	segments []*segment
+	current  *os.File
+	closed   bool
+}
+
+func newSegmentStore(
+	dir string,
+	segmentDuration time.Duration,
+	maxBytes int64,
+	maxAge time.Duration,
+	logger logging.Logger,
+) (*segmentStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	s := &segmentStore{dir: dir, segmentDuration: segmentDuration, maxBytes: maxBytes, maxAge: maxAge, logger: logger}
+	for _, entry := range entries {
+		seg, ok := parseSegmentName(filepath.Join(dir, entry.Name()))
+		if !ok || entry.IsDir() {
+			continue
+		}
+		frames, size, err := readSegment(seg.path)
+		if err != nil {
+			logger.Warnw("skipping unreadable video segment", "path", seg.path, "error", err)
+			continue
+		}
+		if len(frames) == 0 {
+			continue
+		}
+		seg.end = frames[len(frames)-1].time
+		seg.size = size
+		s.segments = append(s.segments, seg)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].start.Before(s.segments[j].start) })
+	return s, nil
+}
+
+func segmentName(codec string, start time.Time) string {
+	return strconv.FormatInt(start.UnixNano(), 10) + "." + codec
+}
+
+func parseSegmentName(path string) (*segment, bool) {
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	codec := strings.TrimPrefix(ext, ".")
+	if codec != codecMJPEG && codec != codecH264 {
+		return nil, false
+	}
+	nanos, err := strconv.ParseInt(strings.TrimSuffix(base, ext), 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &segment{path: path, codec: codec, start: time.Unix(0, nanos)}, true
+}
+
+// readSegment returns the frames of a segment and the size of its complete frames. A frame cut short by a crash
+// while it was being written ends the segment.
+func readSegment(path string) ([]frame, int64, error) {
+	//nolint:gosec
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer goutils.UncheckedErrorFunc(f.Close)
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	r := bufio.NewReader(f)
+	var frames []frame
+	var offset int64
+	header := make([]byte, frameHeaderSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		length := int64(binary.BigEndian.Uint32(header[9:]))
+		if offset+frameHeaderSize+length > info.Size() {
+			break
+		}
+		if _, err := r.Discard(int(length)); err != nil {
+			break
+		}
+		frames = append(frames, frame{
+			path:     path,
+			offset:   offset + frameHeaderSize,
+			length:   int(length),
+			time:     time.Unix(0, int64(binary.BigEndian.Uint64(header))),
+			keyframe: header[8]&flagKeyframe != 0,
+		})
+		offset += frameHeaderSize + length
+	}
+	return frames, offset, nil
+}
+
+// write records a frame. A segment of H.264 has to start with a keyframe to be decoded, so frames before the first
+// keyframe are dropped and a new segment is only started at a keyframe. Frames written after the store is closed are
+// dropped too.
+func (s *segmentStore) write(codec string, t time.Time, data []byte, keyframe bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+
+	var last *segment
+	if s.current != nil {
+		last = s.segments[len(s.segments)-1]
+	}
+	canStart := codec != codecH264 || keyframe
+	if last == nil || last.codec != codec || (t.Sub(last.start) >= s.segmentDuration && canStart) {
+		if !canStart {
+			return nil
+		}
+		if err := s.startSegment(codec, t); err != nil {
+			return err
+		}
+		last = s.segments[len(s.segments)-1]
+	}
+
+	buf := make([]byte, frameHeaderSize, frameHeaderSize+len(data))
+	binary.BigEndian.PutUint64(buf, uint64(t.UnixNano()))
+	if keyframe {
+		buf[8] = flagKeyframe
+	}
+	binary.BigEndian.PutUint32(buf[9:], uint32(len(data)))
+	buf = append(buf, data...)
+	if _, err := s.current.Write(buf); err != nil {
+		return err
+	}
+	last.end = t
+	last.size += int64(len(buf))
+	s.prune(t)
+	return nil
+}
+
+// startSegment closes the current segment and starts a new one.
+func (s *segmentStore) startSegment(codec string, t time.Time) error {
+	if err := s.closeCurrent(); err != nil {
+		return err
+	}
+	seg := &segment{path: filepath.Join(s.dir, segmentName(codec, t)), codec: codec, start: t, end: t}
+	//nolint:gosec
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	s.current = f
+	s.segments = append(s.segments, seg)
+	return nil
+}
+
+// prune deletes the oldest segments, other than the current one, until the rest are within the limits.
+func (s *segmentStore) prune(now time.Time) {
+	var total int64
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+	for len(s.segments) > 1 {
+		oldest := s.segments[0]
+		tooBig := s.maxBytes > 0 && total > s.maxBytes
+		tooOld := s.maxAge > 0 && now.Sub(oldest.end) > s.maxAge
+		if !tooBig && !tooOld {
+			return
+		}
+		if err := os.Remove(oldest.path); err != nil && !os.IsNotExist(err) {
+			s.logger.Warnw("failed to delete video segment", "path", oldest.path, "error", err)
+		}
+		total -= oldest.size
+		s.segments = s.segments[1:]
+	}
+}
+
+// frames returns the recorded frames in the codec between start and end. H.264 frames start from the last keyframe
+// at or before start so that the first frames can be decoded.
+func (s *segmentStore) frames(codec string, start, end time.Time) ([]frame, error) {
+	s.mu.Lock()
+	var overlapping []*segment
+	for _, seg := range s.segments {
+		if seg.codec == codec && !seg.start.After(end) && !seg.end.Before(start) {
+			copied := *seg
+			overlapping = append(overlapping, &copied)
+		}
+	}
+	s.mu.Unlock()
+
+	var frames []frame
+	for _, seg := range overlapping {
+		segFrames, _, err := readSegment(seg.path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				// It was deleted since the segments were listed.
+				continue
+			}
+			return nil, err
+		}
+		for _, f := range segFrames {
+			if f.time.After(end) {
+				break
+			}
+			if !f.time.Before(start) {
+				frames = append(frames, f)
+				continue
+			}
+			if codec == codecH264 && f.keyframe {
+				frames = append(frames[:0], f)
+			} else if codec == codecH264 && len(frames) > 0 {
+				frames = append(frames, f)
+			}
+		}
+	}
+	return frames, nil
+}
+
+// readFrames calls fn with the data of each frame in order, keeping the segment files open between frames of the
+// same segment.
+func readFrames(frames []frame, fn func(f frame, data []byte) error) (err error) {
+	var file *os.File
+	defer func() {
+		if file != nil {
+			err = multierr.Combine(err, file.Close())
+		}
+	}()
+	for _, f := range frames {
+		if file == nil || file.Name() != f.path {
+			if file != nil {
+				if err := file.Close(); err != nil {
+					return err
+				}
+			}
+			//nolint:gosec
+			if file, err = os.Open(f.path); err != nil {
+				return errors.Wrap(err, "video segment was deleted while being read")
+			}
+		}
+		data := make([]byte, f.length)
+		if _, err := file.ReadAt(data, f.offset); err != nil {
+			return err
+		}
+		if err := fn(f, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *segmentStore) closeCurrent() error {
+	if s.current == nil {
+		return nil
+	}
+	err := s.current.Close()
+	s.current = nil
+	return err
+}
+
+func (s *segmentStore) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return s.closeCurrent()
+}
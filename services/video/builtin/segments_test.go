@@ -0,0 +1,99 @@
+package builtin
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/logging"
+)
+
+func readAll(t *testing.T, frames []frame) []string {
+	t.Helper()
+	var data []string
+	test.That(t, readFrames(frames, func(f frame, d []byte) error {
+		data = append(data, string(d))
+		return nil
+	}), test.ShouldBeNil)
+	return data
+}
+
+func TestSegmentStore(t *testing.T) {
+	logger := logging.NewTestLogger(t)
+	dir := t.TempDir()
+	start := time.Unix(1000, 0)
+	// Each frame takes up 15 bytes, so four segments of three frames are 180 bytes.
+	store, err := newSegmentStore(dir, 3*time.Second, 160, 0, logger)
+	test.That(t, err, test.ShouldBeNil)
+	for i := 0; i < 12; i++ {
+		test.That(t, store.write(codecMJPEG, start.Add(time.Duration(i)*time.Second), []byte{'a' + byte(i), 'z'}, true),
+			test.ShouldBeNil)
+	}
+	// The oldest segment was deleted to make room for the newest.
+	test.That(t, store.segments, test.ShouldHaveLength, 3)
+	entries, err := os.ReadDir(dir)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, entries, test.ShouldHaveLength, 3)
+
+	frames, err := store.frames(codecMJPEG, start.Add(4*time.Second), start.Add(7*time.Second))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readAll(t, frames), test.ShouldResemble, []string{"ez", "fz", "gz", "hz"})
+	test.That(t, frames[0].time, test.ShouldEqual, start.Add(4*time.Second))
+	frames, err = store.frames(codecMJPEG, start, start.Add(time.Hour))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, frames, test.ShouldHaveLength, 9)
+	frames, err = store.frames(codecH264, start, start.Add(time.Hour))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, frames, test.ShouldBeEmpty)
+	test.That(t, store.close(), test.ShouldBeNil)
+
+	// A frame cut short at the end of a segment is left out when the segments are read again.
+	last := store.segments[len(store.segments)-1].path
+	f, err := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0o600)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = f.Write([]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 0, 0, 0, 5, 'x'})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, f.Close(), test.ShouldBeNil)
+	store, err = newSegmentStore(dir, 3*time.Second, 0, time.Minute, logger)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, store.segments, test.ShouldHaveLength, 3)
+	test.That(t, store.segments[2].end, test.ShouldEqual, start.Add(11*time.Second))
+	test.That(t, store.segments[2].size, test.ShouldEqual, 45)
+	frames, err = store.frames(codecMJPEG, start, start.Add(time.Hour))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, frames, test.ShouldHaveLength, 9)
+
+	// Segments older than the maximum age are deleted when a new segment starts.
+	test.That(t, store.write(codecMJPEG, start.Add(70*time.Second), []byte("later"), true), test.ShouldBeNil)
+	test.That(t, store.segments, test.ShouldHaveLength, 2)
+	test.That(t, store.close(), test.ShouldBeNil)
+	test.That(t, store.write(codecMJPEG, start.Add(71*time.Second), []byte("closed"), true), test.ShouldBeNil)
+	test.That(t, store.segments, test.ShouldHaveLength, 2)
+}
+
+func TestSegmentStoreH264(t *testing.T) {
+	store, err := newSegmentStore(t.TempDir(), 2*time.Second, 0, 0, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	defer store.close()
+	start := time.Unix(1000, 0)
+	for i := 0; i < 12; i++ {
+		// Keyframes come every third frame, and the first frames come before any keyframe.
+		keyframe := i%3 == 2
+		test.That(t, store.write(codecH264, start.Add(time.Duration(i)*time.Second), []byte{'a' + byte(i)}, keyframe),
+			test.ShouldBeNil)
+	}
+	// New segments only start at keyframes.
+	test.That(t, store.segments, test.ShouldHaveLength, 4)
+	test.That(t, store.segments[0].start, test.ShouldEqual, start.Add(2*time.Second))
+	test.That(t, store.segments[1].start, test.ShouldEqual, start.Add(5*time.Second))
+
+	// The frames start from the keyframe before the start time.
+	frames, err := store.frames(codecH264, start.Add(9*time.Second), start.Add(10*time.Second))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readAll(t, frames), test.ShouldResemble, []string{"i", "j", "k"})
+	frames, err = store.frames(codecH264, start, start.Add(3*time.Second))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, readAll(t, frames), test.ShouldResemble, []string{"c", "d"})
+}
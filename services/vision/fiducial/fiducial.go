@@ -0,0 +1,165 @@
+// Package fiducial detects AprilTags and ArUco markers, and reports the corners of each tag it finds through
+// DoCommand.
+package fiducial
+
+import (
+	"context"
+	"image"
+	"math"
+
+	"github.com/pkg/errors"
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/vision/fiducial"
+	"go.viam.com/rdk/vision/objectdetection"
+)
+
+var model = resource.DefaultModelFamily.WithModel("fiducial")
+
+// DetectFiducialsCommand is the DoCommand key whose value is the name of a camera, or empty for the default camera,
+// to detect the tags in the next image of, with their corners.
+const DetectFiducialsCommand = "detect_fiducials"
+
+func init() {
+	resource.RegisterService(vision.API, model, resource.Registration[vision.Service, *Config]{
+		Constructor: func(
+			ctx context.Context, deps resource.Dependencies, c resource.Config, logger logging.Logger,
+		) (vision.Service, error) {
+			conf, err := resource.NativeConfig[*Config](c)
+			if err != nil {
+				return nil, err
+			}
+			return newFiducialDetector(ctx, c.ResourceName(), conf, deps, logger)
+		},
+	})
+}
+
+// Config describes the tags to detect.
+type Config struct {
+	DefaultCamera string `json:"camera_name,omitempty"`
+	// Families are the names of the built in families to detect, all of them if neither these nor custom families
+	// are given.
+	Families       []string                `json:"families,omitempty"`
+	CustomFamilies []fiducial.FamilyConfig `json:"custom_families,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid and returns the default camera as a dependency.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	if _, err := fiducial.FamiliesFromConfig(conf.Families, conf.CustomFamilies); err != nil {
+		return nil, nil, resource.NewConfigValidationError(path, err)
+	}
+	if conf.DefaultCamera == "" {
+		return nil, nil, nil
+	}
+	return []string{conf.DefaultCamera}, nil, nil
+}
+
+// fiducialDetector is a vision service whose detections are tags, labeled by family and ID.
+type fiducialDetector struct {
+	vision.Service
+	detector      *fiducial.Detector
+	defaultCamera string
+	getCamera     func(name string) (camera.Camera, error)
+}
+
+func newFiducialDetector(
+	ctx context.Context,
+	name resource.Name,
+	conf *Config,
+	deps resource.Dependencies,
+	logger logging.Logger,
+) (vision.Service, error) {
+	_, span := trace.StartSpan(ctx, "service::vision::newFiducialDetector")
+	defer span.End()
+	families, err := fiducial.FamiliesFromConfig(conf.Families, conf.CustomFamilies)
+	if err != nil {
+		return nil, err
+	}
+	detector := fiducial.NewDetector(families...)
+	svc, err := vision.NewService(name, deps, logger, nil, nil, objectDetector(detector), nil, conf.DefaultCamera)
+	if err != nil {
+		return nil, err
+	}
+	return &fiducialDetector{
+		Service:       svc,
+		detector:      detector,
+		defaultCamera: conf.DefaultCamera,
+		getCamera: func(name string) (camera.Camera, error) {
+			return camera.FromProvider(deps, name)
+		},
+	}, nil
+}
+
+// objectDetector returns an object detector whose detections are the bounding boxes of the tags the detector finds.
+func objectDetector(detector *fiducial.Detector) objectdetection.Detector {
+	maxCorrected := map[string]int{}
+	for _, family := range detector.Families() {
+		maxCorrected[family.Name] = family.MaxCorrectedBits
+	}
+	return func(ctx context.Context, img image.Image) ([]objectdetection.Detection, error) {
+		tags := detector.Detect(img)
+		detections := make([]objectdetection.Detection, 0, len(tags))
+		for _, tag := range tags {
+			minX, minY := math.Inf(1), math.Inf(1)
+			maxX, maxY := math.Inf(-1), math.Inf(-1)
+			for _, corner := range tag.Corners {
+				minX, minY = math.Min(minX, corner.X), math.Min(minY, corner.Y)
+				maxX, maxY = math.Max(maxX, corner.X), math.Max(maxY, corner.Y)
+			}
+			box := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+			// Each corrected cell makes the detection less certain.
+			score := 1 - float64(tag.Hamming)/float64(maxCorrected[tag.Family]+1)
+			detections = append(detections, objectdetection.NewDetection(img.Bounds(), box, score, tag.Label()))
+		}
+		return detections, nil
+	}
+}
+
+// DoCommand detects the tags in the next image of a camera when given DetectFiducialsCommand, and returns them with
+// their corners, which the detections of the vision service do not have.
+func (fd *fiducialDetector) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	value, ok := cmd[DetectFiducialsCommand]
+	if !ok {
+		return fd.Service.DoCommand(ctx, cmd)
+	}
+	cameraName, ok := value.(string)
+	if !ok {
+		return nil, errors.Errorf("%s must be the name of a camera, not %v", DetectFiducialsCommand, value)
+	}
+	if cameraName == "" {
+		cameraName = fd.defaultCamera
+	}
+	if cameraName == "" {
+		return nil, errors.New("no camera name provided and no default camera found")
+	}
+	cam, err := fd.getCamera(cameraName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find camera named %s", cameraName)
+	}
+	img, err := camera.DecodeImageFromCamera(ctx, cam, nil, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not get image from %s", cameraName)
+	}
+
+	tags := fd.detector.Detect(img)
+	fiducials := make([]interface{}, 0, len(tags))
+	for _, tag := range tags {
+		corners := make([]interface{}, 0, len(tag.Corners))
+		for _, corner := range tag.Corners {
+			corners = append(corners, []interface{}{corner.X, corner.Y})
+		}
+		fiducials = append(fiducials, map[string]interface{}{
+			"family":  tag.Family,
+			"id":      tag.ID,
+			"label":   tag.Label(),
+			"corners": corners,
+			"center":  []interface{}{tag.Center.X, tag.Center.Y},
+			"hamming": tag.Hamming,
+		})
+	}
+	return map[string]interface{}{"fiducials": fiducials}, nil
+}
@@ -0,0 +1,101 @@
+package fiducial
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/testutils/inject"
+	"go.viam.com/rdk/utils"
+	"go.viam.com/rdk/vision/fiducial"
+)
+
+func TestValidate(t *testing.T) {
+	conf := &Config{}
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldBeEmpty)
+
+	conf = &Config{DefaultCamera: "cam", Families: []string{fiducial.Tag16h5}}
+	deps, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"cam"})
+
+	conf = &Config{Families: []string{"tag36h11"}}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "tag36h11")
+
+	conf = &Config{CustomFamilies: []fiducial.FamilyConfig{{Name: "custom", GridSize: 3}}}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "no codes")
+}
+
+func TestFiducialDetector(t *testing.T) {
+	ctx := context.Background()
+	family, err := fiducial.FamilyByName(fiducial.Tag16h5)
+	test.That(t, err, test.ShouldBeNil)
+	tag, err := family.Render(9, 10)
+	test.That(t, err, test.ShouldBeNil)
+	img := image.NewGray(image.Rect(0, 0, 200, 100))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	for y := 0; y < tag.Bounds().Dy(); y++ {
+		for x := 0; x < tag.Bounds().Dx(); x++ {
+			img.SetGray(x+50, y+10, tag.GrayAt(x, y))
+		}
+	}
+
+	cam := inject.NewCamera("cam")
+	cam.ImagesFunc = func(
+		ctx context.Context, filterSourceNames []string, extra map[string]interface{},
+	) ([]camera.NamedImage, resource.ResponseMetadata, error) {
+		namedImg, err := camera.NamedImageFromImage(img, "", utils.MimeTypeJPEG, data.Annotations{})
+		return []camera.NamedImage{namedImg}, resource.ResponseMetadata{}, err
+	}
+	deps := resource.Dependencies{cam.Name(): cam}
+	name := vision.Named("tags")
+	svc, err := newFiducialDetector(ctx, name, &Config{DefaultCamera: "cam", Families: []string{fiducial.Tag16h5}}, deps, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, svc.Name(), test.ShouldResemble, name)
+
+	props, err := svc.GetProperties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.DetectionSupported, test.ShouldBeTrue)
+	test.That(t, props.ClassificationSupported, test.ShouldBeFalse)
+
+	detections, err := svc.DetectionsFromCamera(ctx, "", nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, detections, test.ShouldHaveLength, 1)
+	test.That(t, detections[0].Label(), test.ShouldEqual, "tag16h5_9")
+	test.That(t, detections[0].Score(), test.ShouldEqual, 1)
+	test.That(t, *detections[0].BoundingBox(), test.ShouldResemble, image.Rect(60, 20, 120, 80))
+
+	resp, err := svc.DoCommand(ctx, map[string]interface{}{DetectFiducialsCommand: ""})
+	test.That(t, err, test.ShouldBeNil)
+	fiducials := resp["fiducials"].([]interface{})
+	test.That(t, fiducials, test.ShouldHaveLength, 1)
+	found := fiducials[0].(map[string]interface{})
+	test.That(t, found["family"], test.ShouldEqual, fiducial.Tag16h5)
+	test.That(t, found["id"], test.ShouldEqual, 9)
+	test.That(t, found["label"], test.ShouldEqual, "tag16h5_9")
+	corner := found["corners"].([]interface{})[2].([]interface{})
+	test.That(t, corner[0], test.ShouldAlmostEqual, 120, 0.5)
+	test.That(t, corner[1], test.ShouldAlmostEqual, 80, 0.5)
+
+	_, err = svc.DoCommand(ctx, map[string]interface{}{DetectFiducialsCommand: "missing"})
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = svc.DoCommand(ctx, map[string]interface{}{DetectFiducialsCommand: 3})
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = svc.DoCommand(ctx, map[string]interface{}{"other": true})
+	test.That(t, err, test.ShouldBeError, resource.ErrDoUnimplemented)
+}
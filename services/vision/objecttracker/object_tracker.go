@@ -0,0 +1,187 @@
+// Package objecttracker wraps the detector of another vision service with a tracker, so that its detections keep the
+// same identity from one image of a camera to the next.
+package objecttracker
+
+import (
+	"context"
+	"image"
+
+	"github.com/benbjohnson/clock"
+	"github.com/pkg/errors"
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/utils"
+	"go.viam.com/rdk/vision/objectdetection"
+	"go.viam.com/rdk/vision/objecttracking"
+)
+
+var model = resource.DefaultModelFamily.WithModel("object_tracker")
+
+const (
+	// ListTracksCommand is the DoCommand key that returns the current tracks, including those not yet confirmed.
+	ListTracksCommand = "list_tracks"
+	// ClearTracksCommand is the DoCommand key that drops every track.
+	ClearTracksCommand = "clear_tracks"
+)
+
+func init() {
+	resource.RegisterService(vision.API, model, resource.Registration[vision.Service, *Config]{
+		Constructor: func(
+			ctx context.Context, deps resource.Dependencies, c resource.Config, logger logging.Logger,
+		) (vision.Service, error) {
+			conf, err := resource.NativeConfig[*Config](c)
+			if err != nil {
+				return nil, err
+			}
+			return newObjectTracker(ctx, c.ResourceName(), conf, deps, clock.New(), logger)
+		},
+	})
+}
+
+// Config describes the detector to track the detections of, the camera whose images they are from and how the
+// detections are associated with tracks.
+type Config struct {
+	DetectorName  string `json:"detector_name"`
+	DefaultCamera string `json:"camera_name"`
+	// IoUThreshold is the smallest overlap between a detection and the predicted box of a track for the detection to
+	// continue the track, 0.3 if not given.
+	IoUThreshold float64 `json:"iou_threshold,omitempty"`
+	// MaxMissed is the number of images in a row a track can go undetected before it is dropped, 5 if not given.
+	MaxMissed *int `json:"max_missed_frames,omitempty"`
+	// MinHits is the number of times an object must be detected before its detections are returned, 3 if not given.
+	MinHits int `json:"min_hits,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid and returns the detector and camera as dependencies.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	if conf.DetectorName == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "detector_name")
+	}
+	if conf.DefaultCamera == "" {
+		return nil, nil, resource.NewConfigValidationFieldRequiredError(path, "camera_name")
+	}
+	if err := conf.trackerConfig().CheckValid(); err != nil {
+		return nil, nil, resource.NewConfigValidationError(path, err)
+	}
+	return []string{conf.DetectorName, conf.DefaultCamera}, nil, nil
+}
+
+// trackerConfig returns the config of the tracker with the defaults filled in.
+func (conf *Config) trackerConfig() objecttracking.Config {
+	cfg := objecttracking.DefaultConfig()
+	if conf.IoUThreshold != 0 {
+		cfg.IoUThreshold = conf.IoUThreshold
+	}
+	if conf.MaxMissed != nil {
+		cfg.MaxMissed = *conf.MaxMissed
+	}
+	if conf.MinHits != 0 {
+		cfg.MinHits = conf.MinHits
+	}
+	return cfg
+}
+
+// objectTracker is a vision service whose detections are those of another vision service, labeled with the IDs of
+// their tracks. Detections of images not from its camera would be mixed up with those of the camera, so it only
+// detects from its own camera.
+type objectTracker struct {
+	vision.Service
+	detector      vision.Service
+	tracker       *objecttracking.Tracker
+	defaultCamera string
+	clock         clock.Clock
+}
+
+func newObjectTracker(
+	ctx context.Context,
+	name resource.Name,
+	conf *Config,
+	deps resource.Dependencies,
+	clk clock.Clock,
+	logger logging.Logger,
+) (vision.Service, error) {
+	_, span := trace.StartSpan(ctx, "service::vision::newObjectTracker")
+	defer span.End()
+	detector, err := vision.FromDependencies(deps, conf.DetectorName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find detector %q", conf.DetectorName)
+	}
+	tracker, err := objecttracking.NewTracker(conf.trackerConfig())
+	if err != nil {
+		return nil, err
+	}
+	ot := &objectTracker{detector: detector, tracker: tracker, defaultCamera: conf.DefaultCamera, clock: clk}
+	ot.Service, err = vision.NewService(name, deps, logger, nil, nil, ot.detect, nil, conf.DefaultCamera)
+	if err != nil {
+		return nil, err
+	}
+	return ot, nil
+}
+
+// detect tracks the detections the detector finds in an image.
+func (ot *objectTracker) detect(ctx context.Context, img image.Image) ([]objectdetection.Detection, error) {
+	now := ot.clock.Now()
+	namedImg, err := camera.NamedImageFromImage(img, "", utils.MimeTypePNG, data.Annotations{})
+	if err != nil {
+		return nil, err
+	}
+	detections, err := ot.detector.Detections(ctx, &namedImg, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ot.tracker.Update(detections, now)
+}
+
+// DetectionsFromCamera tracks the detections the detector finds in the next image of the camera, letting the
+// detector get the image itself.
+func (ot *objectTracker) DetectionsFromCamera(
+	ctx context.Context,
+	cameraName string,
+	extra map[string]interface{},
+) ([]objectdetection.Detection, error) {
+	if cameraName == "" {
+		cameraName = ot.defaultCamera
+	}
+	if cameraName != ot.defaultCamera {
+		return nil, errors.Errorf("can only track the detections of camera %q, not %q", ot.defaultCamera, cameraName)
+	}
+	now := ot.clock.Now()
+	detections, err := ot.detector.DetectionsFromCamera(ctx, cameraName, extra)
+	if err != nil {
+		return nil, err
+	}
+	return ot.tracker.Update(detections, now)
+}
+
+// DoCommand lists the tracks when given ListTracksCommand and drops them when given ClearTracksCommand.
+func (ot *objectTracker) DoCommand(ctx context.Context, cmd map[string]interface{}) (map[string]interface{}, error) {
+	if _, ok := cmd[ClearTracksCommand]; ok {
+		ot.tracker.Clear()
+		return map[string]interface{}{}, nil
+	}
+	if _, ok := cmd[ListTracksCommand]; !ok {
+		return ot.Service.DoCommand(ctx, cmd)
+	}
+	minHits := ot.tracker.Config().MinHits
+	tracks := ot.tracker.Tracks()
+	list := make([]interface{}, 0, len(tracks))
+	for _, track := range tracks {
+		box := track.BoundingBox
+		list = append(list, map[string]interface{}{
+			"id":                track.ID,
+			"label":             track.Label,
+			"bounding_box":      []interface{}{box.Min.X, box.Min.Y, box.Max.X, box.Max.Y},
+			"velocity_px_per_s": []interface{}{track.Velocity.X, track.Velocity.Y},
+			"age_s":             track.Age().Seconds(),
+			"hits":              track.Hits,
+			"missed":            track.Missed,
+			"confirmed":         track.Confirmed(minHits),
+		})
+	}
+	return map[string]interface{}{"tracks": list}, nil
+}
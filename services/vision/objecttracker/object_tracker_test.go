@@ -0,0 +1,138 @@
+package objecttracker
+
+import (
+	"context"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/data"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/testutils/inject"
+	"go.viam.com/rdk/utils"
+	"go.viam.com/rdk/vision/objectdetection"
+	"go.viam.com/rdk/vision/objecttracking"
+)
+
+func TestValidate(t *testing.T) {
+	conf := &Config{DetectorName: "detector", DefaultCamera: "cam"}
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"detector", "cam"})
+
+	conf = &Config{DefaultCamera: "cam"}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "detector_name")
+
+	conf = &Config{DetectorName: "detector"}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "camera_name")
+
+	conf = &Config{DetectorName: "detector", DefaultCamera: "cam", IoUThreshold: 2}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+
+	zero := 0
+	conf = &Config{DetectorName: "detector", DefaultCamera: "cam", MaxMissed: &zero, MinHits: 1}
+	test.That(t, conf.trackerConfig(), test.ShouldResemble, objecttracking.Config{IoUThreshold: 0.3, MaxMissed: 0, MinHits: 1})
+}
+
+func TestObjectTracker(t *testing.T) {
+	ctx := context.Background()
+	// The detector sees a person walking right by 10 pixels a frame.
+	frame := 0
+	personAt := func() []objectdetection.Detection {
+		box := image.Rect(100+10*frame, 100, 140+10*frame, 180)
+		frame++
+		return []objectdetection.Detection{objectdetection.NewDetection(image.Rect(0, 0, 640, 480), box, 0.9, "person")}
+	}
+	detector := inject.NewVisionService("detector")
+	detector.DetectionsFromCameraFunc = func(
+		ctx context.Context, cameraName string, extra map[string]interface{},
+	) ([]objectdetection.Detection, error) {
+		test.That(t, cameraName, test.ShouldEqual, "cam")
+		return personAt(), nil
+	}
+	detector.DetectionsFunc = func(
+		ctx context.Context, img *camera.NamedImage, extra map[string]interface{},
+	) ([]objectdetection.Detection, error) {
+		return personAt(), nil
+	}
+	cam := inject.NewCamera("cam")
+	deps := resource.Dependencies{detector.Name(): detector, cam.Name(): cam}
+	mockClock := clock.NewMock()
+	name := vision.Named("tracker")
+	svc, err := newObjectTracker(ctx, name, &Config{DetectorName: "detector", DefaultCamera: "cam"}, deps, mockClock,
+		logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, svc.Name(), test.ShouldResemble, name)
+
+	props, err := svc.GetProperties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.DetectionSupported, test.ShouldBeTrue)
+
+	var detections []objectdetection.Detection
+	for i := 0; i < 5; i++ {
+		detections, err = svc.DetectionsFromCamera(ctx, "", nil)
+		test.That(t, err, test.ShouldBeNil)
+		if i < 2 {
+			test.That(t, detections, test.ShouldBeEmpty)
+		}
+		mockClock.Add(100 * time.Millisecond)
+	}
+	test.That(t, detections, test.ShouldHaveLength, 1)
+	test.That(t, detections[0].Label(), test.ShouldEqual, "person_1")
+	test.That(t, *detections[0].BoundingBox(), test.ShouldResemble, image.Rect(140, 100, 180, 180))
+	test.That(t, detections[0].NormalizedBoundingBox(), test.ShouldNotBeNil)
+	tracked, ok := detections[0].(*objecttracking.Detection)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, tracked.Track.Velocity.X, test.ShouldAlmostEqual, 100, 10)
+	test.That(t, tracked.Track.Age(), test.ShouldEqual, 400*time.Millisecond)
+
+	// Images given to the service go through the same tracker.
+	img := image.NewRGBA(image.Rect(0, 0, 640, 480))
+	namedImg, err := camera.NamedImageFromImage(img, "", utils.MimeTypePNG, data.Annotations{})
+	test.That(t, err, test.ShouldBeNil)
+	detections, err = svc.Detections(ctx, &namedImg, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, detections, test.ShouldHaveLength, 1)
+	test.That(t, detections[0].Label(), test.ShouldEqual, "person_1")
+
+	_, err = svc.DetectionsFromCamera(ctx, "other", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "other")
+
+	resp, err := svc.DoCommand(ctx, map[string]interface{}{ListTracksCommand: true})
+	test.That(t, err, test.ShouldBeNil)
+	tracks, ok := resp["tracks"].([]interface{})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, tracks, test.ShouldHaveLength, 1)
+	track, ok := tracks[0].(map[string]interface{})
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, track["id"], test.ShouldEqual, 1)
+	test.That(t, track["label"], test.ShouldEqual, "person")
+	test.That(t, track["hits"], test.ShouldEqual, 6)
+	test.That(t, track["confirmed"], test.ShouldBeTrue)
+	test.That(t, track["bounding_box"], test.ShouldHaveLength, 4)
+
+	_, err = svc.DoCommand(ctx, map[string]interface{}{ClearTracksCommand: true})
+	test.That(t, err, test.ShouldBeNil)
+	resp, err = svc.DoCommand(ctx, map[string]interface{}{ListTracksCommand: true})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, resp["tracks"], test.ShouldBeEmpty)
+	detections, err = svc.DetectionsFromCamera(ctx, "cam", nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, detections, test.ShouldBeEmpty)
+
+	_, err = newObjectTracker(ctx, name, &Config{DetectorName: "missing", DefaultCamera: "cam"}, deps, mockClock,
+		logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldNotBeNil)
+}
@@ -0,0 +1,140 @@
+// Package obstaclespointcloud finds obstacles in the point clouds of depth cameras, by removing the ground plane and
+// clustering the points that remain into objects with oriented bounding boxes.
+package obstaclespointcloud
+
+import (
+	"context"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/utils/trace"
+
+	"go.viam.com/rdk/components/camera"
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/vision"
+	viz "go.viam.com/rdk/vision"
+	"go.viam.com/rdk/vision/segmentation"
+)
+
+var model = resource.DefaultModelFamily.WithModel("obstacles_pointcloud")
+
+// Default values of the config, for a camera whose points are in mm.
+const (
+	defaultMinPtsInPlane      = 500
+	defaultMinPtsInSegment    = 10
+	defaultClusteringRadiusMm = 30
+	defaultLabel              = "obstacle"
+)
+
+func init() {
+	resource.RegisterService(vision.API, model, resource.Registration[vision.Service, *Config]{
+		Constructor: func(
+			ctx context.Context, deps resource.Dependencies, c resource.Config, logger logging.Logger,
+		) (vision.Service, error) {
+			conf, err := resource.NativeConfig[*Config](c)
+			if err != nil {
+				return nil, err
+			}
+			return newObstaclesPointCloud(ctx, c.ResourceName(), conf, deps, logger)
+		},
+	})
+}
+
+// Config describes how to separate obstacles from the ground. Every field is optional.
+type Config struct {
+	DefaultCamera string `json:"camera_name,omitempty"`
+	// MinPtsInPlane is the fewest points a plane must have to be removed as the ground.
+	MinPtsInPlane int `json:"min_points_in_plane,omitempty"`
+	// MaxDistFromPlane is the furthest in mm that a point can be from the ground plane to be part of it.
+	MaxDistFromPlane float64 `json:"max_dist_from_plane_mm,omitempty"`
+	// NormalVec is the normal of the ground in the frame of the camera, +z if not given.
+	NormalVec *r3.Vector `json:"ground_plane_normal_vec,omitempty"`
+	// AngleTolerance is the largest angle in degrees between NormalVec and a plane that can be the ground.
+	AngleTolerance  float64 `json:"ground_angle_tolerance_degs,omitempty"`
+	MinPtsInSegment int     `json:"min_points_in_segment,omitempty"`
+	// ClusteringRadiusMm is the furthest two points can be from each other to be part of the same obstacle.
+	ClusteringRadiusMm float64 `json:"clustering_radius_mm,omitempty"`
+	// MeanKFiltering is the number of neighbors used to remove outlying points before clustering, or 0 to not remove
+	// any.
+	MeanKFiltering int    `json:"mean_k_filtering,omitempty"`
+	Label          string `json:"label,omitempty"`
+}
+
+// Validate ensures all parts of the config are valid and returns the default camera as a dependency.
+func (conf *Config) Validate(path string) ([]string, []string, error) {
+	if _, err := conf.clusteringConfig(); err != nil {
+		return nil, nil, resource.NewConfigValidationError(path, err)
+	}
+	if conf.DefaultCamera == "" {
+		return nil, nil, nil
+	}
+	return []string{conf.DefaultCamera}, nil, nil
+}
+
+// clusteringConfig returns the radius clustering config with the defaults filled in.
+func (conf *Config) clusteringConfig() (*segmentation.RadiusClusteringConfig, error) {
+	cfg := &segmentation.RadiusClusteringConfig{
+		MinPtsInPlane:      conf.MinPtsInPlane,
+		MaxDistFromPlane:   conf.MaxDistFromPlane,
+		AngleTolerance:     conf.AngleTolerance,
+		MinPtsInSegment:    conf.MinPtsInSegment,
+		ClusteringRadiusMm: conf.ClusteringRadiusMm,
+		MeanKFiltering:     conf.MeanKFiltering,
+		Label:              conf.Label,
+	}
+	if conf.NormalVec != nil {
+		cfg.NormalVec = *conf.NormalVec
+	}
+	if cfg.MinPtsInPlane == 0 {
+		cfg.MinPtsInPlane = defaultMinPtsInPlane
+	}
+	if cfg.MinPtsInSegment == 0 {
+		cfg.MinPtsInSegment = defaultMinPtsInSegment
+	}
+	if cfg.ClusteringRadiusMm == 0 {
+		cfg.ClusteringRadiusMm = defaultClusteringRadiusMm
+	}
+	if cfg.Label == "" {
+		cfg.Label = defaultLabel
+	}
+	if err := cfg.CheckValid(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func newObstaclesPointCloud(
+	ctx context.Context,
+	name resource.Name,
+	conf *Config,
+	deps resource.Dependencies,
+	logger logging.Logger,
+) (vision.Service, error) {
+	_, span := trace.StartSpan(ctx, "service::vision::newObstaclesPointCloud")
+	defer span.End()
+	cfg, err := conf.clusteringConfig()
+	if err != nil {
+		return nil, err
+	}
+	return vision.NewService(name, deps, logger, nil, nil, nil, segmenter(cfg), conf.DefaultCamera)
+}
+
+// segmenter returns a segmenter whose objects are the clusters of points off the ground, in oriented boxes that fit
+// them more tightly than the boxes aligned with the axes of the camera that radius clustering returns.
+func segmenter(cfg *segmentation.RadiusClusteringConfig) segmentation.Segmenter {
+	return func(ctx context.Context, src camera.Camera) ([]*viz.Object, error) {
+		objects, err := cfg.RadiusClustering(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range objects {
+			box, err := pointcloud.OrientedBoundingBoxFromPointCloudWithLabel(object.PointCloud, cfg.Label)
+			if err != nil {
+				return nil, err
+			}
+			object.Geometry = box
+		}
+		return objects, nil
+	}
+}
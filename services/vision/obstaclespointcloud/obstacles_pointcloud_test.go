@@ -0,0 +1,112 @@
+package obstaclespointcloud
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/logging"
+	"go.viam.com/rdk/pointcloud"
+	"go.viam.com/rdk/resource"
+	"go.viam.com/rdk/services/vision"
+	"go.viam.com/rdk/spatialmath"
+	"go.viam.com/rdk/testutils/inject"
+)
+
+func TestValidate(t *testing.T) {
+	conf := &Config{}
+	deps, _, err := conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldBeEmpty)
+
+	conf = &Config{DefaultCamera: "cam", NormalVec: &r3.Vector{Y: -1}}
+	deps, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, deps, test.ShouldResemble, []string{"cam"})
+
+	conf = &Config{NormalVec: &r3.Vector{Y: -2}}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "unit vector")
+
+	conf = &Config{ClusteringRadiusMm: -1}
+	_, _, err = conf.Validate("path")
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "clustering_radius_mm")
+}
+
+// makeScene returns a floor with two boxes on it, one of which is turned so that it does not line up with the axes.
+func makeScene(t *testing.T) pointcloud.PointCloud {
+	t.Helper()
+	cloud := pointcloud.NewBasicEmpty()
+	for x := -1000.; x <= 1000; x += 20 {
+		for y := -1000.; y <= 1000; y += 20 {
+			test.That(t, cloud.Set(r3.Vector{X: x, Y: y}, nil), test.ShouldBeNil)
+		}
+	}
+	boxes := []spatialmath.Pose{
+		spatialmath.NewPoseFromPoint(r3.Vector{X: -500, Y: -500, Z: 100}),
+		spatialmath.NewPose(r3.Vector{X: 500, Y: 400, Z: 100}, &spatialmath.OrientationVectorDegrees{OZ: 1, Theta: 45}),
+	}
+	for _, pose := range boxes {
+		// the sides and top of a box 400 long, 100 wide and 200 tall, standing on the floor
+		for a := -200.; a <= 200; a += 10 {
+			for b := -100.; b <= 100; b += 10 {
+				for _, p := range []r3.Vector{{X: a, Y: -50, Z: b}, {X: a, Y: 50, Z: b}} {
+					test.That(t, cloud.Set(spatialmath.Compose(pose, spatialmath.NewPoseFromPoint(p)).Point(), nil), test.ShouldBeNil)
+				}
+			}
+			for b := -40.; b <= 40; b += 10 {
+				p := r3.Vector{X: a, Y: b, Z: 100}
+				test.That(t, cloud.Set(spatialmath.Compose(pose, spatialmath.NewPoseFromPoint(p)).Point(), nil), test.ShouldBeNil)
+			}
+		}
+	}
+	return cloud
+}
+
+func TestObstaclesPointCloud(t *testing.T) {
+	ctx := context.Background()
+	scene := makeScene(t)
+	cam := inject.NewCamera("cam")
+	cam.NextPointCloudFunc = func(ctx context.Context, extra map[string]interface{}) (pointcloud.PointCloud, error) {
+		return scene, nil
+	}
+	deps := resource.Dependencies{cam.Name(): cam}
+	name := vision.Named("obstacles")
+	svc, err := newObstaclesPointCloud(ctx, name, &Config{DefaultCamera: "cam", MaxDistFromPlane: 15}, deps, logging.NewTestLogger(t))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, svc.Name(), test.ShouldResemble, name)
+
+	props, err := svc.GetProperties(ctx, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, props.ObjectPCDsSupported, test.ShouldBeTrue)
+	test.That(t, props.DetectionSupported, test.ShouldBeFalse)
+
+	objects, err := svc.GetObjectPointClouds(ctx, "", nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, objects, test.ShouldHaveLength, 2)
+	for _, object := range objects {
+		test.That(t, object.Geometry.Label(), test.ShouldEqual, defaultLabel)
+		// the boxes fit what is left of the obstacles above the floor, which may be a row or so of points off since the
+		// floor is only found approximately
+		dims := object.Geometry.ToProtobuf().GetBox().GetDimsMm()
+		test.That(t, dims.X, test.ShouldAlmostEqual, 400, 1e-6)
+		test.That(t, dims.Y, test.ShouldAlmostEqual, 180, 10)
+		test.That(t, dims.Z, test.ShouldAlmostEqual, 100, 1e-6)
+		center := object.Geometry.Pose().Point()
+		if center.X > 0 {
+			test.That(t, center.Distance(r3.Vector{X: 500, Y: 400, Z: 110}), test.ShouldBeLessThan, 10)
+		} else {
+			test.That(t, center.Distance(r3.Vector{X: -500, Y: -500, Z: 110}), test.ShouldBeLessThan, 10)
+		}
+	}
+
+	objects, err = svc.GetObjectPointClouds(ctx, "cam", map[string]interface{}{})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, objects, test.ShouldHaveLength, 2)
+	_, err = svc.GetObjectPointClouds(ctx, "other", nil)
+	test.That(t, err, test.ShouldNotBeNil)
+}
@@ -12,6 +12,7 @@ import (
 	"go.viam.com/utils/rpc"
 	"google.golang.org/grpc"
 	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	"go.viam.com/rdk/logging"
 	"go.viam.com/rdk/utils"
@@ -83,6 +84,24 @@ func ContextWithMetadataUnaryClientInterceptor(
 	return nil
 }
 
+// AddTimestampsToGRPCHeader adds the times a reading was requested and received to the gRPC response header, if one
+// is found in the context. Replayed resources use it to report when the data they return was captured.
+func AddTimestampsToGRPCHeader(ctx context.Context, timeRequested, timeReceived *timestamppb.Timestamp) error {
+	if stream := grpc.ServerTransportStreamFromContext(ctx); stream != nil {
+		var md grpcmetadata.MD = make(map[string][]string)
+		if timeRequested != nil {
+			md.Set(TimeRequestedMetadataKey, timeRequested.AsTime().Format(time.RFC3339Nano))
+		}
+		if timeReceived != nil {
+			md.Set(TimeReceivedMetadataKey, timeReceived.AsTime().Format(time.RFC3339Nano))
+		}
+		if err := grpc.SetHeader(ctx, md); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ContextWithTimeoutIfNoDeadline returns a child timeout context derived from `ctx` if a
 // deadline does not exist. Returns a cancel context and cancel func from `ctx` if deadline exists.
 func ContextWithTimeoutIfNoDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
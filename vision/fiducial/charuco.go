@@ -0,0 +1,144 @@
+package fiducial
+
+import (
+	"image"
+	"sort"
+
+	"github.com/golang/geo/r2"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/rimage/transform"
+)
+
+// A CharucoBoard is a checkerboard with a tag in each of its white squares, so that its corners can be told apart
+// even when only part of the board is seen. It is laid out the way OpenCV draws ChArUco boards: the top left square
+// is black, and the white squares hold the tags of the family with IDs 0, 1, 2... row by row from the top left. The
+// inner corners of the board, where four squares meet, are numbered the same way.
+type CharucoBoard struct {
+	Family *Family
+	// SquaresX and SquaresY are the number of squares along the top and the left side of the board.
+	SquaresX, SquaresY int
+	// SquareSize is the width of a square, and MarkerSize the width of the black border of a tag, in any unit.
+	SquareSize, MarkerSize float64
+}
+
+// NewCharucoBoard returns a board with the given numbers of squares along its top and left side, and the given widths
+// of its squares and of the tags in them.
+func NewCharucoBoard(family *Family, squaresX, squaresY int, squareSize, markerSize float64) (*CharucoBoard, error) {
+	if squaresX < 2 || squaresY < 2 {
+		return nil, errors.Errorf("a ChArUco board must have at least 2 by 2 squares, not %d by %d", squaresX, squaresY)
+	}
+	if squareSize <= 0 || markerSize <= 0 || markerSize >= squareSize {
+		return nil, errors.Errorf("the tags of a ChArUco board must be smaller than its squares, not %v and %v",
+			markerSize, squareSize)
+	}
+	b := &CharucoBoard{
+		Family:     family,
+		SquaresX:   squaresX,
+		SquaresY:   squaresY,
+		SquareSize: squareSize,
+		MarkerSize: markerSize,
+	}
+	if n := b.numMarkers(); n > len(family.Codes) {
+		return nil, errors.Errorf("the board needs %d tags, but family %q only has %d", n, family.Name, len(family.Codes))
+	}
+	return b, nil
+}
+
+// numMarkers is the number of white squares, each of which holds a tag.
+func (b *CharucoBoard) numMarkers() int {
+	return b.SquaresX * b.SquaresY / 2
+}
+
+// markerSquare returns the column and row of the square that holds the tag with the given ID.
+func (b *CharucoBoard) markerSquare(id int) (int, int) {
+	// The white squares are the ones whose column and row add up to an odd number.
+	index := 2*id + 1
+	row := index / b.SquaresX
+	col := index % b.SquaresX
+	if b.SquaresX%2 == 0 && row%2 == 1 {
+		col--
+	}
+	return col, row
+}
+
+// NumCorners returns the number of inner corners of the board.
+func (b *CharucoBoard) NumCorners() int {
+	return (b.SquaresX - 1) * (b.SquaresY - 1)
+}
+
+// CornerPoints returns the inner corners of the board with the given IDs on the plane z = 0, with the top left
+// corner of the board at the origin.
+func (b *CharucoBoard) CornerPoints(ids []int) []r2.Point {
+	points := make([]r2.Point, len(ids))
+	for i, id := range ids {
+		col, row := id%(b.SquaresX-1), id/(b.SquaresX-1)
+		points[i] = r2.Point{X: float64(col+1) * b.SquareSize, Y: float64(row+1) * b.SquareSize}
+	}
+	return points
+}
+
+// FindCorners returns the IDs of the inner corners of the board next to the tags found in the image, and where each
+// of them is to sub-pixel accuracy, in order of ID. Where a corner is guessed from the tags next to it, it must also
+// look like a corner of a checkerboard to be returned.
+func (b *CharucoBoard) FindCorners(img image.Image) ([]int, []r2.Point) {
+	// The largest detection of each tag wins; Detect returns them largest first.
+	markers := map[int]Detection{}
+	for _, detection := range NewDetector(b.Family).Detect(img) {
+		if _, ok := markers[detection.ID]; !ok && detection.ID < b.numMarkers() {
+			markers[detection.ID] = detection
+		}
+	}
+
+	// Guess each inner corner next to a tag from where the corners of the tag were seen, averaged over the tags next
+	// to it. This follows the perspective of the board near each tag, and so also most of the lens distortion.
+	guesses := map[int][]r2.Point{}
+	half := b.MarkerSize / 2
+	for id, marker := range markers {
+		col, row := b.markerSquare(id)
+		center := r2.Point{X: (float64(col) + 0.5) * b.SquareSize, Y: (float64(row) + 0.5) * b.SquareSize}
+		tagCorners := []r2.Point{
+			center.Add(r2.Point{X: -half, Y: -half}),
+			center.Add(r2.Point{X: half, Y: -half}),
+			center.Add(r2.Point{X: half, Y: half}),
+			center.Add(r2.Point{X: -half, Y: half}),
+		}
+		h, err := transform.EstimateExactHomographyFrom8Points(tagCorners, marker.Corners[:], false)
+		if err != nil {
+			continue
+		}
+		for _, offset := range [][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+			cornerCol, cornerRow := col+offset[0]-1, row+offset[1]-1
+			if cornerCol < 0 || cornerRow < 0 || cornerCol >= b.SquaresX-1 || cornerRow >= b.SquaresY-1 {
+				continue
+			}
+			cornerID := cornerRow*(b.SquaresX-1) + cornerCol
+			point := b.CornerPoints([]int{cornerID})[0]
+			guesses[cornerID] = append(guesses[cornerID], h.Apply(point))
+		}
+	}
+
+	ids := make([]int, 0, len(guesses))
+	for id := range guesses {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	points := make([]r2.Point, len(ids))
+	for i, id := range ids {
+		var sum r2.Point
+		for _, guess := range guesses[id] {
+			sum = sum.Add(guess)
+		}
+		points[i] = sum.Mul(1 / float64(len(guesses[id])))
+	}
+	refined, found := transform.RefineCheckerboardCorners(img, points)
+	var cornerIDs []int
+	var corners []r2.Point
+	for i, id := range ids {
+		if found[i] {
+			cornerIDs = append(cornerIDs, id)
+			corners = append(corners, refined[i])
+		}
+	}
+	return cornerIDs, corners
+}
@@ -0,0 +1,133 @@
+package fiducial
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/golang/geo/r2"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/rimage/transform"
+)
+
+// drawCharucoBoard draws the board with a white margin one square wide onto a gray background, so that the homography
+// maps points on the board to where they are in the image.
+func drawCharucoBoard(t *testing.T, b *CharucoBoard, width, height int, h *transform.Homography) *image.Gray {
+	t.Helper()
+	const cellPixels = 8
+	tags := map[int]*image.Gray{}
+	for id := 0; id < b.numMarkers(); id++ {
+		tag, err := b.Family.Render(id, cellPixels)
+		test.That(t, err, test.ShouldBeNil)
+		tags[id] = tag
+	}
+	tagOf := map[[2]int]int{}
+	for id := range tags {
+		col, row := b.markerSquare(id)
+		tagOf[[2]int{col, row}] = id
+	}
+	// The inverse of the homography, from the image onto the board.
+	inverse, err := transform.EstimateExactHomographyFrom8Points(
+		[]r2.Point{h.Apply(r2.Point{}), h.Apply(r2.Point{X: 1}), h.Apply(r2.Point{X: 1, Y: 1}), h.Apply(r2.Point{Y: 1})},
+		[]r2.Point{{}, {X: 1}, {X: 1, Y: 1}, {Y: 1}}, false)
+	test.That(t, err, test.ShouldBeNil)
+
+	cells := float64(b.Family.GridSize + 2)
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var sum int
+			for _, offset := range []r2.Point{{X: 0.25, Y: 0.25}, {X: 0.75, Y: 0.25}, {X: 0.25, Y: 0.75}, {X: 0.75, Y: 0.75}} {
+				p := inverse.Apply(r2.Point{X: float64(x), Y: float64(y)}.Add(offset).Sub(r2.Point{X: 0.5, Y: 0.5}))
+				col, row := int(math.Floor(p.X/b.SquareSize)), int(math.Floor(p.Y/b.SquareSize))
+				value := 160
+				switch {
+				case col >= 0 && row >= 0 && col < b.SquaresX && row < b.SquaresY:
+					value = 255
+					if (col+row)%2 == 0 {
+						value = 0
+						break
+					}
+					// Where the point is within the black border of the tag, in cells.
+					center := r2.Point{X: (float64(col) + 0.5) * b.SquareSize, Y: (float64(row) + 0.5) * b.SquareSize}
+					inTag := p.Sub(center).Mul(cells / b.MarkerSize).Add(r2.Point{X: cells / 2, Y: cells / 2})
+					if inTag.X >= 0 && inTag.Y >= 0 && inTag.X < cells && inTag.Y < cells {
+						value = int(tags[tagOf[[2]int{col, row}]].GrayAt(int((inTag.X+1)*cellPixels), int((inTag.Y+1)*cellPixels)).Y)
+					}
+				case col >= -1 && row >= -1 && col <= b.SquaresX && row <= b.SquaresY:
+					value = 255
+				}
+				sum += value
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(sum / 4)})
+		}
+	}
+	return img
+}
+
+func TestCharucoBoard(t *testing.T) {
+	family, err := FamilyByName(ArucoOriginal)
+	test.That(t, err, test.ShouldBeNil)
+	board, err := NewCharucoBoard(family, 7, 5, 40, 28)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, board.NumCorners(), test.ShouldEqual, 24)
+	test.That(t, board.CornerPoints([]int{0, 7}), test.ShouldResemble, []r2.Point{{X: 40, Y: 40}, {X: 80, Y: 80}})
+	for _, id := range []int{0, 3, 4, 16} {
+		col, row := board.markerSquare(id)
+		test.That(t, (col+row)%2, test.ShouldEqual, 1)
+		test.That(t, col < board.SquaresX && row < board.SquaresY, test.ShouldBeTrue)
+	}
+	evenBoard, err := NewCharucoBoard(family, 6, 4, 40, 28)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, evenBoard.numMarkers(), test.ShouldEqual, 12)
+	for id := 0; id < evenBoard.numMarkers(); id++ {
+		col, row := evenBoard.markerSquare(id)
+		test.That(t, (col+row)%2, test.ShouldEqual, 1)
+		test.That(t, col < evenBoard.SquaresX && row < evenBoard.SquaresY, test.ShouldBeTrue)
+	}
+
+	// The board, tilted away from the camera.
+	h, err := transform.EstimateExactHomographyFrom8Points(
+		[]r2.Point{{}, {X: 280}, {X: 280, Y: 200}, {Y: 200}},
+		[]r2.Point{{X: 130, Y: 90}, {X: 520, Y: 110}, {X: 500, Y: 400}, {X: 110, Y: 370}}, false)
+	test.That(t, err, test.ShouldBeNil)
+	img := drawCharucoBoard(t, board, 640, 480, h)
+
+	ids, corners := board.FindCorners(img)
+	test.That(t, ids, test.ShouldHaveLength, board.NumCorners())
+	for i, object := range board.CornerPoints(ids) {
+		expected := h.Apply(object)
+		if d := corners[i].Sub(expected).Norm(); d > 0.3 {
+			t.Fatalf("corner %d is at %v, %.2f pixels from %v", ids[i], corners[i], d, expected)
+		}
+	}
+
+	// Only the corners next to the tags that can still be seen are found.
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 320; x++ {
+			img.SetGray(x, y, color.Gray{Y: 160})
+		}
+	}
+	ids, corners = board.FindCorners(img)
+	test.That(t, len(ids), test.ShouldBeGreaterThan, 4)
+	test.That(t, len(ids), test.ShouldBeLessThan, board.NumCorners())
+	for i, object := range board.CornerPoints(ids) {
+		test.That(t, corners[i].X, test.ShouldBeGreaterThan, 320)
+		test.That(t, corners[i].Sub(h.Apply(object)).Norm(), test.ShouldBeLessThan, 0.3)
+	}
+
+	ids, corners = board.FindCorners(image.NewGray(image.Rect(0, 0, 640, 480)))
+	test.That(t, ids, test.ShouldBeEmpty)
+	test.That(t, corners, test.ShouldBeEmpty)
+
+	_, err = NewCharucoBoard(family, 1, 5, 40, 28)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewCharucoBoard(family, 7, 5, 40, 40)
+	test.That(t, err, test.ShouldNotBeNil)
+	tag16h5, err := FamilyByName(Tag16h5)
+	test.That(t, err, test.ShouldBeNil)
+	_, err = NewCharucoBoard(tag16h5, 10, 10, 40, 28)
+	test.That(t, err, test.ShouldNotBeNil)
+}
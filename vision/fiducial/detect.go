@@ -0,0 +1,405 @@
+// Package fiducial detects square fiducial markers, such as AprilTags and ArUco markers, in images and estimates
+// their poses.
+package fiducial
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+
+	"github.com/golang/geo/r2"
+
+	"go.viam.com/rdk/rimage/transform"
+)
+
+const (
+	// minTagPixels is the smallest width in pixels a tag, including its border, can be detected at.
+	minTagPixels = 10
+	// thresholdOffset is how much darker than its neighborhood a pixel must be to be part of a tag border.
+	thresholdOffset = 7
+	// minContrast is the smallest difference in brightness between the black border of a tag and the white around it.
+	minContrast = 20
+	// minQuadFill is the smallest fraction of the area of its convex hull a dark region's quadrilateral must cover.
+	minQuadFill = 0.85
+)
+
+// A Detection is a tag found in an image.
+type Detection struct {
+	Family string
+	ID     int
+	// Corners are the outer corners of the black border of the tag in the image, starting at the top left corner of
+	// the tag as it is drawn by Render and going clockwise.
+	Corners [4]r2.Point
+	Center  r2.Point
+	// Hamming is the number of cells that had to be corrected to decode the tag.
+	Hamming int
+}
+
+// Label returns the name of the tag, its family and ID.
+func (d *Detection) Label() string {
+	return fmt.Sprintf("%s_%d", d.Family, d.ID)
+}
+
+// Area returns the area in pixels of the tag in the image.
+func (d *Detection) Area() float64 {
+	return math.Abs(polygonArea(d.Corners[:]))
+}
+
+// A Detector finds the tags of some families in images.
+type Detector struct {
+	families []*Family
+}
+
+// NewDetector returns a detector of the tags of the given families.
+func NewDetector(families ...*Family) *Detector {
+	return &Detector{families: families}
+}
+
+// Families returns the families the detector finds tags of.
+func (d *Detector) Families() []*Family {
+	return d.families
+}
+
+// Detect returns the tags in the image, largest first.
+func (d *Detector) Detect(img image.Image) []Detection {
+	gray := newGrayImage(img)
+	if gray.width < minTagPixels || gray.height < minTagPixels {
+		return nil
+	}
+	var detections []Detection
+	for _, boundary := range gray.darkRegions() {
+		quad, ok := fitQuad(boundary)
+		if !ok {
+			continue
+		}
+		quad = gray.refineQuad(quad)
+		if detection, ok := d.decodeQuad(gray, quad); ok {
+			detections = append(detections, detection)
+		}
+	}
+	return suppressOverlaps(detections)
+}
+
+// decodeQuad reads the tag of any family inside the quadrilateral.
+func (d *Detector) decodeQuad(gray *grayImage, quad [4]r2.Point) (Detection, bool) {
+	tagCorners := []r2.Point{{X: -1, Y: -1}, {X: 1, Y: -1}, {X: 1, Y: 1}, {X: -1, Y: 1}}
+	h, err := transform.EstimateExactHomographyFrom8Points(tagCorners, quad[:], false)
+	if err != nil {
+		return Detection{}, false
+	}
+	for _, family := range d.families {
+		observed, ok := readCode(gray, h, family.GridSize)
+		if !ok {
+			continue
+		}
+		tag, distance, ok := family.decode(observed)
+		if !ok {
+			continue
+		}
+		var corners [4]r2.Point
+		for i := range corners {
+			corners[i] = quad[(i+tag.rotations)%4]
+		}
+		return Detection{
+			Family:  family.Name,
+			ID:      tag.id,
+			Corners: corners,
+			Center:  h.Apply(r2.Point{}),
+			Hamming: distance,
+		}, true
+	}
+	return Detection{}, false
+}
+
+// readCode reads the data cells of a tag with the given number of data cells to a side, mapped into the image by
+// the homography from the square [-1, 1]², if the tag has a black border on a white background.
+func readCode(gray *grayImage, h *transform.Homography, gridSize int) (uint64, bool) {
+	cells := gridSize + 2
+	cellWidth := 2 / float64(cells)
+	sampleCell := func(row, col int) float64 {
+		center := r2.Point{X: -1 + (float64(col)+0.5)*cellWidth, Y: -1 + (float64(row)+0.5)*cellWidth}
+		var sum float64
+		for _, dy := range []float64{-0.25, 0, 0.25} {
+			for _, dx := range []float64{-0.25, 0, 0.25} {
+				sum += gray.at(h.Apply(center.Add(r2.Point{X: dx, Y: dy}.Mul(cellWidth))))
+			}
+		}
+		return sum / 9
+	}
+
+	// The border is black, and it should be surrounded by white.
+	var border, outside []float64
+	for i := -1; i <= cells; i++ {
+		outside = append(outside, sampleCell(-1, i), sampleCell(cells, i))
+		if i > 0 && i < cells-1 {
+			outside = append(outside, sampleCell(i, -1), sampleCell(i, cells))
+		}
+	}
+	for i := 0; i < cells; i++ {
+		border = append(border, sampleCell(0, i), sampleCell(cells-1, i))
+		if i > 0 && i < cells-1 {
+			border = append(border, sampleCell(i, 0), sampleCell(i, cells-1))
+		}
+	}
+	black, white := mean(border), mean(outside)
+	if white-black < minContrast {
+		return 0, false
+	}
+	threshold := (black + white) / 2
+	borderErrors := 0
+	for _, v := range border {
+		if v > threshold {
+			borderErrors++
+		}
+	}
+	if borderErrors > len(border)/8 {
+		return 0, false
+	}
+
+	var code uint64
+	for row := 1; row <= gridSize; row++ {
+		for col := 1; col <= gridSize; col++ {
+			code <<= 1
+			if sampleCell(row, col) > threshold {
+				code |= 1
+			}
+		}
+	}
+	return code, true
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// suppressOverlaps sorts the detections largest first and drops those centered inside a larger one, which are
+// usually the data cells of a tag read as a tag of their own.
+func suppressOverlaps(detections []Detection) []Detection {
+	sort.SliceStable(detections, func(i, j int) bool {
+		return detections[i].Area() > detections[j].Area()
+	})
+	kept := detections[:0]
+	for _, detection := range detections {
+		inside := false
+		for _, larger := range kept {
+			if containsPoint(larger.Corners, detection.Center) {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			kept = append(kept, detection)
+		}
+	}
+	return kept
+}
+
+// grayImage is the brightness of each pixel of an image.
+type grayImage struct {
+	width, height int
+	pix           []uint8
+}
+
+func newGrayImage(img image.Image) *grayImage {
+	bounds := img.Bounds()
+	gray := &grayImage{width: bounds.Dx(), height: bounds.Dy(), pix: make([]uint8, bounds.Dx()*bounds.Dy())}
+	switch img := img.(type) {
+	case *image.Gray:
+		for y := 0; y < gray.height; y++ {
+			start := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(gray.pix[y*gray.width:(y+1)*gray.width], img.Pix[start:start+gray.width])
+		}
+	case *image.YCbCr:
+		for y := 0; y < gray.height; y++ {
+			start := img.YOffset(bounds.Min.X, bounds.Min.Y+y)
+			copy(gray.pix[y*gray.width:(y+1)*gray.width], img.Y[start:start+gray.width])
+		}
+	default:
+		for y := 0; y < gray.height; y++ {
+			for x := 0; x < gray.width; x++ {
+				gray.pix[y*gray.width+x] = color.GrayModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray).Y
+			}
+		}
+	}
+	return gray
+}
+
+// at returns the brightness at a point, interpolated between the centers of the nearest pixels.
+func (g *grayImage) at(p r2.Point) float64 {
+	x, y := p.X-0.5, p.Y-0.5
+	x0, y0 := math.Floor(x), math.Floor(y)
+	fx, fy := x-x0, y-y0
+	pixel := func(x, y int) float64 {
+		x = min(max(x, 0), g.width-1)
+		y = min(max(y, 0), g.height-1)
+		return float64(g.pix[y*g.width+x])
+	}
+	ix, iy := int(x0), int(y0)
+	top := pixel(ix, iy)*(1-fx) + pixel(ix+1, iy)*fx
+	bottom := pixel(ix, iy+1)*(1-fx) + pixel(ix+1, iy+1)*fx
+	return top*(1-fy) + bottom*fy
+}
+
+// darkRegions returns the boundary pixels of each region of pixels darker than their neighborhood that is large
+// enough to be a tag and does not touch the edge of the image.
+func (g *grayImage) darkRegions() [][]r2.Point {
+	w, h := g.width, g.height
+	// Compare each pixel against the mean of a window around it, using a summed area table.
+	integral := make([]int64, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		var row int64
+		for x := 0; x < w; x++ {
+			row += int64(g.pix[y*w+x])
+			integral[(y+1)*(w+1)+x+1] = integral[y*(w+1)+x+1] + row
+		}
+	}
+	radius := max(3, min(w, h)/32)
+	dark := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		y0, y1 := max(y-radius, 0), min(y+radius+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(x-radius, 0), min(x+radius+1, w)
+			sum := integral[y1*(w+1)+x1] - integral[y0*(w+1)+x1] - integral[y1*(w+1)+x0] + integral[y0*(w+1)+x0]
+			count := int64((y1 - y0) * (x1 - x0))
+			dark[y*w+x] = int64(g.pix[y*w+x])*count < sum-thresholdOffset*count
+		}
+	}
+
+	var regions [][]r2.Point
+	visited := make([]bool, w*h)
+	var stack []int
+	for start := range dark {
+		if !dark[start] || visited[start] {
+			continue
+		}
+		visited[start] = true
+		stack = append(stack[:0], start)
+		var boundary []r2.Point
+		minX, minY, maxX, maxY := w, h, -1, -1
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			minX, minY, maxX, maxY = min(minX, x), min(minY, y), max(maxX, x), max(maxY, y)
+			onBoundary := false
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := x+dx, y+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					j := ny*w + nx
+					if !dark[j] {
+						onBoundary = true
+						continue
+					}
+					if !visited[j] {
+						visited[j] = true
+						stack = append(stack, j)
+					}
+				}
+			}
+			if onBoundary {
+				boundary = append(boundary, r2.Point{X: float64(x) + 0.5, Y: float64(y) + 0.5})
+			}
+		}
+		if minX == 0 || minY == 0 || maxX == w-1 || maxY == h-1 {
+			continue
+		}
+		if maxX-minX+1 < minTagPixels || maxY-minY+1 < minTagPixels {
+			continue
+		}
+		regions = append(regions, boundary)
+	}
+	return regions
+}
+
+// refineQuad moves each side of the quadrilateral onto the strongest dark to light edge near it, and returns the
+// intersections of the moved sides.
+func (g *grayImage) refineQuad(quad [4]r2.Point) [4]r2.Point {
+	type line struct{ point, direction r2.Point }
+	var lines [4]line
+	for i := range quad {
+		a, b := quad[i], quad[(i+1)%4]
+		side := b.Sub(a)
+		length := side.Norm()
+		lines[i] = line{a, side.Normalize()}
+		// The quadrilateral is clockwise, so its outside is to the left of each side.
+		normal := r2.Point{X: side.Y, Y: -side.X}.Normalize()
+		samples := max(int(length/2), 4)
+		var points []r2.Point
+		for s := 0; s < samples; s++ {
+			// Stay away from the corners, where the other sides blur the edge.
+			p := a.Add(side.Mul(0.15 + 0.7*(float64(s)+0.5)/float64(samples)))
+			if offset, ok := g.edgeOffset(p, normal); ok {
+				points = append(points, p.Add(normal.Mul(offset)))
+			}
+		}
+		if len(points) >= 3 {
+			lines[i].point, lines[i].direction = fitLine(points)
+		}
+	}
+	refined := quad
+	for i := range refined {
+		prev, next := lines[(i+3)%4], lines[i]
+		denominator := prev.direction.Cross(next.direction)
+		if math.Abs(denominator) < 1e-9 {
+			return quad
+		}
+		t := next.point.Sub(prev.point).Cross(next.direction) / denominator
+		refined[i] = prev.point.Add(prev.direction.Mul(t))
+		if refined[i].Sub(quad[i]).Norm() > 4 {
+			return quad
+		}
+	}
+	return refined
+}
+
+// edgeOffset returns how far along the normal from the point the brightness increases the fastest.
+func (g *grayImage) edgeOffset(p, normal r2.Point) (float64, bool) {
+	const reach, step = 3.0, 0.5
+	var gradients []float64
+	for t := -reach; t <= reach; t += step {
+		gradients = append(gradients, g.at(p.Add(normal.Mul(t+step/2)))-g.at(p.Add(normal.Mul(t-step/2))))
+	}
+	best := 0
+	for i, gradient := range gradients {
+		if gradient > gradients[best] {
+			best = i
+		}
+	}
+	if gradients[best] <= 0 || best == 0 || best == len(gradients)-1 {
+		return 0, false
+	}
+	// Fit a parabola through the strongest gradient and its neighbors.
+	left, center, right := gradients[best-1], gradients[best], gradients[best+1]
+	offset := -reach + float64(best)*step
+	if curvature := left - 2*center + right; curvature < 0 {
+		offset += step * 0.5 * (left - right) / curvature
+	}
+	return offset, true
+}
+
+// fitLine returns the centroid and direction of the line that best fits the points.
+func fitLine(points []r2.Point) (r2.Point, r2.Point) {
+	var centroid r2.Point
+	for _, p := range points {
+		centroid = centroid.Add(p)
+	}
+	centroid = centroid.Mul(1 / float64(len(points)))
+	var sxx, sxy, syy float64
+	for _, p := range points {
+		d := p.Sub(centroid)
+		sxx += d.X * d.X
+		sxy += d.X * d.Y
+		syy += d.Y * d.Y
+	}
+	angle := 0.5 * math.Atan2(2*sxy, sxx-syy)
+	return centroid, r2.Point{X: math.Cos(angle), Y: math.Sin(angle)}
+}
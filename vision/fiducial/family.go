@@ -0,0 +1,216 @@
+package fiducial
+
+import (
+	"image"
+	"image/color"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// A Family is a set of square fiducial markers, or tags. Each tag is a grid of GridSize by GridSize data cells inside
+// a black border one cell wide, which in turn should be printed on a white background. The data cells of a tag are
+// the bits of its code, row by row from the top left cell as the most significant bit, with white cells set.
+type Family struct {
+	Name     string
+	GridSize int
+	Codes    []uint64
+	// MaxCorrectedBits is the number of misread cells a detection may have and still be decoded.
+	MaxCorrectedBits int
+
+	// lookup maps every rotation of every code to its tag.
+	lookup map[uint64]rotatedTag
+}
+
+// rotatedTag is a tag as seen rotated clockwise by quarter turns.
+type rotatedTag struct {
+	id        int
+	rotations int
+}
+
+// tag16h5Codes are the codes of the AprilTag 16h5 family, whose tags are at least 5 bits apart under any rotation.
+var tag16h5Codes = []uint64{
+	0x231b, 0x2ea5, 0x346a, 0x45b9, 0x79a6, 0x7f6b, 0xb358, 0xe745, 0xfe59, 0x156d,
+	0x380b, 0xf0ab, 0x0d84, 0x4736, 0x8c72, 0xaf10, 0x093c, 0x93b4, 0xa503, 0x468f,
+	0xe137, 0x5795, 0xdf42, 0x1c1d, 0xe9dc, 0x73ad, 0xad5f, 0xd530, 0x07ca, 0xaf2e,
+}
+
+// arucoOriginalCodes returns the codes of the original ArUco dictionary, whose tags encode their 10 bit ID two bits
+// to a row, each pair of bits choosing one of four row words.
+func arucoOriginalCodes() []uint64 {
+	words := [4]uint64{0x10, 0x17, 0x09, 0x0e}
+	codes := make([]uint64, 1024)
+	for id := range codes {
+		var code uint64
+		for row := 0; row < 5; row++ {
+			code = code<<5 | words[(id>>(2*(4-row)))&3]
+		}
+		codes[id] = code
+	}
+	return codes
+}
+
+const (
+	// Tag16h5 is the name of the AprilTag 16h5 family.
+	Tag16h5 = "tag16h5"
+	// ArucoOriginal is the name of the original ArUco dictionary.
+	ArucoOriginal = "aruco_original"
+)
+
+// BuiltinFamilies returns the names of the families that FamilyByName knows.
+func BuiltinFamilies() []string {
+	return []string{ArucoOriginal, Tag16h5}
+}
+
+// FamilyByName returns the built in family with the given name.
+func FamilyByName(name string) (*Family, error) {
+	switch name {
+	case Tag16h5:
+		return NewFamily(Tag16h5, 4, tag16h5Codes, 1)
+	case ArucoOriginal:
+		// The row words of the original ArUco dictionary are close together, so no errors are corrected.
+		return NewFamily(ArucoOriginal, 5, arucoOriginalCodes(), 0)
+	default:
+		return nil, errors.Errorf("unknown fiducial family %q, expected one of %v", name, BuiltinFamilies())
+	}
+}
+
+// NewFamily returns a family of tags with the given codes. Codes that look the same as another code, or as
+// themselves, under some rotation are decoded as the lowest such ID, at an arbitrary rotation.
+func NewFamily(name string, gridSize int, codes []uint64, maxCorrectedBits int) (*Family, error) {
+	if gridSize < 2 || gridSize > 8 {
+		return nil, errors.Errorf("the grid of family %q must be between 2 and 8 cells wide, not %d", name, gridSize)
+	}
+	if len(codes) == 0 {
+		return nil, errors.Errorf("family %q has no codes", name)
+	}
+	if maxCorrectedBits < 0 {
+		return nil, errors.Errorf("family %q cannot correct a negative number of bits", name)
+	}
+	nBits := gridSize * gridSize
+	lookup := make(map[uint64]rotatedTag, 4*len(codes))
+	for id, code := range codes {
+		if nBits < 64 && code>>nBits != 0 {
+			return nil, errors.Errorf("code %d of family %q, %#x, has more than %d bits", id, name, code, nBits)
+		}
+		rotated := code
+		for rotations := 0; rotations < 4; rotations++ {
+			if _, ok := lookup[rotated]; !ok {
+				lookup[rotated] = rotatedTag{id: id, rotations: rotations}
+			}
+			rotated = rotateCode(rotated, gridSize)
+		}
+	}
+	return &Family{Name: name, GridSize: gridSize, Codes: codes, MaxCorrectedBits: maxCorrectedBits, lookup: lookup}, nil
+}
+
+// rotateCode returns the code of the grid turned a quarter turn clockwise.
+func rotateCode(code uint64, gridSize int) uint64 {
+	nBits := gridSize * gridSize
+	var rotated uint64
+	for row := 0; row < gridSize; row++ {
+		for col := 0; col < gridSize; col++ {
+			// The cell at (row, col) of the turned grid was at (gridSize-1-col, row).
+			bit := (code >> (nBits - 1 - ((gridSize-1-col)*gridSize + row))) & 1
+			rotated |= bit << (nBits - 1 - (row*gridSize + col))
+		}
+	}
+	return rotated
+}
+
+// decode returns the tag whose code, turned clockwise by some quarter turns, is closest to the observed code.
+func (f *Family) decode(observed uint64) (rotatedTag, int, bool) {
+	if tag, ok := f.lookup[observed]; ok {
+		return tag, 0, true
+	}
+	if f.MaxCorrectedBits == 0 {
+		return rotatedTag{}, 0, false
+	}
+	best, bestDistance := rotatedTag{}, f.MaxCorrectedBits+1
+	for code, tag := range f.lookup {
+		distance := bits.OnesCount64(code ^ observed)
+		if distance < bestDistance || (distance == bestDistance && tag.id < best.id) {
+			best, bestDistance = tag, distance
+		}
+	}
+	return best, bestDistance, bestDistance <= f.MaxCorrectedBits
+}
+
+// Render draws the tag with the given ID, with a white margin one cell wide, at cellPixels pixels per cell.
+func (f *Family) Render(id, cellPixels int) (*image.Gray, error) {
+	if id < 0 || id >= len(f.Codes) {
+		return nil, errors.Errorf("family %q has no tag %d", f.Name, id)
+	}
+	if cellPixels < 1 {
+		return nil, errors.New("cells must be at least one pixel wide")
+	}
+	cells := f.GridSize + 4
+	img := image.NewGray(image.Rect(0, 0, cells*cellPixels, cells*cellPixels))
+	nBits := f.GridSize * f.GridSize
+	for row := 0; row < cells; row++ {
+		for col := 0; col < cells; col++ {
+			white := row == 0 || col == 0 || row == cells-1 || col == cells-1
+			if row >= 2 && col >= 2 && row < cells-2 && col < cells-2 {
+				bit := (row-2)*f.GridSize + col - 2
+				white = (f.Codes[id]>>(nBits-1-bit))&1 == 1
+			}
+			if !white {
+				continue
+			}
+			for y := row * cellPixels; y < (row+1)*cellPixels; y++ {
+				for x := col * cellPixels; x < (col+1)*cellPixels; x++ {
+					img.SetGray(x, y, color.Gray{Y: 255})
+				}
+			}
+		}
+	}
+	return img, nil
+}
+
+// FamilyConfig describes a family of tags that is not built in, such as one of the larger AprilTag families.
+type FamilyConfig struct {
+	Name             string   `json:"name"`
+	GridSize         int      `json:"grid_size"`
+	Codes            []uint64 `json:"codes"`
+	MaxCorrectedBits int      `json:"max_corrected_bits,omitempty"`
+}
+
+// FamiliesFromConfig returns the named built in families followed by the custom ones, or every built in family if
+// there are neither.
+func FamiliesFromConfig(names []string, custom []FamilyConfig) ([]*Family, error) {
+	if len(names) == 0 && len(custom) == 0 {
+		names = BuiltinFamilies()
+	}
+	families := make([]*Family, 0, len(names)+len(custom))
+	seen := map[string]bool{}
+	add := func(family *Family) error {
+		if seen[family.Name] {
+			return errors.Errorf("fiducial family %q is configured more than once", family.Name)
+		}
+		seen[family.Name] = true
+		families = append(families, family)
+		return nil
+	}
+	for _, name := range names {
+		family, err := FamilyByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(family); err != nil {
+			return nil, err
+		}
+	}
+	for _, c := range custom {
+		if c.Name == "" {
+			return nil, errors.New("custom fiducial families must have a name")
+		}
+		family, err := NewFamily(c.Name, c.GridSize, c.Codes, c.MaxCorrectedBits)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(family); err != nil {
+			return nil, err
+		}
+	}
+	return families, nil
+}
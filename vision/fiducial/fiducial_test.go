@@ -0,0 +1,241 @@
+package fiducial
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"math/bits"
+	"testing"
+
+	"github.com/golang/geo/r2"
+	"github.com/golang/geo/r3"
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/rimage/transform"
+	"go.viam.com/rdk/spatialmath"
+)
+
+// drawTag draws the tag onto a gray background so that the outer corners of its black border, clockwise from its top
+// left corner, land on the given points.
+func drawTag(t *testing.T, family *Family, id, width, height int, corners [4]r2.Point) *image.Gray {
+	t.Helper()
+	const cellPixels = 8
+	tag, err := family.Render(id, cellPixels)
+	test.That(t, err, test.ShouldBeNil)
+	lo, hi := float64(cellPixels), float64(tag.Bounds().Dx()-cellPixels)
+	toTag, err := transform.EstimateExactHomographyFrom8Points(
+		corners[:], []r2.Point{{X: lo, Y: lo}, {X: hi, Y: lo}, {X: hi, Y: hi}, {X: lo, Y: hi}}, false)
+	test.That(t, err, test.ShouldBeNil)
+
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Average a few samples per pixel to blur the edges like a real camera.
+			var sum int
+			for _, offset := range []r2.Point{{X: 0.25, Y: 0.25}, {X: 0.75, Y: 0.25}, {X: 0.25, Y: 0.75}, {X: 0.75, Y: 0.75}} {
+				p := toTag.Apply(r2.Point{X: float64(x), Y: float64(y)}.Add(offset))
+				value := 160
+				if p.X >= 0 && p.Y >= 0 && p.X < float64(tag.Bounds().Dx()) && p.Y < float64(tag.Bounds().Dy()) {
+					value = int(tag.GrayAt(int(p.X), int(p.Y)).Y)
+				}
+				sum += value
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(sum / 4)})
+		}
+	}
+	return img
+}
+
+func TestFamilies(t *testing.T) {
+	for _, name := range BuiltinFamilies() {
+		family, err := FamilyByName(name)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, family.Name, test.ShouldEqual, name)
+		for id, code := range family.Codes {
+			rotated := code
+			for rotations := 0; rotations < 4; rotations++ {
+				tag, distance, ok := family.decode(rotated)
+				test.That(t, ok, test.ShouldBeTrue)
+				test.That(t, distance, test.ShouldEqual, 0)
+				test.That(t, tag.id, test.ShouldEqual, id)
+				rotated = rotateCode(rotated, family.GridSize)
+			}
+			test.That(t, rotated, test.ShouldEqual, code)
+		}
+	}
+	test.That(t, len(arucoOriginalCodes()), test.ShouldEqual, 1024)
+
+	tag16h5, err := FamilyByName(Tag16h5)
+	test.That(t, err, test.ShouldBeNil)
+	tag, distance, ok := tag16h5.decode(rotateCode(tag16h5.Codes[7], 4) ^ 0x0100)
+	test.That(t, ok, test.ShouldBeTrue)
+	test.That(t, tag, test.ShouldResemble, rotatedTag{id: 7, rotations: 1})
+	test.That(t, distance, test.ShouldEqual, 1)
+	_, _, ok = tag16h5.decode(tag16h5.Codes[7] ^ 0x0003)
+	test.That(t, ok, test.ShouldBeFalse)
+
+	_, err = FamilyByName("tag36h11")
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewFamily("big", 9, []uint64{1}, 0)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewFamily("empty", 4, nil, 0)
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewFamily("wide", 2, []uint64{0x1f}, 0)
+	test.That(t, err, test.ShouldNotBeNil)
+
+	families, err := FamiliesFromConfig(nil, nil)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, len(families), test.ShouldEqual, len(BuiltinFamilies()))
+	families, err = FamiliesFromConfig([]string{Tag16h5}, []FamilyConfig{{Name: "custom", GridSize: 3, Codes: []uint64{0x1a5}}})
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, families[0].Name, test.ShouldEqual, Tag16h5)
+	test.That(t, families[1].Name, test.ShouldEqual, "custom")
+	_, err = FamiliesFromConfig([]string{Tag16h5}, []FamilyConfig{{Name: Tag16h5, GridSize: 3, Codes: []uint64{0x1a5}}})
+	test.That(t, err, test.ShouldNotBeNil)
+	test.That(t, err.Error(), test.ShouldContainSubstring, "more than once")
+	_, err = FamiliesFromConfig(nil, []FamilyConfig{{GridSize: 3, Codes: []uint64{0x1a5}}})
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestTag16h5Distance(t *testing.T) {
+	family, err := FamilyByName(Tag16h5)
+	test.That(t, err, test.ShouldBeNil)
+	for i, a := range family.Codes {
+		rotated := a
+		for rotations := 0; rotations < 4; rotations++ {
+			for j, b := range family.Codes {
+				if i == j && rotations == 0 {
+					continue
+				}
+				test.That(t, bits.OnesCount64(rotated^b), test.ShouldBeGreaterThanOrEqualTo, 5)
+			}
+			rotated = rotateCode(rotated, family.GridSize)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tag16h5, err := FamilyByName(Tag16h5)
+	test.That(t, err, test.ShouldBeNil)
+	aruco, err := FamilyByName(ArucoOriginal)
+	test.That(t, err, test.ShouldBeNil)
+	detector := NewDetector(aruco, tag16h5)
+
+	t.Run("upright", func(t *testing.T) {
+		corners := [4]r2.Point{{X: 50, Y: 40}, {X: 170, Y: 40}, {X: 170, Y: 160}, {X: 50, Y: 160}}
+		detections := detector.Detect(drawTag(t, tag16h5, 12, 240, 200, corners))
+		test.That(t, len(detections), test.ShouldEqual, 1)
+		test.That(t, detections[0].Family, test.ShouldEqual, Tag16h5)
+		test.That(t, detections[0].ID, test.ShouldEqual, 12)
+		test.That(t, detections[0].Hamming, test.ShouldEqual, 0)
+		for i, corner := range detections[0].Corners {
+			test.That(t, corner.Sub(corners[i]).Norm(), test.ShouldBeLessThan, 0.5)
+		}
+		test.That(t, detections[0].Center.Sub(r2.Point{X: 110, Y: 100}).Norm(), test.ShouldBeLessThan, 0.5)
+	})
+
+	t.Run("rotated and in perspective", func(t *testing.T) {
+		corners := [4]r2.Point{{X: 60, Y: 30}, {X: 190, Y: 55}, {X: 175, Y: 170}, {X: 45, Y: 150}}
+		for rotations := 0; rotations < 4; rotations++ {
+			var rotated [4]r2.Point
+			for i := range rotated {
+				rotated[i] = corners[(i+rotations)%4]
+			}
+			detections := detector.Detect(drawTag(t, aruco, 637, 240, 200, rotated))
+			test.That(t, len(detections), test.ShouldEqual, 1)
+			test.That(t, detections[0].Family, test.ShouldEqual, ArucoOriginal)
+			test.That(t, detections[0].ID, test.ShouldEqual, 637)
+			for i, corner := range detections[0].Corners {
+				test.That(t, corner.Sub(rotated[i]).Norm(), test.ShouldBeLessThan, 1)
+			}
+		}
+	})
+
+	t.Run("several tags", func(t *testing.T) {
+		img := drawTag(t, tag16h5, 3, 320, 160, [4]r2.Point{{X: 20, Y: 30}, {X: 120, Y: 30}, {X: 120, Y: 130}, {X: 20, Y: 130}})
+		other := drawTag(t, tag16h5, 21, 320, 160, [4]r2.Point{{X: 190, Y: 40}, {X: 270, Y: 40}, {X: 270, Y: 120}, {X: 190, Y: 120}})
+		for x := 160; x < 320; x++ {
+			for y := 0; y < 160; y++ {
+				img.SetGray(x, y, other.GrayAt(x, y))
+			}
+		}
+		detections := detector.Detect(img)
+		test.That(t, len(detections), test.ShouldEqual, 2)
+		test.That(t, detections[0].ID, test.ShouldEqual, 3)
+		test.That(t, detections[1].ID, test.ShouldEqual, 21)
+	})
+
+	t.Run("nothing", func(t *testing.T) {
+		img := image.NewGray(image.Rect(0, 0, 100, 100))
+		for y := 20; y < 80; y++ {
+			for x := 20; x < 80; x++ {
+				// A black square is not a tag.
+				img.SetGray(x, y, color.Gray{Y: 255 * uint8((x/70)%2)})
+			}
+		}
+		test.That(t, detector.Detect(img), test.ShouldBeEmpty)
+		test.That(t, detector.Detect(image.NewGray(image.Rect(0, 0, 5, 5))), test.ShouldBeEmpty)
+	})
+}
+
+func TestEstimatePose(t *testing.T) {
+	intrinsics := &transform.PinholeCameraIntrinsics{Width: 320, Height: 240, Fx: 300, Fy: 300, Ppx: 160, Ppy: 120}
+	const tagSize = 100.
+	project := func(pose spatialmath.Pose, distortion transform.Distorter) [4]r2.Point {
+		half := tagSize / 2
+		var corners [4]r2.Point
+		for i, p := range []r3.Vector{{X: -half, Y: -half}, {X: half, Y: -half}, {X: half, Y: half}, {X: -half, Y: half}} {
+			camera := spatialmath.Compose(pose, spatialmath.NewPoseFromPoint(p)).Point()
+			x, y := camera.X/camera.Z, camera.Y/camera.Z
+			if distortion != nil {
+				x, y = distortion.Transform(x, y)
+			}
+			corners[i] = r2.Point{X: x*intrinsics.Fx + intrinsics.Ppx, Y: y*intrinsics.Fy + intrinsics.Ppy}
+		}
+		return corners
+	}
+	expected := spatialmath.NewPose(
+		r3.Vector{X: 30, Y: -20, Z: 500},
+		&spatialmath.R4AA{Theta: 0.5, RX: 0.3, RY: 1, RZ: 0.2},
+	)
+
+	t.Run("exact corners", func(t *testing.T) {
+		pose, err := EstimatePose(project(expected, nil), tagSize, intrinsics, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, spatialmath.PoseAlmostEqualEps(pose, expected, 1e-3), test.ShouldBeTrue)
+
+		facing := spatialmath.NewPoseFromPoint(r3.Vector{Z: 300})
+		pose, err = EstimatePose(project(facing, nil), tagSize, intrinsics, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, spatialmath.PoseAlmostEqualEps(pose, facing, 1e-3), test.ShouldBeTrue)
+	})
+
+	t.Run("distortion", func(t *testing.T) {
+		distortion := &transform.BrownConrady{RadialK1: -0.2, RadialK2: 0.05, TangentialP1: 0.001}
+		pose, err := EstimatePose(project(expected, distortion), tagSize, intrinsics, distortion)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, spatialmath.PoseAlmostEqualEps(pose, expected, 1e-3), test.ShouldBeTrue)
+	})
+
+	t.Run("detected corners", func(t *testing.T) {
+		family, err := FamilyByName(Tag16h5)
+		test.That(t, err, test.ShouldBeNil)
+		img := drawTag(t, family, 4, intrinsics.Width, intrinsics.Height, project(expected, nil))
+		detections := NewDetector(family).Detect(img)
+		test.That(t, len(detections), test.ShouldEqual, 1)
+		pose, err := EstimatePose(detections[0].Corners, tagSize, intrinsics, nil)
+		test.That(t, err, test.ShouldBeNil)
+		test.That(t, pose.Point().Sub(expected.Point()).Norm(), test.ShouldBeLessThan, 5)
+		angle := spatialmath.OrientationBetween(pose.Orientation(), expected.Orientation()).AxisAngles().Theta
+		test.That(t, angle, test.ShouldBeLessThan, 2*math.Pi/180)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := EstimatePose(project(expected, nil), 0, intrinsics, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = EstimatePose(project(expected, nil), tagSize, nil, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+		_, err = EstimatePose([4]r2.Point{}, tagSize, intrinsics, nil)
+		test.That(t, err, test.ShouldNotBeNil)
+	})
+}
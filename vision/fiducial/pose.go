@@ -0,0 +1,212 @@
+package fiducial
+
+import (
+	"math"
+
+	"github.com/golang/geo/r2"
+	"github.com/golang/geo/r3"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/mat"
+
+	"go.viam.com/rdk/rimage/transform"
+	"go.viam.com/rdk/spatialmath"
+)
+
+// EstimatePose returns the pose of a tag in the frame of the camera that saw it, given the corners of its detection,
+// the width of its black border in mm and the intrinsics of the camera. The distortion may be nil.
+//
+// The camera frame has +Z forward out of the lens, +X to the right and +Y down the image. The tag frame is centered on
+// the tag with +X to the right of the tag as it is drawn, +Y down it and +Z into it, so a tag squarely facing the
+// camera has no rotation.
+func EstimatePose(
+	corners [4]r2.Point,
+	tagSize float64,
+	intrinsics *transform.PinholeCameraIntrinsics,
+	distortion transform.Distorter,
+) (spatialmath.Pose, error) {
+	if tagSize <= 0 {
+		return nil, errors.New("tag size must be positive")
+	}
+	if intrinsics == nil || intrinsics.Fx == 0 || intrinsics.Fy == 0 {
+		return nil, errors.New("camera intrinsics with focal lengths are needed to estimate the pose of a tag")
+	}
+	half := tagSize / 2
+	object := []r2.Point{{X: -half, Y: -half}, {X: half, Y: -half}, {X: half, Y: half}, {X: -half, Y: half}}
+	observed := make([]r2.Point, 4)
+	for i, corner := range corners {
+		observed[i] = undistort(r2.Point{
+			X: (corner.X - intrinsics.Ppx) / intrinsics.Fx,
+			Y: (corner.Y - intrinsics.Ppy) / intrinsics.Fy,
+		}, distortion)
+	}
+
+	rotation, translation, err := poseFromHomography(object, observed)
+	if err != nil {
+		return nil, err
+	}
+	rotation, translation = refinePose(rotation, translation, object, observed)
+
+	orientation, err := spatialmath.NewRotationMatrix(rotation[:])
+	if err != nil {
+		return nil, err
+	}
+	return spatialmath.NewPose(translation, orientation), nil
+}
+
+// undistort inverts the distortion of a point in normalized image coordinates by fixed point iteration.
+func undistort(p r2.Point, distortion transform.Distorter) r2.Point {
+	if distortion == nil {
+		return p
+	}
+	undistorted := p
+	for i := 0; i < 20; i++ {
+		x, y := distortion.Transform(undistorted.X, undistorted.Y)
+		undistorted = undistorted.Sub(r2.Point{X: x, Y: y}.Sub(p))
+	}
+	return undistorted
+}
+
+// poseFromHomography returns the rotation, in row major order, and translation that take points on the plane z = 0
+// to where they were observed in normalized image coordinates, by decomposing the homography between them.
+func poseFromHomography(object, observed []r2.Point) ([9]float64, r3.Vector, error) {
+	var rotation [9]float64
+	h, err := transform.EstimateExactHomographyFrom8Points(object, observed, false)
+	if err != nil {
+		return rotation, r3.Vector{}, errors.Wrap(err, "the corners of the tag are degenerate")
+	}
+	// The columns of the homography are the first two columns of the rotation and the translation, up to scale.
+	x := r3.Vector{X: h.At(0, 0), Y: h.At(1, 0), Z: h.At(2, 0)}
+	y := r3.Vector{X: h.At(0, 1), Y: h.At(1, 1), Z: h.At(2, 1)}
+	t := r3.Vector{X: h.At(0, 2), Y: h.At(1, 2), Z: h.At(2, 2)}
+	scale := 2 / (x.Norm() + y.Norm())
+	if t.Z < 0 {
+		// The tag is in front of the camera.
+		scale = -scale
+	}
+	x, y, t = x.Mul(scale), y.Mul(scale), t.Mul(scale)
+	z := x.Cross(y)
+
+	// Find the nearest rotation to the estimate.
+	estimate := mat.NewDense(3, 3, []float64{x.X, y.X, z.X, x.Y, y.Y, z.Y, x.Z, y.Z, z.Z})
+	var svd mat.SVD
+	if !svd.Factorize(estimate, mat.SVDFull) {
+		return rotation, r3.Vector{}, errors.New("could not factorize the rotation of the tag")
+	}
+	var u, v, nearest mat.Dense
+	svd.UTo(&u)
+	svd.VTo(&v)
+	nearest.Mul(&u, v.T())
+	if mat.Det(&nearest) < 0 {
+		for row := 0; row < 3; row++ {
+			u.Set(row, 2, -u.At(row, 2))
+		}
+		nearest.Mul(&u, v.T())
+	}
+	for i := range rotation {
+		rotation[i] = nearest.At(i/3, i%3)
+	}
+	return rotation, t, nil
+}
+
+// refinePose adjusts the rotation and translation to minimize the distance between where the points project and
+// where they were observed, with a few damped Gauss-Newton steps.
+func refinePose(rotation [9]float64, translation r3.Vector, object, observed []r2.Point) ([9]float64, r3.Vector) {
+	residuals := func(rotation [9]float64, translation r3.Vector) []float64 {
+		out := make([]float64, 0, 2*len(object))
+		for i, p := range object {
+			camera := rotate(rotation, r3.Vector{X: p.X, Y: p.Y}).Add(translation)
+			out = append(out, camera.X/camera.Z-observed[i].X, camera.Y/camera.Z-observed[i].Y)
+		}
+		return out
+	}
+	apply := func(step []float64) ([9]float64, r3.Vector) {
+		return multiply(rodrigues(r3.Vector{X: step[0], Y: step[1], Z: step[2]}), rotation),
+			translation.Add(r3.Vector{X: step[3], Y: step[4], Z: step[5]})
+	}
+	cost := func(r []float64) float64 {
+		var sum float64
+		for _, v := range r {
+			sum += v * v
+		}
+		return sum
+	}
+
+	current := residuals(rotation, translation)
+	damping := 1e-3
+	for iteration := 0; iteration < 20; iteration++ {
+		jacobian := mat.NewDense(len(current), 6, nil)
+		for j := 0; j < 6; j++ {
+			step := make([]float64, 6)
+			step[j] = 1e-6 * math.Max(1, math.Abs(translation.Z))
+			if j < 3 {
+				step[j] = 1e-6
+			}
+			perturbed := residuals(apply(step))
+			for i := range current {
+				jacobian.Set(i, j, (perturbed[i]-current[i])/step[j])
+			}
+		}
+		var normal mat.Dense
+		normal.Mul(jacobian.T(), jacobian)
+		for j := 0; j < 6; j++ {
+			normal.Set(j, j, normal.At(j, j)*(1+damping))
+		}
+		var gradient, step mat.VecDense
+		gradient.MulVec(jacobian.T(), mat.NewVecDense(len(current), current))
+		if err := step.SolveVec(&normal, &gradient); err != nil {
+			break
+		}
+		stepValues := make([]float64, 6)
+		for j := range stepValues {
+			stepValues[j] = -step.AtVec(j)
+		}
+		nextRotation, nextTranslation := apply(stepValues)
+		next := residuals(nextRotation, nextTranslation)
+		if cost(next) >= cost(current) {
+			damping *= 10
+			continue
+		}
+		damping = math.Max(damping/10, 1e-9)
+		improvement := cost(current) - cost(next)
+		rotation, translation, current = nextRotation, nextTranslation, next
+		if improvement < 1e-18 {
+			break
+		}
+	}
+	return rotation, translation
+}
+
+func rotate(rotation [9]float64, v r3.Vector) r3.Vector {
+	return r3.Vector{
+		X: rotation[0]*v.X + rotation[1]*v.Y + rotation[2]*v.Z,
+		Y: rotation[3]*v.X + rotation[4]*v.Y + rotation[5]*v.Z,
+		Z: rotation[6]*v.X + rotation[7]*v.Y + rotation[8]*v.Z,
+	}
+}
+
+func multiply(a, b [9]float64) [9]float64 {
+	var product [9]float64
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			for k := 0; k < 3; k++ {
+				product[3*row+col] += a[3*row+k] * b[3*k+col]
+			}
+		}
+	}
+	return product
+}
+
+// rodrigues returns the rotation matrix of a rotation vector.
+func rodrigues(v r3.Vector) [9]float64 {
+	theta := v.Norm()
+	if theta < 1e-12 {
+		return [9]float64{1, -v.Z, v.Y, v.Z, 1, -v.X, -v.Y, v.X, 1}
+	}
+	k := v.Mul(1 / theta)
+	c, s := math.Cos(theta), math.Sin(theta)
+	return [9]float64{
+		c + k.X*k.X*(1-c), k.X*k.Y*(1-c) - k.Z*s, k.X*k.Z*(1-c) + k.Y*s,
+		k.Y*k.X*(1-c) + k.Z*s, c + k.Y*k.Y*(1-c), k.Y*k.Z*(1-c) - k.X*s,
+		k.Z*k.X*(1-c) - k.Y*s, k.Z*k.Y*(1-c) + k.X*s, c + k.Z*k.Z*(1-c),
+	}
+}
@@ -0,0 +1,134 @@
+package fiducial
+
+import (
+	"math"
+	"sort"
+
+	"github.com/golang/geo/r2"
+)
+
+// fitQuad returns the largest quadrilateral inside the convex hull of the points, clockwise in image coordinates, if
+// it covers nearly all of the hull.
+func fitQuad(points []r2.Point) ([4]r2.Point, bool) {
+	var quad [4]r2.Point
+	hull := convexHull(points)
+	if len(hull) < 4 {
+		return quad, false
+	}
+	hullArea := polygonArea(hull)
+
+	// Start from two far apart points and the points farthest to either side of the line between them.
+	var centroid r2.Point
+	for _, p := range hull {
+		centroid = centroid.Add(p)
+	}
+	centroid = centroid.Mul(1 / float64(len(hull)))
+	farthest := func(score func(r2.Point) float64) int {
+		best := 0
+		for i, p := range hull {
+			if score(p) > score(hull[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	var corners [4]int
+	corners[0] = farthest(func(p r2.Point) float64 { return p.Sub(centroid).Norm() })
+	corners[2] = farthest(func(p r2.Point) float64 { return p.Sub(hull[corners[0]]).Norm() })
+	diagonal := hull[corners[2]].Sub(hull[corners[0]])
+	corners[1] = farthest(func(p r2.Point) float64 { return diagonal.Cross(p.Sub(hull[corners[0]])) })
+	corners[3] = farthest(func(p r2.Point) float64 { return -diagonal.Cross(p.Sub(hull[corners[0]])) })
+
+	// Move one corner at a time to wherever on the hull gives the largest quadrilateral, until none moves.
+	area := func() float64 {
+		for i := range quad {
+			quad[i] = hull[corners[i]]
+		}
+		return math.Abs(polygonArea(quad[:]))
+	}
+	best := area()
+	for iteration := 0; iteration < 10; iteration++ {
+		moved := false
+		for c := range corners {
+			current := corners[c]
+			for i := range hull {
+				corners[c] = i
+				if a := area(); a > best+1e-9 {
+					best, current, moved = a, i, true
+				}
+			}
+			corners[c] = current
+		}
+		if !moved {
+			break
+		}
+	}
+	area()
+
+	if best < minQuadFill*math.Abs(hullArea) {
+		return quad, false
+	}
+	for i := range quad {
+		if quad[i].Sub(quad[(i+1)%4]).Norm() < minTagPixels/2 {
+			return quad, false
+		}
+		// Every turn must be in the same direction for the quadrilateral to be convex.
+		turn := quad[(i+1)%4].Sub(quad[i]).Cross(quad[(i+2)%4].Sub(quad[(i+1)%4]))
+		if turn*polygonArea(quad[:]) <= 0 {
+			return quad, false
+		}
+	}
+	if polygonArea(quad[:]) < 0 {
+		quad[1], quad[3] = quad[3], quad[1]
+	}
+	return quad, true
+}
+
+// convexHull returns the convex hull of the points, clockwise in image coordinates.
+func convexHull(points []r2.Point) []r2.Point {
+	sorted := append([]r2.Point(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].X != sorted[j].X {
+			return sorted[i].X < sorted[j].X
+		}
+		return sorted[i].Y < sorted[j].Y
+	})
+	if len(sorted) < 3 {
+		return sorted
+	}
+	hull := make([]r2.Point, 0, 2*len(sorted))
+	for pass := 0; pass < 2; pass++ {
+		start := len(hull)
+		for _, p := range sorted {
+			for len(hull) >= start+2 && hull[len(hull)-1].Sub(hull[len(hull)-2]).Cross(p.Sub(hull[len(hull)-1])) <= 0 {
+				hull = hull[:len(hull)-1]
+			}
+			hull = append(hull, p)
+		}
+		// The last point of each chain is the first of the other.
+		hull = hull[:len(hull)-1]
+		for i, j := 0, len(sorted)-1; i < j; i, j = i+1, j-1 {
+			sorted[i], sorted[j] = sorted[j], sorted[i]
+		}
+	}
+	return hull
+}
+
+// polygonArea returns the area of the polygon, positive if it is clockwise in image coordinates.
+func polygonArea(polygon []r2.Point) float64 {
+	var area float64
+	for i, p := range polygon {
+		area += p.Cross(polygon[(i+1)%len(polygon)])
+	}
+	return area / 2
+}
+
+// containsPoint returns whether the point is inside the clockwise convex quadrilateral.
+func containsPoint(quad [4]r2.Point, p r2.Point) bool {
+	for i := range quad {
+		if quad[(i+1)%4].Sub(quad[i]).Cross(p.Sub(quad[i])) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package objecttracking
+
+import "math"
+
+// assign solves the assignment problem for a rectangular cost matrix with the Hungarian method, returning the
+// column assigned to each row, or -1 for the rows left over when there are more rows than columns.
+func assign(cost [][]float64) []int {
+	rows := len(cost)
+	if rows == 0 {
+		return nil
+	}
+	cols := len(cost[0])
+	if cols == 0 {
+		assignment := make([]int, rows)
+		for i := range assignment {
+			assignment[i] = -1
+		}
+		return assignment
+	}
+	if rows > cols {
+		transposed := make([][]float64, cols)
+		for j := range transposed {
+			transposed[j] = make([]float64, rows)
+			for i := range cost {
+				transposed[j][i] = cost[i][j]
+			}
+		}
+		assignment := make([]int, rows)
+		for i := range assignment {
+			assignment[i] = -1
+		}
+		for j, i := range assign(transposed) {
+			assignment[i] = j
+		}
+		return assignment
+	}
+
+	// The potentials u and v of the rows and columns, and the row matched to each column, are indexed from 1 so that
+	// column 0 can stand for the row being added.
+	u := make([]float64, rows+1)
+	v := make([]float64, cols+1)
+	matched := make([]int, cols+1)
+	way := make([]int, cols+1)
+	for i := 1; i <= rows; i++ {
+		matched[0] = i
+		j0 := 0
+		minSlack := make([]float64, cols+1)
+		used := make([]bool, cols+1)
+		for j := range minSlack {
+			minSlack[j] = math.Inf(1)
+		}
+		for matched[j0] != 0 {
+			used[j0] = true
+			i0, delta, j1 := matched[j0], math.Inf(1), 0
+			for j := 1; j <= cols; j++ {
+				if used[j] {
+					continue
+				}
+				slack := cost[i0-1][j-1] - u[i0] - v[j]
+				if slack < minSlack[j] {
+					minSlack[j], way[j] = slack, j0
+				}
+				if minSlack[j] < delta {
+					delta, j1 = minSlack[j], j
+				}
+			}
+			for j := 0; j <= cols; j++ {
+				if used[j] {
+					u[matched[j]] += delta
+					v[j] -= delta
+				} else {
+					minSlack[j] -= delta
+				}
+			}
+			j0 = j1
+		}
+		// Flip the matches along the augmenting path.
+		for j0 != 0 {
+			j1 := way[j0]
+			matched[j0] = matched[j1]
+			j0 = j1
+		}
+	}
+
+	assignment := make([]int, rows)
+	for j := 1; j <= cols; j++ {
+		if matched[j] != 0 {
+			assignment[matched[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
@@ -0,0 +1,71 @@
+package objecttracking
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"go.viam.com/test"
+)
+
+// bestCost finds the cheapest assignment of rows to distinct columns by trying all of them.
+func bestCost(cost [][]float64, row int, used []bool) float64 {
+	if row == len(cost) {
+		return 0
+	}
+	best := math.Inf(1)
+	for j := range cost[row] {
+		if used[j] {
+			continue
+		}
+		used[j] = true
+		best = math.Min(best, cost[row][j]+bestCost(cost, row+1, used))
+		used[j] = false
+	}
+	return best
+}
+
+func TestAssign(t *testing.T) {
+	test.That(t, assign(nil), test.ShouldBeNil)
+	test.That(t, assign([][]float64{{}, {}}), test.ShouldResemble, []int{-1, -1})
+	test.That(t, assign([][]float64{{4, 1, 3}, {2, 0, 5}, {3, 2, 2}}), test.ShouldResemble, []int{1, 0, 2})
+
+	//nolint:gosec
+	r := rand.New(rand.NewSource(1))
+	for trial := 0; trial < 50; trial++ {
+		rows, cols := 1+r.Intn(5), 1+r.Intn(5)
+		cost := make([][]float64, rows)
+		for i := range cost {
+			cost[i] = make([]float64, cols)
+			for j := range cost[i] {
+				cost[i][j] = r.Float64()
+			}
+		}
+		assignment := assign(cost)
+		test.That(t, assignment, test.ShouldHaveLength, rows)
+		total := 0.
+		assigned := map[int]bool{}
+		for i, j := range assignment {
+			if j < 0 {
+				continue
+			}
+			test.That(t, assigned[j], test.ShouldBeFalse)
+			assigned[j] = true
+			total += cost[i][j]
+		}
+		test.That(t, assigned, test.ShouldHaveLength, min(rows, cols))
+
+		// Brute force over the smaller side.
+		expected := cost
+		if rows > cols {
+			expected = make([][]float64, cols)
+			for j := range expected {
+				expected[j] = make([]float64, rows)
+				for i := range cost {
+					expected[j][i] = cost[i][j]
+				}
+			}
+		}
+		test.That(t, total, test.ShouldAlmostEqual, bestCost(expected, 0, make([]bool, max(rows, cols))))
+	}
+}
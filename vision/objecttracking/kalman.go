@@ -0,0 +1,173 @@
+package objecttracking
+
+import (
+	"image"
+	"math"
+
+	"github.com/golang/geo/r2"
+	"gonum.org/v1/gonum/mat"
+)
+
+// Indices into the state of the filter, which follows SORT: the center of the box, its area and its aspect ratio,
+// with the rates of change of all but the aspect ratio.
+const (
+	stateU         = iota // center x, in pixels
+	stateV                // center y, in pixels
+	stateArea             // in pixels squared
+	stateRatio            // width over height
+	stateUVelocity        // in pixels per second
+	stateVVelocity        // in pixels per second
+	stateAreaRate         // in pixels squared per second
+	stateSize
+	measurementSize = stateRatio + 1
+)
+
+// The noise of the filter. Changes in velocity and in the rate of change of the area are modeled as white noise
+// accelerations, and the aspect ratio as a random walk.
+const (
+	centerAccelerationVariance = 1000. * 1000. // (pixels/s^2)^2
+	areaAccelerationVariance   = 1e5 * 1e5     // (pixels^2/s^2)^2
+	ratioVariancePerSecond     = 0.01
+)
+
+var (
+	measurementVariances     = []float64{1, 1, 100, 0.01}
+	initialVelocityVariances = []float64{1000. * 1000., 1000. * 1000., 1e5 * 1e5}
+)
+
+// boxFilter is a linear Kalman filter of the motion of a bounding box in an image, assuming constant velocity.
+type boxFilter struct {
+	state *mat.VecDense
+	cov   *mat.SymDense
+}
+
+// newBoxFilter returns a filter at the given box, with an unknown velocity.
+func newBoxFilter(box image.Rectangle) *boxFilter {
+	state := mat.NewVecDense(stateSize, nil)
+	z := boxToMeasurement(box)
+	for i, value := range z {
+		state.SetVec(i, value)
+	}
+	cov := mat.NewSymDense(stateSize, nil)
+	for i, variance := range measurementVariances {
+		cov.SetSym(i, i, variance)
+	}
+	for i, variance := range initialVelocityVariances {
+		cov.SetSym(stateUVelocity+i, stateUVelocity+i, variance)
+	}
+	return &boxFilter{state: state, cov: cov}
+}
+
+// predict advances the estimate by dt seconds.
+func (f *boxFilter) predict(dt float64) {
+	if dt <= 0 {
+		return
+	}
+	// The area cannot shrink past zero.
+	if f.state.AtVec(stateArea)+f.state.AtVec(stateAreaRate)*dt <= 0 {
+		f.state.SetVec(stateAreaRate, 0)
+	}
+	transition := mat.NewDense(stateSize, stateSize, nil)
+	for i := 0; i < stateSize; i++ {
+		transition.Set(i, i, 1)
+	}
+	transition.Set(stateU, stateUVelocity, dt)
+	transition.Set(stateV, stateVVelocity, dt)
+	transition.Set(stateArea, stateAreaRate, dt)
+
+	var state mat.VecDense
+	state.MulVec(transition, f.state)
+	f.state = &state
+
+	processNoise := mat.NewDense(stateSize, stateSize, nil)
+	for _, pair := range []struct {
+		position, rate int
+		variance       float64
+	}{
+		{stateU, stateUVelocity, centerAccelerationVariance},
+		{stateV, stateVVelocity, centerAccelerationVariance},
+		{stateArea, stateAreaRate, areaAccelerationVariance},
+	} {
+		processNoise.Set(pair.position, pair.position, dt*dt*dt*dt/4*pair.variance)
+		processNoise.Set(pair.position, pair.rate, dt*dt*dt/2*pair.variance)
+		processNoise.Set(pair.rate, pair.position, dt*dt*dt/2*pair.variance)
+		processNoise.Set(pair.rate, pair.rate, dt*dt*pair.variance)
+	}
+	processNoise.Set(stateRatio, stateRatio, dt*ratioVariancePerSecond)
+
+	var transitionCov, propagated mat.Dense
+	transitionCov.Mul(transition, f.cov)
+	propagated.Mul(&transitionCov, transition.T())
+	propagated.Add(&propagated, processNoise)
+	f.cov = symmetrize(&propagated)
+}
+
+// update corrects the estimate with a measured box.
+func (f *boxFilter) update(box image.Rectangle) error {
+	h := mat.NewDense(measurementSize, stateSize, nil)
+	for i := 0; i < measurementSize; i++ {
+		h.Set(i, i, 1)
+	}
+	var predicted mat.VecDense
+	predicted.MulVec(h, f.state)
+	residual := mat.NewVecDense(measurementSize, boxToMeasurement(box))
+	residual.SubVec(residual, &predicted)
+
+	var hCov, innovation, innovationInv mat.Dense
+	hCov.Mul(h, f.cov)
+	innovation.Mul(&hCov, h.T())
+	innovation.Add(&innovation, mat.NewDiagDense(measurementSize, measurementVariances))
+	if err := innovationInv.Inverse(&innovation); err != nil {
+		return err
+	}
+	var gain mat.Dense
+	gain.Mul(hCov.T(), &innovationInv)
+
+	var correction mat.VecDense
+	correction.MulVec(&gain, residual)
+	f.state.AddVec(f.state, &correction)
+
+	var gainH, correctionCov, cov mat.Dense
+	gainH.Mul(&gain, h)
+	correctionCov.Mul(&gainH, f.cov)
+	cov.Sub(f.cov, &correctionCov)
+	f.cov = symmetrize(&cov)
+	return nil
+}
+
+// box returns the estimated bounding box.
+func (f *boxFilter) box() image.Rectangle {
+	area := math.Max(f.state.AtVec(stateArea), 0)
+	ratio := math.Max(f.state.AtVec(stateRatio), 1e-6)
+	w := math.Sqrt(area * ratio)
+	h := area / math.Max(w, 1e-6)
+	u, v := f.state.AtVec(stateU), f.state.AtVec(stateV)
+	return image.Rect(int(math.Round(u-w/2)), int(math.Round(v-h/2)), int(math.Round(u+w/2)), int(math.Round(v+h/2)))
+}
+
+// velocity returns the estimated velocity of the center of the box in pixels per second.
+func (f *boxFilter) velocity() r2.Point {
+	return r2.Point{X: f.state.AtVec(stateUVelocity), Y: f.state.AtVec(stateVVelocity)}
+}
+
+// boxToMeasurement converts a box to the center, area and aspect ratio measured by the filter.
+func boxToMeasurement(box image.Rectangle) []float64 {
+	w, h := float64(box.Dx()), float64(box.Dy())
+	return []float64{
+		float64(box.Min.X) + w/2,
+		float64(box.Min.Y) + h/2,
+		w * h,
+		w / math.Max(h, 1),
+	}
+}
+
+func symmetrize(m *mat.Dense) *mat.SymDense {
+	n, _ := m.Dims()
+	sym := mat.NewSymDense(n, nil)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			sym.SetSym(i, j, (m.At(i, j)+m.At(j, i))/2)
+		}
+	}
+	return sym
+}
@@ -0,0 +1,221 @@
+// Package objecttracking follows the objects found by a detector from one image to the next, in the manner of SORT
+// ("Simple Online and Realtime Tracking" by Bewley et al. 2016): a Kalman filter predicts where each tracked box will
+// be, and the Hungarian method matches the predictions to the new detections by how much they overlap.
+package objecttracking
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/geo/r2"
+	"github.com/pkg/errors"
+
+	"go.viam.com/rdk/vision/objectdetection"
+)
+
+// Config describes how the tracker associates detections with tracks.
+type Config struct {
+	// IoUThreshold is the smallest intersection over union between a detection and the predicted box of a track for
+	// the detection to continue the track.
+	IoUThreshold float64
+	// MaxMissed is the number of updates in a row that a track can go without a detection before it is dropped.
+	MaxMissed int
+	// MinHits is the number of detections a track needs before its detections are reported.
+	MinHits int
+}
+
+// DefaultConfig returns the config SORT uses, except that tracks survive a few missed detections.
+func DefaultConfig() Config {
+	return Config{IoUThreshold: 0.3, MaxMissed: 5, MinHits: 3}
+}
+
+// CheckValid checks that the config can be used to track.
+func (c Config) CheckValid() error {
+	if c.IoUThreshold <= 0 || c.IoUThreshold > 1 {
+		return errors.Errorf("iou threshold must be in (0, 1], got %v", c.IoUThreshold)
+	}
+	if c.MaxMissed < 0 {
+		return errors.Errorf("max missed must not be negative, got %d", c.MaxMissed)
+	}
+	if c.MinHits < 1 {
+		return errors.Errorf("min hits must be at least 1, got %d", c.MinHits)
+	}
+	return nil
+}
+
+// Track is the state of one tracked object.
+type Track struct {
+	ID    int
+	Label string
+	// BoundingBox is the box the filter estimates the object is in.
+	BoundingBox image.Rectangle
+	// Velocity is the velocity of the center of the box in pixels per second.
+	Velocity  r2.Point
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Hits is the number of detections of the object, and Missed the number of updates in a row without one.
+	Hits   int
+	Missed int
+}
+
+// Age is how long the object has been tracked for.
+func (t Track) Age() time.Duration {
+	return t.LastSeen.Sub(t.FirstSeen)
+}
+
+// Confirmed returns whether the track has been detected often enough for its detections to be reported.
+func (t Track) Confirmed(minHits int) bool {
+	return t.Hits >= minHits
+}
+
+// Detection is a detection that continues a track. Its label is the label of the detection followed by the ID of the
+// track, such as "person_3", so that the identity of the object survives going through the vision service API.
+type Detection struct {
+	objectdetection.Detection
+	Track Track
+}
+
+// Label returns the label of the detection with the ID of its track.
+func (d *Detection) Label() string {
+	return fmt.Sprintf("%s_%d", d.Detection.Label(), d.Track.ID)
+}
+
+// String turns the detection into a string.
+func (d *Detection) String() string {
+	return fmt.Sprintf("Label: %s, Score: %.2f, Box: %v, Velocity: %v, Age: %v",
+		d.Label(), d.Score(), d.BoundingBox(), d.Track.Velocity, d.Track.Age())
+}
+
+type trackState struct {
+	track  Track
+	filter *boxFilter
+}
+
+// Tracker assigns the detections in a sequence of images to tracks. It is safe to use concurrently.
+type Tracker struct {
+	config Config
+
+	mu         sync.Mutex
+	tracks     []*trackState
+	nextID     int
+	lastUpdate time.Time
+}
+
+// NewTracker returns a tracker with no tracks.
+func NewTracker(config Config) (*Tracker, error) {
+	if err := config.CheckValid(); err != nil {
+		return nil, err
+	}
+	return &Tracker{config: config, nextID: 1}, nil
+}
+
+// Config returns the config of the tracker.
+func (t *Tracker) Config() Config {
+	return t.config
+}
+
+// Update matches the detections of an image taken at the given time to the tracks, starting new tracks for the
+// detections that match none, and returns the detections of the confirmed tracks.
+func (t *Tracker) Update(detections []objectdetection.Detection, now time.Time) ([]objectdetection.Detection, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	dt := 0.
+	if !t.lastUpdate.IsZero() {
+		dt = now.Sub(t.lastUpdate).Seconds()
+	}
+	t.lastUpdate = now
+	predicted := make([]image.Rectangle, len(t.tracks))
+	for i, ts := range t.tracks {
+		ts.filter.predict(dt)
+		predicted[i] = ts.filter.box()
+	}
+
+	// The cost of matching a detection to a track is one minus their overlap, and detections only continue tracks with
+	// the same label.
+	cost := make([][]float64, len(detections))
+	for i, detection := range detections {
+		cost[i] = make([]float64, len(t.tracks))
+		for j, ts := range t.tracks {
+			cost[i][j] = 1
+			if detection.Label() == ts.track.Label {
+				cost[i][j] = 1 - IoU(*detection.BoundingBox(), predicted[j])
+			}
+		}
+	}
+	matchedTracks := make([]bool, len(t.tracks))
+	tracked := make([]objectdetection.Detection, 0, len(detections))
+	for i, j := range assign(cost) {
+		detection := detections[i]
+		var ts *trackState
+		if j >= 0 && 1-cost[i][j] >= t.config.IoUThreshold {
+			ts = t.tracks[j]
+			matchedTracks[j] = true
+			if err := ts.filter.update(*detection.BoundingBox()); err != nil {
+				return nil, errors.Wrapf(err, "could not update track %d", ts.track.ID)
+			}
+			ts.track.Hits++
+			ts.track.Missed = 0
+			ts.track.LastSeen = now
+		} else {
+			ts = &trackState{
+				track:  Track{ID: t.nextID, Label: detection.Label(), FirstSeen: now, LastSeen: now, Hits: 1},
+				filter: newBoxFilter(*detection.BoundingBox()),
+			}
+			t.nextID++
+			t.tracks = append(t.tracks, ts)
+		}
+		ts.track.BoundingBox = ts.filter.box()
+		ts.track.Velocity = ts.filter.velocity()
+		if ts.track.Confirmed(t.config.MinHits) {
+			tracked = append(tracked, &Detection{Detection: detection, Track: ts.track})
+		}
+	}
+
+	kept := t.tracks[:0]
+	for j, ts := range t.tracks {
+		if j < len(matchedTracks) && !matchedTracks[j] {
+			ts.track.Missed++
+			ts.track.BoundingBox = predicted[j]
+		}
+		if ts.track.Missed <= t.config.MaxMissed {
+			kept = append(kept, ts)
+		}
+	}
+	t.tracks = kept
+	return tracked, nil
+}
+
+// Tracks returns the current tracks, including those not yet confirmed, in the order they were started.
+func (t *Tracker) Tracks() []Track {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	tracks := make([]Track, 0, len(t.tracks))
+	for _, ts := range t.tracks {
+		tracks = append(tracks, ts.track)
+	}
+	sort.Slice(tracks, func(i, j int) bool { return tracks[i].ID < tracks[j].ID })
+	return tracks
+}
+
+// Clear drops every track. The IDs of new tracks keep counting up from those of the dropped ones, so that an ID is
+// never reused for a different object.
+func (t *Tracker) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tracks = nil
+	t.lastUpdate = time.Time{}
+}
+
+// IoU returns the intersection over union of two boxes.
+func IoU(a, b image.Rectangle) float64 {
+	intersection := a.Intersect(b)
+	if intersection.Empty() {
+		return 0
+	}
+	area := func(r image.Rectangle) float64 { return float64(r.Dx() * r.Dy()) }
+	return area(intersection) / (area(a) + area(b) - area(intersection))
+}
@@ -0,0 +1,119 @@
+package objecttracking
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"go.viam.com/test"
+
+	"go.viam.com/rdk/vision/objectdetection"
+)
+
+func detect(label string, x, y int) objectdetection.Detection {
+	return objectdetection.NewDetectionWithoutImgBounds(image.Rect(x, y, x+40, y+80), 0.9, label)
+}
+
+func TestConfig(t *testing.T) {
+	test.That(t, DefaultConfig().CheckValid(), test.ShouldBeNil)
+	_, err := NewTracker(Config{IoUThreshold: 0, MinHits: 1})
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewTracker(Config{IoUThreshold: 0.3, MaxMissed: -1, MinHits: 1})
+	test.That(t, err, test.ShouldNotBeNil)
+	_, err = NewTracker(Config{IoUThreshold: 0.3})
+	test.That(t, err, test.ShouldNotBeNil)
+}
+
+func TestIoU(t *testing.T) {
+	test.That(t, IoU(image.Rect(0, 0, 10, 10), image.Rect(0, 0, 10, 10)), test.ShouldEqual, 1)
+	test.That(t, IoU(image.Rect(0, 0, 10, 10), image.Rect(5, 0, 15, 10)), test.ShouldAlmostEqual, 1./3)
+	test.That(t, IoU(image.Rect(0, 0, 10, 10), image.Rect(20, 0, 30, 10)), test.ShouldEqual, 0)
+}
+
+func TestTracker(t *testing.T) {
+	tracker, err := NewTracker(DefaultConfig())
+	test.That(t, err, test.ShouldBeNil)
+	start := time.Unix(1000, 0)
+	frame := 100 * time.Millisecond
+
+	// A person walks right at 100 pixels per second past a cat walking left at 50, and a dog stands still.
+	var tracked []objectdetection.Detection
+	for i := 0; i < 20; i++ {
+		detections := []objectdetection.Detection{
+			detect("cat", 400-5*i, 100),
+			detect("person", 100+10*i, 100),
+			detect("dog", 300, 300),
+		}
+		// The dog is hidden for a few frames.
+		if i >= 10 && i < 13 {
+			detections = detections[:2]
+		}
+		tracked, err = tracker.Update(detections, start.Add(time.Duration(i)*frame))
+		test.That(t, err, test.ShouldBeNil)
+		if i < 2 {
+			// The tracks are not confirmed until they have been seen three times.
+			test.That(t, tracked, test.ShouldBeEmpty)
+		}
+	}
+	test.That(t, tracked, test.ShouldHaveLength, 3)
+	byLabel := map[string]*Detection{}
+	for _, d := range tracked {
+		detection, ok := d.(*Detection)
+		test.That(t, ok, test.ShouldBeTrue)
+		byLabel[detection.Track.Label] = detection
+	}
+	// The IDs are in the order the objects were first seen, and stay the same across the frames.
+	test.That(t, byLabel["cat"].Label(), test.ShouldEqual, "cat_1")
+	test.That(t, byLabel["person"].Label(), test.ShouldEqual, "person_2")
+	test.That(t, byLabel["dog"].Label(), test.ShouldEqual, "dog_3")
+	test.That(t, byLabel["person"].Track.Velocity.X, test.ShouldAlmostEqual, 100, 5)
+	test.That(t, byLabel["person"].Track.Velocity.Y, test.ShouldAlmostEqual, 0, 5)
+	test.That(t, byLabel["cat"].Track.Velocity.X, test.ShouldAlmostEqual, -50, 5)
+	test.That(t, byLabel["dog"].Track.Velocity.Norm(), test.ShouldBeLessThan, 5)
+	test.That(t, byLabel["person"].Track.Age(), test.ShouldEqual, 19*frame)
+	test.That(t, byLabel["dog"].Track.Hits, test.ShouldEqual, 17)
+	test.That(t, *byLabel["person"].BoundingBox(), test.ShouldResemble, image.Rect(290, 100, 330, 180))
+
+	// A track that is missed too many times is dropped, and a new one is started when the object comes back.
+	now := start.Add(20 * frame)
+	for i := 0; i < 6; i++ {
+		_, err = tracker.Update([]objectdetection.Detection{detect("cat", 300-5*i, 100), detect("dog", 300, 300)}, now)
+		test.That(t, err, test.ShouldBeNil)
+		now = now.Add(frame)
+	}
+	tracks := tracker.Tracks()
+	test.That(t, tracks, test.ShouldHaveLength, 2)
+	test.That(t, tracks[0].ID, test.ShouldEqual, 1)
+	test.That(t, tracks[1].ID, test.ShouldEqual, 3)
+	_, err = tracker.Update([]objectdetection.Detection{detect("person", 330, 100)}, now)
+	test.That(t, err, test.ShouldBeNil)
+	tracks = tracker.Tracks()
+	test.That(t, tracks, test.ShouldHaveLength, 3)
+	test.That(t, tracks[2].ID, test.ShouldEqual, 4)
+	test.That(t, tracks[2].Label, test.ShouldEqual, "person")
+	test.That(t, tracks[2].Confirmed(3), test.ShouldBeFalse)
+	test.That(t, tracks[0].Missed, test.ShouldEqual, 1)
+
+	tracker.Clear()
+	test.That(t, tracker.Tracks(), test.ShouldBeEmpty)
+	_, err = tracker.Update([]objectdetection.Detection{detect("person", 330, 100)}, now)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, tracker.Tracks()[0].ID, test.ShouldEqual, 5)
+}
+
+func TestTrackerLabels(t *testing.T) {
+	tracker, err := NewTracker(Config{IoUThreshold: 0.3, MinHits: 1})
+	test.That(t, err, test.ShouldBeNil)
+	now := time.Unix(1000, 0)
+	tracked, err := tracker.Update([]objectdetection.Detection{detect("cat", 0, 0)}, now)
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, tracked, test.ShouldHaveLength, 1)
+	test.That(t, tracked[0].Label(), test.ShouldEqual, "cat_1")
+
+	// A detection in the same place with a different label is a different object.
+	tracked, err = tracker.Update([]objectdetection.Detection{detect("dog", 0, 0)}, now.Add(time.Second))
+	test.That(t, err, test.ShouldBeNil)
+	test.That(t, tracked[0].Label(), test.ShouldEqual, "dog_2")
+	// With no missed detections allowed, the cat's track is already gone.
+	test.That(t, tracker.Tracks(), test.ShouldHaveLength, 1)
+}
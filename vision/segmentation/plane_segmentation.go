@@ -223,6 +223,7 @@ func findBestEq(ctx context.Context, cloud pc.PointCloud, nIterations int, equat
 	for i, result := range bestResults {
 		if result.inliers > bestInliers {
 			bestIdx = i
+			bestInliers = result.inliers
 		}
 	}
 	bestEquation = bestResults[bestIdx].equation